@@ -1,10 +1,16 @@
 package cli
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/session"
@@ -16,8 +22,21 @@ var (
 	attachWithBash  bool
 	attachSlot      int
 	attachWorkspace string
+	attachRelaunch  bool
+	attachList      bool
+	attachFormat    string
+	attachTimeout   time.Duration
 )
 
+// attachSession is a running session as shown by coi attach --list
+type attachSession struct {
+	ContainerName  string `json:"container_name"`
+	Slot           int    `json:"slot,omitempty"`
+	Workspace      string `json:"workspace,omitempty"`
+	TmuxSession    string `json:"tmux_session"`
+	ClientAttached bool   `json:"client_attached"`
+}
+
 var attachCmd = &cobra.Command{
 	Use:   "attach [container-name]",
 	Short: "Attach to a running AI coding session",
@@ -26,12 +45,23 @@ var attachCmd = &cobra.Command{
 If no container name is provided, lists all running sessions.
 If only one session is running, attaches to it automatically.
 
+If the AI tool has exited inside the session (only the fallback shell is left,
+or the pane is dead), attach offers to relaunch it, resuming the container's
+latest session, or to drop to bash. --relaunch relaunches without asking.
+
+Before attaching, the container's tmux server is probed; if it does not answer
+within --attach-timeout (e.g. it is wedged), attach fails instead of leaving a
+frozen terminal - use --bash to get a shell and investigate.
+
 Examples:
   coi attach                    # List sessions or auto-attach if only one
   coi attach claude-abc123-1    # Attach to specific session
+  coi attach myenv              # Attach to a named container (coi shell --name myenv)
   coi attach --slot=1           # Attach to slot 1 for current workspace
   coi attach --bash             # Attach to bash shell instead of tmux session
-  coi attach coi-123 --bash     # Attach to specific container with bash`,
+  coi attach coi-123 --bash     # Attach to specific container with bash
+  coi attach --relaunch         # Restart the tool if it exited, then attach
+  coi attach --list --format json  # List running sessions as JSON (for scripts and editors)`,
 	RunE: attachCommand,
 }
 
@@ -39,12 +69,26 @@ func init() {
 	attachCmd.Flags().BoolVar(&attachWithBash, "bash", false, "Attach to bash shell instead of tmux session")
 	attachCmd.Flags().IntVar(&attachSlot, "slot", 0, "Slot number to attach to (requires workspace context)")
 	attachCmd.Flags().StringVarP(&attachWorkspace, "workspace", "w", ".", "Workspace directory (for --slot)")
+	attachCmd.Flags().BoolVar(&attachRelaunch, "relaunch", false, "Relaunch the AI tool without asking if it has exited in the session")
+	attachCmd.Flags().BoolVar(&attachList, "list", false, "List running sessions without attaching")
+	attachCmd.Flags().StringVar(&attachFormat, "format", "text", "Output format for --list: text or json")
+	attachCmd.Flags().DurationVar(&attachTimeout, "attach-timeout", 5*time.Second, "How long to wait for the container's tmux server to respond before giving up")
 	rootCmd.AddCommand(attachCmd)
 }
 
 func attachCommand(cmd *cobra.Command, args []string) error {
 	var targetContainer string
 
+	if attachFormat != "text" && attachFormat != "json" {
+		return fmt.Errorf("invalid format '%s' - must be 'text' or 'json'", attachFormat)
+	}
+	if cmd.Flags().Changed("format") && !attachList {
+		return fmt.Errorf("--format requires --list")
+	}
+	if attachList {
+		return listAttachSessions()
+	}
+
 	// If --slot is provided, calculate container name from workspace and slot
 	if attachSlot > 0 {
 		// Resolve workspace path
@@ -74,17 +118,11 @@ func attachCommand(cmd *cobra.Command, args []string) error {
 
 		// If container name provided, use it
 		if len(args) > 0 {
-			targetContainer = args[0]
 			// Verify it exists and is running
-			found := false
-			for _, c := range containers {
-				if c == targetContainer {
-					found = true
-					break
-				}
-			}
+			var found bool
+			targetContainer, found = resolveAttachTarget(args[0], containers)
 			if !found {
-				return fmt.Errorf("container %s not found or not running", targetContainer)
+				return fmt.Errorf("container %s not found or not running", args[0])
 			}
 		} else if len(containers) == 0 {
 			// No container name provided and no sessions running
@@ -117,6 +155,90 @@ func attachCommand(cmd *cobra.Command, args []string) error {
 	return attachToContainer(targetContainer)
 }
 
+// listAttachSessions prints the running sessions (coi attach --list)
+func listAttachSessions() error {
+	prefix := regexp.QuoteMeta(session.GetContainerPrefix())
+	containers, err := container.ListContainers(prefix + ".*")
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var workspaces map[string]string
+	if toolInstance, err := getConfiguredTool(cfg); err == nil {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			workspaces, _ = loadContainerMetadata(session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance))
+		}
+	}
+
+	sessions := []attachSession{}
+	user := container.CodeUID
+	for _, c := range containers {
+		mgr := container.NewManager(c)
+		if running, err := mgr.Running(); err != nil || !running {
+			continue
+		}
+		info := attachSession{
+			ContainerName: c,
+			Workspace:     workspaces[c],
+			TmuxSession:   fmt.Sprintf("coi-%s", c),
+		}
+		if _, slot, err := session.ParseContainerName(c); err == nil {
+			info.Slot = slot
+		}
+		// No tmux session (e.g. --tmux=false) counts as not attached
+		info.ClientAttached, _ = session.TmuxClientAttached(mgr, info.TmuxSession, user)
+		sessions = append(sessions, info)
+	}
+
+	if attachFormat == "json" {
+		data, err := json.MarshalIndent(sessions, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal sessions: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(sessions) == 0 {
+		fmt.Println("No active sessions")
+		return nil
+	}
+	fmt.Println("Active sessions:")
+	for i, s := range sessions {
+		var details []string
+		if s.Slot > 0 {
+			details = append(details, fmt.Sprintf("slot %d", s.Slot))
+		}
+		if s.Workspace != "" {
+			details = append(details, s.Workspace)
+		}
+		if s.ClientAttached {
+			details = append(details, "attached")
+		}
+		if len(details) > 0 {
+			fmt.Printf("  %d. %s (%s)\n", i+1, s.ContainerName, strings.Join(details, ", "))
+		} else {
+			fmt.Printf("  %d. %s\n", i+1, s.ContainerName)
+		}
+	}
+	return nil
+}
+
+// resolveAttachTarget finds the container an attach argument refers to: a full
+// container name, or the name given to coi shell --name
+func resolveAttachTarget(arg string, containers []string) (string, bool) {
+	candidates := []string{arg}
+	if named, err := session.NamedContainerName(arg); err == nil {
+		candidates = append(candidates, named)
+	}
+	for _, candidate := range candidates {
+		if slices.Contains(containers, candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
 func attachToContainer(containerName string) error {
 	// Calculate the tmux session name (consistent with shell command)
 	tmuxSessionName := fmt.Sprintf("coi-%s", containerName)
@@ -130,9 +252,39 @@ func attachToContainer(containerName string) error {
 
 	// Execute as code user with proper environment setup
 	user := container.CodeUID
+
+	// A wedged tmux server would make the interactive attach hang forever
+	if err := session.ProbeTmuxSession(mgr, tmuxSessionName, user, attachTimeout); err != nil {
+		switch {
+		case errors.Is(err, session.ErrNoTmuxSession):
+			fmt.Fprintf(os.Stderr, "\nNo tmux session found in container.\n")
+		case errors.Is(err, context.DeadlineExceeded):
+			fmt.Fprintf(os.Stderr, "\nError: tmux in container %s did not respond within %s (--attach-timeout), its tmux server may be stuck\n", containerName, attachTimeout)
+		default:
+			return fmt.Errorf("failed to check tmux session %s: %w", tmuxSessionName, err)
+		}
+		fmt.Fprintf(os.Stderr, "The container is still running. To get a shell, use:\n")
+		fmt.Fprintf(os.Stderr, "  coi attach %s --bash\n", containerName)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return exitError(1, "")
+		}
+		return nil
+	}
+
+	// The tool may have exited while detached, leaving only the fallback shell
+	if state, err := session.GetTmuxPaneState(mgr, tmuxSessionName, user); err == nil && state.ToolExited() {
+		switch chooseExitedSessionAction(tmuxSessionName) {
+		case "relaunch":
+			if err := relaunchTool(mgr, tmuxSessionName, state, termEnv); err != nil {
+				return err
+			}
+		case "bash":
+			return attachToContainerWithBash(containerName)
+		}
+	}
 	opts := container.ExecCommandOptions{
 		User:        &user,
-		Cwd:         "/workspace",
+		Cwd:         session.WorkspaceMountPath(mgr),
 		Interactive: true,
 		Env: map[string]string{
 			"TERM": termEnv,
@@ -170,7 +322,7 @@ func attachToContainerWithBash(containerName string) error {
 	user := container.CodeUID
 	opts := container.ExecCommandOptions{
 		User:        &user,
-		Cwd:         "/workspace",
+		Cwd:         session.WorkspaceMountPath(mgr),
 		Interactive: true,
 	}
 
@@ -189,3 +341,95 @@ func attachToContainerWithBash(containerName string) error {
 
 	return nil
 }
+
+// chooseExitedSessionAction asks what to do with a session whose tool has
+// exited: "relaunch", "bash" or "attach" (the default, also without a terminal)
+func chooseExitedSessionAction(tmuxSessionName string) string {
+	if attachRelaunch {
+		return "relaunch"
+	}
+
+	fmt.Printf("The AI tool is no longer running in tmux session %s.\n", tmuxSessionName)
+	fmt.Printf("  [r] Relaunch it (resumes the latest session)\n")
+	fmt.Printf("  [b] Open a bash shell instead\n")
+	fmt.Printf("  [a] Attach anyway (default)\n")
+	fmt.Print("Choice [r/b/A]: ")
+	var response string
+	_, _ = fmt.Scanln(&response)
+	switch strings.ToLower(response) {
+	case "r":
+		return "relaunch"
+	case "b":
+		return "bash"
+	default:
+		return "attach"
+	}
+}
+
+// relaunchTool restarts the AI tool in an existing tmux session, resuming the
+// container's latest session with the same BuildCommand path as coi shell
+func relaunchTool(mgr *container.Manager, tmuxSessionName string, state session.TmuxPaneState, termEnv string) error {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+
+	metadata, err := session.GetLatestSessionForContainer(sessionsDir, mgr.ContainerName)
+	if err != nil {
+		return fmt.Errorf("cannot relaunch: %w - use 'coi attach %s --bash' to get a shell", err, mgr.ContainerName)
+	}
+
+	// A fresh session runs the tool with the COI session ID; a resumed one with the ID in its saved state
+	sessionStatePath := filepath.Join(sessionsDir, metadata.SessionID)
+	if configDir := toolInstance.ConfigDirName(); configDir != "" {
+		sessionStatePath = filepath.Join(sessionStatePath, configDir)
+	}
+	cliSessionID, _ := toolInstance.DiscoverSessionID(sessionStatePath)
+	if cliSessionID == "" {
+		cliSessionID = metadata.SessionID
+	}
+
+	cmd := toolInstance.BuildCommand(metadata.SessionID, true, cliSessionID)
+	if getEnvValue("COI_USE_DUMMY") == "1" && len(cmd) > 0 {
+		cmd[0] = "dummy"
+	}
+	cliCmd := container.ShellJoin(cmd)
+
+	user := container.CodeUID
+	opts := container.ExecCommandOptions{Capture: true, User: &user}
+
+	var relaunchCmd string
+	if state.Dead {
+		// Respawn with the environment and directory of the original launch
+		// (proxy, --env and passthrough vars, --cwd); older sessions did not record them
+		env := map[string]string{}
+		for k, v := range metadata.LaunchEnv {
+			env[k] = v
+		}
+		if len(env) == 0 {
+			env["HOME"] = "/home/" + container.CodeUser
+			env["IS_SANDBOX"] = "1"
+			addLocaleEnv(env)
+		}
+		env["TERM"] = termEnv
+		cwd := metadata.LaunchCwd
+		if cwd == "" {
+			cwd = session.WorkspaceMountPath(mgr)
+		}
+		relaunchCmd = session.TmuxRespawnPaneCommand(tmuxSessionName, cwd, env, cliCmd)
+	} else {
+		// The fallback shell still has the session's environment, so run the tool from it
+		relaunchCmd = container.ShellJoin([]string{"tmux", "send-keys", "-t", tmuxSessionName, cliCmd, "Enter"})
+	}
+
+	if _, err := mgr.ExecCommand(relaunchCmd, opts); err != nil {
+		return fmt.Errorf("failed to relaunch %s: %w", toolInstance.Name(), err)
+	}
+	fmt.Printf("Relaunched %s (session %s)\n", toolInstance.Name(), metadata.SessionID)
+	return nil
+}
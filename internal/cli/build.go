@@ -4,13 +4,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/health"
 	"github.com/mensfeld/code-on-incus/internal/image"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/tool"
 	"github.com/spf13/cobra"
 )
 
-var buildForce bool
+var (
+	buildForce  bool
+	buildBase   string
+	buildFormat string
+	buildQuiet  bool
+	buildTest   bool
+	buildStrict bool
+	buildLabels []string
+
+	buildFromDockerfile string
+)
 
 var buildCmd = &cobra.Command{
 	Use:   "build",
@@ -26,12 +41,52 @@ The coi image includes:
   - tmux
   - dummy (test stub for testing)
 
+The image is built on ubuntu/22.04 by default. --base selects another base
+(ubuntu 22.04 or newer, debian 12 or newer); other bases are rejected before
+the build starts.
+
+For Make/CI, --quiet suppresses progress output and --format json prints a
+single result line on stdout (success, alias, version, fingerprint, skipped,
+duration_seconds, error). The exit code is 0 on success, including when the
+image already exists (skipped), and non-zero on failure. Both flags also apply
+to 'coi build custom'.
+
+Built images carry user.coi.version, user.coi.tool and user.coi.base image
+properties (see 'coi image inspect'); --label key=value adds more.
+
+--from-dockerfile builds a custom image <alias> from an existing Dockerfile
+instead: its RUN, ENV, ARG, WORKDIR, COPY and local ADD steps are translated
+into a build script run in a container from --base (default: coi), with the
+Dockerfile's directory as the build context (paths listed in its
+.dockerignore are not pushed). This is a best-effort subset -
+FROM, USER, CMD, HEALTHCHECK, further stages and other instructions without
+an Incus equivalent are skipped with a warning.
+
+--test launches a throwaway container from the new image and checks that the
+configured tool is installed and that DNS and HTTPS work, before the alias is
+moved to it; a failed test fails the build. With --strict as well, the alias
+keeps pointing at the previous image when the test fails.
+
 Examples:
   coi build
   coi build --force
+  coi build --force --base images:ubuntu/24.04
+  coi build --base images:debian/12
+  coi build --quiet --format json
+  coi build --force --test --strict
+  coi build --force --label team=platform --label ticket=OPS-12
   coi build custom my-image --script setup.sh
+  coi build --from-dockerfile Dockerfile my-dev-image
 `,
-	Args: cobra.NoArgs,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if buildFromDockerfile != "" {
+			if len(args) != 1 {
+				return fmt.Errorf("--from-dockerfile needs the image alias to build, e.g. coi build --from-dockerfile Dockerfile my-image")
+			}
+			return nil
+		}
+		return cobra.NoArgs(cmd, args)
+	},
 	RunE: buildCommand,
 }
 
@@ -43,8 +98,13 @@ var buildCustomCmd = &cobra.Command{
 
 The build script should be a bash script that will be executed as root in the container.
 
+With --context, the given directory is pushed to /tmp/build-context and the script
+runs with it as the working directory, so it can use sibling files (config templates,
+requirements.txt, ...). The context is removed before the image is created.
+
 Examples:
   coi build custom my-rust-image --script build-rust.sh
+  coi build custom my-python-image --context ./image --script ./image/setup.sh
   coi build custom my-image --base coi --script setup.sh
   coi build custom my-image --base images:ubuntu/24.04 --script setup.sh`,
 	Args: cobra.ExactArgs(1),
@@ -53,10 +113,18 @@ Examples:
 
 func init() {
 	buildCmd.Flags().BoolVar(&buildForce, "force", false, "Force rebuild even if image exists")
+	buildCmd.Flags().StringVar(&buildBase, "base", "", "Base image for the coi image (default: "+image.BaseImage+")")
+	buildCmd.Flags().StringVar(&buildFromDockerfile, "from-dockerfile", "", "Build a custom image from this Dockerfile's RUN/ENV/COPY steps (takes the image alias as argument)")
+	buildCmd.PersistentFlags().StringVar(&buildFormat, "format", "text", "Output format: text or json (a single result line)")
+	buildCmd.PersistentFlags().BoolVar(&buildQuiet, "quiet", false, "Suppress build progress output")
+	buildCmd.PersistentFlags().BoolVar(&buildTest, "test", false, "Test the new image (tool installed, DNS and HTTPS) before using it")
+	buildCmd.PersistentFlags().BoolVar(&buildStrict, "strict", false, "With --test, keep the previous image when the test fails")
+	buildCmd.PersistentFlags().StringArrayVar(&buildLabels, "label", nil, "Image property to set (key=value, repeatable)")
 
 	// Custom build flags
 	buildCustomCmd.Flags().String("script", "", "Path to build script (required)")
 	buildCustomCmd.Flags().String("base", "", "Base image to build from (default: coi)")
+	buildCustomCmd.Flags().String("context", "", "Directory pushed to /tmp/build-context and used as the script's working directory")
 	buildCustomCmd.Flags().BoolVar(&buildForce, "force", false, "Force rebuild even if image exists")
 	_ = buildCustomCmd.MarkFlagRequired("script") // Always succeeds for valid flag names.
 
@@ -64,34 +132,53 @@ func init() {
 }
 
 func buildCommand(cmd *cobra.Command, args []string) error {
+	if buildFromDockerfile != "" {
+		return buildDockerfileCommand(args[0])
+	}
+
+	err := validateBuildFormat()
+	if err != nil {
+		return err
+	}
+	started := time.Now()
+
 	// Check if Incus is available
 	if !container.Available() {
-		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+		return buildFailed(image.CoiAlias, started, fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group"))
 	}
 
 	// Configure build options
-	opts := image.BuildOptions{
-		Force:       buildForce,
-		ImageType:   "coi",
-		BaseImage:   image.BaseImage,
-		AliasName:   image.CoiAlias,
-		Description: "coi image (Docker + build tools + Claude CLI + GitHub CLI)",
-		Logger: func(msg string) {
-			fmt.Println(msg)
-		},
+	opts := coiBuildOptions(buildForce, buildLogger(os.Stdout))
+	opts.Quiet = buildQuiet
+	if buildBase != "" {
+		opts.BaseImage = buildBase
+	}
+	if opts.Properties, err = image.ParseProperties(buildLabels); err != nil {
+		return buildFailed(opts.AliasName, started, err)
+	}
+	if err := configureBuildTest(&opts); err != nil {
+		return err
 	}
 
 	// Build the image
-	fmt.Println("Building coi image...")
+	opts.Logger(fmt.Sprintf("Building coi image from %s...", opts.BaseImage))
 	builder := image.NewBuilder(opts)
 	result := builder.Build()
 
+	if buildFormat == "json" {
+		return printBuildReport(opts.AliasName, result, started)
+	}
 	if result.Error != nil {
 		return fmt.Errorf("build failed: %w", result.Error)
 	}
 
 	if result.Skipped {
-		fmt.Printf("\nImage already exists. Use --force to rebuild.\n")
+		if !buildQuiet {
+			fmt.Printf("\nImage already exists. Use --force to rebuild.\n")
+		}
+		return nil
+	}
+	if buildQuiet {
 		return nil
 	}
 
@@ -101,44 +188,184 @@ func buildCommand(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// coiBuildOptions returns the build options for the standard coi image
+func coiBuildOptions(force bool, logger func(string)) image.BuildOptions {
+	return image.BuildOptions{
+		Force:       force,
+		ImageType:   "coi",
+		BaseImage:   image.BaseImage,
+		AliasName:   image.CoiAlias,
+		Description: "coi image (Docker + build tools + Claude CLI + GitHub CLI)",
+		CoiVersion:  Version,
+		Tool:        buildToolName(),
+		Logger:      logger,
+	}
+}
+
+// buildToolName is the configured tool, stamped on built images
+func buildToolName() string {
+	if cfg == nil || cfg.Tool.Name == "" {
+		return "claude"
+	}
+	return cfg.Tool.Name
+}
+
+// ensureImageBuilt builds the coi image inline when it is missing and auto-build
+// is enabled (--build or auto_build = true). Custom images are never built
+// here because they need a build script, so Setup reports them as missing.
+func ensureImageBuilt(imageAlias string) error {
+	if imageAlias == "" {
+		imageAlias = image.CoiAlias
+	}
+	if imageAlias != image.CoiAlias {
+		return nil
+	}
+
+	exists, err := container.ImageExists(imageAlias)
+	if err != nil {
+		return fmt.Errorf("failed to check image: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Image '%s' not found, building it now (this takes a few minutes)...\n", imageAlias)
+	result := image.NewBuilder(coiBuildOptions(false, func(msg string) {
+		fmt.Fprintln(os.Stderr, msg)
+	})).Build()
+	if result.Error != nil {
+		return fmt.Errorf("automatic image build failed: %w", result.Error)
+	}
+	fmt.Fprintf(os.Stderr, "Image '%s' built (%s)\n\n", imageAlias, result.VersionAlias)
+	return nil
+}
+
 func buildCustomCommand(cmd *cobra.Command, args []string) error {
 	imageName := args[0]
 	scriptPath, _ := cmd.Flags().GetString("script")
 	baseImage, _ := cmd.Flags().GetString("base")
+	contextDir, _ := cmd.Flags().GetString("context")
+
+	if err := validateBuildFormat(); err != nil {
+		return err
+	}
+	started := time.Now()
 
 	// Check if Incus is available
 	if !container.Available() {
-		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+		return buildFailed(imageName, started, fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group"))
 	}
 
 	// Verify script exists
 	if _, err := os.Stat(scriptPath); err != nil {
-		return fmt.Errorf("build script not found: %s", scriptPath)
+		return buildFailed(imageName, started, fmt.Errorf("build script not found: %s", scriptPath))
+	}
+
+	// Verify build context before launching anything
+	if contextDir != "" {
+		if _, err := image.ValidateBuildContext(contextDir, nil); err != nil {
+			return buildFailed(imageName, started, err)
+		}
+	}
+
+	return runCustomBuild(imageName, baseImage, scriptPath, contextDir, nil, started)
+}
+
+// buildDockerfileCommand builds a custom image from a Dockerfile translated
+// into a build script (--from-dockerfile)
+func buildDockerfileCommand(imageName string) error {
+	if err := validateBuildFormat(); err != nil {
+		return err
+	}
+	started := time.Now()
+
+	if !container.Available() {
+		return buildFailed(imageName, started, fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group"))
+	}
+
+	content, err := os.ReadFile(buildFromDockerfile)
+	if err != nil {
+		return buildFailed(imageName, started, fmt.Errorf("failed to read Dockerfile: %w", err))
+	}
+	script, warnings, err := image.TranslateDockerfile(string(content))
+	if err != nil {
+		return buildFailed(imageName, started, fmt.Errorf("failed to translate %s: %w", buildFromDockerfile, err))
+	}
+	if !buildQuiet {
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", buildFromDockerfile, warning)
+		}
+	}
+
+	scriptFile, err := os.CreateTemp("", "coi-dockerfile-*.sh")
+	if err != nil {
+		return buildFailed(imageName, started, fmt.Errorf("failed to create build script: %w", err))
 	}
+	defer os.Remove(scriptFile.Name())
+	if _, err := scriptFile.WriteString(script); err != nil {
+		scriptFile.Close()
+		return buildFailed(imageName, started, fmt.Errorf("failed to write build script: %w", err))
+	}
+	if err := scriptFile.Close(); err != nil {
+		return buildFailed(imageName, started, fmt.Errorf("failed to write build script: %w", err))
+	}
+
+	// The Dockerfile's directory is the build context, as with 'docker build .'
+	// and its .dockerignore decides what is left out
+	contextDir := filepath.Dir(buildFromDockerfile)
+	ignore, err := image.ReadDockerignore(contextDir)
+	if err != nil {
+		return buildFailed(imageName, started, err)
+	}
+	if _, err := image.ValidateBuildContext(contextDir, ignore); err != nil {
+		return buildFailed(imageName, started, err)
+	}
+
+	return runCustomBuild(imageName, buildBase, scriptFile.Name(), contextDir, ignore, started)
+}
 
+// runCustomBuild builds a custom image from a build script on baseImage
+// (default: the coi image) and reports the result. Paths of contextDir
+// matching the .dockerignore patterns in ignore are not pushed.
+func runCustomBuild(imageName, baseImage, scriptPath, contextDir string, ignore []string, started time.Time) error {
 	// Default to coi base image
 	if baseImage == "" {
 		baseImage = image.CoiAlias
 	}
 
+	properties, err := image.ParseProperties(buildLabels)
+	if err != nil {
+		return buildFailed(imageName, started, err)
+	}
+
 	// Configure build options
 	opts := image.BuildOptions{
-		ImageType:   "custom",
-		AliasName:   imageName,
-		Description: fmt.Sprintf("Custom image: %s", imageName),
-		BaseImage:   baseImage,
-		BuildScript: scriptPath,
-		Force:       buildForce,
-		Logger: func(msg string) {
-			fmt.Fprintf(os.Stderr, "%s\n", msg)
-		},
+		ImageType:     "custom",
+		AliasName:     imageName,
+		Description:   fmt.Sprintf("Custom image: %s", imageName),
+		BaseImage:     baseImage,
+		BuildScript:   scriptPath,
+		BuildContext:  contextDir,
+		ContextIgnore: ignore,
+		Force:         buildForce,
+		Quiet:         buildQuiet,
+		CoiVersion:    Version,
+		Tool:          buildToolName(),
+		Properties:    properties,
+		Logger:        buildLogger(os.Stderr),
+	}
+	if err := configureBuildTest(&opts); err != nil {
+		return err
 	}
 
 	// Build the image
-	fmt.Fprintf(os.Stderr, "Building custom image '%s' from '%s'...\n", imageName, baseImage)
+	opts.Logger(fmt.Sprintf("Building custom image '%s' from '%s'...", imageName, baseImage))
 	builder := image.NewBuilder(opts)
 	result := builder.Build()
 
+	if buildFormat == "json" {
+		return printBuildReport(imageName, result, started)
+	}
 	if result.Error != nil {
 		return fmt.Errorf("build failed: %w", result.Error)
 	}
@@ -151,7 +378,7 @@ func buildCustomCommand(cmd *cobra.Command, args []string) error {
 
 	if !result.Skipped {
 		output["fingerprint"] = result.Fingerprint
-	} else {
+	} else if !buildQuiet {
 		fmt.Fprintf(os.Stderr, "\nImage already exists. Use --force to rebuild.\n")
 	}
 
@@ -160,3 +387,109 @@ func buildCustomCommand(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// configureBuildTest sets up the --test self-test of a freshly built image
+func configureBuildTest(opts *image.BuildOptions) error {
+	if buildStrict && !buildTest {
+		return fmt.Errorf("--strict requires --test")
+	}
+	if !buildTest {
+		return nil
+	}
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+	logger := opts.Logger
+	opts.StrictTest = buildStrict
+	opts.Test = func(imageAlias string) error {
+		return testBuiltImage(imageAlias, toolInstance, logger)
+	}
+	return nil
+}
+
+// testBuiltImage checks a new image in a throwaway container: the tool must be
+// installed, and DNS plus HTTPS must work (health container_connectivity)
+func testBuiltImage(imageAlias string, t tool.Tool, logger func(string)) error {
+	containerName := fmt.Sprintf("coi-build-test-%d", time.Now().UnixNano())
+	if err := container.LaunchContainer(imageAlias, containerName); err != nil {
+		return fmt.Errorf("failed to launch test container: %w", err)
+	}
+	mgr := container.NewManager(containerName)
+	toolErr := func() error {
+		defer func() {
+			_ = mgr.Delete(true) // Best effort: the container is ephemeral anyway
+		}()
+		if err := waitForContainer(mgr, 30); err != nil {
+			return err
+		}
+		return session.ValidateTool(mgr, t, imageAlias, false)
+	}()
+	if toolErr != nil {
+		logger(fmt.Sprintf("  [FAIL] Tool: %v", toolErr))
+		return toolErr
+	}
+	logger(fmt.Sprintf("  [OK]   Tool: %s is installed", t.Binary()))
+
+	check := health.CheckContainerConnectivity(imageAlias)
+	if check.Status == health.StatusFailed {
+		logger(fmt.Sprintf("  [FAIL] Connectivity: %s", check.Message))
+		return fmt.Errorf("connectivity check failed: %s", check.Message)
+	}
+	if check.Status == health.StatusWarning {
+		logger(fmt.Sprintf("  [WARN] Connectivity: %s", check.Message))
+	} else {
+		logger(fmt.Sprintf("  [OK]   Connectivity: %s", check.Message))
+	}
+	return nil
+}
+
+// validateBuildFormat rejects unknown --format values
+func validateBuildFormat() error {
+	if buildFormat != "text" && buildFormat != "json" {
+		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", buildFormat)
+	}
+	return nil
+}
+
+// buildLogger returns the progress logger for a build: silent with --quiet,
+// and always on stderr with --format json so stdout only carries the result
+func buildLogger(out *os.File) func(string) {
+	if buildQuiet {
+		return func(string) {}
+	}
+	if buildFormat == "json" {
+		out = os.Stderr
+	}
+	return func(msg string) {
+		fmt.Fprintln(out, msg)
+	}
+}
+
+// buildFailed reports a build that failed before the builder ran
+func buildFailed(alias string, started time.Time, err error) error {
+	if buildFormat == "json" {
+		return printBuildReport(alias, &image.BuildResult{Error: err}, started)
+	}
+	return err
+}
+
+// printBuildReport prints the JSON result line and exits non-zero on failure
+func printBuildReport(alias string, result *image.BuildResult, started time.Time) error {
+	report := image.NewReport(alias, result, time.Since(started))
+	line, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode build result: %w", err)
+	}
+	fmt.Println(string(line))
+
+	if result.Error != nil {
+		// The error is already in the result line; only repeat it on stderr when not quiet
+		msg := ""
+		if !buildQuiet {
+			msg = fmt.Sprintf("build failed: %v", result.Error)
+		}
+		return exitError(1, msg)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
@@ -15,6 +16,7 @@ var (
 	cleanAll      bool
 	cleanForce    bool
 	cleanSessions bool
+	cleanCaches   bool
 )
 
 var cleanCmd = &cobra.Command{
@@ -27,6 +29,7 @@ By default, cleans only stopped containers. Use flags to control what gets clean
 Examples:
   coi clean                    # Clean stopped containers
   coi clean --sessions         # Clean saved session data
+  coi clean --caches           # Clean persistent caches (coi shell --mount-home)
   coi clean --all              # Clean everything
   coi clean --all --force      # Clean without confirmation
 `,
@@ -37,6 +40,7 @@ func init() {
 	cleanCmd.Flags().BoolVar(&cleanAll, "all", false, "Clean all containers and sessions")
 	cleanCmd.Flags().BoolVar(&cleanForce, "force", false, "Skip confirmation prompts")
 	cleanCmd.Flags().BoolVar(&cleanSessions, "sessions", false, "Clean saved session data")
+	cleanCmd.Flags().BoolVar(&cleanCaches, "caches", false, "Clean persistent ~/.cache directories of workspaces without a container (--mount-home)")
 }
 
 func cleanCommand(cmd *cobra.Command, args []string) error {
@@ -62,7 +66,7 @@ func cleanCommand(cmd *cobra.Command, args []string) error {
 	cleaned := 0
 
 	// Clean stopped containers
-	if cleanAll || (!cleanSessions) {
+	if cleanAll || (!cleanSessions && !cleanCaches) {
 		fmt.Println("Checking for stopped claude-on-incus containers...")
 
 		containers, err := listActiveContainers()
@@ -72,7 +76,7 @@ func cleanCommand(cmd *cobra.Command, args []string) error {
 
 		stoppedContainers := []string{}
 		for _, c := range containers {
-			if c.Status == "Stopped" || c.Status == "STOPPED" {
+			if isStoppedStatus(c.Status) {
 				stoppedContainers = append(stoppedContainers, c.Name)
 			}
 		}
@@ -153,6 +157,17 @@ func cleanCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Clean persistent caches
+	if cleanAll || cleanCaches {
+		fmt.Println("\nChecking for persistent caches...")
+
+		removed, err := cleanHomeCaches(baseDir)
+		if err != nil {
+			return err
+		}
+		cleaned += removed
+	}
+
 	if cleaned > 0 {
 		fmt.Printf("\n✓ Cleaned %d item(s)\n", cleaned)
 	} else {
@@ -161,3 +176,81 @@ func cleanCommand(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// cleanHomeCaches deletes the --mount-home cache directories of workspaces
+// that have no container left. Caches still mounted by any container are
+// kept, since the container would fail to start without them.
+func cleanHomeCaches(baseDir string) (int, error) {
+	cachesDir := session.HomeCachesDir(baseDir)
+	entries, err := os.ReadDir(cachesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to read caches directory: %w", err)
+	}
+
+	containers, err := listActiveContainers()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	// Containers named with --name don't carry the workspace hash, so also
+	// keep every cache some container mounts
+	mounted, err := session.MountedHomeCaches(baseDir)
+	if err != nil {
+		return 0, err
+	}
+
+	var unused []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if mounted[entry.Name()] {
+			fmt.Printf("  Keeping %s (mounted by a container)\n", entry.Name())
+			continue
+		}
+		inUse := false
+		for _, c := range containers {
+			if strings.HasPrefix(c.Name, session.GetContainerPrefix()+entry.Name()+"-") {
+				inUse = true
+				break
+			}
+		}
+		if inUse {
+			fmt.Printf("  Keeping %s (workspace has a container)\n", entry.Name())
+			continue
+		}
+		unused = append(unused, entry.Name())
+	}
+
+	if len(unused) == 0 {
+		fmt.Println("  (no unused caches found)")
+		return 0, nil
+	}
+
+	fmt.Printf("Found %d unused cache(s):\n", len(unused))
+	for _, name := range unused {
+		size, _ := session.DirSize(filepath.Join(cachesDir, name))
+		fmt.Printf("  - %s (%s)\n", name, formatBytes(size))
+	}
+
+	if !cleanForce {
+		fmt.Print("\nDelete these caches? [y/N]: ")
+		var response string
+		_, _ = fmt.Scanln(&response) // Ignore error, default to "no" if read fails
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled.")
+			return 0, nil
+		}
+	}
+
+	removed := 0
+	for _, name := range unused {
+		fmt.Printf("Deleting cache %s...\n", name)
+		if err := os.RemoveAll(filepath.Join(cachesDir, name)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to delete %s: %v\n", name, err)
+		} else {
+			removed++
+		}
+	}
+	return removed, nil
+}
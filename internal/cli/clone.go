@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/image"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var cloneKeepImage bool
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone <slot> <new-workspace>",
+	Short: "Start a new session from a copy of an existing container",
+	Long: `Duplicate a session's container environment for a different workspace.
+
+The container in <slot> of the current workspace (or --workspace) is snapshotted
+and published as a temporary image. A new session is then started for
+<new-workspace> from that image, so installed packages and tool state carry over
+while the workspace mount points at the new directory. The source container may
+be running or stopped and is left as it is.
+
+The temporary image is deleted when the new session ends unless --keep-image is given.
+
+All 'coi shell' flags that are global (--persistent, --network, --mount, limits, ...)
+apply to the new session.
+
+Examples:
+  coi clone 1 ../project-copy              # Clone slot 1 into another directory
+  coi clone 2 ~/src/experiment --keep-image
+`,
+	Args: cobra.ExactArgs(2),
+	RunE: cloneCommand,
+}
+
+func init() {
+	cloneCmd.Flags().BoolVar(&cloneKeepImage, "keep-image", false, "Keep the temporary image after the new session ends")
+}
+
+func cloneCommand(cmd *cobra.Command, args []string) error {
+	sourceSlot, err := strconv.Atoi(args[0])
+	if err != nil || sourceSlot < 1 {
+		return fmt.Errorf("invalid slot '%s' - must be a positive number", args[0])
+	}
+
+	newWorkspace, err := filepath.Abs(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid workspace path: %w", err)
+	}
+	if info, err := os.Stat(newWorkspace); err != nil || !info.IsDir() {
+		return fmt.Errorf("new workspace '%s' does not exist or is not a directory", newWorkspace)
+	}
+
+	sourceWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return fmt.Errorf("invalid workspace path: %w", err)
+	}
+	if filepath.Clean(sourceWorkspace) == filepath.Clean(newWorkspace) {
+		return fmt.Errorf("new workspace must differ from the source workspace (use 'coi shell --slot' for another session here)")
+	}
+
+	if !container.Available() {
+		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+	}
+
+	sourceName := session.ContainerName(sourceWorkspace, sourceSlot)
+	mgr := container.NewManager(sourceName)
+	exists, err := mgr.Exists()
+	if err != nil {
+		return fmt.Errorf("failed to check container: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("container '%s' (slot %d of %s) not found", sourceName, sourceSlot, sourceWorkspace)
+	}
+
+	running, err := mgr.Running()
+	if err != nil {
+		return fmt.Errorf("failed to check container state: %w", err)
+	}
+	coiDerived := cloneSourceCoiDerived(mgr, running)
+
+	timestamp := time.Now().Format("20060102-150405")
+	snapshotName := "coi-clone-" + timestamp
+	imageAlias := fmt.Sprintf("coi-clone-%s-%s", sourceName, timestamp)
+
+	fmt.Fprintf(os.Stderr, "Snapshotting %s...\n", sourceName)
+	if err := mgr.CreateSnapshot(snapshotName, false); err != nil {
+		return fmt.Errorf("failed to snapshot container: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Publishing temporary image %s...\n", imageAlias)
+	_, publishErr := container.PublishSnapshot(sourceName, snapshotName, imageAlias, fmt.Sprintf("Clone of %s", sourceName))
+
+	// The snapshot is only needed to publish the image
+	if err := mgr.DeleteSnapshot(snapshotName); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to delete snapshot %s: %v\n", snapshotName, err)
+	}
+
+	if publishErr != nil {
+		return fmt.Errorf("failed to publish clone image: %w", publishErr)
+	}
+
+	if cloneKeepImage {
+		fmt.Fprintf(os.Stderr, "Keeping image %s (delete with: coi image delete %s)\n", imageAlias, imageAlias)
+	} else {
+		// Also runs when Ctrl+C ends the session, which exits without running deferred functions
+		removeImage := sync.OnceFunc(func() {
+			fmt.Fprintf(os.Stderr, "Removing temporary image %s...\n", imageAlias)
+			if err := container.DeleteImage(imageAlias); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Failed to delete image %s: %v\n", imageAlias, err)
+			}
+		})
+		defer removeImage()
+		sessionExitHook = removeImage
+	}
+
+	// Start a normal session for the new workspace from the cloned image
+	workspace = newWorkspace
+	imageName = imageAlias
+	imageCoiDerived = coiDerived
+	slot = 0
+	fmt.Fprintf(os.Stderr, "Starting session for %s from %s...\n", newWorkspace, sourceName)
+	return shellCommand(cmd, nil)
+}
+
+// cloneSourceCoiDerived reports whether the source container runs the tool as
+// the code user, as containers from the coi image do. A running container is
+// checked for the user's home; a stopped one by the coi build properties of its
+// image, which Incus copies into the container config as image.*.
+func cloneSourceCoiDerived(mgr *container.Manager, running bool) bool {
+	if running {
+		exists, _ := mgr.DirExists("/home/" + container.CodeUser)
+		return exists
+	}
+	for _, key := range []string{"image." + image.PropertyVersion, "image." + image.PropertyTool} {
+		if value, err := container.IncusOutput("config", "get", mgr.ContainerName, key); err == nil && value != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect coi configuration",
+	Long: `Inspect coi configuration.
+
+Examples:
+  coi config schema > coi.schema.json   # Export JSON Schema for editors
+`,
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema for the config file",
+	Long: `Print a JSON Schema describing every field of config.toml / .coi.toml.
+
+Editors can use the schema for autocompletion and validation, e.g. VS Code
+with the Even Better TOML extension:
+
+  coi config schema > ~/.config/coi/config.schema.json
+
+Then add to the top of your config file:
+
+  #:schema ~/.config/coi/config.schema.json
+`,
+	Args: cobra.NoArgs,
+	RunE: configSchemaCommand,
+}
+
+func init() {
+	configCmd.AddCommand(configSchemaCmd)
+}
+
+func configSchemaCommand(cmd *cobra.Command, args []string) error {
+	data, err := json.MarshalIndent(config.GenerateSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -2,11 +2,16 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -34,6 +39,16 @@ var containerLaunchCmd = &cobra.Command{
 		}
 
 		fmt.Fprintf(os.Stderr, "Container %s launched from %s\n", name, image)
+
+		if port, _ := cmd.Flags().GetInt("wait-port"); port > 0 {
+			timeout, _ := cmd.Flags().GetDuration("wait-timeout")
+			if err := waitForPort(mgr, port, timeout); err != nil {
+				if errors.Is(err, container.ErrPortTimeout) {
+					return exitError(waitPortTimeoutExitCode, err.Error())
+				}
+				return exitError(1, err.Error())
+			}
+		}
 		return nil
 	},
 }
@@ -66,7 +81,13 @@ var containerStopCmd = &cobra.Command{
 		force, _ := cmd.Flags().GetBool("force")
 
 		mgr := container.NewManager(name)
-		if err := mgr.Stop(force); err != nil {
+		var err error
+		if force {
+			err = mgr.Stop(true)
+		} else {
+			err = mgr.StopWithTimeout(configuredStopTimeout())
+		}
+		if err != nil {
 			return exitError(1, fmt.Sprintf("failed to stop container: %v", err))
 		}
 
@@ -279,6 +300,50 @@ var containerRunningCmd = &cobra.Command{
 	},
 }
 
+// containerLogsCmd prints a container's console log
+var containerLogsCmd = &cobra.Command{
+	Use:   "logs <name>",
+	Short: "Show a container's console (boot) log",
+	Long: `Print the console log of a container (incus console --show-log). When a
+container fails to become ready, this usually shows why.
+
+Examples:
+  coi container logs coi-abc12345-1
+  coi container logs coi-abc12345-1 --tail 50`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		tail, _ := cmd.Flags().GetInt("tail")
+		if tail < 0 {
+			return exitError(2, "--tail must not be negative")
+		}
+
+		mgr := container.NewManager(name)
+		exists, err := mgr.Exists()
+		if err != nil {
+			return exitError(1, fmt.Sprintf("failed to check container: %v", err))
+		}
+		if !exists {
+			return exitError(1, fmt.Sprintf("container %s does not exist", name))
+		}
+
+		consoleLog, err := mgr.ConsoleLog()
+		if err != nil {
+			return exitError(1, fmt.Sprintf("failed to get console log: %v", err))
+		}
+		if tail > 0 {
+			consoleLog = container.TailLines(consoleLog, tail)
+		}
+		consoleLog = strings.TrimRight(consoleLog, "\n")
+		if consoleLog == "" {
+			fmt.Fprintf(os.Stderr, "Console log of %s is empty\n", name)
+			return nil
+		}
+		fmt.Println(consoleLog)
+		return nil
+	},
+}
+
 // containerMountCmd mounts a disk to a container
 var containerMountCmd = &cobra.Command{
 	Use:   "mount <name> <device-name> <source> <path>",
@@ -306,6 +371,69 @@ Example:
 	},
 }
 
+// containerRenameCmd renames a stopped container
+var containerRenameCmd = &cobra.Command{
+	Use:   "rename <name> <new-name>",
+	Short: "Rename a stopped container",
+	Long: `Rename a stopped container (incus rename) and update saved sessions and
+saved network state that refer to it, so coi list, --resume and coi network
+refresh keep working.
+
+Names in the workspace slot scheme (coi-<hash>-<slot>) are refused unless they
+belong to the same workspace as the container.
+
+Example:
+  coi container rename coi-abc12345-1 coi-refactor`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		newName := args[1]
+
+		if err := session.ValidateRenameTarget(name, newName); err != nil {
+			return exitError(2, err.Error())
+		}
+
+		mgr := container.NewManager(name)
+		if exists, err := mgr.Exists(); err != nil {
+			return exitError(1, fmt.Sprintf("failed to check container: %v", err))
+		} else if !exists {
+			return exitError(1, fmt.Sprintf("container %s not found", name))
+		}
+		if exists, err := container.NewManager(newName).Exists(); err != nil {
+			return exitError(1, fmt.Sprintf("failed to check container: %v", err))
+		} else if exists {
+			return exitError(1, fmt.Sprintf("container %s already exists", newName))
+		}
+		if running, err := mgr.Running(); err != nil {
+			return exitError(1, fmt.Sprintf("failed to check container: %v", err))
+		} else if running {
+			return exitError(1, fmt.Sprintf("container %s is running - stop it first (coi container stop %s)", name, name))
+		}
+
+		if err := mgr.Rename(newName); err != nil {
+			return exitError(1, fmt.Sprintf("failed to rename container: %v", err))
+		}
+		fmt.Fprintf(os.Stderr, "Container %s renamed to %s\n", name, newName)
+
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			updated, err := session.RenameContainerInSessions(filepath.Join(homeDir, ".coi"), name, newName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to update saved sessions: %v\n", err)
+			} else if updated > 0 {
+				fmt.Fprintf(os.Stderr, "Updated %d saved session(s)\n", updated)
+			}
+		}
+		if err := network.RenameContainer(name, newName); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to update saved network state: %v\n", err)
+		}
+
+		if !strings.HasPrefix(newName, session.GetContainerPrefix()) {
+			fmt.Fprintf(os.Stderr, "Warning: %s does not start with %s, so coi list and coi attach will not show it\n", newName, session.GetContainerPrefix())
+		}
+		return nil
+	},
+}
+
 // exitError returns an error with a specific exit code
 func exitError(code int, message string) error {
 	if message != "" {
@@ -318,6 +446,8 @@ func exitError(code int, message string) error {
 func init() {
 	// Add flags to launch command
 	containerLaunchCmd.Flags().Bool("ephemeral", false, "Create ephemeral container")
+	containerLaunchCmd.Flags().Int("wait-port", 0, "Wait until this port accepts connections inside the container")
+	containerLaunchCmd.Flags().Duration("wait-timeout", 60*time.Second, fmt.Sprintf("How long --wait-port waits before giving up (exit code %d)", waitPortTimeoutExitCode))
 
 	// Add flags to stop command
 	containerStopCmd.Flags().Bool("force", false, "Force stop")
@@ -333,6 +463,9 @@ func init() {
 	containerExecCmd.Flags().Bool("capture", false, "Capture output as JSON")
 	containerExecCmd.Flags().String("format", "json", "Output format when using --capture: json or raw")
 
+	// Add flags to logs command
+	containerLogsCmd.Flags().Int("tail", 0, "Only show the last N lines (0 = all)")
+
 	// Add flags to mount command
 	containerMountCmd.Flags().Bool("shift", true, "Enable UID/GID shifting")
 
@@ -344,5 +477,7 @@ func init() {
 	containerCmd.AddCommand(containerExecCmd)
 	containerCmd.AddCommand(containerExistsCmd)
 	containerCmd.AddCommand(containerRunningCmd)
+	containerCmd.AddCommand(containerLogsCmd)
 	containerCmd.AddCommand(containerMountCmd)
+	containerCmd.AddCommand(containerRenameCmd)
 }
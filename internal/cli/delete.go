@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/spf13/cobra"
+)
+
+var (
+	deleteAll     bool
+	deleteStopped bool
+	deleteForce   bool
+)
+
+var deleteCmd = &cobra.Command{
+	Use:   "delete [container-name...]",
+	Short: "Delete containers, saving their session data first",
+	Long: `Delete one or more containers by name, or all coi containers with --all.
+
+Session data is saved (for --resume) before each container is deleted, and its
+network isolation is torn down. Running containers are stopped first; use
+--stopped with --all to only delete containers that are already stopped.
+
+Examples:
+  coi delete coi-abc12345-1          # Delete one container
+  coi delete --all --stopped         # Delete every stopped coi container
+  coi delete --all --force           # Delete everything without confirmation
+`,
+	RunE: deleteCommand,
+}
+
+func init() {
+	deleteCmd.Flags().BoolVar(&deleteAll, "all", false, "Delete all containers")
+	deleteCmd.Flags().BoolVar(&deleteStopped, "stopped", false, "With --all, only delete stopped containers")
+	deleteCmd.Flags().BoolVar(&deleteForce, "force", false, "Skip confirmation prompts")
+}
+
+func deleteCommand(cmd *cobra.Command, args []string) error {
+	if deleteStopped && !deleteAll {
+		return fmt.Errorf("--stopped requires --all")
+	}
+
+	containerNames, err := selectBulkContainers(args, deleteAll, deleteForce, "Delete", func(c ContainerInfo) bool {
+		return !deleteStopped || isStoppedStatus(c.Status)
+	})
+	if err != nil || containerNames == nil {
+		return err
+	}
+
+	bulk, err := newBulkSessionSaver()
+	if err != nil {
+		return err
+	}
+
+	deleted := 0
+	for _, name := range containerNames {
+		fmt.Printf("Deleting container %s...\n", name)
+		mgr := container.NewManager(name)
+
+		exists, err := mgr.Exists()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to check if %s exists: %v\n", name, err)
+			continue
+		}
+		if !exists {
+			fmt.Fprintf(os.Stderr, "  Warning: Container %s does not exist\n", name)
+			continue
+		}
+
+		running, err := mgr.Running()
+		if err == nil && running {
+			if err := stopSessionContainer(mgr, configuredStopTimeout()); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+				continue
+			}
+		} else {
+			// Stopped containers are matched to their rules by their DHCP leases
+			if err := network.TeardownContainer(name); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: Failed to remove network rules of %s: %v\n", name, err)
+			}
+		}
+		bulk.save(mgr)
+
+		if err := mgr.Delete(true); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to delete %s: %v\n", name, err)
+			continue
+		}
+		deleted++
+		fmt.Printf("  ✓ Deleted %s\n", name)
+	}
+
+	if deleted > 0 {
+		fmt.Printf("\nDeleted %d container(s)\n", deleted)
+	} else {
+		fmt.Println("\nNo containers were deleted")
+		if !deleteAll {
+			// User specified containers but none were deleted - this is an error
+			return fmt.Errorf("failed to delete specified containers")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffBaseline string
+	diffPaths    []string
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [container-name]",
+	Short: "Show what changed in a container since a baseline snapshot",
+	Long: `Compare a running container's filesystem against a baseline recorded with
+'coi snapshot create <name> --baseline', e.g. right after 'coi shell --init-only'.
+
+Shows installed, removed and upgraded packages, and files added (+), removed (-)
+and modified (~, size or modification time changed) outside the workspace, to
+audit what an agent did to the container. Exits 1 when anything changed.
+
+Baselines are stored on the host (~/.coi/baselines), out of the container's
+reach. Files are compared by size and modification time only, and the listing
+is taken with the container's own find, so a root agent that resets
+timestamps or replaces find can hide changes. Use snapshots to restore a
+known state.
+
+The container is resolved from the argument, --slot, or the current workspace.
+Without --baseline the most recently recorded baseline is used.
+
+Examples:
+  coi shell --init-only && coi snapshot create base --baseline
+  coi diff
+  coi diff --slot 2 --baseline base
+  coi diff --path /usr/local --path /etc
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: diffCommand,
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffBaseline, "baseline", "", "Baseline (snapshot name) to compare against (default: most recent)")
+	diffCmd.Flags().StringSliceVar(&diffPaths, "path", nil, "Only show file changes under these paths (repeatable)")
+}
+
+func diffCommand(cmd *cobra.Command, args []string) error {
+	containerName, err := resolveWorkspaceContainer(args)
+	if err != nil {
+		return err
+	}
+
+	mgr := container.NewManager(containerName)
+	running, err := mgr.Running()
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+
+	baselineDir, err := containerBaselineDir(containerName)
+	if err != nil {
+		return err
+	}
+	baselineName := diffBaseline
+	if baselineName == "" {
+		if baselineName, err = mgr.LatestBaseline(baselineDir); err != nil {
+			return err
+		}
+	}
+
+	baseline, err := mgr.LoadBaseline(baselineDir, baselineName)
+	if err != nil {
+		return err
+	}
+
+	paths := baseline.Paths
+	if len(paths) == 0 {
+		paths = container.DefaultBaselinePaths
+	}
+	fmt.Fprintf(os.Stderr, "Listing files in %s...\n", containerName)
+	current, err := mgr.CaptureManifest(paths)
+	if err != nil {
+		return err
+	}
+
+	diff := container.DiffManifests(baseline, container.ParseManifest(current)).FilterPaths(diffPaths)
+
+	recorded := "unknown time"
+	if !baseline.Recorded.IsZero() {
+		recorded = baseline.Recorded.Local().Format("2006-01-02 15:04:05")
+	}
+	fmt.Printf("Changes in %s since baseline '%s' (recorded %s)\n", containerName, baselineName, recorded)
+
+	if diff.Empty() {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	if len(diff.PackagesInstalled)+len(diff.PackagesRemoved)+len(diff.PackagesChanged) > 0 {
+		fmt.Println("\nPackages:")
+		for _, pkg := range diff.PackagesInstalled {
+			fmt.Printf("+ %s\n", pkg)
+		}
+		for _, pkg := range diff.PackagesRemoved {
+			fmt.Printf("- %s\n", pkg)
+		}
+		for _, pkg := range diff.PackagesChanged {
+			fmt.Printf("~ %s\n", pkg)
+		}
+	}
+
+	if len(diff.Added)+len(diff.Removed)+len(diff.Modified) > 0 {
+		fmt.Println("\nFiles:")
+		for _, path := range diff.Added {
+			fmt.Printf("+ %s\n", path)
+		}
+		for _, path := range diff.Removed {
+			fmt.Printf("- %s\n", path)
+		}
+		for _, path := range diff.Modified {
+			fmt.Printf("~ %s\n", path)
+		}
+	}
+
+	fmt.Printf("\n%d files added, %d removed, %d modified; %d packages installed, %d removed, %d changed\n",
+		len(diff.Added), len(diff.Removed), len(diff.Modified),
+		len(diff.PackagesInstalled), len(diff.PackagesRemoved), len(diff.PackagesChanged))
+
+	return exitError(1, "")
+}
+
+// containerBaselineDir returns the host directory holding the baselines of containerName
+func containerBaselineDir(containerName string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return container.BaselineDir(filepath.Join(homeDir, ".coi"), containerName), nil
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -56,7 +57,7 @@ Examples:
 			if !recursive {
 				return exitError(2, "source is a directory, use -r flag")
 			}
-			if err := mgr.PushDirectory(localPath, remotePath); err != nil {
+			if err := mgr.PushDirectory(context.Background(), localPath, remotePath, nil); err != nil {
 				return exitError(1, fmt.Sprintf("failed to push directory: %v", err))
 			}
 			fmt.Fprintf(os.Stderr, "Pushed directory %s -> %s:%s\n", localPath, containerName, remotePath)
@@ -99,13 +100,13 @@ Example:
 
 		// For now, always pull recursively if -r is specified
 		if recursive {
-			if err := mgr.PullDirectory(remotePath, localPath); err != nil {
+			if err := mgr.PullDirectory(context.Background(), remotePath, localPath, nil); err != nil {
 				return exitError(1, fmt.Sprintf("failed to pull directory: %v", err))
 			}
 			fmt.Fprintf(os.Stderr, "Pulled directory %s:%s -> %s\n", containerName, remotePath, localPath)
 		} else {
 			// Pull single file - use the same PullDirectory but with single file path
-			if err := mgr.PullDirectory(remotePath, localPath); err != nil {
+			if err := mgr.PullDirectory(context.Background(), remotePath, localPath, nil); err != nil {
 				return exitError(1, fmt.Sprintf("failed to pull file: %v", err))
 			}
 			fmt.Fprintf(os.Stderr, "Pulled file %s:%s -> %s\n", containerName, remotePath, localPath)
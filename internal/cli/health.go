@@ -13,13 +13,17 @@ import (
 )
 
 var (
-	healthFormat  string
-	healthVerbose bool
+	healthFormat    string
+	healthVerbose   bool
+	healthFix       bool
+	healthFixDriver string
+	healthChecks    []string
 )
 
 var healthCmd = &cobra.Command{
-	Use:   "health",
-	Short: "Check system health and dependencies",
+	Use:     "health",
+	Aliases: []string{"doctor"},
+	Short:   "Check system health and dependencies",
 	Long: `Check all system dependencies and report their status.
 
 This helps diagnose setup issues and verify your environment is correctly configured.
@@ -28,6 +32,13 @@ Examples:
   coi health                  # Basic health check (text output)
   coi health --format json    # JSON output for scripting
   coi health --verbose        # Include additional checks
+  coi health --fix            # Create the default profile's storage pool if it is missing
+  coi health --check incus    # Run a single check
+  coi health --check permissions --check image
+  coi doctor                  # Same as coi health
+
+With --check only the named checks run (including ones normally skipped, such
+as dns_resolution), and the exit code reflects just those checks.
 
 Exit codes:
   0 = healthy (all checks pass)
@@ -40,6 +51,9 @@ Exit codes:
 func init() {
 	healthCmd.Flags().StringVar(&healthFormat, "format", "text", "Output format: text or json")
 	healthCmd.Flags().BoolVarP(&healthVerbose, "verbose", "v", false, "Include additional verbose checks")
+	healthCmd.Flags().BoolVar(&healthFix, "fix", false, "Fix what can be fixed automatically (creates the default profile's storage pool if missing)")
+	healthCmd.Flags().StringVar(&healthFixDriver, "fix-storage-driver", "dir", "Storage driver for the pool created by --fix: dir or btrfs")
+	healthCmd.Flags().StringSliceVar(&healthChecks, "check", nil, "Run only the named check (repeatable or comma-separated, e.g. incus,network_bridge)")
 }
 
 func healthCommand(cmd *cobra.Command, args []string) error {
@@ -55,8 +69,32 @@ func healthCommand(cmd *cobra.Command, args []string) error {
 		cfg = config.GetDefaultConfig()
 	}
 
-	// Run all health checks
-	result := health.RunAllChecks(cfg, healthVerbose)
+	// Run all health checks, or just the ones asked for
+	runChecks := func() (*health.HealthResult, error) {
+		if len(healthChecks) > 0 {
+			return health.RunChecks(cfg, healthChecks)
+		}
+		return health.RunAllChecks(cfg, healthVerbose), nil
+	}
+	result, err := runChecks()
+	if err != nil {
+		return err
+	}
+
+	// Apply automatic fixes, then re-check so the report reflects the result
+	if healthFix {
+		if check, ok := result.Checks["storage_pool"]; ok && check.Status == health.StatusFailed {
+			fmt.Fprintf(os.Stderr, "Fixing storage pool (%s driver)...\n", healthFixDriver)
+			if pool, err := health.FixStoragePool(healthFixDriver); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: Could not fix storage pool: %v\n", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "Storage pool '%s' is ready\n", pool)
+			}
+			if result, err = runChecks(); err != nil {
+				return err
+			}
+		}
+	}
 
 	// Output based on format
 	if healthFormat == "json" {
@@ -89,11 +127,11 @@ func outputHealthText(result *health.HealthResult) error {
 	// Group checks by category
 	categories := map[string][]string{
 		"SYSTEM":        {"os"},
-		"CRITICAL":      {"incus", "permissions", "image", "image_age"},
-		"NETWORKING":    {"network_bridge", "ip_forwarding", "firewall"},
+		"CRITICAL":      {"incus", "incus_access", "permissions", "storage_pool", "image", "image_age"},
+		"NETWORKING":    {"network_bridge", "ip_forwarding", "firewall", "network_mode_connectivity"},
 		"STORAGE":       {"coi_directory", "sessions_directory", "disk_space"},
 		"CONFIGURATION": {"config", "network_mode", "tool"},
-		"STATUS":        {"active_containers", "saved_sessions"},
+		"STATUS":        {"active_containers", "saved_sessions", "clock_drift"},
 		"OPTIONAL":      {"dns_resolution", "passwordless_sudo"},
 	}
 
@@ -202,24 +240,28 @@ func outputHealthText(result *health.HealthResult) error {
 func formatCheckName(name string) string {
 	// Special cases for better display
 	specialCases := map[string]string{
-		"os":                 "Operating system",
-		"incus":              "Incus",
-		"permissions":        "Permissions",
-		"image":              "Default image",
-		"image_age":          "Image age",
-		"network_bridge":     "Network bridge",
-		"ip_forwarding":      "IP forwarding",
-		"firewall":           "Firewalld",
-		"coi_directory":      "COI directory",
-		"sessions_directory": "Sessions dir",
-		"disk_space":         "Disk space",
-		"config":             "Config loaded",
-		"network_mode":       "Network mode",
-		"tool":               "Tool",
-		"active_containers":  "Containers",
-		"saved_sessions":     "Saved sessions",
-		"dns_resolution":     "DNS resolution",
-		"passwordless_sudo":  "Passwordless sudo",
+		"os":                        "Operating system",
+		"incus":                     "Incus",
+		"incus_access":              "Incus access",
+		"permissions":               "Permissions",
+		"storage_pool":              "Storage pool",
+		"image":                     "Default image",
+		"image_age":                 "Image age",
+		"network_bridge":            "Network bridge",
+		"ip_forwarding":             "IP forwarding",
+		"firewall":                  "Firewalld",
+		"network_mode_connectivity": "Mode connectivity",
+		"coi_directory":             "COI directory",
+		"sessions_directory":        "Sessions dir",
+		"disk_space":                "Disk space",
+		"config":                    "Config loaded",
+		"network_mode":              "Network mode",
+		"tool":                      "Tool",
+		"active_containers":         "Containers",
+		"saved_sessions":            "Saved sessions",
+		"clock_drift":               "Clock drift",
+		"dns_resolution":            "DNS resolution",
+		"passwordless_sudo":         "Passwordless sudo",
 	}
 
 	if displayName, ok := specialCases[name]; ok {
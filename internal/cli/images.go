@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/image"
@@ -120,44 +123,224 @@ var imageExistsCmd = &cobra.Command{
 	},
 }
 
+// imageInspectCmd shows an image's properties
+var imageInspectCmd = &cobra.Command{
+	Use:   "inspect <alias>",
+	Short: "Show an image's properties",
+	Long: `Show the properties of an image, including the user.coi.version,
+user.coi.tool and user.coi.base properties stamped by coi build and any
+--label properties.
+
+Examples:
+  coi image inspect coi
+  coi image inspect coi --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		if format != "text" && format != "json" {
+			return exitError(2, fmt.Sprintf("invalid format '%s' - must be 'text' or 'json'", format))
+		}
+
+		properties, err := image.Properties(args[0])
+		if err != nil {
+			return exitError(1, fmt.Sprintf("failed to inspect image: %v", err))
+		}
+
+		if format == "json" {
+			jsonOutput, _ := json.MarshalIndent(properties, "", "  ")
+			fmt.Println(string(jsonOutput))
+			return nil
+		}
+
+		keys := make([]string, 0, len(properties))
+		for key := range properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("%-20s %s\n", key+":", properties[key])
+		}
+		return nil
+	},
+}
+
 // imageCleanupCmd cleans up old image versions
 var imageCleanupCmd = &cobra.Command{
 	Use:   "cleanup <prefix>",
-	Short: "Delete old image versions, keeping only N most recent",
-	Long: `Delete old versions of images matching a prefix, keeping only the N most recent.
+	Short: "Delete old image versions by count or age",
+	Long: `Delete old versions of images matching a prefix.
+
+--keep N keeps only the N most recent versions. --older-than deletes versions
+whose alias timestamp is older than the given age (e.g. 72h, 14d) regardless of
+count. With both, a version is deleted if either selects it. Versions whose
+alias has no timestamp are never deleted for their age. The newest version and
+images that still carry an unversioned alias (e.g. the one sessions launch
+from) are always kept.
 
 Image aliases must follow format: prefix-YYYYMMDD-HHMMSS
 
-Example:
+Examples:
   # Keep only the 3 most recent versions of node-42 images
-  coi image cleanup claudeyard-node-42- --keep 3`,
+  coi image cleanup claudeyard-node-42- --keep 3
+
+  # Preview deleting CI images older than two weeks
+  coi image cleanup ci-build- --older-than 14d --dry-run`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		prefix := args[0]
 		keepCount, _ := cmd.Flags().GetInt("keep")
+		olderThanValue, _ := cmd.Flags().GetString("older-than")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-		if keepCount <= 0 {
+		if !cmd.Flags().Changed("keep") && olderThanValue == "" {
+			return exitError(2, "--keep or --older-than is required")
+		}
+		if cmd.Flags().Changed("keep") && keepCount <= 0 {
 			return exitError(2, "--keep must be > 0")
 		}
 
-		deleted, kept, err := image.Cleanup(prefix, keepCount)
+		var olderThan time.Duration
+		if olderThanValue != "" {
+			var err error
+			olderThan, err = image.ParseAge(olderThanValue)
+			if err != nil {
+				return exitError(2, err.Error())
+			}
+		}
+
+		result, err := image.Cleanup(prefix, image.CleanupOptions{
+			KeepCount: keepCount,
+			OlderThan: olderThan,
+			DryRun:    dryRun,
+		})
 		if err != nil {
+			if result != nil && len(result.Deleted) > 0 {
+				fmt.Fprintf(os.Stderr, "Deleted before the failure: %s\n", strings.Join(result.Deleted, ", "))
+			}
 			return exitError(1, fmt.Sprintf("cleanup failed: %v", err))
 		}
 
-		fmt.Fprintf(os.Stderr, "Cleanup complete:\n")
-		if len(deleted) > 0 {
-			fmt.Fprintf(os.Stderr, "\nDeleted %d old version(s):\n", len(deleted))
-			for _, alias := range deleted {
+		deletedLabel := "Deleted"
+		if dryRun {
+			fmt.Fprintf(os.Stderr, "Dry run - no images deleted:\n")
+			deletedLabel = "Would delete"
+		} else {
+			fmt.Fprintf(os.Stderr, "Cleanup complete:\n")
+		}
+		if len(result.Deleted) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%s %d old version(s):\n", deletedLabel, len(result.Deleted))
+			for _, alias := range result.Deleted {
 				fmt.Fprintf(os.Stderr, "  - %s\n", alias)
 			}
 		}
-		if len(kept) > 0 {
-			fmt.Fprintf(os.Stderr, "\nKept %d recent version(s):\n", len(kept))
-			for _, alias := range kept {
+		if len(result.Kept) > 0 {
+			fmt.Fprintf(os.Stderr, "\nKept %d recent version(s):\n", len(result.Kept))
+			for _, alias := range result.Kept {
 				fmt.Fprintf(os.Stderr, "  - %s\n", alias)
 			}
 		}
+		if len(result.Deleted) > 0 {
+			reclaimedLabel := "Reclaimed"
+			if dryRun {
+				reclaimedLabel = "Would reclaim"
+			}
+			fmt.Fprintf(os.Stderr, "\n%s %s\n", reclaimedLabel, formatBytes(result.ReclaimedBytes))
+		}
+
+		return nil
+	},
+}
+
+// imageExportCmd exports an image to a tarball
+var imageExportCmd = &cobra.Command{
+	Use:   "export <alias> <file>",
+	Short: "Export an image to a tarball for offline distribution",
+	Long: `Export an image to a tarball with 'incus image export', e.g. to carry it to
+an air-gapped machine. Incus adds the file extension (<file>.tar.gz); split
+images produce a separate rootfs file next to it.
+
+Example:
+  coi image export coi /media/usb/coi-image
+  coi image import /media/usb/coi-image.tar.gz --alias coi   # on the other machine`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aliasName := args[0]
+
+		target, err := filepath.Abs(args[1])
+		if err != nil {
+			return exitError(1, fmt.Sprintf("invalid target path: %v", err))
+		}
+
+		exists, err := container.ImageExists(aliasName)
+		if err != nil {
+			return exitError(1, fmt.Sprintf("failed to check image: %v", err))
+		}
+		if !exists {
+			return exitError(1, fmt.Sprintf("image '%s' not found", aliasName))
+		}
+
+		fmt.Fprintf(os.Stderr, "Exporting image %s...\n", aliasName)
+		if err := container.ExportImage(aliasName, target); err != nil {
+			return exitError(1, fmt.Sprintf("failed to export image: %v", err))
+		}
+
+		fmt.Fprintf(os.Stderr, "Image %s exported to %s\n", aliasName, target)
+		return nil
+	},
+}
+
+// imageImportCmd imports an image tarball
+var imageImportCmd = &cobra.Command{
+	Use:   "import <file> [rootfs-file]",
+	Short: "Import an image tarball (from coi image export)",
+	Long: `Import an image tarball created by 'coi image export' or 'incus image export'.
+Pass the rootfs file as a second argument for split images.
+
+With --alias the alias is pointed at the imported image, replacing any existing
+alias of that name (use --alias coi to make it the default image).
+
+Example:
+  coi image import coi-image.tar.gz --alias coi`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aliasName, _ := cmd.Flags().GetString("alias")
+
+		var files []string
+		for _, arg := range args {
+			path, err := filepath.Abs(arg)
+			if err != nil {
+				return exitError(1, fmt.Sprintf("invalid path %s: %v", arg, err))
+			}
+			if _, err := os.Stat(path); err != nil {
+				return exitError(1, fmt.Sprintf("image file not found: %s", arg))
+			}
+			files = append(files, path)
+		}
+
+		rootfs := ""
+		if len(files) == 2 {
+			rootfs = files[1]
+		}
+
+		fmt.Fprintf(os.Stderr, "Importing image from %s...\n", args[0])
+		fingerprint, err := container.ImportImage(files[0], rootfs)
+		if err != nil {
+			return exitError(1, fmt.Sprintf("failed to import image: %v", err))
+		}
+
+		if aliasName != "" {
+			if err := image.SetAlias(aliasName, fingerprint); err != nil {
+				return exitError(1, fmt.Sprintf("image imported as %s but %v", fingerprint, err))
+			}
+		}
+
+		// Output as JSON (same shape as publish)
+		result := map[string]string{
+			"fingerprint": fingerprint,
+			"alias":       aliasName,
+		}
+		jsonOutput, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(jsonOutput))
 
 		return nil
 	},
@@ -175,16 +358,26 @@ func init() {
 	// Add flags to publish command
 	imagePublishCmd.Flags().String("description", "", "Image description")
 
+	// Add flags to inspect command
+	imageInspectCmd.Flags().String("format", "text", "Output format: text or json")
+
+	// Add flags to import command
+	imageImportCmd.Flags().String("alias", "", "Point this alias at the imported image (e.g. coi)")
+
 	// Add flags to cleanup command
-	imageCleanupCmd.Flags().Int("keep", 0, "Number of versions to keep (required)")
-	_ = imageCleanupCmd.MarkFlagRequired("keep") // Always succeeds for valid flag names.
+	imageCleanupCmd.Flags().Int("keep", 0, "Number of most recent versions to keep")
+	imageCleanupCmd.Flags().String("older-than", "", "Delete versions older than this age (e.g. 72h, 14d)")
+	imageCleanupCmd.Flags().Bool("dry-run", false, "Show what would be deleted without deleting")
 
 	// Add subcommands to image command
 	imageCmd.AddCommand(imageListCmd)
 	imageCmd.AddCommand(imagePublishCmd)
 	imageCmd.AddCommand(imageDeleteCmd)
 	imageCmd.AddCommand(imageExistsCmd)
+	imageCmd.AddCommand(imageInspectCmd)
 	imageCmd.AddCommand(imageCleanupCmd)
+	imageCmd.AddCommand(imageExportCmd)
+	imageCmd.AddCommand(imageImportCmd)
 }
 
 func imageListCommand(cmd *cobra.Command, args []string) error {
@@ -293,10 +486,7 @@ func imageListCommand(cmd *cobra.Command, args []string) error {
 
 // listAllImages lists all local Incus images
 func listAllImages() error {
-	mgr := container.NewManager("temp")
-
-	// Get list of images using incus image list with sg wrapper
-	output, err := mgr.ExecHostCommand("sg incus-admin -c 'incus image list --format=csv -c l,s,u'", true)
+	output, err := container.IncusOutput("image", "list", "--format=csv", "-c", "l,s,u")
 	if err != nil {
 		return fmt.Errorf("failed to list images: %w", err)
 	}
@@ -6,28 +6,62 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/spf13/cobra"
 )
 
+var infoFormat string
+
 var infoCmd = &cobra.Command{
 	Use:   "info [SESSION_ID]",
 	Short: "Show detailed information about a session",
-	Long: `Show detailed information about a saved session.
+	Long: `Show detailed information about a saved session (the latest one when no ID
+is given): tool, workspace, save time, persistent flag, network mode, the
+command the tool was last launched with (shows whether it resumed), size on
+disk, transcript files and the tool's own session ID (used by --resume).
 
 Examples:
   coi info abc123
   coi info
+  coi info abc123 --format json
 `,
 	Args: cobra.MaximumNArgs(1),
 	RunE: infoCommand,
 }
 
+var sessionCmd = &cobra.Command{
+	Use:   "session",
+	Short: "Inspect saved sessions",
+	Long: `Inspect saved sessions (see 'coi list --all' for their IDs).
+
+Examples:
+  coi session info abc123
+  coi session info abc123 --format json
+`,
+}
+
+var sessionInfoCmd = &cobra.Command{
+	Use:   "info SESSION_ID",
+	Short: "Show everything known about a saved session",
+	Long: `Show a saved session's metadata and saved tool state (same as 'coi info').
+
+Examples:
+  coi session info abc123
+  coi session info abc123 --format json
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: infoCommand,
+}
+
+func init() {
+	infoCmd.Flags().StringVar(&infoFormat, "format", "text", "Output format: text or json")
+	sessionInfoCmd.Flags().StringVar(&infoFormat, "format", "text", "Output format: text or json")
+	sessionCmd.AddCommand(sessionInfoCmd)
+}
+
 func infoCommand(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+	if infoFormat != "text" && infoFormat != "json" {
+		return fmt.Errorf("invalid format '%s' - must be 'text' or 'json'", infoFormat)
 	}
 
 	// Get configured tool to determine tool-specific sessions directory
@@ -35,14 +69,11 @@ func infoCommand(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-
-	// Get tool-specific sessions directory
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return fmt.Errorf("failed to get home directory: %w", err)
 	}
-	baseDir := filepath.Join(homeDir, ".coi")
-	sessionsDir := session.GetSessionsDir(baseDir, toolInstance)
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
 
 	// Get session ID
 	var sessionID string
@@ -56,80 +87,71 @@ func infoCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Check if session exists
-	sessionDir := filepath.Join(sessionsDir, sessionID)
-	if _, err := os.Stat(sessionDir); os.IsNotExist(err) {
-		return fmt.Errorf("session not found: %s", sessionID)
+	report, err := session.BuildReport(sessionsDir, sessionID, toolInstance)
+	if err != nil {
+		return err
+	}
+	for _, warning := range report.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
 	}
 
-	// Read metadata
-	metadataPath := filepath.Join(sessionDir, "metadata.json")
-	var metadata session.SessionMetadata
-
-	if data, err := os.ReadFile(metadataPath); err == nil {
-		if err := json.Unmarshal(data, &metadata); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Could not parse metadata: %v\n", err)
+	if infoFormat == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal session info: %w", err)
 		}
-	} else {
-		fmt.Fprintf(os.Stderr, "Warning: No metadata found\n")
+		fmt.Println(string(data))
+		return nil
 	}
 
-	// Check if .claude directory exists
-	statePath := filepath.Join(sessionDir, ".claude")
-	claudeExists := false
-	if info, err := os.Stat(statePath); err == nil && info.IsDir() {
-		claudeExists = true
+	printSessionReport(report)
+	return nil
+}
+
+// printSessionReport prints a session report as text
+func printSessionReport(report *session.Report) {
+	valueOr := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
 	}
 
-	// Display information
 	fmt.Printf("Session Information\n")
 	fmt.Printf("===================\n\n")
-	fmt.Printf("Session ID:     %s\n", sessionID)
-
-	if metadata.ContainerName != "" {
-		fmt.Printf("Container:      %s\n", metadata.ContainerName)
+	fmt.Printf("Session ID:       %s\n", report.ID)
+	fmt.Printf("Tool:             %s\n", report.Tool)
+	fmt.Printf("Workspace:        %s\n", valueOr(report.Workspace, "unknown"))
+	fmt.Printf("Container:        %s\n", valueOr(report.ContainerName, "unknown"))
+	fmt.Printf("Saved At:         %s\n", valueOr(report.SavedAt, "unknown"))
+	fmt.Printf("Persistent:       %t\n", report.Persistent)
+	if report.NetworkMode != "" {
+		fmt.Printf("Network:          %s\n", report.NetworkMode)
+		for _, domain := range report.AllowedDomains {
+			fmt.Printf("                  - %s\n", domain)
+		}
 	}
-
-	if metadata.SavedAt != "" {
-		fmt.Printf("Saved At:       %s\n", metadata.SavedAt)
+	if report.LaunchCommand != "" {
+		fmt.Printf("Launch Command:   %s\n", report.LaunchCommand)
 	}
-
-	fmt.Printf("Session Data:   ")
-	if claudeExists {
-		fmt.Printf("✓ Present (.claude directory)\n")
+	if report.LaunchCwd != "" {
+		fmt.Printf("Launch Directory: %s\n", report.LaunchCwd)
+	}
+	if report.HomeCacheDir != "" {
+		fmt.Printf("Cache Directory:  %s\n", report.HomeCacheDir)
+	}
+	fmt.Printf("Size on Disk:     %s\n", formatBytes(report.SizeBytes))
+	if report.HasState {
+		fmt.Printf("Transcripts:      %d\n", report.TranscriptFiles)
+		fmt.Printf("Tool Session ID:  %s\n", valueOr(report.ToolSessionID, "not found"))
 	} else {
-		fmt.Printf("✗ Missing\n")
+		fmt.Printf("Tool State:       missing (nothing to resume)\n")
 	}
+	fmt.Printf("Session Path:     %s\n", report.Path)
 
-	// Show directory size
-	if claudeExists {
-		size, err := getDirSize(statePath)
-		if err == nil {
-			fmt.Printf("Data Size:      %s\n", formatBytes(size))
-		}
+	if report.ResumeCommand != "" {
+		fmt.Printf("\nResume:           %s\n", report.ResumeCommand)
 	}
-
-	fmt.Printf("\nSession Path:   %s\n", sessionDir)
-
-	// Show resumability
-	fmt.Printf("\nResume:         coi shell --resume %s\n", sessionID)
-
-	return nil
-}
-
-// getDirSize calculates the total size of a directory
-func getDirSize(path string) (int64, error) {
-	var size int64
-	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			size += info.Size()
-		}
-		return nil
-	})
-	return size, err
 }
 
 // formatBytes formats bytes into human-readable string
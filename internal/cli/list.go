@@ -3,6 +3,7 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"time"
@@ -15,8 +16,13 @@ import (
 )
 
 var (
-	listAll    bool
-	listFormat string
+	listAll      bool
+	listFormat   string
+	listLabels   []string
+	listRunning  bool
+	listStopped  bool
+	listTool     string
+	listAllTools bool
 )
 
 var listCmd = &cobra.Command{
@@ -25,10 +31,20 @@ var listCmd = &cobra.Command{
 	Long: `List active claude-on-incus containers and saved sessions.
 
 By default, shows only active containers. Use --all to also show saved sessions.
+Use --label key=value (repeatable) to only show containers with matching labels.
+Use --running or --stopped to only show containers in that state.
+
+Saved sessions are kept per tool (~/.coi/sessions-<tool>). --all shows the
+configured tool's sessions; add --all-tools to show every tool's sessions, or
+--tool NAME for one tool's sessions.
 
 Examples:
   coi list
   coi list --all
+  coi list --all --all-tools
+  coi list --all --tool aider
+  coi list --stopped
+  coi list --label task=refactor
 `,
 	RunE: listCommand,
 }
@@ -36,6 +52,13 @@ Examples:
 func init() {
 	listCmd.Flags().BoolVar(&listAll, "all", false, "Show saved sessions in addition to active containers")
 	listCmd.Flags().StringVar(&listFormat, "format", "text", "Output format: text or json")
+	listCmd.Flags().StringArrayVar(&listLabels, "label", []string{}, "Only show containers with this label (key=value, repeatable)")
+	listCmd.Flags().BoolVar(&listRunning, "running", false, "Only show running containers")
+	listCmd.Flags().BoolVar(&listStopped, "stopped", false, "Only show stopped containers")
+	listCmd.Flags().StringVar(&listTool, "tool", "", "Show saved sessions of this tool instead of the configured one")
+	listCmd.Flags().BoolVar(&listAllTools, "all-tools", false, "Show saved sessions of every tool, not just the configured one")
+	listCmd.MarkFlagsMutuallyExclusive("running", "stopped")
+	listCmd.MarkFlagsMutuallyExclusive("tool", "all-tools")
 }
 
 func listCommand(cmd *cobra.Command, args []string) error {
@@ -49,6 +72,14 @@ func listCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid format '%s': must be 'text' or 'json'", listFormat)
 	}
 
+	labelFilter, err := session.ParseLabels(listLabels)
+	if err != nil {
+		return err
+	}
+
+	// Don't list stopped containers whose grace period has already passed
+	deleteExpiredContainers()
+
 	// Get configured tool to determine tool-specific sessions directory
 	toolInstance, err := getConfiguredTool(cfg)
 	if err != nil {
@@ -61,41 +92,39 @@ func listCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get home directory: %w", err)
 	}
 	baseDir := filepath.Join(homeDir, ".coi")
-	sessionsDir := session.GetSessionsDir(baseDir, toolInstance)
+	toolDirs, err := listSessionsDirs(baseDir, toolInstance)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions directories: %w", err)
+	}
 
 	// List active containers
 	containers, err := listActiveContainers()
 	if err != nil {
 		return fmt.Errorf("failed to list containers: %w", err)
 	}
+	containers = filterByLabels(containers, labelFilter)
+	containers = filterByStatus(containers, listRunning, listStopped)
 
 	// Build maps of container name -> workspace and container name -> persistent from saved sessions
-	// We search for metadata.json files directly (not using listSavedSessions which requires .claude dir)
-	// because metadata is saved early at session start, before .claude directory exists
 	containerWorkspaces := make(map[string]string)
 	containerPersistent := make(map[string]bool)
-	if entries, err := os.ReadDir(sessionsDir); err == nil {
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
-			metadataPath := filepath.Join(sessionsDir, entry.Name(), "metadata.json")
-			if data, err := os.ReadFile(metadataPath); err == nil {
-				var metadata session.SessionMetadata
-				if err := json.Unmarshal(data, &metadata); err == nil && metadata.ContainerName != "" {
-					containerWorkspaces[metadata.ContainerName] = metadata.Workspace
-					containerPersistent[metadata.ContainerName] = metadata.Persistent
-				}
-			}
-		}
+	for _, dir := range toolDirs {
+		workspaces, persistent := loadContainerMetadata(dir.Dir)
+		maps.Copy(containerWorkspaces, workspaces)
+		maps.Copy(containerPersistent, persistent)
 	}
 
 	// Get saved sessions if --all
 	var sessions []SessionInfo
 	if listAll {
-		sessions, err = listSavedSessions(sessionsDir, toolInstance)
-		if err != nil {
-			return fmt.Errorf("failed to list sessions: %w", err)
+		// Initialize as empty slice instead of nil so the section always appears with --all
+		sessions = []SessionInfo{}
+		for _, dir := range toolDirs {
+			toolSessions, err := listToolSessions(dir)
+			if err != nil {
+				return fmt.Errorf("failed to list sessions: %w", err)
+			}
+			sessions = append(sessions, toolSessions...)
 		}
 	}
 
@@ -114,6 +143,8 @@ type ContainerInfo struct {
 	CreatedAt string
 	Image     string
 	IPv4      string
+	Labels    map[string]string
+	Network   *container.NetworkCounters // Since the container started; nil if stopped
 }
 
 // SessionInfo holds information about a saved session
@@ -121,6 +152,54 @@ type SessionInfo struct {
 	ID        string
 	SavedAt   string
 	Workspace string
+	Tool      string
+}
+
+// listSessionsDirs returns the sessions directories coi list reads: the
+// configured tool's, the one picked with --tool, or every tool's with --all-tools
+func listSessionsDirs(baseDir string, toolInstance tool.Tool) ([]session.ToolSessionsDir, error) {
+	switch {
+	case listAllTools:
+		return session.ListToolSessionsDirs(baseDir)
+	case listTool != "":
+		return []session.ToolSessionsDir{{Tool: listTool, Dir: filepath.Join(baseDir, "sessions-"+listTool)}}, nil
+	default:
+		return []session.ToolSessionsDir{{Tool: toolInstance.Name(), Dir: session.GetSessionsDir(baseDir, toolInstance)}}, nil
+	}
+}
+
+// listToolSessions lists the saved sessions of one tool. Tools this version
+// of coi does not support are listed by their metadata alone.
+func listToolSessions(dir session.ToolSessionsDir) ([]SessionInfo, error) {
+	configDirName := ""
+	if t, err := tool.Get(dir.Tool); err == nil {
+		configDirName = t.ConfigDirName()
+	}
+	return listSessionsIn(dir.Dir, configDirName, dir.Tool)
+}
+
+// loadContainerMetadata maps container names to their workspace and persistent flag.
+// We search for metadata.json files directly (not using listSavedSessions which requires .claude dir)
+// because metadata is saved early at session start, before .claude directory exists
+func loadContainerMetadata(sessionsDir string) (map[string]string, map[string]bool) {
+	containerWorkspaces := make(map[string]string)
+	containerPersistent := make(map[string]bool)
+	if entries, err := os.ReadDir(sessionsDir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			metadataPath := filepath.Join(sessionsDir, entry.Name(), "metadata.json")
+			if data, err := os.ReadFile(metadataPath); err == nil {
+				var metadata session.SessionMetadata
+				if err := json.Unmarshal(data, &metadata); err == nil && metadata.ContainerName != "" {
+					containerWorkspaces[metadata.ContainerName] = metadata.Workspace
+					containerPersistent[metadata.ContainerName] = metadata.Persistent
+				}
+			}
+		}
+	}
+	return containerWorkspaces, containerPersistent
 }
 
 // listActiveContainers lists all active claude-on-incus containers
@@ -139,6 +218,9 @@ func listActiveContainers() ([]ContainerInfo, error) {
 		return nil, err
 	}
 
+	// Counters are informational, so a parse problem only leaves them out
+	counters, _ := container.ParseNetworkCounters(output)
+
 	var result []ContainerInfo
 	for _, c := range containers {
 		name, _ := c["name"].(string)            // Type assertion, default to "" if fails
@@ -158,20 +240,60 @@ func listActiveContainers() ([]ContainerInfo, error) {
 		// Extract IPv4 address from eth0 interface
 		ipv4 := extractEth0IPv4(c)
 
-		result = append(result, ContainerInfo{
+		info := ContainerInfo{
 			Name:      name,
 			Status:    status,
 			CreatedAt: createdTime,
 			Image:     image,
 			IPv4:      ipv4,
-		})
+			Labels:    session.LabelsFromConfig(config),
+		}
+		if c, ok := counters[name]; ok {
+			info.Network = &c
+		}
+		result = append(result, info)
 	}
 
 	return result, nil
 }
 
+// filterByLabels keeps only containers whose labels match every pair in filter
+func filterByLabels(containers []ContainerInfo, filter map[string]string) []ContainerInfo {
+	if len(filter) == 0 {
+		return containers
+	}
+	var result []ContainerInfo
+	for _, c := range containers {
+		if session.MatchLabels(c.Labels, filter) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// filterByStatus keeps only running or only stopped containers when requested
+func filterByStatus(containers []ContainerInfo, running, stopped bool) []ContainerInfo {
+	if !running && !stopped {
+		return containers
+	}
+	var result []ContainerInfo
+	for _, c := range containers {
+		if (running && isRunningStatus(c.Status)) || (stopped && isStoppedStatus(c.Status)) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
 // listSavedSessions lists all saved sessions
 func listSavedSessions(sessionsDir string, toolInstance tool.Tool) ([]SessionInfo, error) {
+	return listSessionsIn(sessionsDir, toolInstance.ConfigDirName(), toolInstance.Name())
+}
+
+// listSessionsIn lists the saved sessions in a tool's sessions directory. A
+// session counts when it holds the tool's config directory (configDirName),
+// or its metadata for tools without one.
+func listSessionsIn(sessionsDir, configDirName, toolName string) ([]SessionInfo, error) {
 	entries, err := os.ReadDir(sessionsDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -189,21 +311,10 @@ func listSavedSessions(sessionsDir string, toolInstance tool.Tool) ([]SessionInf
 
 		sessionID := entry.Name()
 
-		// Check if it has the tool's config directory (e.g., .claude, .aider, .cursor)
-		// Skip if tool uses ENV-based auth (ConfigDirName returns "")
-		configDirName := toolInstance.ConfigDirName()
-		if configDirName == "" {
-			// For ENV-based tools, only check if metadata.json exists
-			metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
-			if _, err := os.Stat(metadataPath); err != nil {
-				continue
-			}
-		} else {
-			// For config-based tools, check if config directory exists
-			statePath := filepath.Join(sessionsDir, sessionID, configDirName)
-			if info, err := os.Stat(statePath); err != nil || !info.IsDir() {
-				continue
-			}
+		// Same definition of a saved session as coi health and resume: the
+		// tool's config directory, or metadata for ENV-based tools
+		if !session.SessionExists(sessionsDir, sessionID, configDirName) {
+			continue
 		}
 
 		// Try to read metadata
@@ -230,6 +341,7 @@ func listSavedSessions(sessionsDir string, toolInstance tool.Tool) ([]SessionInf
 			ID:        sessionID,
 			SavedAt:   savedAt,
 			Workspace: workspace,
+			Tool:      toolName,
 		})
 	}
 
@@ -293,10 +405,14 @@ func outputJSON(containers []ContainerInfo, sessions []SessionInfo,
 			"image":      c.Image,
 			"persistent": persistent[c.Name],
 			"ipv4":       c.IPv4,
+			"labels":     c.Labels,
 		}
 		if ws, ok := workspaces[c.Name]; ok {
 			item["workspace"] = ws
 		}
+		if c.Network != nil {
+			item["network"] = c.Network
+		}
 		enrichedContainers = append(enrichedContainers, item)
 	}
 
@@ -339,13 +455,22 @@ func outputText(containers []ContainerInfo, sessions []SessionInfo,
 				fmt.Printf("  %s (ephemeral)\n", c.Name)
 			}
 			fmt.Printf("    Status: %s\n", c.Status)
+			if name, ok := session.ParseNamedContainerName(c.Name); ok {
+				fmt.Printf("    Name: %s (coi attach %s)\n", name, name)
+			}
 			if c.IPv4 != "" {
 				fmt.Printf("    IPv4: %s\n", c.IPv4)
 			}
+			if c.Network != nil {
+				fmt.Printf("    Network: %s received, %s sent\n", formatBytes(c.Network.BytesReceived), formatBytes(c.Network.BytesSent))
+			}
 			fmt.Printf("    Created: %s\n", c.CreatedAt)
 			if c.Image != "" {
 				fmt.Printf("    Image: %s\n", c.Image)
 			}
+			if len(c.Labels) > 0 {
+				fmt.Printf("    Labels: %s\n", session.FormatLabels(c.Labels))
+			}
 			// Show workspace if we have it from session metadata
 			if workspace, ok := workspaces[c.Name]; ok && workspace != "" {
 				fmt.Printf("    Workspace: %s\n", workspace)
@@ -363,6 +488,9 @@ func outputText(containers []ContainerInfo, sessions []SessionInfo,
 		} else {
 			for _, s := range sessions {
 				fmt.Printf("  %s\n", s.ID)
+				if listAllTools || listTool != "" {
+					fmt.Printf("    Tool: %s\n", s.Tool)
+				}
 				fmt.Printf("    Saved: %s\n", s.SavedAt)
 				if s.Workspace != "" {
 					fmt.Printf("    Workspace: %s\n", s.Workspace)
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/spf13/cobra"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Maintain the network log",
+	Long: `Rotate or clear the network log configured in [network.logging].
+
+The log rotates itself to network.log.1, network.log.2, ... once it reaches
+max_size_mb, keeping max_files rotated files:
+
+  [network.logging]
+  path = "~/.coi/logs/network.log"
+  max_size_mb = 10
+  max_files = 3
+
+Examples:
+  coi logs rotate   # Rotate now, regardless of size
+  coi logs clear    # Remove the log and all rotated files
+`,
+}
+
+var logsRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Rotate the network log now",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logging := cfg.Network.Logging
+		if err := network.RotateLog(logging.Path, logging.MaxFiles); err != nil {
+			return err
+		}
+		fmt.Printf("Rotated %s (keeping %d rotated files)\n", logging.Path, logging.MaxFiles)
+		return nil
+	},
+}
+
+var logsClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the network log and its rotated files",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		removed, err := network.ClearLog(cfg.Network.Logging.Path)
+		for _, path := range removed {
+			fmt.Printf("Removed %s\n", path)
+		}
+		if err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			fmt.Println("No network logs to clear")
+		}
+		return nil
+	},
+}
+
+func init() {
+	logsCmd.AddCommand(logsRotateCmd)
+	logsCmd.AddCommand(logsClearCmd)
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Summarize locally recorded session metrics",
+	Long: `Summarize session metrics recorded in ~/.coi/metrics.jsonl.
+
+Metrics are opt-in and never leave this machine. Enable them in config:
+
+  [defaults]
+  metrics = true
+
+Each finished session appends one JSON line with its session ID, tool, network
+mode, duration, exit reason, whether it was resumed and the image fingerprint.
+
+Examples:
+  coi metrics   # Sessions per day, average duration, most used tool
+`,
+	Args: cobra.NoArgs,
+	RunE: metricsCommand,
+}
+
+func metricsCommand(cmd *cobra.Command, args []string) error {
+	path, err := session.MetricsPath()
+	if err != nil {
+		return err
+	}
+
+	records, err := session.LoadMetrics(path)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No session metrics recorded.")
+		if !cfg.Defaults.Metrics {
+			fmt.Println("Enable them with 'metrics = true' under [defaults] in your config.")
+		}
+		return nil
+	}
+
+	summary := session.SummarizeMetrics(records)
+	fmt.Printf("Sessions:          %d (over %d days)\n", summary.Sessions, summary.Days)
+	fmt.Printf("Sessions per day:  %.1f\n", summary.SessionsPerDay)
+	fmt.Printf("Average duration:  %s\n", summary.AverageDuration.Round(time.Second))
+	fmt.Printf("Most used tool:    %s\n", summary.MostUsedTool)
+
+	reasons := make([]string, 0, len(summary.ExitReasons))
+	for reason := range summary.ExitReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	fmt.Println("Exit reasons:")
+	for _, reason := range reasons {
+		fmt.Printf("  %-12s %d\n", reason, summary.ExitReasons[reason])
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Manage network isolation for running containers",
+	Long: `Manage network isolation (firewall rules) for running containers.
+
+Examples:
+  coi network refresh                  # Re-resolve allowlist for this workspace's container
+  coi network refresh --slot 2         # Re-resolve allowlist for slot 2
+  coi network refresh coi-abc12345-1   # Re-resolve allowlist for a specific container
+  coi network policy diff              # Compare intended and applied firewall rules
+  coi network modes                    # Explain the network modes
+`,
+}
+
+var networkModesCmd = &cobra.Command{
+	Use:   "modes",
+	Short: "Explain the network modes and show the configured one",
+	Long: `Show what each network mode (open, restricted, allowlist) allows and blocks,
+whether it needs firewalld, whether firewalld is available on this host, and
+the mode configured for new sessions.
+
+The rules are built by the same code that applies them to a session, for an
+example container and with your [network] settings (block_private_networks,
+allow_local_network_access, denied_domains, ...). Rules are evaluated in
+priority order: the first matching rule wins.
+
+Examples:
+  coi network modes
+  coi network modes --network allowlist   # Show allowlist mode as the configured one
+`,
+	Args: cobra.NoArgs,
+	RunE: networkModesCommand,
+}
+
+var networkRefreshCmd = &cobra.Command{
+	Use:   "refresh [container-name]",
+	Short: "Re-resolve allowed domains and update firewall rules now",
+	Long: `Force an immediate re-resolution of allowlisted domains for a running container.
+
+In allowlist mode, domain IPs are re-resolved every refresh_interval_minutes.
+CDN-backed APIs can change IPs in between, which blocks the agent until the
+next refresh. This command re-resolves all allowed domains right away and
+updates the firewall rules if any IPs changed. In open and restricted modes it
+re-resolves the denied_domains instead.
+
+The container is resolved from the argument, --slot, or the current workspace.
+
+Examples:
+  coi network refresh
+  coi network refresh --slot 2
+  coi network refresh coi-abc12345-1
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: networkRefreshCommand,
+}
+
+var networkPolicyCmd = &cobra.Command{
+	Use:   "policy",
+	Short: "Inspect the firewall policy of running containers",
+}
+
+var networkPolicyDiffCmd = &cobra.Command{
+	Use:   "diff [container-name]",
+	Short: "Compare the firewall rules COI intends with the rules applied",
+	Long: `Compute the firewall rules COI would apply to a running container now (from
+its saved network config, fresh DNS resolution and the IP cache) and compare
+them with the firewalld direct rules currently applied for it.
+
+Use this when a container can suddenly reach something it shouldn't, e.g.
+after a refresh or manual firewall changes. Differences are shown as:
+
+  +  applied but not intended (stale or added by hand)
+  -  intended but not applied
+  ~  applied at a different priority than intended
+
+Allowlist IPs that changed since the last refresh also show up here; run
+'coi network refresh' to apply them.
+
+Exits with status 1 when the rules differ.
+
+The container is resolved from the argument, --slot, or the current workspace.
+
+Examples:
+  coi network policy diff
+  coi network policy diff --slot 2
+  coi network policy diff coi-abc12345-1
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: networkPolicyDiffCommand,
+}
+
+func init() {
+	networkPolicyCmd.AddCommand(networkPolicyDiffCmd)
+	networkCmd.AddCommand(networkRefreshCmd)
+	networkCmd.AddCommand(networkPolicyCmd)
+	networkCmd.AddCommand(networkModesCmd)
+}
+
+func networkRefreshCommand(cmd *cobra.Command, args []string) error {
+	containerName, err := resolveWorkspaceContainer(args)
+	if err != nil {
+		return err
+	}
+
+	running, err := container.ContainerRunning(containerName)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+
+	netManager, err := network.NewManagerForContainer(containerName)
+	if err != nil {
+		return err
+	}
+
+	updated, err := netManager.Refresh()
+	if err != nil {
+		return fmt.Errorf("failed to refresh network rules: %w", err)
+	}
+
+	if updated {
+		fmt.Printf("Firewall rules updated for %s\n", containerName)
+	} else {
+		fmt.Printf("Allowed IPs unchanged for %s\n", containerName)
+	}
+	return nil
+}
+
+func networkPolicyDiffCommand(cmd *cobra.Command, args []string) error {
+	containerName, err := resolveWorkspaceContainer(args)
+	if err != nil {
+		return err
+	}
+
+	running, err := container.ContainerRunning(containerName)
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+
+	netManager, err := network.NewManagerForContainer(containerName)
+	if err != nil {
+		return err
+	}
+
+	report, err := netManager.ComparePolicy()
+	if err != nil {
+		return fmt.Errorf("failed to compare firewall rules: %w", err)
+	}
+
+	fmt.Printf("Firewall policy for %s (%s mode): %d rules intended, %d applied\n",
+		containerName, report.Mode, len(report.Intended), len(report.Applied))
+
+	if report.Diff.Empty() {
+		fmt.Println("Applied rules match the intended policy")
+		return nil
+	}
+
+	fmt.Println()
+	for _, rule := range report.Diff.Added {
+		fmt.Printf("+ %s\n", rule)
+	}
+	for _, rule := range report.Diff.Removed {
+		fmt.Printf("- %s\n", rule)
+	}
+	for _, move := range report.Diff.Reordered {
+		fmt.Printf("~ %s (applied at priority %d)\n", move.Rule, move.LivePriority)
+	}
+	fmt.Printf("\n%d added, %d removed, %d reordered\n",
+		len(report.Diff.Added), len(report.Diff.Removed), len(report.Diff.Reordered))
+
+	return exitError(1, "")
+}
+
+// resolveWorkspaceContainer picks the target container from an explicit name,
+// the --slot flag, or the single container running for the current workspace
+func resolveWorkspaceContainer(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	absWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return "", fmt.Errorf("invalid workspace path: %w", err)
+	}
+
+	if slot > 0 {
+		return session.ContainerName(absWorkspace, slot), nil
+	}
+
+	sessions, err := session.ListWorkspaceSessions(absWorkspace)
+	if err != nil {
+		return "", fmt.Errorf("failed to list workspace sessions: %w", err)
+	}
+
+	switch len(sessions) {
+	case 0:
+		return "", fmt.Errorf("no COI containers found for current workspace")
+	case 1:
+		for _, name := range sessions {
+			return name, nil
+		}
+	}
+
+	names := make([]string, 0, len(sessions))
+	for _, name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("multiple COI containers found for workspace, use --slot or a container name: %s", strings.Join(names, ", "))
+}
+
+func networkModesCommand(cmd *cobra.Command, args []string) error {
+	firewall := "not available"
+	if network.FirewallAvailable() {
+		firewall = "available"
+	}
+
+	mode := cfg.Network.Mode
+	if networkMode != "" {
+		mode = config.NetworkMode(networkMode)
+	}
+
+	fmt.Printf("Configured mode: %s\n", mode)
+	fmt.Printf("firewalld: %s\n", firewall)
+
+	for _, example := range network.ModeExamples(&cfg.Network) {
+		current := ""
+		if example.Mode == mode {
+			current = " (configured)"
+		}
+		fmt.Printf("\n%s%s\n", example.Mode, current)
+		if example.RequiresFirewall {
+			fmt.Println("  Requires firewalld")
+		} else {
+			fmt.Println("  Works without firewalld (unrestricted if it is not running)")
+		}
+		fmt.Printf("  Rules for a container at %s:\n", network.ExampleContainerIP)
+		for _, rule := range example.Rules {
+			verb := "allow"
+			if rule.Action != "ACCEPT" {
+				verb = "block"
+			}
+			fmt.Printf("    %3d  %-5s  %s\n", rule.Priority, verb, network.DescribeDestination(rule.Destination))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/image"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nukeYes    bool
+	nukeImages bool
+)
+
+var nukeCmd = &cobra.Command{
+	Use:   "nuke",
+	Short: "Remove all COI containers, network state and saved sessions",
+	Long: `Remove all COI state for a clean reset.
+
+This stops and deletes every container matching the container prefix (coi- by
+default), removes their firewall rules and saved network state, and deletes all
+saved session data (~/.coi/sessions-*) and persistent caches (~/.coi/caches,
+see coi shell --mount-home). With --images the coi image is deleted too.
+
+The full list of what will be removed is printed first. Requires --yes or an
+interactive confirmation. This cannot be undone.
+
+Examples:
+  coi nuke                # Show what will be removed and ask for confirmation
+  coi nuke --yes          # Remove everything without asking
+  coi nuke --yes --images # Also delete the coi image
+`,
+	Args: cobra.NoArgs,
+	RunE: nukeCommand,
+}
+
+func init() {
+	nukeCmd.Flags().BoolVar(&nukeYes, "yes", false, "Skip the confirmation prompt")
+	nukeCmd.Flags().BoolVar(&nukeImages, "images", false, "Also delete the coi image")
+}
+
+func nukeCommand(cmd *cobra.Command, args []string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	baseDir := filepath.Join(homeDir, ".coi")
+
+	containers, err := listActiveContainers()
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	// Session data for every tool (sessions-claude, sessions-aider, ...)
+	sessionDirs, err := filepath.Glob(filepath.Join(baseDir, "sessions-*"))
+	if err != nil {
+		return fmt.Errorf("failed to find session directories: %w", err)
+	}
+
+	networkCacheDir := filepath.Join(baseDir, "network-cache")
+	hasNetworkCache := false
+	if _, err := os.Stat(networkCacheDir); err == nil {
+		hasNetworkCache = true
+	}
+
+	cachesDir := session.HomeCachesDir(baseDir)
+	hasCaches := false
+	if _, err := os.Stat(cachesDir); err == nil {
+		hasCaches = true
+	}
+
+	deleteImage := false
+	if nukeImages {
+		exists, err := container.ImageExists(image.CoiAlias)
+		if err != nil {
+			return fmt.Errorf("failed to check image: %w", err)
+		}
+		deleteImage = exists
+	}
+
+	if len(containers) == 0 && len(sessionDirs) == 0 && !hasNetworkCache && !hasCaches && !deleteImage {
+		fmt.Println("Nothing to remove.")
+		return nil
+	}
+
+	// Show exactly what will be removed
+	fmt.Println("This will permanently remove:")
+	if len(containers) > 0 {
+		fmt.Printf("\nContainers (%s*):\n", session.GetContainerPrefix())
+		for _, c := range containers {
+			fmt.Printf("  - %s (%s)\n", c.Name, c.Status)
+		}
+	}
+	if len(containers) > 0 || hasNetworkCache {
+		fmt.Println("\nNetwork state:")
+		if len(containers) > 0 {
+			fmt.Println("  - firewall rules of the containers above")
+		}
+		if hasNetworkCache {
+			fmt.Printf("  - %s\n", networkCacheDir)
+		}
+	}
+	if len(sessionDirs) > 0 {
+		fmt.Println("\nSaved sessions:")
+		for _, dir := range sessionDirs {
+			fmt.Printf("  - %s\n", dir)
+		}
+	}
+	if hasCaches {
+		fmt.Println("\nPersistent caches:")
+		fmt.Printf("  - %s\n", cachesDir)
+	}
+	if deleteImage {
+		fmt.Println("\nImages:")
+		fmt.Printf("  - %s\n", image.CoiAlias)
+	}
+
+	if !nukeYes {
+		fmt.Print("\nRemove all of the above? [y/N]: ")
+		var response string
+		_, _ = fmt.Scanln(&response) // Ignore error, default to "no" if read fails
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	failed := 0
+
+	// Rules are keyed by address, so look the addresses up before deleting;
+	// stopped containers still have rules but no address in their state
+	var addresses map[string][]string
+	if len(containers) > 0 && network.FirewallAvailable() {
+		names := make([]string, 0, len(containers))
+		for _, c := range containers {
+			names = append(names, c.Name)
+		}
+		if addresses, err = network.ContainerAddresses(names); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to look up container addresses, firewall rules may remain: %v\n", err)
+		}
+	}
+
+	for _, c := range containers {
+		fmt.Printf("Deleting container %s...\n", c.Name)
+		mgr := container.NewManager(c.Name)
+
+		// Delete (force stops running containers), then remove its firewall rules
+		if err := mgr.Delete(true); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to delete %s: %v\n", c.Name, err)
+			failed++
+			continue
+		}
+		if len(addresses[c.Name]) > 0 {
+			if err := network.RemoveRulesFrom(addresses[c.Name]); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: Failed to remove firewall rules for %s: %v\n", c.Name, err)
+			}
+		}
+	}
+
+	if hasNetworkCache {
+		fmt.Println("Removing saved network state...")
+		if err := os.RemoveAll(networkCacheDir); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to remove %s: %v\n", networkCacheDir, err)
+			failed++
+		}
+	}
+
+	for _, dir := range sessionDirs {
+		fmt.Printf("Removing %s...\n", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to remove %s: %v\n", dir, err)
+			failed++
+		}
+	}
+
+	if hasCaches {
+		fmt.Printf("Removing %s...\n", cachesDir)
+		if err := os.RemoveAll(cachesDir); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to remove %s: %v\n", cachesDir, err)
+			failed++
+		}
+	}
+
+	if deleteImage {
+		fmt.Printf("Deleting image %s...\n", image.CoiAlias)
+		if err := container.DeleteImage(image.CoiAlias); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to delete image %s: %v\n", image.CoiAlias, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to remove %d item(s) - see warnings above", failed)
+	}
+
+	fmt.Println("\n✓ All COI state removed")
+	return nil
+}
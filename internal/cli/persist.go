@@ -145,6 +145,11 @@ func persistCommand(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
+		// Mark the container itself so a stopped persistent container keeps its slot
+		if err := container.IncusExec("config", "set", name, session.PersistentConfigKey, "true"); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to mark container as persistent: %v\n", err)
+		}
+
 		persisted++
 		fmt.Printf("  ✓ Persisted %s\n", name)
 	}
@@ -211,16 +216,5 @@ func updatePersistentFlag(metadataPath string, persistent bool) error {
 	// Update persistent field
 	metadata.Persistent = persistent
 
-	// Write back using same format as cleanup.go:saveMetadata
-	content := fmt.Sprintf(`{
-  "session_id": "%s",
-  "container_name": "%s",
-  "persistent": %t,
-  "workspace": "%s",
-  "saved_at": "%s"
-}
-`, metadata.SessionID, metadata.ContainerName, metadata.Persistent,
-		metadata.Workspace, metadata.SavedAt)
-
-	return os.WriteFile(metadataPath, []byte(content), 0o644)
+	return session.SaveMetadata(metadataPath, *metadata)
 }
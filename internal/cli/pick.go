@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+	"github.com/spf13/cobra"
+)
+
+var pickCmd = &cobra.Command{
+	Use:   "pick",
+	Short: "Choose a running session to attach to or a saved session to resume",
+	Long: `Show a menu of running sessions and resumable saved sessions (with workspace,
+tool and age) and attach to or resume the selected one.
+
+Keys:
+  Up/Down, j/k   Move the selection
+  1-9            Jump to an entry
+  Enter          Attach (running) or resume (saved)
+  r              Resume the selected saved session
+  q, Esc         Cancel
+
+Without a terminal (e.g. piped input) a numbered prompt is shown instead.
+
+Examples:
+  coi pick
+`,
+	Args: cobra.NoArgs,
+	RunE: pickCommand,
+}
+
+func pickCommand(cmd *cobra.Command, args []string) error {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+
+	entries, err := collectPickEntries(sessionsDir, toolInstance)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No running or saved sessions")
+		return nil
+	}
+
+	choice, action, err := runPickMenu(entries, toolInstance.Name())
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case session.PickAttach:
+		fmt.Printf("Attaching to %s...\n", entries[choice].Name)
+		return attachToContainer(entries[choice].Name)
+	case session.PickResume:
+		entry := entries[choice]
+		resume = entry.Name
+		if entry.Workspace != "" {
+			workspace = entry.Workspace
+		}
+		return shellCmd.RunE(cmd, nil)
+	default:
+		fmt.Println("Cancelled.")
+		return nil
+	}
+}
+
+// collectPickEntries lists running coi containers, then saved sessions newest first
+func collectPickEntries(sessionsDir string, toolInstance tool.Tool) ([]session.PickEntry, error) {
+	containers, err := listActiveContainers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	containerWorkspaces, _ := loadContainerMetadata(sessionsDir)
+
+	var running []session.PickEntry
+	for _, c := range containers {
+		if !isRunningStatus(c.Status) {
+			continue
+		}
+		running = append(running, session.PickEntry{
+			Running:   true,
+			Name:      c.Name,
+			Workspace: containerWorkspaces[c.Name],
+			Since:     session.ParsePickTime(c.CreatedAt),
+		})
+	}
+
+	sessions, err := listSavedSessions(sessionsDir, toolInstance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved sessions: %w", err)
+	}
+	saved := make([]session.PickEntry, 0, len(sessions))
+	for _, s := range sessions {
+		saved = append(saved, session.PickEntry{
+			Name:      s.ID,
+			Workspace: s.Workspace,
+			Since:     session.ParsePickTime(s.SavedAt),
+		})
+	}
+
+	return session.OrderPickEntries(running, saved), nil
+}
+
+// runPickMenu lets the user choose an entry, with an arrow-key menu when stdin
+// is a terminal and a numbered prompt otherwise
+func runPickMenu(entries []session.PickEntry, toolName string) (int, session.PickAction, error) {
+	restore, err := setRawTerminal()
+	if err != nil {
+		return promptPick(entries, toolName)
+	}
+	defer restore()
+
+	selected := 0
+	drawn := 0
+	draw := func() {
+		var out strings.Builder
+		if drawn > 0 {
+			fmt.Fprintf(&out, "\x1b[%dA\r\x1b[J", drawn) // Move back up and clear the previous menu
+		}
+		out.WriteString("Select a session (Enter: attach/resume, r: resume, q: cancel)\r\n")
+		for i, entry := range entries {
+			marker := "  "
+			if i == selected {
+				marker = "> "
+			}
+			out.WriteString(marker + session.PickLine(i+1, entry, toolName, time.Now()) + "\r\n")
+		}
+		fmt.Fprint(os.Stderr, out.String())
+		drawn = len(entries) + 1
+	}
+
+	buf := make([]byte, 8)
+	for {
+		draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return 0, session.PickCancel, fmt.Errorf("failed to read key: %w", err)
+		}
+		var action session.PickAction
+		var done bool
+		selected, action, done = session.PickKey(string(buf[:n]), entries, selected)
+		if done {
+			return selected, action, nil
+		}
+	}
+}
+
+// promptPick is the numbered fallback for runPickMenu
+func promptPick(entries []session.PickEntry, toolName string) (int, session.PickAction, error) {
+	fmt.Println("Sessions:")
+	for i, entry := range entries {
+		fmt.Printf("  %s\n", session.PickLine(i+1, entry, toolName, time.Now()))
+	}
+
+	fmt.Fprintf(os.Stderr, "Select session [1]: ")
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(response)
+	if response == "" {
+		return 0, session.DefaultPickAction(entries[0]), nil
+	}
+	choice, err := strconv.Atoi(response)
+	if err != nil || choice < 1 || choice > len(entries) {
+		return 0, session.PickCancel, fmt.Errorf("invalid selection '%s'", response)
+	}
+	return choice - 1, session.DefaultPickAction(entries[choice-1]), nil
+}
+
+// setRawTerminal switches the terminal on stdin to raw mode with stty (so no
+// terminal library is needed) and returns a function that restores it
+func setRawTerminal() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() {
+		_, _ = stty(strings.TrimSpace(saved)) // Best effort: nothing more to do if the terminal is gone
+	}, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	return string(output), err
+}
@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/plugin"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Run and list coi plugins (coi-<name> executables on PATH)",
+	Long: `Plugins are separate executables named coi-<name> on PATH, for functionality
+shipped outside the coi binary (e.g. MCP servers, custom resolvers).
+
+'coi <name> [args...]' runs coi-<name> when <name> is not a built-in command;
+'coi plugin exec <name>' does the same explicitly. Arguments, stdin/stdout and
+the exit code are passed through. Plugins get coi's context in the environment:
+  COI_CONTAINER_PREFIX  Prefix of coi container names
+  COI_CONFIG            The most specific config file in effect, if any
+  COI_PROFILE           The --profile given before the plugin name, if any
+
+Examples:
+  coi plugin list
+  coi plugin exec mcp --port 8080
+  coi mcp --port 8080
+  coi --profile work mcp --port 8080
+`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List plugins found on PATH",
+	Args:  cobra.NoArgs,
+	RunE:  pluginListCommand,
+}
+
+var pluginExecCmd = &cobra.Command{
+	Use:                "exec <name> [args...]",
+	Short:              "Run a plugin",
+	Args:               cobra.MinimumNArgs(1),
+	DisableFlagParsing: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := plugin.Find(args[0])
+		if err != nil {
+			return err
+		}
+		return runPlugin(path, args[1:])
+	},
+}
+
+func init() {
+	pluginCmd.AddCommand(pluginListCmd)
+	pluginCmd.AddCommand(pluginExecCmd)
+}
+
+func pluginListCommand(cmd *cobra.Command, args []string) error {
+	plugins := plugin.List()
+	if len(plugins) == 0 {
+		fmt.Printf("No plugins found (executables named %s<name> on PATH)\n", plugin.Prefix)
+		return nil
+	}
+
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%-20s %s\n", name, plugins[name])
+	}
+	return nil
+}
+
+// pluginEnv returns the environment a plugin runs with: coi's own plus the
+// context plugins need to find coi containers and config
+func pluginEnv() []string {
+	return plugin.Env(os.Environ(), plugin.Context{
+		ContainerPrefix: session.GetContainerPrefix(),
+		ConfigPaths:     config.GetConfigPaths(),
+		Profile:         profile,
+	})
+}
+
+// runPlugin runs the plugin at path with args, exiting with its exit code
+func runPlugin(path string, args []string) error {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = pluginEnv()
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitError(exitErr.ExitCode(), "")
+		}
+		return fmt.Errorf("failed to run plugin %s: %w", path, err)
+	}
+	return nil
+}
+
+// pluginInvocation reports whether args call a plugin ('coi [flags] <name> ...'
+// where <name> is not a built-in command) and returns its path and arguments.
+// Global flags before the name are parsed (--profile reaches the plugin as
+// COI_PROFILE); the rest only affect built-in commands.
+func pluginInvocation(args []string) (string, []string, bool) {
+	flags, name, rest, ok := plugin.SplitArgs(args, rootFlagLookup)
+	if !ok {
+		return "", nil, false
+	}
+	// help, completion and the __complete* commands are added by cobra at execution
+	if name == "help" || name == "completion" || strings.HasPrefix(name, "__") {
+		return "", nil, false
+	}
+	if cmd, _, err := rootCmd.Find([]string{name}); err == nil && cmd != rootCmd {
+		return "", nil, false
+	}
+	path, err := plugin.Find(name)
+	if err != nil {
+		return "", nil, false
+	}
+	if err := rootCmd.ParseFlags(flags); err != nil {
+		return "", nil, false
+	}
+	return path, rest, true
+}
+
+// rootFlagLookup is the plugin.FlagLookup for coi's global flags
+func rootFlagLookup(name string, shorthand bool) (bool, bool) {
+	flags := rootCmd.Flags()
+	flags.AddFlagSet(rootCmd.PersistentFlags()) // As cobra does before parsing
+	flag := flags.Lookup(name)
+	if shorthand {
+		flag = flags.ShorthandLookup(name)
+	}
+	if flag == nil {
+		return false, false
+	}
+	return true, flag.Value.Type() != "bool" && flag.NoOptDefVal == ""
+}
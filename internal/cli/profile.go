@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Inspect configured profiles",
+	Long: `Inspect profiles defined under [profiles.<name>] in config.
+
+Use a profile with the global --profile flag, e.g. coi shell --profile rust.
+
+Examples:
+  coi profile list   # Show every profile's image, persistence and environment
+`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured profiles",
+	Args:  cobra.NoArgs,
+	RunE:  profileListCommand,
+}
+
+func init() {
+	profileCmd.AddCommand(profileListCmd)
+}
+
+func profileListCommand(cmd *cobra.Command, args []string) error {
+	names := cfg.ProfileNames()
+	if len(names) == 0 {
+		fmt.Println("No profiles configured.")
+		fmt.Println("Define one under [profiles.<name>] in ~/.config/coi/config.toml or .coi.toml.")
+		return nil
+	}
+
+	for i, name := range names {
+		p := cfg.Profiles[name]
+		if i > 0 {
+			fmt.Println()
+		}
+
+		image := p.Image
+		if image == "" {
+			image = "(default)"
+		}
+
+		fmt.Printf("%s\n", name)
+		fmt.Printf("  Image:       %s\n", image)
+		fmt.Printf("  Persistent:  %v\n", p.Persistent)
+		if p.Limits != nil {
+			fmt.Printf("  Limits:      yes\n")
+		}
+
+		if len(p.Environment) == 0 {
+			fmt.Printf("  Environment: (none)\n")
+			continue
+		}
+		keys := make([]string, 0, len(p.Environment))
+		for key := range p.Environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		pairs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			pairs = append(pairs, key+"="+p.Environment[key])
+		}
+		fmt.Printf("  Environment: %s\n", strings.Join(pairs, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+// reapPollInterval is how often the reaper re-checks the container and its deadline
+const reapPollInterval = time.Minute
+
+var (
+	reapDeadline    string
+	reapSessionID   string
+	reapDeleteAfter string
+)
+
+// reapCmd enforces --max-duration, and deletes stopped containers kept for
+// delete_grace_minutes (--delete-after). It is started detached by 'coi shell'
+// so both also apply after coi has exited.
+var reapCmd = &cobra.Command{
+	Use:    "reap <container>",
+	Short:  "Tear down a session once its --max-duration deadline or grace period passes (internal)",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	RunE:   reapCommand,
+}
+
+func init() {
+	reapCmd.Flags().StringVar(&reapDeadline, "deadline", "", "Deadline (RFC3339) this reaper was started for")
+	reapCmd.Flags().StringVar(&reapSessionID, "session-id", "", "COI session ID to save before teardown")
+	reapCmd.Flags().StringVar(&reapDeleteAfter, "delete-after", "", "Delete the stopped container once this time (RFC3339) passes")
+	reapCmd.MarkFlagsOneRequired("deadline", "delete-after")
+	reapCmd.MarkFlagsMutuallyExclusive("deadline", "delete-after")
+}
+
+// startReaper launches 'coi reap' as a detached process (own session, output to
+// a log file) that outlives this coi invocation
+func startReaper(containerName, sessionID, workspace string, deadline time.Time) error {
+	return spawnReaper(containerName, workspace, "--deadline", session.FormatDeadline(deadline), "--session-id", sessionID)
+}
+
+// startDeleteReaper launches a detached 'coi reap --delete-after' that deletes a
+// stopped container once its delete_grace_minutes grace period ends
+func startDeleteReaper(containerName, workspace string, deleteAfter time.Time) error {
+	return spawnReaper(containerName, workspace, "--delete-after", session.FormatDeadline(deleteAfter))
+}
+
+// spawnReaper runs 'coi reap <container> <flags>' detached, logging to the
+// container's reaper log
+func spawnReaper(containerName, workspace string, flags ...string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate coi executable: %w", err)
+	}
+
+	logsDir := cfg.Paths.LogsDir
+	if err := os.MkdirAll(logsDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create logs directory: %w", err)
+	}
+	logPath := filepath.Join(logsDir, fmt.Sprintf("reaper-%s.log", containerName))
+	logFile, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open reaper log: %w", err)
+	}
+	defer logFile.Close()
+
+	args := append([]string{"reap", containerName}, flags...)
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+
+	reaper := exec.Command(executable, args...)
+	reaper.Dir = workspace // Load the same project config (.coi.toml) as this session
+	reaper.Stdout = logFile
+	reaper.Stderr = logFile
+	reaper.SysProcAttr = &syscall.SysProcAttr{Setsid: true} // Survive the terminal closing
+	if err := reaper.Start(); err != nil {
+		return fmt.Errorf("failed to start reaper: %w", err)
+	}
+	return reaper.Process.Release()
+}
+
+func reapCommand(cmd *cobra.Command, args []string) error {
+	containerName := args[0]
+	logf := func(format string, a ...interface{}) {
+		fmt.Printf("%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, a...))
+	}
+	if reapDeleteAfter != "" {
+		return reapStoppedContainer(containerName, logf)
+	}
+
+	deadline, ok, err := session.ParseDeadline(reapDeadline)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("--deadline is required")
+	}
+
+	logf("Watching %s, deadline %s", containerName, session.FormatDeadline(deadline))
+
+	mgr := container.NewManager(containerName)
+	for {
+		exists, err := mgr.Exists()
+		if err != nil {
+			logf("Warning: could not check container: %v", err)
+		} else if !exists {
+			logf("Container %s is gone, nothing to do", containerName)
+			return nil
+		}
+
+		// A later coi shell run on a reused container replaces or clears the deadline
+		current, ok, err := session.GetDeadline(containerName)
+		if err == nil && (!ok || !current.Equal(deadline)) {
+			logf("Deadline changed or cleared, exiting")
+			return nil
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			break
+		}
+		time.Sleep(min(wait, reapPollInterval))
+	}
+
+	// coi shell cleans up when the session ends (e.g. because this stops the
+	// container), so only one of them may save and delete at a time
+	lock, err := session.LockContainerCleanup(containerName)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// Checked while holding the lock: coi shell may have cleaned up already
+	if running, _ := mgr.Running(); !running {
+		logf("Container %s is not running, nothing to do", containerName)
+		return nil
+	}
+
+	logf("Max duration reached, tearing down %s", containerName)
+	return reapContainer(mgr, reapSessionID, logf)
+}
+
+// reapStoppedContainer waits for --delete-after and then deletes the container,
+// unless it was restarted, deleted or re-marked in the meantime
+func reapStoppedContainer(containerName string, logf func(string, ...interface{})) error {
+	deleteAfter, ok, err := session.ParseDeadline(reapDeleteAfter)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("--delete-after is required")
+	}
+	logf("Keeping stopped %s until %s", containerName, session.FormatDeadline(deleteAfter))
+
+	mgr := container.NewManager(containerName)
+	for {
+		exists, err := mgr.Exists()
+		if err != nil {
+			logf("Warning: could not check container: %v", err)
+		} else if !exists {
+			logf("Container %s is gone, nothing to do", containerName)
+			return nil
+		}
+
+		// A later session on the same slot recreates the container without the mark
+		current, ok, err := session.GetDeleteAfter(containerName)
+		if err == nil && (!ok || !current.Equal(deleteAfter)) {
+			logf("Deletion time changed or cleared, exiting")
+			return nil
+		}
+
+		if running, _ := mgr.Running(); running {
+			return keepRestartedContainer(mgr, logf)
+		}
+
+		wait := time.Until(deleteAfter)
+		if wait <= 0 {
+			break
+		}
+		time.Sleep(min(wait, reapPollInterval))
+	}
+
+	if running, _ := mgr.Running(); running {
+		return keepRestartedContainer(mgr, logf)
+	}
+
+	logf("Grace period over, deleting %s", containerName)
+	if err := mgr.Delete(true); err != nil {
+		return fmt.Errorf("failed to delete container: %w", err)
+	}
+	return nil
+}
+
+// keepRestartedContainer clears the deletion mark of a container that was
+// started again during its grace period, so it is not deleted once it stops
+func keepRestartedContainer(mgr *container.Manager, logf func(string, ...interface{})) error {
+	logf("Container %s was restarted, keeping it", mgr.ContainerName)
+	if err := session.ClearDeleteAfter(mgr); err != nil {
+		logf("Warning: could not clear the deletion time: %v", err)
+	}
+	return nil
+}
+
+// deleteExpiredContainers deletes stopped containers whose delete_grace_minutes
+// grace period has passed, in case their reaper did not run (best effort)
+func deleteExpiredContainers() {
+	_ = session.DeleteExpiredContainers(func(msg string) {
+		fmt.Fprintln(os.Stderr, msg)
+	})
+}
+
+// reapContainer removes network isolation, stops the container and then runs
+// the normal session cleanup (save session data, delete unless persistent).
+// The caller holds the container's cleanup lock.
+func reapContainer(mgr *container.Manager, sessionID string, logf func(string, ...interface{})) error {
+	// Firewall rules are keyed by container IP, so remove them while it still has one
+	if err := network.TeardownContainer(mgr.ContainerName); err != nil {
+		logf("Warning: network teardown failed: %v", err)
+	}
+
+	if err := mgr.StopWithTimeout(configuredStopTimeout()); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+
+	cleanupOpts := session.CleanupOptions{
+		ContainerName: mgr.ContainerName,
+		SessionID:     sessionID,
+		SessionsDir:   sessionsDir,
+		SaveSession:   sessionID != "",
+		Tool:          toolInstance,
+		Logger: func(msg string) {
+			logf("[cleanup] %s", msg)
+		},
+	}
+	if metadata, err := session.LoadSessionMetadata(filepath.Join(sessionsDir, sessionID, "metadata.json")); err == nil {
+		cleanupOpts.Persistent = metadata.Persistent
+		cleanupOpts.Workspace = metadata.Workspace
+	}
+
+	if err := session.Cleanup(cleanupOpts); err != nil {
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+	logf("Session %s torn down after reaching max duration", sessionID)
+	return nil
+}
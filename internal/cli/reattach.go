@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var reattachPick bool
+
+var reattachCmd = &cobra.Command{
+	Use:   "reattach",
+	Short: "Attach to the most recently active session in any workspace",
+	Long: `Find the most recently active AI coding session across all workspaces and attach to it.
+
+Running containers are ordered by their latest tmux activity. Without --pick the
+most recent one is attached; with --pick a numbered list is shown to choose from.
+
+Examples:
+  coi reattach           # Attach to the most recently active session
+  coi reattach --pick    # Choose from all running sessions
+`,
+	Args: cobra.NoArgs,
+	RunE: reattachCommand,
+}
+
+func init() {
+	reattachCmd.Flags().BoolVar(&reattachPick, "pick", false, "Show a numbered list of running sessions to choose from")
+}
+
+// reattachCandidate is a running container with its latest tmux activity
+type reattachCandidate struct {
+	Name      string
+	Workspace string
+	Activity  int64 // Unix time of the latest tmux activity, 0 if no tmux session
+}
+
+func reattachCommand(cmd *cobra.Command, args []string) error {
+	containers, err := listActiveContainers()
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	// Enrich with workspace from saved session metadata
+	var containerWorkspaces map[string]string
+	if toolInstance, err := getConfiguredTool(cfg); err == nil {
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+			containerWorkspaces, _ = loadContainerMetadata(sessionsDir)
+		}
+	}
+
+	var candidates []reattachCandidate
+	for _, c := range containers {
+		if c.Status != "Running" {
+			continue
+		}
+		mgr := container.NewManager(c.Name)
+		output, err := tmuxExec(mgr, "tmux list-sessions -F '#{session_activity}' 2>/dev/null")
+		activity := int64(0)
+		if err == nil {
+			activity = latestTmuxActivity(output)
+		}
+		candidates = append(candidates, reattachCandidate{
+			Name:      c.Name,
+			Workspace: containerWorkspaces[c.Name],
+			Activity:  activity,
+		})
+	}
+
+	if len(candidates) == 0 {
+		fmt.Println("No active sessions")
+		return nil
+	}
+
+	sortByActivity(candidates)
+
+	target := candidates[0]
+	if reattachPick && len(candidates) > 1 {
+		fmt.Println("Active sessions (most recent first):")
+		for i, c := range candidates {
+			workspace := c.Workspace
+			if workspace == "" {
+				workspace = "unknown workspace"
+			}
+			lastActive := "no tmux session"
+			if c.Activity > 0 {
+				lastActive = "active " + time.Unix(c.Activity, 0).Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("  %d. %s  %s  (%s)\n", i+1, c.Name, workspace, lastActive)
+		}
+
+		fmt.Fprintf(os.Stderr, "Select session [1]: ")
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(response)
+		if response != "" {
+			choice, err := strconv.Atoi(response)
+			if err != nil || choice < 1 || choice > len(candidates) {
+				return fmt.Errorf("invalid selection '%s'", response)
+			}
+			target = candidates[choice-1]
+		}
+	}
+
+	if target.Workspace != "" {
+		fmt.Printf("Attaching to %s (%s)...\n", target.Name, target.Workspace)
+	} else {
+		fmt.Printf("Attaching to %s...\n", target.Name)
+	}
+	return attachToContainer(target.Name)
+}
+
+// latestTmuxActivity returns the highest session_activity timestamp from tmux list-sessions output
+func latestTmuxActivity(output string) int64 {
+	var latest int64
+	for _, line := range strings.Split(output, "\n") {
+		if ts, err := strconv.ParseInt(strings.TrimSpace(line), 10, 64); err == nil && ts > latest {
+			latest = ts
+		}
+	}
+	return latest
+}
+
+// sortByActivity orders candidates by most recent tmux activity, then by name
+func sortByActivity(candidates []reattachCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Activity != candidates[j].Activity {
+			return candidates[i].Activity > candidates[j].Activity
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+}
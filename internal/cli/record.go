@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/terminal"
+)
+
+// shellRecording is an asciicast recording of an interactive session (coi shell --record)
+type shellRecording struct {
+	file     *os.File
+	recorder *terminal.CastRecorder
+	width    int
+	height   int
+}
+
+// activeRecording is the recording of the current session, nil when not recording
+var activeRecording *shellRecording
+
+// startRecording creates the recording file at path, sized to the host terminal
+func startRecording(path, title string) (*shellRecording, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recording: %w", err)
+	}
+
+	width, height := terminal.Size()
+	env := map[string]string{"TERM": terminal.SanitizeTerm(os.Getenv("TERM"))}
+	recorder, err := terminal.NewCastRecorder(file, width, height, title, env)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &shellRecording{file: file, recorder: recorder, width: width, height: height}, nil
+}
+
+// Close finishes the recording and closes its file
+func (r *shellRecording) Close() error {
+	err := r.recorder.Close()
+	if closeErr := r.file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write recording: %w", closeErr)
+	}
+	return err
+}
+
+// recordCommand returns command (run with ExecCommand) set up for the active
+// recording, if any: its output is copied to the recording, and since incus no
+// longer sees a terminal on stdout, the container terminal is sized first
+func recordCommand(command string, opts *container.ExecCommandOptions) string {
+	if activeRecording == nil {
+		return command
+	}
+	opts.Output = activeRecording.recorder
+	return fmt.Sprintf("stty cols %d rows %d 2>/dev/null; %s", activeRecording.width, activeRecording.height, command)
+}
+
+// recordArgs is recordCommand for command arguments run with ExecArgs
+func recordArgs(args []string, opts *container.ExecCommandOptions) []string {
+	if activeRecording == nil {
+		return args
+	}
+	opts.Output = activeRecording.recorder
+	wrapped := []string{
+		"sh", "-c", `stty cols "$1" rows "$2" 2>/dev/null; shift 2; exec "$@"`, "sh",
+		fmt.Sprintf("%d", activeRecording.width), fmt.Sprintf("%d", activeRecording.height),
+	}
+	return append(wrapped, args...)
+}
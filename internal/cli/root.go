@@ -2,8 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/network"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +25,8 @@ var (
 	envVars         []string
 	mountPairs      []string // --mount flag for custom mounts
 	networkMode     string
+	disableShift    bool // --disable-shift: never use UID shifting for bind mounts
+	forceShift      bool // --shift: use UID shifting even inside Colima/Lima
 
 	// Limit flags
 	limitCPU           string
@@ -75,15 +80,34 @@ Examples:
 		// Apply profile if specified
 		if profile != "" {
 			if !cfg.ApplyProfile(profile) {
-				return fmt.Errorf("profile '%s' not found", profile)
+				if len(cfg.Profiles) == 0 {
+					return fmt.Errorf("profile '%s' not found (no profiles configured)", profile)
+				}
+				return fmt.Errorf("profile '%s' not found (available: %s)", profile, strings.Join(cfg.ProfileNames(), ", "))
 			}
+			// Profile environment applies unless overridden with --env
+			envVars = cfg.GetProfile(profile).MergeEnv(envVars)
 		}
 
+		// Network setup messages also go to the network log
+		network.TeeLog(cfg.Network.Logging, os.Stderr)
+
 		// Apply config defaults to flags that weren't explicitly set
 		if !cmd.Flags().Changed("persistent") {
 			persistent = cfg.Defaults.Persistent
 		}
 
+		// UID shifting flags override [incus] disable_shift and auto-detection
+		if disableShift && forceShift {
+			return fmt.Errorf("--shift and --disable-shift cannot be used together")
+		}
+		if disableShift {
+			cfg.Incus.DisableShift = true
+		}
+		if forceShift {
+			cfg.Incus.DisableShift = false
+		}
+
 		return nil
 	},
 }
@@ -93,6 +117,10 @@ func Execute(isCoi bool) error {
 	if !isCoi {
 		rootCmd.Use = "claude-on-incus"
 	}
+	// Unknown subcommands run the coi-<name> plugin from PATH, if there is one
+	if path, args, ok := pluginInvocation(os.Args[1:]); ok {
+		return runPlugin(path, args)
+	}
 	return rootCmd.Execute()
 }
 
@@ -110,6 +138,8 @@ func init() {
 	rootCmd.PersistentFlags().StringSliceVarP(&envVars, "env", "e", []string{}, "Environment variables (KEY=VALUE)")
 	rootCmd.PersistentFlags().StringArrayVar(&mountPairs, "mount", []string{}, "Mount directory (HOST:CONTAINER, repeatable)")
 	rootCmd.PersistentFlags().StringVar(&networkMode, "network", "", "Network mode: restricted (default), open")
+	rootCmd.PersistentFlags().BoolVar(&disableShift, "disable-shift", false, "Disable UID shifting for bind mounts (auto-detected in Colima/Lima)")
+	rootCmd.PersistentFlags().BoolVar(&forceShift, "shift", false, "Force UID shifting for bind mounts, overriding Colima/Lima detection")
 
 	// Resource limit flags
 	rootCmd.PersistentFlags().StringVar(&limitCPU, "limit-cpu", "", "CPU count limit (e.g., '2', '0-3', '0,1,3')")
@@ -135,13 +165,31 @@ func init() {
 	rootCmd.AddCommand(imageCmd)     // New: coi image <subcommand>
 	rootCmd.AddCommand(containerCmd) // New: coi container <subcommand>
 	rootCmd.AddCommand(fileCmd)      // New: coi file <subcommand>
+	rootCmd.AddCommand(syncCmd)
 	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(killCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(persistCmd)
 	rootCmd.AddCommand(tmuxCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(healthCmd)
 	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(networkCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(transcriptCmd)
+	rootCmd.AddCommand(cloneCmd)
+	rootCmd.AddCommand(reattachCmd)
+	rootCmd.AddCommand(pickCmd)
+	rootCmd.AddCommand(nukeCmd)
+	rootCmd.AddCommand(metricsCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(reapCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
+	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(sessionCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(pluginCmd)
 }
 
 var versionCmd = &cobra.Command{
@@ -1,10 +1,13 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
@@ -14,25 +17,47 @@ import (
 )
 
 var (
-	capture bool
-	timeout int
-	format  string
+	capture        bool
+	timeout        int
+	format         string
+	runStdin       bool
+	runInteractive bool
+
+	// --wait-port (coi run, coi shell)
+	waitPort        int
+	waitPortTimeout time.Duration
 )
 
+// runScriptPath is where a script read from stdin is pushed in the container
+const runScriptPath = "/tmp/coi-run-script"
+
 var runCmd = &cobra.Command{
-	Use:   "run COMMAND",
+	Use:   "run COMMAND | run -",
 	Short: "Run a command in an ephemeral container",
 	Long: `Execute a command in an ephemeral Incus container.
 
 The container is automatically cleaned up after the command completes.
 
+With "-" (or --stdin) the command is read from stdin as a script, pushed into
+the container and run with bash (or its #! interpreter). The script's exit code
+is returned.
+
+With --interactive the command gets a pseudo-terminal attached to yours, for
+commands that prompt (e.g. npm init). Its exit code is still returned.
+
+With --wait-port N the command only starts once port N accepts connections
+inside the container; if it never does within --wait-timeout, coi exits with
+code 124.
+
 Examples:
   coi run "echo hello"
   coi run "npm test" --capture
+  coi run --interactive "npm init"
   coi run "pytest" --slot 2
   coi run --workspace ~/project "make build"
+  cat script.sh | coi run -
 `,
-	Args: cobra.MinimumNArgs(1),
+	Args: cobra.ArbitraryArgs,
 	RunE: runCommand,
 }
 
@@ -40,9 +65,34 @@ func init() {
 	runCmd.Flags().BoolVar(&capture, "capture", false, "Capture output instead of streaming")
 	runCmd.Flags().IntVar(&timeout, "timeout", 120, "Command timeout in seconds")
 	runCmd.Flags().StringVar(&format, "format", "pretty", "Output format (pretty|json)")
+	runCmd.Flags().BoolVar(&runStdin, "stdin", false, "Read the command/script from stdin (same as 'coi run -')")
+	runCmd.Flags().BoolVarP(&runInteractive, "interactive", "i", false, "Run the command with a pseudo-terminal attached to yours (for prompts)")
+	runCmd.MarkFlagsMutuallyExclusive("interactive", "capture")
+	addWaitPortFlags(runCmd)
 }
 
 func runCommand(cmd *cobra.Command, args []string) error {
+	// Read the script from stdin before doing any container work
+	fromStdin := runStdin || (len(args) == 1 && args[0] == "-")
+	var scriptFile string
+	removeScript := func() {}
+	if fromStdin {
+		if runStdin && len(args) > 0 {
+			return fmt.Errorf("--stdin cannot be combined with a command argument")
+		}
+		if runInteractive {
+			return fmt.Errorf("--interactive cannot be combined with a script from stdin (stdin is the script)")
+		}
+		path, cleanup, err := writeStdinScript(os.Stdin)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		scriptFile, removeScript = path, cleanup
+	} else if len(args) == 0 {
+		return fmt.Errorf("requires a command to run (or '-' to read it from stdin)")
+	}
+
 	// Get absolute workspace path
 	absWorkspace, err := filepath.Abs(workspace)
 	if err != nil {
@@ -54,6 +104,14 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
 	}
 
+	// Hold the workspace slot lock across allocation and launch, like coi shell,
+	// so concurrent runs cannot pick the same slot
+	slotLock, err := session.LockWorkspaceSlots(absWorkspace)
+	if err != nil {
+		return err
+	}
+	defer slotLock.Release() // Released once the container exists; this covers early returns
+
 	// Allocate slot if not specified
 	slotNum := slot
 	if slotNum == 0 {
@@ -118,8 +176,11 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// The container now holds its slot
+	slotLock.Release()
+
 	// Cleanup container on exit (only if ephemeral)
-	defer func() {
+	cleanup := func() {
 		if !persistent {
 			fmt.Fprintf(os.Stderr, "Cleaning up container %s...\n", containerName)
 			_ = mgr.Delete(true) // Best effort cleanup
@@ -127,10 +188,11 @@ func runCommand(cmd *cobra.Command, args []string) error {
 			// Only stop if container is running (avoids spurious error messages)
 			if running, _ := mgr.Running(); running {
 				fmt.Fprintf(os.Stderr, "Stopping persistent container %s...\n", containerName)
-				_ = mgr.Stop(false) // Best effort stop
+				_ = mgr.StopWithTimeout(configuredStopTimeout()) // Best effort stop
 			}
 		}
-	}()
+	}
+	defer cleanup()
 
 	// Apply resource limits (only for new containers, not restarted persistent ones)
 	wasRestarted := containerExists && persistent
@@ -174,10 +236,12 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	// Mount workspace (skip if restarting existing persistent container)
-	useShift := !cfg.Incus.DisableShift
+	useShift := session.ResolveUIDShift(cfg.Incus.DisableShift, forceShift, func(msg string) {
+		fmt.Fprintln(os.Stderr, msg)
+	})
 	if !wasRestarted {
 		fmt.Fprintf(os.Stderr, "Mounting workspace %s...\n", absWorkspace)
-		if err := mgr.MountDisk("workspace", absWorkspace, "/workspace", useShift); err != nil {
+		if err := mgr.MountDisk("workspace", absWorkspace, session.ContainerWorkspacePath, useShift); err != nil {
 			return fmt.Errorf("failed to mount workspace: %w", err)
 		}
 
@@ -211,25 +275,69 @@ func runCommand(cmd *cobra.Command, args []string) error {
 		fmt.Fprintf(os.Stderr, "Reusing existing workspace mount...\n")
 	}
 
-	// Execute command directly (args are already the full command to run)
-	fmt.Fprintf(os.Stderr, "Executing: %s\n", strings.Join(args, " "))
-
-	// Build incus exec command directly with proper args
-	incusArgs := []string{
-		"exec", containerName, "--user", fmt.Sprintf("%d", container.CodeUID),
-		"--group", fmt.Sprintf("%d", container.CodeUID), "--cwd", "/workspace",
+	if waitPort > 0 {
+		if err := waitForPort(mgr, waitPort, waitPortTimeout); err != nil {
+			if errors.Is(err, container.ErrPortTimeout) {
+				cleanup() // exitError skips deferred cleanup
+				return exitError(waitPortTimeoutExitCode, err.Error())
+			}
+			return err
+		}
 	}
 
-	// Add environment variables from -e flags
-	for _, e := range envVars {
-		incusArgs = append(incusArgs, "--env", e)
+	// Push the stdin script and run it instead of the (absent) command args
+	if scriptFile != "" {
+		if err := mgr.PushFile(scriptFile, runScriptPath); err != nil {
+			return fmt.Errorf("failed to push script: %w", err)
+		}
+		if err := mgr.Chown(runScriptPath, container.CodeUID, container.CodeUID); err != nil {
+			return fmt.Errorf("failed to set script ownership: %w", err)
+		}
+		args = scriptCommand(scriptFile)
+		fmt.Fprintf(os.Stderr, "Executing script from stdin\n")
+	} else {
+		// Execute command directly (args are already the full command to run)
+		fmt.Fprintf(os.Stderr, "Executing: %s\n", strings.Join(args, " "))
 	}
 
-	incusArgs = append(incusArgs, "--")
-	incusArgs = append(incusArgs, args...)
+	// A reused container keeps the mount path it was created with (--mount-at)
+	workDir := session.WorkspaceMountPath(mgr)
+
+	var output string
+	if runInteractive {
+		// Give the command a pseudo-terminal; its output goes straight to the terminal
+		user := container.CodeUID
+		err = mgr.ExecArgs(args, container.ExecCommandOptions{
+			User:        &user,
+			Cwd:         workDir,
+			Env:         container.EnvMap(envVars),
+			Interactive: true,
+		})
+	} else {
+		// Build incus exec command directly with proper args
+		incusArgs := []string{
+			"exec", containerName, "--user", fmt.Sprintf("%d", container.CodeUID),
+			"--group", fmt.Sprintf("%d", container.CodeUID), "--cwd", workDir,
+		}
+
+		// Add environment variables from -e flags
+		for _, e := range envVars {
+			incusArgs = append(incusArgs, "--env", e)
+		}
+
+		incusArgs = append(incusArgs, "--")
+		incusArgs = append(incusArgs, args...)
+
+		// Execute and capture output and exit code
+		output, err = container.IncusOutputWithArgs(incusArgs...)
+	}
 
-	// Execute and capture output and exit code
-	output, err := container.IncusOutputWithArgs(incusArgs...)
+	// Remove the script now - a failing command exits below without running defers
+	if scriptFile != "" {
+		// Best-effort: persistent containers keep their /tmp
+		_, _ = mgr.ExecCommand("rm -f "+runScriptPath, container.ExecCommandOptions{Capture: true})
+		removeScript()
+	}
 
 	// Print output to stdout (not stderr) so it can be captured
 	if output != "" {
@@ -239,9 +347,9 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	// Handle exit codes: if command ran but failed, exit with same code
 	if err != nil {
 		// Try to extract exit code from error message
-		if exitErr, ok := err.(*container.ExitError); ok {
-			fmt.Fprintf(os.Stderr, "\nCommand exited with code %d\n", exitErr.ExitCode)
-			os.Exit(exitErr.ExitCode)
+		if code, ok := container.CommandExitCode(err); ok {
+			fmt.Fprintf(os.Stderr, "\nCommand exited with code %d\n", code)
+			os.Exit(code)
 		}
 		// If we can't extract exit code, return error normally
 		return fmt.Errorf("command failed: %w", err)
@@ -251,6 +359,50 @@ func runCommand(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// writeStdinScript copies the script from r to an executable temp file.
+// The returned cleanup function removes the file.
+func writeStdinScript(r io.Reader) (string, func(), error) {
+	script, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read script from stdin: %w", err)
+	}
+	if strings.TrimSpace(string(script)) == "" {
+		return "", nil, fmt.Errorf("no script received on stdin")
+	}
+
+	file, err := os.CreateTemp("", "coi-run-*.sh")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp script: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(file.Name()) }
+
+	if _, err := file.Write(script); err != nil {
+		file.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write temp script: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to write temp script: %w", err)
+	}
+	// incus file push keeps the mode, so a #! script can be executed directly
+	if err := os.Chmod(file.Name(), 0o755); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to make temp script executable: %w", err)
+	}
+
+	return file.Name(), cleanup, nil
+}
+
+// scriptCommand returns the command that runs the pushed script:
+// directly if it has a #! line, otherwise with bash
+func scriptCommand(localScript string) []string {
+	if data, err := os.ReadFile(localScript); err == nil && strings.HasPrefix(string(data), "#!") {
+		return []string{runScriptPath}
+	}
+	return []string{"bash", runScriptPath}
+}
+
 // waitForContainer waits for container to be ready
 func waitForContainer(mgr *container.Manager, maxRetries int) error {
 	for i := 0; i < maxRetries; i++ {
@@ -273,6 +425,27 @@ func waitForContainer(mgr *container.Manager, maxRetries int) error {
 	return fmt.Errorf("container failed to become ready")
 }
 
+// waitPortTimeoutExitCode is the exit code when --wait-port times out (as timeout(1))
+const waitPortTimeoutExitCode = 124
+
+// addWaitPortFlags registers --wait-port and --wait-timeout on cmd
+func addWaitPortFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&waitPort, "wait-port", 0, "Wait until this port accepts connections inside the container")
+	cmd.Flags().DurationVar(&waitPortTimeout, "wait-timeout", 60*time.Second, fmt.Sprintf("How long --wait-port waits before giving up (exit code %d)", waitPortTimeoutExitCode))
+}
+
+// waitForPort blocks until port accepts connections inside the container (for
+// --wait-port). Timeouts wrap container.ErrPortTimeout; callers exit with
+// waitPortTimeoutExitCode.
+func waitForPort(mgr *container.Manager, port int, timeout time.Duration) error {
+	fmt.Fprintf(os.Stderr, "Waiting for port %d in %s (timeout %s)...\n", port, mgr.ContainerName, timeout)
+	if err := mgr.WaitForPort(port, timeout); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Port %d is accepting connections\n", port)
+	return nil
+}
+
 // hasAnyLimits checks if any limits are configured (used in run.go)
 func hasAnyLimits(cfg *config.LimitsConfig) bool {
 	if cfg == nil {
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/health"
+	"github.com/mensfeld/code-on-incus/internal/image"
+	"github.com/mensfeld/code-on-incus/internal/update"
+	"github.com/spf13/cobra"
+)
+
+// installCommand is the documented way to install or upgrade the coi binary
+const installCommand = "curl -fsSL https://raw.githubusercontent.com/mensfeld/code-on-incus/master/install.sh | bash"
+
+var (
+	selfUpdateCheck bool
+	selfUpdateImage bool
+)
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Check whether coi and the coi image are up to date",
+	Long: `Report whether a newer coi release exists and whether the local coi image
+is older than the recommended age, with the commands to update each.
+
+Nothing is downloaded. --check exits with status 1 when either is out of date,
+for scripts and cron jobs. --image rebuilds the coi image (coi build --force).
+
+The release lookup is a network call to [update] release_url, so it only runs
+when enabled in config:
+
+  [update]
+  enabled = true
+  # release_url = "https://api.github.com/repos/mensfeld/code-on-incus/releases/latest"
+  # max_image_age_days = 30
+
+Examples:
+  coi self-update           # Report only
+  coi self-update --check   # Report, exit 1 if anything is out of date
+  coi self-update --image   # Rebuild the coi image
+`,
+	Args: cobra.NoArgs,
+	RunE: selfUpdateCommand,
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateCheck, "check", false, "Exit with status 1 if coi or the coi image is out of date")
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateImage, "image", false, "Rebuild the coi image (coi build --force)")
+	selfUpdateCmd.MarkFlagsMutuallyExclusive("check", "image")
+}
+
+func selfUpdateCommand(cmd *cobra.Command, args []string) error {
+	binaryOutdated := checkRelease()
+	fmt.Println()
+
+	if selfUpdateImage {
+		return rebuildImage()
+	}
+	imageOutdated := checkImageFreshness()
+
+	if selfUpdateCheck && (binaryOutdated || imageOutdated) {
+		return exitError(1, "")
+	}
+	return nil
+}
+
+// checkRelease compares this binary with the latest release, when enabled.
+// Returns true if a newer release exists.
+func checkRelease() bool {
+	fmt.Printf("coi binary: v%s\n", Version)
+	if !cfg.Update.Enabled {
+		fmt.Println("  Release check disabled - set enabled = true in [update] to query for new releases")
+		return false
+	}
+
+	release, err := update.FetchLatest(context.Background(), cfg.Update.ReleaseURL)
+	if err != nil {
+		fmt.Printf("  Could not check for a new release: %v\n", err)
+		return false
+	}
+
+	cmp, ok := update.CompareVersions(Version, release.Version())
+	switch {
+	case !ok:
+		fmt.Printf("  Latest release is v%s (cannot compare with this build)\n", release.Version())
+	case cmp < 0:
+		fmt.Printf("  Update available: v%s\n", release.Version())
+		if release.URL != "" {
+			fmt.Printf("  Release notes: %s\n", release.URL)
+		}
+		fmt.Printf("  Update with: %s\n", installCommand)
+		return true
+	default:
+		fmt.Println("  Up to date")
+	}
+	return false
+}
+
+// checkImageFreshness reports the coi image age against [update] max_image_age_days.
+// Returns true if the image is missing or older than that.
+func checkImageFreshness() bool {
+	fmt.Printf("Image '%s':\n", image.CoiAlias)
+
+	createdAt, found, err := health.ImageCreatedAt(image.CoiAlias)
+	if err != nil {
+		fmt.Printf("  Could not get image info: %v\n", err)
+		return false
+	}
+	if !found {
+		fmt.Println("  Not built yet")
+		fmt.Println("  Build with: coi build")
+		return true
+	}
+
+	days := int(time.Since(createdAt).Hours() / 24)
+	if maxDays := cfg.Update.MaxImageAgeDays; maxDays > 0 && days > maxDays {
+		fmt.Printf("  %d days old (built %s, recommended rebuild after %d days)\n", days, createdAt.Format("2006-01-02"), maxDays)
+		fmt.Println("  Rebuild with: coi build --force (or coi self-update --image)")
+		return true
+	}
+	fmt.Printf("  %d days old (built %s) - up to date\n", days, createdAt.Format("2006-01-02"))
+	return false
+}
+
+// rebuildImage force-rebuilds the coi image
+func rebuildImage() error {
+	if !container.Available() {
+		return fmt.Errorf("incus is not available - please install Incus and ensure you're in the incus-admin group")
+	}
+
+	fmt.Printf("Rebuilding image '%s'...\n", image.CoiAlias)
+	result := image.NewBuilder(coiBuildOptions(true, func(msg string) {
+		fmt.Println(msg)
+	})).Build()
+	if result.Error != nil {
+		return fmt.Errorf("build failed: %w", result.Error)
+	}
+
+	fmt.Printf("\nImage '%s' rebuilt (%s)\n", image.CoiAlias, result.VersionAlias)
+	return nil
+}
@@ -1,16 +1,21 @@
 package cli
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
 	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/mensfeld/code-on-incus/internal/terminal"
 	"github.com/mensfeld/code-on-incus/internal/tool"
@@ -18,40 +23,171 @@ import (
 )
 
 var (
-	debugShell bool
-	background bool
-	useTmux    bool
+	debugShell       bool
+	background       bool
+	useTmux          bool
+	removeOnExit     bool
+	noSave           bool
+	sshAgent         bool
+	sandboxSet       []string
+	noInjectSettings bool
+	noCredentials    bool
+	proxyURL         string
+	labelPairs       []string
+	workDirFlag      string
+	mountAt          string
+	allowPresets     []string
+	detachKeys       string
+	initOnly         bool
+	autoBuild        bool
+	maxDuration      string
+	shellName        string
+	autoResume       bool
+	mountHome        bool
+	recordPath       string
+	strictResume     bool
+	pullImage        bool
+
+	envPassthrough []string
+	// passthroughEnv holds the host variables selected by --env-passthrough
+	passthroughEnv map[string]string
+
+	// imageCoiDerived marks --image as published from a coi container (set by coi clone)
+	imageCoiDerived bool
+
+	// sessionExitHook runs after cleanup when Ctrl+C ends the session, since
+	// os.Exit skips the caller's deferred functions (set by coi clone)
+	sessionExitHook func()
 )
 
 var shellCmd = &cobra.Command{
 	Use:   "shell",
 	Short: "Start an interactive AI coding session",
-	Long: `Start an interactive AI coding session in a container (always runs in tmux).
+	// Long is rendered by shellLongHelp for the configured tool
+	Long: `Start an interactive AI coding session in a container (runs in tmux by default).
 
-By default, runs Claude Code. Other tools can be configured via the tool.name config option.
+Runs {{.Tool}}, the tool set by the tool.name config option (supported:
+{{.Supported}}).
 
-All sessions run in tmux for monitoring and detach/reattach support:
+Sessions run in tmux for monitoring and detach/reattach support:
   - Interactive: Automatically attaches to tmux session
   - Background: Runs detached, use 'coi tmux capture' to view output
-  - Detach anytime: Ctrl+B d (session keeps running)
+  - Detach anytime: Ctrl+B d ({{.Name}} keeps running), see --detach-keys
   - Reattach: Run 'coi shell' again in same workspace
 
+With --tmux=false (or use_tmux = false in [defaults]) the tool runs directly
+attached to your terminal. There is no detach/reattach in this mode.
+
+Tmux options can be set in the [tmux] config section (mouse, scrollback,
+prefix, detach_keys). --detach-keys C-q binds a single key that detaches
+without the prefix.
+
+--sandbox-set key=value (repeatable) overrides a single sandbox setting that coi
+injects into the tool config for this session. Values are parsed as JSON
+(true, 3, {"a":1}); other values are used as strings. Dotted keys address
+nested settings.
+--no-inject-settings (or inject_settings = false in [tool]) copies the tool's
+settings.json and state file unchanged, for users who manage those themselves.
+--no-credentials copies no host credentials or tool config at all; {{.Name}} only
+gets the sandbox settings and has to log in inside the container (or fails),
+for verifying isolation without exposing real tokens.
+
+With --rm the container is always deleted when the session ends, however you
+exit (exit, detach or shutdown). Session data is still saved for --resume, but
+the container itself cannot be re-attached or reused.
+
+With --no-save nothing is written to the sessions directory: the tool's config
+and transcript are not saved on exit and no metadata is recorded, so the
+session cannot be resumed and does not show up in 'coi list --all'.
+
+With --init-only the container is created and configured (mounts, network,
+credentials) but the tool is not started. The container name is printed on
+stdout and the container keeps running; use 'coi attach --bash' or
+'coi container exec' to work in it, and 'coi kill' to remove it. Add
+--wait-port N to also wait until a service in the container accepts
+connections on port N (exit code 124 if it doesn't within --wait-timeout).
+
+With --record FILE the session's terminal output is saved as an asciicast v2
+recording (replay with 'asciinema play FILE'). Everything shown in the terminal
+is recorded, including any secrets printed or typed with echo on, so treat the
+file like a transcript. While recording, resizing the window is not passed on
+to the session.
+
+With --ssh-agent the host SSH agent ($SSH_AUTH_SOCK) is forwarded into the
+container so the tool can push over SSH. Security tradeoff: anything running in
+the container can then use every key loaded in your agent for the lifetime of
+the session (keys are not copied, but they can be used to sign). The forwarding
+is removed when the session ends. Consider a dedicated agent with only a deploy
+key loaded.
+
 Examples:
   coi shell                         # Interactive session in tmux
   coi shell --background            # Run in background (detached)
   coi shell --resume                # Resume latest session (auto)
   coi shell --resume=<session-id>   # Resume specific session (note: = is required)
   coi shell --continue=<session-id> # Same as --resume (alias)
+  coi shell --resume --strict-resume  # Fail rather than start fresh if the session can't be restored
+  coi shell --auto-resume           # Resume the latest session if saved within 7 days, else start fresh
   coi shell --slot 2                # Use specific slot
-  coi shell --debug                 # Launch bash for debugging
+  coi shell --name myenv --persistent  # Named container coi-myenv (coi attach myenv)
+  coi shell --debug                 # Launch bash instead of {{.Name}} for debugging
+  coi shell --tmux=false            # Run directly without tmux
+  coi shell --rm                    # Delete the container when the session ends
+  coi shell --rm --no-save          # Throwaway session: nothing kept afterwards
+  coi shell --ssh-agent             # Forward host SSH agent for git over SSH
+  coi shell --proxy http://proxy.corp:3128  # Route HTTP(S) through a proxy
+  coi shell --sandbox-set permissions.defaultMode=acceptEdits  # Override a sandbox setting
+  coi shell --record session.cast   # Record the session (asciinema play session.cast)
+  coi shell --label task=refactor   # Label the session (filter with coi list --label)
+  coi shell --cwd packages/api      # Start {{.Name}} in a workspace subdirectory
+  coi shell --image images:ubuntu/24.04 --pull-image  # Start from the latest published image
+  coi shell --init-only             # Provision the container without starting {{.Name}}
+  coi shell --network=allowlist --allow-preset node --allow-preset github  # Add preset domains
 `,
 	RunE: shellCommand,
 }
 
 func init() {
+	longTemplate := shellCmd.Long
+	shellCmd.Long = shellLongHelp(longTemplate, tool.GetDefault())
+	defaultHelp := shellCmd.HelpFunc()
+	shellCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
+		// Help runs without PersistentPreRunE, so read the configured tool here
+		// (without creating coi's directories just for printing help)
+		if loaded, err := config.Read(); err == nil {
+			if t, err := getConfiguredTool(loaded); err == nil {
+				cmd.Long = shellLongHelp(longTemplate, t)
+			}
+		}
+		defaultHelp(cmd, args)
+	})
+
 	shellCmd.Flags().BoolVar(&debugShell, "debug", false, "Launch interactive bash instead of AI tool (for debugging)")
 	shellCmd.Flags().BoolVar(&background, "background", false, "Run AI tool in background tmux session (detached)")
-	shellCmd.Flags().BoolVar(&useTmux, "tmux", true, "Use tmux for session management (default true)")
+	shellCmd.Flags().BoolVar(&useTmux, "tmux", true, "Use tmux for session management (default from config, true if unset)")
+	shellCmd.Flags().BoolVar(&removeOnExit, "rm", false, "Always delete the container when the session ends (even on normal exit or detach)")
+	shellCmd.Flags().BoolVar(&noSave, "no-save", false, "Don't save session data (tool config, transcript) - the session cannot be resumed")
+	shellCmd.Flags().BoolVar(&sshAgent, "ssh-agent", false, "Forward the host SSH agent ($SSH_AUTH_SOCK) into the container")
+	shellCmd.Flags().StringVar(&proxyURL, "proxy", "", "HTTP(S) proxy URL for the container (overrides [network] proxy)")
+	shellCmd.Flags().StringArrayVar(&sandboxSet, "sandbox-set", []string{}, "Override a tool sandbox setting for this session (key=value, value parsed as JSON, repeatable)")
+	shellCmd.Flags().BoolVar(&noInjectSettings, "no-inject-settings", false, "Copy the tool's settings files unchanged instead of merging coi's sandbox settings (or inject_settings = false in [tool])")
+	shellCmd.Flags().BoolVar(&noCredentials, "no-credentials", false, "Give the tool no host credentials or config, only the sandbox settings (for testing the sandbox unauthenticated)")
+	shellCmd.Flags().StringArrayVar(&labelPairs, "label", []string{}, "Label the session container (key=value, repeatable)")
+	shellCmd.Flags().StringArrayVar(&allowPresets, "allow-preset", []string{}, "Add a named domain preset to the allowlist (built-in: anthropic, github, node, python; repeatable)")
+	shellCmd.Flags().StringVar(&detachKeys, "detach-keys", "", "Key that detaches from the tmux session without the prefix, e.g. C-q (overrides [tmux] detach_keys)")
+	shellCmd.Flags().BoolVar(&initOnly, "init-only", false, "Create and configure the container, print its name and exit without starting the tool")
+	shellCmd.Flags().StringVar(&maxDuration, "max-duration", "", "Tear down the session after this wall-clock time (e.g. 30m), even after coi exits (--background)")
+	shellCmd.Flags().BoolVar(&pullImage, "pull-image", false, "Fetch the latest version of a remote --image (e.g. images:ubuntu/24.04) before launching instead of using the cached one")
+	shellCmd.Flags().BoolVar(&autoBuild, "build", false, "Build the coi image first if it does not exist (or set auto_build = true in [defaults])")
+	shellCmd.Flags().StringArrayVar(&envPassthrough, "env-passthrough", []string{}, "Forward host env vars matching a glob, e.g. 'GIT_*' (repeatable; secret-looking names need an exact pattern)")
+	shellCmd.Flags().StringVar(&workDirFlag, "cwd", "", "Start the tool in this directory, relative to the workspace (e.g. packages/api)")
+	shellCmd.Flags().StringVar(&mountAt, "mount-at", "", "Mount the workspace at this absolute path instead of /workspace (e.g. its host path)")
+	shellCmd.Flags().BoolVar(&mountHome, "mount-home", false, "Mount a persistent per-workspace cache directory (~/.coi/caches/<hash>) at ~/.cache in new containers (or mount_home = true in [defaults])")
+	shellCmd.Flags().BoolVar(&autoResume, "auto-resume", false, "Resume this workspace's latest session (saved within 7 days) when --resume is not given (or auto_resume = true in [defaults])")
+	shellCmd.Flags().BoolVar(&strictResume, "strict-resume", false, "Fail instead of starting a fresh conversation when the resumed session's state is missing or can't be restored")
+	shellCmd.Flags().StringVar(&recordPath, "record", "", "Record the session's terminal output to this file (asciicast v2, includes anything shown such as secrets)")
+	shellCmd.Flags().StringVar(&shellName, "name", "", "Use the named container coi-<name> instead of a workspace slot (attach with 'coi attach <name>')")
+	addWaitPortFlags(shellCmd)
 }
 
 func shellCommand(cmd *cobra.Command, args []string) error {
@@ -60,6 +196,75 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unexpected argument '%s' - did you mean --resume=%s? (note: use = when specifying session ID)", args[0], args[0])
 	}
 
+	// Apply config default for tmux unless --tmux was explicitly set
+	if !cmd.Flags().Changed("tmux") && cfg.Defaults.UseTmux != nil {
+		useTmux = *cfg.Defaults.UseTmux
+	}
+
+	// Validate tmux key bindings before they are written into tmux.conf
+	if cmd.Flags().Changed("detach-keys") {
+		cfg.Tmux.DetachKeys = detachKeys
+	}
+	for _, key := range []string{cfg.Tmux.Prefix, cfg.Tmux.DetachKeys} {
+		if key == "" {
+			continue
+		}
+		if err := config.ValidateTmuxKey(key); err != nil {
+			return err
+		}
+	}
+
+	// Background sessions need tmux to keep running detached
+	if background && !useTmux {
+		return fmt.Errorf("--background requires tmux (drop --tmux=false or set use_tmux = true)")
+	}
+
+	// Named containers replace the workspace slot scheme
+	if shellName != "" {
+		if cmd.Flags().Changed("slot") {
+			return fmt.Errorf("--name cannot be combined with --slot")
+		}
+		if _, err := session.NamedContainerName(shellName); err != nil {
+			return err
+		}
+	}
+
+	// --rm deletes the container on exit, which conflicts with keeping it around
+	if removeOnExit {
+		if cmd.Flags().Changed("persistent") && persistent {
+			return fmt.Errorf("--rm cannot be combined with --persistent")
+		}
+		if background {
+			return fmt.Errorf("--rm cannot be combined with --background (the container would be deleted immediately)")
+		}
+		if initOnly {
+			return fmt.Errorf("--rm cannot be combined with --init-only (the container is left running for later use)")
+		}
+	}
+	if recordPath != "" && (background || initOnly) {
+		return fmt.Errorf("--record cannot be combined with --background or --init-only (there is no terminal output to record)")
+	}
+	if waitPort > 0 && !initOnly {
+		return fmt.Errorf("--wait-port requires --init-only")
+	}
+	if !slices.Contains(config.CleanupPolicies(), cfg.Defaults.CleanupPolicy) {
+		return fmt.Errorf("invalid cleanup_policy '%s' in [defaults] - must be 'keep', 'delete' or 'ask'", cfg.Defaults.CleanupPolicy)
+	}
+	if cfg.Defaults.DeleteGraceMinutes < 0 {
+		return fmt.Errorf("invalid delete_grace_minutes %d in [defaults] - must be 0 or more", cfg.Defaults.DeleteGraceMinutes)
+	}
+	deleteExpiredContainers()
+
+	// Resolve the host SSH agent socket before doing any container work
+	var sshAgentSocket string
+	if sshAgent {
+		var err error
+		sshAgentSocket, err = hostSSHAgentSocket()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Get absolute workspace path
 	absWorkspace, err := filepath.Abs(workspace)
 	if err != nil {
@@ -77,6 +282,44 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// Parse --sandbox-set overrides before doing any container work
+	sandboxOverrides, err := session.ParseSandboxOverrides(sandboxSet)
+	if err != nil {
+		return err
+	}
+	warnUnknownSandboxKeys(toolInstance, sandboxOverrides)
+
+	skipSettings := noInjectSettings || (cfg.Tool.InjectSettings != nil && !*cfg.Tool.InjectSettings)
+	if skipSettings && len(sandboxOverrides) > 0 {
+		return fmt.Errorf("--sandbox-set has no effect when sandbox settings are not injected (--no-inject-settings or inject_settings = false)")
+	}
+
+	labels, err := session.ParseLabels(labelPairs)
+	if err != nil {
+		return err
+	}
+
+	if _, err := session.LocaleEnv(cfg.Defaults.Locale); err != nil {
+		return fmt.Errorf("[defaults] locale: %w", err)
+	}
+
+	if err := session.ValidateEnvPatterns(envPassthrough); err != nil {
+		return err
+	}
+	var blockedEnv []string
+	passthroughEnv, blockedEnv = session.PassthroughEnv(os.Environ(), envPassthrough)
+	if len(blockedEnv) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: not forwarding secret-looking variables %s - pass each name exactly to --env-passthrough to include it\n", strings.Join(blockedEnv, ", "))
+	}
+
+	var sessionMaxDuration time.Duration
+	if maxDuration != "" {
+		sessionMaxDuration, err = time.ParseDuration(maxDuration)
+		if err != nil || sessionMaxDuration <= 0 {
+			return fmt.Errorf("invalid --max-duration %q (expected e.g. 30m or 2h)", maxDuration)
+		}
+	}
+
 	// Get sessions directory (tool-specific: sessions-claude, sessions-aider, etc.)
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -97,27 +340,71 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	// Check if resume/continue flag was explicitly set
 	resumeFlagSet := cmd.Flags().Changed("resume") || cmd.Flags().Changed("continue")
 
+	// With auto-resume, a plain coi shell continues the workspace's recent session.
+	// Checks resumeID rather than the flags: coi pick sets resume without them.
+	if !cmd.Flags().Changed("auto-resume") {
+		autoResume = cfg.Defaults.AutoResume
+	}
+	if autoResume && resumeID == "" && shellName == "" {
+		if recentID, savedAt, ok := session.RecentSessionForWorkspace(sessionsDir, absWorkspace, session.AutoResumeMaxAge, time.Now()); ok {
+			resumeID = recentID
+			fmt.Fprintf(os.Stderr, "Auto-resume: this workspace's latest session was saved %s (--auto-resume=false starts fresh)\n", session.FormatAge(savedAt, time.Now()))
+		}
+	}
+
 	// Auto-detect if flag was set but value is empty or "auto"
 	if resumeFlagSet && (resumeID == "" || resumeID == "auto") {
 		// Auto-detect latest for workspace (only looks at sessions from the same workspace)
 		resumeID, err = session.GetLatestSessionForWorkspace(sessionsDir, absWorkspace)
 		if err != nil {
-			return fmt.Errorf("no previous session to resume for this workspace: %w", err)
+			return fmt.Errorf("no previous session to resume for this workspace: %w (if the workspace was moved, resume by ID: coi shell --resume=<session-id>)", err)
 		}
 		fmt.Fprintf(os.Stderr, "Auto-detected session: %s\n", resumeID)
 	} else if resumeID != "" {
 		// Validate that the explicitly provided session exists
-		if !session.SessionExists(sessionsDir, resumeID) {
-			return fmt.Errorf("session '%s' not found - check available sessions with: coi list --all", resumeID)
+		if !session.SessionExists(sessionsDir, resumeID, toolInstance.ConfigDirName()) {
+			// The session may belong to another tool (tool.name changed since it was saved)
+			owner, found := session.FindSessionTool(baseDir, resumeID)
+			if !found || owner.Tool == toolInstance.Name() {
+				return fmt.Errorf("session '%s' not found - check available sessions with: coi list --all --all-tools", resumeID)
+			}
+			ownerTool, err := tool.Get(owner.Tool)
+			if err != nil {
+				return fmt.Errorf("session '%s' belongs to tool '%s' (%s), which this version of coi does not support", resumeID, owner.Tool, owner.Dir)
+			}
+			if !session.SessionExists(owner.Dir, resumeID, ownerTool.ConfigDirName()) {
+				return fmt.Errorf("session '%s' of tool '%s' has no saved state to resume", resumeID, owner.Tool)
+			}
+			fmt.Fprintf(os.Stderr, "Session %s belongs to tool '%s' (configured: '%s') - resuming with %s\n", resumeID, owner.Tool, toolInstance.Name(), owner.Tool)
+			toolInstance = ownerTool
+			sessionsDir = owner.Dir
 		}
 		fmt.Fprintf(os.Stderr, "Resuming session: %s\n", resumeID)
 	}
 
-	// When resuming, inherit persistent flag from the original session
-	// unless it was explicitly overridden by the user
+	// When resuming, inherit persistent flag and network settings from the
+	// original session unless they were explicitly overridden by the user
+	var resumedMetadata *session.SessionMetadata
 	if resumeID != "" {
 		metadataPath := filepath.Join(sessionsDir, resumeID, "metadata.json")
-		if metadata, err := session.LoadSessionMetadata(metadataPath); err == nil {
+		metadata, err := session.LoadSessionMetadata(metadataPath)
+		if strictResume {
+			// Check the saved state before creating a container for it
+			if err != nil {
+				return fmt.Errorf("cannot resume session %s (--strict-resume): %w", resumeID, err)
+			}
+			if _, err := discoverResumeSessionID(toolInstance, sessionsDir, resumeID); err != nil {
+				return err
+			}
+		}
+		if err == nil {
+			// Detect a moved/renamed workspace and decide which directory to mount
+			absWorkspace, err = resolveResumeWorkspace(cmd, metadata.Workspace, absWorkspace)
+			if err != nil {
+				return err
+			}
+			resumedMetadata = metadata
+
 			// Inherit persistent flag if not explicitly set by user
 			if !cmd.Flags().Changed("persistent") {
 				persistent = metadata.Persistent
@@ -128,58 +415,146 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// --rm overrides persistent mode from config or the resumed session
+	if removeOnExit {
+		if persistent {
+			fmt.Fprintf(os.Stderr, "Ignoring persistent mode because --rm was given\n")
+			persistent = false
+		}
+		fmt.Fprintf(os.Stderr, "Container will be deleted when the session ends (--rm) - it cannot be re-attached\n")
+	}
+
+	// cleanup_policy covers sessions you exit or detach from; --rm is "delete"
+	// for one run, and background sessions are meant to keep running
+	cleanupPolicy := cfg.Defaults.CleanupPolicy
+	if removeOnExit {
+		cleanupPolicy = config.CleanupDelete
+	} else if background {
+		cleanupPolicy = config.CleanupKeep
+	}
+	if noSave {
+		fmt.Fprintf(os.Stderr, "Session data will not be saved (--no-save) - this session cannot be resumed\n")
+	}
+
+	// Resolve where the workspace is mounted (--mount-at, else where the resumed
+	// session had it, since the tool keeps transcripts per directory)
+	mountPath := session.ContainerWorkspacePath
+	if resumedMetadata != nil && resumedMetadata.MountPath != "" {
+		mountPath = resumedMetadata.MountPath
+	}
+	if cmd.Flags().Changed("mount-at") {
+		if err := session.ValidateMountPath(mountAt); err != nil {
+			return err
+		}
+		if resumedMetadata != nil && filepath.Clean(mountAt) != mountPath {
+			fmt.Fprintf(os.Stderr, "Warning: session %s was started with the workspace at %s - the tool may not find its conversation at %s\n", resumeID, mountPath, filepath.Clean(mountAt))
+		}
+		mountPath = filepath.Clean(mountAt)
+	}
+
+	// Resolve the container working directory (--cwd, else the tool's default)
+	relWorkDir := toolInstance.WorkingDir()
+	if cmd.Flags().Changed("cwd") {
+		relWorkDir = workDirFlag
+	}
+	workDir, err := session.ResolveWorkDirAt(mountPath, relWorkDir)
+	if err != nil {
+		return err
+	}
+	if workDir != mountPath {
+		hostDir := filepath.Join(absWorkspace, strings.TrimPrefix(workDir, mountPath+"/"))
+		if info, err := os.Stat(hostDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("working directory %s does not exist in the workspace", hostDir)
+		}
+	}
+
 	// Generate or use session ID
 	var sessionID string
 	if resumeID != "" {
 		sessionID = resumeID // Reuse the same session ID when resuming
 	} else {
-		sessionID, err = session.GenerateSessionID()
+		generate := session.GenerateSessionID
+		if cfg.Defaults.FriendlySessionIDs {
+			generate = session.GenerateFriendlySessionID
+		}
+		sessionID, err = session.UniqueSessionID(sessionsDir, generate)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Allocate slot - always check for availability and auto-increment if needed
-	slotNum := slot
-	if slotNum == 0 {
-		// No slot specified, find first available
-		slotNum, err = session.AllocateSlot(absWorkspace, 10)
-		if err != nil {
-			return fmt.Errorf("failed to allocate slot: %w", err)
+	// Build the coi image on first run when opted in (before taking the slot lock,
+	// since a build takes minutes)
+	if autoBuild || cfg.Defaults.AutoBuild {
+		if err := ensureImageBuilt(imageName); err != nil {
+			return err
 		}
-		fmt.Fprintf(os.Stderr, "Auto-allocated slot %d\n", slotNum)
-	} else {
-		// Slot specified, but check if it's available
-		// If not, find next available slot starting from the specified one
-		available, err := session.IsSlotAvailable(absWorkspace, slotNum)
+	}
+
+	// Named containers (--name) are one per name and skip slot allocation
+	var slotLock *session.SlotLock
+	var slotNum int
+	if shellName == "" {
+		// Hold the workspace slot lock across allocation and container creation so
+		// concurrent coi shell runs cannot pick the same slot
+		slotLock, err = session.LockWorkspaceSlots(absWorkspace)
 		if err != nil {
-			return fmt.Errorf("failed to check slot availability: %w", err)
+			return err
 		}
+		defer slotLock.Release() // Setup releases it once the container runs; this covers early returns
 
-		if !available {
-			// Slot is occupied, find next available starting from slot+1
-			originalSlot := slotNum
-			slotNum, err = session.AllocateSlotFrom(absWorkspace, slotNum+1, 10)
-			if err != nil {
-				return fmt.Errorf("slot %d is occupied and failed to find next available slot: %w", originalSlot, err)
-			}
-			fmt.Fprintf(os.Stderr, "Slot %d is occupied, using slot %d instead\n", originalSlot, slotNum)
+		if slotNum, err = allocateShellSlot(absWorkspace, slot); err != nil {
+			return err
 		}
 	}
 
 	// Prepare network configuration
 	networkConfig := cfg.Network // Copy from loaded config
-	// Override network mode from flag if specified
+	// Override network mode from flag if specified, else inherit it when resuming
 	if networkMode != "" {
 		networkConfig.Mode = config.NetworkMode(networkMode)
+	} else if resumedMetadata != nil && resumedMetadata.NetworkMode != "" {
+		networkConfig.Mode = config.NetworkMode(resumedMetadata.NetworkMode)
+		if len(resumedMetadata.AllowedDomains) > 0 {
+			networkConfig.AllowedDomains = resumedMetadata.AllowedDomains
+		}
+		if networkConfig.Mode != cfg.Network.Mode {
+			fmt.Fprintf(os.Stderr, "Inherited network mode from session: %s\n", networkConfig.Mode)
+		}
+	}
+	if proxyURL != "" {
+		networkConfig.Proxy = proxyURL
+	}
+	if networkConfig.Proxy != "" {
+		if _, err := network.ProxyHost(networkConfig.Proxy); err != nil {
+			return err
+		}
+	}
+	if len(allowPresets) > 0 {
+		networkConfig, err = networkConfig.WithAllowlistPresets(allowPresets)
+		if err != nil {
+			return err
+		}
+		if networkConfig.Mode != config.NetworkModeAllowlist {
+			fmt.Fprintf(os.Stderr, "Warning: --allow-preset only applies in allowlist mode (current mode: %s)\n", networkConfig.Mode)
+		}
 	}
 
 	// Determine CLI config path based on tool
 	// For ENV-based tools (ConfigDirName returns ""), this will be empty
 	var cliConfigPath string
 	configDirName := toolInstance.ConfigDirName()
+	credentialSource, err := session.ParseCredentialSource(cfg.Tool.CredentialSource)
+	if err != nil {
+		return err
+	}
 	if configDirName != "" {
 		cliConfigPath = filepath.Join(homeDir, configDirName)
+
+		// Expired host credentials would be copied in and fail auth inside the container
+		if !noCredentials && !credentialSource.Keyring && !toolInstance.CredentialsValid(cliConfigPath) {
+			fmt.Fprintf(os.Stderr, "Warning: your host credentials in %s look expired - run '%s' on the host to log in again first, or the session may fail to authenticate\n", cliConfigPath, toolInstance.Binary())
+		}
 	}
 
 	// Merge limits configuration from config file and CLI flags
@@ -187,18 +562,50 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 
 	// Setup session
 	setupOpts := session.SetupOptions{
-		WorkspacePath: absWorkspace,
-		Image:         imageName,
-		Persistent:    persistent,
-		ResumeFromID:  resumeID,
-		Slot:          slotNum,
-		SessionsDir:   sessionsDir,
-		CLIConfigPath: cliConfigPath,
-		Tool:          toolInstance,
-		NetworkConfig: &networkConfig,
-		DisableShift:  cfg.Incus.DisableShift,
-		LimitsConfig:  limitsConfig,
-		IncusProject:  cfg.Incus.Project,
+		WorkspacePath:    absWorkspace,
+		MountPath:        mountPath,
+		Image:            imageName,
+		Persistent:       persistent,
+		ResumeFromID:     resumeID,
+		StrictResume:     strictResume,
+		Slot:             slotNum,
+		Name:             shellName,
+		SessionsDir:      sessionsDir,
+		CLIConfigPath:    cliConfigPath,
+		CredentialSource: credentialSource,
+		Tool:             toolInstance,
+		NetworkConfig:    &networkConfig,
+		DisableShift:     cfg.Incus.DisableShift,
+		ForceShift:       forceShift,
+		LimitsConfig:     limitsConfig,
+		IncusProject:     cfg.Incus.Project,
+		SSHAgentSocket:   sshAgentSocket,
+		CoiDerived:       imageCoiDerived,
+		PullImage:        pullImage,
+		SandboxOverrides: sandboxOverrides,
+		SkipSettings:     skipSettings,
+		NoCredentials:    noCredentials,
+		Labels:           labels,
+		SlotLock:         slotLock,
+	}
+
+	setupOpts.ReadyProbe = cfg.Defaults.ReadyProbe
+	setupOpts.CheckClockDrift = cfg.Defaults.CheckClockDrift
+
+	if cfg.Defaults.SyncTimezone {
+		setupOpts.Timezone = session.HostTimezone()
+		if setupOpts.Timezone == "" {
+			fmt.Fprintf(os.Stderr, "Warning: sync_timezone is set but the host timezone could not be determined (set TZ)\n")
+		}
+	}
+
+	if cfg.Defaults.SyncGitIdentity {
+		identity := session.HostGitIdentity()
+		if identity.Empty() {
+			fmt.Fprintf(os.Stderr, "Warning: sync_git_identity is set but no global git user.name/user.email was found on the host\n")
+		} else {
+			setupOpts.GitIdentity = &identity
+		}
 	}
 
 	// Parse and validate mount configuration
@@ -214,6 +621,14 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 
 	setupOpts.MountConfig = mountConfig
 
+	// Tool caches survive ephemeral containers when the workspace has a persistent cache
+	if !cmd.Flags().Changed("mount-home") {
+		mountHome = cfg.Defaults.MountHome
+	}
+	if mountHome {
+		setupOpts.HomeCacheDir = session.HomeCacheDir(baseDir, absWorkspace)
+	}
+
 	fmt.Fprintf(os.Stderr, "Setting up session %s...\n", sessionID)
 	result, err := session.Setup(setupOpts)
 	if err != nil {
@@ -221,48 +636,168 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	}
 
 	// Save metadata early so coi list shows correct persistent/ephemeral status
-	if err := session.SaveMetadataEarly(sessionsDir, sessionID, result.ContainerName, absWorkspace, persistent); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: Failed to save early metadata: %v\n", err)
+	earlyMetadata := session.SessionMetadata{
+		SessionID:     sessionID,
+		ContainerName: result.ContainerName,
+		Persistent:    persistent,
+		Workspace:     absWorkspace,
+		NetworkMode:   string(networkConfig.Mode),
+		// Keep keyring credentials out of the saved session state
+		KeyringCredentials: credentialSource.Keyring && configDirName != "",
+	}
+	if mountPath != session.ContainerWorkspacePath {
+		earlyMetadata.MountPath = mountPath
+	}
+	if networkConfig.Mode == config.NetworkModeAllowlist {
+		earlyMetadata.AllowedDomains = networkConfig.AllowedDomains
+	}
+	earlyMetadata.HomeCacheDir = setupOpts.HomeCacheDir
+	if !noSave {
+		if err := session.SaveMetadataEarly(sessionsDir, earlyMetadata); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to save early metadata: %v\n", err)
+		}
 	}
 
-	// Setup cleanup on exit
-	defer func() {
-		fmt.Fprintf(os.Stderr, "\nCleaning up session...\n")
-
-		// Stop timeout monitor if it was started
-		if result.TimeoutMonitor != nil {
-			result.TimeoutMonitor.Stop()
+	// --max-duration: persist the deadline on the container and hand it to a detached
+	// reaper, so the limit holds after coi exits (background sessions, detach)
+	if sessionMaxDuration > 0 {
+		deadline := time.Now().Add(sessionMaxDuration)
+		if err := session.SetDeadline(result.Manager, deadline); err != nil {
+			removeEarlyMetadata(sessionsDir, sessionID)
+			return fmt.Errorf("failed to set session deadline: %w", err)
+		}
+		reaperSessionID := sessionID
+		if noSave {
+			reaperSessionID = "" // The reaper then tears down without saving
+		}
+		if err := startReaper(result.ContainerName, reaperSessionID, absWorkspace, deadline); err != nil {
+			removeEarlyMetadata(sessionsDir, sessionID)
+			return err
 		}
+		fmt.Fprintf(os.Stderr, "Session will be torn down at %s (--max-duration %s)\n", deadline.Format("15:04:05"), sessionMaxDuration)
+	} else {
+		_ = session.ClearDeadline(result.Manager) // Drop a deadline left by an earlier run on a reused container
+	}
 
-		cleanupOpts := session.CleanupOptions{
-			ContainerName:  result.ContainerName,
-			SessionID:      sessionID,
-			Persistent:     persistent,
-			SessionsDir:    sessionsDir,
-			SaveSession:    true, // Always save session data
-			Workspace:      absWorkspace,
-			Tool:           toolInstance,
-			NetworkManager: result.NetworkManager,
+	// --init-only: leave the provisioned container running, skip the tool and cleanup
+	if initOnly {
+		if waitPort > 0 {
+			if err := waitForPort(result.Manager, waitPort, waitPortTimeout); err != nil {
+				if errors.Is(err, container.ErrPortTimeout) {
+					return exitError(waitPortTimeoutExitCode, err.Error())
+				}
+				return err
+			}
 		}
-		if err := session.Cleanup(cleanupOpts); err != nil {
-			fmt.Fprintf(os.Stderr, "Cleanup error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Container %s is ready (tool not started)\n", result.ContainerName)
+		fmt.Fprintf(os.Stderr, "  Shell:  coi attach %s --bash\n", result.ContainerName)
+		fmt.Fprintf(os.Stderr, "  Remove: coi kill %s\n", result.ContainerName)
+		if result.TimeoutMonitor != nil {
+			fmt.Fprintf(os.Stderr, "Note: max_duration is not enforced after coi exits\n")
 		}
-	}()
+		fmt.Println(result.ContainerName)
+		return nil
+	}
 
-	// Handle Ctrl+C gracefully
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+
+	sessionStart := time.Now()
+	var oomMonitor *session.OOMMonitor
+
+	// Cleanup runs exactly once: when the session returns, or from the signal
+	// handler, which has to call it itself since os.Exit skips deferred functions
+	var cleanupOnce sync.Once
+	cleanupSession := func(err error) {
+		cleanupOnce.Do(func() {
+			fmt.Fprintf(os.Stderr, "\nCleaning up session...\n")
+
+			// Check for OOM kills before the container (and its counter) may be deleted
+			var stopReason string
+			if oomMonitor != nil {
+				if kills := oomMonitor.Stop(); kills > 0 {
+					stopReason = session.OOMStopReason(kills, containerMemoryLimit(result.ContainerName))
+				}
+			}
+
+			// Record metrics before cleanup, while the container (and its image fingerprint) still exists
+			if cfg.Defaults.Metrics {
+				recordSessionMetric(session.MetricRecord{
+					SessionID:        sessionID,
+					Tool:             toolInstance.Name(),
+					NetworkMode:      string(networkConfig.Mode),
+					StartedAt:        sessionStart,
+					DurationSeconds:  time.Since(sessionStart).Seconds(),
+					ExitReason:       sessionExitReason(err, background),
+					Resumed:          resumeID != "",
+					ImageFingerprint: containerImageFingerprint(result.ContainerName),
+				})
+			}
+
+			// During cleanup, Ctrl+C cancels saving session data instead of killing coi mid-transfer
+			signal.Stop(sigChan)
+			cleanupCtx, stopCleanupSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stopCleanupSignals()
+
+			// Stop timeout monitor if it was started
+			if result.TimeoutMonitor != nil {
+				result.TimeoutMonitor.Stop()
+			}
+
+			cleanupOpts := session.CleanupOptions{
+				ContainerName:  result.ContainerName,
+				SessionID:      sessionID,
+				Persistent:     persistent,
+				Policy:         cleanupPolicy,
+				SessionsDir:    sessionsDir,
+				SaveSession:    !noSave,
+				Workspace:      absWorkspace,
+				Tool:           toolInstance,
+				NetworkManager: result.NetworkManager,
+				Context:        cleanupCtx,
+				StopReason:     stopReason,
+				DeleteGrace:    time.Duration(cfg.Defaults.DeleteGraceMinutes) * time.Minute,
+				ScheduleDelete: func(deleteAfter time.Time) error {
+					return startDeleteReaper(result.ContainerName, absWorkspace, deleteAfter)
+				},
+			}
+			// A --max-duration reaper may be tearing the container down right now
+			cleanupLock, lockErr := session.LockContainerCleanup(result.ContainerName)
+			if lockErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", lockErr)
+			}
+			defer cleanupLock.Release()
+
+			if err := session.Cleanup(cleanupOpts); err != nil {
+				fmt.Fprintf(os.Stderr, "Cleanup error: %v\n", err)
+			}
+		})
+	}
+	defer func() { cleanupSession(err) }()
+
+	// Handle Ctrl+C gracefully
 	go func() {
 		<-sigChan
 		fmt.Fprintf(os.Stderr, "\nReceived interrupt signal, cleaning up...\n")
-		os.Exit(0) // Defer will run
+		cleanupSession(errSessionInterrupted)
+		if sessionExitHook != nil {
+			sessionExitHook()
+		}
+		os.Exit(0)
 	}()
 
 	// Run CLI tool
 	fmt.Fprintf(os.Stderr, "\nStarting session...\n")
+	fmt.Fprintf(os.Stderr, "Tool: %s\n", toolInstance.Description())
 	fmt.Fprintf(os.Stderr, "Session ID: %s\n", sessionID)
 	fmt.Fprintf(os.Stderr, "Container: %s\n", result.ContainerName)
 	fmt.Fprintf(os.Stderr, "Workspace: %s\n", absWorkspace)
+	if workDir != session.ContainerWorkspacePath {
+		fmt.Fprintf(os.Stderr, "Working directory: %s\n", workDir)
+	}
+
+	// Watch for OOM kills so a tool killed for lack of memory isn't reported as a normal exit
+	oomMonitor = session.StartOOMMonitor(result.Manager)
 
 	// Determine resume mode
 	// The difference is:
@@ -274,6 +809,14 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	useResumeFlag := (resumeID != "") && persistent
 	restoreOnly := (resumeID != "") && !persistent
 
+	if recordPath != "" {
+		activeRecording, err = startRecording(recordPath, "coi shell "+result.ContainerName)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Recording session to %s\n", recordPath)
+	}
+
 	// Choose execution mode
 	if useTmux {
 		if background {
@@ -287,7 +830,7 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "Resume mode: Persistent session\n")
 		}
 		fmt.Fprintf(os.Stderr, "\n")
-		err = runCLIInTmux(result, sessionID, background, useResumeFlag, restoreOnly, sessionsDir, resumeID, toolInstance)
+		err = runCLIInTmux(result, sessionID, background, useResumeFlag, restoreOnly, sessionsDir, resumeID, workDir, toolInstance)
 	} else {
 		fmt.Fprintf(os.Stderr, "Mode: Direct (no tmux)\n")
 		if restoreOnly {
@@ -296,7 +839,16 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 			fmt.Fprintf(os.Stderr, "Resume mode: Persistent session\n")
 		}
 		fmt.Fprintf(os.Stderr, "\n")
-		err = runCLI(result, sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, toolInstance)
+		err = runCLI(result, sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, workDir, toolInstance)
+	}
+
+	if activeRecording != nil {
+		if closeErr := activeRecording.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", closeErr)
+		} else {
+			fmt.Fprintf(os.Stderr, "Session recorded to %s\n", recordPath)
+		}
+		activeRecording = nil
 	}
 
 	// Handle expected exit conditions gracefully
@@ -320,6 +872,130 @@ func shellCommand(cmd *cobra.Command, args []string) error {
 	return err
 }
 
+// removeEarlyMetadata drops the session directory saved at launch when the
+// session fails to start, so it doesn't linger as an empty saved session
+func removeEarlyMetadata(sessionsDir, sessionID string) {
+	if noSave {
+		return
+	}
+	if err := session.RemoveEarlyMetadata(sessionsDir, sessionID); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to remove metadata of failed session: %v\n", err)
+	}
+}
+
+// errSessionInterrupted is the exit error of a session ended by a signal to coi
+var errSessionInterrupted = errors.New("interrupted by signal")
+
+// sessionExitReason classifies how a session ended for metrics, mirroring the
+// expected exit conditions handled after the tool returns
+func sessionExitReason(err error, background bool) string {
+	if errors.Is(err, errSessionInterrupted) {
+		return session.ExitReasonInterrupted
+	}
+	if err == nil {
+		if background {
+			return session.ExitReasonDetached
+		}
+		return session.ExitReasonExited
+	}
+
+	errStr := err.Error()
+	switch {
+	case errStr == "exit status 130":
+		return session.ExitReasonInterrupted
+	case strings.Contains(errStr, "Failed to retrieve PID"),
+		strings.Contains(errStr, "server exited"),
+		strings.Contains(errStr, "connection reset"):
+		return session.ExitReasonShutdown
+	default:
+		return session.ExitReasonError
+	}
+}
+
+// containerImageFingerprint returns the fingerprint of the image a container was
+// created from, or "" if it cannot be determined
+func containerImageFingerprint(containerName string) string {
+	fingerprint, err := container.IncusOutput("config", "get", containerName, "volatile.base_image")
+	if err != nil {
+		return ""
+	}
+	return fingerprint
+}
+
+// containerMemoryLimit returns the container's limits.memory, or "" if unlimited
+// or it cannot be determined
+func containerMemoryLimit(containerName string) string {
+	limit, err := container.IncusOutput("config", "get", containerName, "limits.memory")
+	if err != nil {
+		return ""
+	}
+	return limit
+}
+
+// recordSessionMetric appends a session record to ~/.coi/metrics.jsonl.
+// Metrics are best-effort: failures only produce a warning.
+func recordSessionMetric(record session.MetricRecord) {
+	path, err := session.MetricsPath()
+	if err == nil {
+		err = session.AppendMetric(path, record)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to record session metrics: %v\n", err)
+	}
+}
+
+// resolveResumeWorkspace decides which host directory to mount when resuming.
+// The conversation lives in the saved session data (always under /workspace in
+// the container), so a session can be re-pointed to a moved workspace.
+func resolveResumeWorkspace(cmd *cobra.Command, original, current string) (string, error) {
+	// Explicit --workspace always wins
+	if cmd.Flags().Changed("workspace") {
+		if session.CheckSessionWorkspace(original, current) != session.WorkspaceSame {
+			fmt.Fprintf(os.Stderr, "Re-pointing session workspace: %s -> %s\n", original, current)
+		}
+		return current, nil
+	}
+
+	switch session.CheckSessionWorkspace(original, current) {
+	case session.WorkspaceMissing:
+		fmt.Fprintf(os.Stderr, "Warning: Session workspace %s no longer exists (moved or renamed?)\n", original)
+		if !confirmAction(fmt.Sprintf("Mount current directory %s instead and keep the conversation?", current)) {
+			return "", fmt.Errorf("session workspace %s not found - use --workspace <new-path> to re-point it", original)
+		}
+		fmt.Fprintf(os.Stderr, "Re-pointing session workspace: %s -> %s\n", original, current)
+	case session.WorkspaceDifferent:
+		fmt.Fprintf(os.Stderr, "Warning: Session was started in %s, mounting %s instead (use --workspace to choose)\n", original, current)
+	}
+
+	return current, nil
+}
+
+// recordLaunchCommand saves the tool command line, environment and working
+// directory in the session metadata for coi session info and coi attach
+// --relaunch (skipped with --no-save, which records no metadata)
+func recordLaunchCommand(sessionsDir, sessionID string, cmd []string, env map[string]string, cwd string) {
+	if noSave || debugShell {
+		return
+	}
+	if err := session.RecordLaunchCommand(sessionsDir, sessionID, container.ShellJoin(cmd), env, cwd); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to record launch command: %v\n", err)
+	}
+}
+
+// addLocaleEnv sets LANG/LC_ALL from [defaults] locale (user --env can still override)
+func addLocaleEnv(containerEnv map[string]string) {
+	env, _ := session.LocaleEnv(cfg.Defaults.Locale) // Validated in shellCommand
+	for k, v := range env {
+		containerEnv[k] = v
+	}
+}
+
+// addPassthroughEnv adds host variables selected by --env-passthrough, keeping
+// the variables coi set (explicit --env values still override them)
+func addPassthroughEnv(containerEnv map[string]string) {
+	session.AddPassthroughEnv(containerEnv, passthroughEnv)
+}
+
 // getEnvValue checks for an env var in --env flags first, then os.Getenv
 func getEnvValue(key string) string {
 	// Check --env flags first
@@ -333,6 +1009,15 @@ func getEnvValue(key string) string {
 	return os.Getenv(key)
 }
 
+// shellLongHelp fills the tool placeholders of the shell command help text
+func shellLongHelp(text string, t tool.Tool) string {
+	return strings.NewReplacer(
+		"{{.Tool}}", t.Description(),
+		"{{.Name}}", t.Name(),
+		"{{.Supported}}", strings.Join(tool.ListSupported(), ", "),
+	).Replace(text)
+}
+
 // getConfiguredTool returns the tool to use based on config
 func getConfiguredTool(cfg *config.Config) (tool.Tool, error) {
 	toolName := cfg.Tool.Name
@@ -349,42 +1034,34 @@ func getConfiguredTool(cfg *config.Config) (tool.Tool, error) {
 }
 
 // runCLI executes the CLI tool in the container interactively
-func runCLI(result *session.SetupResult, sessionID string, useResumeFlag, restoreOnly bool, sessionsDir, resumeID string, t tool.Tool) error {
+// The tool is exec'd directly (no tmux server, no wrapper shell)
+func runCLI(result *session.SetupResult, sessionID string, useResumeFlag, restoreOnly bool, sessionsDir, resumeID, workDir string, t tool.Tool) error {
 	// Build command - either bash for debugging or CLI tool
-	var cmdToRun string
+	var cmdToRun []string
 	if debugShell {
 		// Debug mode: launch interactive bash
-		cmdToRun = "bash"
+		cmdToRun = []string{"bash"}
 	} else {
 		// Determine resume mode and CLI session ID
 		var cliSessionID string
 		if useResumeFlag || restoreOnly {
-			// Try to discover the tool's internal session ID from saved state
-			// The exact discovery mechanism is tool-specific (e.g. some tools read
-			// config files, others use environment variables) and may return ""
-			// if no previous session can be found (start fresh).
-			var sessionStatePath string
-			if configDir := t.ConfigDirName(); configDir != "" {
-				sessionStatePath = filepath.Join(sessionsDir, resumeID, configDir)
-			} else {
-				sessionStatePath = filepath.Join(sessionsDir, resumeID)
+			var err error
+			if cliSessionID, err = discoverResumeSessionID(t, sessionsDir, resumeID); err != nil {
+				return err
 			}
-			cliSessionID = t.DiscoverSessionID(sessionStatePath)
 		}
 
 		// Build command using tool abstraction
 		// This handles tool-specific flags (--verbose, --permission-mode, etc.)
-		cmd := t.BuildCommand(sessionID, useResumeFlag || restoreOnly, cliSessionID)
+		cmdToRun = t.BuildCommand(sessionID, useResumeFlag || restoreOnly, cliSessionID)
 
 		// Handle dummy mode override (for testing)
 		if getEnvValue("COI_USE_DUMMY") == "1" {
-			if len(cmd) > 0 {
-				cmd[0] = "dummy"
+			if len(cmdToRun) > 0 {
+				cmdToRun[0] = "dummy"
 			}
 			fmt.Fprintf(os.Stderr, "Using dummy (test stub) for faster testing\n")
 		}
-
-		cmdToRun = strings.Join(cmd, " ")
 	}
 
 	// Execute in container
@@ -402,6 +1079,17 @@ func runCLI(result *session.SetupResult, sessionID string, useResumeFlag, restor
 		"IS_SANDBOX": "1",                                      // Always set sandbox mode
 	}
 
+	addLocaleEnv(containerEnv)
+
+	// Proxy settings (HTTP_PROXY, HTTPS_PROXY, NO_PROXY) - user --env can still override
+	if result.NetworkManager != nil {
+		for k, v := range result.NetworkManager.ProxyEnv() {
+			containerEnv[k] = v
+		}
+	}
+
+	addPassthroughEnv(containerEnv)
+
 	// Merge user-provided --env vars
 	for _, e := range envVars {
 		parts := strings.SplitN(e, "=", 2)
@@ -415,23 +1103,124 @@ func runCLI(result *session.SetupResult, sessionID string, useResumeFlag, restor
 		containerEnv["TERM"] = terminal.SanitizeTerm(userTerm)
 	}
 
+	// Point SSH clients at the forwarded agent socket
+	if sshAgent {
+		containerEnv["SSH_AUTH_SOCK"] = session.SSHAgentContainerSocket
+	}
+	recordLaunchCommand(sessionsDir, sessionID, cmdToRun, containerEnv, workDir)
+
 	opts := container.ExecCommandOptions{
 		User:        userPtr,
-		Cwd:         "/workspace",
+		Cwd:         workDir,
 		Env:         containerEnv,
 		Interactive: true, // Attach stdin/stdout/stderr for interactive session
 	}
+	cmdToRun = recordArgs(cmdToRun, &opts)
 
-	_, err := result.Manager.ExecCommand(cmdToRun, opts)
-	return err
+	return result.Manager.ExecArgs(cmdToRun, opts)
+}
+
+// discoverResumeSessionID finds the tool's internal session ID in the saved
+// state of resumeID. The discovery mechanism is tool-specific and may find
+// nothing, in which case the tool starts fresh - unless --strict-resume is
+// set, which turns that into an error.
+func discoverResumeSessionID(t tool.Tool, sessionsDir, resumeID string) (string, error) {
+	sessionStatePath := filepath.Join(sessionsDir, resumeID)
+	if configDir := t.ConfigDirName(); configDir != "" {
+		sessionStatePath = filepath.Join(sessionStatePath, configDir)
+	}
+
+	cliSessionID, err := t.DiscoverSessionID(sessionStatePath)
+	if strictResume {
+		if err != nil {
+			return "", fmt.Errorf("cannot resume session %s (--strict-resume): %w", resumeID, err)
+		}
+		if cliSessionID == "" {
+			return "", fmt.Errorf("cannot resume session %s (--strict-resume): no %s conversation found in its saved state", resumeID, t.Name())
+		}
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read %s's saved state for session %s: %v\n", t.Name(), resumeID, err)
+	}
+	return cliSessionID, nil
+}
+
+// buildTmuxConf generates tmux.conf contents from the [tmux] config section
+// Returns "" when nothing is configured so the image's defaults are kept
+func buildTmuxConf(tmuxCfg config.TmuxConfig) string {
+	var lines []string
+	if tmuxCfg.Mouse {
+		lines = append(lines, "set -g mouse on")
+	}
+	if tmuxCfg.Scrollback > 0 {
+		lines = append(lines, fmt.Sprintf("set -g history-limit %d", tmuxCfg.Scrollback))
+	}
+	if tmuxCfg.Prefix != "" {
+		lines = append(lines,
+			fmt.Sprintf("set -g prefix %s", tmuxCfg.Prefix),
+			"unbind C-b",
+			fmt.Sprintf("bind %s send-prefix", tmuxCfg.Prefix),
+		)
+	}
+	if tmuxCfg.DetachKeys != "" {
+		lines = append(lines, fmt.Sprintf("bind -n %s detach-client", tmuxCfg.DetachKeys))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "# Generated by coi from the [tmux] config section\n" + strings.Join(lines, "\n") + "\n"
+}
+
+// tmuxDetachHint describes how to detach given the [tmux] config, e.g. "C-b d"
+func tmuxDetachHint(tmuxCfg config.TmuxConfig) string {
+	if tmuxCfg.DetachKeys != "" {
+		return tmuxCfg.DetachKeys
+	}
+	prefix := tmuxCfg.Prefix
+	if prefix == "" {
+		prefix = "C-b"
+	}
+	return prefix + " d"
+}
+
+// writeTmuxConf pushes the generated tmux.conf into the container home directory
+// Must run before the tmux server starts, since tmux only reads it at startup
+func writeTmuxConf(result *session.SetupResult, user int) error {
+	if cfg == nil {
+		return nil
+	}
+	content := buildTmuxConf(cfg.Tmux)
+	if content == "" {
+		return nil
+	}
+
+	confPath := filepath.Join(result.HomeDir, ".tmux.conf")
+	if err := result.Manager.CreateFile(confPath, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", confPath, err)
+	}
+	if user != 0 {
+		if err := result.Manager.Chown(confPath, user, user); err != nil {
+			return fmt.Errorf("failed to chown %s: %w", confPath, err)
+		}
+	}
+	return nil
 }
 
 // runCLIInTmux executes CLI tool in a tmux session for background/monitoring support
-func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool, useResumeFlag, restoreOnly bool, sessionsDir, resumeID string, t tool.Tool) error {
+func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool, useResumeFlag, restoreOnly bool, sessionsDir, resumeID, workDir string, t tool.Tool) error {
+	// Custom images may not ship tmux - don't wait for a server that can never start
+	if !imageHasTmux(result.Manager) {
+		if detached {
+			return fmt.Errorf("this image has no tmux, which --background requires - rebuild the image with tmux or run interactively with --tmux=false")
+		}
+		fmt.Fprintf(os.Stderr, "Warning: this image has no tmux - running directly (no detach/reattach). Use --tmux=false to skip this check or rebuild the image with tmux.\n")
+		return runCLI(result, sessionID, useResumeFlag, restoreOnly, sessionsDir, resumeID, workDir, t)
+	}
+
 	tmuxSessionName := fmt.Sprintf("coi-%s", result.ContainerName)
 
 	// Build CLI command
 	var cliCmd string
+	var cmd []string
 	if debugShell {
 		// Debug mode: launch interactive bash
 		cliCmd = "bash"
@@ -439,22 +1228,15 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		// Determine resume mode and CLI session ID
 		var cliSessionID string
 		if useResumeFlag || restoreOnly {
-			// Try to discover the tool's internal session ID from saved state
-			// The exact discovery mechanism is tool-specific (e.g. some tools read
-			// config files, others use environment variables) and may return ""
-			// if no previous session can be found (start fresh).
-			var sessionStatePath string
-			if configDir := t.ConfigDirName(); configDir != "" {
-				sessionStatePath = filepath.Join(sessionsDir, resumeID, configDir)
-			} else {
-				sessionStatePath = filepath.Join(sessionsDir, resumeID)
+			var err error
+			if cliSessionID, err = discoverResumeSessionID(t, sessionsDir, resumeID); err != nil {
+				return err
 			}
-			cliSessionID = t.DiscoverSessionID(sessionStatePath)
 		}
 
 		// Build command using tool abstraction
 		// This handles tool-specific flags (--verbose, --permission-mode, etc.)
-		cmd := t.BuildCommand(sessionID, useResumeFlag || restoreOnly, cliSessionID)
+		cmd = t.BuildCommand(sessionID, useResumeFlag || restoreOnly, cliSessionID)
 
 		// Handle dummy mode override (for testing)
 		if getEnvValue("COI_USE_DUMMY") == "1" {
@@ -463,8 +1245,7 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 			}
 			fmt.Fprintf(os.Stderr, "Using dummy (test stub) for faster testing\n")
 		}
-
-		cliCmd = strings.Join(cmd, " ")
+		cliCmd = container.ShellJoin(cmd)
 	}
 
 	// Build environment variables
@@ -483,6 +1264,17 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		"IS_SANDBOX": "1", // Always set sandbox mode
 	}
 
+	addLocaleEnv(containerEnv)
+
+	// Proxy settings (HTTP_PROXY, HTTPS_PROXY, NO_PROXY) - user --env can still override
+	if result.NetworkManager != nil {
+		for k, v := range result.NetworkManager.ProxyEnv() {
+			containerEnv[k] = v
+		}
+	}
+
+	addPassthroughEnv(containerEnv)
+
 	// Merge user-provided --env vars
 	for _, e := range envVars {
 		parts := strings.SplitN(e, "=", 2)
@@ -496,10 +1288,15 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		containerEnv["TERM"] = terminal.SanitizeTerm(userTerm)
 	}
 
-	// Build environment export commands for tmux
-	envExports := ""
-	for k, v := range containerEnv {
-		envExports += fmt.Sprintf("export %s=%q; ", k, v)
+	// Point SSH clients at the forwarded agent socket
+	if sshAgent {
+		containerEnv["SSH_AUTH_SOCK"] = session.SSHAgentContainerSocket
+	}
+	recordLaunchCommand(sessionsDir, sessionID, cmd, containerEnv, workDir)
+
+	// Write tmux.conf before the server starts so the options take effect
+	if err := writeTmuxConf(result, user); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to apply tmux config: %v\n", err)
 	}
 
 	// Ensure tmux server is running first (critical for CI and new containers)
@@ -532,7 +1329,7 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		// Session exists - attach or send command
 		if detached {
 			// Send command to existing session
-			sendCmd := fmt.Sprintf("tmux send-keys -t %s %q Enter", tmuxSessionName, cliCmd)
+			sendCmd := container.ShellJoin([]string{"tmux", "send-keys", "-t", tmuxSessionName, cliCmd, "Enter"})
 			_, err := result.Manager.ExecCommand(sendCmd, container.ExecCommandOptions{
 				Capture: true,
 				User:    userPtr,
@@ -546,29 +1343,26 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		} else {
 			// Attach to existing session
 			fmt.Fprintf(os.Stderr, "Attaching to existing tmux session: %s\n", tmuxSessionName)
+			fmt.Fprintf(os.Stderr, "Detach with %s (%s keeps running)\n", tmuxDetachHint(cfg.Tmux), t.Name())
 			attachCmd := fmt.Sprintf("tmux attach -t %s", tmuxSessionName)
 			opts := container.ExecCommandOptions{
 				User:        userPtr,
-				Cwd:         "/workspace",
+				Cwd:         workDir,
 				Interactive: true,
 			}
+			attachCmd = recordCommand(attachCmd, &opts)
 			_, err := result.Manager.ExecCommand(attachCmd, opts)
 			return err
 		}
 	}
 
 	// Create new tmux session
-	// When claude exits, fall back to bash so user can still interact
+	// When the tool exits, fall back to bash so user can still interact
 	// User can then: exit (leaves container running), Ctrl+b d (detach), or sudo shutdown 0 (stop)
-	// Use trap to prevent bash from exiting on SIGINT while allowing Ctrl+C to work in claude
+	// Use trap to prevent bash from exiting on SIGINT while allowing Ctrl+C to work in the tool
 	if detached {
 		// Background mode: create detached session
-		createCmd := fmt.Sprintf(
-			"tmux new-session -d -s %s -c /workspace \"bash -c 'trap : INT; %s %s; exec bash'\"",
-			tmuxSessionName,
-			envExports,
-			cliCmd,
-		)
+		createCmd := session.TmuxNewSessionCommand(tmuxSessionName, workDir, containerEnv, cliCmd)
 		opts := container.ExecCommandOptions{
 			Capture: true,
 			User:    userPtr,
@@ -578,9 +1372,9 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 			return fmt.Errorf("failed to create tmux session: %w", err)
 		}
 
-		fmt.Fprintf(os.Stderr, "Created background tmux session: %s\n", tmuxSessionName)
-		fmt.Fprintf(os.Stderr, "Use 'coi tmux capture %s' to view output\n", result.ContainerName)
-		fmt.Fprintf(os.Stderr, "Use 'coi tmux send %s \"<command>\"' to send commands\n", result.ContainerName)
+		fmt.Fprintf(os.Stderr, "Created background tmux session: %s (running %s)\n", tmuxSessionName, t.Description())
+		fmt.Fprintf(os.Stderr, "Use 'coi tmux capture %s' to view %s's output\n", result.ContainerName, t.Name())
+		fmt.Fprintf(os.Stderr, "Use 'coi tmux send %s \"<command>\"' to send input to %s\n", result.ContainerName, t.Name())
 		return nil
 	} else {
 		// Interactive mode: create detached session, then attach
@@ -624,15 +1418,10 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 
 		// Step 2: Create detached session if it doesn't exist
 		if checkErr != nil {
-			createCmd := fmt.Sprintf(
-				"tmux new-session -d -s %s -c /workspace \"bash -c 'trap : INT; %s %s; exec bash'\"",
-				tmuxSessionName,
-				envExports,
-				cliCmd,
-			)
+			createCmd := session.TmuxNewSessionCommand(tmuxSessionName, workDir, containerEnv, cliCmd)
 			createOpts := container.ExecCommandOptions{
 				User:    userPtr,
-				Cwd:     "/workspace",
+				Cwd:     workDir,
 				Capture: true,
 			}
 			if _, err := result.Manager.ExecCommand(createCmd, createOpts); err != nil {
@@ -644,14 +1433,87 @@ func runCLIInTmux(result *session.SetupResult, sessionID string, detached bool,
 		}
 
 		// Step 3: Attach to the session
+		fmt.Fprintf(os.Stderr, "Detach with %s (%s keeps running)\n", tmuxDetachHint(cfg.Tmux), t.Name())
 		attachCmd := fmt.Sprintf("tmux attach -t %s", tmuxSessionName)
 		attachOpts := container.ExecCommandOptions{
 			User:        userPtr,
-			Cwd:         "/workspace",
+			Cwd:         workDir,
 			Interactive: true,
 			Env:         containerEnv,
 		}
+		attachCmd = recordCommand(attachCmd, &attachOpts)
 		_, err := result.Manager.ExecCommand(attachCmd, attachOpts)
 		return err
 	}
 }
+
+// allocateShellSlot picks the workspace slot for a new session: the first free
+// one, or the requested slot (moving on to the next free one if it is taken).
+// The caller holds the workspace slot lock.
+func allocateShellSlot(absWorkspace string, slot int) (int, error) {
+	if slot == 0 {
+		// No slot specified, find first available
+		slotNum, err := session.AllocateSlot(absWorkspace, 10)
+		if err != nil {
+			return 0, fmt.Errorf("failed to allocate slot: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Auto-allocated slot %d\n", slotNum)
+		return slotNum, nil
+	}
+
+	// Slot specified, but check if it's available
+	// If not, find next available slot starting from the specified one
+	available, err := session.IsSlotAvailable(absWorkspace, slot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check slot availability: %w", err)
+	}
+	if available {
+		return slot, nil
+	}
+
+	// Slot is occupied, find next available starting from slot+1
+	slotNum, err := session.AllocateSlotFrom(absWorkspace, slot+1, 10)
+	if err != nil {
+		return 0, fmt.Errorf("slot %d is occupied and failed to find next available slot: %w", slot, err)
+	}
+	fmt.Fprintf(os.Stderr, "Slot %d is occupied, using slot %d instead\n", slot, slotNum)
+	return slotNum, nil
+}
+
+// imageHasTmux reports whether tmux is installed in the container
+func imageHasTmux(mgr *container.Manager) bool {
+	_, err := mgr.ExecCommand("command -v tmux >/dev/null 2>&1", container.ExecCommandOptions{Capture: true})
+	return err == nil
+}
+
+// hostSSHAgentSocket returns the host SSH agent socket from $SSH_AUTH_SOCK
+func hostSSHAgentSocket() (string, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return "", fmt.Errorf("--ssh-agent requires a running SSH agent (SSH_AUTH_SOCK is not set)")
+	}
+	info, err := os.Stat(socket)
+	if err != nil {
+		return "", fmt.Errorf("SSH agent socket %s is not accessible: %w", socket, err)
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return "", fmt.Errorf("SSH_AUTH_SOCK %s is not a unix socket", socket)
+	}
+	absSocket, err := filepath.Abs(socket)
+	if err != nil {
+		return "", fmt.Errorf("invalid SSH_AUTH_SOCK path: %w", err)
+	}
+	return absSocket, nil
+}
+
+// warnUnknownSandboxKeys warns about --sandbox-set keys the tool does not declare.
+// Unknown keys are still merged as-is, since tools accept settings coi doesn't know about.
+func warnUnknownSandboxKeys(t tool.Tool, overrides map[string]interface{}) {
+	known := t.GetSandboxSettings()
+	for key := range overrides {
+		topLevel := strings.SplitN(key, ".", 2)[0]
+		if _, ok := known[topLevel]; !ok {
+			fmt.Fprintf(os.Stderr, "Warning: sandbox setting '%s' is not a default %s setting, merging as-is\n", key, t.Name())
+		}
+	}
+}
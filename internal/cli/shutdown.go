@@ -20,13 +20,13 @@ var shutdownCmd = &cobra.Command{
 	Short: "Gracefully stop and delete containers",
 	Long: `Gracefully stop and delete one or more containers by name.
 
-This attempts a graceful shutdown first, waiting for the timeout before
-force-killing if necessary.
+This attempts a graceful shutdown first, waiting for --timeout (default:
+[defaults] stop_timeout_seconds) before force-killing if necessary.
 
 Use 'coi list' to see active containers.
 
 Examples:
-  coi shutdown claude-abc12345-1             # Graceful shutdown (60s timeout)
+  coi shutdown claude-abc12345-1             # Graceful shutdown (configured timeout)
   coi shutdown --timeout=30 claude-abc12345-1  # 30 second timeout
   coi shutdown --all                         # Shutdown all containers
   coi shutdown --all --force                 # Shutdown all without confirmation
@@ -35,7 +35,7 @@ Examples:
 }
 
 func init() {
-	shutdownCmd.Flags().IntVar(&shutdownTimeout, "timeout", 60, "Timeout in seconds to wait for graceful shutdown before force-killing")
+	shutdownCmd.Flags().IntVar(&shutdownTimeout, "timeout", 0, "Timeout in seconds to wait for graceful shutdown before force-killing (default: [defaults] stop_timeout_seconds)")
 	shutdownCmd.Flags().BoolVar(&shutdownForce, "force", false, "Skip confirmation prompts")
 	shutdownCmd.Flags().BoolVar(&shutdownAll, "all", false, "Shutdown all containers")
 	rootCmd.AddCommand(shutdownCmd)
@@ -96,10 +96,15 @@ func shutdownCommand(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	timeout := configuredStopTimeout()
+	if shutdownTimeout > 0 {
+		timeout = time.Duration(shutdownTimeout) * time.Second
+	}
+
 	// Shutdown each container
 	shutdown := 0
 	for _, name := range containerNames {
-		fmt.Printf("Shutting down container %s (timeout: %ds)...\n", name, shutdownTimeout)
+		fmt.Printf("Shutting down container %s (timeout: %s)...\n", name, timeout)
 		mgr := container.NewManager(name)
 
 		// Check if container is running
@@ -110,31 +115,12 @@ func shutdownCommand(cmd *cobra.Command, args []string) error {
 		}
 
 		if running {
-			// First attempt graceful stop
+			// Graceful stop, force-killed once the timeout has passed
 			fmt.Printf("  Attempting graceful shutdown...\n")
-			gracefulDone := make(chan error, 1)
-			go func() {
-				gracefulDone <- mgr.Stop(false) // graceful stop
-			}()
-
-			// Wait for graceful stop or timeout
-			select {
-			case err := <-gracefulDone:
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "  Warning: Graceful stop failed: %v\n", err)
-				} else {
-					fmt.Printf("  Graceful shutdown successful\n")
-				}
-			case <-time.After(time.Duration(shutdownTimeout) * time.Second):
-				// Check if container stopped during timeout (avoids spurious errors)
-				if stillRunning, _ := mgr.Running(); stillRunning {
-					fmt.Printf("  Timeout reached, force-killing...\n")
-					if err := mgr.Stop(true); err != nil {
-						fmt.Fprintf(os.Stderr, "  Warning: Force stop failed: %v\n", err)
-					}
-				} else {
-					fmt.Printf("  Container stopped during timeout\n")
-				}
+			if err := mgr.StopWithTimeout(timeout); err != nil {
+				fmt.Fprintf(os.Stderr, "  Warning: Stop failed: %v\n", err)
+			} else {
+				fmt.Printf("  Container stopped\n")
 			}
 		}
 
@@ -44,6 +44,9 @@ var (
 	snapshotStateful  bool
 	snapshotForce     bool
 	snapshotAll       bool
+
+	snapshotBaseline      bool
+	snapshotBaselinePaths []string
 )
 
 // snapshotCreateCmd creates a new snapshot
@@ -59,6 +62,11 @@ Examples:
   coi snapshot create checkpoint-1        # Named snapshot
   coi snapshot create --stateful live     # Include process memory state
   coi snapshot create -c coi-abc-1 backup # Specific container
+  coi snapshot create base --baseline     # Also record a file listing for 'coi diff'
+
+With --baseline, a listing of files (under --baseline-path, default /etc /usr
+/opt /var /root /home /tmp) and installed packages is stored in the running
+container, so 'coi diff' can later show what changed outside the workspace.
 `,
 	Args: cobra.MaximumNArgs(1),
 	RunE: snapshotCreateCommand,
@@ -132,6 +140,8 @@ func init() {
 	// Add flags to create command
 	snapshotCreateCmd.Flags().StringVarP(&snapshotContainer, "container", "c", "", "Container name (default: auto-detect from workspace)")
 	snapshotCreateCmd.Flags().BoolVar(&snapshotStateful, "stateful", false, "Include process memory state in snapshot")
+	snapshotCreateCmd.Flags().BoolVar(&snapshotBaseline, "baseline", false, "Record a file and package listing for 'coi diff' (container must be running)")
+	snapshotCreateCmd.Flags().StringSliceVar(&snapshotBaselinePaths, "baseline-path", container.DefaultBaselinePaths, "Directories the --baseline listing covers (repeatable)")
 
 	// Add flags to list command
 	snapshotListCmd.Flags().StringVarP(&snapshotContainer, "container", "c", "", "Container name (default: auto-detect from workspace)")
@@ -266,6 +276,25 @@ func snapshotCreateCommand(cmd *cobra.Command, args []string) error {
 		return exitError(1, fmt.Sprintf("snapshot '%s' already exists for container '%s'", snapshotName, containerName))
 	}
 
+	// Record the baseline first, so it matches the snapshot
+	if snapshotBaseline {
+		running, err := mgr.Running()
+		if err != nil {
+			return exitError(1, fmt.Sprintf("failed to check container status: %v", err))
+		}
+		if !running {
+			return exitError(1, fmt.Sprintf("container '%s' must be running to record a baseline", containerName))
+		}
+		fmt.Fprintf(os.Stderr, "Recording filesystem baseline...\n")
+		baselineDir, err := containerBaselineDir(containerName)
+		if err != nil {
+			return exitError(1, err.Error())
+		}
+		if err := mgr.RecordBaseline(baselineDir, snapshotName, snapshotBaselinePaths); err != nil {
+			return exitError(1, fmt.Sprintf("failed to record baseline: %v", err))
+		}
+	}
+
 	// Create snapshot
 	if err := mgr.CreateSnapshot(snapshotName, snapshotStateful); err != nil {
 		return exitError(1, fmt.Sprintf("failed to create snapshot: %v", err))
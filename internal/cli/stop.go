@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/mensfeld/code-on-incus/internal/tool"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stopAll     bool
+	stopForce   bool
+	stopTimeout int
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop [container-name...]",
+	Short: "Stop containers without deleting them",
+	Long: `Stop one or more containers by name, or all coi containers with --all.
+
+Each container's network isolation is torn down and its session data is saved
+(for --resume) before a graceful stop; containers still running after --timeout
+(default: [defaults] stop_timeout_seconds) are force-stopped. Stopped containers keep their filesystem - persistent
+containers are restarted by the next 'coi shell', and 'coi delete --all --stopped'
+removes them for good.
+
+Use 'coi list --running' to see running containers.
+
+Examples:
+  coi stop coi-abc12345-1        # Stop one container
+  coi stop --all                 # Stop every running coi container
+  coi stop --all --force         # Without confirmation
+`,
+	RunE: stopCommand,
+}
+
+func init() {
+	stopCmd.Flags().BoolVar(&stopAll, "all", false, "Stop all running containers")
+	stopCmd.Flags().BoolVar(&stopForce, "force", false, "Skip confirmation prompts")
+	stopCmd.Flags().IntVar(&stopTimeout, "timeout", 0, "Seconds to wait for a graceful stop before force-stopping (default: [defaults] stop_timeout_seconds)")
+}
+
+func stopCommand(cmd *cobra.Command, args []string) error {
+	containerNames, err := selectBulkContainers(args, stopAll, stopForce, "Stop", func(c ContainerInfo) bool {
+		return isRunningStatus(c.Status)
+	})
+	if err != nil || containerNames == nil {
+		return err
+	}
+
+	bulk, err := newBulkSessionSaver()
+	if err != nil {
+		return err
+	}
+
+	timeout := configuredStopTimeout()
+	if stopTimeout > 0 {
+		timeout = time.Duration(stopTimeout) * time.Second
+	}
+
+	stopped := 0
+	for _, name := range containerNames {
+		fmt.Printf("Stopping container %s...\n", name)
+		mgr := container.NewManager(name)
+
+		running, err := mgr.Running()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: Failed to check status of %s: %v\n", name, err)
+			continue
+		}
+		if !running {
+			fmt.Printf("  %s is already stopped\n", name)
+			continue
+		}
+
+		if err := stopSessionContainer(mgr, timeout); err != nil {
+			fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+			continue
+		}
+		bulk.save(mgr)
+
+		stopped++
+		fmt.Printf("  ✓ Stopped %s\n", name)
+	}
+
+	if stopped > 0 {
+		fmt.Printf("\nStopped %d container(s)\n", stopped)
+	} else {
+		fmt.Println("\nNo containers were stopped")
+		if !stopAll {
+			// User specified containers but none were stopped - this is an error
+			return fmt.Errorf("failed to stop specified containers")
+		}
+	}
+
+	return nil
+}
+
+// isRunningStatus reports whether an Incus container status means it is running
+func isRunningStatus(status string) bool {
+	return status == "Running" || status == "RUNNING"
+}
+
+// isStoppedStatus reports whether an Incus container status means it is stopped
+func isStoppedStatus(status string) bool {
+	return status == "Stopped" || status == "STOPPED"
+}
+
+// selectBulkContainers resolves the containers a bulk command operates on:
+// the named ones, or with all every coi container matching keep. Returns nil
+// names (and no error) when there is nothing to do or the user declined.
+func selectBulkContainers(args []string, all, force bool, verb string, keep func(ContainerInfo) bool) ([]string, error) {
+	if !all {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("no container names provided - use 'coi list' to see containers, or --all")
+		}
+
+		// Confirm unless --force
+		if !force && len(args) > 1 {
+			fmt.Printf("%s %d container(s)? [y/N]: ", verb, len(args))
+			var response string
+			_, _ = fmt.Scanln(&response)
+			if response != "y" && response != "Y" {
+				fmt.Println("Cancelled.")
+				return nil, nil
+			}
+		}
+		return args, nil
+	}
+
+	if len(args) > 0 {
+		return nil, fmt.Errorf("--all cannot be combined with container names")
+	}
+
+	containers, err := listActiveContainers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var names []string
+	for _, c := range containers {
+		if keep(c) {
+			names = append(names, c.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		fmt.Println("No matching containers")
+		return nil, nil
+	}
+
+	fmt.Printf("Found %d container(s):\n", len(names))
+	for _, name := range names {
+		fmt.Printf("  - %s\n", name)
+	}
+
+	// Confirm unless --force
+	if !force {
+		fmt.Printf("\n%s all these containers? [y/N]: ", verb)
+		var response string
+		_, _ = fmt.Scanln(&response)
+		if response != "y" && response != "Y" {
+			fmt.Println("Cancelled.")
+			return nil, nil
+		}
+	}
+
+	return names, nil
+}
+
+// stopSessionContainer removes a running container's network isolation and
+// stops it, gracefully first and forcibly once timeout has passed
+func stopSessionContainer(mgr *container.Manager, timeout time.Duration) error {
+	// Firewall rules are keyed by container IP, so remove them while it still has one
+	if err := network.TeardownContainer(mgr.ContainerName); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: Network teardown failed: %v\n", err)
+	}
+
+	if err := mgr.StopWithTimeout(timeout); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", mgr.ContainerName, err)
+	}
+	return nil
+}
+
+// configuredStopTimeout is [defaults] stop_timeout_seconds, the graceful stop
+// timeout used when tearing containers down
+func configuredStopTimeout() time.Duration {
+	return time.Duration(cfg.Defaults.StopTimeoutSeconds) * time.Second
+}
+
+// bulkSessionSaver saves the latest session of each container a bulk command
+// stops or deletes, so those sessions stay resumable
+type bulkSessionSaver struct {
+	sessionsDir string
+	tool        tool.Tool
+}
+
+func newBulkSessionSaver() (*bulkSessionSaver, error) {
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return nil, err
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return &bulkSessionSaver{
+		sessionsDir: session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance),
+		tool:        toolInstance,
+	}, nil
+}
+
+// save pulls the container's session data; containers without a recorded
+// session (e.g. created with 'coi container launch') are skipped
+func (b *bulkSessionSaver) save(mgr *container.Manager) {
+	metadata, err := session.GetLatestSessionForContainer(b.sessionsDir, mgr.ContainerName)
+	if err != nil {
+		return
+	}
+	logger := func(msg string) {
+		fmt.Printf("  %s\n", msg)
+	}
+	if err := session.SaveSessionData(context.Background(), mgr, *metadata, b.sessionsDir, b.tool, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: Failed to save session data: %v\n", err)
+	}
+}
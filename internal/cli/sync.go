@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	syncWatch    bool
+	syncInterval time.Duration
+	syncDebounce time.Duration
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync <slot> <host-file>:<container-path>...",
+	Short: "Push host files into a running session container, optionally on every change",
+	Long: `Copy files from the host into the running container of a workspace slot,
+owned by the code user. Use it for files the agent needs to see live that live
+outside the workspace, e.g. a prompt file or an env file, instead of
+restarting the session.
+
+With --watch coi keeps running and pushes a file again whenever it changes on
+the host, until Ctrl+C. Changes are detected by polling every --interval and
+pushed once the file has been unchanged for --debounce, so an editor saving in
+several steps causes a single push.
+
+Examples:
+  coi sync 1 ./prompt.md:/home/code/prompt.md
+  coi sync 1 .env.local:/home/code/.env --watch
+  coi sync 2 notes.md:/tmp/notes.md ~/.npmrc:/home/code/.npmrc --watch
+`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: syncCommand,
+}
+
+func init() {
+	syncCmd.Flags().BoolVar(&syncWatch, "watch", false, "Keep running and push files again whenever they change")
+	syncCmd.Flags().DurationVar(&syncInterval, "interval", 500*time.Millisecond, "How often to check the files for changes (with --watch)")
+	syncCmd.Flags().DurationVar(&syncDebounce, "debounce", 300*time.Millisecond, "Wait until a changed file is unchanged this long before pushing (with --watch)")
+}
+
+func syncCommand(cmd *cobra.Command, args []string) error {
+	syncSlot, err := strconv.Atoi(args[0])
+	if err != nil || syncSlot < 1 {
+		return fmt.Errorf("invalid slot '%s' - must be a positive number", args[0])
+	}
+	if syncInterval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	mappings := make(map[string]session.SyncMapping, len(args)-1)
+	hostPaths := make([]string, 0, len(args)-1)
+	for _, arg := range args[1:] {
+		mapping, err := session.ParseSyncMapping(arg)
+		if err != nil {
+			return err
+		}
+		if _, ok := mappings[mapping.HostPath]; ok {
+			return fmt.Errorf("host file '%s' is mapped more than once", mapping.HostPath)
+		}
+		mappings[mapping.HostPath] = mapping
+		hostPaths = append(hostPaths, mapping.HostPath)
+	}
+
+	absWorkspace, err := filepath.Abs(workspace)
+	if err != nil {
+		return fmt.Errorf("invalid workspace path: %w", err)
+	}
+	containerName := session.ContainerName(absWorkspace, syncSlot)
+	mgr := container.NewManager(containerName)
+	running, err := mgr.Running()
+	if err != nil {
+		return fmt.Errorf("failed to check container status: %w", err)
+	}
+	if !running {
+		return fmt.Errorf("container %s (slot %d of %s) is not running", containerName, syncSlot, absWorkspace)
+	}
+
+	for _, hostPath := range hostPaths {
+		if err := syncFile(mgr, mappings[hostPath]); err != nil {
+			return err
+		}
+	}
+	if !syncWatch {
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Watching %d file(s) for changes (Ctrl+C to stop)...\n", len(hostPaths))
+	session.WatchFiles(ctx, hostPaths, syncInterval, syncDebounce, func(hostPath string) {
+		// Keep watching when a push fails, e.g. while the container restarts
+		if err := syncFile(mgr, mappings[hostPath]); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	})
+	return nil
+}
+
+// syncFile pushes a mapped host file into the container, owned by the code
+// user like the parent directories it creates
+func syncFile(mgr *container.Manager, mapping session.SyncMapping) error {
+	dir := path.Dir(mapping.ContainerPath)
+	if _, err := mgr.ExecCommand(session.SyncParentDirsCommand(mapping.ContainerPath, container.CodeUID), container.ExecCommandOptions{Capture: true}); err != nil {
+		return fmt.Errorf("failed to create %s in %s: %w", dir, mgr.ContainerName, err)
+	}
+	if err := mgr.PushFile(mapping.HostPath, mapping.ContainerPath); err != nil {
+		return fmt.Errorf("failed to push %s: %w", mapping.HostPath, err)
+	}
+	if err := mgr.Chown(mapping.ContainerPath, container.CodeUID, container.CodeUID); err != nil {
+		return fmt.Errorf("failed to chown %s: %w", mapping.ContainerPath, err)
+	}
+	fmt.Fprintf(os.Stderr, "[%s] Pushed %s -> %s:%s\n", time.Now().Format("15:04:05"), mapping.HostPath, mgr.ContainerName, mapping.ContainerPath)
+	return nil
+}
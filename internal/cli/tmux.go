@@ -2,8 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/session"
 	"github.com/spf13/cobra"
 )
 
@@ -11,7 +15,14 @@ var tmuxCmd = &cobra.Command{
 	Use:   "tmux",
 	Short: "Interact with tmux sessions in containers",
 	Long: `Send commands to or capture output from AI coding sessions running in tmux.
-This is primarily for automated workflows.`,
+This is primarily for automated workflows and driving background sessions.
+
+Examples:
+  coi tmux list                                  # List sessions in all containers
+  coi tmux list --slot 2                         # Only slot 2 of this workspace
+  coi tmux send coi-abc12345-1 "run the tests"   # Send keys (followed by Enter)
+  coi tmux capture coi-abc12345-1                # Print current pane output
+  coi tmux capture --slot 1                      # Resolve container from slot`,
 }
 
 var tmuxSendCmd = &cobra.Command{
@@ -24,19 +35,27 @@ The session name should be the container name (e.g., coi-abc123-1).`,
 }
 
 var tmuxCaptureCmd = &cobra.Command{
-	Use:   "capture SESSION_NAME",
+	Use:   "capture [SESSION_NAME]",
 	Short: "Capture output from a tmux session",
 	Long: `Capture the current pane output from a tmux session.
-The session name should be the container name (e.g., coi-abc123-1).`,
-	Args: cobra.ExactArgs(1),
+The session name should be the container name (e.g., coi-abc123-1).
+Without a name, the container is resolved from --slot or the current workspace.
+
+Examples:
+  coi tmux capture coi-abc123-1
+  coi tmux capture --slot 2
+`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: tmuxCaptureCommand,
 }
 
 var tmuxListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List active tmux sessions",
-	Long:  `List all active tmux sessions across all containers.`,
-	RunE:  tmuxListCommand,
+	Long: `List all active tmux sessions across all containers.
+With --slot, only the container for that slot of the current workspace is shown.`,
+	Args: cobra.NoArgs,
+	RunE: tmuxListCommand,
 }
 
 func init() {
@@ -45,32 +64,48 @@ func init() {
 	tmuxCmd.AddCommand(tmuxListCmd)
 }
 
-func tmuxSendCommand(cmd *cobra.Command, args []string) error {
-	containerName := args[0]
-	command := args[1]
+// tmuxExec runs a tmux command in the container. Sessions started by 'coi shell'
+// belong to the code user, so that is tried first, falling back to root
+// (used for non-coi images and sessions created via 'coi container exec').
+func tmuxExec(mgr *container.Manager, command string) (string, error) {
+	user := container.CodeUID
+	output, err := mgr.ExecCommand(command, container.ExecCommandOptions{
+		User:    &user,
+		Capture: true,
+	})
+	if err == nil {
+		return output, nil
+	}
 
-	mgr := container.NewManager(containerName)
+	return mgr.ExecCommand(command, container.ExecCommandOptions{Capture: true})
+}
 
-	// Check if container is running
+// ensureRunning returns an error if the container is not running
+func ensureRunning(mgr *container.Manager) error {
 	running, err := mgr.Running()
 	if err != nil {
 		return fmt.Errorf("failed to check container status: %w", err)
 	}
 	if !running {
-		return fmt.Errorf("container %s is not running", containerName)
+		return fmt.Errorf("container %s is not running", mgr.ContainerName)
 	}
+	return nil
+}
 
-	// Send command to tmux session
-	tmuxSession := fmt.Sprintf("coi-%s", containerName)
-	tmuxCmd := fmt.Sprintf("tmux send-keys -t %s %q Enter", tmuxSession, command)
+func tmuxSendCommand(cmd *cobra.Command, args []string) error {
+	containerName := args[0]
+	command := args[1]
 
-	opts := container.ExecCommandOptions{
-		Interactive: false,
-		Capture:     true,
+	mgr := container.NewManager(containerName)
+	if err := ensureRunning(mgr); err != nil {
+		return err
 	}
 
-	_, err = mgr.ExecCommand(tmuxCmd, opts)
-	if err != nil {
+	// Send command to tmux session
+	tmuxSession := fmt.Sprintf("coi-%s", containerName)
+	tmuxCmd := container.ShellJoin([]string{"tmux", "send-keys", "-t", tmuxSession, command, "Enter"})
+
+	if _, err := tmuxExec(mgr, tmuxCmd); err != nil {
 		return fmt.Errorf("failed to send command to tmux session: %w", err)
 	}
 
@@ -79,29 +114,21 @@ func tmuxSendCommand(cmd *cobra.Command, args []string) error {
 }
 
 func tmuxCaptureCommand(cmd *cobra.Command, args []string) error {
-	containerName := args[0]
-
-	mgr := container.NewManager(containerName)
-
-	// Check if container is running
-	running, err := mgr.Running()
+	containerName, err := resolveWorkspaceContainer(args)
 	if err != nil {
-		return fmt.Errorf("failed to check container status: %w", err)
+		return err
 	}
-	if !running {
-		return fmt.Errorf("container %s is not running", containerName)
+
+	mgr := container.NewManager(containerName)
+	if err := ensureRunning(mgr); err != nil {
+		return err
 	}
 
 	// Capture tmux pane output
 	tmuxSession := fmt.Sprintf("coi-%s", containerName)
-	tmuxCmd := fmt.Sprintf("tmux capture-pane -t %s -p", tmuxSession)
-
-	opts := container.ExecCommandOptions{
-		Interactive: false,
-		Capture:     true,
-	}
+	tmuxCmd := container.ShellJoin([]string{"tmux", "capture-pane", "-t", tmuxSession, "-p"})
 
-	output, err := mgr.ExecCommand(tmuxCmd, opts)
+	output, err := tmuxExec(mgr, tmuxCmd)
 	if err != nil {
 		return fmt.Errorf("failed to capture tmux output: %w", err)
 	}
@@ -111,18 +138,24 @@ func tmuxCaptureCommand(cmd *cobra.Command, args []string) error {
 }
 
 func tmuxListCommand(cmd *cobra.Command, args []string) error {
-	// List all running containers with configured prefix
-	containers, err := container.ListContainers("coi-.*")
-	if err != nil {
-		return fmt.Errorf("failed to list containers: %w", err)
-	}
-
-	if len(containers) == 0 {
-		fmt.Println("No active sessions")
-		return nil
+	var containers []string
+	if slot > 0 {
+		// Only the container for the requested slot of this workspace
+		absWorkspace, err := filepath.Abs(workspace)
+		if err != nil {
+			return fmt.Errorf("invalid workspace path: %w", err)
+		}
+		containers = []string{session.ContainerName(absWorkspace, slot)}
+	} else {
+		// List all containers with configured prefix
+		var err error
+		containers, err = container.ListContainers("^" + regexp.QuoteMeta(session.GetContainerPrefix()) + ".*")
+		if err != nil {
+			return fmt.Errorf("failed to list containers: %w", err)
+		}
 	}
 
-	fmt.Println("Active sessions:")
+	var lines []string
 	for _, c := range containers {
 		mgr := container.NewManager(c)
 
@@ -132,20 +165,27 @@ func tmuxListCommand(cmd *cobra.Command, args []string) error {
 			continue
 		}
 
-		// Check if tmux session exists
-		tmuxSession := fmt.Sprintf("coi-%s", c)
-		tmuxCmd := fmt.Sprintf("tmux has-session -t %s 2>/dev/null", tmuxSession)
-
-		opts := container.ExecCommandOptions{
-			Interactive: false,
-			Capture:     false,
+		// List tmux sessions inside the container
+		output, err := tmuxExec(mgr, "tmux list-sessions -F '#{session_name}' 2>/dev/null")
+		if err != nil {
+			continue
 		}
-
-		_, err = mgr.ExecCommand(tmuxCmd, opts)
-		if err == nil {
-			fmt.Printf("  - %s (tmux session: %s)\n", c, tmuxSession)
+		for _, name := range strings.Split(strings.TrimSpace(output), "\n") {
+			if name = strings.TrimSpace(name); name != "" {
+				lines = append(lines, fmt.Sprintf("  - %s (tmux session: %s)", c, name))
+			}
 		}
 	}
 
+	if len(lines) == 0 {
+		fmt.Println("No active sessions")
+		return nil
+	}
+
+	fmt.Println("Active sessions:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+
 	return nil
 }
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/session"
+	"github.com/spf13/cobra"
+)
+
+var (
+	transcriptGrep   string
+	transcriptSince  string
+	transcriptFormat string
+)
+
+var transcriptCmd = &cobra.Command{
+	Use:   "transcript [SESSION_ID]",
+	Short: "Show the conversation transcript of a saved session",
+	Long: `Show a readable summary of a saved session's conversation (roles, timestamps, text).
+
+Reads the transcript files saved with the session, so you can review what an
+agent did without attaching. Defaults to the latest saved session.
+
+Examples:
+  coi transcript                           # Latest session
+  coi transcript abc123                    # Specific session
+  coi transcript abc123 --grep "npm test"  # Only messages matching a regex
+  coi transcript --since 2h                # Messages from the last 2 hours
+  coi transcript --since 2026-01-10        # Messages since a date (or RFC3339 time)
+  coi transcript --format json             # Machine-readable output
+`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: transcriptCommand,
+}
+
+func init() {
+	transcriptCmd.Flags().StringVar(&transcriptGrep, "grep", "", "Only show messages matching this regular expression")
+	transcriptCmd.Flags().StringVar(&transcriptSince, "since", "", "Only show messages since a duration ago (e.g. 30m, 2h) or a time (2026-01-10, RFC3339)")
+	transcriptCmd.Flags().StringVar(&transcriptFormat, "format", "text", "Output format: text or json")
+}
+
+func transcriptCommand(cmd *cobra.Command, args []string) error {
+	if transcriptFormat != "text" && transcriptFormat != "json" {
+		return fmt.Errorf("invalid format '%s' - must be 'text' or 'json'", transcriptFormat)
+	}
+
+	var pattern *regexp.Regexp
+	if transcriptGrep != "" {
+		var err error
+		pattern, err = regexp.Compile(transcriptGrep)
+		if err != nil {
+			return fmt.Errorf("invalid --grep pattern: %w", err)
+		}
+	}
+
+	var since time.Time
+	if transcriptSince != "" {
+		var err error
+		since, err = parseSince(transcriptSince, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	toolInstance, err := getConfiguredTool(cfg)
+	if err != nil {
+		return err
+	}
+	configDirName := toolInstance.ConfigDirName()
+	if configDirName == "" {
+		return fmt.Errorf("tool '%s' does not store transcripts in a config directory", toolInstance.Name())
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	sessionsDir := session.GetSessionsDir(filepath.Join(homeDir, ".coi"), toolInstance)
+
+	var sessionID string
+	if len(args) > 0 {
+		sessionID = args[0]
+	} else {
+		sessionID, err = session.GetLatestSession(sessionsDir)
+		if err != nil {
+			return fmt.Errorf("no sessions found (specify session ID or use 'coi list --all')")
+		}
+	}
+
+	files, err := session.TranscriptFiles(sessionsDir, sessionID, configDirName)
+	if err != nil || len(files) == 0 {
+		return fmt.Errorf("no transcript found for session %s", sessionID)
+	}
+
+	var entries []session.TranscriptEntry
+	for _, file := range files {
+		fileEntries, err := session.ReadTranscript(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+	// Files are read one after another; interleave their messages in time order
+	session.SortTranscript(entries)
+	entries = session.FilterTranscript(entries, since, pattern)
+
+	if transcriptFormat == "json" {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal transcript: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No matching messages")
+		return nil
+	}
+
+	for _, entry := range entries {
+		timestamp := "unknown time"
+		if !entry.Timestamp.IsZero() {
+			timestamp = entry.Timestamp.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("[%s] %s:\n", timestamp, entry.Role)
+		for _, line := range strings.Split(entry.Text, "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
+
+// parseSince parses a --since value: a duration ago (30m, 2h), a date, or an RFC3339 time
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value '%s' - use a duration (30m, 2h), a date (2006-01-02) or RFC3339 time", value)
+}
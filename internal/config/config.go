@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 )
 
 // Config represents the complete configuration
@@ -14,6 +17,8 @@ type Config struct {
 	Tool     ToolConfig               `toml:"tool"`
 	Mounts   MountsConfig             `toml:"mounts"`
 	Limits   LimitsConfig             `toml:"limits"`
+	Tmux     TmuxConfig               `toml:"tmux"`
+	Update   UpdateConfig             `toml:"update"`
 	Profiles map[string]ProfileConfig `toml:"profiles"`
 }
 
@@ -22,8 +27,38 @@ type DefaultsConfig struct {
 	Image      string `toml:"image"`
 	Persistent bool   `toml:"persistent"`
 	Model      string `toml:"model"`
+	UseTmux    *bool  `toml:"use_tmux"`   // nil means default (true)
+	Metrics    bool   `toml:"metrics"`    // Record local session metrics to ~/.coi/metrics.jsonl
+	AutoBuild  bool   `toml:"auto_build"` // Build the coi image on first use if it is missing
+
+	SyncTimezone    bool   `toml:"sync_timezone"`     // Set the container timezone to the host's
+	SyncGitIdentity bool   `toml:"sync_git_identity"` // Copy the host's global git user.name/email and gitignore
+	Locale          string `toml:"locale"`            // LANG/LC_ALL for the tool, e.g. C.UTF-8
+	ReadyProbe      string `toml:"ready_probe"`       // Command (bash -c, as root) that must exit 0 before the tool starts
+
+	CheckClockDrift    bool          `toml:"check_clock_drift"`    // Warn when a container clock differs from the host's
+	StopTimeoutSeconds int           `toml:"stop_timeout_seconds"` // Graceful stop timeout before force-stopping a container
+	CleanupPolicy      CleanupPolicy `toml:"cleanup_policy"`       // What to do with a non-persistent container still running at exit
+	DeleteGraceMinutes int           `toml:"delete_grace_minutes"` // Keep a stopped non-persistent container this long before deleting it (0 = delete at once)
+	FriendlySessionIDs bool          `toml:"friendly_session_ids"` // New sessions get IDs like swift-otter-4821 instead of UUIDs
+	AutoResume         bool          `toml:"auto_resume"`          // A plain coi shell resumes the workspace's latest recent session
+	MountHome          bool          `toml:"mount_home"`           // Mount a persistent per-workspace cache directory at ~/.cache
 }
 
+// CleanupPolicy decides what happens to a non-persistent container that is
+// still running when the session ends (the user exited or detached). Stopped
+// containers (sudo shutdown 0) are always deleted, after delete_grace_minutes.
+type CleanupPolicy string
+
+const (
+	// CleanupKeep keeps the container running for coi attach
+	CleanupKeep CleanupPolicy = "keep"
+	// CleanupDelete deletes the container (what --rm does for one run)
+	CleanupDelete CleanupPolicy = "delete"
+	// CleanupAsk asks whether to delete the container
+	CleanupAsk CleanupPolicy = "ask"
+)
+
 // PathsConfig contains path settings
 type PathsConfig struct {
 	SessionsDir string `toml:"sessions_dir"`
@@ -54,19 +89,31 @@ const (
 
 // NetworkConfig contains network isolation settings
 type NetworkConfig struct {
-	Mode                    NetworkMode          `toml:"mode"`
-	BlockPrivateNetworks    bool                 `toml:"block_private_networks"`
-	BlockMetadataEndpoint   bool                 `toml:"block_metadata_endpoint"`
-	AllowedDomains          []string             `toml:"allowed_domains"`
-	RefreshIntervalMinutes  int                  `toml:"refresh_interval_minutes"`
-	AllowLocalNetworkAccess bool                 `toml:"allow_local_network_access"` // Allow established connections from entire local network (not just gateway)
-	Logging                 NetworkLoggingConfig `toml:"logging"`
+	Mode                    NetworkMode                `toml:"mode"`
+	BlockPrivateNetworks    bool                       `toml:"block_private_networks"`
+	BlockMetadataEndpoint   bool                       `toml:"block_metadata_endpoint"`
+	AllowedDomains          []string                   `toml:"allowed_domains"`
+	DeniedDomains           []string                   `toml:"denied_domains"` // Domains blocked in open and restricted modes (resolved to IPs and refreshed like the allowlist)
+	RefreshIntervalMinutes  int                        `toml:"refresh_interval_minutes"`
+	AllowLocalNetworkAccess bool                       `toml:"allow_local_network_access"` // Allow established connections from entire local network (not just gateway)
+	Proxy                   string                     `toml:"proxy"`                      // HTTP(S) proxy URL injected as HTTP_PROXY/HTTPS_PROXY and allowed through the firewall
+	DNSServers              []string                   `toml:"dns_servers"`                // Nameservers written to the container's /etc/resolv.conf (default: the Incus network's DNS)
+	Presets                 map[string]AllowlistPreset `toml:"presets"`                    // Named domain sets for --allow-preset (override built-ins by name)
+	WildcardSubdomains      map[string][]string        `toml:"wildcard_subdomains"`        // Known subdomains for "*.example.com" allowlist entries, e.g. {"*.example.com" = ["api", "cdn"]}
+	Logging                 NetworkLoggingConfig       `toml:"logging"`
+}
+
+// AllowlistPreset is a named set of domains that can be added to the allowlist with --allow-preset
+type AllowlistPreset struct {
+	Domains []string `toml:"domains"`
 }
 
 // NetworkLoggingConfig contains network logging settings
 type NetworkLoggingConfig struct {
-	Enabled bool   `toml:"enabled"`
-	Path    string `toml:"path"`
+	Enabled   bool   `toml:"enabled"`
+	Path      string `toml:"path"`
+	MaxSizeMB int    `toml:"max_size_mb"` // Rotate the log once it reaches this size
+	MaxFiles  int    `toml:"max_files"`   // Rotated files to keep (network.log.1, .2, ...)
 }
 
 // ProfileConfig represents a named profile
@@ -81,6 +128,15 @@ type ProfileConfig struct {
 type ToolConfig struct {
 	Name   string `toml:"name"`   // Tool name: "claude", "aider", "cursor", etc.
 	Binary string `toml:"binary"` // Binary name to execute (if empty, uses tool name)
+
+	// CredentialSource is "file" (default: the credentials file in the host
+	// config directory) or "keyring:<service>/<account>" (host keyring)
+	CredentialSource string `toml:"credential_source"`
+
+	// InjectSettings merges the tool's sandbox settings into its config files
+	// in the container; false leaves settings.json/.claude.json as on the host.
+	// nil means default (true).
+	InjectSettings *bool `toml:"inject_settings"`
 }
 
 // MountEntry represents a single directory mount configuration
@@ -94,6 +150,25 @@ type MountsConfig struct {
 	Default []MountEntry `toml:"default"` // Default mounts for all sessions
 }
 
+// TmuxConfig contains tmux settings written to the container's tmux.conf
+type TmuxConfig struct {
+	Mouse      bool   `toml:"mouse"`       // Enable mouse support (scrolling, pane selection)
+	Scrollback int    `toml:"scrollback"`  // history-limit in lines (0 = tmux default)
+	Prefix     string `toml:"prefix"`      // Prefix key in tmux notation, e.g. "C-a" (empty = tmux default C-b)
+	DetachKeys string `toml:"detach_keys"` // Key that detaches directly without the prefix, e.g. "C-q"
+}
+
+// UpdateConfig controls 'coi self-update --check'. Checking for a new release
+// is a network call, so it is off unless enabled.
+type UpdateConfig struct {
+	Enabled         bool   `toml:"enabled"`            // Allow coi self-update to query ReleaseURL
+	ReleaseURL      string `toml:"release_url"`        // GitHub "latest release" API URL (or a compatible endpoint)
+	MaxImageAgeDays int    `toml:"max_image_age_days"` // Recommend rebuilding the coi image after this many days
+}
+
+// DefaultReleaseURL is where coi looks up the latest release
+const DefaultReleaseURL = "https://api.github.com/repos/mensfeld/code-on-incus/releases/latest"
+
 // LimitsConfig contains resource and time limits for containers
 type LimitsConfig struct {
 	CPU     CPULimits     `toml:"cpu"`
@@ -142,9 +217,11 @@ func GetDefaultConfig() *Config {
 
 	return &Config{
 		Defaults: DefaultsConfig{
-			Image:      "coi",
-			Persistent: false,
-			Model:      "claude-sonnet-4-5",
+			Image:              "coi",
+			Persistent:         false,
+			Model:              "claude-sonnet-4-5",
+			StopTimeoutSeconds: 10,
+			CleanupPolicy:      CleanupKeep,
 		},
 		Paths: PathsConfig{
 			SessionsDir: filepath.Join(baseDir, "sessions"),
@@ -173,8 +250,10 @@ func GetDefaultConfig() *Config {
 			},
 			RefreshIntervalMinutes: 30,
 			Logging: NetworkLoggingConfig{
-				Enabled: true,
-				Path:    filepath.Join(baseDir, "logs", "network.log"),
+				Enabled:   true,
+				Path:      filepath.Join(baseDir, "logs", "network.log"),
+				MaxSizeMB: 10,
+				MaxFiles:  3,
 			},
 		},
 		Tool: ToolConfig{
@@ -208,6 +287,10 @@ func GetDefaultConfig() *Config {
 				StopGraceful: true,
 			},
 		},
+		Update: UpdateConfig{
+			ReleaseURL:      DefaultReleaseURL,
+			MaxImageAgeDays: 30,
+		},
 		Profiles: make(map[string]ProfileConfig),
 	}
 }
@@ -269,6 +352,50 @@ func (c *Config) Merge(other *Config) {
 	// In TOML, if a field is not present, it will be false (zero value)
 	// This is a limitation - we'll just override if file exists
 	c.Defaults.Persistent = other.Defaults.Persistent
+	if other.Defaults.UseTmux != nil {
+		useTmux := *other.Defaults.UseTmux
+		c.Defaults.UseTmux = &useTmux
+	}
+	// Metrics is opt-in: any config file enabling it turns it on
+	if other.Defaults.Metrics {
+		c.Defaults.Metrics = true
+	}
+	if other.Defaults.AutoBuild {
+		c.Defaults.AutoBuild = true
+	}
+	if other.Defaults.SyncTimezone {
+		c.Defaults.SyncTimezone = true
+	}
+	if other.Defaults.SyncGitIdentity {
+		c.Defaults.SyncGitIdentity = true
+	}
+	if other.Defaults.AutoResume {
+		c.Defaults.AutoResume = true
+	}
+	if other.Defaults.MountHome {
+		c.Defaults.MountHome = true
+	}
+	if other.Defaults.Locale != "" {
+		c.Defaults.Locale = other.Defaults.Locale
+	}
+	if other.Defaults.ReadyProbe != "" {
+		c.Defaults.ReadyProbe = other.Defaults.ReadyProbe
+	}
+	if other.Defaults.CheckClockDrift {
+		c.Defaults.CheckClockDrift = true
+	}
+	if other.Defaults.StopTimeoutSeconds != 0 {
+		c.Defaults.StopTimeoutSeconds = other.Defaults.StopTimeoutSeconds
+	}
+	if other.Defaults.CleanupPolicy != "" {
+		c.Defaults.CleanupPolicy = other.Defaults.CleanupPolicy
+	}
+	if other.Defaults.DeleteGraceMinutes != 0 {
+		c.Defaults.DeleteGraceMinutes = other.Defaults.DeleteGraceMinutes
+	}
+	if other.Defaults.FriendlySessionIDs {
+		c.Defaults.FriendlySessionIDs = true
+	}
 
 	// Merge paths
 	if other.Paths.SessionsDir != "" {
@@ -309,6 +436,40 @@ func (c *Config) Merge(other *Config) {
 	if len(other.Network.AllowedDomains) > 0 {
 		c.Network.AllowedDomains = other.Network.AllowedDomains
 	}
+	if len(other.Network.DeniedDomains) > 0 {
+		c.Network.DeniedDomains = other.Network.DeniedDomains
+	}
+
+	if other.Network.Proxy != "" {
+		c.Network.Proxy = other.Network.Proxy
+	}
+	if len(other.Network.DNSServers) > 0 {
+		c.Network.DNSServers = other.Network.DNSServers
+	}
+
+	// Merge allowlist presets (replace by name)
+	if len(other.Network.Presets) > 0 {
+		presets := make(map[string]AllowlistPreset, len(c.Network.Presets)+len(other.Network.Presets))
+		for name, preset := range c.Network.Presets {
+			presets[name] = preset
+		}
+		for name, preset := range other.Network.Presets {
+			presets[name] = preset
+		}
+		c.Network.Presets = presets
+	}
+
+	// Merge wildcard subdomain expansions (replace by pattern)
+	if len(other.Network.WildcardSubdomains) > 0 {
+		expansions := make(map[string][]string, len(c.Network.WildcardSubdomains)+len(other.Network.WildcardSubdomains))
+		for pattern, subdomains := range c.Network.WildcardSubdomains {
+			expansions[pattern] = subdomains
+		}
+		for pattern, subdomains := range other.Network.WildcardSubdomains {
+			expansions[pattern] = subdomains
+		}
+		c.Network.WildcardSubdomains = expansions
+	}
 
 	// Merge refresh interval
 	if other.Network.RefreshIntervalMinutes != 0 {
@@ -319,6 +480,12 @@ func (c *Config) Merge(other *Config) {
 		c.Network.Logging.Path = ExpandPath(other.Network.Logging.Path)
 	}
 	c.Network.Logging.Enabled = other.Network.Logging.Enabled
+	if other.Network.Logging.MaxSizeMB != 0 {
+		c.Network.Logging.MaxSizeMB = other.Network.Logging.MaxSizeMB
+	}
+	if other.Network.Logging.MaxFiles != 0 {
+		c.Network.Logging.MaxFiles = other.Network.Logging.MaxFiles
+	}
 
 	// Merge Tool settings
 	if other.Tool.Name != "" {
@@ -327,6 +494,13 @@ func (c *Config) Merge(other *Config) {
 	if other.Tool.Binary != "" {
 		c.Tool.Binary = other.Tool.Binary
 	}
+	if other.Tool.CredentialSource != "" {
+		c.Tool.CredentialSource = other.Tool.CredentialSource
+	}
+	if other.Tool.InjectSettings != nil {
+		injectSettings := *other.Tool.InjectSettings
+		c.Tool.InjectSettings = &injectSettings
+	}
 	// For DisableShift, if the other config sets it to true, use it
 	if other.Incus.DisableShift {
 		c.Incus.DisableShift = true
@@ -337,6 +511,31 @@ func (c *Config) Merge(other *Config) {
 		c.Mounts.Default = append(c.Mounts.Default, other.Mounts.Default...)
 	}
 
+	// Merge tmux settings
+	if other.Tmux.Mouse {
+		c.Tmux.Mouse = true
+	}
+	if other.Tmux.Scrollback != 0 {
+		c.Tmux.Scrollback = other.Tmux.Scrollback
+	}
+	if other.Tmux.Prefix != "" {
+		c.Tmux.Prefix = other.Tmux.Prefix
+	}
+	if other.Tmux.DetachKeys != "" {
+		c.Tmux.DetachKeys = other.Tmux.DetachKeys
+	}
+
+	// Merge update settings
+	if other.Update.Enabled {
+		c.Update.Enabled = true
+	}
+	if other.Update.ReleaseURL != "" {
+		c.Update.ReleaseURL = other.Update.ReleaseURL
+	}
+	if other.Update.MaxImageAgeDays != 0 {
+		c.Update.MaxImageAgeDays = other.Update.MaxImageAgeDays
+	}
+
 	// Merge limits
 	mergeLimits(&c.Limits, &other.Limits)
 
@@ -405,6 +604,39 @@ func (c *Config) GetProfile(name string) *ProfileConfig {
 	return nil
 }
 
+// ProfileNames returns the sorted names of all configured profiles
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MergeEnv returns envVars (KEY=VALUE) plus the profile's environment for keys
+// not already set, so explicit --env values always win
+func (p *ProfileConfig) MergeEnv(envVars []string) []string {
+	set := make(map[string]bool)
+	for _, e := range envVars {
+		set[strings.SplitN(e, "=", 2)[0]] = true
+	}
+
+	keys := make([]string, 0, len(p.Environment))
+	for key := range p.Environment {
+		if !set[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	merged := append([]string{}, envVars...)
+	for _, key := range keys {
+		merged = append(merged, key+"="+p.Environment[key])
+	}
+	return merged
+}
+
 // ApplyProfile applies a profile's settings to the defaults
 func (c *Config) ApplyProfile(name string) bool {
 	profile := c.GetProfile(name)
@@ -424,3 +656,12 @@ func (c *Config) ApplyProfile(name string) bool {
 
 	return true
 }
+
+// ValidateTmuxKey checks that key is a single tmux key name (e.g. "C-a", "M-d", "F12")
+// that can be written into a generated tmux.conf
+func ValidateTmuxKey(key string) error {
+	if key == "" || strings.ContainsAny(key, " \t\n'\"#;\\") {
+		return fmt.Errorf("invalid tmux key '%s' - use tmux key notation like C-a, M-d or F12", key)
+	}
+	return nil
+}
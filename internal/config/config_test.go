@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -110,6 +111,78 @@ func TestConfigMerge(t *testing.T) {
 	}
 }
 
+func TestConfigMergeReadyProbe(t *testing.T) {
+	base := GetDefaultConfig()
+	base.Merge(&Config{Defaults: DefaultsConfig{ReadyProbe: "pg_isready"}})
+	if base.Defaults.ReadyProbe != "pg_isready" {
+		t.Errorf("Expected ready probe 'pg_isready', got '%s'", base.Defaults.ReadyProbe)
+	}
+
+	base.Merge(&Config{})
+	if base.Defaults.ReadyProbe != "pg_isready" {
+		t.Errorf("Expected an unset ready probe not to override, got '%s'", base.Defaults.ReadyProbe)
+	}
+}
+
+func TestConfigMergeCheckClockDrift(t *testing.T) {
+	base := GetDefaultConfig()
+	base.Merge(&Config{Defaults: DefaultsConfig{CheckClockDrift: true}})
+	if !base.Defaults.CheckClockDrift {
+		t.Error("Expected check_clock_drift to be enabled")
+	}
+}
+
+func TestConfigMergeStopTimeoutSeconds(t *testing.T) {
+	base := GetDefaultConfig()
+	base.Merge(&Config{})
+	if base.Defaults.StopTimeoutSeconds != 10 {
+		t.Errorf("Expected default stop timeout 10, got %d", base.Defaults.StopTimeoutSeconds)
+	}
+
+	base.Merge(&Config{Defaults: DefaultsConfig{StopTimeoutSeconds: 45}})
+	if base.Defaults.StopTimeoutSeconds != 45 {
+		t.Errorf("Expected stop timeout 45, got %d", base.Defaults.StopTimeoutSeconds)
+	}
+}
+
+func TestConfigMergeCleanupPolicy(t *testing.T) {
+	base := GetDefaultConfig()
+	base.Merge(&Config{})
+	if base.Defaults.CleanupPolicy != CleanupKeep {
+		t.Errorf("Expected default cleanup policy 'keep', got '%s'", base.Defaults.CleanupPolicy)
+	}
+
+	base.Merge(&Config{Defaults: DefaultsConfig{CleanupPolicy: CleanupAsk}})
+	if base.Defaults.CleanupPolicy != CleanupAsk {
+		t.Errorf("Expected cleanup policy 'ask', got '%s'", base.Defaults.CleanupPolicy)
+	}
+}
+
+func TestConfigMergeFriendlySessionIDs(t *testing.T) {
+	base := GetDefaultConfig()
+	base.Merge(&Config{Defaults: DefaultsConfig{FriendlySessionIDs: true}})
+	if !base.Defaults.FriendlySessionIDs {
+		t.Error("Expected friendly_session_ids to be enabled")
+	}
+}
+
+func TestConfigMergeDeleteGraceMinutes(t *testing.T) {
+	base := GetDefaultConfig()
+	if base.Defaults.DeleteGraceMinutes != 0 {
+		t.Errorf("Expected no grace period by default, got %d", base.Defaults.DeleteGraceMinutes)
+	}
+
+	base.Merge(&Config{Defaults: DefaultsConfig{DeleteGraceMinutes: 15}})
+	if base.Defaults.DeleteGraceMinutes != 15 {
+		t.Errorf("Expected delete grace 15 minutes, got %d", base.Defaults.DeleteGraceMinutes)
+	}
+
+	base.Merge(&Config{})
+	if base.Defaults.DeleteGraceMinutes != 15 {
+		t.Errorf("Expected an unset grace period not to override, got %d", base.Defaults.DeleteGraceMinutes)
+	}
+}
+
 func TestGetProfile(t *testing.T) {
 	cfg := GetDefaultConfig()
 
@@ -136,6 +209,35 @@ func TestGetProfile(t *testing.T) {
 	}
 }
 
+func TestProfileNames(t *testing.T) {
+	cfg := GetDefaultConfig()
+	cfg.Profiles["rust"] = ProfileConfig{}
+	cfg.Profiles["go"] = ProfileConfig{}
+
+	names := cfg.ProfileNames()
+	if strings.Join(names, ",") != "go,rust" {
+		t.Errorf("Expected sorted names [go rust], got %v", names)
+	}
+}
+
+func TestProfileMergeEnv(t *testing.T) {
+	profile := ProfileConfig{Environment: map[string]string{
+		"RUST_LOG": "debug",
+		"CARGO":    "1",
+	}}
+
+	merged := profile.MergeEnv([]string{"RUST_LOG=info"})
+	want := []string{"RUST_LOG=info", "CARGO=1"}
+	if strings.Join(merged, " ") != strings.Join(want, " ") {
+		t.Errorf("MergeEnv() = %v, want %v (explicit --env must win)", merged, want)
+	}
+
+	empty := (&ProfileConfig{}).MergeEnv(nil)
+	if len(empty) != 0 {
+		t.Errorf("Expected no env for empty profile, got %v", empty)
+	}
+}
+
 func TestApplyProfile(t *testing.T) {
 	cfg := GetDefaultConfig()
 	cfg.Defaults.Image = "original-image"
@@ -273,4 +375,154 @@ func TestToolConfigMerge(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("merge credential source", func(t *testing.T) {
+		testBase := GetDefaultConfig()
+		testBase.Merge(&Config{Tool: ToolConfig{CredentialSource: "keyring:coi/me"}})
+		testBase.Merge(&Config{})
+		if testBase.Tool.CredentialSource != "keyring:coi/me" {
+			t.Errorf("Expected credential source 'keyring:coi/me', got '%s'", testBase.Tool.CredentialSource)
+		}
+	})
+
+	t.Run("merge inject settings", func(t *testing.T) {
+		testBase := GetDefaultConfig()
+		if testBase.Tool.InjectSettings != nil {
+			t.Fatalf("Expected default inject_settings to be unset, got %v", *testBase.Tool.InjectSettings)
+		}
+		injectSettings := false
+		testBase.Merge(&Config{Tool: ToolConfig{InjectSettings: &injectSettings}})
+		testBase.Merge(&Config{})
+		if testBase.Tool.InjectSettings == nil || *testBase.Tool.InjectSettings {
+			t.Errorf("Expected inject_settings false to survive merges, got %v", testBase.Tool.InjectSettings)
+		}
+	})
+}
+
+func TestTmuxConfigMerge(t *testing.T) {
+	base := GetDefaultConfig()
+
+	if base.Defaults.UseTmux != nil {
+		t.Errorf("Expected default use_tmux to be unset, got %v", *base.Defaults.UseTmux)
+	}
+
+	// Config without tmux settings should not change anything
+	base.Merge(&Config{})
+	if base.Defaults.UseTmux != nil {
+		t.Error("Expected use_tmux to stay unset when not configured")
+	}
+	if base.Tmux.Mouse || base.Tmux.Scrollback != 0 {
+		t.Errorf("Expected empty tmux config, got %+v", base.Tmux)
+	}
+
+	useTmux := false
+	base.Merge(&Config{
+		Defaults: DefaultsConfig{UseTmux: &useTmux},
+		Tmux:     TmuxConfig{Mouse: true, Scrollback: 50000, Prefix: "C-a", DetachKeys: "C-q"},
+	})
+
+	if base.Defaults.UseTmux == nil || *base.Defaults.UseTmux {
+		t.Error("Expected use_tmux to be false after merge")
+	}
+	if !base.Tmux.Mouse {
+		t.Error("Expected tmux mouse to be enabled")
+	}
+	if base.Tmux.Scrollback != 50000 {
+		t.Errorf("Expected scrollback 50000, got %d", base.Tmux.Scrollback)
+	}
+	if base.Tmux.Prefix != "C-a" || base.Tmux.DetachKeys != "C-q" {
+		t.Errorf("Expected prefix C-a and detach keys C-q, got %+v", base.Tmux)
+	}
+
+	// Later config without tmux settings keeps earlier values
+	base.Merge(&Config{})
+	if base.Defaults.UseTmux == nil || *base.Defaults.UseTmux {
+		t.Error("Expected use_tmux to remain false")
+	}
+	if base.Tmux.Scrollback != 50000 {
+		t.Errorf("Expected scrollback to remain 50000, got %d", base.Tmux.Scrollback)
+	}
+	if base.Tmux.Prefix != "C-a" {
+		t.Errorf("Expected prefix to remain C-a, got '%s'", base.Tmux.Prefix)
+	}
+}
+
+func TestValidateTmuxKey(t *testing.T) {
+	for _, key := range []string{"C-a", "M-d", "F12", "C-Space", "`"} {
+		if err := ValidateTmuxKey(key); err != nil {
+			t.Errorf("Expected '%s' to be valid, got %v", key, err)
+		}
+	}
+	for _, key := range []string{"", "C-a d", "C-a;kill-server", "'x'", "#"} {
+		if err := ValidateTmuxKey(key); err == nil {
+			t.Errorf("Expected '%s' to be invalid", key)
+		}
+	}
+}
+
+func TestUpdateConfigMerge(t *testing.T) {
+	base := GetDefaultConfig()
+
+	if base.Update.Enabled {
+		t.Error("Expected update checks to be disabled by default")
+	}
+	if base.Update.ReleaseURL != DefaultReleaseURL || base.Update.MaxImageAgeDays != 30 {
+		t.Errorf("Unexpected update defaults: %+v", base.Update)
+	}
+
+	base.Merge(&Config{Update: UpdateConfig{Enabled: true, ReleaseURL: "https://example.com/latest"}})
+	if !base.Update.Enabled || base.Update.ReleaseURL != "https://example.com/latest" {
+		t.Errorf("Expected merged update settings, got %+v", base.Update)
+	}
+	if base.Update.MaxImageAgeDays != 30 {
+		t.Errorf("Expected max image age to be kept, got %d", base.Update.MaxImageAgeDays)
+	}
+
+	// Later config without update settings keeps earlier values
+	base.Merge(&Config{})
+	if !base.Update.Enabled || base.Update.ReleaseURL != "https://example.com/latest" {
+		t.Errorf("Expected update settings to persist, got %+v", base.Update)
+	}
+}
+
+func TestNetworkLoggingRotationMerge(t *testing.T) {
+	base := GetDefaultConfig()
+
+	if base.Network.Logging.MaxSizeMB != 10 || base.Network.Logging.MaxFiles != 3 {
+		t.Errorf("Unexpected rotation defaults: %+v", base.Network.Logging)
+	}
+
+	base.Merge(&Config{Network: NetworkConfig{Logging: NetworkLoggingConfig{MaxSizeMB: 50}}})
+	if base.Network.Logging.MaxSizeMB != 50 {
+		t.Errorf("Expected max size 50, got %d", base.Network.Logging.MaxSizeMB)
+	}
+	if base.Network.Logging.MaxFiles != 3 {
+		t.Errorf("Expected max files to be kept, got %d", base.Network.Logging.MaxFiles)
+	}
+}
+
+func TestDeniedDomainsMerge(t *testing.T) {
+	base := GetDefaultConfig()
+	if len(base.Network.DeniedDomains) != 0 {
+		t.Errorf("Expected no denied domains by default, got %v", base.Network.DeniedDomains)
+	}
+
+	base.Merge(&Config{Network: NetworkConfig{DeniedDomains: []string{"pastebin.com"}}})
+	base.Merge(&Config{Network: NetworkConfig{Mode: NetworkModeOpen}})
+	if len(base.Network.DeniedDomains) != 1 || base.Network.DeniedDomains[0] != "pastebin.com" {
+		t.Errorf("Expected denied domains to be kept, got %v", base.Network.DeniedDomains)
+	}
+}
+
+func TestDNSServersMerge(t *testing.T) {
+	base := GetDefaultConfig()
+	if len(base.Network.DNSServers) != 0 {
+		t.Errorf("Expected no DNS servers by default, got %v", base.Network.DNSServers)
+	}
+
+	base.Merge(&Config{Network: NetworkConfig{DNSServers: []string{"1.1.1.1", "9.9.9.9"}}})
+	base.Merge(&Config{Network: NetworkConfig{Mode: NetworkModeRestricted}})
+	if len(base.Network.DNSServers) != 2 || base.Network.DNSServers[0] != "1.1.1.1" {
+		t.Errorf("Expected DNS servers to be kept, got %v", base.Network.DNSServers)
+	}
 }
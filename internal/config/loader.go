@@ -16,6 +16,22 @@ import (
 // 4. Project config (./.coi.toml)
 // 5. Environment variables (CLAUDE_ON_INCUS_* or COI_*)
 func Load() (*Config, error) {
+	cfg, err := Read()
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure directories exist
+	if err := ensureDirectories(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read loads configuration like Load but does not create the coi
+// directories, for read-only uses such as rendering help
+func Read() (*Config, error) {
 	// Start with defaults
 	cfg := GetDefaultConfig()
 
@@ -34,11 +50,6 @@ func Load() (*Config, error) {
 	// Load from environment variables
 	loadFromEnv(cfg)
 
-	// Ensure directories exist
-	if err := ensureDirectories(cfg); err != nil {
-		return nil, err
-	}
-
 	return cfg, nil
 }
 
@@ -141,6 +152,48 @@ image = "coi"
 # Set persistent=true to reuse containers across sessions (keeps installed tools)
 persistent = false
 model = "claude-sonnet-4-5"
+# Set use_tmux=false to run the tool directly attached (no tmux, no detach/reattach)
+# use_tmux = true
+# Set metrics=true to log session durations/outcomes locally (~/.coi/metrics.jsonl, see coi metrics)
+# metrics = false
+# Set auto_build=true to build the coi image automatically the first time coi shell needs it
+# auto_build = false
+# Set sync_timezone=true to give containers the host timezone (default is UTC)
+# sync_timezone = false
+# Set sync_git_identity=true to copy your global git user.name/user.email (and global gitignore)
+# into containers, so agent commits aren't authored by "unknown". Credentials are never copied.
+# sync_git_identity = false
+# LANG/LC_ALL for the AI tool (C.UTF-8 is always available; others need the locale installed in the image)
+# locale = "C.UTF-8"
+# Command that must exit 0 (run as root) before the AI tool starts, e.g. to wait for a service
+# ready_probe = "pg_isready -h localhost"
+# Set check_clock_drift=true to warn when a container clock differs from the host's (coi shell, coi health)
+# check_clock_drift = false
+# Seconds to wait for a container to stop gracefully before force-stopping it
+# stop_timeout_seconds = 10
+# What to do with a non-persistent container still running when you exit or
+# detach: keep (for coi attach), delete, or ask. --rm means delete for one run.
+# cleanup_policy = "keep"
+# Minutes to keep a stopped non-persistent container (e.g. after sudo shutdown 0)
+# so files can still be pulled from it before it is deleted (0 = delete at once)
+# delete_grace_minutes = 0
+# Give new sessions easy to type IDs like swift-otter-4821 instead of UUIDs
+# friendly_session_ids = false
+# Set auto_resume=true to make a plain coi shell resume this workspace's latest session
+# (if saved within the last 7 days) instead of starting fresh (or coi shell --auto-resume)
+# auto_resume = false
+# Set mount_home=true to keep tool caches (npm, pip, build caches) in ~/.cache across
+# ephemeral sessions, stored per workspace under ~/.coi/caches (or coi shell --mount-home)
+# mount_home = false
+
+[tool]
+# Fetch credentials from the host keyring at launch instead of copying
+# ~/.claude/.credentials.json (macOS Keychain or Secret Service); they are
+# written into the container only and never saved with the session
+# credential_source = "keyring:<service>/<account>"
+# Set inject_settings=false to copy your own settings.json/.claude.json unchanged
+# instead of merging coi's sandbox settings into them (or coi shell --no-inject-settings)
+# inject_settings = true
 
 [paths]
 sessions_dir = "~/.coi/sessions"
@@ -172,6 +225,24 @@ code_user = "code"
 # host = "/var/run/docker.sock"
 # container = "/var/run/docker.sock"
 
+[tmux]
+# Options written to ~/.tmux.conf inside the container (tmux sessions only)
+# Enable mouse support (scroll with the wheel, click to select panes)
+mouse = false
+# Scrollback history in lines (0 = tmux default of 2000)
+scrollback = 0
+# Prefix key in tmux notation (empty = tmux default C-b)
+# prefix = "C-a"
+# Single key that detaches from the session without the prefix
+# detach_keys = "C-q"
+
+[update]
+# 'coi self-update --check' queries release_url for the latest release (opt-in)
+enabled = false
+# release_url = "https://api.github.com/repos/mensfeld/code-on-incus/releases/latest"
+# Recommend rebuilding the coi image once it is older than this
+max_image_age_days = 30
+
 [limits]
 # Resource and time limits for containers (empty = unlimited)
 
@@ -29,6 +29,22 @@ func TestLoad(t *testing.T) {
 	}
 }
 
+func TestReadCreatesNoDirectories(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg, err := Read()
+	if err != nil {
+		t.Fatalf("Read() failed: %v", err)
+	}
+	if cfg.Paths.SessionsDir != filepath.Join(home, ".coi", "sessions") {
+		t.Errorf("Expected sessions dir under the test home, got %s", cfg.Paths.SessionsDir)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".coi")); !os.IsNotExist(err) {
+		t.Errorf("Expected Read() not to create %s, got %v", filepath.Join(home, ".coi"), err)
+	}
+}
+
 func TestLoadFromEnv(t *testing.T) {
 	// Set environment variables
 	os.Setenv("CLAUDE_ON_INCUS_IMAGE", "env-image")
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// builtinAllowlistPresets are available without any configuration.
+// A [network.presets.<name>] section with the same name replaces the built-in.
+var builtinAllowlistPresets = map[string][]string{
+	"anthropic": {
+		"api.anthropic.com",
+		"platform.claude.com",
+	},
+	"github": {
+		"github.com",
+		"api.github.com",
+		"codeload.github.com",
+		"objects.githubusercontent.com",
+		"raw.githubusercontent.com",
+	},
+	"node": {
+		"registry.npmjs.org",
+		"registry.yarnpkg.com",
+		"npm.pkg.github.com",
+		"nodejs.org",
+	},
+	"python": {
+		"pypi.org",
+		"files.pythonhosted.org",
+	},
+}
+
+// AllowlistPresetNames returns the sorted names of all built-in and configured presets
+func (n *NetworkConfig) AllowlistPresetNames() []string {
+	seen := make(map[string]bool)
+	for name := range builtinAllowlistPresets {
+		seen[name] = true
+	}
+	for name := range n.Presets {
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PresetDomains returns the union of the domains of the named presets, in order
+// and without duplicates. Configured presets take precedence over built-ins.
+func (n *NetworkConfig) PresetDomains(names []string) ([]string, error) {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		var presetDomains []string
+		if preset, ok := n.Presets[name]; ok {
+			presetDomains = preset.Domains
+		} else if builtin, ok := builtinAllowlistPresets[name]; ok {
+			presetDomains = builtin
+		} else {
+			return nil, fmt.Errorf("unknown allowlist preset '%s' (available: %s)", name, strings.Join(n.AllowlistPresetNames(), ", "))
+		}
+
+		for _, domain := range presetDomains {
+			if !seen[domain] {
+				seen[domain] = true
+				domains = append(domains, domain)
+			}
+		}
+	}
+	return domains, nil
+}
+
+// WithAllowlistPresets returns a copy of the config whose AllowedDomains also
+// contain the domains of the named presets. The receiver is not modified.
+func (n *NetworkConfig) WithAllowlistPresets(names []string) (NetworkConfig, error) {
+	result := *n
+	presetDomains, err := n.PresetDomains(names)
+	if err != nil {
+		return result, err
+	}
+
+	domains := make([]string, 0, len(n.AllowedDomains)+len(presetDomains))
+	seen := make(map[string]bool)
+	for _, domain := range append(append([]string{}, n.AllowedDomains...), presetDomains...) {
+		if !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	result.AllowedDomains = domains
+	return result, nil
+}
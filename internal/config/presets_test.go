@@ -0,0 +1,89 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPresetDomains(t *testing.T) {
+	cfg := &NetworkConfig{
+		Presets: map[string]AllowlistPreset{
+			"internal": {Domains: []string{"git.corp.example", "api.anthropic.com"}},
+			"python":   {Domains: []string{"pypi.corp.example"}}, // Overrides the built-in
+		},
+	}
+
+	domains, err := cfg.PresetDomains([]string{"anthropic", "internal", "python"})
+	if err != nil {
+		t.Fatalf("PresetDomains() unexpected error: %v", err)
+	}
+
+	expected := []string{"api.anthropic.com", "platform.claude.com", "git.corp.example", "pypi.corp.example"}
+	if !reflect.DeepEqual(domains, expected) {
+		t.Errorf("Expected %v, got %v", expected, domains)
+	}
+
+	if _, err := cfg.PresetDomains([]string{"missing"}); err == nil {
+		t.Error("Expected error for unknown preset")
+	}
+}
+
+func TestAllowlistPresetNames(t *testing.T) {
+	cfg := &NetworkConfig{
+		Presets: map[string]AllowlistPreset{
+			"internal": {Domains: []string{"git.corp.example"}},
+			"node":     {Domains: []string{"registry.corp.example"}},
+		},
+	}
+
+	expected := []string{"anthropic", "github", "internal", "node", "python"}
+	if names := cfg.AllowlistPresetNames(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestWithAllowlistPresets(t *testing.T) {
+	cfg := &NetworkConfig{
+		Mode:           NetworkModeAllowlist,
+		AllowedDomains: []string{"8.8.8.8", "registry.npmjs.org"},
+	}
+
+	result, err := cfg.WithAllowlistPresets([]string{"node", "python"})
+	if err != nil {
+		t.Fatalf("WithAllowlistPresets() unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"8.8.8.8", "registry.npmjs.org",
+		"registry.yarnpkg.com", "npm.pkg.github.com", "nodejs.org",
+		"pypi.org", "files.pythonhosted.org",
+	}
+	if !reflect.DeepEqual(result.AllowedDomains, expected) {
+		t.Errorf("Expected %v, got %v", expected, result.AllowedDomains)
+	}
+
+	// Original config must not be modified
+	if len(cfg.AllowedDomains) != 2 {
+		t.Errorf("Expected original domains unchanged, got %v", cfg.AllowedDomains)
+	}
+}
+
+func TestMergeNetworkPresets(t *testing.T) {
+	base := GetDefaultConfig()
+	base.Network.Presets = map[string]AllowlistPreset{
+		"team": {Domains: []string{"a.example"}},
+	}
+
+	other := &Config{
+		Network: NetworkConfig{
+			Presets: map[string]AllowlistPreset{
+				"project": {Domains: []string{"b.example"}},
+			},
+		},
+	}
+	base.Merge(other)
+
+	if len(base.Network.Presets) != 2 {
+		t.Errorf("Expected presets from both configs, got %v", base.Network.Presets)
+	}
+}
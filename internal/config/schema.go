@@ -0,0 +1,107 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SchemaURL is the JSON Schema draft used by GenerateSchema
+const SchemaURL = "http://json-schema.org/draft-07/schema#"
+
+// networkModeType and cleanupPolicyType are used to attach enum values to
+// NetworkMode and CleanupPolicy fields
+var (
+	networkModeType   = reflect.TypeOf(NetworkMode(""))
+	cleanupPolicyType = reflect.TypeOf(CleanupPolicy(""))
+)
+
+// GenerateSchema builds a JSON Schema describing the config file format.
+// The schema is derived from the toml tags on Config via reflection, so it
+// stays in sync with the struct definitions automatically.
+func GenerateSchema() map[string]interface{} {
+	schema := schemaForType(reflect.TypeOf(Config{}))
+	schema["$schema"] = SchemaURL
+	schema["title"] = "code-on-incus configuration"
+	schema["description"] = "Configuration for coi (~/.config/coi/config.toml, .coi.toml)"
+	return schema
+}
+
+// NetworkModes returns all valid network modes
+func NetworkModes() []NetworkMode {
+	return []NetworkMode{NetworkModeRestricted, NetworkModeOpen, NetworkModeAllowlist}
+}
+
+// CleanupPolicies returns all valid cleanup policies
+func CleanupPolicies() []CleanupPolicy {
+	return []CleanupPolicy{CleanupKeep, CleanupDelete, CleanupAsk}
+}
+
+// schemaForType returns the JSON Schema fragment for a Go type
+func schemaForType(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case networkModeType:
+		modes := NetworkModes()
+		enum := make([]interface{}, 0, len(modes))
+		for _, mode := range modes {
+			enum = append(enum, string(mode))
+		}
+		return map[string]interface{}{
+			"type": "string",
+			"enum": enum,
+		}
+	case cleanupPolicyType:
+		policies := CleanupPolicies()
+		enum := make([]interface{}, 0, len(policies))
+		for _, policy := range policies {
+			enum = append(enum, string(policy))
+		}
+		return map[string]interface{}{
+			"type": "string",
+			"enum": enum,
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("toml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = schemaForType(field.Type)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaForType(t.Elem()),
+		}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaForType(t.Elem()),
+		}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	schema := GenerateSchema()
+
+	if schema["$schema"] != SchemaURL {
+		t.Errorf("Expected $schema '%s', got '%v'", SchemaURL, schema["$schema"])
+	}
+
+	// Schema must be serializable
+	if _, err := json.Marshal(schema); err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	for _, section := range []string{"defaults", "paths", "incus", "network", "tool", "mounts", "limits", "profiles"} {
+		if _, ok := properties[section]; !ok {
+			t.Errorf("Expected section '%s' in schema", section)
+		}
+	}
+
+	// Network mode should be an enum of all modes
+	network := properties["network"].(map[string]interface{})
+	mode := network["properties"].(map[string]interface{})["mode"].(map[string]interface{})
+	enum, ok := mode["enum"].([]interface{})
+	if !ok {
+		t.Fatal("Expected network.mode to have enum values")
+	}
+	if len(enum) != len(NetworkModes()) {
+		t.Errorf("Expected %d network modes, got %d", len(NetworkModes()), len(enum))
+	}
+
+	// Cleanup policy is an enum too
+	defaults := properties["defaults"].(map[string]interface{})
+	policy := defaults["properties"].(map[string]interface{})["cleanup_policy"].(map[string]interface{})
+	if policies, ok := policy["enum"].([]interface{}); !ok || len(policies) != len(CleanupPolicies()) {
+		t.Errorf("Expected defaults.cleanup_policy enum of %d policies, got %v", len(CleanupPolicies()), policy["enum"])
+	}
+
+	// Profiles is a map of profile objects with nested limits
+	profiles := properties["profiles"].(map[string]interface{})
+	profile, ok := profiles["additionalProperties"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected profiles to describe profile objects")
+	}
+	profileProps := profile["properties"].(map[string]interface{})
+	if _, ok := profileProps["limits"]; !ok {
+		t.Error("Expected profile schema to include limits")
+	}
+
+	// Mount entries are an array of objects
+	mounts := properties["mounts"].(map[string]interface{})
+	def := mounts["properties"].(map[string]interface{})["default"].(map[string]interface{})
+	if def["type"] != "array" {
+		t.Errorf("Expected mounts.default to be an array, got '%v'", def["type"])
+	}
+	items := def["items"].(map[string]interface{})
+	if _, ok := items["properties"].(map[string]interface{})["host"]; !ok {
+		t.Error("Expected mount entry schema to include host")
+	}
+}
@@ -0,0 +1,59 @@
+package container
+
+import (
+	"os/exec"
+	"runtime"
+	"sync"
+)
+
+// IncusAccessMode describes how incus commands are invoked
+type IncusAccessMode string
+
+const (
+	// AccessDirect runs incus directly (macOS, root, or incus-admin already active)
+	AccessDirect IncusAccessMode = "direct"
+	// AccessSG wraps incus in `sg incus-admin -c` to gain group permissions
+	AccessSG IncusAccessMode = "sg"
+)
+
+var (
+	accessOnce sync.Once
+	accessMode IncusAccessMode
+	incusPath  string
+)
+
+// DetectIncusAccess probes once whether incus works without sg and caches the result.
+// Wrapping every command in sg is only needed when the incus-admin group is not
+// already active in the current process (e.g. freshly added to the group); when it
+// is, sg is redundant and can prompt for a group password or fail under sudo.
+func DetectIncusAccess() IncusAccessMode {
+	accessOnce.Do(func() {
+		incusPath = "incus"
+		if path, err := exec.LookPath("incus"); err == nil {
+			// Use the absolute path so sg's login shell doesn't need incus on its PATH
+			incusPath = path
+		}
+
+		if runtime.GOOS == "darwin" {
+			// macOS doesn't have the incus-admin group
+			accessMode = AccessDirect
+			return
+		}
+
+		cmd := exec.Command(incusPath, "--project", IncusProject, "info")
+		cmd.Stdout = nil
+		cmd.Stderr = nil
+		if cmd.Run() == nil {
+			accessMode = AccessDirect
+		} else {
+			accessMode = AccessSG
+		}
+	})
+	return accessMode
+}
+
+// incusBinary returns the incus binary to invoke (absolute path when resolvable)
+func incusBinary() string {
+	DetectIncusAccess()
+	return incusPath
+}
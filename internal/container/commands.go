@@ -1,13 +1,15 @@
 package container
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"regexp"
-	"runtime"
 	"strings"
 	"time"
 )
@@ -19,44 +21,52 @@ const (
 	IncusProject = "default"
 )
 
-// execIncusCommand creates an exec.Cmd for running incus commands.
-// On Linux, it wraps the command with sg for group permissions.
-// On macOS, it runs incus directly (no incus-admin group).
-func execIncusCommand(cmdArgs []string) *exec.Cmd {
-	if runtime.GOOS == "darwin" {
-		// macOS: run incus directly without sg wrapper
-		// cmdArgs is in format: [IncusGroup, "-c", "incus --project ... command"]
-		// Extract the actual incus command from the third element
-		incusCmd := cmdArgs[2] // "incus --project ... command"
-		return exec.Command("sh", "-c", incusCmd)
-	}
-	// Linux: use sg for group permissions
-	return exec.Command("sg", cmdArgs...)
-}
-
-// IncusExec executes an Incus command via sg wrapper for group permissions (Linux) or directly (macOS)
+// IncusExec executes an Incus command via sg wrapper for group permissions or directly (see DetectIncusAccess)
 func IncusExec(args ...string) error {
-	cmdArgs := buildIncusCommand(args...)
-	cmd := execIncusCommand(cmdArgs)
+	cmd := NewIncusCommand(args...).Cmd()
 	cmd.Stdout = os.Stderr // Send stdout to stderr so it's visible
 	cmd.Stderr = os.Stderr // Show errors instead of silencing them
 	return cmd.Run()
 }
 
+// IncusExecContext is IncusExec that stops the command when ctx is cancelled
+func IncusExecContext(ctx context.Context, args ...string) error {
+	cmd := NewIncusCommand(args...).CmdContext(ctx)
+	cmd.Stdout = os.Stderr // Send stdout to stderr so it's visible
+	cmd.Stderr = os.Stderr // Show errors instead of silencing them
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
 // IncusExecInteractive executes an Incus command with stdin/stdout/stderr attached
 func IncusExecInteractive(args ...string) error {
-	cmdArgs := buildIncusCommand(args...)
-	cmd := execIncusCommand(cmdArgs)
+	cmd := NewIncusCommand(args...).Cmd()
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// IncusExecInteractiveTee is IncusExecInteractive that also copies the
+// command's stdout to w, e.g. to record a session. With stdout no longer a
+// terminal incus can't see the window size, so callers should set it in the
+// container themselves.
+func IncusExecInteractiveTee(w io.Writer, args ...string) error {
+	cmd := NewIncusCommand(args...).Cmd()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, w)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 // IncusExecQuiet executes an Incus command silently (suppress stdout/stderr)
 func IncusExecQuiet(args ...string) error {
-	cmdArgs := buildIncusCommand(args...)
-	cmd := execIncusCommand(cmdArgs)
+	cmd := NewIncusCommand(args...).Cmd()
 	cmd.Stdout = nil
 	cmd.Stderr = nil
 	return cmd.Run()
@@ -64,8 +74,7 @@ func IncusExecQuiet(args ...string) error {
 
 // IncusOutput executes an Incus command and returns the output (trimmed)
 func IncusOutput(args ...string) (string, error) {
-	cmdArgs := buildIncusCommand(args...)
-	cmd := execIncusCommand(cmdArgs)
+	cmd := NewIncusCommand(args...).Cmd()
 
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
@@ -90,8 +99,15 @@ func IncusOutput(args ...string) (string, error) {
 
 // IncusOutputRaw executes an Incus command and returns the output (not trimmed)
 func IncusOutputRaw(args ...string) (string, error) {
-	cmdArgs := buildIncusCommand(args...)
-	cmd := execIncusCommand(cmdArgs)
+	return IncusOutputRawContext(context.Background(), args...)
+}
+
+// IncusOutputRawContext is IncusOutputRaw that stops the command when ctx is
+// cancelled, returning ctx.Err()
+func IncusOutputRawContext(ctx context.Context, args ...string) (string, error) {
+	cmd := NewIncusCommand(args...).CmdContext(ctx)
+	// With the sg wrapper only sg is killed; don't wait for incus to close stdout
+	cmd.WaitDelay = time.Second
 
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
@@ -101,6 +117,9 @@ func IncusOutputRaw(args ...string) (string, error) {
 	output := stdout.String()
 
 	if err != nil {
+		if ctx.Err() != nil {
+			return output, ctx.Err()
+		}
 		// Extract exit code if available
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return output, &ExitError{
@@ -114,21 +133,53 @@ func IncusOutputRaw(args ...string) (string, error) {
 	return output, nil
 }
 
-// IncusOutputWithArgs executes incus with raw args (no additional wrapping)
-func IncusOutputWithArgs(args ...string) (string, error) {
-	// Build command with project flag
-	incusArgs := append([]string{"--project", IncusProject}, args...)
+// IncusStreamLines executes an Incus command and calls onLine for every stdout
+// line as it arrives (without the trailing newline), so long outputs are never
+// buffered in memory. Its stderr is passed through to ours. Returns the
+// command's exit error once it finishes.
+func IncusStreamLines(onLine func(string), args ...string) error {
+	cmd := NewIncusCommand(args...).Cmd()
+	cmd.Stderr = os.Stderr // Show errors instead of silencing them
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
 
-	// Build properly quoted command
-	quotedArgs := make([]string, len(incusArgs))
-	for i, arg := range incusArgs {
-		quotedArgs[i] = shellQuote(arg)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024) // Allow long lines (e.g. minified JSON)
+	for scanner.Scan() {
+		onLine(scanner.Text())
+	}
+	scanErr := scanner.Err()
+	if scanErr != nil {
+		// Keep draining so the command is not blocked writing to a full pipe
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// Extract exit code if available
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return &ExitError{
+				ExitCode: exitErr.ExitCode(),
+				Err:      err,
+			}
+		}
+		return err
 	}
 
-	incusCmd := "incus " + strings.Join(quotedArgs, " ")
-	sgArgs := []string{IncusGroup, "-c", incusCmd}
+	if scanErr != nil {
+		return fmt.Errorf("failed to read command output: %w", scanErr)
+	}
+	return nil
+}
 
-	cmd := execIncusCommand(sgArgs)
+// IncusOutputWithArgs executes incus with raw args (no additional wrapping)
+func IncusOutputWithArgs(args ...string) (string, error) {
+	cmd := NewIncusCommand(args...).Cmd()
 
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
@@ -153,8 +204,7 @@ func IncusOutputWithArgs(args ...string) (string, error) {
 
 // IncusFilePush pushes a file into a container
 func IncusFilePush(source, destination string) error {
-	cmdArgs := buildIncusCommand("file", "push", source, destination)
-	cmd := execIncusCommand(cmdArgs)
+	cmd := NewIncusCommand("file", "push", source, destination).Cmd()
 	return cmd.Run()
 }
 
@@ -197,24 +247,14 @@ func ContainerExec(containerName, command string, opts ContainerExecOptions) (st
 		envFlags = append(envFlags, "--env", fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// Build full incus command
-	incusArgs := append([]string{"--project", IncusProject}, args...)
-	incusArgs = append(incusArgs, envFlags...)
-	incusArgs = append(incusArgs, "--", "bash", "-c", command)
+	args = append(args, envFlags...)
+	args = append(args, "--", "bash", "-c", command)
 
-	// Build sg command
-	incusCmd := "incus " + strings.Join(incusArgs, " ")
-	sgArgs := []string{IncusGroup, "-c", incusCmd}
-
-	// Add timeout wrapper if specified
-	var cmd *exec.Cmd
+	incusCmd := NewIncusCommand(args...)
 	if opts.Timeout != nil {
-		timeoutCmd := fmt.Sprintf("timeout %d %s", int(opts.Timeout.Seconds()), incusCmd)
-		timeoutSgArgs := []string{IncusGroup, "-c", timeoutCmd}
-		cmd = execIncusCommand(timeoutSgArgs)
-	} else {
-		cmd = execIncusCommand(sgArgs)
+		incusCmd.WithTimeout(*opts.Timeout)
 	}
+	cmd := incusCmd.Cmd()
 
 	if opts.CaptureOutput {
 		var stdout bytes.Buffer
@@ -331,14 +371,11 @@ func PublishContainer(containerName, aliasName, description string) (string, err
 	}
 
 	// Extract fingerprint from output
-	re := regexp.MustCompile(`fingerprint:\s*([a-f0-9]+)`)
-	matches := re.FindStringSubmatch(output)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("could not extract fingerprint from output")
+	fingerprint, err := extractFingerprint(output)
+	if err != nil {
+		return "", err
 	}
 
-	fingerprint := matches[1]
-
 	// Cleanup container after successful publish
 	if err := DeleteContainer(containerName); err != nil {
 		return fingerprint, err // Return fingerprint even if cleanup fails
@@ -347,6 +384,55 @@ func PublishContainer(containerName, aliasName, description string) (string, err
 	return fingerprint, nil
 }
 
+// PublishSnapshot publishes a container snapshot as an image.
+// Unlike PublishContainer, the source container keeps running and is not deleted.
+func PublishSnapshot(containerName, snapshotName, aliasName, description string) (string, error) {
+	args := []string{"publish", containerName + "/" + snapshotName, "--alias", aliasName}
+	if description != "" {
+		args = append(args, fmt.Sprintf("description=%s", description))
+	}
+
+	output, err := IncusOutput(args...)
+	if err != nil {
+		return "", err
+	}
+
+	return extractFingerprint(output)
+}
+
+// ExportImage writes an image to a tarball with 'incus image export'. Incus adds
+// the file extension itself (e.g. target.tar.gz) and writes a separate rootfs
+// file for split images.
+func ExportImage(aliasName, target string) error {
+	return IncusExec("image", "export", aliasName, target)
+}
+
+// ImportImage imports an image tarball (plus an optional rootfs file for split
+// images) and returns the new image's fingerprint
+func ImportImage(tarball, rootfs string) (string, error) {
+	args := []string{"image", "import", tarball}
+	if rootfs != "" {
+		args = append(args, rootfs)
+	}
+
+	output, err := IncusOutput(args...)
+	if err != nil {
+		return "", err
+	}
+
+	return extractFingerprint(output)
+}
+
+// extractFingerprint extracts the image fingerprint from `incus publish` / `incus image import` output
+func extractFingerprint(output string) (string, error) {
+	re := regexp.MustCompile(`fingerprint:\s*([a-f0-9]+)`)
+	matches := re.FindStringSubmatch(output)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not extract fingerprint from output")
+	}
+	return matches[1], nil
+}
+
 // DeleteImage deletes an image by alias
 func DeleteImage(aliasName string) error {
 	return IncusExecQuiet("image", "delete", aliasName)
@@ -380,6 +466,126 @@ func ImageExists(aliasName string) (bool, error) {
 	return false, nil
 }
 
+// RemoteImageRef splits an image reference such as images:ubuntu/24.04 into
+// its remote and alias. Local images (no remote, or local:) report ok false.
+func RemoteImageRef(image string) (remote, alias string, ok bool) {
+	remote, alias, found := strings.Cut(image, ":")
+	if !found || remote == "" || remote == "local" || alias == "" {
+		return "", "", false
+	}
+	return remote, alias, true
+}
+
+// PulledImageAlias is the local alias under which RefreshImage keeps the
+// latest pulled version of a remote image, e.g. coi-pulled/images/ubuntu/24.04
+func PulledImageAlias(remote, alias string) string {
+	return "coi-pulled/" + remote + "/" + alias
+}
+
+// RefreshImage makes sure the local image store has the latest version of
+// image. Remote references are downloaded again if the remote publishes a
+// newer version (incus init then starts from it); local aliases are refreshed
+// from the remote they were copied from.
+func RefreshImage(image string) error {
+	if remote, alias, ok := RemoteImageRef(image); ok {
+		return pullRemoteImage(image, PulledImageAlias(remote, alias))
+	}
+
+	alias := strings.TrimPrefix(image, "local:")
+	output, err := NewIncusCommand("image", "refresh", alias).Cmd().CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to refresh image %s (only images copied from a remote can be refreshed): %s", alias, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// pullRemoteImage copies the current version of a remote image to the local
+// store under localAlias, replacing the version pulled before. Keeping the
+// copy aliased means it is replaced on the next pull instead of lingering.
+func pullRemoteImage(image, localAlias string) error {
+	latest, err := ImageFingerprint(image)
+	if err != nil {
+		return fmt.Errorf("failed to look up image %s: %w", image, err)
+	}
+	previous, _ := ImageFingerprint(localAlias) // Empty if never pulled before
+	if previous == latest {
+		return nil
+	}
+
+	if previous != "" {
+		if err := IncusExecQuiet("image", "alias", "delete", localAlias); err != nil {
+			return fmt.Errorf("failed to replace image alias %s: %w", localAlias, err)
+		}
+	}
+	if _, err := ImageFingerprint(latest); err == nil {
+		// Already in the local store (e.g. cached by an earlier launch): just alias it
+		err = IncusExecQuiet("image", "alias", "create", localAlias, latest)
+	} else {
+		err = IncusExecQuiet("image", "copy", image, "local:", "--alias", localAlias)
+	}
+	if err != nil {
+		if previous != "" {
+			_ = IncusExecQuiet("image", "alias", "create", localAlias, previous) // Keep the previous version tracked
+		}
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+
+	// Containers keep their own copy of the root filesystem, so the previous
+	// version can go unless something else still refers to it by alias
+	if previous != "" {
+		if aliases, err := ImageAliases(previous); err == nil && len(aliases) == 0 {
+			_ = DeleteImage(previous)
+		}
+	}
+	return nil
+}
+
+// ImageFingerprint returns the fingerprint of an image reference (alias,
+// fingerprint, or remote:alias)
+func ImageFingerprint(image string) (string, error) {
+	output, err := IncusOutput("image", "info", image)
+	if err != nil {
+		return "", err
+	}
+	return parseImageInfoFingerprint(output)
+}
+
+// parseImageInfoFingerprint extracts the fingerprint from 'incus image info' output
+func parseImageInfoFingerprint(output string) (string, error) {
+	matches := regexp.MustCompile(`(?m)^Fingerprint:\s*([a-f0-9]+)\s*$`).FindStringSubmatch(output)
+	if len(matches) < 2 {
+		return "", fmt.Errorf("could not find the image fingerprint")
+	}
+	return matches[1], nil
+}
+
+// ImageAliases returns the aliases of the local image with the given fingerprint
+func ImageAliases(fingerprint string) ([]string, error) {
+	output, err := IncusOutput("image", "list", fingerprint, "--format=json")
+	if err != nil {
+		return nil, err
+	}
+	var images []struct {
+		Fingerprint string `json:"fingerprint"`
+		Aliases     []struct {
+			Name string `json:"name"`
+		} `json:"aliases"`
+	}
+	if err := json.Unmarshal([]byte(output), &images); err != nil {
+		return nil, err
+	}
+	var aliases []string
+	for _, img := range images {
+		if img.Fingerprint != fingerprint {
+			continue
+		}
+		for _, alias := range img.Aliases {
+			aliases = append(aliases, alias.Name)
+		}
+	}
+	return aliases, nil
+}
+
 // ListImagesByPrefix lists images by alias prefix
 func ListImagesByPrefix(prefix string) ([]string, error) {
 	output, err := IncusOutput("image", "list", "--format=json")
@@ -440,22 +646,8 @@ func ListContainers(pattern string) ([]string, error) {
 	return matching, nil
 }
 
-// buildIncusCommand builds the full incus command with project flag
-func buildIncusCommand(args ...string) []string {
-	incusArgs := append([]string{"--project", IncusProject}, args...)
-
-	// Properly quote arguments for shell execution
-	quotedArgs := make([]string, len(incusArgs))
-	for i, arg := range incusArgs {
-		quotedArgs[i] = shellQuote(arg)
-	}
-
-	incusCmd := "incus " + strings.Join(quotedArgs, " ")
-	return []string{IncusGroup, "-c", incusCmd}
-}
-
-// shellQuote quotes a string for safe use in a shell command
-func shellQuote(s string) string {
+// ShellQuote quotes a string for safe use in a shell command
+func ShellQuote(s string) string {
 	// If string contains no special characters, don't quote
 	if regexp.MustCompile(`^[a-zA-Z0-9@%+=:,./_-]+$`).MatchString(s) {
 		return s
@@ -466,6 +658,16 @@ func shellQuote(s string) string {
 	return "'" + escaped + "'"
 }
 
+// ShellJoin quotes each argument with ShellQuote and joins them into a single
+// shell command line
+func ShellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = ShellQuote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
 // SnapshotCreate creates a snapshot of a container
 func SnapshotCreate(containerName, snapshotName string, stateful bool) error {
 	args := []string{"snapshot", "create", containerName, snapshotName}
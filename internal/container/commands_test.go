@@ -0,0 +1,55 @@
+package container
+
+import "testing"
+
+func TestRemoteImageRef(t *testing.T) {
+	tests := []struct {
+		image  string
+		remote string
+		alias  string
+		ok     bool
+	}{
+		{"images:ubuntu/24.04", "images", "ubuntu/24.04", true},
+		{"ubuntu:24.04", "ubuntu", "24.04", true},
+		{"coi", "", "", false},
+		{"local:coi", "", "", false},
+		{"images:", "", "", false},
+		{":coi", "", "", false},
+	}
+	for _, tt := range tests {
+		remote, alias, ok := RemoteImageRef(tt.image)
+		if remote != tt.remote || alias != tt.alias || ok != tt.ok {
+			t.Errorf("RemoteImageRef(%q) = %q, %q, %v; expected %q, %q, %v", tt.image, remote, alias, ok, tt.remote, tt.alias, tt.ok)
+		}
+	}
+}
+
+func TestPulledImageAlias(t *testing.T) {
+	if got := PulledImageAlias("images", "ubuntu/24.04"); got != "coi-pulled/images/ubuntu/24.04" {
+		t.Errorf("Unexpected alias %q", got)
+	}
+}
+
+func TestParseImageInfoFingerprint(t *testing.T) {
+	output := `Fingerprint: 3f1c0a9e2b7d4c5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6
+Size: 128.50MiB
+Architecture: x86_64
+Type: container
+Properties:
+    description: Ubuntu noble amd64 (20260301_07:42)
+Aliases:
+    - ubuntu/24.04
+`
+	got, err := parseImageInfoFingerprint(output)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := "3f1c0a9e2b7d4c5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// A property that merely mentions a fingerprint must not match
+	if _, err := parseImageInfoFingerprint("Properties:\n    fingerprint: abc\n"); err == nil {
+		t.Error("Expected error when the output has no Fingerprint line")
+	}
+}
@@ -0,0 +1,298 @@
+package container
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BaselineDir returns the host directory holding the filesystem manifests
+// recorded with 'coi snapshot create --baseline' for containerName, one file
+// per snapshot name. baseDir is coi's directory (~/.coi). The manifests are kept
+// on the host, where the audited container cannot rewrite them.
+func BaselineDir(baseDir, containerName string) string {
+	return filepath.Join(baseDir, "baselines", containerName)
+}
+
+// DefaultBaselinePaths are the directories a baseline manifest covers. The
+// workspace and other mounts are skipped (find -xdev).
+var DefaultBaselinePaths = []string{"/etc", "/usr", "/opt", "/var", "/root", "/home", "/tmp"}
+
+// manifestScript lists files and symlinks under the given paths as
+// "f<TAB>path<TAB>size<TAB>mtime" and installed Debian packages as
+// "p<TAB>name<TAB>version". Unreadable paths are skipped.
+const manifestScript = `for p in "$@"; do
+  [ -e "$p" ] || continue
+  find "$p" -xdev \( -type f -o -type l \) -printf 'f\t%p\t%s\t%T@\n' 2>/dev/null
+done
+if command -v dpkg-query >/dev/null 2>&1; then
+  dpkg-query -W -f='p\t${Package}\t${Version}\n' 2>/dev/null
+fi
+exit 0`
+
+// FileEntry is a file recorded in a manifest
+type FileEntry struct {
+	Size    int64
+	ModTime string // Seconds since the epoch as printed by find, compared as text
+}
+
+// Manifest is a listing of a container's files and installed packages
+type Manifest struct {
+	Recorded time.Time
+	Instance string // volatile.uuid of the container the listing was taken of
+	Paths    []string
+	Files    map[string]FileEntry
+	Packages map[string]string // Package name -> version
+}
+
+// CaptureManifest lists the files under paths and the installed packages of
+// the running container, in the format ParseManifest reads
+func (m *Manager) CaptureManifest(paths []string) (string, error) {
+	args := append([]string{"sh", "-c", manifestScript, "sh"}, paths...)
+	output, err := m.ExecArgsCapture(args, ExecCommandOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list container files: %w", err)
+	}
+
+	var header strings.Builder
+	fmt.Fprintf(&header, "# recorded %s\n", time.Now().UTC().Format(time.RFC3339))
+	if instance, err := m.instanceUUID(); err == nil && instance != "" {
+		fmt.Fprintf(&header, "# instance %s\n", instance)
+	}
+	for _, path := range paths {
+		fmt.Fprintf(&header, "# path %s\n", path)
+	}
+	return header.String() + output, nil
+}
+
+// instanceUUID returns the container's volatile.uuid, which changes when a
+// container with the same name is created again
+func (m *Manager) instanceUUID() (string, error) {
+	output, err := IncusOutput("config", "get", m.ContainerName, "volatile.uuid")
+	return strings.TrimSpace(output), err
+}
+
+// RecordBaseline captures a manifest of paths and stores it in dir (see
+// BaselineDir) under name, for a later 'coi diff'
+func (m *Manager) RecordBaseline(dir, name string, paths []string) error {
+	manifest, err := m.CaptureManifest(paths)
+	if err != nil {
+		return err
+	}
+	return writeBaseline(dir, name, manifest)
+}
+
+// LoadBaseline reads the baseline manifest stored in dir under name. A
+// baseline of an earlier container with the same name is an error.
+func (m *Manager) LoadBaseline(dir, name string) (*Manifest, error) {
+	manifest, err := readBaseline(dir, name)
+	if err != nil {
+		return nil, fmt.Errorf("no baseline '%s' for container %s (record one with 'coi snapshot create %s --baseline')", name, m.ContainerName, name)
+	}
+	if manifest.Instance != "" {
+		if instance, err := m.instanceUUID(); err == nil && instance != "" && instance != manifest.Instance {
+			return nil, fmt.Errorf("baseline '%s' was recorded for an earlier container named %s (record a new one with 'coi snapshot create <name> --baseline')", name, m.ContainerName)
+		}
+	}
+	return manifest, nil
+}
+
+// LatestBaseline returns the name of the most recently recorded baseline in dir
+func (m *Manager) LatestBaseline(dir string) (string, error) {
+	name, err := latestBaseline(dir)
+	if err != nil {
+		return "", fmt.Errorf("no baseline recorded for container %s (record one with 'coi snapshot create <name> --baseline')", m.ContainerName)
+	}
+	return name, nil
+}
+
+// baselinePath returns the file of baseline name in dir, rejecting names that
+// would point outside it
+func baselinePath(dir, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid baseline name '%s'", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// writeBaseline stores manifest in dir under name
+func writeBaseline(dir, name, manifest string) error {
+	path, err := baselinePath(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+		return fmt.Errorf("failed to store baseline: %w", err)
+	}
+	return nil
+}
+
+// readBaseline parses the manifest stored in dir under name
+func readBaseline(dir, name string) (*Manifest, error) {
+	path, err := baselinePath(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseManifest(string(data)), nil
+}
+
+// latestBaseline returns the name of the most recently written manifest in dir
+func latestBaseline(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestTime time.Time
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if latest == "" || info.ModTime().After(latestTime) {
+			latest, latestTime = entry.Name(), info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", errors.New("no baselines")
+	}
+	return latest, nil
+}
+
+// ParseManifest parses a manifest written by CaptureManifest. Malformed lines
+// are skipped.
+func ParseManifest(text string) *Manifest {
+	manifest := &Manifest{
+		Files:    make(map[string]FileEntry),
+		Packages: make(map[string]string),
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "# recorded "); ok {
+			manifest.Recorded, _ = time.Parse(time.RFC3339, value)
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "# instance "); ok {
+			manifest.Instance = value
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "# path "); ok {
+			manifest.Paths = append(manifest.Paths, value)
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		switch {
+		case len(fields) == 4 && fields[0] == "f":
+			size, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			manifest.Files[fields[1]] = FileEntry{Size: size, ModTime: fields[3]}
+		case len(fields) == 3 && fields[0] == "p":
+			manifest.Packages[fields[1]] = fields[2]
+		}
+	}
+	return manifest
+}
+
+// FilesystemDiff lists what changed between two manifests. Each list is sorted.
+type FilesystemDiff struct {
+	Added    []string // Files present only in the later manifest
+	Removed  []string // Files present only in the earlier manifest
+	Modified []string // Files whose size or modification time changed
+
+	PackagesInstalled []string // "name version"
+	PackagesRemoved   []string // "name version"
+	PackagesChanged   []string // "name old -> new"
+}
+
+// Empty reports whether nothing changed
+func (d FilesystemDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0 &&
+		len(d.PackagesInstalled) == 0 && len(d.PackagesRemoved) == 0 && len(d.PackagesChanged) == 0
+}
+
+// DiffManifests compares a baseline manifest with a later one
+func DiffManifests(before, after *Manifest) FilesystemDiff {
+	var diff FilesystemDiff
+
+	for path, entry := range after.Files {
+		previous, ok := before.Files[path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, path)
+		case previous != entry:
+			diff.Modified = append(diff.Modified, path)
+		}
+	}
+	for path := range before.Files {
+		if _, ok := after.Files[path]; !ok {
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+
+	for name, version := range after.Packages {
+		previous, ok := before.Packages[name]
+		switch {
+		case !ok:
+			diff.PackagesInstalled = append(diff.PackagesInstalled, name+" "+version)
+		case previous != version:
+			diff.PackagesChanged = append(diff.PackagesChanged, fmt.Sprintf("%s %s -> %s", name, previous, version))
+		}
+	}
+	for name, version := range before.Packages {
+		if _, ok := after.Packages[name]; !ok {
+			diff.PackagesRemoved = append(diff.PackagesRemoved, name+" "+version)
+		}
+	}
+
+	for _, list := range [][]string{diff.Added, diff.Removed, diff.Modified, diff.PackagesInstalled, diff.PackagesRemoved, diff.PackagesChanged} {
+		sort.Strings(list)
+	}
+	return diff
+}
+
+// FilterPaths keeps only the file changes under one of prefixes (all of them
+// if prefixes is empty). Package changes are kept as they are.
+func (d FilesystemDiff) FilterPaths(prefixes []string) FilesystemDiff {
+	if len(prefixes) == 0 {
+		return d
+	}
+
+	keep := func(paths []string) []string {
+		var kept []string
+		for _, path := range paths {
+			for _, prefix := range prefixes {
+				prefix = strings.TrimSuffix(prefix, "/")
+				if path == prefix || strings.HasPrefix(path, prefix+"/") {
+					kept = append(kept, path)
+					break
+				}
+			}
+		}
+		return kept
+	}
+
+	d.Added = keep(d.Added)
+	d.Removed = keep(d.Removed)
+	d.Modified = keep(d.Modified)
+	return d
+}
@@ -0,0 +1,142 @@
+package container
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const baselineManifest = "# recorded 2026-01-08T10:30:00Z\n" +
+	"# instance 6f1c2d7e-0b9a-4a57-9d3e-2c1f0e8b7a65\n" +
+	"# path /etc\n" +
+	"# path /usr\n" +
+	"f\t/etc/hosts\t120\t1767868200.0000000000\n" +
+	"f\t/etc/motd\t10\t1767868200.0000000000\n" +
+	"f\t/usr/bin/curl\t2048\t1767868200.0000000000\n" +
+	"p\tcurl\t7.88.1\n" +
+	"p\tvim\t9.0\n"
+
+const laterManifest = "# recorded 2026-01-08T12:00:00Z\n" +
+	"f\t/etc/hosts\t140\t1767875400.0000000000\n" +
+	"f\t/usr/bin/curl\t2048\t1767868200.0000000000\n" +
+	"f\t/usr/local/bin/agent-tool\t4096\t1767875400.0000000000\n" +
+	"not a manifest line\n" +
+	"p\tcurl\t7.88.2\n" +
+	"p\tnodejs\t20.11.0\n"
+
+func TestParseManifest(t *testing.T) {
+	manifest := ParseManifest(baselineManifest)
+
+	if want := time.Date(2026, 1, 8, 10, 30, 0, 0, time.UTC); !manifest.Recorded.Equal(want) {
+		t.Errorf("Expected recorded time %v, got %v", want, manifest.Recorded)
+	}
+	if !slices.Equal(manifest.Paths, []string{"/etc", "/usr"}) {
+		t.Errorf("Unexpected paths: %v", manifest.Paths)
+	}
+	if len(manifest.Files) != 3 {
+		t.Errorf("Expected 3 files, got %d", len(manifest.Files))
+	}
+	if entry := manifest.Files["/etc/hosts"]; entry.Size != 120 {
+		t.Errorf("Expected /etc/hosts size 120, got %d", entry.Size)
+	}
+	if manifest.Packages["vim"] != "9.0" {
+		t.Errorf("Expected vim 9.0, got %q", manifest.Packages["vim"])
+	}
+
+	if manifest.Instance != "6f1c2d7e-0b9a-4a57-9d3e-2c1f0e8b7a65" {
+		t.Errorf("Expected instance UUID, got %q", manifest.Instance)
+	}
+	if later := ParseManifest(laterManifest); later.Instance != "" || len(later.Files) != 3 {
+		t.Errorf("Expected no instance and 3 files (malformed line skipped), got %q and %d", later.Instance, len(later.Files))
+	}
+}
+
+func TestBaselineStorage(t *testing.T) {
+	dir := BaselineDir(t.TempDir(), "coi-abc-1")
+
+	if _, err := latestBaseline(dir); err == nil {
+		t.Error("Expected an error without baselines")
+	}
+
+	if err := writeBaseline(dir, "base", baselineManifest); err != nil {
+		t.Fatalf("writeBaseline() unexpected error: %v", err)
+	}
+	if err := writeBaseline(dir, "after-install", laterManifest); err != nil {
+		t.Fatalf("writeBaseline() unexpected error: %v", err)
+	}
+	// Make "base" the most recent regardless of filesystem timestamp granularity
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "base"), future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := latestBaseline(dir)
+	if err != nil || latest != "base" {
+		t.Errorf("Expected latest baseline 'base', got %q (%v)", latest, err)
+	}
+
+	manifest, err := readBaseline(dir, "base")
+	if err != nil {
+		t.Fatalf("readBaseline() unexpected error: %v", err)
+	}
+	if len(manifest.Files) != 3 || len(manifest.Packages) != 2 {
+		t.Errorf("Expected the stored manifest back, got %+v", manifest)
+	}
+
+	for _, name := range []string{"", ".", "..", "../escape", `a\b`} {
+		if err := writeBaseline(dir, name, baselineManifest); err == nil {
+			t.Errorf("Expected invalid baseline name %q to be rejected", name)
+		}
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	diff := DiffManifests(ParseManifest(baselineManifest), ParseManifest(laterManifest))
+
+	checks := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"added", diff.Added, []string{"/usr/local/bin/agent-tool"}},
+		{"removed", diff.Removed, []string{"/etc/motd"}},
+		{"modified", diff.Modified, []string{"/etc/hosts"}},
+		{"installed", diff.PackagesInstalled, []string{"nodejs 20.11.0"}},
+		{"removed packages", diff.PackagesRemoved, []string{"vim 9.0"}},
+		{"changed packages", diff.PackagesChanged, []string{"curl 7.88.1 -> 7.88.2"}},
+	}
+	for _, c := range checks {
+		if !slices.Equal(c.got, c.want) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, c.got)
+		}
+	}
+
+	if diff.Empty() {
+		t.Error("Expected a non-empty diff")
+	}
+	if !DiffManifests(ParseManifest(baselineManifest), ParseManifest(baselineManifest)).Empty() {
+		t.Error("Expected an empty diff for identical manifests")
+	}
+}
+
+func TestFilesystemDiffFilterPaths(t *testing.T) {
+	diff := DiffManifests(ParseManifest(baselineManifest), ParseManifest(laterManifest))
+
+	filtered := diff.FilterPaths([]string{"/usr/local/"})
+	if !slices.Equal(filtered.Added, []string{"/usr/local/bin/agent-tool"}) {
+		t.Errorf("Expected only /usr/local changes, got added %v", filtered.Added)
+	}
+	if len(filtered.Removed) != 0 || len(filtered.Modified) != 0 {
+		t.Errorf("Expected /etc changes filtered out, got removed %v modified %v", filtered.Removed, filtered.Modified)
+	}
+	if len(filtered.PackagesChanged) != 1 {
+		t.Errorf("Expected package changes to be kept, got %v", filtered.PackagesChanged)
+	}
+
+	// A prefix must match whole path components
+	if got := diff.FilterPaths([]string{"/et"}); len(got.Modified) != 0 {
+		t.Errorf("Expected /et not to match /etc/hosts, got %v", got.Modified)
+	}
+}
@@ -0,0 +1,78 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// IncusCommand builds a single incus invocation. It is the one place that adds
+// the global --project flag, quotes arguments and wraps the call in
+// `sg incus-admin -c` when DetectIncusAccess found the group is not active, so
+// every helper that runs incus behaves the same way.
+//
+// Remotes are not a global incus flag - they are selected by prefixing instance
+// and image names ("remote:name") - so they are passed as part of the arguments.
+type IncusCommand struct {
+	args    []string
+	project string
+	timeout time.Duration
+}
+
+// NewIncusCommand starts building an incus command with the given arguments
+// (e.g. "exec", name, "--", "true") in the default project
+func NewIncusCommand(args ...string) *IncusCommand {
+	return &IncusCommand{args: args, project: IncusProject}
+}
+
+// WithProject runs the command in project instead of the default one ("" keeps
+// the default)
+func (c *IncusCommand) WithProject(project string) *IncusCommand {
+	if project != "" {
+		c.project = project
+	}
+	return c
+}
+
+// WithTimeout kills the command after d using timeout(1); 0 disables it
+func (c *IncusCommand) WithTimeout(d time.Duration) *IncusCommand {
+	c.timeout = d
+	return c
+}
+
+// Args returns the full incus argument list, global flags first
+func (c *IncusCommand) Args() []string {
+	return append([]string{"--project", c.project}, c.args...)
+}
+
+// String returns the command as a quoted shell command line (without sg)
+func (c *IncusCommand) String() string {
+	parts := make([]string, 0, len(c.args)+4)
+	if c.timeout > 0 {
+		parts = append(parts, "timeout", fmt.Sprintf("%d", int(c.timeout.Seconds())))
+	}
+	parts = append(parts, ShellQuote(incusBinary()))
+	for _, arg := range c.Args() {
+		parts = append(parts, ShellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Cmd returns an exec.Cmd for the command
+func (c *IncusCommand) Cmd() *exec.Cmd {
+	return c.CmdContext(context.Background())
+}
+
+// CmdContext returns an exec.Cmd for the command that is killed when ctx is done
+func (c *IncusCommand) CmdContext(ctx context.Context) *exec.Cmd {
+	if DetectIncusAccess() == AccessDirect {
+		if c.timeout > 0 {
+			return exec.CommandContext(ctx, "sh", "-c", c.String())
+		}
+		return exec.CommandContext(ctx, incusBinary(), c.Args()...)
+	}
+	// Use sg for group permissions
+	return exec.CommandContext(ctx, "sg", IncusGroup, "-c", c.String())
+}
@@ -0,0 +1,131 @@
+package container
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeIncusAccess makes commands use mode and the incus binary at path for
+// the duration of the test
+func fakeIncusAccess(t *testing.T, mode IncusAccessMode, path string) {
+	t.Helper()
+	DetectIncusAccess() // Run the real probe first so it cannot overwrite the fake later
+	previousMode, previousPath := accessMode, incusPath
+	accessMode, incusPath = mode, path
+	t.Cleanup(func() { accessMode, incusPath = previousMode, previousPath })
+}
+
+func TestIncusCommandArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      *IncusCommand
+		expected []string
+	}{
+		{
+			name:     "default project",
+			cmd:      NewIncusCommand("list", "--format=json"),
+			expected: []string{"--project", "default", "list", "--format=json"},
+		},
+		{
+			name:     "custom project",
+			cmd:      NewIncusCommand("info", "coi-abc-1").WithProject("team"),
+			expected: []string{"--project", "team", "info", "coi-abc-1"},
+		},
+		{
+			name:     "empty project keeps the default",
+			cmd:      NewIncusCommand("info").WithProject(""),
+			expected: []string{"--project", "default", "info"},
+		},
+		{
+			name:     "remote is part of the arguments",
+			cmd:      NewIncusCommand("image", "copy", "images:ubuntu/22.04", "local:").WithProject("team"),
+			expected: []string{"--project", "team", "image", "copy", "images:ubuntu/22.04", "local:"},
+		},
+		{
+			name:     "no arguments",
+			cmd:      NewIncusCommand(),
+			expected: []string{"--project", "default"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.Args(); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIncusCommandString(t *testing.T) {
+	fakeIncusAccess(t, AccessDirect, "/usr/bin/incus")
+
+	tests := []struct {
+		name     string
+		cmd      *IncusCommand
+		expected string
+	}{
+		{
+			name:     "plain arguments",
+			cmd:      NewIncusCommand("list"),
+			expected: "/usr/bin/incus --project default list",
+		},
+		{
+			name:     "arguments are quoted",
+			cmd:      NewIncusCommand("exec", "coi-abc-1", "--", "bash", "-c", "echo 'hi'; ls $HOME").WithProject("my project"),
+			expected: `/usr/bin/incus --project 'my project' exec coi-abc-1 -- bash -c 'echo '"'"'hi'"'"'; ls $HOME'`,
+		},
+		{
+			name:     "timeout",
+			cmd:      NewIncusCommand("info").WithTimeout(30 * time.Second),
+			expected: "timeout 30 /usr/bin/incus --project default info",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd.String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIncusCommandCmd(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     IncusAccessMode
+		cmd      *IncusCommand
+		expected []string
+	}{
+		{
+			name:     "direct",
+			mode:     AccessDirect,
+			cmd:      NewIncusCommand("list", "coi-"),
+			expected: []string{"/usr/bin/incus", "--project", "default", "list", "coi-"},
+		},
+		{
+			name:     "direct with timeout runs through sh",
+			mode:     AccessDirect,
+			cmd:      NewIncusCommand("list").WithTimeout(5 * time.Second),
+			expected: []string{"sh", "-c", "timeout 5 /usr/bin/incus --project default list"},
+		},
+		{
+			name:     "sg wrapper",
+			mode:     AccessSG,
+			cmd:      NewIncusCommand("delete", "coi abc").WithProject("team"),
+			expected: []string{"sg", IncusGroup, "-c", "/usr/bin/incus --project team delete 'coi abc'"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeIncusAccess(t, tt.mode, "/usr/bin/incus")
+			if got := tt.cmd.CmdContext(context.Background()).Args; !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
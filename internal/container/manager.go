@@ -1,14 +1,18 @@
 package container
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
-	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -29,6 +33,32 @@ func (e *ExitError) Error() string {
 	return fmt.Sprintf("exit status %d", e.ExitCode)
 }
 
+// CommandExitCode extracts the exit code of a command run with incus exec, from
+// either a captured run (ExitError) or an interactive one (exec.ExitError)
+func CommandExitCode(err error) (int, bool) {
+	var incusErr *ExitError
+	if errors.As(err, &incusErr) {
+		return incusErr.ExitCode, true
+	}
+	var execErr *exec.ExitError
+	if errors.As(err, &execErr) {
+		return execErr.ExitCode(), true
+	}
+	return 0, false
+}
+
+// EnvMap turns KEY=VALUE assignments (as given to -e) into the Env of
+// ExecCommandOptions. Values may contain "="; an assignment without one sets
+// an empty value.
+func EnvMap(assignments []string) map[string]string {
+	env := make(map[string]string, len(assignments))
+	for _, e := range assignments {
+		key, value, _ := strings.Cut(e, "=")
+		env[key] = value
+	}
+	return env
+}
+
 // NewManager creates a new container manager
 func NewManager(containerName string) *Manager {
 	return &Manager{
@@ -52,6 +82,29 @@ func (m *Manager) Stop(force bool) error {
 	return IncusExec("stop", m.ContainerName)
 }
 
+// StopWithTimeout stops the container gracefully, waiting up to timeout for it
+// to shut down (incus stop --timeout), and force-stops it if it is still
+// running after that. A timeout of zero waits indefinitely, like Stop(false).
+func (m *Manager) StopWithTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return m.Stop(false)
+	}
+
+	seconds := max(int(timeout.Round(time.Second)/time.Second), 1)
+	gracefulErr := IncusExec("stop", m.ContainerName, "--timeout", strconv.Itoa(seconds))
+	if gracefulErr == nil {
+		return nil
+	}
+	if running, err := m.Running(); err == nil && !running {
+		return nil
+	}
+
+	if err := m.Stop(true); err != nil {
+		return fmt.Errorf("graceful stop failed (%v) and force stop failed: %w", gracefulErr, err)
+	}
+	return nil
+}
+
 // Delete deletes the container
 func (m *Manager) Delete(force bool) error {
 	if force {
@@ -65,6 +118,44 @@ func (m *Manager) Running() (bool, error) {
 	return ContainerRunning(m.ContainerName)
 }
 
+// ErrPortTimeout is returned by WaitForPort when the port never opened
+var ErrPortTimeout = errors.New("timed out waiting for port")
+
+// WaitForPort waits until something inside the container accepts TCP
+// connections on port (checked once a second with bash's /dev/tcp, like the
+// image builder's network check). Returns ErrPortTimeout after timeout.
+func (m *Manager) WaitForPort(port int, timeout time.Duration) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %d", port)
+	}
+
+	check := portCheckCommand(port)
+	return pollPort(port, timeout, time.Second, func() bool {
+		_, err := m.ExecCommand(check, ExecCommandOptions{Capture: true})
+		return err == nil
+	})
+}
+
+// portCheckCommand succeeds once something accepts connections on port
+func portCheckCommand(port int) string {
+	return fmt.Sprintf("timeout 2 bash -c 'exec 3<>/dev/tcp/127.0.0.1/%d' 2>/dev/null", port)
+}
+
+// pollPort calls open every interval until it reports the port open, giving up
+// with ErrPortTimeout once timeout has passed. open is always tried at least once.
+func pollPort(port int, timeout, interval time.Duration, open func() bool) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if open() {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w %d after %s", ErrPortTimeout, port, timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
 // Exists checks if container exists (running or stopped)
 func (m *Manager) Exists() (bool, error) {
 	output, err := IncusOutput("list", "^"+m.ContainerName+"$", "--format=csv", "--columns=n")
@@ -74,6 +165,40 @@ func (m *Manager) Exists() (bool, error) {
 	return len(output) > 0 && output != "\n", nil
 }
 
+// DeviceConfig returns a config key of one of the container's devices
+// (incus config device get), e.g. the path of a disk device
+func (m *Manager) DeviceConfig(device, key string) (string, error) {
+	output, err := IncusOutput("config", "device", "get", m.ContainerName, device, key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// Rename renames the (stopped) container with incus rename
+func (m *Manager) Rename(newName string) error {
+	if err := IncusExec("rename", m.ContainerName, newName); err != nil {
+		return err
+	}
+	m.ContainerName = newName
+	return nil
+}
+
+// ConsoleLog returns the container's console log (incus console --show-log),
+// which holds boot output such as failing init units
+func (m *Manager) ConsoleLog() (string, error) {
+	return IncusOutputRaw("console", m.ContainerName, "--show-log")
+}
+
+// TailLines returns the last n lines of text, ignoring trailing newlines
+func TailLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
 // Start starts a stopped container
 func (m *Manager) Start() error {
 	return IncusExec("start", m.ContainerName)
@@ -93,6 +218,47 @@ func (m *Manager) MountDisk(name, source, path string, shift bool) error {
 	return IncusExec(args...)
 }
 
+// AddSocketProxy exposes a host unix socket inside the container via an Incus proxy device.
+// A disk device cannot bind-mount a socket reliably, so the proxy listens on
+// containerPath and forwards connections to hostSocket. Any existing device
+// with the same name is replaced (the host socket path may have changed).
+func (m *Manager) AddSocketProxy(name, hostSocket, containerPath string, uid, gid int) error {
+	// Ignore errors - device may not exist yet
+	_ = IncusExecQuiet("config", "device", "remove", m.ContainerName, name)
+
+	return IncusExec(
+		"config", "device", "add", m.ContainerName, name, "proxy",
+		fmt.Sprintf("connect=unix:%s", hostSocket),
+		fmt.Sprintf("listen=unix:%s", containerPath),
+		"bind=container",
+		fmt.Sprintf("uid=%d", uid),
+		fmt.Sprintf("gid=%d", gid),
+		"mode=0600",
+	)
+}
+
+// RemoveDevice removes the named device from the container. It is not an
+// error if the container has no such device.
+func (m *Manager) RemoveDevice(name string) error {
+	output, err := IncusOutput("config", "device", "list", m.ContainerName)
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+	if !slices.Contains(strings.Fields(output), name) {
+		return nil
+	}
+	return IncusExec("config", "device", "remove", m.ContainerName, name)
+}
+
+// SetUserConfig sets a user-defined config key on the container.
+// The "user." prefix required by Incus is added if key does not already have it.
+func (m *Manager) SetUserConfig(key, value string) error {
+	if !strings.HasPrefix(key, "user.") {
+		key = "user." + key
+	}
+	return IncusExec("config", "set", m.ContainerName, key, value)
+}
+
 // Exec executes a command in the container (no output capture)
 func (m *Manager) Exec(args ...string) error {
 	cmdArgs := append([]string{"exec", m.ContainerName, "--"}, args...)
@@ -101,39 +267,13 @@ func (m *Manager) Exec(args ...string) error {
 
 // ExecArgs executes command arguments in the container with options
 func (m *Manager) ExecArgs(commandArgs []string, opts ExecCommandOptions) error {
-	args := []string{"exec", m.ContainerName}
-
-	// Add force-interactive flag for interactive sessions (required for tmux attach)
-	if opts.Interactive {
-		args = append(args, "--force-interactive")
-	}
-
-	// Add environment variables
-	for k, v := range opts.Env {
-		args = append(args, "--env", fmt.Sprintf("%s=%s", k, v))
-	}
-
-	// Add working directory
-	if opts.Cwd != "" {
-		args = append(args, "--cwd", opts.Cwd)
-	}
-
-	// Add user/group
-	if opts.User != nil {
-		args = append(args, "--user", fmt.Sprintf("%d", *opts.User))
-		group := opts.User // default to same as user
-		if opts.Group != nil {
-			group = opts.Group
-		}
-		args = append(args, "--group", fmt.Sprintf("%d", *group))
-	}
-
-	// Add command arguments
-	args = append(args, "--")
-	args = append(args, commandArgs...)
+	args := m.execArgs(commandArgs, opts)
 
 	// Support interactive mode
 	if opts.Interactive {
+		if opts.Output != nil {
+			return IncusExecInteractiveTee(opts.Output, args...)
+		}
 		return IncusExecInteractive(args...)
 	}
 
@@ -142,34 +282,15 @@ func (m *Manager) ExecArgs(commandArgs []string, opts ExecCommandOptions) error
 
 // ExecArgsCapture executes a command with raw arguments and captures output (no bash -c wrapping, preserves whitespace)
 func (m *Manager) ExecArgsCapture(commandArgs []string, opts ExecCommandOptions) (string, error) {
-	args := []string{"exec", m.ContainerName}
-
-	// Add environment variables
-	for k, v := range opts.Env {
-		args = append(args, "--env", fmt.Sprintf("%s=%s", k, v))
-	}
-
-	// Add working directory
-	if opts.Cwd != "" {
-		args = append(args, "--cwd", opts.Cwd)
-	}
-
-	// Add user/group
-	if opts.User != nil {
-		args = append(args, "--user", fmt.Sprintf("%d", *opts.User))
-		group := opts.User // default to same as user
-		if opts.Group != nil {
-			group = opts.Group
-		}
-		args = append(args, "--group", fmt.Sprintf("%d", *group))
-	}
-
-	// Add command arguments
-	args = append(args, "--")
-	args = append(args, commandArgs...)
+	return m.ExecArgsCaptureContext(context.Background(), commandArgs, opts)
+}
 
-	// Use IncusOutputRaw to preserve whitespace
-	return IncusOutputRaw(args...)
+// ExecArgsCaptureContext is ExecArgsCapture that stops the command when ctx is
+// cancelled, e.g. to bound a probe with a timeout
+func (m *Manager) ExecArgsCaptureContext(ctx context.Context, commandArgs []string, opts ExecCommandOptions) (string, error) {
+	opts.Interactive = false
+	// Use IncusOutputRawContext to preserve whitespace
+	return IncusOutputRawContext(ctx, m.execArgs(commandArgs, opts)...)
 }
 
 // ExecCommandOptions holds options for executing commands
@@ -179,11 +300,39 @@ type ExecCommandOptions struct {
 	Cwd         string
 	Env         map[string]string
 	Capture     bool
-	Interactive bool // Attach stdin/stdout/stderr for interactive sessions
+	Interactive bool      // Attach stdin/stdout/stderr for interactive sessions
+	Output      io.Writer // With Interactive, also copy stdout here (e.g. a session recording)
 }
 
 // ExecCommand executes a bash command in the container with user context
 func (m *Manager) ExecCommand(command string, opts ExecCommandOptions) (string, error) {
+	args := m.execArgs([]string{"bash", "-c", command}, opts)
+
+	if opts.Capture {
+		return IncusOutput(args...)
+	}
+
+	if opts.Interactive {
+		if opts.Output != nil {
+			return "", IncusExecInteractiveTee(opts.Output, args...)
+		}
+		return "", IncusExecInteractive(args...)
+	}
+
+	return "", IncusExec(args...)
+}
+
+// ExecStream executes a bash command in the container and calls onLine for each
+// line of stdout in real time; stderr goes to our stderr. Returns the exit
+// error once the command finishes. opts.Capture and opts.Interactive are ignored.
+func (m *Manager) ExecStream(command string, opts ExecCommandOptions, onLine func(string)) error {
+	opts.Interactive = false
+	return IncusStreamLines(onLine, m.execArgs([]string{"bash", "-c", command}, opts)...)
+}
+
+// execArgs builds the incus exec arguments running commandArgs in the
+// container with opts (environment sorted by name, so the order is stable)
+func (m *Manager) execArgs(commandArgs []string, opts ExecCommandOptions) []string {
 	args := []string{"exec", m.ContainerName}
 
 	// Add force-interactive flag for interactive sessions (required for tmux attach)
@@ -192,8 +341,8 @@ func (m *Manager) ExecCommand(command string, opts ExecCommandOptions) (string,
 	}
 
 	// Add environment variables
-	for k, v := range opts.Env {
-		args = append(args, "--env", fmt.Sprintf("%s=%s", k, v))
+	for _, k := range slices.Sorted(maps.Keys(opts.Env)) {
+		args = append(args, "--env", fmt.Sprintf("%s=%s", k, opts.Env[k]))
 	}
 
 	// Add working directory
@@ -211,18 +360,9 @@ func (m *Manager) ExecCommand(command string, opts ExecCommandOptions) (string,
 		args = append(args, "--group", fmt.Sprintf("%d", *group))
 	}
 
-	// Add command
-	args = append(args, "--", "bash", "-c", command)
-
-	if opts.Capture {
-		return IncusOutput(args...)
-	}
-
-	if opts.Interactive {
-		return "", IncusExecInteractive(args...)
-	}
-
-	return "", IncusExec(args...)
+	// Add command arguments
+	args = append(args, "--")
+	return append(args, commandArgs...)
 }
 
 // PushFile pushes a file into the container
@@ -235,8 +375,16 @@ func (m *Manager) PushFile(source, destination string) error {
 	return IncusFilePush(source, dest)
 }
 
-// PullDirectory pulls a directory from the container recursively
-func (m *Manager) PullDirectory(containerPath, localPath string) error {
+// PullDirectory pulls a directory from the container recursively.
+// The pull is aborted when ctx is cancelled, and progress (if non-nil) is
+// called periodically with the number of files and bytes received so far.
+func (m *Manager) PullDirectory(ctx context.Context, containerPath, localPath string, progress ProgressFunc) error {
+	return m.PullDirectoryExcluding(ctx, containerPath, localPath, nil, progress)
+}
+
+// PullDirectoryExcluding is PullDirectory, dropping paths that match exclude
+// (see RemoveExcluded) before they reach localPath.
+func (m *Manager) PullDirectoryExcluding(ctx context.Context, containerPath, localPath string, exclude []string, progress ProgressFunc) error {
 	// Incus creates a subdirectory when pulling, so we pull to a temp location
 	// then move the contents to the desired location
 	tempDir, err := os.MkdirTemp("", "coi-pull-*")
@@ -247,7 +395,10 @@ func (m *Manager) PullDirectory(containerPath, localPath string) error {
 
 	// Pull to temp directory (creates tempDir/dirname/)
 	source := m.ContainerName + containerPath
-	if err := IncusExec("file", "pull", "-r", source, tempDir); err != nil {
+	stopProgress := watchDirProgress(ctx, tempDir, progress)
+	err = IncusExecContext(ctx, "file", "pull", "-r", source, tempDir)
+	stopProgress()
+	if err != nil {
 		return err
 	}
 
@@ -262,10 +413,18 @@ func (m *Manager) PullDirectory(containerPath, localPath string) error {
 
 	// Move the pulled directory to the desired location
 	pulledDir := filepath.Join(tempDir, entries[0].Name())
+	if err := RemoveExcluded(pulledDir, exclude); err != nil {
+		return err
+	}
 	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
 		return err
 	}
 
+	// Report the final totals
+	if progress != nil {
+		progress(dirStats(pulledDir))
+	}
+
 	// Remove destination if it exists
 	os.RemoveAll(localPath)
 
@@ -282,7 +441,7 @@ func (m *Manager) PullDirectory(containerPath, localPath string) error {
 
 			// Copy into a temp target, then atomically rename to the final location
 			tempTarget := filepath.Join(tempDestDir, filepath.Base(localPath))
-			if err := copyDirRecursive(pulledDir, tempTarget); err != nil {
+			if err := copyDirRecursive(ctx, pulledDir, tempTarget); err != nil {
 				return err
 			}
 			return os.Rename(tempTarget, localPath)
@@ -292,6 +451,50 @@ func (m *Manager) PullDirectory(containerPath, localPath string) error {
 	return nil
 }
 
+// RemoveExcluded deletes everything under root matching one of the glob
+// patterns. Patterns are matched against slash-separated paths relative to
+// root; a pattern without "/" also matches a file or directory name at any
+// depth. Matching directories are removed with their contents.
+func RemoveExcluded(root string, patterns []string) error {
+	if len(patterns) == 0 {
+		return nil
+	}
+	return filepath.WalkDir(root, func(current string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, current)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !matchesExclude(filepath.ToSlash(rel), patterns) {
+			return nil
+		}
+		if err := os.RemoveAll(current); err != nil {
+			return fmt.Errorf("failed to remove excluded %s: %w", rel, err)
+		}
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
+
+// matchesExclude reports whether the relative path rel matches a pattern
+func matchesExclude(rel string, patterns []string) bool {
+	name := path.Base(rel)
+	for _, pattern := range patterns {
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = name
+		}
+		if matched, _ := path.Match(pattern, target); matched {
+			return true
+		}
+	}
+	return false
+}
+
 // isCrossDeviceError checks if the error is a cross-device link error (EXDEV)
 func isCrossDeviceError(err error) bool {
 	var linkErr *os.LinkError
@@ -303,8 +506,8 @@ func isCrossDeviceError(err error) bool {
 	return false
 }
 
-// copyDirRecursive copies a directory recursively from src to dst
-func copyDirRecursive(src, dst string) error {
+// copyDirRecursive copies a directory recursively from src to dst, stopping when ctx is cancelled
+func copyDirRecursive(ctx context.Context, src, dst string) error {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
 		return err
@@ -320,6 +523,10 @@ func copyDirRecursive(src, dst string) error {
 	}
 
 	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		srcPath := filepath.Join(src, entry.Name())
 		dstPath := filepath.Join(dst, entry.Name())
 
@@ -332,7 +539,7 @@ func copyDirRecursive(src, dst string) error {
 		}
 
 		if entry.IsDir() {
-			if err := copyDirRecursive(srcPath, dstPath); err != nil {
+			if err := copyDirRecursive(ctx, srcPath, dstPath); err != nil {
 				return err
 			}
 		} else {
@@ -382,8 +589,10 @@ func copyFile(src, dst string) error {
 	return dstFile.Close()
 }
 
-// PushDirectory pushes a directory to the container recursively
-func (m *Manager) PushDirectory(localPath, containerPath string) error {
+// PushDirectory pushes a directory to the container recursively.
+// The push is aborted when ctx is cancelled. Incus reports nothing while
+// pushing, so progress (if non-nil) is called once with the totals when done.
+func (m *Manager) PushDirectory(ctx context.Context, localPath, containerPath string, progress ProgressFunc) error {
 	// Check if source directory exists
 	if info, err := os.Stat(localPath); err != nil || !info.IsDir() {
 		return nil // Skip if not a directory (intentional nilerr)
@@ -397,26 +606,33 @@ func (m *Manager) PushDirectory(localPath, containerPath string) error {
 		parentPath = "/"
 	}
 	dest := m.ContainerName + parentPath
-	return IncusExec("file", "push", "-r", localPath, dest)
+	if err := IncusExecContext(ctx, "file", "push", "-r", localPath, dest); err != nil {
+		return err
+	}
+
+	if progress != nil {
+		progress(dirStats(localPath))
+	}
+	return nil
 }
 
 // Chown changes ownership of a path in the container
 func (m *Manager) Chown(path string, uid, gid int) error {
-	cmd := fmt.Sprintf("chown -R %d:%d %s", uid, gid, path)
+	cmd := fmt.Sprintf("chown -R %d:%d %s", uid, gid, ShellQuote(path))
 	_, err := m.ExecCommand(cmd, ExecCommandOptions{})
 	return err
 }
 
 // DirExists checks if a directory exists in the container
 func (m *Manager) DirExists(path string) (bool, error) {
-	cmd := fmt.Sprintf("[ -d %s ]", path)
+	cmd := fmt.Sprintf("[ -d %s ]", ShellQuote(path))
 	_, err := m.ExecCommand(cmd, ExecCommandOptions{})
 	return err == nil, nil
 }
 
 // FileExists checks if a file exists in the container
 func (m *Manager) FileExists(path string) (bool, error) {
-	cmd := fmt.Sprintf("[ -f %s ]", path)
+	cmd := fmt.Sprintf("[ -f %s ]", ShellQuote(path))
 	_, err := m.ExecCommand(cmd, ExecCommandOptions{})
 	return err == nil, nil
 }
@@ -428,16 +644,8 @@ func Available() bool {
 		return false
 	}
 
-	// On macOS, run incus directly without sg group switching
-	// macOS doesn't have the incus-admin group like Linux
-	var cmd *exec.Cmd
-	if runtime.GOOS == "darwin" {
-		cmd = exec.Command("incus", "--project", IncusProject, "info")
-	} else {
-		// Linux - use sg to run with group permissions
-		cmd = exec.Command("sg", IncusGroup, "-c", fmt.Sprintf("incus --project %s info", IncusProject))
-	}
-
+	// Run directly when possible (macOS, group already active), otherwise via sg
+	cmd := NewIncusCommand("info").Cmd()
 	cmd.Stdout = nil
 	cmd.Stderr = nil
 	return cmd.Run() == nil
@@ -451,8 +659,8 @@ func ImageExistsGlobal(imageAlias string) (bool, error) {
 // Helper function to create a file with content
 func (m *Manager) CreateFile(containerPath, content string) error {
 	// Create temp file locally
-	tmpFile := filepath.Join(os.TempDir(), fmt.Sprintf("coi-%s", filepath.Base(containerPath)))
-	if err := os.WriteFile(tmpFile, []byte(content), 0o644); err != nil {
+	tmpFile, err := writeTempFile(filepath.Base(containerPath), content)
+	if err != nil {
 		return err
 	}
 	defer os.Remove(tmpFile)
@@ -461,6 +669,43 @@ func (m *Manager) CreateFile(containerPath, content string) error {
 	return m.PushFile(tmpFile, containerPath)
 }
 
+// WriteSecretFile writes content to containerPath (mode 0600) through incus
+// exec's stdin, so unlike CreateFile it never touches the host filesystem
+func (m *Manager) WriteSecretFile(containerPath, content string) error {
+	cmd := NewIncusCommand("exec", m.ContainerName, "--", "sh", "-c", `umask 077 && cat > "$1"`, "sh", containerPath).Cmd()
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", containerPath, err)
+	}
+	return nil
+}
+
+// writeTempFile writes content to a new, uniquely named temp file and returns
+// its path. Unique names keep concurrent sessions creating files with the same
+// basename (e.g. settings.json) from pushing each other's content.
+func writeTempFile(base, content string) (string, error) {
+	file, err := os.CreateTemp("", "coi-*-"+base)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	path := file.Name()
+
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// incus file push keeps the source mode, and CreateTemp uses 0600
+		err = os.Chmod(path, 0o644)
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return path, nil
+}
+
 // ExecHostCommand executes a command on the host (not in container)
 func (m *Manager) ExecHostCommand(command string, capture bool) (string, error) {
 	// Use sg wrapper if needed, otherwise direct execution
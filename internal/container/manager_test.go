@@ -0,0 +1,238 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteTempFileConcurrent(t *testing.T) {
+	const writers = 2
+
+	paths := make([]string, writers)
+	errs := make([]error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paths[i], errs[i] = writeTempFile("settings.json", fmt.Sprintf("content %d", i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < writers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("writeTempFile() unexpected error: %v", errs[i])
+		}
+		defer os.Remove(paths[i])
+	}
+	if paths[0] == paths[1] {
+		t.Fatalf("Expected unique temp paths, both got %s", paths[0])
+	}
+
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		if want := fmt.Sprintf("content %d", i); string(data) != want {
+			t.Errorf("Temp file %s = %q, want %q", path, data, want)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o644 {
+			t.Errorf("Expected mode 0644, got %o", info.Mode().Perm())
+		}
+	}
+}
+
+func TestRemoveExcluded(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{
+		"settings.json",
+		"debug/latest.txt",
+		"projects/-workspace/abc.jsonl",
+		"projects/-workspace/run.log",
+		"todos/a.json",
+	} {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RemoveExcluded(root, []string{"debug", "*.log", "todos/*"}); err != nil {
+		t.Fatalf("RemoveExcluded() unexpected error: %v", err)
+	}
+
+	for _, kept := range []string{"settings.json", "projects/-workspace/abc.jsonl", "todos"} {
+		if _, err := os.Stat(filepath.Join(root, kept)); err != nil {
+			t.Errorf("Expected %s to be kept, got err %v", kept, err)
+		}
+	}
+	for _, removed := range []string{"debug", "projects/-workspace/run.log", "todos/a.json"} {
+		if _, err := os.Stat(filepath.Join(root, removed)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed, got err %v", removed, err)
+		}
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		text     string
+		n        int
+		expected string
+	}{
+		{"a\nb\nc\nd\n", 2, "c\nd"},
+		{"a\nb\n\n\n", 5, "a\nb"},
+		{"only", 3, "only"},
+		{"", 3, ""},
+	}
+
+	for _, tt := range tests {
+		if got := TailLines(tt.text, tt.n); got != tt.expected {
+			t.Errorf("TailLines(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.expected)
+		}
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	mgr := NewManager("coi-abc-1")
+	user, group := 1000, 2000
+
+	tests := []struct {
+		name     string
+		command  []string
+		opts     ExecCommandOptions
+		expected []string
+	}{
+		{
+			name:     "no options",
+			command:  []string{"bash", "-c", "ip addr show"},
+			expected: []string{"exec", "coi-abc-1", "--", "bash", "-c", "ip addr show"},
+		},
+		{
+			name:    "all options",
+			command: []string{"ls", "-la"},
+			opts: ExecCommandOptions{
+				User:        &user,
+				Group:       &group,
+				Cwd:         "/workspace",
+				Env:         map[string]string{"TERM": "xterm", "HOME": "/home/code", "A": "x=y"},
+				Interactive: true,
+			},
+			expected: []string{
+				"exec", "coi-abc-1", "--force-interactive",
+				"--env", "A=x=y", "--env", "HOME=/home/code", "--env", "TERM=xterm",
+				"--cwd", "/workspace", "--user", "1000", "--group", "2000",
+				"--", "ls", "-la",
+			},
+		},
+		{
+			name:     "group defaults to user",
+			command:  []string{"id"},
+			opts:     ExecCommandOptions{User: &user},
+			expected: []string{"exec", "coi-abc-1", "--user", "1000", "--group", "1000", "--", "id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mgr.execArgs(tt.command, tt.opts); !slices.Equal(got, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCommandExitCode(t *testing.T) {
+	interactiveErr := exec.Command("sh", "-c", "exit 3").Run()
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		wantOK   bool
+	}{
+		{"captured", &ExitError{ExitCode: 2}, 2, true},
+		{"wrapped", fmt.Errorf("failed: %w", &ExitError{ExitCode: 127}), 127, true},
+		{"interactive", interactiveErr, 3, true},
+		{"not an exit", errors.New("incus not found"), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, ok := CommandExitCode(tt.err)
+			if code != tt.wantCode || ok != tt.wantOK {
+				t.Errorf("CommandExitCode(%v) = %d, %t; want %d, %t", tt.err, code, ok, tt.wantCode, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEnvMap(t *testing.T) {
+	got := EnvMap([]string{"A=1", "URL=http://x?a=b", "EMPTY=", "BARE"})
+	want := map[string]string{"A": "1", "URL": "http://x?a=b", "EMPTY": "", "BARE": ""}
+	if !maps.Equal(got, want) {
+		t.Errorf("EnvMap() = %v, want %v", got, want)
+	}
+	if got := EnvMap(nil); len(got) != 0 {
+		t.Errorf("EnvMap(nil) = %v, want an empty map", got)
+	}
+}
+
+func TestWaitForPortInvalid(t *testing.T) {
+	mgr := NewManager("coi-abc-1")
+	for _, port := range []int{0, -1, 65536} {
+		err := mgr.WaitForPort(port, time.Second)
+		if err == nil || errors.Is(err, ErrPortTimeout) {
+			t.Errorf("WaitForPort(%d) = %v, want an invalid port error", port, err)
+		}
+	}
+}
+
+func TestPortCheckCommand(t *testing.T) {
+	want := "timeout 2 bash -c 'exec 3<>/dev/tcp/127.0.0.1/8080' 2>/dev/null"
+	if got := portCheckCommand(8080); got != want {
+		t.Errorf("portCheckCommand(8080) = %q, want %q", got, want)
+	}
+}
+
+func TestPollPort(t *testing.T) {
+	calls := 0
+	err := pollPort(8080, time.Second, time.Millisecond, func() bool {
+		calls++
+		return calls == 3
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("pollPort() = %v after %d calls, want success after 3", err, calls)
+	}
+
+	calls = 0
+	err = pollPort(8080, 0, time.Millisecond, func() bool {
+		calls++
+		return false
+	})
+	if !errors.Is(err, ErrPortTimeout) {
+		t.Errorf("pollPort() = %v, want ErrPortTimeout", err)
+	}
+	if calls == 0 {
+		t.Error("pollPort() gave up without checking the port")
+	}
+	if want := "timed out waiting for port 8080 after 0s"; err != nil && err.Error() != want {
+		t.Errorf("pollPort() error = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,79 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NetworkCounters are a container's network byte counters, summed over its
+// interfaces (loopback excluded). Incus counts from the container's side, so
+// BytesReceived is ingress and BytesSent is egress. The counters start at zero
+// when the container starts and are unavailable while it is stopped.
+type NetworkCounters struct {
+	BytesReceived int64 `json:"bytes_received"`
+	BytesSent     int64 `json:"bytes_sent"`
+}
+
+// instanceNetworkState is the part of 'incus list --format=json' output
+// holding the per-interface counters
+type instanceNetworkState struct {
+	Name  string `json:"name"`
+	State struct {
+		Network map[string]struct {
+			Counters struct {
+				BytesReceived int64 `json:"bytes_received"`
+				BytesSent     int64 `json:"bytes_sent"`
+			} `json:"counters"`
+		} `json:"network"`
+	} `json:"state"`
+}
+
+// counters sums the interface counters, skipping loopback
+func (s instanceNetworkState) counters() (NetworkCounters, bool) {
+	var total NetworkCounters
+	found := false
+	for name, iface := range s.State.Network {
+		if name == "lo" {
+			continue
+		}
+		total.BytesReceived += iface.Counters.BytesReceived
+		total.BytesSent += iface.Counters.BytesSent
+		found = true
+	}
+	return total, found
+}
+
+// NetworkCounters returns the container's current network byte counters
+func (m *Manager) NetworkCounters() (NetworkCounters, error) {
+	output, err := IncusOutput("list", "^"+m.ContainerName+"$", "--format=json")
+	if err != nil {
+		return NetworkCounters{}, fmt.Errorf("failed to get container state: %w", err)
+	}
+	counters, err := ParseNetworkCounters(output)
+	if err != nil {
+		return NetworkCounters{}, err
+	}
+	c, ok := counters[m.ContainerName]
+	if !ok {
+		return NetworkCounters{}, fmt.Errorf("no network counters for container %s (is it running?)", m.ContainerName)
+	}
+	return c, nil
+}
+
+// ParseNetworkCounters reads the network counters of every container in
+// 'incus list --format=json' output. Containers without network state
+// (stopped) are left out.
+func ParseNetworkCounters(output string) (map[string]NetworkCounters, error) {
+	var instances []instanceNetworkState
+	if err := json.Unmarshal([]byte(output), &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse container state: %w", err)
+	}
+
+	counters := make(map[string]NetworkCounters)
+	for _, instance := range instances {
+		if c, ok := instance.counters(); ok {
+			counters[instance.Name] = c
+		}
+	}
+	return counters, nil
+}
@@ -0,0 +1,34 @@
+package container
+
+import "testing"
+
+func TestParseNetworkCounters(t *testing.T) {
+	output := `[
+  {"name": "coi-abc-1", "state": {"network": {
+    "eth0": {"counters": {"bytes_received": 1048576, "bytes_sent": 2048}},
+    "eth1": {"counters": {"bytes_received": 100, "bytes_sent": 50}},
+    "lo": {"counters": {"bytes_received": 999999, "bytes_sent": 999999}}
+  }}},
+  {"name": "coi-abc-2", "state": {"network": null}},
+  {"name": "coi-abc-3", "state": null}
+]`
+
+	counters, err := ParseNetworkCounters(output)
+	if err != nil {
+		t.Fatalf("ParseNetworkCounters failed: %v", err)
+	}
+
+	want := NetworkCounters{BytesReceived: 1048676, BytesSent: 2098}
+	if got := counters["coi-abc-1"]; got != want {
+		t.Errorf("Expected %+v (loopback excluded), got %+v", want, got)
+	}
+	for _, name := range []string{"coi-abc-2", "coi-abc-3"} {
+		if _, ok := counters[name]; ok {
+			t.Errorf("Expected no counters for stopped container %s", name)
+		}
+	}
+
+	if _, err := ParseNetworkCounters("not json"); err == nil {
+		t.Error("Expected an error for invalid output")
+	}
+}
@@ -0,0 +1,69 @@
+package container
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// progressInterval is how often directory transfers report progress
+var progressInterval = 2 * time.Second
+
+// TransferProgress describes how much of a directory transfer has completed
+type TransferProgress struct {
+	Files int
+	Bytes int64
+}
+
+// ProgressFunc receives periodic transfer progress updates (may be nil)
+type ProgressFunc func(TransferProgress)
+
+// dirStats counts regular files and their total size under dir
+func dirStats(dir string) TransferProgress {
+	var stats TransferProgress
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Files may appear/disappear while a transfer is running
+		}
+		if d.Type().IsRegular() {
+			stats.Files++
+			if info, err := d.Info(); err == nil {
+				stats.Bytes += info.Size()
+			}
+		}
+		return nil
+	})
+	return stats
+}
+
+// watchDirProgress reports the growth of dir every progressInterval until the
+// returned stop function is called. Does nothing if progress is nil.
+func watchDirProgress(ctx context.Context, dir string, progress ProgressFunc) func() {
+	if progress == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				progress(dirStats(dir))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
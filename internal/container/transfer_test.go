@@ -0,0 +1,109 @@
+package container
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDirStats(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		"a.txt":       "hello",
+		"sub/b.txt":   "0123456789",
+		"sub/empty":   "",
+		"sub/c.jsonl": "{}",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("a.txt", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := dirStats(dir)
+	want := TransferProgress{Files: 4, Bytes: 17}
+	if got != want {
+		t.Errorf("Expected %+v (symlinks and directories not counted), got %+v", want, got)
+	}
+
+	if got := dirStats(filepath.Join(dir, "missing")); got != (TransferProgress{}) {
+		t.Errorf("Expected no files for a missing directory, got %+v", got)
+	}
+}
+
+// shortProgressInterval makes watchDirProgress report quickly for the test
+func shortProgressInterval(t *testing.T) {
+	t.Helper()
+	previous := progressInterval
+	progressInterval = 5 * time.Millisecond
+	t.Cleanup(func() { progressInterval = previous })
+}
+
+func TestWatchDirProgress(t *testing.T) {
+	shortProgressInterval(t)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "f"), []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	reports := make(chan TransferProgress, 100)
+	var calls atomic.Int32
+	stop := watchDirProgress(context.Background(), dir, func(p TransferProgress) {
+		calls.Add(1)
+		reports <- p
+	})
+
+	select {
+	case p := <-reports:
+		if p != (TransferProgress{Files: 1, Bytes: 3}) {
+			t.Errorf("Unexpected progress %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a progress report")
+	}
+
+	// Once stop returns, the watcher is gone and reports nothing more
+	stop()
+	after := calls.Load()
+	time.Sleep(20 * progressInterval)
+	if calls.Load() != after {
+		t.Error("Expected no progress reports after stop")
+	}
+}
+
+func TestWatchDirProgressStopsWithContext(t *testing.T) {
+	shortProgressInterval(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	stop := watchDirProgress(ctx, t.TempDir(), func(TransferProgress) { calls.Add(1) })
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		stop() // Must not block once the watcher exited for the cancelled context
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected stop to return after the context was cancelled")
+	}
+	after := calls.Load()
+	time.Sleep(20 * progressInterval)
+	if calls.Load() != after {
+		t.Error("Expected no progress reports after the context was cancelled")
+	}
+}
+
+func TestWatchDirProgressNil(t *testing.T) {
+	stop := watchDirProgress(context.Background(), t.TempDir(), nil)
+	stop() // No watcher, nothing to wait for
+}
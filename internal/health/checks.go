@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
 	"syscall"
@@ -148,6 +149,32 @@ func CheckIncus() HealthCheck {
 	}
 }
 
+// CheckIncusAccess reports how incus commands are invoked (directly or via sg)
+func CheckIncusAccess() HealthCheck {
+	if _, err := exec.LookPath("incus"); err != nil {
+		return HealthCheck{
+			Name:    "incus_access",
+			Status:  StatusFailed,
+			Message: "Incus binary not found on PATH",
+		}
+	}
+
+	mode := container.DetectIncusAccess()
+	message := "Direct (no sg wrapper needed)"
+	if mode == container.AccessSG {
+		message = fmt.Sprintf("Via 'sg %s' (group not active in this shell)", container.IncusGroup)
+	}
+
+	return HealthCheck{
+		Name:    "incus_access",
+		Status:  StatusOK,
+		Message: message,
+		Details: map[string]interface{}{
+			"mode": string(mode),
+		},
+	}
+}
+
 // CheckPermissions verifies user has correct group membership
 func CheckPermissions() HealthCheck {
 	// On macOS, no group check needed
@@ -344,6 +371,187 @@ func CheckNetworkBridge() HealthCheck {
 	}
 }
 
+// storagePool is the subset of incus storage list --format=json we need
+type storagePool struct {
+	Name   string `json:"name"`
+	Driver string `json:"driver"`
+	Status string `json:"status"`
+}
+
+// CheckStoragePool verifies the default profile's root disk uses an existing storage pool.
+// Fresh Incus installs may have no pool at all, which makes incus init fail during setup.
+func CheckStoragePool() HealthCheck {
+	pools, err := listStoragePools()
+	if err != nil {
+		return HealthCheck{
+			Name:    "storage_pool",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Could not list storage pools: %v", err),
+		}
+	}
+
+	if len(pools) == 0 {
+		return HealthCheck{
+			Name:    "storage_pool",
+			Status:  StatusFailed,
+			Message: "No storage pool configured (run 'coi health --fix' or 'incus admin init')",
+		}
+	}
+
+	poolName, err := defaultProfileRootPool()
+	if err != nil {
+		return HealthCheck{
+			Name:    "storage_pool",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Could not get default profile: %v", err),
+		}
+	}
+	if poolName == "" {
+		return HealthCheck{
+			Name:    "storage_pool",
+			Status:  StatusFailed,
+			Message: "Default profile has no root disk (run 'coi health --fix' or 'incus profile device add default root disk path=/ pool=<pool>')",
+		}
+	}
+
+	for _, pool := range pools {
+		if pool.Name != poolName {
+			continue
+		}
+		if pool.Status != "" && pool.Status != "Created" {
+			return HealthCheck{
+				Name:    "storage_pool",
+				Status:  StatusFailed,
+				Message: fmt.Sprintf("Storage pool %s is %s", pool.Name, pool.Status),
+			}
+		}
+		return HealthCheck{
+			Name:    "storage_pool",
+			Status:  StatusOK,
+			Message: fmt.Sprintf("%s (%s)", pool.Name, pool.Driver),
+			Details: map[string]interface{}{
+				"name":   pool.Name,
+				"driver": pool.Driver,
+			},
+		}
+	}
+
+	return HealthCheck{
+		Name:    "storage_pool",
+		Status:  StatusFailed,
+		Message: fmt.Sprintf("Default profile uses missing storage pool %s", poolName),
+	}
+}
+
+// incusProfile is the subset of an incus profile list --format=json entry coi uses
+type incusProfile struct {
+	Name    string                       `json:"name"`
+	Devices map[string]map[string]string `json:"devices"`
+}
+
+// listStoragePools returns the Incus storage pools
+func listStoragePools() ([]storagePool, error) {
+	output, err := container.IncusOutput("storage", "list", "--format=json")
+	if err != nil {
+		return nil, err
+	}
+	var pools []storagePool
+	if err := json.Unmarshal([]byte(output), &pools); err != nil {
+		return nil, fmt.Errorf("failed to parse storage pools: %w", err)
+	}
+	return pools, nil
+}
+
+// defaultProfileRootPool returns the storage pool of the default profile's
+// root disk, or "" if the profile has no root disk
+func defaultProfileRootPool() (string, error) {
+	output, err := container.IncusOutput("profile", "list", "--format=json")
+	if err != nil {
+		return "", err
+	}
+	return profileRootPool(output, "default")
+}
+
+// profileRootPool extracts the pool of a profile's root disk (the disk device
+// mounted at /) from incus profile list --format=json output
+func profileRootPool(profilesJSON, profileName string) (string, error) {
+	var profiles []incusProfile
+	if err := json.Unmarshal([]byte(profilesJSON), &profiles); err != nil {
+		return "", fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	for _, profile := range profiles {
+		if profile.Name != profileName {
+			continue
+		}
+		for _, device := range profile.Devices {
+			if device["type"] == "disk" && device["path"] == "/" {
+				return device["pool"], nil
+			}
+		}
+		return "", nil
+	}
+	return "", fmt.Errorf("profile '%s' not found", profileName)
+}
+
+// fixPoolName picks the pool FixStoragePool makes usable: the one the default
+// profile's root disk names, else an existing "default" pool or the only pool,
+// else a new "default" pool
+func fixPoolName(rootPool string, pools []storagePool) string {
+	if rootPool != "" {
+		return rootPool
+	}
+	for _, pool := range pools {
+		if pool.Name == "default" {
+			return pool.Name
+		}
+	}
+	if len(pools) == 1 {
+		return pools[0].Name
+	}
+	return "default"
+}
+
+// FixStoragePool creates the storage pool the default profile's root disk
+// uses, with the given driver (dir or btrfs), if it does not exist. A profile
+// without a root disk gets one on the pool chosen by fixPoolName. Returns the
+// pool's name.
+func FixStoragePool(driver string) (string, error) {
+	if driver != "dir" && driver != "btrfs" {
+		return "", fmt.Errorf("unsupported storage driver '%s': must be 'dir' or 'btrfs'", driver)
+	}
+
+	pools, err := listStoragePools()
+	if err != nil {
+		return "", fmt.Errorf("failed to list storage pools: %w", err)
+	}
+	rootPool, err := defaultProfileRootPool()
+	if err != nil {
+		return "", fmt.Errorf("failed to get default profile: %w", err)
+	}
+	poolName := fixPoolName(rootPool, pools)
+
+	exists := false
+	for _, pool := range pools {
+		if pool.Name == poolName {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		if err := container.IncusExec("storage", "create", poolName, driver); err != nil {
+			return "", fmt.Errorf("failed to create storage pool: %w", err)
+		}
+	}
+
+	if rootPool == "" {
+		if err := container.IncusExec("profile", "device", "add", "default", "root", "disk", "path=/", "pool="+poolName); err != nil {
+			return "", fmt.Errorf("failed to add root disk to default profile: %w", err)
+		}
+	}
+
+	return poolName, nil
+}
+
 // CheckIPForwarding verifies IP forwarding is enabled
 func CheckIPForwarding() HealthCheck {
 	// On macOS, IP forwarding works differently
@@ -929,7 +1137,7 @@ func CheckNetworkRestriction(imageName string) HealthCheck {
 		BlockMetadataEndpoint: true,
 	}
 
-	if err := firewallManager.ApplyRestricted(restrictedConfig); err != nil {
+	if err := firewallManager.ApplyRestricted(restrictedConfig, nil); err != nil {
 		return HealthCheck{
 			Name:    "network_restriction",
 			Status:  StatusFailed,
@@ -1149,6 +1357,58 @@ func CheckActiveContainers() HealthCheck {
 	}
 }
 
+// CheckClockDrift compares the clock of each running COI container with the
+// host's ([defaults] check_clock_drift)
+func CheckClockDrift() HealthCheck {
+	prefix := session.GetContainerPrefix()
+	names, err := container.ListContainers("^" + regexp.QuoteMeta(prefix))
+	if err != nil {
+		return HealthCheck{
+			Name:    "clock_drift",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Could not list containers: %v", err),
+		}
+	}
+
+	var drifted []string
+	checked := 0
+	for _, name := range names {
+		drift, err := session.MeasureClockDrift(container.NewManager(name))
+		if err != nil {
+			continue // Stopped or unreachable containers have no clock to compare
+		}
+		checked++
+		if session.ClockDriftExceeded(drift) {
+			drifted = append(drifted, fmt.Sprintf("%s (%s)", name, session.DescribeClockDrift(drift)))
+		}
+	}
+
+	if len(drifted) > 0 {
+		return HealthCheck{
+			Name:    "clock_drift",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Clock drift over %s: %s - restart the container(s) to resync", session.MaxClockDrift, strings.Join(drifted, ", ")),
+			Details: map[string]interface{}{
+				"checked": checked,
+				"drifted": drifted,
+			},
+		}
+	}
+
+	message := fmt.Sprintf("%d container(s) in sync with the host", checked)
+	if checked == 0 {
+		message = "No running containers"
+	}
+	return HealthCheck{
+		Name:    "clock_drift",
+		Status:  StatusOK,
+		Message: message,
+		Details: map[string]interface{}{
+			"checked": checked,
+		},
+	}
+}
+
 // CheckSavedSessions counts saved sessions
 func CheckSavedSessions(cfg *config.Config) HealthCheck {
 	// Get configured tool
@@ -1173,57 +1433,48 @@ func CheckSavedSessions(cfg *config.Config) HealthCheck {
 	baseDir := filepath.Join(homeDir, ".coi")
 	sessionsDir := session.GetSessionsDir(baseDir, toolInstance)
 
-	entries, err := os.ReadDir(sessionsDir)
+	// Count sessions the way coi list --all does
+	sessions, err := session.ListSavedSessions(sessionsDir, toolInstance.ConfigDirName())
 	if err != nil {
-		if os.IsNotExist(err) {
-			return HealthCheck{
-				Name:    "saved_sessions",
-				Status:  StatusOK,
-				Message: "None",
-				Details: map[string]interface{}{
-					"count": 0,
-				},
-			}
-		}
 		return HealthCheck{
 			Name:    "saved_sessions",
 			Status:  StatusWarning,
 			Message: fmt.Sprintf("Could not read sessions directory: %v", err),
 		}
 	}
-
-	// Count directories (sessions)
-	count := 0
-	for _, entry := range entries {
-		if entry.IsDir() {
-			count++
-		}
-	}
+	incomplete, _ := session.ListIncompleteSessions(sessionsDir, toolInstance.ConfigDirName())
+	count := len(sessions)
 
 	message := fmt.Sprintf("%d session(s)", count)
 	if count == 0 {
 		message = "None"
 	}
+	// Directories with only the metadata written at launch: sessions still
+	// running, or that failed before anything was saved
+	if len(incomplete) > 0 {
+		message += fmt.Sprintf(" (+%d without saved state: running or failed to start)", len(incomplete))
+	}
 
 	return HealthCheck{
 		Name:    "saved_sessions",
 		Status:  StatusOK,
 		Message: message,
 		Details: map[string]interface{}{
-			"count": count,
-			"path":  sessionsDir,
+			"count":      count,
+			"incomplete": len(incomplete),
+			"path":       sessionsDir,
 		},
 	}
 }
 
-// CheckImageAge checks if the COI image is outdated
-func CheckImageAge(imageName string) HealthCheck {
+// CheckImageAge checks if the COI image is older than maxDays ([update]
+// max_image_age_days). A maxDays of 0 disables the warning.
+func CheckImageAge(imageName string, maxDays int) HealthCheck {
 	if imageName == "" {
 		imageName = "coi"
 	}
 
-	// Get image info
-	output, err := container.IncusOutput("image", "list", imageName, "--format=json")
+	createdAt, found, err := ImageCreatedAt(imageName)
 	if err != nil {
 		return HealthCheck{
 			Name:    "image_age",
@@ -1231,6 +1482,45 @@ func CheckImageAge(imageName string) HealthCheck {
 			Message: fmt.Sprintf("Could not get image info: %v", err),
 		}
 	}
+	if !found {
+		return HealthCheck{
+			Name:    "image_age",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Image '%s' not found", imageName),
+		}
+	}
+
+	days := int(time.Since(createdAt).Hours() / 24)
+	details := map[string]interface{}{
+		"created_at": createdAt.Format("2006-01-02"),
+		"age_days":   days,
+		"max_days":   maxDays,
+	}
+
+	if maxDays > 0 && days > maxDays {
+		return HealthCheck{
+			Name:    "image_age",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("%d days old (consider rebuilding with 'coi build --force')", days),
+			Details: details,
+		}
+	}
+
+	return HealthCheck{
+		Name:    "image_age",
+		Status:  StatusOK,
+		Message: fmt.Sprintf("%d days old", days),
+		Details: details,
+	}
+}
+
+// ImageCreatedAt returns when the image with the given alias was created.
+// found is false if no image has that alias.
+func ImageCreatedAt(imageName string) (createdAt time.Time, found bool, err error) {
+	output, err := container.IncusOutput("image", "list", imageName, "--format=json")
+	if err != nil {
+		return time.Time{}, false, err
+	}
 
 	var images []struct {
 		CreatedAt time.Time `json:"created_at"`
@@ -1240,49 +1530,15 @@ func CheckImageAge(imageName string) HealthCheck {
 	}
 
 	if err := json.Unmarshal([]byte(output), &images); err != nil {
-		return HealthCheck{
-			Name:    "image_age",
-			Status:  StatusWarning,
-			Message: fmt.Sprintf("Could not parse image info: %v", err),
-		}
+		return time.Time{}, false, fmt.Errorf("failed to parse image info: %w", err)
 	}
 
-	// Find the image
 	for _, img := range images {
 		for _, alias := range img.Aliases {
 			if alias.Name == imageName {
-				age := time.Since(img.CreatedAt)
-				days := int(age.Hours() / 24)
-
-				// Warn if older than 30 days
-				if days > 30 {
-					return HealthCheck{
-						Name:    "image_age",
-						Status:  StatusWarning,
-						Message: fmt.Sprintf("%d days old (consider rebuilding with 'coi build --force')", days),
-						Details: map[string]interface{}{
-							"created_at": img.CreatedAt.Format("2006-01-02"),
-							"age_days":   days,
-						},
-					}
-				}
-
-				return HealthCheck{
-					Name:    "image_age",
-					Status:  StatusOK,
-					Message: fmt.Sprintf("%d days old", days),
-					Details: map[string]interface{}{
-						"created_at": img.CreatedAt.Format("2006-01-02"),
-						"age_days":   days,
-					},
-				}
+				return img.CreatedAt, true, nil
 			}
 		}
 	}
-
-	return HealthCheck{
-		Name:    "image_age",
-		Status:  StatusWarning,
-		Message: fmt.Sprintf("Image '%s' not found", imageName),
-	}
+	return time.Time{}, false, nil
 }
@@ -0,0 +1,87 @@
+package health
+
+import "testing"
+
+func TestProfileRootPool(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "root disk with pool",
+			output:   `[{"name":"default","devices":{"eth0":{"name":"eth0","network":"incusbr0","type":"nic"},"root":{"path":"/","pool":"default","type":"disk"}}}]`,
+			expected: "default",
+		},
+		{
+			name:     "custom pool and device name",
+			output:   `[{"name":"other","devices":{"root":{"path":"/","pool":"other","type":"disk"}}},{"name":"default","devices":{"rootfs":{"path":"/","pool":"fast-btrfs","type":"disk"}}}]`,
+			expected: "fast-btrfs",
+		},
+		{
+			name:     "no root device",
+			output:   `[{"name":"default","devices":{"eth0":{"name":"eth0","network":"incusbr0","type":"nic"}}}]`,
+			expected: "",
+		},
+		{
+			name:     "pool of another disk is ignored",
+			output:   `[{"name":"default","devices":{"data":{"path":"/data","pool":"other","type":"disk"}}}]`,
+			expected: "",
+		},
+		{
+			name:     "empty profile",
+			output:   `[{"name":"default","devices":{}}]`,
+			expected: "",
+		},
+		{
+			name:    "missing profile",
+			output:  `[]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid output",
+			output:  "root:\n  pool: default\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := profileRootPool(tt.output, "default")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestFixPoolName(t *testing.T) {
+	tests := []struct {
+		name     string
+		rootPool string
+		pools    []storagePool
+		expected string
+	}{
+		{"profile pool wins", "fast", []storagePool{{Name: "default"}}, "fast"},
+		{"missing profile pool is kept", "fast", nil, "fast"},
+		{"existing default pool", "", []storagePool{{Name: "big"}, {Name: "default"}}, "default"},
+		{"only pool", "", []storagePool{{Name: "big"}}, "big"},
+		{"several pools without default", "", []storagePool{{Name: "a"}, {Name: "b"}}, "default"},
+		{"no pools", "", nil, "default"},
+	}
+
+	for _, tt := range tests {
+		if got := fixPoolName(tt.rootPool, tt.pools); got != tt.expected {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestFixStoragePool_InvalidDriver(t *testing.T) {
+	if _, err := FixStoragePool("zfs"); err == nil {
+		t.Error("Expected error for unsupported driver")
+	}
+}
@@ -1,6 +1,8 @@
 package health
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/config"
@@ -48,59 +50,123 @@ type HealthSummary struct {
 	Failed   int `json:"failed"`
 }
 
-// RunAllChecks runs all health checks and returns the result
-func RunAllChecks(cfg *config.Config, verbose bool) *HealthResult {
-	checks := make(map[string]HealthCheck)
+// registeredCheck is a named health check. enabled reports whether RunAllChecks
+// includes it; nil means always. Checks selected by name always run.
+type registeredCheck struct {
+	name    string
+	run     func(cfg *config.Config) HealthCheck
+	enabled func(cfg *config.Config, verbose bool) bool
+}
+
+// verboseOnly enables a check only with --verbose
+func verboseOnly(_ *config.Config, verbose bool) bool {
+	return verbose
+}
 
+// registry lists every health check in the order they run
+var registry = []registeredCheck{
 	// System checks
-	checks["os"] = CheckOS()
+	{name: "os", run: func(*config.Config) HealthCheck { return CheckOS() }},
 
 	// Critical checks
-	checks["incus"] = CheckIncus()
-	checks["permissions"] = CheckPermissions()
-	checks["image"] = CheckImage(cfg.Defaults.Image)
-	checks["image_age"] = CheckImageAge(cfg.Defaults.Image)
+	{name: "incus", run: func(*config.Config) HealthCheck { return CheckIncus() }},
+	{name: "incus_access", run: func(*config.Config) HealthCheck { return CheckIncusAccess() }},
+	{name: "permissions", run: func(*config.Config) HealthCheck { return CheckPermissions() }},
+	{name: "storage_pool", run: func(*config.Config) HealthCheck { return CheckStoragePool() }},
+	{name: "image", run: func(cfg *config.Config) HealthCheck { return CheckImage(cfg.Defaults.Image) }},
+	{name: "image_age", run: func(cfg *config.Config) HealthCheck {
+		return CheckImageAge(cfg.Defaults.Image, cfg.Update.MaxImageAgeDays)
+	}},
 
 	// Networking checks
-	checks["network_bridge"] = CheckNetworkBridge()
-	checks["ip_forwarding"] = CheckIPForwarding()
-	checks["firewall"] = CheckFirewall(cfg.Network.Mode)
+	{name: "network_bridge", run: func(*config.Config) HealthCheck { return CheckNetworkBridge() }},
+	{name: "ip_forwarding", run: func(*config.Config) HealthCheck { return CheckIPForwarding() }},
+	{name: "firewall", run: func(cfg *config.Config) HealthCheck { return CheckFirewall(cfg.Network.Mode) }},
 
 	// Storage checks
-	checks["coi_directory"] = CheckCOIDirectory()
-	checks["sessions_directory"] = CheckSessionsDirectory(cfg)
-	checks["disk_space"] = CheckDiskSpace()
+	{name: "coi_directory", run: func(*config.Config) HealthCheck { return CheckCOIDirectory() }},
+	{name: "sessions_directory", run: CheckSessionsDirectory},
+	{name: "disk_space", run: func(*config.Config) HealthCheck { return CheckDiskSpace() }},
 
 	// Configuration checks
-	checks["config"] = CheckConfiguration(cfg)
-	checks["network_mode"] = CheckNetworkMode(cfg.Network.Mode)
-	checks["tool"] = CheckTool(cfg.Tool.Name)
+	{name: "config", run: CheckConfiguration},
+	{name: "network_mode", run: func(cfg *config.Config) HealthCheck { return CheckNetworkMode(cfg.Network.Mode) }},
+	{name: "tool", run: func(cfg *config.Config) HealthCheck { return CheckTool(cfg.Tool.Name) }},
 
 	// Status checks
-	checks["active_containers"] = CheckActiveContainers()
-	checks["saved_sessions"] = CheckSavedSessions(cfg)
+	{name: "active_containers", run: func(*config.Config) HealthCheck { return CheckActiveContainers() }},
+	{name: "saved_sessions", run: CheckSavedSessions},
+	{
+		name: "clock_drift",
+		run:  func(*config.Config) HealthCheck { return CheckClockDrift() },
+		enabled: func(cfg *config.Config, _ bool) bool {
+			return cfg.Defaults.CheckClockDrift
+		},
+	},
 
 	// Container networking checks (critical for detecting real networking issues)
-	checks["container_connectivity"] = CheckContainerConnectivity(cfg.Defaults.Image)
-	checks["network_restriction"] = CheckNetworkRestriction(cfg.Defaults.Image)
+	{name: "container_connectivity", run: func(cfg *config.Config) HealthCheck { return CheckContainerConnectivity(cfg.Defaults.Image) }},
+	{name: "network_restriction", run: func(cfg *config.Config) HealthCheck { return CheckNetworkRestriction(cfg.Defaults.Image) }},
+	{name: "network_mode_connectivity", run: func(cfg *config.Config) HealthCheck {
+		return CheckNetworkModeConnectivity(cfg.Defaults.Image, &cfg.Network)
+	}},
 
 	// Optional checks (only if verbose)
-	if verbose {
-		checks["dns_resolution"] = CheckDNS()
-		checks["passwordless_sudo"] = CheckPasswordlessSudo()
+	{name: "dns_resolution", run: func(*config.Config) HealthCheck { return CheckDNS() }, enabled: verboseOnly},
+	{name: "passwordless_sudo", run: func(*config.Config) HealthCheck { return CheckPasswordlessSudo() }, enabled: verboseOnly},
+}
+
+// CheckNames returns the names of all registered checks in run order
+func CheckNames() []string {
+	names := make([]string, 0, len(registry))
+	for _, check := range registry {
+		names = append(names, check.name)
 	}
+	return names
+}
 
-	// Calculate summary
-	summary := calculateSummary(checks)
+// RunAllChecks runs all health checks and returns the result
+func RunAllChecks(cfg *config.Config, verbose bool) *HealthResult {
+	checks := make(map[string]HealthCheck)
+	for _, check := range registry {
+		if check.enabled != nil && !check.enabled(cfg, verbose) {
+			continue
+		}
+		checks[check.name] = check.run(cfg)
+	}
+	return newResult(checks)
+}
 
-	// Determine overall status
-	status := determineStatus(checks)
+// RunChecks runs only the named checks, regardless of --verbose or settings
+// that would normally skip them. Unknown names are an error; nothing runs.
+func RunChecks(cfg *config.Config, names []string) (*HealthResult, error) {
+	byName := make(map[string]registeredCheck, len(registry))
+	for _, check := range registry {
+		byName[check.name] = check
+	}
+	for _, name := range names {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("unknown check '%s' (available: %s)", name, strings.Join(CheckNames(), ", "))
+		}
+	}
+
+	checks := make(map[string]HealthCheck)
+	for _, name := range names {
+		if _, done := checks[name]; done {
+			continue
+		}
+		checks[name] = byName[name].run(cfg)
+	}
+	return newResult(checks), nil
+}
 
+// newResult summarizes a set of check results
+func newResult(checks map[string]HealthCheck) *HealthResult {
 	return &HealthResult{
-		Status:    status,
+		Status:    determineStatus(checks),
 		Timestamp: time.Now(),
 		Checks:    checks,
-		Summary:   summary,
+		Summary:   calculateSummary(checks),
 	}
 }
 
@@ -0,0 +1,42 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestCheckNames_Unique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, name := range CheckNames() {
+		if seen[name] {
+			t.Errorf("check %q registered twice", name)
+		}
+		seen[name] = true
+	}
+	for _, name := range []string{"incus", "network_bridge", "permissions", "image", "dns_resolution", "clock_drift"} {
+		if !seen[name] {
+			t.Errorf("check %q not registered", name)
+		}
+	}
+}
+
+func TestRunChecks_Selected(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	result, err := RunChecks(cfg, []string{"network_mode", "network_mode"})
+	if err != nil {
+		t.Fatalf("RunChecks failed: %v", err)
+	}
+	if len(result.Checks) != 1 || result.Summary.Total != 1 {
+		t.Fatalf("expected exactly one check, got %v", result.Checks)
+	}
+	if result.Checks["network_mode"].Status != StatusOK || result.ExitCode() != 0 {
+		t.Errorf("expected network_mode to pass, got %+v", result.Checks["network_mode"])
+	}
+}
+
+func TestRunChecks_UnknownName(t *testing.T) {
+	if _, err := RunChecks(config.GetDefaultConfig(), []string{"network_mode", "nope"}); err == nil {
+		t.Error("expected error for unknown check")
+	}
+}
@@ -0,0 +1,282 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+	"github.com/mensfeld/code-on-incus/internal/network"
+)
+
+// defaultProbeDomain is reached from the test container in open and restricted mode
+const defaultProbeDomain = "api.anthropic.com"
+
+// probeResult is how a connection attempt from the test container ended
+type probeResult int
+
+const (
+	probeConnected probeResult = iota // The connection got through
+	probeRejected                     // Refused or rejected (curl exit 7), as coi's REJECT rules do
+	probeTimedOut                     // No answer (curl exit 28): dropped somewhere, not rejected
+	probeFailed                       // The probe itself failed
+)
+
+// String returns the name used in the check details
+func (r probeResult) String() string {
+	switch r {
+	case probeConnected:
+		return "connected"
+	case probeRejected:
+		return "rejected"
+	case probeTimedOut:
+		return "timed out"
+	default:
+		return "failed"
+	}
+}
+
+// curl exit codes that tell a rejected connection from one nobody answered
+const (
+	curlExitCouldNotConnect = 7
+	curlExitTimeout         = 28
+)
+
+// classifyProbe maps the error of a curl probe run with incus exec (which
+// passes curl's exit code through) to a probeResult. Other curl errors, such
+// as an empty reply, happen after the connection was made.
+func classifyProbe(err error) probeResult {
+	if err == nil {
+		return probeConnected
+	}
+	var exitErr *container.ExitError
+	if !errors.As(err, &exitErr) {
+		return probeFailed
+	}
+	switch exitErr.ExitCode {
+	case curlExitCouldNotConnect:
+		return probeRejected
+	case curlExitTimeout:
+		return probeTimedOut
+	case 1, 2, 126, 127:
+		// curl setup errors, or curl missing from the image
+		return probeFailed
+	default:
+		return probeConnected
+	}
+}
+
+// modeProbes holds the outcome of each probe run inside the test container
+type modeProbes struct {
+	DNS      bool        // Probe domain resolves
+	Domain   probeResult // HTTPS connection to the probe domain
+	Private  probeResult // Connection to 10.0.0.1
+	Metadata probeResult // Connection to 169.254.169.254
+}
+
+// CheckNetworkModeConnectivity launches a test container, applies the configured
+// network mode exactly like 'coi shell' does, and reports DNS resolution, access
+// to an allowed domain and RFC1918/metadata blocking as separate results so a DNS
+// failure can be told apart from a routing or firewall one.
+func CheckNetworkModeConnectivity(imageName string, netCfg *config.NetworkConfig) HealthCheck {
+	mode := netCfg.Mode
+	if mode != config.NetworkModeOpen && !network.FirewallAvailable() {
+		return HealthCheck{
+			Name:    "network_mode_connectivity",
+			Status:  StatusWarning,
+			Message: fmt.Sprintf("Skipped (%s mode requires firewalld)", mode),
+		}
+	}
+
+	domain := probeDomain(netCfg)
+
+	if imageName == "" {
+		imageName = "coi"
+	}
+	exists, err := container.ImageExists(imageName)
+	if err != nil || !exists {
+		return HealthCheck{
+			Name:    "network_mode_connectivity",
+			Status:  StatusWarning,
+			Message: "Skipped (image not available)",
+		}
+	}
+
+	containerName := fmt.Sprintf("coi-mode-check-%d", time.Now().UnixNano())
+	if err := container.LaunchContainer(imageName, containerName); err != nil {
+		return HealthCheck{
+			Name:    "network_mode_connectivity",
+			Status:  StatusFailed,
+			Message: fmt.Sprintf("Failed to launch test container: %v", err),
+		}
+	}
+
+	// The network manager logs its progress; keep it out of the health report
+	logOutput := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(logOutput)
+
+	netManager := network.NewManager(netCfg)
+	defer func() {
+		// Remove firewall rules while the container still has its IP
+		_ = netManager.Teardown(context.Background(), containerName)
+		_ = container.StopContainer(containerName)
+		_ = container.DeleteContainer(containerName)
+	}()
+
+	containerIP, err := network.GetContainerIP(containerName)
+	if err != nil {
+		return HealthCheck{
+			Name:    "network_mode_connectivity",
+			Status:  StatusFailed,
+			Message: "Container failed to get IP address (DHCP not working)",
+		}
+	}
+
+	if err := netManager.SetupForContainer(context.Background(), containerName); err != nil {
+		return HealthCheck{
+			Name:    "network_mode_connectivity",
+			Status:  StatusFailed,
+			Message: fmt.Sprintf("Failed to apply %s mode: %v", mode, err),
+		}
+	}
+
+	var probes modeProbes
+	if domain != "" {
+		dnsOutput, dnsErr := container.IncusOutput("exec", containerName, "--", "getent", "hosts", domain)
+		probes.DNS = dnsErr == nil && dnsOutput != ""
+
+		// Any HTTP status (or a certificate error, ignored with -k) means packets got through
+		_, httpErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "-k", "--connect-timeout", "5", "-o", "/dev/null", "https://"+domain)
+		probes.Domain = classifyProbe(httpErr)
+	}
+
+	privateExpected, metadataExpected := expectedBlocking(netCfg)
+	if privateExpected {
+		_, privateErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--connect-timeout", "2", "-o", "/dev/null", "http://10.0.0.1:80")
+		probes.Private = classifyProbe(privateErr)
+	}
+	if metadataExpected {
+		_, metadataErr := container.IncusOutput("exec", containerName, "--", "curl", "-s", "--connect-timeout", "2", "-o", "/dev/null", "http://169.254.169.254/")
+		probes.Metadata = classifyProbe(metadataErr)
+	}
+
+	status, message := evaluateModeProbes(netCfg, domain, probes)
+	details := map[string]interface{}{
+		"mode":         string(mode),
+		"container_ip": containerIP,
+		"probe_domain": domain,
+		"dns":          probes.DNS,
+		"domain":       probes.Domain.String(),
+	}
+	if privateExpected {
+		details["rfc1918"] = probes.Private.String()
+	}
+	if metadataExpected {
+		details["metadata"] = probes.Metadata.String()
+	}
+
+	return HealthCheck{
+		Name:    "network_mode_connectivity",
+		Status:  status,
+		Message: message,
+		Details: details,
+	}
+}
+
+// probeDomain picks the domain the test container should be able to reach: the
+// first concrete allowlist entry in allowlist mode, api.anthropic.com otherwise.
+// Returns "" when the allowlist has no concrete domain (e.g. proxy only).
+func probeDomain(netCfg *config.NetworkConfig) string {
+	if netCfg.Mode != config.NetworkModeAllowlist {
+		return defaultProbeDomain
+	}
+	domains, _ := network.ExpandWildcards(netCfg.AllowedDomains, netCfg.WildcardSubdomains)
+	if len(domains) == 0 {
+		return ""
+	}
+	return domains[0]
+}
+
+// expectedBlocking reports whether the mode's firewall rules should block RFC1918
+// ranges and the metadata endpoint, mirroring FirewallManager.ApplyRestricted and
+// ApplyAllowlist
+func expectedBlocking(netCfg *config.NetworkConfig) (private, metadata bool) {
+	switch netCfg.Mode {
+	case config.NetworkModeRestricted:
+		return netCfg.BlockPrivateNetworks && !netCfg.AllowLocalNetworkAccess, netCfg.BlockMetadataEndpoint
+	case config.NetworkModeAllowlist:
+		// Everything not allowed is rejected
+		return !netCfg.AllowLocalNetworkAccess, true
+	default:
+		return false, false
+	}
+}
+
+// evaluateModeProbes turns the probe results into a status and a one-line
+// summary. DNS failing is reported separately from a resolvable domain that
+// cannot be reached, which points at routing or firewall rules instead. A
+// blocked range only counts as blocked when the connection is rejected: coi's
+// rules reject, so a timeout means they did not apply (the address may simply
+// not answer) and is reported as unconfirmed.
+func evaluateModeProbes(netCfg *config.NetworkConfig, domain string, probes modeProbes) (CheckStatus, string) {
+	status := StatusOK
+	var results []string
+	fail := func(result string) {
+		status = StatusFailed
+		results = append(results, result)
+	}
+	warn := func(result string) {
+		if status == StatusOK {
+			status = StatusWarning
+		}
+		results = append(results, result)
+	}
+
+	switch {
+	case domain == "":
+		warn("no allowed domain to probe")
+	case !probes.DNS:
+		fail(fmt.Sprintf("DNS FAILED for %s", domain))
+	case probes.Domain == probeRejected:
+		fail(fmt.Sprintf("routing FAILED (%s resolves but the connection is rejected - check the firewall rules)", domain))
+	case probes.Domain == probeTimedOut:
+		fail(fmt.Sprintf("routing FAILED (%s resolves but the connection times out)", domain))
+	case probes.Domain != probeConnected:
+		fail(fmt.Sprintf("routing FAILED (%s resolves but is unreachable)", domain))
+	default:
+		results = append(results, "DNS ok", fmt.Sprintf("%s reachable", domain))
+	}
+
+	privateExpected, metadataExpected := expectedBlocking(netCfg)
+	blocked := []struct {
+		name     string
+		expected bool
+		result   probeResult
+	}{
+		{"RFC1918", privateExpected, probes.Private},
+		{"metadata", metadataExpected, probes.Metadata},
+	}
+	for _, probe := range blocked {
+		if !probe.expected {
+			continue
+		}
+		switch probe.result {
+		case probeRejected:
+			results = append(results, probe.name+" blocked")
+		case probeConnected:
+			fail(probe.name + " NOT blocked")
+		case probeTimedOut:
+			warn(probe.name + " timed out instead of being rejected (block rule not confirmed)")
+		default:
+			warn(probe.name + " probe failed")
+		}
+	}
+
+	return status, fmt.Sprintf("%s: %s", netCfg.Mode, strings.Join(results, ", "))
+}
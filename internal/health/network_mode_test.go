@@ -0,0 +1,178 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+func TestProbeDomain(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.NetworkConfig
+		want string
+	}{
+		{"open", config.NetworkConfig{Mode: config.NetworkModeOpen}, defaultProbeDomain},
+		{"restricted", config.NetworkConfig{Mode: config.NetworkModeRestricted}, defaultProbeDomain},
+		{
+			"allowlist first domain",
+			config.NetworkConfig{Mode: config.NetworkModeAllowlist, AllowedDomains: []string{"github.com", "pypi.org"}},
+			"github.com",
+		},
+		{
+			"allowlist wildcard expanded",
+			config.NetworkConfig{
+				Mode:               config.NetworkModeAllowlist,
+				AllowedDomains:     []string{"*.example.com"},
+				WildcardSubdomains: map[string][]string{"*.example.com": {"api"}},
+			},
+			"api.example.com",
+		},
+		{"allowlist empty", config.NetworkConfig{Mode: config.NetworkModeAllowlist}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := probeDomain(&tt.cfg); got != tt.want {
+				t.Errorf("probeDomain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpectedBlocking(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          config.NetworkConfig
+		wantPrivate  bool
+		wantMetadata bool
+	}{
+		{"open", config.NetworkConfig{Mode: config.NetworkModeOpen, BlockPrivateNetworks: true, BlockMetadataEndpoint: true}, false, false},
+		{"restricted defaults", config.NetworkConfig{Mode: config.NetworkModeRestricted, BlockPrivateNetworks: true, BlockMetadataEndpoint: true}, true, true},
+		{"restricted local access", config.NetworkConfig{Mode: config.NetworkModeRestricted, BlockPrivateNetworks: true, AllowLocalNetworkAccess: true}, false, false},
+		{"allowlist", config.NetworkConfig{Mode: config.NetworkModeAllowlist}, true, true},
+		{"allowlist local access", config.NetworkConfig{Mode: config.NetworkModeAllowlist, AllowLocalNetworkAccess: true}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			private, metadata := expectedBlocking(&tt.cfg)
+			if private != tt.wantPrivate || metadata != tt.wantMetadata {
+				t.Errorf("expectedBlocking() = (%v, %v), want (%v, %v)", private, metadata, tt.wantPrivate, tt.wantMetadata)
+			}
+		})
+	}
+}
+
+func TestEvaluateModeProbes(t *testing.T) {
+	restricted := &config.NetworkConfig{Mode: config.NetworkModeRestricted, BlockPrivateNetworks: true, BlockMetadataEndpoint: true}
+	open := &config.NetworkConfig{Mode: config.NetworkModeOpen}
+
+	tests := []struct {
+		name        string
+		cfg         *config.NetworkConfig
+		domain      string
+		probes      modeProbes
+		wantStatus  CheckStatus
+		wantMessage string
+	}{
+		{
+			"restricted all good",
+			restricted, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeConnected, Private: probeRejected, Metadata: probeRejected},
+			StatusOK, "restricted: DNS ok, api.anthropic.com reachable, RFC1918 blocked, metadata blocked",
+		},
+		{
+			"dns failure",
+			restricted, "api.anthropic.com",
+			modeProbes{Domain: probeFailed, Private: probeRejected, Metadata: probeRejected},
+			StatusFailed, "DNS FAILED for api.anthropic.com",
+		},
+		{
+			"routing failure",
+			restricted, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeFailed, Private: probeRejected, Metadata: probeRejected},
+			StatusFailed, "routing FAILED",
+		},
+		{
+			"domain rejected",
+			restricted, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeRejected, Private: probeRejected, Metadata: probeRejected},
+			StatusFailed, "the connection is rejected",
+		},
+		{
+			"domain timed out",
+			restricted, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeTimedOut, Private: probeRejected, Metadata: probeRejected},
+			StatusFailed, "the connection times out",
+		},
+		{
+			"private not blocked",
+			restricted, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeConnected, Private: probeConnected, Metadata: probeRejected},
+			StatusFailed, "RFC1918 NOT blocked",
+		},
+		{
+			"private timed out",
+			restricted, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeConnected, Private: probeTimedOut, Metadata: probeRejected},
+			StatusWarning, "RFC1918 timed out instead of being rejected",
+		},
+		{
+			"failure outranks a timeout",
+			restricted, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeConnected, Private: probeTimedOut, Metadata: probeConnected},
+			StatusFailed, "metadata NOT blocked",
+		},
+		{
+			"open mode skips blocking",
+			open, "api.anthropic.com",
+			modeProbes{DNS: true, Domain: probeConnected},
+			StatusOK, "open: DNS ok, api.anthropic.com reachable",
+		},
+		{
+			"nothing to probe",
+			&config.NetworkConfig{Mode: config.NetworkModeAllowlist}, "",
+			modeProbes{Private: probeRejected, Metadata: probeRejected},
+			StatusWarning, "no allowed domain to probe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message := evaluateModeProbes(tt.cfg, tt.domain, tt.probes)
+			if status != tt.wantStatus {
+				t.Errorf("status = %s, want %s (message %q)", status, tt.wantStatus, message)
+			}
+			if !strings.Contains(message, tt.wantMessage) {
+				t.Errorf("message = %q, want it to contain %q", message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestClassifyProbe(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want probeResult
+	}{
+		{"success", nil, probeConnected},
+		{"rejected", &container.ExitError{ExitCode: 7}, probeRejected},
+		{"timed out", &container.ExitError{ExitCode: 28}, probeTimedOut},
+		{"empty reply after connecting", &container.ExitError{ExitCode: 52}, probeConnected},
+		{"curl missing", &container.ExitError{ExitCode: 127}, probeFailed},
+		{"incus failed to run", errors.New("executable file not found"), probeFailed},
+		{"wrapped exit error", fmt.Errorf("probe: %w", &container.ExitError{ExitCode: 7}), probeRejected},
+	}
+
+	for _, tt := range tests {
+		if got := classifyProbe(tt.err); got != tt.want {
+			t.Errorf("%s: classifyProbe() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package image
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// coiMinVersions lists the distributions scripts/build/coi.sh supports, with the
+// oldest supported major release of each (it relies on apt, systemd and the
+// Docker/NodeSource/GitHub CLI apt repositories for that distribution)
+var coiMinVersions = map[string]int{
+	"ubuntu": 22,
+	"debian": 12,
+}
+
+// osRelease holds the /etc/os-release fields the coi build cares about
+type osRelease struct {
+	ID        string // e.g. "ubuntu", "debian"
+	VersionID string // e.g. "24.04", "12"
+	Name      string // PRETTY_NAME, for messages
+}
+
+// parseOSRelease parses the contents of an /etc/os-release file
+func parseOSRelease(content string) osRelease {
+	var release osRelease
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			release.ID = value
+		case "VERSION_ID":
+			release.VersionID = value
+		case "PRETTY_NAME":
+			release.Name = value
+		}
+	}
+	if release.Name == "" {
+		release.Name = strings.TrimSpace(release.ID + " " + release.VersionID)
+	}
+	return release
+}
+
+// checkCoiBase reports whether the coi build script supports a base image's
+// distribution and release
+func checkCoiBase(release osRelease) error {
+	minMajor, ok := coiMinVersions[release.ID]
+	if !ok {
+		return fmt.Errorf("unsupported base image distribution %q (%s): the coi build supports ubuntu >= 22.04 and debian >= 12", release.ID, release.Name)
+	}
+
+	major, err := strconv.Atoi(strings.SplitN(release.VersionID, ".", 2)[0])
+	if err != nil {
+		return fmt.Errorf("cannot determine the release of base image %s (VERSION_ID %q)", release.Name, release.VersionID)
+	}
+	if major < minMajor {
+		return fmt.Errorf("base image %s is too old: the coi build needs %s %d or newer", release.Name, release.ID, minMajor)
+	}
+	return nil
+}
+
+// checkCoiBaseOS verifies the launched build container runs a distribution the
+// coi build script supports, before spending minutes on the build
+func (b *Builder) checkCoiBaseOS() error {
+	output, err := b.mgr.ExecArgsCapture([]string{"cat", "/etc/os-release"}, container.ExecCommandOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to read /etc/os-release from base image %s: %w", b.opts.BaseImage, err)
+	}
+
+	release := parseOSRelease(output)
+	if err := checkCoiBase(release); err != nil {
+		return err
+	}
+	b.opts.Logger(fmt.Sprintf("Base image: %s", release.Name))
+	return nil
+}
@@ -0,0 +1,102 @@
+package image
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    osRelease
+	}{
+		{
+			name: "ubuntu",
+			content: `PRETTY_NAME="Ubuntu 24.04.1 LTS"
+NAME="Ubuntu"
+VERSION_ID="24.04"
+VERSION="24.04.1 LTS (Noble Numbat)"
+ID=ubuntu
+ID_LIKE=debian
+`,
+			want: osRelease{ID: "ubuntu", VersionID: "24.04", Name: "Ubuntu 24.04.1 LTS"},
+		},
+		{
+			name:    "debian with single quotes",
+			content: "PRETTY_NAME='Debian GNU/Linux 12 (bookworm)'\nVERSION_ID='12'\nID=debian\n",
+			want:    osRelease{ID: "debian", VersionID: "12", Name: "Debian GNU/Linux 12 (bookworm)"},
+		},
+		{
+			name:    "no pretty name",
+			content: "ID=alpine\nVERSION_ID=3.20.0\n",
+			want:    osRelease{ID: "alpine", VersionID: "3.20.0", Name: "alpine 3.20.0"},
+		},
+		{
+			name:    "comments and indentation",
+			content: "# generated\n  ID=debian  \n\nVERSION_ID=\"13\"\nnot a field\n",
+			want:    osRelease{ID: "debian", VersionID: "13", Name: "debian 13"},
+		},
+		{
+			name:    "empty",
+			content: "",
+			want:    osRelease{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseOSRelease(tt.content); got != tt.want {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckCoiBase(t *testing.T) {
+	tests := []struct {
+		name    string
+		release osRelease
+		wantErr string
+	}{
+		{name: "ubuntu 22.04", release: osRelease{ID: "ubuntu", VersionID: "22.04", Name: "Ubuntu 22.04"}},
+		{name: "ubuntu 24.04", release: osRelease{ID: "ubuntu", VersionID: "24.04", Name: "Ubuntu 24.04"}},
+		{name: "debian 12", release: osRelease{ID: "debian", VersionID: "12", Name: "Debian 12"}},
+		{name: "debian 13", release: osRelease{ID: "debian", VersionID: "13", Name: "Debian 13"}},
+		{
+			name:    "ubuntu too old",
+			release: osRelease{ID: "ubuntu", VersionID: "20.04", Name: "Ubuntu 20.04"},
+			wantErr: "base image Ubuntu 20.04 is too old: the coi build needs ubuntu 22 or newer",
+		},
+		{
+			name:    "debian too old",
+			release: osRelease{ID: "debian", VersionID: "11", Name: "Debian 11"},
+			wantErr: "the coi build needs debian 12 or newer",
+		},
+		{
+			name:    "unsupported distribution",
+			release: osRelease{ID: "alpine", VersionID: "3.20", Name: "Alpine 3.20"},
+			wantErr: `unsupported base image distribution "alpine" (Alpine 3.20)`,
+		},
+		{
+			name:    "missing version",
+			release: osRelease{ID: "debian", Name: "Debian sid"},
+			wantErr: `cannot determine the release of base image Debian sid (VERSION_ID "")`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCoiBase(tt.release)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,9 +1,12 @@
 package image
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,17 +18,34 @@ const (
 	BaseImage      = "images:ubuntu/22.04"
 	CoiAlias       = "coi"
 	BuildContainer = "coi-build"
+
+	// BuildContextPath is where the build context directory is pushed in the build container
+	BuildContextPath = "/tmp/build-context"
+	// MaxBuildContextSize is the largest build context we are willing to push (512 MiB)
+	MaxBuildContextSize = 512 * 1024 * 1024
 )
 
 // BuildOptions contains options for building an image
 type BuildOptions struct {
-	ImageType   string // "coi" or "custom"
-	AliasName   string
-	Description string
-	BaseImage   string
-	Force       bool
-	BuildScript string // For custom images
-	Logger      func(string)
+	ImageType     string // "coi" or "custom"
+	AliasName     string
+	Description   string
+	BaseImage     string
+	Force         bool
+	BuildScript   string            // For custom images
+	BuildContext  string            // Optional directory pushed to BuildContextPath (custom images)
+	ContextIgnore []string          // .dockerignore patterns of paths in BuildContext not to push
+	Quiet         bool              // Keep build script output in the container log, only surfacing it on failure
+	CoiVersion    string            // Stamped as the user.coi.version property
+	Tool          string            // Stamped as the user.coi.tool property
+	Properties    map[string]string // Extra image properties (coi build --label)
+	Logger        func(string)
+
+	// Test, if set, self-tests the new image (by version alias) before the
+	// alias is moved to it. A failure fails the build; with StrictTest the
+	// alias keeps pointing at the previous image.
+	Test       func(imageAlias string) error
+	StrictTest bool
 }
 
 // BuildResult contains the result of an image build
@@ -37,6 +57,35 @@ type BuildResult struct {
 	Error        error
 }
 
+// Report is the single result line 'coi build --format json' prints
+type Report struct {
+	Success         bool    `json:"success"`
+	Alias           string  `json:"alias"`
+	Version         string  `json:"version,omitempty"`
+	Fingerprint     string  `json:"fingerprint,omitempty"`
+	Skipped         bool    `json:"skipped"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// NewReport summarizes a build of alias that took duration. Version and
+// fingerprint are only set when an image was actually built.
+func NewReport(alias string, result *BuildResult, duration time.Duration) Report {
+	report := Report{
+		Success:         result.Error == nil,
+		Alias:           alias,
+		Skipped:         result.Skipped,
+		DurationSeconds: duration.Round(time.Millisecond).Seconds(),
+	}
+	if result.Error != nil {
+		report.Error = result.Error.Error()
+	} else if !result.Skipped {
+		report.Version = result.VersionAlias
+		report.Fingerprint = result.Fingerprint
+	}
+	return report
+}
+
 // Builder handles Incus image building
 type Builder struct {
 	opts BuildOptions
@@ -86,6 +135,15 @@ func (b *Builder) Build() *BuildResult {
 		return result
 	}
 
+	// Fail fast on base images the coi build script can't handle
+	if b.opts.ImageType == "coi" {
+		if err := b.checkCoiBaseOS(); err != nil {
+			result.Error = err
+			b.cleanup()
+			return result
+		}
+	}
+
 	if err := b.waitForNetwork(); err != nil {
 		result.Error = err
 		b.cleanup()
@@ -111,12 +169,27 @@ func (b *Builder) Build() *BuildResult {
 	// Cleanup build container
 	b.cleanup()
 
+	var testErr error
+	if b.opts.Test != nil {
+		b.opts.Logger(fmt.Sprintf("Testing image '%s'...", result.VersionAlias))
+		testErr = b.opts.Test(result.VersionAlias)
+		if testErr != nil && b.opts.StrictTest {
+			result.Error = fmt.Errorf("image test failed, '%s' still points to the previous image (new image kept as '%s'): %w", b.opts.AliasName, result.VersionAlias, testErr)
+			return result
+		}
+	}
+
 	// Update alias
 	if err := b.updateAlias(result.VersionAlias, b.opts.AliasName); err != nil {
 		result.Error = err
 		return result
 	}
 
+	if testErr != nil {
+		result.Error = fmt.Errorf("image '%s' was built but failed its test: %w", b.opts.AliasName, testErr)
+		return result
+	}
+
 	b.opts.Logger(fmt.Sprintf("Image '%s' built successfully! (version: %s)", b.opts.AliasName, result.VersionAlias))
 	result.Success = true
 	return result
@@ -214,13 +287,16 @@ func (b *Builder) waitForNetwork() error {
 		time.Sleep(1 * time.Second)
 	}
 
-	// Final diagnostic before failing
+	// Final diagnostic before failing (streamed line by line)
 	b.opts.Logger("Network timeout - gathering diagnostic info...")
-	ipOutput, _ := b.mgr.ExecCommand("ip addr show", container.ExecCommandOptions{Capture: true})
-	b.opts.Logger(fmt.Sprintf("Final IP addresses:\n%s", ipOutput))
+	logLine := func(line string) {
+		b.opts.Logger("  " + line)
+	}
+	b.opts.Logger("Final IP addresses:")
+	_ = b.mgr.ExecStream("ip addr show", container.ExecCommandOptions{}, logLine) // Diagnostics only
 
-	routeOutput, _ := b.mgr.ExecCommand("ip route show", container.ExecCommandOptions{Capture: true})
-	b.opts.Logger(fmt.Sprintf("Final routes:\n%s", routeOutput))
+	b.opts.Logger("Final routes:")
+	_ = b.mgr.ExecStream("ip route show", container.ExecCommandOptions{}, logLine) // Diagnostics only
 
 	return fmt.Errorf("network timeout after %d seconds", maxAttempts)
 }
@@ -357,7 +433,7 @@ func (b *Builder) runBuildScript(scriptPath string) error {
 
 	// Execute script
 	b.opts.Logger("Executing build script...")
-	if _, err := b.mgr.ExecCommand("/tmp/build.sh", container.ExecCommandOptions{Capture: false}); err != nil {
+	if err := b.execBuildScript(container.ExecCommandOptions{}); err != nil {
 		return fmt.Errorf("build script failed: %w", err)
 	}
 
@@ -365,6 +441,38 @@ func (b *Builder) runBuildScript(scriptPath string) error {
 	return nil
 }
 
+// buildScriptLog is where Quiet builds send build script output
+const buildScriptLog = "/tmp/build.log"
+
+// execBuildScript runs /tmp/build.sh. Output is shown as it runs, or with Quiet
+// written to buildScriptLog, whose tail is included in the error on failure.
+func (b *Builder) execBuildScript(opts container.ExecCommandOptions) error {
+	if !b.opts.Quiet {
+		_, err := b.mgr.ExecCommand(buildScriptCommand(false), opts)
+		return err
+	}
+
+	opts.Capture = true
+	if _, err := b.mgr.ExecCommand(buildScriptCommand(true), opts); err != nil {
+		tail, _ := b.mgr.ExecArgsCapture([]string{"tail", "-n", "20", buildScriptLog}, container.ExecCommandOptions{})
+		if tail = strings.TrimSpace(tail); tail != "" {
+			return fmt.Errorf("%w\nlast lines of build output:\n%s", err, tail)
+		}
+		return err
+	}
+	_, _ = b.mgr.ExecArgsCapture([]string{"rm", "-f", buildScriptLog}, container.ExecCommandOptions{}) // Keep the log out of the image
+	return nil
+}
+
+// buildScriptCommand is the command running /tmp/build.sh, with its output
+// redirected to buildScriptLog when quiet
+func buildScriptCommand(quiet bool) string {
+	if quiet {
+		return "/tmp/build.sh > " + buildScriptLog + " 2>&1"
+	}
+	return "/tmp/build.sh"
+}
+
 // buildCustom runs a custom build script
 func (b *Builder) buildCustom() error {
 	if b.opts.BuildScript == "" {
@@ -400,16 +508,136 @@ func (b *Builder) buildCustom() error {
 		return err
 	}
 
+	// Push build context (if any) so the script can access its sibling files
+	execOpts := container.ExecCommandOptions{}
+	if b.opts.BuildContext != "" {
+		if err := b.pushBuildContext(); err != nil {
+			return err
+		}
+		execOpts.Cwd = BuildContextPath
+	}
+
 	// Execute script as root
 	b.opts.Logger(fmt.Sprintf("Executing build script (%d bytes)...", len(scriptBytes)))
-	if _, err := b.mgr.ExecCommand("/tmp/build.sh", container.ExecCommandOptions{Capture: false}); err != nil {
+	if err := b.execBuildScript(execOpts); err != nil {
 		return fmt.Errorf("custom build script failed: %w", err)
 	}
 
+	// Remove build context so it doesn't end up in the image
+	if b.opts.BuildContext != "" {
+		if _, err := b.mgr.ExecCommand("rm -rf "+container.ShellQuote(BuildContextPath), container.ExecCommandOptions{Capture: true}); err != nil {
+			b.opts.Logger(fmt.Sprintf("Warning: failed to remove build context: %v", err))
+		}
+	}
+
 	b.opts.Logger("Custom build script completed successfully")
 	return nil
 }
 
+// pushBuildContext pushes the build context directory to BuildContextPath
+func (b *Builder) pushBuildContext() error {
+	size, err := ValidateBuildContext(b.opts.BuildContext, b.opts.ContextIgnore)
+	if err != nil {
+		return err
+	}
+
+	b.opts.Logger(fmt.Sprintf("Pushing build context %s (%d bytes) to %s...", b.opts.BuildContext, size, BuildContextPath))
+
+	// Incus pushes a directory as <dest>/<basename>, so push into a staging
+	// directory first and then move it to the fixed context path
+	stageDir := "/tmp/coi-build-context-stage"
+	if _, err := b.mgr.ExecCommand(prepareBuildContextCommand(stageDir), container.ExecCommandOptions{Capture: true}); err != nil {
+		return fmt.Errorf("failed to prepare build context directory: %w", err)
+	}
+
+	absContext, err := filepath.Abs(b.opts.BuildContext)
+	if err != nil {
+		return fmt.Errorf("invalid build context path: %w", err)
+	}
+	baseName := filepath.Base(absContext)
+
+	// Push a copy without the ignored paths, under the same name
+	if len(b.opts.ContextIgnore) > 0 {
+		filtered, err := os.MkdirTemp("", "coi-build-context-*")
+		if err != nil {
+			return fmt.Errorf("failed to stage build context: %w", err)
+		}
+		defer os.RemoveAll(filtered)
+
+		filter, err := newContextFilter(b.opts.ContextIgnore)
+		if err != nil {
+			return err
+		}
+		absContext = filepath.Join(filtered, baseName)
+		if err := copyBuildContext(b.opts.BuildContext, absContext, filter); err != nil {
+			return fmt.Errorf("failed to stage build context: %w", err)
+		}
+	}
+
+	if err := b.mgr.PushDirectory(context.Background(), absContext, stageDir+"/"+baseName, nil); err != nil {
+		return fmt.Errorf("failed to push build context: %w", err)
+	}
+
+	if _, err := b.mgr.ExecCommand(moveBuildContextCommand(stageDir, baseName), container.ExecCommandOptions{Capture: true}); err != nil {
+		return fmt.Errorf("failed to move build context into place: %w", err)
+	}
+
+	return nil
+}
+
+// prepareBuildContextCommand returns the shell command clearing the build
+// context path and creating an empty staging directory
+func prepareBuildContextCommand(stageDir string) string {
+	contextPath, stage := container.ShellQuote(BuildContextPath), container.ShellQuote(stageDir)
+	return fmt.Sprintf("rm -rf %s %s && mkdir -p %s", contextPath, stage, stage)
+}
+
+// moveBuildContextCommand returns the shell command moving the pushed context
+// directory baseName (named after the host directory, so any characters are
+// possible) from the staging directory to BuildContextPath
+func moveBuildContextCommand(stageDir, baseName string) string {
+	return fmt.Sprintf("mv %s %s && rmdir %s",
+		container.ShellQuote(stageDir+"/"+baseName), container.ShellQuote(BuildContextPath), container.ShellQuote(stageDir))
+}
+
+// ValidateBuildContext checks that a build context directory exists and is not
+// larger than MaxBuildContextSize, leaving out paths matching the
+// .dockerignore patterns in ignore. Returns the total size in bytes.
+func ValidateBuildContext(dir string, ignore []string) (int64, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return 0, fmt.Errorf("build context not found: %s", dir)
+	}
+	if !info.IsDir() {
+		return 0, fmt.Errorf("build context is not a directory: %s", dir)
+	}
+
+	filter, err := newContextFilter(ignore)
+	if err != nil {
+		return 0, err
+	}
+
+	var size int64
+	err = walkBuildContext(dir, filter, func(_, _ string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		if size > MaxBuildContextSize {
+			return fmt.Errorf("build context %s is larger than %d MiB", dir, MaxBuildContextSize/(1024*1024))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
 // createImage publishes the container as an image
 func (b *Builder) createImage(versionAlias string) (string, error) {
 	b.opts.Logger("Stopping container for imaging...")
@@ -419,12 +647,9 @@ func (b *Builder) createImage(versionAlias string) (string, error) {
 
 	b.opts.Logger(fmt.Sprintf("Creating image '%s'...", versionAlias))
 
-	// Publish container as image
-	_, err := container.IncusOutput(
-		"publish", BuildContainer,
-		"--alias", versionAlias,
-		fmt.Sprintf("description=%s", b.opts.Description),
-	)
+	// Publish container as image, with the description and coi properties
+	args := append([]string{"publish", BuildContainer, "--alias", versionAlias}, propertyArgs(b.imageProperties())...)
+	_, err := container.IncusOutput(args...)
 	if err != nil {
 		return "", fmt.Errorf("failed to create image: %w", err)
 	}
@@ -457,13 +682,19 @@ func (b *Builder) updateAlias(versionAlias, mainAlias string) error {
 		return err
 	}
 
+	return SetAlias(mainAlias, fingerprint)
+}
+
+// SetAlias points alias at the image with the given fingerprint, replacing any
+// existing alias of that name
+func SetAlias(alias, fingerprint string) error {
 	// Delete old alias if it exists
-	if exists, _ := container.ImageExists(mainAlias); exists {
-		_ = container.IncusExec("image", "alias", "delete", mainAlias) // Best effort
+	if exists, _ := container.ImageExists(alias); exists {
+		_ = container.IncusExec("image", "alias", "delete", alias) // Best effort
 	}
 
 	// Create new alias
-	if err := container.IncusExec("image", "alias", "create", mainAlias, fingerprint); err != nil {
+	if err := container.IncusExec("image", "alias", "create", alias, fingerprint); err != nil {
 		return fmt.Errorf("failed to create alias: %w", err)
 	}
 
@@ -0,0 +1,117 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMoveBuildContextCommand(t *testing.T) {
+	tests := []struct {
+		baseName string
+		want     string
+	}{
+		{"ctx", "mv /tmp/stage/ctx /tmp/build-context && rmdir /tmp/stage"},
+		{"my context", "mv '/tmp/stage/my context' /tmp/build-context && rmdir /tmp/stage"},
+		{"x;reboot", "mv '/tmp/stage/x;reboot' /tmp/build-context && rmdir /tmp/stage"},
+		{"it's", `mv '/tmp/stage/it'"'"'s' /tmp/build-context && rmdir /tmp/stage`},
+	}
+	for _, tt := range tests {
+		if got := moveBuildContextCommand("/tmp/stage", tt.baseName); got != tt.want {
+			t.Errorf("moveBuildContextCommand(%q) = %q, want %q", tt.baseName, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareBuildContextCommand(t *testing.T) {
+	want := "rm -rf /tmp/build-context '/tmp/a stage' && mkdir -p '/tmp/a stage'"
+	if got := prepareBuildContextCommand("/tmp/a stage"); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestValidateBuildContext(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a"), []byte("12345"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b"), []byte("123"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := ValidateBuildContext(dir, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if size != 8 {
+		t.Errorf("Expected size 8, got %d", size)
+	}
+
+	if _, err := ValidateBuildContext(filepath.Join(dir, "missing"), nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if _, err := ValidateBuildContext(filepath.Join(dir, "a"), nil); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("Expected not a directory error, got %v", err)
+	}
+
+	// A sparse file is enough to exceed the limit without using the disk space
+	big := t.TempDir()
+	f, err := os.Create(filepath.Join(big, "huge"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Truncate(MaxBuildContextSize + 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := ValidateBuildContext(big, nil); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Expected size limit error, got %v", err)
+	}
+}
+
+func TestBuildScriptCommand(t *testing.T) {
+	if got := buildScriptCommand(false); got != "/tmp/build.sh" {
+		t.Errorf("buildScriptCommand(false) = %q, want the bare script", got)
+	}
+	if got, want := buildScriptCommand(true), "/tmp/build.sh > /tmp/build.log 2>&1"; got != want {
+		t.Errorf("buildScriptCommand(true) = %q, want %q", got, want)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	built := NewReport("coi", &BuildResult{Success: true, VersionAlias: "coi-20260101-120000", Fingerprint: "abc123"}, 1500400*time.Microsecond)
+	want := Report{Success: true, Alias: "coi", Version: "coi-20260101-120000", Fingerprint: "abc123", DurationSeconds: 1.5}
+	if built != want {
+		t.Errorf("NewReport(built) = %+v, want %+v", built, want)
+	}
+
+	skipped := NewReport("coi", &BuildResult{Skipped: true, VersionAlias: "ignored", Fingerprint: "ignored"}, time.Second)
+	want = Report{Success: true, Alias: "coi", Skipped: true, DurationSeconds: 1}
+	if skipped != want {
+		t.Errorf("NewReport(skipped) = %+v, want %+v", skipped, want)
+	}
+
+	failed := NewReport("my-image", &BuildResult{Error: errors.New("build script failed"), Fingerprint: "ignored"}, 0)
+	want = Report{Alias: "my-image", Error: "build script failed"}
+	if failed != want {
+		t.Errorf("NewReport(failed) = %+v, want %+v", failed, want)
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	line, err := json.Marshal(Report{Success: true, Alias: "coi", Skipped: true, DurationSeconds: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"success":true,"alias":"coi","skipped":true,"duration_seconds":0.5}`
+	if string(line) != want {
+		t.Errorf("Report JSON = %s, want %s", line, want)
+	}
+}
@@ -0,0 +1,289 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// dockerfilePreamble starts every translated Dockerfile script. Docker runs
+// instructions from / with the build context as COPY source, so the script
+// does too. coi_copy DEST SRC... copies like COPY: directories by content,
+// into DEST when it ends with / or names a directory.
+const dockerfilePreamble = `#!/bin/bash
+# Translated from a Dockerfile by 'coi build --from-dockerfile'
+set -e
+COI_BUILD_CONTEXT=` + BuildContextPath + `
+cd /
+
+coi_copy() {
+  local dest="$1" src
+  shift
+  case "$dest" in */) mkdir -p "$dest" ;; esac
+  [ $# -gt 1 ] && mkdir -p "$dest"
+  for src in "$@"; do
+    if [ -d "$src" ]; then
+      mkdir -p "$dest" && cp -a "$src"/. "$dest"/
+    elif [ -d "$dest" ]; then
+      cp -a "$src" "$dest"/
+    else
+      mkdir -p "$(dirname "$dest")" && cp -a "$src" "$dest"
+    fi
+  done
+}
+`
+
+// TranslateDockerfile turns the RUN, ENV, ARG, WORKDIR, COPY and (local) ADD
+// instructions of a Dockerfile into a bash build script for a custom image.
+// ENV values are also written to /etc/environment so they outlive the build.
+// Instructions without an equivalent (FROM, USER, CMD, HEALTHCHECK, further
+// stages, ...) are skipped with a warning; heredocs are not supported.
+func TranslateDockerfile(content string) (string, []string, error) {
+	var script strings.Builder
+	var warnings []string
+	script.WriteString(dockerfilePreamble)
+
+	warn := func(line int, format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf("line %d: %s", line, fmt.Sprintf(format, args...)))
+	}
+
+	stages := 0
+	for _, inst := range dockerfileInstructions(content) {
+		keyword, rest, _ := strings.Cut(inst.text, " ")
+		rest = strings.TrimSpace(rest)
+		keyword = strings.ToUpper(keyword)
+
+		switch keyword {
+		case "FROM":
+			stages++
+			if stages == 1 {
+				warn(inst.line, "FROM %s ignored - the image is built on --base", rest)
+			} else {
+				warn(inst.line, "multi-stage builds are not supported - instructions after this FROM run in the same container")
+			}
+
+		case "RUN":
+			command, flags := cutInstructionFlags(rest)
+			if len(flags) > 0 {
+				warn(inst.line, "RUN flags %s ignored", strings.Join(flags, " "))
+			}
+			if strings.Contains(command, "<<") {
+				warn(inst.line, "heredocs are not supported, RUN skipped")
+				continue
+			}
+			if strings.HasPrefix(command, "[") {
+				var args []string
+				if err := json.Unmarshal([]byte(command), &args); err != nil {
+					return "", nil, fmt.Errorf("line %d: invalid RUN exec form: %w", inst.line, err)
+				}
+				command = container.ShellJoin(args)
+			}
+			fmt.Fprintf(&script, "\n# RUN (line %d)\n(\n%s\n)\n", inst.line, command)
+
+		case "ENV":
+			pairs, err := envPairs(rest)
+			if err != nil {
+				return "", nil, fmt.Errorf("line %d: %w", inst.line, err)
+			}
+			fmt.Fprintf(&script, "\n# ENV (line %d)\n", inst.line)
+			for _, pair := range pairs {
+				name, _, _ := strings.Cut(pair, "=")
+				fmt.Fprintf(&script, "export %s\nprintf '%%s=%%s\\n' %s \"$%s\" >> /etc/environment\n", pair, name, name)
+			}
+
+		case "ARG":
+			// Build arguments only get their defaults; there is no --build-arg
+			for _, word := range splitWords(rest) {
+				if strings.Contains(word, "=") {
+					fmt.Fprintf(&script, "\n# ARG (line %d)\nexport %s\n", inst.line, word)
+				}
+			}
+
+		case "WORKDIR":
+			if strings.Contains(rest, "$") {
+				warn(inst.line, "variables in WORKDIR are not expanded")
+			}
+			dir := container.ShellQuote(rest)
+			fmt.Fprintf(&script, "\n# WORKDIR (line %d)\nmkdir -p %s && cd %s\n", inst.line, dir, dir)
+
+		case "COPY", "ADD":
+			args, flags := cutInstructionFlags(rest)
+			words, err := copyArgs(args)
+			if err != nil {
+				return "", nil, fmt.Errorf("line %d: %s %w", inst.line, keyword, err)
+			}
+			skip := false
+			for _, flag := range flags {
+				if strings.HasPrefix(flag, "--from") {
+					warn(inst.line, "%s %s (from another stage or image) is not supported, skipped", keyword, flag)
+					skip = true
+				} else {
+					warn(inst.line, "%s flag %s ignored - files are owned by root", keyword, flag)
+				}
+			}
+			sources, dest := words[:len(words)-1], words[len(words)-1]
+			if keyword == "ADD" {
+				for _, src := range sources {
+					if strings.Contains(src, "://") || isArchive(src) {
+						warn(inst.line, "ADD of URLs and archives is not supported, skipped (use RUN curl/tar)")
+						skip = true
+						break
+					}
+				}
+			}
+			if skip {
+				continue
+			}
+			if !strings.HasPrefix(dest, "/") && !strings.HasPrefix(dest, "$") {
+				dest = `"$PWD"/` + dest
+			}
+			fmt.Fprintf(&script, "\n# %s (line %d)\n(coi_dest=%s; cd \"$COI_BUILD_CONTEXT\" && coi_copy \"$coi_dest\" %s)\n",
+				keyword, inst.line, dest, strings.Join(sources, " "))
+
+		case "USER":
+			warn(inst.line, "USER ignored - build steps run as root")
+		case "LABEL":
+			warn(inst.line, "LABEL ignored - use --label to set image properties")
+		case "CMD", "ENTRYPOINT", "EXPOSE", "VOLUME", "STOPSIGNAL", "HEALTHCHECK", "SHELL", "ONBUILD", "MAINTAINER":
+			warn(inst.line, "%s is not supported, skipped", keyword)
+		default:
+			warn(inst.line, "unknown instruction %s, skipped", keyword)
+		}
+	}
+
+	return script.String(), warnings, nil
+}
+
+// dockerfileInstruction is one logical Dockerfile line (continuations joined)
+type dockerfileInstruction struct {
+	line int // Line number where the instruction starts
+	text string
+}
+
+// dockerfileInstructions splits a Dockerfile into instructions, joining
+// backslash continuations and dropping comments and blank lines
+func dockerfileInstructions(content string) []dockerfileInstruction {
+	var instructions []dockerfileInstruction
+	var current strings.Builder
+	start := 0
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(strings.TrimSuffix(raw, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if current.Len() == 0 {
+			start = i + 1
+		}
+		if body, ok := strings.CutSuffix(line, "\\"); ok {
+			current.WriteString(body)
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(line)
+		instructions = append(instructions, dockerfileInstruction{line: start, text: current.String()})
+		current.Reset()
+	}
+	if current.Len() > 0 {
+		instructions = append(instructions, dockerfileInstruction{line: start, text: strings.TrimSpace(current.String())})
+	}
+	return instructions
+}
+
+// cutInstructionFlags splits leading --flag words off an instruction's arguments
+func cutInstructionFlags(rest string) (string, []string) {
+	var flags []string
+	for strings.HasPrefix(rest, "--") {
+		flag, remaining, _ := strings.Cut(rest, " ")
+		flags = append(flags, flag)
+		rest = strings.TrimSpace(remaining)
+	}
+	return rest, flags
+}
+
+// envPairs returns the name=value words of an ENV instruction, converting the
+// legacy "ENV name value" form
+func envPairs(rest string) ([]string, error) {
+	words := splitWords(rest)
+	if len(words) == 0 {
+		return nil, fmt.Errorf("ENV needs a name and value")
+	}
+	if !strings.Contains(words[0], "=") {
+		name, value, _ := strings.Cut(rest, " ")
+		return []string{name + "=" + container.ShellQuote(strings.TrimSpace(value))}, nil
+	}
+	for _, word := range words {
+		if !strings.Contains(word, "=") {
+			return nil, fmt.Errorf("invalid ENV pair '%s' - expected name=value", word)
+		}
+	}
+	return words, nil
+}
+
+// copyArgs returns the sources and destination of COPY/ADD (last word), in
+// shell or JSON form
+func copyArgs(args string) ([]string, error) {
+	var words []string
+	if strings.HasPrefix(args, "[") {
+		var parsed []string
+		if err := json.Unmarshal([]byte(args), &parsed); err != nil {
+			return nil, fmt.Errorf("has an invalid exec form: %w", err)
+		}
+		for _, word := range parsed {
+			words = append(words, container.ShellQuote(word))
+		}
+	} else {
+		words = splitWords(args)
+	}
+	if len(words) < 2 {
+		return nil, fmt.Errorf("needs a source and a destination")
+	}
+	return words, nil
+}
+
+// splitWords splits s at unquoted whitespace, keeping quotes and escapes in
+// the words so the shell interprets them as Docker would
+func splitWords(s string) []string {
+	var words []string
+	var word strings.Builder
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// isArchive reports whether an ADD source looks like an archive Docker would extract
+func isArchive(src string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz"} {
+		if strings.HasSuffix(src, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package image
+
+import (
+	"strings"
+	"testing"
+)
+
+// translatedSteps returns the script TranslateDockerfile generates without
+// its preamble
+func translatedSteps(t *testing.T, content string) (string, []string) {
+	t.Helper()
+	script, warnings, err := TranslateDockerfile(content)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return strings.TrimPrefix(script, dockerfilePreamble), warnings
+}
+
+func TestTranslateDockerfile(t *testing.T) {
+	tests := []struct {
+		name         string
+		dockerfile   string
+		wantSteps    string
+		wantWarnings []string
+	}{
+		{
+			name:       "RUN shell form",
+			dockerfile: "RUN apt-get update && \\\n    apt-get install -y jq",
+			wantSteps:  "\n# RUN (line 1)\n(\napt-get update &&  apt-get install -y jq\n)\n",
+		},
+		{
+			name:       "RUN exec form",
+			dockerfile: `RUN ["echo", "hello world"]`,
+			wantSteps:  "\n# RUN (line 1)\n(\necho 'hello world'\n)\n",
+		},
+		{
+			name:         "RUN flags ignored",
+			dockerfile:   "RUN --mount=type=cache,target=/root/.cache pip install x",
+			wantSteps:    "\n# RUN (line 1)\n(\npip install x\n)\n",
+			wantWarnings: []string{"line 1: RUN flags --mount=type=cache,target=/root/.cache ignored"},
+		},
+		{
+			name:         "RUN heredoc skipped",
+			dockerfile:   "RUN <<EOF\necho hi\nEOF",
+			wantSteps:    "",
+			wantWarnings: []string{"line 1: heredocs are not supported, RUN skipped", "line 2: unknown instruction ECHO, skipped", "line 3: unknown instruction EOF, skipped"},
+		},
+		{
+			name:       "ENV pairs",
+			dockerfile: `ENV A=1 B="two words"`,
+			wantSteps: "\n# ENV (line 1)\n" +
+				"export A=1\nprintf '%s=%s\\n' A \"$A\" >> /etc/environment\n" +
+				"export B=\"two words\"\nprintf '%s=%s\\n' B \"$B\" >> /etc/environment\n",
+		},
+		{
+			name:       "ENV legacy form",
+			dockerfile: "ENV GREETING hello world",
+			wantSteps:  "\n# ENV (line 1)\nexport GREETING='hello world'\nprintf '%s=%s\\n' GREETING \"$GREETING\" >> /etc/environment\n",
+		},
+		{
+			name:       "ARG defaults only",
+			dockerfile: "ARG VERSION=1.2\nARG TOKEN",
+			wantSteps:  "\n# ARG (line 1)\nexport VERSION=1.2\n",
+		},
+		{
+			name:       "WORKDIR",
+			dockerfile: "WORKDIR /opt/app",
+			wantSteps:  "\n# WORKDIR (line 1)\nmkdir -p /opt/app && cd /opt/app\n",
+		},
+		{
+			name:       "WORKDIR quoted",
+			dockerfile: "WORKDIR /opt/my app;reboot",
+			wantSteps:  "\n# WORKDIR (line 1)\nmkdir -p '/opt/my app;reboot' && cd '/opt/my app;reboot'\n",
+		},
+		{
+			name:         "WORKDIR variables",
+			dockerfile:   "WORKDIR $HOME/app",
+			wantSteps:    "\n# WORKDIR (line 1)\nmkdir -p '$HOME/app' && cd '$HOME/app'\n",
+			wantWarnings: []string{"line 1: variables in WORKDIR are not expanded"},
+		},
+		{
+			name:       "COPY relative destination",
+			dockerfile: "COPY package.json src/ app/",
+			wantSteps:  "\n# COPY (line 1)\n(coi_dest=\"$PWD\"/app/; cd \"$COI_BUILD_CONTEXT\" && coi_copy \"$coi_dest\" package.json src/)\n",
+		},
+		{
+			name:       "COPY exec form",
+			dockerfile: `COPY ["my file", "/opt/"]`,
+			wantSteps:  "\n# COPY (line 1)\n(coi_dest=/opt/; cd \"$COI_BUILD_CONTEXT\" && coi_copy \"$coi_dest\" 'my file')\n",
+		},
+		{
+			name:         "COPY --chown ignored",
+			dockerfile:   "COPY --chown=app:app . /app",
+			wantSteps:    "\n# COPY (line 1)\n(coi_dest=/app; cd \"$COI_BUILD_CONTEXT\" && coi_copy \"$coi_dest\" .)\n",
+			wantWarnings: []string{"line 1: COPY flag --chown=app:app ignored - files are owned by root"},
+		},
+		{
+			name:         "COPY --from skipped",
+			dockerfile:   "COPY --from=builder /out /app",
+			wantWarnings: []string{"line 1: COPY --from=builder (from another stage or image) is not supported, skipped"},
+		},
+		{
+			name:         "ADD URL skipped",
+			dockerfile:   "ADD https://example.com/tool /usr/local/bin/tool",
+			wantWarnings: []string{"line 1: ADD of URLs and archives is not supported, skipped (use RUN curl/tar)"},
+		},
+		{
+			name:         "ADD archive skipped",
+			dockerfile:   "ADD vendor.tar.gz /opt/",
+			wantWarnings: []string{"line 1: ADD of URLs and archives is not supported, skipped (use RUN curl/tar)"},
+		},
+		{
+			name:       "unsupported instructions",
+			dockerfile: "# comment\nFROM ubuntu:22.04\nUSER app\nCMD [\"bash\"]\nHEALTHCHECK NONE\nFROM alpine\nFOO bar",
+			wantWarnings: []string{
+				"line 2: FROM ubuntu:22.04 ignored - the image is built on --base",
+				"line 3: USER ignored - build steps run as root",
+				"line 4: CMD is not supported, skipped",
+				"line 5: HEALTHCHECK is not supported, skipped",
+				"line 6: multi-stage builds are not supported - instructions after this FROM run in the same container",
+				"line 7: unknown instruction FOO, skipped",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, warnings := translatedSteps(t, tt.dockerfile)
+			if steps != tt.wantSteps {
+				t.Errorf("Expected steps %q, got %q", tt.wantSteps, steps)
+			}
+			if strings.Join(warnings, "\n") != strings.Join(tt.wantWarnings, "\n") {
+				t.Errorf("Expected warnings %q, got %q", tt.wantWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestTranslateDockerfileErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		wantErr    string
+	}{
+		{"invalid RUN exec form", `RUN ["echo", ]`, "line 1: invalid RUN exec form"},
+		{"ENV without name", "ENV", "line 1: ENV needs a name and value"},
+		{"invalid ENV pair", "ENV A=1 B", "line 1: invalid ENV pair 'B'"},
+		{"COPY without destination", "RUN true\nCOPY app", "line 2: COPY needs a source and a destination"},
+		{"invalid ADD exec form", `ADD ["a", `, "line 1: ADD has an invalid exec form"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := TranslateDockerfile(tt.dockerfile)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
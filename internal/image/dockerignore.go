@@ -0,0 +1,230 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DockerignoreFile lists the paths of a Dockerfile's build context that are
+// not pushed to the build container
+const DockerignoreFile = ".dockerignore"
+
+// ReadDockerignore returns the patterns of dir's .dockerignore, or nil if
+// there is none
+func ReadDockerignore(dir string) ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, DockerignoreFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", DockerignoreFile, err)
+	}
+	return parseDockerignore(string(content)), nil
+}
+
+// parseDockerignore returns the patterns of a .dockerignore file, dropping
+// comments and blank lines. Patterns are relative to the context root, so a
+// leading / is removed; a leading ! (exception) is kept.
+func parseDockerignore(content string) []string {
+	var patterns []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		exception := strings.HasPrefix(line, "!")
+		line = strings.TrimSpace(strings.TrimPrefix(line, "!"))
+		line = strings.TrimPrefix(path.Clean("/"+line), "/")
+		if line == "" {
+			continue
+		}
+		if exception {
+			line = "!" + line
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// ignorePattern is one compiled .dockerignore pattern
+type ignorePattern struct {
+	re        *regexp.Regexp
+	exception bool
+}
+
+// contextFilter decides which build context paths .dockerignore excludes.
+// As with Docker, the last pattern matching a path (or one of its parent
+// directories) wins, and ! patterns re-include paths.
+type contextFilter struct {
+	patterns      []ignorePattern
+	hasExceptions bool
+}
+
+// newContextFilter compiles .dockerignore patterns. Returns nil (nothing
+// excluded) for no patterns.
+func newContextFilter(patterns []string) (*contextFilter, error) {
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	filter := &contextFilter{}
+	for _, pattern := range patterns {
+		exception := strings.HasPrefix(pattern, "!")
+		re, err := regexp.Compile(ignorePatternRegexp(strings.TrimPrefix(pattern, "!")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern '%s': %w", DockerignoreFile, pattern, err)
+		}
+		filter.patterns = append(filter.patterns, ignorePattern{re: re, exception: exception})
+		filter.hasExceptions = filter.hasExceptions || exception
+	}
+	return filter, nil
+}
+
+// excluded reports whether the slash-separated path rel (relative to the
+// context root) is left out of the build context
+func (f *contextFilter) excluded(rel string) bool {
+	if f == nil {
+		return false
+	}
+	excluded := false
+	for _, p := range f.patterns {
+		if p.matches(rel) {
+			excluded = !p.exception
+		}
+	}
+	return excluded
+}
+
+// matches reports whether the pattern matches rel or one of its parent directories
+func (p ignorePattern) matches(rel string) bool {
+	for candidate := rel; candidate != "."; candidate = path.Dir(candidate) {
+		if p.re.MatchString(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignorePatternRegexp translates a .dockerignore glob into a regular
+// expression: * and ? do not cross /, ** matches any number of directories
+func ignorePatternRegexp(pattern string) string {
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					re.WriteString("(.*/)?")
+				} else {
+					re.WriteString(".*")
+				}
+			} else {
+				re.WriteString("[^/]*")
+			}
+		case '?':
+			re.WriteString("[^/]")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			if end := strings.IndexByte(pattern[i:], ']'); end > 0 {
+				class := pattern[i : i+end+1]
+				re.WriteString(strings.Replace(class, "[!", "[^", 1))
+				i += end
+			} else {
+				re.WriteString(`\[`)
+			}
+		default:
+			re.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	re.WriteString("$")
+	return re.String()
+}
+
+// walkBuildContext calls fn for every path under dir the filter keeps. An
+// excluded directory is only descended into when exceptions could re-include
+// something below it.
+func walkBuildContext(dir string, filter *contextFilter, fn func(current, rel string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(dir, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, current)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if filter.excluded(rel) {
+			if d.IsDir() && !filter.hasExceptions {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		return fn(current, rel, d)
+	})
+}
+
+// copyBuildContext copies the paths of the build context src that the filter
+// keeps to dst
+func copyBuildContext(src, dst string, filter *contextFilter) error {
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return err
+	}
+	return walkBuildContext(src, filter, func(current, rel string, d fs.DirEntry) error {
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		// Files re-included by an exception may sit in an excluded directory
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(current)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyContextFile(current, target, info.Mode().Perm())
+		default:
+			return nil // Sockets, devices and pipes are not part of a build context
+		}
+	})
+}
+
+// copyContextFile copies a single regular file with the given permissions
+func copyContextFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,165 @@
+package image
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseDockerignore(t *testing.T) {
+	content := "# build output\n\nnode_modules\n/dist/\n  *.log  \n!keep.log\n./tmp\r\n"
+	got := parseDockerignore(content)
+	want := []string{"node_modules", "dist", "*.log", "!keep.log", "tmp"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestContextFilterExcluded(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		rel      string
+		want     bool
+	}{
+		{[]string{"node_modules"}, "node_modules", true},
+		{[]string{"node_modules"}, "node_modules/pkg/index.js", true},
+		{[]string{"node_modules"}, "web/node_modules", false}, // Root only, as in Docker
+		{[]string{"**/node_modules"}, "web/node_modules/x", true},
+		{[]string{"**/node_modules"}, "node_modules", true},
+		{[]string{"*.log"}, "app.log", true},
+		{[]string{"*.log"}, "logs/app.log", false},
+		{[]string{"**/*.log"}, "logs/app.log", true},
+		{[]string{"*.log", "!keep.log"}, "keep.log", false},
+		{[]string{"!keep.log", "*.log"}, "keep.log", true}, // Last match wins
+		{[]string{"docs/?.md"}, "docs/a.md", true},
+		{[]string{"docs/?.md"}, "docs/ab.md", false},
+		{[]string{"file[0-9]"}, "file7", true},
+		{[]string{"file[!0-9]"}, "file7", false},
+		{[]string{"secret"}, "secrets", false},
+		{nil, "anything", false},
+	}
+
+	for _, tt := range tests {
+		filter, err := newContextFilter(tt.patterns)
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", tt.patterns, err)
+		}
+		if got := filter.excluded(tt.rel); got != tt.want {
+			t.Errorf("patterns %v, path %q: expected excluded=%v, got %v", tt.patterns, tt.rel, tt.want, got)
+		}
+	}
+}
+
+// writeContextFiles creates files (slash-separated paths) under dir
+func writeContextFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopyBuildContext(t *testing.T) {
+	src := t.TempDir()
+	writeContextFiles(t, src, map[string]string{
+		"Dockerfile":                 "RUN true",
+		"app/main.go":                "package main",
+		"app/debug.log":              "noise",
+		"node_modules/pkg/index.js":  "x",
+		"node_modules/pkg/LICENSE":   "MIT",
+		"build/output.bin":           "bin",
+		"build/keep/important.txt":   "keep",
+		"docs/guide.md":              "guide",
+		"docs/drafts/unfinished.md":  "draft",
+		".git/config":                "[core]",
+		"nested/node_modules/a.js":   "kept - not at the root",
+		"nested/deeper/trace.log":    "noise",
+		"nested/deeper/readme.txt":   "kept",
+		"build/keep/more/ignored.md": "not re-included",
+	})
+	if err := os.Symlink("app/main.go", filepath.Join(src, "main-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := newContextFilter([]string{
+		".git", "node_modules", "**/*.log", "build", "!build/keep/important.txt", "docs/drafts",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "ctx")
+	if err := copyBuildContext(src, dst, filter); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	err = filepath.WalkDir(dst, func(current string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dst, current)
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{
+		"Dockerfile",
+		"app/main.go",
+		"build/keep/important.txt",
+		"docs/guide.md",
+		"main-link",
+		"nested/deeper/readme.txt",
+		"nested/node_modules/a.js",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if link, err := os.Readlink(filepath.Join(dst, "main-link")); err != nil || link != "app/main.go" {
+		t.Errorf("Expected symlink to be copied as a link, got %q (%v)", link, err)
+	}
+}
+
+func TestValidateBuildContextIgnore(t *testing.T) {
+	dir := t.TempDir()
+	writeContextFiles(t, dir, map[string]string{
+		"main.go":        "12345",
+		"node_modules/x": strings.Repeat("x", 100),
+	})
+
+	size, err := ValidateBuildContext(dir, []string{"node_modules"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if size != 5 {
+		t.Errorf("Expected ignored paths not to count, got size %d", size)
+	}
+}
+
+func TestReadDockerignore(t *testing.T) {
+	dir := t.TempDir()
+	patterns, err := ReadDockerignore(dir)
+	if err != nil || patterns != nil {
+		t.Errorf("Expected no patterns without a .dockerignore, got %v (%v)", patterns, err)
+	}
+
+	writeContextFiles(t, dir, map[string]string{DockerignoreFile: "node_modules\n"})
+	patterns, err = ReadDockerignore(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(patterns, []string{"node_modules"}) {
+		t.Errorf("Expected [node_modules], got %v", patterns)
+	}
+}
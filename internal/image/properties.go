@@ -0,0 +1,105 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// Image properties stamped on every image coi builds, so tooling can read
+// structured data instead of parsing the description
+const (
+	PropertyVersion = "user.coi.version" // coi version that built the image
+	PropertyTool    = "user.coi.tool"    // AI tool configured at build time
+	PropertyBase    = "user.coi.base"    // Image the build started from
+)
+
+// propertyKeyPattern matches keys that are valid Incus image property names
+var propertyKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// reservedProperties are set by the builder and cannot be given with --label
+var reservedProperties = []string{"description", PropertyVersion, PropertyTool, PropertyBase}
+
+// ParseProperties parses --label key=value pairs into image properties
+func ParseProperties(pairs []string) (map[string]string, error) {
+	properties := make(map[string]string)
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label '%s' - expected key=value", pair)
+		}
+		key = strings.TrimSpace(key)
+		if !propertyKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid label key '%s' - use letters, digits, '-', '_' and '.'", key)
+		}
+		for _, reserved := range reservedProperties {
+			if key == reserved {
+				return nil, fmt.Errorf("label '%s' is set by coi build and cannot be overridden", key)
+			}
+		}
+		properties[key] = value
+	}
+	return properties, nil
+}
+
+// imageProperties returns the properties to publish the image with
+func (b *Builder) imageProperties() map[string]string {
+	properties := map[string]string{
+		"description": b.opts.Description,
+		PropertyBase:  b.opts.BaseImage,
+	}
+	if b.opts.CoiVersion != "" {
+		properties[PropertyVersion] = b.opts.CoiVersion
+	}
+	if b.opts.Tool != "" {
+		properties[PropertyTool] = b.opts.Tool
+	}
+	for key, value := range b.opts.Properties {
+		properties[key] = value
+	}
+	return properties
+}
+
+// propertyArgs renders properties as sorted key=value arguments for incus publish
+func propertyArgs(properties map[string]string) []string {
+	args := make([]string, 0, len(properties))
+	for key, value := range properties {
+		args = append(args, key+"="+value)
+	}
+	sort.Strings(args)
+	return args
+}
+
+// Properties returns the properties of the image an alias points to
+func Properties(alias string) (map[string]string, error) {
+	output, err := container.IncusOutput("image", "list", alias, "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	var images []struct {
+		Aliases []struct {
+			Name string `json:"name"`
+		} `json:"aliases"`
+		Properties map[string]string `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(output), &images); err != nil {
+		return nil, fmt.Errorf("failed to parse images: %w", err)
+	}
+
+	for _, img := range images {
+		for _, a := range img.Aliases {
+			if a.Name == alias {
+				if img.Properties == nil {
+					return map[string]string{}, nil
+				}
+				return img.Properties, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("image not found: %s", alias)
+}
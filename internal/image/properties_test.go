@@ -0,0 +1,104 @@
+package image
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:  "no labels",
+			pairs: nil,
+			want:  map[string]string{},
+		},
+		{
+			name:  "key value pairs",
+			pairs: []string{"team=platform", "user.project=api", "os.release_1=a=b"},
+			want:  map[string]string{"team": "platform", "user.project": "api", "os.release_1": "a=b"},
+		},
+		{
+			name:  "empty value and trimmed key",
+			pairs: []string{" owner =", "owner2= spaced value "},
+			want:  map[string]string{"owner": "", "owner2": " spaced value "},
+		},
+		{
+			name:  "later label wins",
+			pairs: []string{"team=a", "team=b"},
+			want:  map[string]string{"team": "b"},
+		},
+		{
+			name:    "missing value",
+			pairs:   []string{"team"},
+			wantErr: "invalid label 'team' - expected key=value",
+		},
+		{
+			name:    "empty key",
+			pairs:   []string{"=value"},
+			wantErr: "invalid label key ''",
+		},
+		{
+			name:    "invalid key characters",
+			pairs:   []string{"my team=a"},
+			wantErr: "invalid label key 'my team'",
+		},
+		{
+			name:    "key starting with a dot",
+			pairs:   []string{".hidden=a"},
+			wantErr: "invalid label key '.hidden'",
+		},
+		{
+			name:    "reserved description",
+			pairs:   []string{"description=mine"},
+			wantErr: "label 'description' is set by coi build",
+		},
+		{
+			name:    "reserved coi property",
+			pairs:   []string{PropertyVersion + "=1.0"},
+			wantErr: "label '" + PropertyVersion + "' is set by coi build",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProperties(tt.pairs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestImageProperties(t *testing.T) {
+	b := NewBuilder(BuildOptions{
+		Description: "Custom image: dev",
+		BaseImage:   CoiAlias,
+		CoiVersion:  "1.2.3",
+		Properties:  map[string]string{"team": "platform"},
+	})
+
+	want := []string{
+		"description=Custom image: dev",
+		"team=platform",
+		PropertyBase + "=coi",
+		PropertyVersion + "=1.2.3",
+	}
+	if got := propertyArgs(b.imageProperties()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v (no tool property when unset), got %v", want, got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,10 @@ type ImageInfo struct {
 	Aliases     []string  `json:"aliases"`
 	Size        int64     `json:"size"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Aliases of the image (matching the prefix or not) without a version
+	// timestamp, e.g. the "coi" alias sessions launch from (set by ListVersions)
+	LiveAliases []string `json:"-"`
 }
 
 // ListVersions returns all images matching a prefix, sorted by timestamp
@@ -52,11 +57,19 @@ func ListVersions(prefix string) ([]ImageInfo, error) {
 					}
 				}
 
+				var liveAliases []string
+				for _, a := range img.Aliases {
+					if ValidateVersionedAlias(a.Name) != nil {
+						liveAliases = append(liveAliases, a.Name)
+					}
+				}
+
 				images = append(images, ImageInfo{
 					Fingerprint: img.Fingerprint,
 					Aliases:     matchingAliases,
 					Size:        img.Size,
 					CreatedAt:   img.CreatedAt,
+					LiveAliases: liveAliases,
 				})
 				break // Only add image once
 			}
@@ -92,9 +105,9 @@ func ExtractTimestamp(alias string) (time.Time, error) {
 	dateStr := matches[1]
 	timeStr := matches[2]
 
-	// Parse as YYYYMMDD-HHMMSS -> 20060102-150405
+	// Parse as YYYYMMDD-HHMMSS -> 20060102-150405, in local time like the builder writes it
 	combined := dateStr + timeStr
-	t, err := time.Parse("20060102150405", combined)
+	t, err := time.ParseInLocation("20060102150405", combined, time.Local)
 	if err != nil {
 		return time.Time{}, fmt.Errorf("failed to parse timestamp: %w", err)
 	}
@@ -102,63 +115,96 @@ func ExtractTimestamp(alias string) (time.Time, error) {
 	return t, nil
 }
 
-// Cleanup deletes old versions, keeping only the N most recent
-// Returns lists of deleted and kept aliases
-func Cleanup(prefix string, keepCount int) (deleted []string, kept []string, err error) {
-	if keepCount <= 0 {
-		return nil, nil, fmt.Errorf("keepCount must be > 0")
+// CleanupOptions selects which versions Cleanup deletes. At least one of
+// KeepCount and OlderThan must be set; a version is deleted if either selects it.
+type CleanupOptions struct {
+	KeepCount int           // Keep only the N most recent versions (0: no count limit)
+	OlderThan time.Duration // Delete versions whose alias timestamp is older than this (0: no age limit)
+	DryRun    bool          // Report what would be deleted without deleting anything
+}
+
+// CleanupResult lists the aliases Cleanup deleted and kept
+type CleanupResult struct {
+	Deleted        []string // Deleted aliases (would-be deleted in a dry run)
+	Kept           []string
+	ReclaimedBytes int64 // Total size of the deleted images
+}
+
+// Cleanup deletes old versions matching prefix according to opts
+func Cleanup(prefix string, opts CleanupOptions) (*CleanupResult, error) {
+	if opts.KeepCount < 0 || opts.OlderThan < 0 {
+		return nil, fmt.Errorf("keep count and age must not be negative")
+	}
+	if opts.KeepCount == 0 && opts.OlderThan == 0 {
+		return nil, fmt.Errorf("either a keep count or a maximum age is required")
 	}
 
-	// Get all versions
+	// Get all versions (sorted oldest first)
 	images, err := ListVersions(prefix)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	if len(images) == 0 {
-		return nil, nil, nil
-	}
+	remove, keep := selectForCleanup(images, opts, time.Now())
 
-	// Sort by timestamp (oldest first)
-	sort.Slice(images, func(i, j int) bool {
-		timeI, errI := ExtractTimestamp(images[i].Aliases[0])
-		timeJ, errJ := ExtractTimestamp(images[j].Aliases[0])
+	result := &CleanupResult{}
+	for _, img := range keep {
+		result.Kept = append(result.Kept, img.Aliases...)
+	}
 
-		if errI != nil || errJ != nil {
-			return images[i].Aliases[0] < images[j].Aliases[0]
+	for _, img := range remove {
+		if !opts.DryRun {
+			// Delete by fingerprint (removes all aliases for this image)
+			if err := container.DeleteImage(img.Fingerprint); err != nil {
+				return result, fmt.Errorf("failed to delete image %s: %w", img.Fingerprint, err)
+			}
 		}
+		result.Deleted = append(result.Deleted, img.Aliases...)
+		result.ReclaimedBytes += img.Size
+	}
 
-		return timeI.Before(timeJ)
-	})
+	return result, nil
+}
 
-	// Determine which to delete (oldest ones beyond keepCount)
-	deleteCount := len(images) - keepCount
-	if deleteCount <= 0 {
-		// Keep all
-		for _, img := range images {
-			kept = append(kept, img.Aliases...)
-		}
-		return nil, kept, nil
+// selectForCleanup splits versions (sorted oldest first) into those to delete
+// and those to keep. Versions without a parseable timestamp are never deleted
+// for their age. The newest version is always kept, and so is any image that
+// also carries a live (unversioned) alias, since deleting by fingerprint
+// would remove that alias too.
+func selectForCleanup(images []ImageInfo, opts CleanupOptions, now time.Time) (remove, keep []ImageInfo) {
+	beyondCount := 0
+	if opts.KeepCount > 0 && len(images) > opts.KeepCount {
+		beyondCount = len(images) - opts.KeepCount
 	}
 
-	// Delete old versions
-	for i := 0; i < deleteCount; i++ {
-		img := images[i]
-
-		// Delete by fingerprint (removes all aliases for this image)
-		if err := container.DeleteImage(img.Fingerprint); err != nil {
-			return deleted, kept, fmt.Errorf("failed to delete image %s: %w", img.Fingerprint, err)
+	for i, img := range images {
+		expired := false
+		if opts.OlderThan > 0 {
+			if created, err := ExtractTimestamp(img.Aliases[0]); err == nil {
+				expired = now.Sub(created) > opts.OlderThan
+			}
 		}
 
-		deleted = append(deleted, img.Aliases...)
+		newest := i == len(images)-1
+		if (i < beyondCount || expired) && !newest && len(img.LiveAliases) == 0 {
+			remove = append(remove, img)
+		} else {
+			keep = append(keep, img)
+		}
 	}
+	return remove, keep
+}
 
-	// Collect kept aliases
-	for i := deleteCount; i < len(images); i++ {
-		kept = append(kept, images[i].Aliases...)
+// ParseAge parses a maximum image age: a Go duration (72h) or whole days (14d)
+func ParseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
 	}
-
-	return deleted, kept, nil
+	return 0, fmt.Errorf("invalid --older-than value '%s' - use a duration (72h) or days (14d)", value)
 }
 
 // ValidateVersionedAlias validates that an alias follows the versioned format
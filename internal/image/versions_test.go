@@ -0,0 +1,147 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSelectForCleanup(t *testing.T) {
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.Local)
+	version := func(alias string, live ...string) ImageInfo {
+		return ImageInfo{Fingerprint: alias, Aliases: []string{alias}, LiveAliases: live}
+	}
+	// Sorted oldest first, like ListVersions returns them
+	images := []ImageInfo{
+		version("ci-20260301-120000"),
+		version("ci-20260310-120000"),
+		version("ci-20260315-120000"),
+		version("ci-20260319-120000"),
+	}
+
+	tests := []struct {
+		name       string
+		images     []ImageInfo
+		opts       CleanupOptions
+		wantRemove []string
+	}{
+		{
+			name:       "keep count",
+			images:     images,
+			opts:       CleanupOptions{KeepCount: 2},
+			wantRemove: []string{"ci-20260301-120000", "ci-20260310-120000"},
+		},
+		{
+			name:       "keep count larger than versions",
+			images:     images,
+			opts:       CleanupOptions{KeepCount: 10},
+			wantRemove: nil,
+		},
+		{
+			name:       "older than",
+			images:     images,
+			opts:       CleanupOptions{OlderThan: 7 * 24 * time.Hour},
+			wantRemove: []string{"ci-20260301-120000", "ci-20260310-120000"},
+		},
+		{
+			name:       "either selects",
+			images:     images,
+			opts:       CleanupOptions{KeepCount: 3, OlderThan: 14 * 24 * time.Hour},
+			wantRemove: []string{"ci-20260301-120000"},
+		},
+		{
+			name:       "older than never removes the newest version",
+			images:     images,
+			opts:       CleanupOptions{OlderThan: time.Hour},
+			wantRemove: []string{"ci-20260301-120000", "ci-20260310-120000", "ci-20260315-120000"},
+		},
+		{
+			name: "image with a live alias is kept",
+			images: []ImageInfo{
+				version("coi-20260301-120000", "coi"),
+				version("coi-20260310-120000"),
+				version("coi-20260319-120000"),
+			},
+			opts:       CleanupOptions{KeepCount: 1},
+			wantRemove: []string{"coi-20260310-120000"},
+		},
+		{
+			name: "alias without timestamp is not removed for its age",
+			images: []ImageInfo{
+				version("ci-manual"),
+				version("ci-20260319-120000"),
+			},
+			opts:       CleanupOptions{OlderThan: time.Hour},
+			wantRemove: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remove, keep := selectForCleanup(tt.images, tt.opts, now)
+
+			var got []string
+			for _, img := range remove {
+				got = append(got, img.Aliases[0])
+			}
+			if !reflect.DeepEqual(got, tt.wantRemove) {
+				t.Errorf("Expected to remove %v, got %v", tt.wantRemove, got)
+			}
+			if len(remove)+len(keep) != len(tt.images) {
+				t.Errorf("Expected every version to be removed or kept, got %d + %d of %d", len(remove), len(keep), len(tt.images))
+			}
+		})
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "72h", want: 72 * time.Hour},
+		{value: "90m", want: 90 * time.Minute},
+		{value: "14d", want: 14 * 24 * time.Hour},
+		{value: "1d", want: 24 * time.Hour},
+		{value: "0d", wantErr: true},
+		{value: "-1d", wantErr: true},
+		{value: "1.5d", wantErr: true},
+		{value: "0s", wantErr: true},
+		{value: "-2h", wantErr: true},
+		{value: "two weeks", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAge(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExtractTimestamp(t *testing.T) {
+	got, err := ExtractTimestamp("node-42-20260108-103000")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := time.Date(2026, 1, 8, 10, 30, 0, 0, time.Local); !got.Equal(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := ExtractTimestamp("coi"); err == nil {
+		t.Error("Expected error for alias without timestamp")
+	}
+}
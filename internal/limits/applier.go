@@ -2,8 +2,9 @@ package limits
 
 import (
 	"fmt"
-	"os/exec"
 	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
 )
 
 // ApplyOptions contains options for applying limits
@@ -147,14 +148,9 @@ func applyProcessLimits(containerName string, maxProcesses int, project string)
 func setIncusConfig(containerName, key, value, project string) error {
 	args := []string{"config", "set"}
 
-	// Add project flag if specified
-	if project != "" && project != "default" {
-		args = append(args, "--project", project)
-	}
-
 	args = append(args, containerName, fmt.Sprintf("%s=%s", key, value))
 
-	cmd := exec.Command("incus", args...)
+	cmd := container.NewIncusCommand(args...).WithProject(project).Cmd()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("incus config set %s=%s failed: %w (output: %s)", key, value, err, string(output))
@@ -192,13 +188,9 @@ func RemoveLimits(containerName, project string) error {
 func unsetIncusConfig(containerName, key, project string) error {
 	args := []string{"config", "unset"}
 
-	if project != "" && project != "default" {
-		args = append(args, "--project", project)
-	}
-
 	args = append(args, containerName, key)
 
-	cmd := exec.Command("incus", args...)
+	cmd := container.NewIncusCommand(args...).WithProject(project).Cmd()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		// Check if error is because key doesn't exist (which is fine)
@@ -216,13 +208,9 @@ func unsetIncusConfig(containerName, key, project string) error {
 func GetCurrentLimits(containerName, project string) (map[string]string, error) {
 	args := []string{"config", "show"}
 
-	if project != "" && project != "default" {
-		args = append(args, "--project", project)
-	}
-
 	args = append(args, containerName)
 
-	cmd := exec.Command("incus", args...)
+	cmd := container.NewIncusCommand(args...).WithProject(project).Cmd()
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get container config: %w (output: %s)", err, string(output))
@@ -3,14 +3,18 @@ package network
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
 )
 
 // IPCache stores resolved domain IPs with timestamp
 type IPCache struct {
 	Domains    map[string][]string `json:"domains"`
+	TTLs       map[string]int      `json:"ttls,omitempty"` // Shortest DNS TTL per domain (seconds), when known
 	LastUpdate time.Time           `json:"last_update"`
 }
 
@@ -34,17 +38,17 @@ func (c *CacheManager) Load(containerName string) (*IPCache, error) {
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Return empty cache if file doesn't exist
-			return &IPCache{
-				Domains:    make(map[string][]string),
-				LastUpdate: time.Time{},
-			}, nil
+			return emptyIPCache(), nil
 		}
 		return nil, fmt.Errorf("failed to read cache file: %w", err)
 	}
 
 	var cache IPCache
 	if err := json.Unmarshal(data, &cache); err != nil {
-		return nil, fmt.Errorf("failed to parse cache file: %w", err)
+		// A damaged cache (e.g. written by an older coi that truncated in
+		// place) only costs a fresh resolution, so start over rather than fail
+		log.Printf("Warning: Ignoring unreadable IP cache %s: %v", cachePath, err)
+		return emptyIPCache(), nil
 	}
 
 	// Initialize domains map if nil
@@ -69,13 +73,51 @@ func (c *CacheManager) Save(containerName string, cache *IPCache) error {
 		return fmt.Errorf("failed to marshal cache: %w", err)
 	}
 
-	if err := os.WriteFile(cachePath, data, 0o644); err != nil {
+	if err := writeFileAtomic(cachePath, data, 0o644); err != nil {
 		return fmt.Errorf("failed to write cache file: %w", err)
 	}
 
 	return nil
 }
 
+// emptyIPCache returns a cache with nothing resolved yet
+func emptyIPCache() *IPCache {
+	return &IPCache{
+		Domains:    make(map[string][]string),
+		LastUpdate: time.Time{},
+	}
+}
+
+// writeFileAtomic writes data to a temp file in path's directory and renames it
+// into place, so readers never see a half-written file and concurrent writers
+// leave one complete version rather than an interleaved mix
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+
+	_, err = file.Write(data)
+	if syncErr := file.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
 // Delete removes the cache file for a container
 func (c *CacheManager) Delete(containerName string) error {
 	cachePath := filepath.Join(c.cacheDir, fmt.Sprintf("%s.json", containerName))
@@ -89,3 +131,68 @@ func (c *CacheManager) Delete(containerName string) error {
 
 	return nil
 }
+
+// SaveConfig persists the network config used for a container so that a
+// manager can be reconstructed later (e.g. by 'coi network refresh')
+func (c *CacheManager) SaveConfig(containerName string, cfg *config.NetworkConfig) error {
+	if err := os.MkdirAll(c.cacheDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	configPath := filepath.Join(c.cacheDir, fmt.Sprintf("%s.config.json", containerName))
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal network config: %w", err)
+	}
+
+	if err := writeFileAtomic(configPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write network config file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadConfig reads the persisted network config for a container
+func (c *CacheManager) LoadConfig(containerName string) (*config.NetworkConfig, error) {
+	configPath := filepath.Join(c.cacheDir, fmt.Sprintf("%s.config.json", containerName))
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg config.NetworkConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse network config file: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// DeleteConfig removes the persisted network config for a container
+func (c *CacheManager) DeleteConfig(containerName string) error {
+	configPath := filepath.Join(c.cacheDir, fmt.Sprintf("%s.config.json", containerName))
+
+	if err := os.Remove(configPath); err != nil {
+		if os.IsNotExist(err) {
+			return nil // Already deleted
+		}
+		return fmt.Errorf("failed to delete network config file: %w", err)
+	}
+
+	return nil
+}
+
+// Rename moves a container's IP cache and persisted network config to its new
+// name (after 'coi container rename'). Missing files are skipped.
+func (c *CacheManager) Rename(oldName, newName string) error {
+	for _, suffix := range []string{".json", ".config.json"} {
+		oldPath := filepath.Join(c.cacheDir, oldName+suffix)
+		newPath := filepath.Join(c.cacheDir, newName+suffix)
+		if err := os.Rename(oldPath, newPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rename network cache file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package network
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestCacheManager_ConfigRoundTrip(t *testing.T) {
+	cm := NewCacheManager(t.TempDir())
+
+	cfg := &config.NetworkConfig{
+		Mode:                   config.NetworkModeAllowlist,
+		AllowedDomains:         []string{"api.anthropic.com", "8.8.8.8"},
+		RefreshIntervalMinutes: 15,
+	}
+
+	if err := cm.SaveConfig("coi-test-1", cfg); err != nil {
+		t.Fatalf("SaveConfig() unexpected error: %v", err)
+	}
+
+	loaded, err := cm.LoadConfig("coi-test-1")
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+
+	if loaded.Mode != cfg.Mode {
+		t.Errorf("Expected mode '%s', got '%s'", cfg.Mode, loaded.Mode)
+	}
+	if len(loaded.AllowedDomains) != 2 || loaded.AllowedDomains[0] != "api.anthropic.com" {
+		t.Errorf("Expected allowed domains %v, got %v", cfg.AllowedDomains, loaded.AllowedDomains)
+	}
+	if loaded.RefreshIntervalMinutes != 15 {
+		t.Errorf("Expected refresh interval 15, got %d", loaded.RefreshIntervalMinutes)
+	}
+
+	if err := cm.DeleteConfig("coi-test-1"); err != nil {
+		t.Fatalf("DeleteConfig() unexpected error: %v", err)
+	}
+	if _, err := cm.LoadConfig("coi-test-1"); !os.IsNotExist(err) {
+		t.Errorf("Expected not-exist error after delete, got %v", err)
+	}
+
+	// Deleting twice is not an error
+	if err := cm.DeleteConfig("coi-test-1"); err != nil {
+		t.Errorf("DeleteConfig() on missing file unexpected error: %v", err)
+	}
+}
+
+func TestCacheManager_SaveLoad(t *testing.T) {
+	cm := NewCacheManager(t.TempDir())
+
+	cache := &IPCache{
+		Domains: map[string][]string{"api.anthropic.com": {"160.79.104.10"}},
+		TTLs:    map[string]int{"api.anthropic.com": 300},
+	}
+	if err := cm.Save("coi-test-1", cache); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	// Overwriting an existing cache replaces it
+	cache.Domains["github.com"] = []string{"140.82.112.3"}
+	if err := cm.Save("coi-test-1", cache); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	loaded, err := cm.Load("coi-test-1")
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+	if len(loaded.Domains) != 2 || loaded.Domains["github.com"][0] != "140.82.112.3" {
+		t.Errorf("Expected both domains to round-trip, got %v", loaded.Domains)
+	}
+
+	// No temp files are left behind next to the cache
+	entries, err := os.ReadDir(cm.cacheDir)
+	if err != nil {
+		t.Fatalf("ReadDir() unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("Expected only the cache file, got %v", names)
+	}
+}
+
+func TestCacheManager_LoadTruncatedCache(t *testing.T) {
+	cm := NewCacheManager(t.TempDir())
+
+	if err := cm.Save("coi-test-1", &IPCache{Domains: map[string][]string{"github.com": {"140.82.112.3"}}}); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	cachePath := filepath.Join(cm.cacheDir, "coi-test-1.json")
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("ReadFile() unexpected error: %v", err)
+	}
+	if err := os.WriteFile(cachePath, data[:len(data)/2], 0o644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	loaded, err := cm.Load("coi-test-1")
+	if err != nil {
+		t.Fatalf("Load() of a truncated cache should fall back to empty, got error: %v", err)
+	}
+	if loaded.Domains == nil || len(loaded.Domains) != 0 {
+		t.Errorf("Expected empty domains, got %v", loaded.Domains)
+	}
+}
+
+func TestCacheManager_Rename(t *testing.T) {
+	cm := NewCacheManager(t.TempDir())
+
+	cache := &IPCache{Domains: map[string][]string{"github.com": {"140.82.112.3"}}}
+	if err := cm.Save("coi-abc-1", cache); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	if err := cm.SaveConfig("coi-abc-1", &config.NetworkConfig{Mode: config.NetworkModeAllowlist}); err != nil {
+		t.Fatalf("SaveConfig() unexpected error: %v", err)
+	}
+
+	if err := cm.Rename("coi-abc-1", "coi-refactor"); err != nil {
+		t.Fatalf("Rename() unexpected error: %v", err)
+	}
+
+	loaded, err := cm.Load("coi-refactor")
+	if err != nil || len(loaded.Domains["github.com"]) != 1 {
+		t.Errorf("Expected the IP cache under the new name, got %v (%v)", loaded, err)
+	}
+	cfg, err := cm.LoadConfig("coi-refactor")
+	if err != nil || cfg.Mode != config.NetworkModeAllowlist {
+		t.Errorf("Expected the network config under the new name, got %v (%v)", cfg, err)
+	}
+	if _, err := cm.LoadConfig("coi-abc-1"); !os.IsNotExist(err) {
+		t.Errorf("Expected no network config under the old name, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cm.cacheDir, "coi-abc-1.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no IP cache under the old name, got %v", err)
+	}
+
+	// Containers without saved network state (e.g. open mode) rename cleanly
+	if err := cm.Rename("coi-none-1", "coi-other"); err != nil {
+		t.Errorf("Rename() without cache files unexpected error: %v", err)
+	}
+}
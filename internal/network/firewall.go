@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os/exec"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +19,10 @@ import (
 type FirewallManager struct {
 	containerIP string
 	gatewayIP   string
+
+	// Set on dual-stack networks (see SetIPv6)
+	containerIPv6 string
+	gatewayIPv6   string
 }
 
 // NewFirewallManager creates a new firewall manager for a container
@@ -27,153 +33,345 @@ func NewFirewallManager(containerIP, gatewayIP string) *FirewallManager {
 	}
 }
 
-// ApplyRestricted applies restricted mode rules (block RFC1918, allow internet)
-func (f *FirewallManager) ApplyRestricted(cfg *config.NetworkConfig) error {
-	// Ensure base rules for return traffic are in place
-	if err := EnsureBaseRules(); err != nil {
-		log.Printf("Warning: failed to ensure base rules: %v", err)
+// SetIPv6 enables the IPv6 gateway allow rule and IPv6 denied-domain rules for
+// a container on a dual-stack network
+func (f *FirewallManager) SetIPv6(containerIPv6, gatewayIPv6 string) {
+	f.containerIPv6 = containerIPv6
+	f.gatewayIPv6 = gatewayIPv6
+}
+
+// FirewallRule is a firewalld direct rule in the FORWARD chain for a container
+type FirewallRule struct {
+	Family      string // "ipv4" or "ipv6"
+	Priority    int
+	Source      string
+	Destination string // Empty matches any destination
+	Action      string // ACCEPT or REJECT
+}
+
+// String renders the rule the way 'firewall-cmd --direct --get-all-rules' prints it
+func (r FirewallRule) String() string {
+	rule := fmt.Sprintf("%s filter FORWARD %d -s %s", r.Family, r.Priority, r.Source)
+	if r.Destination != "" {
+		rule += " -d " + r.Destination
 	}
+	return rule + " -j " + r.Action
+}
 
-	// Priority 0: Allow gateway (for host communication)
-	if f.gatewayIP != "" {
-		if err := f.addRule(0, f.containerIP, f.gatewayIP+"/32", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add gateway allow rule: %w", err)
+// args returns the rule as firewall-cmd --direct arguments
+func (r FirewallRule) args() []string {
+	args := []string{r.Family, "filter", "FORWARD", strconv.Itoa(r.Priority), "-s", r.Source}
+	if r.Destination != "" {
+		args = append(args, "-d", r.Destination)
+	}
+	return append(args, "-j", r.Action)
+}
+
+// ParseFirewallRule parses a line of 'firewall-cmd --direct --get-all-rules'
+// output. Rules that are not simple source/destination rules in the FORWARD
+// chain (e.g. the shared conntrack rule) are reported as not ok.
+func ParseFirewallRule(line string) (FirewallRule, bool) {
+	// "ipv4 filter FORWARD 10 -s 10.47.62.50 -d 10.0.0.0/8 -j REJECT"
+	parts := strings.Fields(line)
+	if len(parts) < 4 || parts[1] != "filter" || parts[2] != "FORWARD" {
+		return FirewallRule{}, false
+	}
+	priority, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return FirewallRule{}, false
+	}
+
+	rule := FirewallRule{Family: parts[0], Priority: priority}
+	for i := 4; i < len(parts); i += 2 {
+		if i+1 >= len(parts) {
+			return FirewallRule{}, false
+		}
+		switch parts[i] {
+		case "-s":
+			rule.Source = parts[i+1]
+		case "-d":
+			rule.Destination = parts[i+1]
+		case "-j":
+			rule.Action = parts[i+1]
+		default:
+			return FirewallRule{}, false
 		}
 	}
+	if rule.Source == "" || rule.Action == "" {
+		return FirewallRule{}, false
+	}
+	return rule, true
+}
+
+// rule builds a rule for traffic from the container's IPv4 address
+func (f *FirewallManager) rule(priority int, destination, action string) FirewallRule {
+	return FirewallRule{Family: ruleFamily(f.containerIP), Priority: priority, Source: f.containerIP, Destination: destination, Action: action}
+}
+
+// rule6 builds a rule for traffic from the container's IPv6 address. Callers
+// check that the container has one.
+func (f *FirewallManager) rule6(priority int, destination, action string) FirewallRule {
+	return FirewallRule{Family: "ipv6", Priority: priority, Source: f.containerIPv6, Destination: destination, Action: action}
+}
+
+// gatewayRules allows the gateway (host communication, and DNS via the
+// bridge's dnsmasq), including its IPv6 counterpart if configured
+func (f *FirewallManager) gatewayRules() []FirewallRule {
+	var rules []FirewallRule
+	if f.gatewayIP != "" {
+		rules = append(rules, f.rule(0, f.gatewayIP+"/32", "ACCEPT"))
+	}
+	if f.containerIPv6 != "" && f.gatewayIPv6 != "" {
+		rules = append(rules, f.rule6(0, f.gatewayIPv6+"/128", "ACCEPT"))
+	}
+	return rules
+}
+
+// privateNetworkRules applies action to the RFC1918 ranges at priority, and to
+// IPv6 unique local addresses if the container has an IPv6 address
+func (f *FirewallManager) privateNetworkRules(priority int, action string) []FirewallRule {
+	rules := []FirewallRule{
+		f.rule(priority, "10.0.0.0/8", action),
+		f.rule(priority, "172.16.0.0/12", action),
+		f.rule(priority, "192.168.0.0/16", action),
+	}
+	if f.containerIPv6 != "" {
+		rules = append(rules, f.rule6(priority, "fc00::/7", action))
+	}
+	return rules
+}
+
+// metadataRules rejects link-local addresses (cloud metadata endpoints) at
+// priority, over IPv6 too if the container has an IPv6 address
+func (f *FirewallManager) metadataRules(priority int) []FirewallRule {
+	rules := []FirewallRule{f.rule(priority, "169.254.0.0/16", "REJECT")}
+	if f.containerIPv6 != "" {
+		rules = append(rules, f.rule6(priority, "fe80::/10", "REJECT"), f.rule6(priority, "fd00:ec2::254/128", "REJECT"))
+	}
+	return rules
+}
+
+// catchAllRules applies action to all remaining traffic at priority, over
+// IPv6 too if the container has an IPv6 address
+func (f *FirewallManager) catchAllRules(priority int, action string) []FirewallRule {
+	rules := []FirewallRule{f.rule(priority, "0.0.0.0/0", action)}
+	if f.containerIPv6 != "" {
+		rules = append(rules, f.rule6(priority, "::/0", action))
+	}
+	return rules
+}
+
+// RestrictedRules returns the rules for restricted mode (block RFC1918, allow internet).
+// deniedIPs come from [network] denied_domains and are rejected ahead of the internet allow.
+func (f *FirewallManager) RestrictedRules(cfg *config.NetworkConfig, deniedIPs []string) []FirewallRule {
+	// Priority 0: Allow gateway (for host communication)
+	rules := f.gatewayRules()
+
+	// Priority 5: Block denied domains (after local network access, before the catch-all)
+	rules = append(rules, f.DenyIPRules(deniedIPs)...)
 
 	// Handle local network access
 	if cfg.AllowLocalNetworkAccess {
 		// Allow all RFC1918 when local network access is enabled
-		if err := f.addRule(1, f.containerIP, "10.0.0.0/8", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
-		}
-		if err := f.addRule(1, f.containerIP, "172.16.0.0/12", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
-		}
-		if err := f.addRule(1, f.containerIP, "192.168.0.0/16", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
-		}
+		rules = append(rules, f.privateNetworkRules(1, "ACCEPT")...)
 	} else if cfg.BlockPrivateNetworks {
 		// Block RFC1918 ranges
-		if err := f.addRule(10, f.containerIP, "10.0.0.0/8", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
-		}
-		if err := f.addRule(10, f.containerIP, "172.16.0.0/12", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
-		}
-		if err := f.addRule(10, f.containerIP, "192.168.0.0/16", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
-		}
+		rules = append(rules, f.privateNetworkRules(10, "REJECT")...)
 	}
 
 	// Block metadata endpoints
 	if cfg.BlockMetadataEndpoint {
-		if err := f.addRule(10, f.containerIP, "169.254.0.0/16", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add metadata block rule: %w", err)
-		}
+		rules = append(rules, f.metadataRules(10)...)
 	}
 
 	// Explicitly allow all other traffic (internet)
 	// Needed because FORWARD chain policy might be DROP with firewalld
-	if err := f.addRule(50, f.containerIP, "0.0.0.0/0", "ACCEPT"); err != nil {
-		return fmt.Errorf("failed to add default allow rule: %w", err)
-	}
-
-	return nil
+	return append(rules, f.catchAllRules(50, "ACCEPT")...)
 }
 
-// ApplyAllowlist applies allowlist mode rules (allow specific IPs, block all else)
-func (f *FirewallManager) ApplyAllowlist(cfg *config.NetworkConfig, allowedIPs []string) error {
-	// Ensure base rules for return traffic are in place
-	if err := EnsureBaseRules(); err != nil {
-		log.Printf("Warning: failed to ensure base rules: %v", err)
-	}
-
+// AllowlistRules returns the rules for allowlist mode (allow specific IPs, block all else)
+func (f *FirewallManager) AllowlistRules(cfg *config.NetworkConfig, allowedIPs []string) []FirewallRule {
 	// Priority 0: Allow gateway (for host communication and DNS via dnsmasq)
 	// DNS works through the bridge's dnsmasq - no public DNS servers allowed
 	// to prevent DNS exfiltration attacks
-	if f.gatewayIP != "" {
-		if err := f.addRule(0, f.containerIP, f.gatewayIP+"/32", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add gateway allow rule: %w", err)
-		}
-	}
+	rules := f.gatewayRules()
 
 	// Handle local network access
 	if cfg.AllowLocalNetworkAccess {
 		// Allow all RFC1918 when local network access is enabled
-		if err := f.addRule(1, f.containerIP, "10.0.0.0/8", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
-		}
-		if err := f.addRule(1, f.containerIP, "172.16.0.0/12", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
-		}
-		if err := f.addRule(1, f.containerIP, "192.168.0.0/16", "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 allow rule: %w", err)
-		}
+		rules = append(rules, f.privateNetworkRules(1, "ACCEPT")...)
 	}
 
-	// Priority 1: Allow specific IPs (from resolved domains)
+	// Priority 1: Allow specific IPs (from resolved domains, IPv4 only - all
+	// other IPv6 traffic is rejected by the default deny)
 	// Sort for deterministic ordering
 	sortedIPs := make([]string, len(allowedIPs))
 	copy(sortedIPs, allowedIPs)
 	sort.Strings(sortedIPs)
+	rules = append(rules, f.AllowIPRules(sortedIPs)...)
 
+	// Block RFC1918 and metadata (unless local network access is enabled)
+	if !cfg.AllowLocalNetworkAccess {
+		rules = append(rules, f.privateNetworkRules(10, "REJECT")...)
+		rules = append(rules, f.metadataRules(10)...)
+	}
+
+	// Priority 99: Default deny for allowlist mode
+	return append(rules, f.catchAllRules(99, "REJECT")...)
+}
+
+// OpenRules returns the rules for open mode with denied domains: everything is
+// allowed except deniedIPs. Without denied domains open mode uses EnsureOpenModeRules.
+func (f *FirewallManager) OpenRules(deniedIPs []string) []FirewallRule {
+	// Priority 5: Block denied domains
+	rules := f.DenyIPRules(deniedIPs)
+
+	// Allow all other traffic (FORWARD chain policy might be DROP with firewalld)
+	return append(rules, f.catchAllRules(50, "ACCEPT")...)
+}
+
+// DenyIPRules returns rules rejecting specific IPs ahead of the catch-all allow
+// of open and restricted modes, in sorted order. IPv6 addresses are rejected
+// from the container's IPv6 address; without one it cannot reach them anyway.
+func (f *FirewallManager) DenyIPRules(ips []string) []FirewallRule {
+	sortedIPs := make([]string, len(ips))
+	copy(sortedIPs, ips)
+	sort.Strings(sortedIPs)
+
+	rules := make([]FirewallRule, 0, len(sortedIPs))
 	for _, ip := range sortedIPs {
+		if ruleFamily(ip) == "ipv6" {
+			if f.containerIPv6 == "" {
+				continue
+			}
+			dest := ip
+			if !strings.Contains(ip, "/") {
+				dest = ip + "/128"
+			}
+			rules = append(rules, f.rule6(5, dest, "REJECT"))
+			continue
+		}
 		dest := ip
 		if !strings.Contains(ip, "/") {
 			dest = ip + "/32"
 		}
-		if err := f.addRule(1, f.containerIP, dest, "ACCEPT"); err != nil {
-			return fmt.Errorf("failed to add allowlist rule for %s: %w", ip, err)
-		}
+		rules = append(rules, f.rule(5, dest, "REJECT"))
 	}
+	return rules
+}
 
-	// Block RFC1918 and metadata (unless local network access is enabled)
-	if !cfg.AllowLocalNetworkAccess {
-		if err := f.addRule(10, f.containerIP, "10.0.0.0/8", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
-		}
-		if err := f.addRule(10, f.containerIP, "172.16.0.0/12", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
-		}
-		if err := f.addRule(10, f.containerIP, "192.168.0.0/16", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add RFC1918 block rule: %w", err)
-		}
-		if err := f.addRule(10, f.containerIP, "169.254.0.0/16", "REJECT"); err != nil {
-			return fmt.Errorf("failed to add metadata block rule: %w", err)
+// AllowIPRules returns rules allowing specific IPs ahead of the RFC1918 block rules
+func (f *FirewallManager) AllowIPRules(ips []string) []FirewallRule {
+	rules := make([]FirewallRule, 0, len(ips))
+	for _, ip := range ips {
+		dest := ip
+		if !strings.Contains(ip, "/") {
+			dest = ip + "/32"
 		}
+		rules = append(rules, f.rule(1, dest, "ACCEPT"))
+	}
+	return rules
+}
+
+// ApplyRestricted applies restricted mode rules (block RFC1918, allow internet)
+func (f *FirewallManager) ApplyRestricted(cfg *config.NetworkConfig, deniedIPs []string) error {
+	// Ensure base rules for return traffic are in place
+	if err := EnsureBaseRules(); err != nil {
+		log.Printf("Warning: failed to ensure base rules: %v", err)
 	}
+	return f.addRules(f.RestrictedRules(cfg, deniedIPs))
+}
 
-	// Priority 99: Default deny for allowlist mode
-	if err := f.addRule(99, f.containerIP, "0.0.0.0/0", "REJECT"); err != nil {
-		return fmt.Errorf("failed to add default deny rule: %w", err)
+// ApplyOpen applies open mode rules with denied domains (block deniedIPs, allow all else)
+func (f *FirewallManager) ApplyOpen(deniedIPs []string) error {
+	// Ensure base rules for return traffic are in place
+	if err := EnsureBaseRules(); err != nil {
+		log.Printf("Warning: failed to ensure base rules: %v", err)
 	}
+	return f.addRules(f.OpenRules(deniedIPs))
+}
 
-	return nil
+// ApplyAllowlist applies allowlist mode rules (allow specific IPs, block all else)
+func (f *FirewallManager) ApplyAllowlist(cfg *config.NetworkConfig, allowedIPs []string) error {
+	// Ensure base rules for return traffic are in place
+	if err := EnsureBaseRules(); err != nil {
+		log.Printf("Warning: failed to ensure base rules: %v", err)
+	}
+	return f.addRules(f.AllowlistRules(cfg, allowedIPs))
+}
+
+// AllowIPs allows the container to reach specific IPs ahead of the RFC1918 block rules
+func (f *FirewallManager) AllowIPs(ips []string) error {
+	return f.addRules(f.AllowIPRules(ips))
 }
 
-// RemoveRules removes all firewall rules for this container's IP
+// RemoveRules removes all firewall rules from this container's addresses
 func (f *FirewallManager) RemoveRules() error {
-	if f.containerIP == "" {
+	return RemoveRulesFrom(f.sources())
+}
+
+// sources returns the container addresses this manager's rules use
+func (f *FirewallManager) sources() []string {
+	var sources []string
+	for _, address := range []string{f.containerIP, f.containerIPv6} {
+		if address != "" {
+			sources = append(sources, address)
+		}
+	}
+	return sources
+}
+
+// RemoveRulesFrom removes the direct rules whose source is one of addresses.
+// Unlike RemoveRules it needs no container, e.g. for one that was deleted.
+func RemoveRulesFrom(addresses []string) error {
+	if len(addresses) == 0 {
 		return nil
 	}
 
-	// List all direct rules
-	rules, err := f.listDirectRules()
+	f := &FirewallManager{}
+	lines, err := f.listDirectRules()
 	if err != nil {
 		return fmt.Errorf("failed to list firewall rules: %w", err)
 	}
+	for _, rule := range rulesFromSources(lines, addresses) {
+		if err := f.removeRule(rule); err != nil {
+			log.Printf("Warning: failed to remove firewall rule: %v", err)
+		}
+	}
+	return nil
+}
 
-	// Remove rules that match this container's IP
-	for _, rule := range rules {
-		if strings.Contains(rule, f.containerIP) {
-			if err := f.removeRule(rule); err != nil {
-				log.Printf("Warning: failed to remove firewall rule: %v", err)
-			}
+// rulesFromSources returns the direct rule lines whose source is exactly one
+// of sources (10.0.0.1 must not match the rules of 10.0.0.12)
+func rulesFromSources(lines, sources []string) []string {
+	var matching []string
+	for _, line := range lines {
+		rule, ok := ParseFirewallRule(line)
+		if ok && slices.Contains(sources, rule.Source) {
+			matching = append(matching, line)
 		}
 	}
+	return matching
+}
 
-	return nil
+// LiveRules returns the direct rules currently applied for this container
+func (f *FirewallManager) LiveRules() ([]FirewallRule, error) {
+	lines, err := f.listDirectRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list firewall rules: %w", err)
+	}
+
+	var rules []FirewallRule
+	for _, line := range lines {
+		rule, ok := ParseFirewallRule(line)
+		if !ok {
+			continue
+		}
+		if slices.Contains(f.sources(), rule.Source) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
 }
 
 // EnsureBaseRules adds the base rules needed for container networking
@@ -181,20 +379,29 @@ func (f *FirewallManager) RemoveRules() error {
 func EnsureBaseRules() error {
 	// Add conntrack rule for return traffic via firewalld direct rules
 	// Priority -1 ensures this runs before all other rules (including our container rules at 0+)
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--add-rule",
-		"ipv4", "filter", "FORWARD", "-1",
-		"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// Rule might already exist, that's OK
-		if !strings.Contains(string(output), "ALREADY_ENABLED") {
-			log.Printf("Warning: failed to add conntrack rule via firewalld: %s", strings.TrimSpace(string(output)))
+	// Both families, so return traffic also works on dual-stack networks
+	for _, family := range []string{"ipv4", "ipv6"} {
+		cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--add-rule",
+			family, "filter", "FORWARD", "-1",
+			"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			// Rule might already exist, that's OK
+			if !strings.Contains(string(output), "ALREADY_ENABLED") {
+				log.Printf("Warning: failed to add %s conntrack rule via firewalld: %s", family, strings.TrimSpace(string(output)))
+			}
 		}
 	}
 
 	return nil
 }
 
+// OpenModeRule returns the rule accepting all traffic from a container in open
+// mode without denied domains
+func OpenModeRule(containerIP string) FirewallRule {
+	return FirewallRule{Family: ruleFamily(containerIP), Priority: 0, Source: containerIP, Action: "ACCEPT"}
+}
+
 // EnsureOpenModeRules adds rules to allow all traffic for a container in open mode
 // This is needed because FORWARD chain policy may be DROP
 func EnsureOpenModeRules(containerIP string) error {
@@ -204,9 +411,8 @@ func EnsureOpenModeRules(containerIP string) error {
 	}
 
 	// Add ACCEPT rule for all traffic from this container
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--add-rule",
-		"ipv4", "filter", "FORWARD", "0",
-		"-s", containerIP, "-j", "ACCEPT")
+	args := append([]string{"-n", "firewall-cmd", "--direct", "--add-rule"}, OpenModeRule(containerIP).args()...)
+	cmd := exec.Command("sudo", args...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		if !strings.Contains(string(output), "ALREADY_ENABLED") {
@@ -217,21 +423,89 @@ func EnsureOpenModeRules(containerIP string) error {
 	return nil
 }
 
-// addRule adds a firewall direct rule using firewall-cmd
-func (f *FirewallManager) addRule(priority int, source, destination, action string) error {
-	// firewall-cmd --direct --add-rule ipv4 filter FORWARD <priority> -s <src> -d <dst> -j <action>
-	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--add-rule",
-		"ipv4", "filter", "FORWARD", fmt.Sprintf("%d", priority),
-		"-s", source, "-d", destination, "-j", action)
+// addRules adds firewall direct rules in order using firewall-cmd, then reads
+// the rules back to make sure all of them are in effect
+func (f *FirewallManager) addRules(rules []FirewallRule) error {
+	for _, rule := range rules {
+		if err := addRule(rule); err != nil {
+			return err
+		}
+	}
 
-	output, err := cmd.CombinedOutput()
+	live, err := f.LiveRules()
 	if err != nil {
-		return fmt.Errorf("firewall-cmd failed: %s: %w", strings.TrimSpace(string(output)), err)
+		return fmt.Errorf("failed to verify firewall rules: %w", err)
+	}
+	if missing := missingRules(rules, live); len(missing) > 0 {
+		return fmt.Errorf("firewall rules not in effect after applying them (was firewalld reloaded?): %s", strings.Join(missing, "; "))
 	}
-
 	return nil
 }
 
+// addRule adds one direct rule. firewall-cmd fails intermittently while
+// firewalld is busy (e.g. reloading as a fast launch brings the bridge up),
+// so failures other than invalid rules and sudo problems are retried.
+func addRule(rule FirewallRule) error {
+	const maxAttempts = 4
+	const retryDelay = 250 * time.Millisecond
+
+	// firewall-cmd --direct --add-rule <ipv4|ipv6> filter FORWARD <priority> -s <src> -d <dst> -j <action>
+	args := append([]string{"-n", "firewall-cmd", "--direct", "--add-rule"}, rule.args()...)
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		output, err := exec.Command("sudo", args...).CombinedOutput()
+		if err == nil || strings.Contains(string(output), "ALREADY_ENABLED") {
+			return nil
+		}
+		lastErr = fmt.Errorf("failed to add firewall rule '%s': firewall-cmd failed: %s: %w", rule, strings.TrimSpace(string(output)), err)
+
+		if !transientFirewallError(string(output)) {
+			break
+		}
+		if attempt < maxAttempts {
+			time.Sleep(retryDelay * time.Duration(attempt))
+		}
+	}
+	return lastErr
+}
+
+// transientFirewallError reports whether firewall-cmd output describes a
+// failure worth retrying, as opposed to a rule firewalld rejects or sudo
+// refusing to run firewall-cmd at all
+func transientFirewallError(output string) bool {
+	for _, permanent := range []string{"INVALID_", "password is required", "not allowed to execute", "command not found"} {
+		if strings.Contains(output, permanent) {
+			return false
+		}
+	}
+	return true
+}
+
+// missingRules returns the intended rules not present in live, as text
+func missingRules(intended, live []FirewallRule) []string {
+	present := make(map[FirewallRule]bool, len(live))
+	for _, rule := range live {
+		present[rule] = true
+	}
+
+	var missing []string
+	for _, rule := range intended {
+		if !present[rule] {
+			missing = append(missing, rule.String())
+		}
+	}
+	return missing
+}
+
+// ruleFamily returns the firewalld direct rule family ("ipv4" or "ipv6") for an address
+func ruleFamily(address string) string {
+	if strings.Contains(address, ":") {
+		return "ipv6"
+	}
+	return "ipv4"
+}
+
 // listDirectRules lists all direct rules in the FORWARD chain
 func (f *FirewallManager) listDirectRules() ([]string, error) {
 	cmd := exec.Command("sudo", "-n", "firewall-cmd", "--direct", "--get-all-rules")
@@ -303,6 +577,38 @@ func getContainerIPOnce(containerName string) (string, error) {
 		return "", fmt.Errorf("failed to get container info: %w", err)
 	}
 
+	ip, err := parseContainerAddress(output, containerName, "inet")
+	if err != nil {
+		return "", err
+	}
+	if ip == "" {
+		return "", fmt.Errorf("no IPv4 address found for container %s", containerName)
+	}
+	return ip, nil
+}
+
+// GetContainerIPv6 retrieves the global IPv6 address of a container's eth0.
+// Unlike GetContainerIP it does not wait, since IPv4 setup already waited for DHCP.
+func GetContainerIPv6(containerName string) (string, error) {
+	output, err := container.IncusOutput("list", containerName, "--format=json")
+	if err != nil {
+		return "", fmt.Errorf("failed to get container info: %w", err)
+	}
+
+	ip, err := parseContainerAddress(output, containerName, "inet6")
+	if err != nil {
+		return "", err
+	}
+	if ip == "" {
+		return "", fmt.Errorf("no global IPv6 address found for container %s", containerName)
+	}
+	return ip, nil
+}
+
+// parseContainerAddress returns the first eth0 address of the given family
+// ("inet" or "inet6") from 'incus list --format=json' output, skipping
+// link-local addresses. Returns "" if there is none.
+func parseContainerAddress(output, containerName, family string) (string, error) {
 	var containers []struct {
 		Name  string `json:"name"`
 		State struct {
@@ -310,6 +616,7 @@ func getContainerIPOnce(containerName string) (string, error) {
 				Addresses []struct {
 					Family  string `json:"family"`
 					Address string `json:"address"`
+					Scope   string `json:"scope"`
 				} `json:"addresses"`
 			} `json:"network"`
 		} `json:"state"`
@@ -321,10 +628,10 @@ func getContainerIPOnce(containerName string) (string, error) {
 
 	for _, c := range containers {
 		if c.Name == containerName {
-			// Look for eth0 IPv4 address
+			// Look for eth0 address of the requested family
 			if eth0, ok := c.State.Network["eth0"]; ok {
 				for _, addr := range eth0.Addresses {
-					if addr.Family == "inet" {
+					if addr.Family == family && addr.Scope != "link" {
 						return addr.Address, nil
 					}
 				}
@@ -332,7 +639,82 @@ func getContainerIPOnce(containerName string) (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf("no IPv4 address found for container %s", containerName)
+	return "", nil
+}
+
+// ContainerAddresses returns the IPv4 and IPv6 addresses the firewall rules
+// of the named containers use. Running containers report them in their state;
+// stopped ones no longer do, so their DHCP leases on the bridge are used.
+func ContainerAddresses(names []string) (map[string][]string, error) {
+	listOutput, err := container.IncusOutput("list", "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	// Without leases only running containers' addresses are known
+	leasesOutput := ""
+	if networkName, err := defaultNetworkName(); err == nil {
+		leasesOutput, _ = container.IncusOutput("network", "list-leases", networkName, "--format=json")
+	}
+	return containerAddresses(names, listOutput, leasesOutput)
+}
+
+// containerAddresses picks the addresses of the named containers from
+// 'incus list --format=json' output, falling back to their leases from
+// 'incus network list-leases --format=json' output
+func containerAddresses(names []string, listOutput, leasesOutput string) (map[string][]string, error) {
+	leases, err := parseLeases(leasesOutput)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make(map[string][]string)
+	for _, name := range names {
+		var found []string
+		for _, family := range []string{"inet", "inet6"} {
+			address, err := parseContainerAddress(listOutput, name, family)
+			if err != nil {
+				return nil, err
+			}
+			if address != "" {
+				found = append(found, address)
+			}
+		}
+		if len(found) == 0 {
+			found = leases[name]
+		}
+		if len(found) > 0 {
+			addresses[name] = found
+		}
+	}
+	return addresses, nil
+}
+
+// parseLeases maps instance names to their leased addresses from
+// 'incus network list-leases --format=json' output, skipping the gateway
+// entries and link-local addresses
+func parseLeases(output string) (map[string][]string, error) {
+	if strings.TrimSpace(output) == "" {
+		return nil, nil
+	}
+
+	var entries []struct {
+		Hostname string `json:"hostname"`
+		Address  string `json:"address"`
+		Type     string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(output), &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse network leases: %w", err)
+	}
+
+	leases := make(map[string][]string)
+	for _, entry := range entries {
+		if entry.Type == "GATEWAY" || entry.Hostname == "" || strings.HasPrefix(strings.ToLower(entry.Address), "fe80:") {
+			continue
+		}
+		leases[entry.Hostname] = append(leases[entry.Hostname], entry.Address)
+	}
+	return leases, nil
 }
 
 // FirewallAvailable checks if firewalld is available and running
@@ -0,0 +1,371 @@
+package network
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+const dualStackContainerList = `[{"name":"coi-abc-1","state":{"network":{"eth0":{"addresses":[
+  {"family":"inet","address":"10.128.178.42","scope":"global"},
+  {"family":"inet6","address":"fe80::216:3eff:fe12:3456","scope":"link"},
+  {"family":"inet6","address":"fd42:4242:4242:1010:216:3eff:fe12:3456","scope":"global"}
+]}}}}]`
+
+func TestParseContainerAddress(t *testing.T) {
+	tests := []struct {
+		family   string
+		expected string
+	}{
+		{"inet", "10.128.178.42"},
+		{"inet6", "fd42:4242:4242:1010:216:3eff:fe12:3456"}, // link-local skipped
+	}
+
+	for _, tt := range tests {
+		got, err := parseContainerAddress(dualStackContainerList, "coi-abc-1", tt.family)
+		if err != nil {
+			t.Errorf("parseContainerAddress(%s) unexpected error: %v", tt.family, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("parseContainerAddress(%s): expected '%s', got '%s'", tt.family, tt.expected, got)
+		}
+	}
+
+	if got, _ := parseContainerAddress(dualStackContainerList, "other", "inet"); got != "" {
+		t.Errorf("Expected no address for unknown container, got '%s'", got)
+	}
+	if _, err := parseContainerAddress("not json", "coi-abc-1", "inet"); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestRuleFamily(t *testing.T) {
+	if got := ruleFamily("10.0.0.5"); got != "ipv4" {
+		t.Errorf("Expected ipv4, got %s", got)
+	}
+	if got := ruleFamily("fd42::5"); got != "ipv6" {
+		t.Errorf("Expected ipv6, got %s", got)
+	}
+}
+
+func TestParseFirewallRule(t *testing.T) {
+	tests := []struct {
+		line string
+		want FirewallRule
+		ok   bool
+	}{
+		{
+			"ipv4 filter FORWARD 10 -s 10.47.62.50 -d 10.0.0.0/8 -j REJECT",
+			FirewallRule{Family: "ipv4", Priority: 10, Source: "10.47.62.50", Destination: "10.0.0.0/8", Action: "REJECT"},
+			true,
+		},
+		{
+			"ipv4 filter FORWARD 0 -s 10.47.62.50 -j ACCEPT",
+			FirewallRule{Family: "ipv4", Priority: 0, Source: "10.47.62.50", Action: "ACCEPT"},
+			true,
+		},
+		{"ipv4 filter FORWARD -1 -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT", FirewallRule{}, false},
+		{"ipv4 nat POSTROUTING 0 -s 10.47.62.50 -j MASQUERADE", FirewallRule{}, false},
+		{"ipv4 filter FORWARD 10 -s", FirewallRule{}, false},
+		{"", FirewallRule{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseFirewallRule(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseFirewallRule(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+		if ok && got.String() != tt.line {
+			t.Errorf("String() = %q, want %q", got.String(), tt.line)
+		}
+	}
+}
+
+func TestAllowlistRules(t *testing.T) {
+	f := NewFirewallManager("10.0.0.5", "10.0.0.1")
+	cfg := &config.NetworkConfig{Mode: config.NetworkModeAllowlist}
+
+	rules := f.AllowlistRules(cfg, []string{"2.2.2.2", "1.1.1.1"})
+	var lines []string
+	for _, rule := range rules {
+		lines = append(lines, rule.String())
+	}
+
+	expected := []string{
+		"ipv4 filter FORWARD 0 -s 10.0.0.5 -d 10.0.0.1/32 -j ACCEPT",
+		"ipv4 filter FORWARD 1 -s 10.0.0.5 -d 1.1.1.1/32 -j ACCEPT",
+		"ipv4 filter FORWARD 1 -s 10.0.0.5 -d 2.2.2.2/32 -j ACCEPT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 10.0.0.0/8 -j REJECT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 172.16.0.0/12 -j REJECT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 192.168.0.0/16 -j REJECT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 169.254.0.0/16 -j REJECT",
+		"ipv4 filter FORWARD 99 -s 10.0.0.5 -d 0.0.0.0/0 -j REJECT",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("AllowlistRules:\n got %v\nwant %v", lines, expected)
+	}
+}
+
+func TestAllowlistRulesIPv6(t *testing.T) {
+	f := NewFirewallManager("10.0.0.5", "10.0.0.1")
+	f.SetIPv6("fd42::5", "fd42::1")
+	cfg := &config.NetworkConfig{Mode: config.NetworkModeAllowlist}
+
+	var lines []string
+	for _, rule := range f.AllowlistRules(cfg, []string{"1.1.1.1"}) {
+		if rule.Family == "ipv6" {
+			lines = append(lines, rule.String())
+		}
+	}
+
+	// Allowed domains resolve to IPv4 only, so IPv6 may only reach the gateway
+	expected := []string{
+		"ipv6 filter FORWARD 0 -s fd42::5 -d fd42::1/128 -j ACCEPT",
+		"ipv6 filter FORWARD 10 -s fd42::5 -d fc00::/7 -j REJECT",
+		"ipv6 filter FORWARD 10 -s fd42::5 -d fe80::/10 -j REJECT",
+		"ipv6 filter FORWARD 10 -s fd42::5 -d fd00:ec2::254/128 -j REJECT",
+		"ipv6 filter FORWARD 99 -s fd42::5 -d ::/0 -j REJECT",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("AllowlistRules:\n got %v\nwant %v", lines, expected)
+	}
+}
+
+func TestRestrictedRulesIPv6(t *testing.T) {
+	f := NewFirewallManager("10.0.0.5", "10.0.0.1")
+	f.SetIPv6("fd42::5", "fd42::1")
+
+	tests := []struct {
+		name     string
+		cfg      *config.NetworkConfig
+		expected []string
+	}{
+		{
+			name: "block private networks",
+			cfg:  &config.NetworkConfig{Mode: config.NetworkModeRestricted, BlockPrivateNetworks: true, BlockMetadataEndpoint: true},
+			expected: []string{
+				"ipv6 filter FORWARD 0 -s fd42::5 -d fd42::1/128 -j ACCEPT",
+				"ipv6 filter FORWARD 10 -s fd42::5 -d fc00::/7 -j REJECT",
+				"ipv6 filter FORWARD 10 -s fd42::5 -d fe80::/10 -j REJECT",
+				"ipv6 filter FORWARD 10 -s fd42::5 -d fd00:ec2::254/128 -j REJECT",
+				"ipv6 filter FORWARD 50 -s fd42::5 -d ::/0 -j ACCEPT",
+			},
+		},
+		{
+			name: "local network access",
+			cfg:  &config.NetworkConfig{Mode: config.NetworkModeRestricted, AllowLocalNetworkAccess: true},
+			expected: []string{
+				"ipv6 filter FORWARD 0 -s fd42::5 -d fd42::1/128 -j ACCEPT",
+				"ipv6 filter FORWARD 1 -s fd42::5 -d fc00::/7 -j ACCEPT",
+				"ipv6 filter FORWARD 50 -s fd42::5 -d ::/0 -j ACCEPT",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			for _, rule := range f.RestrictedRules(tt.cfg, nil) {
+				if rule.Family == "ipv6" {
+					lines = append(lines, rule.String())
+				}
+			}
+			if !slices.Equal(lines, tt.expected) {
+				t.Errorf("RestrictedRules:\n got %v\nwant %v", lines, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRestrictedRulesDeniedIPs(t *testing.T) {
+	f := NewFirewallManager("10.0.0.5", "10.0.0.1")
+	cfg := &config.NetworkConfig{Mode: config.NetworkModeRestricted, BlockPrivateNetworks: true, BlockMetadataEndpoint: true}
+
+	rules := f.RestrictedRules(cfg, []string{"2.2.2.2", "1.1.1.1"})
+	var lines []string
+	for _, rule := range rules {
+		lines = append(lines, rule.String())
+	}
+
+	expected := []string{
+		"ipv4 filter FORWARD 0 -s 10.0.0.5 -d 10.0.0.1/32 -j ACCEPT",
+		"ipv4 filter FORWARD 5 -s 10.0.0.5 -d 1.1.1.1/32 -j REJECT",
+		"ipv4 filter FORWARD 5 -s 10.0.0.5 -d 2.2.2.2/32 -j REJECT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 10.0.0.0/8 -j REJECT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 172.16.0.0/12 -j REJECT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 192.168.0.0/16 -j REJECT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.5 -d 169.254.0.0/16 -j REJECT",
+		"ipv4 filter FORWARD 50 -s 10.0.0.5 -d 0.0.0.0/0 -j ACCEPT",
+	}
+	if !slices.Equal(lines, expected) {
+		t.Errorf("RestrictedRules:\n got %v\nwant %v", lines, expected)
+	}
+}
+
+func TestDeniedIPsPrecedeCatchAllAllow(t *testing.T) {
+	f := NewFirewallManager("10.0.0.5", "10.0.0.1")
+	denied := []string{"3.3.3.3", "4.4.4.0/24"}
+
+	tests := []struct {
+		name  string
+		rules []FirewallRule
+	}{
+		{"open", f.OpenRules(denied)},
+		{"restricted", f.RestrictedRules(&config.NetworkConfig{Mode: config.NetworkModeRestricted}, denied)},
+		{"restricted with local network access", f.RestrictedRules(&config.NetworkConfig{Mode: config.NetworkModeRestricted, AllowLocalNetworkAccess: true}, denied)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowIndex := slices.IndexFunc(tt.rules, func(r FirewallRule) bool {
+				return r.Destination == "0.0.0.0/0" && r.Action == "ACCEPT"
+			})
+			if allowIndex < 0 {
+				t.Fatalf("No catch-all ACCEPT rule in %v", tt.rules)
+			}
+			allow := tt.rules[allowIndex]
+
+			var rejected []string
+			for i, rule := range tt.rules {
+				if rule.Action != "REJECT" || rule.Priority != 5 {
+					continue
+				}
+				rejected = append(rejected, rule.Destination)
+				if i > allowIndex || rule.Priority >= allow.Priority {
+					t.Errorf("Deny rule %s must precede the catch-all allow %s", rule, allow)
+				}
+			}
+			if want := []string{"3.3.3.3/32", "4.4.4.0/24"}; !slices.Equal(rejected, want) {
+				t.Errorf("Expected denied destinations %v, got %v", want, rejected)
+			}
+		})
+	}
+}
+
+func TestDenyIPRulesIPv6(t *testing.T) {
+	denied := []string{"2606:4700::1111", "1.1.1.1", "2001:db8::/32"}
+
+	// Without an IPv6 address the container cannot reach IPv6 destinations
+	f := NewFirewallManager("10.0.0.5", "")
+	var lines []string
+	for _, rule := range f.DenyIPRules(denied) {
+		lines = append(lines, rule.String())
+	}
+	if want := []string{"ipv4 filter FORWARD 5 -s 10.0.0.5 -d 1.1.1.1/32 -j REJECT"}; !slices.Equal(lines, want) {
+		t.Errorf("Expected %v, got %v", want, lines)
+	}
+
+	f.SetIPv6("fd42::5", "")
+	lines = nil
+	for _, rule := range f.DenyIPRules(denied) {
+		lines = append(lines, rule.String())
+	}
+	want := []string{
+		"ipv4 filter FORWARD 5 -s 10.0.0.5 -d 1.1.1.1/32 -j REJECT",
+		"ipv6 filter FORWARD 5 -s fd42::5 -d 2001:db8::/32 -j REJECT",
+		"ipv6 filter FORWARD 5 -s fd42::5 -d 2606:4700::1111/128 -j REJECT",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("Expected %v, got %v", want, lines)
+	}
+}
+
+func TestTransientFirewallError(t *testing.T) {
+	tests := []struct {
+		output    string
+		transient bool
+	}{
+		{"Error: COMMAND_FAILED: 'python-nftables' failed", true},
+		{"Error: NOT_RUNNING", true},
+		{"Error: INVALID_RULE: -s 10.0.0.5 -d bogus -j ACCEPT", false},
+		{"Error: INVALID_IPV: ipv5", false},
+		{"sudo: a password is required", false},
+	}
+
+	for _, tt := range tests {
+		if got := transientFirewallError(tt.output); got != tt.transient {
+			t.Errorf("transientFirewallError(%q) = %v, want %v", tt.output, got, tt.transient)
+		}
+	}
+}
+
+func TestMissingRules(t *testing.T) {
+	f := NewFirewallManager("10.0.0.5", "10.0.0.1")
+	intended := f.RestrictedRules(&config.NetworkConfig{Mode: config.NetworkModeRestricted}, nil)
+
+	if missing := missingRules(intended, intended); len(missing) != 0 {
+		t.Errorf("Expected nothing missing, got %v", missing)
+	}
+
+	// A rule at another priority does not count as applied
+	live := []FirewallRule{intended[0], f.rule(40, "0.0.0.0/0", "ACCEPT")}
+	want := []string{"ipv4 filter FORWARD 50 -s 10.0.0.5 -d 0.0.0.0/0 -j ACCEPT"}
+	if missing := missingRules(intended, live); !slices.Equal(missing, want) {
+		t.Errorf("Expected %v missing, got %v", want, missing)
+	}
+}
+
+func TestRulesFromSources(t *testing.T) {
+	lines := []string{
+		"ipv4 filter FORWARD -1 -m conntrack --ctstate RELATED,ESTABLISHED -j ACCEPT",
+		"ipv4 filter FORWARD 0 -s 10.0.0.1 -d 10.0.0.254/32 -j ACCEPT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.12 -d 10.0.0.0/8 -j REJECT",
+		"ipv6 filter FORWARD 0 -s fd42::5 -d fd42::1/128 -j ACCEPT",
+		"ipv4 filter FORWARD 10 -s 10.0.0.7 -d 10.0.0.0/8 -j REJECT",
+	}
+
+	got := rulesFromSources(lines, []string{"10.0.0.1", "fd42::5"})
+	want := []string{lines[1], lines[3]} // Not the rule of 10.0.0.12
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestContainerAddresses(t *testing.T) {
+	list := `[
+  {"name":"coi-abc-1","state":{"network":{"eth0":{"addresses":[
+    {"family":"inet","address":"10.128.178.42","scope":"global"},
+    {"family":"inet6","address":"fd42::42","scope":"global"}]}}}},
+  {"name":"coi-abc-2","status":"Stopped","state":null},
+  {"name":"coi-abc-3","status":"Stopped","state":null}
+]`
+	leases := `[
+  {"hostname":"incusbr0.gw","address":"10.128.178.1","type":"GATEWAY"},
+  {"hostname":"coi-abc-1","address":"10.128.178.99","type":"DYNAMIC"},
+  {"hostname":"coi-abc-2","address":"10.128.178.43","type":"DYNAMIC"},
+  {"hostname":"coi-abc-2","address":"fd42::43","type":"DYNAMIC"},
+  {"hostname":"coi-abc-2","address":"fe80::43","type":"DYNAMIC"}
+]`
+
+	got, err := containerAddresses([]string{"coi-abc-1", "coi-abc-2", "coi-abc-3"}, list, leases)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := map[string][]string{
+		"coi-abc-1": {"10.128.178.42", "fd42::42"}, // Running: its state wins over a stale lease
+		"coi-abc-2": {"10.128.178.43", "fd42::43"}, // Stopped: from its leases, link-local skipped
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	for name, addresses := range want {
+		if !slices.Equal(got[name], addresses) {
+			t.Errorf("%s: expected %v, got %v", name, addresses, got[name])
+		}
+	}
+
+	// No leases (e.g. the network could not be determined): running ones only
+	got, err = containerAddresses([]string{"coi-abc-1", "coi-abc-2"}, list, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(got) != 1 || len(got["coi-abc-1"]) != 2 {
+		t.Errorf("Expected only the running container's addresses, got %v", got)
+	}
+
+	if _, err := containerAddresses([]string{"coi-abc-1"}, list, "not json"); err == nil {
+		t.Error("Expected error for invalid leases output")
+	}
+}
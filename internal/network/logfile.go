@@ -0,0 +1,184 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+// LogFile is an append-only log file that rotates itself to path.1, path.2, ...
+// once it reaches maxBytes, keeping at most maxFiles rotated files
+type LogFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// OpenLogFile opens (or creates) the log at path. A maxBytes of 0 disables
+// rotation.
+func OpenLogFile(path string, maxBytes int64, maxFiles int) (*LogFile, error) {
+	l := &LogFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// OpenNetworkLog opens the network log configured in [network.logging]
+func OpenNetworkLog(cfg config.NetworkLoggingConfig) (*LogFile, error) {
+	return OpenLogFile(cfg.Path, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxFiles)
+}
+
+// TeeLog copies the network package's log output (firewall rules, domain
+// resolution, IP refreshes) from w to the log configured in [network.logging],
+// when enabled. The log is opened on the first message, so commands that log
+// nothing do not create it, and stays open for the life of the process.
+func TeeLog(cfg config.NetworkLoggingConfig, w io.Writer) {
+	if !cfg.Enabled || cfg.Path == "" {
+		return
+	}
+	log.SetOutput(io.MultiWriter(w, &lazyLog{cfg: cfg, warn: w}))
+}
+
+// lazyLog opens the network log on its first write. Failing to open it is
+// reported to warn once; later writes are dropped.
+type lazyLog struct {
+	once sync.Once
+	cfg  config.NetworkLoggingConfig
+	warn io.Writer
+	file *LogFile
+}
+
+func (l *lazyLog) Write(p []byte) (int, error) {
+	l.once.Do(func() {
+		file, err := OpenNetworkLog(l.cfg)
+		if err != nil {
+			fmt.Fprintf(l.warn, "Warning: %v\n", err)
+			return
+		}
+		l.file = file
+	})
+	if l.file == nil {
+		return len(p), nil // MultiWriter stops at the first error; keep w working
+	}
+	return l.file.Write(p)
+}
+
+func (l *LogFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log %s: %w", l.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log %s: %w", l.path, err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Write appends p, rotating first if it would take the log past maxBytes
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxBytes > 0 && l.size > 0 && l.size+int64(len(p)) > l.maxBytes {
+		if err := l.file.Close(); err != nil {
+			return 0, fmt.Errorf("failed to close log before rotation: %w", err)
+		}
+		if err := RotateLog(l.path, l.maxFiles); err != nil {
+			return 0, err
+		}
+		if err := l.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying file
+func (l *LogFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// RotateLog shifts path to path.1 (path.1 to path.2, ...), dropping rotated
+// files beyond maxFiles. With maxFiles 0 the log is simply removed. A missing
+// log is not an error.
+func RotateLog(path string, maxFiles int) error {
+	// Drop files that would fall off the end, including leftovers from a larger max_files
+	for i := max(maxFiles, 1); ; i++ {
+		err := os.Remove(rotatedLogPath(path, i))
+		if errors.Is(err, os.ErrNotExist) {
+			if i > maxFiles {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove old log: %w", err)
+		}
+	}
+
+	if maxFiles == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove log: %w", err)
+		}
+		return nil
+	}
+
+	for i := maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(rotatedLogPath(path, i), rotatedLogPath(path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate log: %w", err)
+		}
+	}
+	if err := os.Rename(path, rotatedLogPath(path, 1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to rotate log: %w", err)
+	}
+	return nil
+}
+
+// ClearLog removes the log at path and all its rotated files, returning the
+// paths it removed
+func ClearLog(path string) ([]string, error) {
+	var removed []string
+	for i := 0; ; i++ {
+		target := path
+		if i > 0 {
+			target = rotatedLogPath(path, i)
+		}
+		err := os.Remove(target)
+		if errors.Is(err, os.ErrNotExist) {
+			if i == 0 {
+				continue
+			}
+			return removed, nil
+		}
+		if err != nil {
+			return removed, fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+		removed = append(removed, target)
+	}
+}
+
+func rotatedLogPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
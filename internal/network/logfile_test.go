@@ -0,0 +1,165 @@
+package network
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestLogFileRotatesAtMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "network.log")
+
+	log, err := OpenLogFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenLogFile() unexpected error: %v", err)
+	}
+	defer log.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := log.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+	}
+
+	if got := readLog(t, path); got != "fourth\n" {
+		t.Errorf("Expected current log 'fourth', got %q", got)
+	}
+	if got := readLog(t, path+".1"); got != "third\n" {
+		t.Errorf("Expected .1 to hold 'third', got %q", got)
+	}
+	if got := readLog(t, path+".2"); got != "second\n" {
+		t.Errorf("Expected .2 to hold 'second', got %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected no .3 with max files 2, got err %v", err)
+	}
+}
+
+func TestLogFileAppendsToExistingLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "network.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := OpenLogFile(path, 100, 1)
+	if err != nil {
+		t.Fatalf("OpenLogFile() unexpected error: %v", err)
+	}
+	if _, err := log.Write([]byte("new\n")); err != nil {
+		t.Fatalf("Write() unexpected error: %v", err)
+	}
+	log.Close()
+
+	if got := readLog(t, path); got != "old\nnew\n" {
+		t.Errorf("Expected appended log, got %q", got)
+	}
+}
+
+func TestRotateLog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "network.log")
+	for name, content := range map[string]string{
+		"network.log":   "current",
+		"network.log.1": "one",
+		"network.log.2": "two",
+		"network.log.3": "three", // Left over from a larger max_files
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := RotateLog(path, 2); err != nil {
+		t.Fatalf("RotateLog() unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected current log to be rotated away, got err %v", err)
+	}
+	if got := readLog(t, path+".1"); got != "current" {
+		t.Errorf("Expected .1 to hold 'current', got %q", got)
+	}
+	if got := readLog(t, path+".2"); got != "one" {
+		t.Errorf("Expected .2 to hold 'one', got %q", got)
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected .3 to be removed, got err %v", err)
+	}
+}
+
+func TestRotateLogMissingFile(t *testing.T) {
+	if err := RotateLog(filepath.Join(t.TempDir(), "network.log"), 3); err != nil {
+		t.Errorf("RotateLog() on missing log unexpected error: %v", err)
+	}
+}
+
+func TestClearLog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "network.log")
+	for _, name := range []string{"network.log", "network.log.1", "network.log.2", "other.log"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := ClearLog(path)
+	if err != nil {
+		t.Fatalf("ClearLog() unexpected error: %v", err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("Expected 3 removed files, got %v", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.log")); err != nil {
+		t.Errorf("Expected unrelated log to be kept, got err %v", err)
+	}
+}
+
+func TestTeeLog(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	path := filepath.Join(t.TempDir(), "logs", "network.log")
+	var stderr bytes.Buffer
+
+	TeeLog(config.NetworkLoggingConfig{Enabled: true, Path: path, MaxSizeMB: 10, MaxFiles: 3}, &stderr)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected the log to be created on the first message, got %v", err)
+	}
+
+	log.Printf("Firewall rules applied for container coi-test-1")
+	if !strings.Contains(stderr.String(), "coi-test-1") {
+		t.Errorf("Expected the message on the original output, got %q", stderr.String())
+	}
+	if content := readLog(t, path); !strings.Contains(content, "Firewall rules applied for container coi-test-1") {
+		t.Errorf("Expected the message in the network log, got %q", content)
+	}
+}
+
+func TestTeeLogDisabled(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	path := filepath.Join(t.TempDir(), "network.log")
+	var stderr bytes.Buffer
+	log.SetOutput(&stderr)
+
+	TeeLog(config.NetworkLoggingConfig{Enabled: false, Path: path}, &bytes.Buffer{})
+	log.Printf("not logged to a file")
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no network log when logging is disabled, got %v", err)
+	}
+	if !strings.Contains(stderr.String(), "not logged to a file") {
+		t.Errorf("Expected the log output to be left alone, got %q", stderr.String())
+	}
+}
@@ -34,8 +34,9 @@ type Manager struct {
 	cacheManager  *CacheManager
 	containerName string
 	containerIP   string
+	gatewayIP     string
 
-	// Refresher lifecycle (for allowlist mode)
+	// Refresher lifecycle (for allowlist mode and denied domains)
 	refreshCtx    context.Context
 	refreshCancel context.CancelFunc
 }
@@ -60,6 +61,9 @@ func (m *Manager) SetupForContainer(ctx context.Context, containerName string) e
 	// Handle different network modes
 	switch m.config.Mode {
 	case config.NetworkModeOpen:
+		if len(m.config.DeniedDomains) > 0 {
+			return m.setupOpen(ctx, containerName)
+		}
 		log.Println("Network mode: open (no restrictions)")
 		// Still need to add ACCEPT rules if firewall FORWARD policy is DROP
 		if FirewallAvailable() {
@@ -88,6 +92,45 @@ func (m *Manager) SetupForContainer(ctx context.Context, containerName string) e
 	}
 }
 
+// setupOpen configures open mode with denied domains using firewalld
+func (m *Manager) setupOpen(ctx context.Context, containerName string) error {
+	log.Println("Network mode: open (blocking denied domains)")
+
+	// Denied domains can only be enforced by the firewall
+	if !FirewallAvailable() {
+		return fmt.Errorf("%s", errFirewallNotAvailable)
+	}
+
+	containerIP, err := GetContainerIP(containerName)
+	if err != nil {
+		return fmt.Errorf("failed to get container IP: %w", err)
+	}
+	m.containerIP = containerIP
+	log.Printf("Container IP: %s", containerIP)
+
+	m.firewall = NewFirewallManager(containerIP, "")
+
+	// Denied domains are blocked over IPv6 too on dual-stack networks
+	if gateways, err := getContainerGateways(containerName); err != nil {
+		log.Printf("Warning: Could not auto-detect gateway IP: %v", err)
+	} else if err := m.enableIPv6Gateway(containerName, gateways.IPv6); err != nil {
+		log.Printf("Warning: %v (denied domains are only blocked over IPv4)", err)
+	}
+	m.loadResolver(containerName)
+
+	if err := m.firewall.ApplyOpen(m.resolveDeniedIPs()); err != nil {
+		return fmt.Errorf("failed to apply firewall rules: %w", err)
+	}
+
+	log.Printf("Firewall rules applied for container %s", containerName)
+
+	m.saveConfig(containerName)
+
+	m.startRefresher(ctx)
+
+	return nil
+}
+
 // setupRestricted configures restricted mode using firewalld
 func (m *Manager) setupRestricted(ctx context.Context, containerName string) error {
 	log.Println("Network mode: restricted (blocking local/internal networks)")
@@ -105,24 +148,46 @@ func (m *Manager) setupRestricted(ctx context.Context, containerName string) err
 	m.containerIP = containerIP
 	log.Printf("Container IP: %s", containerIP)
 
-	// Get gateway IP
-	gatewayIP, err := getContainerGatewayIP(containerName)
+	// Get gateway IPs (IPv6 only on dual-stack networks)
+	gateways, err := getContainerGateways(containerName)
 	if err != nil {
 		log.Printf("Warning: Could not auto-detect gateway IP: %v", err)
-	} else {
-		log.Printf("Gateway IP: %s", gatewayIP)
+	} else if gateways.IPv4 != "" {
+		log.Printf("Gateway IP: %s", gateways.IPv4)
 	}
 
+	m.gatewayIP = gateways.IPv4
+
 	// Create firewall manager
-	m.firewall = NewFirewallManager(containerIP, gatewayIP)
+	m.firewall = NewFirewallManager(containerIP, gateways.IPv4)
+	if err := m.enableIPv6Gateway(containerName, gateways.IPv6); err != nil {
+		// Without the container's IPv6 address its IPv6 traffic would go unfiltered
+		return fmt.Errorf("cannot filter IPv6 traffic: %w", err)
+	}
+
+	// Resolve denied domains, if any
+	var deniedIPs []string
+	if len(m.config.DeniedDomains) > 0 {
+		m.loadResolver(containerName)
+		deniedIPs = m.resolveDeniedIPs()
+	}
 
 	// Apply restricted mode rules
-	if err := m.firewall.ApplyRestricted(m.config); err != nil {
+	if err := m.firewall.ApplyRestricted(m.config, deniedIPs); err != nil {
 		return fmt.Errorf("failed to apply firewall rules: %w", err)
 	}
 
+	// Corporate proxies usually live on a private network, which restricted mode blocks
+	if m.config.Proxy != "" {
+		if err := m.allowProxy(); err != nil {
+			return err
+		}
+	}
+
 	log.Printf("Firewall rules applied for container %s", containerName)
 
+	m.saveConfig(containerName)
+
 	// Log what is blocked
 	if m.config.BlockPrivateNetworks {
 		log.Println("  Blocking private networks (RFC1918)")
@@ -131,6 +196,11 @@ func (m *Manager) setupRestricted(ctx context.Context, containerName string) err
 		log.Println("  Blocking cloud metadata endpoints")
 	}
 
+	// Keep denied domain IPs current
+	if len(m.config.DeniedDomains) > 0 {
+		m.startRefresher(ctx)
+	}
+
 	return nil
 }
 
@@ -143,8 +213,9 @@ func (m *Manager) setupAllowlist(ctx context.Context, containerName string) erro
 		return fmt.Errorf("%s", errFirewallNotAvailable)
 	}
 
-	// Validate configuration
-	if len(m.config.AllowedDomains) == 0 {
+	// Validate configuration (a proxy alone is enough - all traffic goes through it)
+	domains := m.allowedDomains()
+	if len(domains) == 0 {
 		return fmt.Errorf("allowlist mode requires at least one allowed domain")
 	}
 
@@ -156,33 +227,29 @@ func (m *Manager) setupAllowlist(ctx context.Context, containerName string) erro
 	m.containerIP = containerIP
 	log.Printf("Container IP: %s", containerIP)
 
-	// Get gateway IP
-	gatewayIP, err := getContainerGatewayIP(containerName)
+	// Get gateway IPs (IPv6 only on dual-stack networks)
+	gateways, err := getContainerGateways(containerName)
 	if err != nil {
 		log.Printf("Warning: Could not auto-detect gateway IP: %v", err)
-	} else {
-		log.Printf("Gateway IP: %s", gatewayIP)
+	} else if gateways.IPv4 != "" {
+		log.Printf("Gateway IP: %s", gateways.IPv4)
 	}
 
-	// Create firewall manager
-	m.firewall = NewFirewallManager(containerIP, gatewayIP)
+	m.gatewayIP = gateways.IPv4
 
-	// Load IP cache
-	cache, err := m.cacheManager.Load(containerName)
-	if err != nil {
-		log.Printf("Warning: Failed to load cache: %v", err)
-		cache = &IPCache{
-			Domains:    make(map[string][]string),
-			LastUpdate: time.Time{},
-		}
+	// Create firewall manager
+	m.firewall = NewFirewallManager(containerIP, gateways.IPv4)
+	if err := m.enableIPv6Gateway(containerName, gateways.IPv6); err != nil {
+		// Without the container's IPv6 address its IPv6 traffic would go unfiltered
+		return fmt.Errorf("cannot filter IPv6 traffic: %w", err)
 	}
 
-	// Initialize resolver with cache
-	m.resolver = NewResolver(cache)
+	// Initialize resolver with the IP cache
+	m.loadResolver(containerName)
 
 	// Resolve domains
-	log.Printf("Resolving %d allowed domains...", len(m.config.AllowedDomains))
-	domainIPs, err := m.resolver.ResolveAll(m.config.AllowedDomains)
+	log.Printf("Resolving %d allowed domains...", len(domains))
+	domainIPs, err := m.resolver.ResolveAll(domains)
 	if err != nil && len(domainIPs) == 0 {
 		return fmt.Errorf("failed to resolve any allowed domains: %w", err)
 	}
@@ -210,6 +277,8 @@ func (m *Manager) setupAllowlist(ctx context.Context, containerName string) erro
 
 	log.Printf("Firewall rules applied for container %s", containerName)
 	log.Println("  Allowing only specified domains")
+
+	m.saveConfig(containerName)
 	log.Println("  Blocking all RFC1918 private networks")
 	log.Println("  Blocking cloud metadata endpoints")
 
@@ -219,6 +288,140 @@ func (m *Manager) setupAllowlist(ctx context.Context, containerName string) erro
 	return nil
 }
 
+// saveConfig persists the network config of containerName. The manager that
+// set up the rules is gone once 'coi shell' exits or detaches, so 'coi network
+// refresh' and 'coi network policy diff' rebuild one from this (see
+// NewManagerForContainer). Failing to save only disables those commands.
+func (m *Manager) saveConfig(containerName string) {
+	if err := m.cacheManager.SaveConfig(containerName, m.config); err != nil {
+		log.Printf("Warning: Failed to save network config: %v", err)
+	}
+}
+
+// allowedDomains returns the configured allowed domains plus the proxy host, if any.
+// With a proxy, all traffic goes through the proxy IP, so domain allowlisting is moot.
+// Wildcard entries are replaced by their configured subdomains (see ExpandWildcards).
+func (m *Manager) allowedDomains() []string {
+	domains, skipped := ExpandWildcards(m.config.AllowedDomains, m.config.WildcardSubdomains)
+	for _, pattern := range skipped {
+		log.Printf("Warning: %s cannot be resolved to IPs - list its subdomains under [network.wildcard_subdomains] (skipping)", pattern)
+	}
+	if m.config.Proxy == "" {
+		return domains
+	}
+
+	host, err := ProxyHost(m.config.Proxy)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return domains
+	}
+	for _, domain := range domains {
+		if domain == host {
+			return domains
+		}
+	}
+	return append(domains, host)
+}
+
+// deniedDomains returns the configured denied domains, with wildcard entries
+// replaced by their configured subdomains like allowedDomains
+func (m *Manager) deniedDomains() []string {
+	domains, skipped := ExpandWildcards(m.config.DeniedDomains, m.config.WildcardSubdomains)
+	for _, pattern := range skipped {
+		log.Printf("Warning: %s cannot be resolved to IPs - list its subdomains under [network.wildcard_subdomains] (skipping)", pattern)
+	}
+	return domains
+}
+
+// refreshDomains returns the domains whose IPs the refresher keeps current
+func (m *Manager) refreshDomains() []string {
+	if m.config.Mode == config.NetworkModeAllowlist {
+		return m.allowedDomains()
+	}
+	return m.deniedDomains()
+}
+
+// resolveDeniedIPs resolves the denied domains and caches the result. Domains
+// that fail to resolve are skipped: there is nothing to block for them yet.
+func (m *Manager) resolveDeniedIPs() []string {
+	domains := m.deniedDomains()
+	log.Printf("Resolving %d denied domains...", len(domains))
+	domainIPs, err := m.resolver.ResolveAll(domains)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	log.Printf("  Blocking %d IPs of denied domains", countIPs(domainIPs))
+
+	m.resolver.UpdateCache(domainIPs)
+	if err := m.cacheManager.Save(m.containerName, m.resolver.GetCache()); err != nil {
+		log.Printf("Warning: Failed to save cache: %v", err)
+	}
+	return collectUniqueIPs(domainIPs)
+}
+
+// applyResolvedIPs applies the rules for the current mode with freshly resolved
+// IPs: the allowed IPs in allowlist mode, the denied IPs otherwise
+func (m *Manager) applyResolvedIPs(ips []string) error {
+	switch m.config.Mode {
+	case config.NetworkModeAllowlist:
+		return m.firewall.ApplyAllowlist(m.config, ips)
+	case config.NetworkModeRestricted:
+		if err := m.firewall.ApplyRestricted(m.config, ips); err != nil {
+			return err
+		}
+		if m.config.Proxy != "" {
+			return m.allowProxy()
+		}
+		return nil
+	default:
+		return m.firewall.ApplyOpen(ips)
+	}
+}
+
+// allowProxy adds firewall rules allowing the container to reach the proxy host
+func (m *Manager) allowProxy() error {
+	host, ips, err := m.resolveProxy()
+	if err != nil {
+		return err
+	}
+
+	if err := m.firewall.AllowIPs(ips); err != nil {
+		return fmt.Errorf("failed to allow proxy: %w", err)
+	}
+	log.Printf("  Allowing proxy %s (%s)", host, strings.Join(ips, ", "))
+	return nil
+}
+
+// resolveProxy resolves the configured proxy host to its IPs
+func (m *Manager) resolveProxy() (string, []string, error) {
+	host, err := ProxyHost(m.config.Proxy)
+	if err != nil {
+		return "", nil, err
+	}
+
+	resolver := NewResolver(&IPCache{Domains: make(map[string][]string)})
+	ips, err := resolver.ResolveDomain(host)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve proxy host: %w", err)
+	}
+	return host, ips, nil
+}
+
+// ProxyEnv returns the proxy environment variables for the container,
+// or nil if no proxy is configured
+func (m *Manager) ProxyEnv() map[string]string {
+	if m.config.Proxy == "" {
+		return nil
+	}
+
+	gatewayIP := m.gatewayIP
+	if gatewayIP == "" && m.containerName != "" {
+		// Open mode doesn't detect the gateway during setup
+		gatewayIP, _ = getContainerGatewayIP(m.containerName)
+	}
+	return ProxyEnv(m.config.Proxy, gatewayIP)
+}
+
 // collectUniqueIPs extracts all unique IPs from domain resolution map
 func collectUniqueIPs(domainIPs map[string][]string) []string {
 	uniqueIPs := make(map[string]bool)
@@ -245,20 +448,29 @@ func (m *Manager) startRefresher(ctx context.Context) {
 	m.refreshCtx, m.refreshCancel = context.WithCancel(ctx)
 
 	interval := time.Duration(m.config.RefreshIntervalMinutes) * time.Minute
-	ticker := time.NewTicker(interval)
+	log.Printf("Starting IP refresh every %d minutes (sooner for domains with shorter DNS TTLs)", m.config.RefreshIntervalMinutes)
 
-	log.Printf("Starting IP refresh every %d minutes", m.config.RefreshIntervalMinutes)
+	// Short-TTL domains (e.g. CDNs) can change IPs well before the configured interval
+	next := func() time.Duration {
+		wait := NextRefreshInterval(interval, m.resolver.MinTTL())
+		if wait != interval {
+			log.Printf("IP refresh: next check in %s (shortest DNS TTL)", wait)
+		}
+		return wait
+	}
+	timer := time.NewTimer(next())
 
 	go func() {
-		defer ticker.Stop()
+		defer timer.Stop()
 
 		for {
 			select {
-			case <-ticker.C:
+			case <-timer.C:
 				log.Println("IP refresh: checking for updated IPs...")
-				if err := m.refreshAllowedIPs(); err != nil {
+				if _, err := m.refreshIPs(); err != nil {
 					log.Printf("Warning: IP refresh failed: %v", err)
 				}
+				timer.Reset(next())
 
 			case <-m.refreshCtx.Done():
 				log.Println("IP refresher stopped")
@@ -276,18 +488,24 @@ func (m *Manager) stopRefresher() {
 	}
 }
 
-// refreshAllowedIPs refreshes domain IPs and updates firewall rules if changed
-func (m *Manager) refreshAllowedIPs() error {
+// refreshIPs refreshes domain IPs and updates firewall rules if changed
+// Returns true if the firewall rules were updated
+func (m *Manager) refreshIPs() (bool, error) {
 	// Resolve all domains again
-	newIPs, err := m.resolver.ResolveAll(m.config.AllowedDomains)
+	newIPs, err := m.resolver.ResolveAll(m.refreshDomains())
 	if err != nil && len(newIPs) == 0 {
-		return fmt.Errorf("failed to resolve any domains")
+		return false, fmt.Errorf("failed to resolve any domains")
 	}
 
 	// Check if anything changed
 	if m.resolver.IPsUnchanged(newIPs) {
 		log.Println("IP refresh: no changes detected")
-		return nil
+		// Still record the fresh TTLs
+		m.resolver.UpdateCache(newIPs)
+		if err := m.cacheManager.Save(m.containerName, m.resolver.GetCache()); err != nil {
+			log.Printf("Warning: Failed to save cache: %v", err)
+		}
+		return false, nil
 	}
 
 	// Update firewall rules with new IPs
@@ -299,9 +517,8 @@ func (m *Manager) refreshAllowedIPs() error {
 		log.Printf("Warning: failed to remove old rules: %v", err)
 	}
 
-	allowedIPs := collectUniqueIPs(newIPs)
-	if err := m.firewall.ApplyAllowlist(m.config, allowedIPs); err != nil {
-		return fmt.Errorf("failed to update firewall rules: %w", err)
+	if err := m.applyResolvedIPs(collectUniqueIPs(newIPs)); err != nil {
+		return false, fmt.Errorf("failed to update firewall rules: %w", err)
 	}
 
 	// Update cache
@@ -311,9 +528,93 @@ func (m *Manager) refreshAllowedIPs() error {
 	}
 
 	log.Printf("IP refresh: successfully updated firewall rules")
+	return true, nil
+}
+
+// NewManagerForContainer reconstructs a network manager for an already running
+// container from the config persisted during setup (see saveConfig)
+func NewManagerForContainer(containerName string) (*Manager, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/tmp"
+	}
+
+	cacheManager := NewCacheManager(homeDir)
+	cfg, err := cacheManager.LoadConfig(containerName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no saved network state for container %s (open mode sessions without denied_domains have no firewall policy)", containerName)
+		}
+		return nil, err
+	}
+
+	return &Manager{
+		config:        cfg,
+		cacheManager:  cacheManager,
+		containerName: containerName,
+	}, nil
+}
+
+// Refresh forces an immediate re-resolution of the allowed (or denied) domains
+// and updates the firewall rules if any IPs changed. Returns true if rules were updated.
+func (m *Manager) Refresh() (bool, error) {
+	if m.config.Mode != config.NetworkModeAllowlist && len(m.config.DeniedDomains) == 0 {
+		return false, fmt.Errorf("network refresh only applies to allowlist mode or denied_domains (container uses %s)", m.config.Mode)
+	}
+
+	if !FirewallAvailable() {
+		return false, fmt.Errorf("%s", errFirewallNotAvailable)
+	}
+
+	if err := m.attach(); err != nil {
+		return false, err
+	}
+
+	return m.refreshIPs()
+}
+
+// attach detects the addresses of the running container and loads its IP
+// cache, for a manager rebuilt with NewManagerForContainer
+func (m *Manager) attach() error {
+	containerIP, err := GetContainerIP(m.containerName)
+	if err != nil {
+		return fmt.Errorf("failed to get container IP: %w", err)
+	}
+	m.containerIP = containerIP
+
+	gateways, err := getContainerGateways(m.containerName)
+	if err != nil {
+		log.Printf("Warning: Could not auto-detect gateway IP: %v", err)
+	}
+	m.gatewayIP = gateways.IPv4
+	m.firewall = NewFirewallManager(containerIP, gateways.IPv4)
+	if err := m.enableIPv6Gateway(m.containerName, gateways.IPv6); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	m.loadResolver(m.containerName)
 	return nil
 }
 
+// loadResolver initializes the resolver with the container's IP cache
+func (m *Manager) loadResolver(containerName string) {
+	cache, err := m.cacheManager.Load(containerName)
+	if err != nil {
+		log.Printf("Warning: Failed to load cache: %v", err)
+		cache = &IPCache{
+			Domains:    make(map[string][]string),
+			LastUpdate: time.Time{},
+		}
+	}
+	m.resolver = NewResolver(cache)
+
+	// Denied domains (open and restricted modes) must be blocked over IPv6
+	// as well; allowlist mode rejects all IPv6 traffic but the gateway
+	if m.config.Mode != config.NetworkModeAllowlist {
+		m.resolver.IncludeIPv6()
+	}
+}
+
 // countIPs counts total IPs across all domains
 func countIPs(domainIPs map[string][]string) int {
 	count := 0
@@ -325,11 +626,11 @@ func countIPs(domainIPs map[string][]string) int {
 
 // Teardown removes network isolation for a container
 func (m *Manager) Teardown(ctx context.Context, containerName string) error {
-	// Stop background refresher if running (for allowlist mode)
+	// Stop background refresher if running (for allowlist mode and denied domains)
 	m.stopRefresher()
 
-	// Nothing to clean up in open mode
-	if m.config.Mode == config.NetworkModeOpen {
+	// Nothing to clean up in open mode, unless domains are denied
+	if m.config.Mode == config.NetworkModeOpen && len(m.config.DeniedDomains) == 0 {
 		return nil
 	}
 
@@ -342,24 +643,108 @@ func (m *Manager) Teardown(ctx context.Context, containerName string) error {
 		}
 	}
 
+	// Remove persisted state used by 'coi network refresh'
+	if err := m.cacheManager.DeleteConfig(containerName); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
 	return nil
 }
 
+// RenameContainer moves a container's saved network state to its new name, so
+// 'coi network refresh' keeps working after 'coi container rename'
+func RenameContainer(oldName, newName string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/tmp"
+	}
+	return NewCacheManager(homeDir).Rename(oldName, newName)
+}
+
+// TeardownContainer removes a container's firewall rules and saved network
+// state without the Manager that set them up, e.g. from a detached process
+// after 'coi shell' has exited. Stopped containers are found by their leases.
+func TeardownContainer(containerName string) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		homeDir = "/tmp"
+	}
+	if err := NewCacheManager(homeDir).DeleteConfig(containerName); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	if !FirewallAvailable() {
+		return nil
+	}
+
+	addresses, err := ContainerAddresses([]string{containerName})
+	if err != nil {
+		return fmt.Errorf("failed to get container addresses: %w", err)
+	}
+	return RemoveRulesFrom(addresses[containerName])
+}
+
 // GetMode returns the current network mode
 func (m *Manager) GetMode() config.NetworkMode {
 	return m.config.Mode
 }
 
-// getContainerGatewayIP auto-detects the gateway IP for a container's network
+// networkGateways holds the gateway addresses of an Incus bridge network.
+// IPv6 is empty unless the network is dual-stack.
+type networkGateways struct {
+	IPv4 string
+	IPv6 string
+}
+
+// getContainerGatewayIP auto-detects the IPv4 gateway IP for a container's network
 func getContainerGatewayIP(containerName string) (string, error) {
-	// Get container's network configuration from default profile
+	gateways, err := getContainerGateways(containerName)
+	if err != nil {
+		return "", err
+	}
+	if gateways.IPv4 == "" {
+		return "", fmt.Errorf("could not find ipv4.address in container network")
+	}
+	return gateways.IPv4, nil
+}
+
+// getContainerGateways auto-detects the IPv4 and IPv6 gateways for a container's network
+func getContainerGateways(containerName string) (networkGateways, error) {
+	networkName, err := defaultNetworkName()
+	if err != nil {
+		return networkGateways{}, err
+	}
+
+	// Get network configuration
+	networkOutput, err := container.IncusOutput("network", "show", networkName)
+	if err != nil {
+		return networkGateways{}, fmt.Errorf("failed to get network info: %w", err)
+	}
+
+	gateways, err := parseNetworkGateways(networkOutput)
+	if err != nil {
+		return networkGateways{}, fmt.Errorf("network %s: %w", networkName, err)
+	}
+	return gateways, nil
+}
+
+// defaultNetworkName returns the network of eth0 in the default profile,
+// which containers are attached to
+func defaultNetworkName() (string, error) {
 	profileOutput, err := container.IncusOutput("profile", "device", "show", "default")
 	if err != nil {
 		return "", fmt.Errorf("failed to get default profile: %w", err)
 	}
 
-	// Parse network name from profile (eth0 device)
-	var networkName string
+	networkName := parseProfileNetworkName(profileOutput)
+	if networkName == "" {
+		return "", fmt.Errorf("could not determine network name from profile")
+	}
+	return networkName, nil
+}
+
+// parseProfileNetworkName extracts the eth0 network name from 'incus profile device show' output
+func parseProfileNetworkName(profileOutput string) string {
 	lines := strings.Split(profileOutput, "\n")
 	for i, line := range lines {
 		if strings.TrimSpace(line) == "eth0:" {
@@ -368,44 +753,73 @@ func getContainerGatewayIP(containerName string) (string, error) {
 				if strings.Contains(lines[j], "network:") {
 					parts := strings.Split(lines[j], ":")
 					if len(parts) >= 2 {
-						networkName = strings.TrimSpace(parts[1])
-						break
+						return strings.TrimSpace(parts[1])
 					}
 				}
 			}
 			break
 		}
 	}
+	return ""
+}
 
-	if networkName == "" {
-		return "", fmt.Errorf("could not determine network name from profile")
-	}
-
-	// Get network configuration
-	networkOutput, err := container.IncusOutput("network", "show", networkName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get network info: %w", err)
-	}
-
-	// Parse gateway IP (ipv4.address field)
+// parseNetworkGateways extracts the gateway addresses (ipv4.address / ipv6.address)
+// from 'incus network show' output. Addresses are validated for their family;
+// "none" or a missing key means the family is disabled on the network.
+func parseNetworkGateways(networkOutput string) (networkGateways, error) {
+	var gateways networkGateways
 	for _, line := range strings.Split(networkOutput, "\n") {
 		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "ipv4.address:") {
-			addressWithMask := strings.TrimSpace(strings.TrimPrefix(line, "ipv4.address:"))
+		for _, family := range []string{"ipv4", "ipv6"} {
+			key := family + ".address:"
+			if !strings.HasPrefix(line, key) {
+				continue
+			}
+
+			value := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, key)), `"'`)
+			if value == "" || value == "none" {
+				continue
+			}
+
 			// Remove CIDR suffix (e.g., "10.128.178.1/24" -> "10.128.178.1")
-			gatewayIP := addressWithMask
-			if idx := strings.Index(addressWithMask, "/"); idx != -1 {
-				gatewayIP = addressWithMask[:idx]
+			gatewayIP := value
+			if idx := strings.Index(value, "/"); idx != -1 {
+				gatewayIP = value[:idx]
 			}
 
-			// Validate that we extracted a valid IPv4 address
-			if net.ParseIP(gatewayIP) == nil {
-				return "", fmt.Errorf("invalid IPv4 address extracted: %s", gatewayIP)
+			// Validate that we extracted an address of the expected family
+			ip := net.ParseIP(gatewayIP)
+			if ip == nil || (family == "ipv4") != (ip.To4() != nil) {
+				return networkGateways{}, fmt.Errorf("invalid %s address extracted: %s", family, gatewayIP)
 			}
 
-			return gatewayIP, nil
+			if family == "ipv4" {
+				gateways.IPv4 = gatewayIP
+			} else {
+				gateways.IPv6 = gatewayIP
+			}
 		}
 	}
 
-	return "", fmt.Errorf("could not find ipv4.address in network %s", networkName)
+	if gateways.IPv4 == "" && gateways.IPv6 == "" {
+		return networkGateways{}, fmt.Errorf("could not find ipv4.address or ipv6.address")
+	}
+	return gateways, nil
+}
+
+// enableIPv6Gateway adds the container's IPv6 address to the firewall rules
+// when its network is dual-stack, so IPv6 traffic is filtered like IPv4
+func (m *Manager) enableIPv6Gateway(containerName, gatewayIPv6 string) error {
+	if gatewayIPv6 == "" {
+		return nil
+	}
+
+	containerIPv6, err := GetContainerIPv6(containerName)
+	if err != nil {
+		return fmt.Errorf("network has IPv6 gateway %s but %w", gatewayIPv6, err)
+	}
+
+	m.firewall.SetIPv6(containerIPv6, gatewayIPv6)
+	log.Printf("Container IPv6: %s, Gateway IPv6: %s", containerIPv6, gatewayIPv6)
+	return nil
 }
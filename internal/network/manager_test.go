@@ -0,0 +1,67 @@
+package network
+
+import "testing"
+
+const dualStackNetworkShow = `config:
+  ipv4.address: 10.128.178.1/24
+  ipv4.nat: "true"
+  ipv6.address: fd42:4242:4242:1010::1/64
+  ipv6.nat: "true"
+description: ""
+name: incusbr0
+type: bridge
+managed: true
+status: Created
+`
+
+func TestParseNetworkGateways(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    networkGateways
+		wantErr bool
+	}{
+		{"dual-stack", dualStackNetworkShow, networkGateways{IPv4: "10.128.178.1", IPv6: "fd42:4242:4242:1010::1"}, false},
+		{"ipv4 only", "config:\n  ipv4.address: 10.0.3.1/24\n  ipv6.address: none\n", networkGateways{IPv4: "10.0.3.1"}, false},
+		{"ipv6 only", "config:\n  ipv4.address: none\n  ipv6.address: fd00::1/64\n", networkGateways{IPv6: "fd00::1"}, false},
+		{"no addresses", "config:\n  ipv4.nat: \"true\"\n", networkGateways{}, true},
+		{"invalid ipv4", "config:\n  ipv4.address: 10.0.3/24\n", networkGateways{}, true},
+		{"ipv6 in ipv4 key", "config:\n  ipv4.address: fd00::1/64\n", networkGateways{}, true},
+		{"ipv4 in ipv6 key", "config:\n  ipv4.address: 10.0.3.1/24\n  ipv6.address: 10.0.4.1/24\n", networkGateways{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseNetworkGateways(tt.output)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got %+v", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestParseProfileNetworkName(t *testing.T) {
+	output := `eth0:
+  name: eth0
+  network: incusbr0
+  type: nic
+root:
+  path: /
+  pool: default
+  type: disk
+`
+	if got := parseProfileNetworkName(output); got != "incusbr0" {
+		t.Errorf("Expected 'incusbr0', got '%s'", got)
+	}
+	if got := parseProfileNetworkName("root:\n  path: /\n"); got != "" {
+		t.Errorf("Expected empty network name without eth0, got '%s'", got)
+	}
+}
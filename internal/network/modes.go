@@ -0,0 +1,86 @@
+package network
+
+import (
+	"sort"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+// Example addresses the rules of each mode are rendered for. Public addresses
+// are from the documentation ranges (RFC 5737).
+const (
+	ExampleContainerIP = "10.47.62.50"
+	exampleGatewayIP   = "10.47.62.1"
+	exampleAllowedIP   = "203.0.113.10"
+	exampleDeniedIP    = "198.51.100.20"
+	exampleProxyIP     = "192.0.2.30"
+)
+
+// ModeExample is a network mode with the firewall rules it applies to an
+// example container, built by the same rule builders as a real session
+type ModeExample struct {
+	Mode config.NetworkMode
+	// RequiresFirewall reports whether the mode (with this config) needs
+	// firewalld; without it open mode leaves the container unrestricted
+	RequiresFirewall bool
+	Rules            []FirewallRule // In evaluation order (by priority)
+}
+
+// ModeExamples returns every network mode with the rules it applies under cfg
+// (block_private_networks, denied_domains, ...), for an example container
+// with one resolved IP per denied or allowed domain
+func ModeExamples(cfg *config.NetworkConfig) []ModeExample {
+	f := NewFirewallManager(ExampleContainerIP, exampleGatewayIP)
+
+	var deniedIPs []string
+	if len(cfg.DeniedDomains) > 0 {
+		deniedIPs = []string{exampleDeniedIP}
+	}
+
+	open := ModeExample{Mode: config.NetworkModeOpen, RequiresFirewall: len(deniedIPs) > 0}
+	if len(deniedIPs) > 0 {
+		open.Rules = f.OpenRules(deniedIPs)
+	} else {
+		open.Rules = []FirewallRule{OpenModeRule(ExampleContainerIP)}
+	}
+
+	restricted := ModeExample{Mode: config.NetworkModeRestricted, RequiresFirewall: true}
+	restricted.Rules = f.RestrictedRules(cfg, deniedIPs)
+	if cfg.Proxy != "" {
+		restricted.Rules = append(restricted.Rules, f.AllowIPRules([]string{exampleProxyIP})...)
+	}
+
+	allowlist := ModeExample{Mode: config.NetworkModeAllowlist, RequiresFirewall: true}
+	allowlist.Rules = f.AllowlistRules(cfg, []string{exampleAllowedIP})
+
+	examples := []ModeExample{open, restricted, allowlist}
+	for _, example := range examples {
+		sort.SliceStable(example.Rules, func(i, j int) bool {
+			return example.Rules[i].Priority < example.Rules[j].Priority
+		})
+	}
+	return examples
+}
+
+// DescribeDestination names what an example rule destination stands for
+func DescribeDestination(destination string) string {
+	switch destination {
+	case "":
+		return "all traffic"
+	case exampleGatewayIP + "/32":
+		return "gateway (host, DNS)"
+	case exampleAllowedIP + "/32":
+		return "IPs of allowed_domains"
+	case exampleDeniedIP + "/32":
+		return "IPs of denied_domains"
+	case exampleProxyIP + "/32":
+		return "proxy"
+	case "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7":
+		return "private network " + destination
+	case "169.254.0.0/16", "fe80::/10", "fd00:ec2::254/128":
+		return "link-local / cloud metadata endpoint"
+	case "0.0.0.0/0", "::/0":
+		return "everything else (internet)"
+	}
+	return destination
+}
@@ -0,0 +1,62 @@
+package network
+
+import (
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestModeExamples(t *testing.T) {
+	cfg := &config.NetworkConfig{
+		BlockPrivateNetworks:  true,
+		BlockMetadataEndpoint: true,
+	}
+
+	examples := ModeExamples(cfg)
+	if len(examples) != 3 {
+		t.Fatalf("Expected 3 modes, got %d", len(examples))
+	}
+
+	byMode := make(map[config.NetworkMode]ModeExample)
+	for _, example := range examples {
+		byMode[example.Mode] = example
+		for i := 1; i < len(example.Rules); i++ {
+			if example.Rules[i].Priority < example.Rules[i-1].Priority {
+				t.Errorf("%s rules are not in priority order: %v", example.Mode, example.Rules)
+			}
+		}
+	}
+
+	open := byMode[config.NetworkModeOpen]
+	if open.RequiresFirewall || len(open.Rules) != 1 || open.Rules[0] != OpenModeRule(ExampleContainerIP) {
+		t.Errorf("Open mode without denied domains should only accept all traffic without firewalld, got %+v", open)
+	}
+
+	allowlist := byMode[config.NetworkModeAllowlist]
+	last := allowlist.Rules[len(allowlist.Rules)-1]
+	if !allowlist.RequiresFirewall || last.Destination != "0.0.0.0/0" || last.Action != "REJECT" {
+		t.Errorf("Allowlist mode should end in a default deny, got %+v", allowlist)
+	}
+
+	restricted := byMode[config.NetworkModeRestricted]
+	last = restricted.Rules[len(restricted.Rules)-1]
+	if !restricted.RequiresFirewall || last.Destination != "0.0.0.0/0" || last.Action != "ACCEPT" {
+		t.Errorf("Restricted mode should end in allowing the internet, got %+v", restricted)
+	}
+}
+
+func TestModeExamplesDeniedDomains(t *testing.T) {
+	cfg := &config.NetworkConfig{DeniedDomains: []string{"example.com"}}
+
+	for _, example := range ModeExamples(cfg) {
+		if example.Mode != config.NetworkModeOpen {
+			continue
+		}
+		if !example.RequiresFirewall {
+			t.Error("Open mode with denied domains should require firewalld")
+		}
+		if DescribeDestination(example.Rules[0].Destination) != "IPs of denied_domains" {
+			t.Errorf("Expected the denied domain rule first, got %v", example.Rules)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+// RuleDiff is the difference between the rules COI intends for a container
+// and the rules firewalld has applied
+type RuleDiff struct {
+	Added     []FirewallRule // Applied but not intended (stale or added by hand)
+	Removed   []FirewallRule // Intended but not applied
+	Reordered []RuleMove     // Applied at a different priority than intended
+}
+
+// RuleMove is an applied rule whose priority differs from the intended one
+type RuleMove struct {
+	Rule         FirewallRule // The intended rule
+	LivePriority int
+}
+
+// Empty reports whether the applied rules match the intended ones
+func (d RuleDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Reordered) == 0
+}
+
+// PolicyReport compares a running container's firewall policy with what COI
+// would apply now
+type PolicyReport struct {
+	Mode     config.NetworkMode
+	Intended []FirewallRule
+	Applied  []FirewallRule
+	Diff     RuleDiff
+}
+
+// DiffRules compares intended and applied rules. Rules match on family,
+// source, destination and action; a match at another priority is a reorder,
+// since priority decides the order firewalld evaluates rules in.
+func DiffRules(intended, applied []FirewallRule) RuleDiff {
+	type ruleKey struct{ family, source, destination, action string }
+	keyOf := func(r FirewallRule) ruleKey {
+		return ruleKey{r.Family, r.Source, r.Destination, r.Action}
+	}
+
+	// Exact matches first, so a reorder is only reported for leftovers
+	unmatched := make(map[FirewallRule]int)
+	for _, rule := range applied {
+		unmatched[rule]++
+	}
+	var missing []FirewallRule
+	for _, rule := range intended {
+		if unmatched[rule] > 0 {
+			unmatched[rule]--
+			continue
+		}
+		missing = append(missing, rule)
+	}
+
+	var diff RuleDiff
+	for _, rule := range missing {
+		moved := false
+		for _, live := range applied {
+			if unmatched[live] > 0 && keyOf(live) == keyOf(rule) {
+				unmatched[live]--
+				diff.Reordered = append(diff.Reordered, RuleMove{Rule: rule, LivePriority: live.Priority})
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			diff.Removed = append(diff.Removed, rule)
+		}
+	}
+	for _, rule := range applied {
+		if unmatched[rule] > 0 {
+			unmatched[rule]--
+			diff.Added = append(diff.Added, rule)
+		}
+	}
+	return diff
+}
+
+// IntendedRules computes the rules COI would apply to the container now, from
+// its saved network config, fresh DNS resolution and the IP cache
+func (m *Manager) IntendedRules() ([]FirewallRule, error) {
+	switch m.config.Mode {
+	case config.NetworkModeOpen:
+		if len(m.config.DeniedDomains) == 0 {
+			return nil, fmt.Errorf("no firewall policy in open mode without denied_domains")
+		}
+		return m.firewall.OpenRules(m.intendedDeniedIPs()), nil
+
+	case config.NetworkModeRestricted:
+		rules := m.firewall.RestrictedRules(m.config, m.intendedDeniedIPs())
+		if m.config.Proxy != "" {
+			_, ips, err := m.resolveProxy()
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, m.firewall.AllowIPRules(ips)...)
+		}
+		return rules, nil
+
+	case config.NetworkModeAllowlist:
+		domainIPs, err := m.resolver.ResolveAll(m.allowedDomains())
+		if err != nil && len(domainIPs) == 0 {
+			return nil, fmt.Errorf("failed to resolve any allowed domains: %w", err)
+		}
+		return m.firewall.AllowlistRules(m.config, collectUniqueIPs(domainIPs)), nil
+
+	default:
+		return nil, fmt.Errorf("no firewall policy in %s mode", m.config.Mode)
+	}
+}
+
+// intendedDeniedIPs resolves the denied domains without updating the cache.
+// Domains that fail to resolve have no rules, as during setup.
+func (m *Manager) intendedDeniedIPs() []string {
+	if len(m.config.DeniedDomains) == 0 {
+		return nil
+	}
+	domainIPs, _ := m.resolver.ResolveAll(m.deniedDomains())
+	return collectUniqueIPs(domainIPs)
+}
+
+// ComparePolicy diffs the rules COI would apply to a running container now
+// against the rules firewalld currently has for it
+func (m *Manager) ComparePolicy() (*PolicyReport, error) {
+	if !FirewallAvailable() {
+		return nil, fmt.Errorf("%s", errFirewallNotAvailable)
+	}
+
+	if err := m.attach(); err != nil {
+		return nil, err
+	}
+
+	intended, err := m.IntendedRules()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.firewall.LiveRules()
+	if err != nil {
+		return nil, err
+	}
+
+	return &PolicyReport{
+		Mode:     m.config.Mode,
+		Intended: intended,
+		Applied:  applied,
+		Diff:     DiffRules(intended, applied),
+	}, nil
+}
@@ -0,0 +1,44 @@
+package network
+
+import "testing"
+
+func TestDiffRules(t *testing.T) {
+	gateway := FirewallRule{Family: "ipv4", Priority: 0, Source: "10.0.0.5", Destination: "10.0.0.1/32", Action: "ACCEPT"}
+	api := FirewallRule{Family: "ipv4", Priority: 1, Source: "10.0.0.5", Destination: "1.1.1.1/32", Action: "ACCEPT"}
+	private := FirewallRule{Family: "ipv4", Priority: 10, Source: "10.0.0.5", Destination: "10.0.0.0/8", Action: "REJECT"}
+	deny := FirewallRule{Family: "ipv4", Priority: 99, Source: "10.0.0.5", Destination: "0.0.0.0/0", Action: "REJECT"}
+
+	t.Run("identical", func(t *testing.T) {
+		if diff := DiffRules([]FirewallRule{gateway, api, deny}, []FirewallRule{deny, api, gateway}); !diff.Empty() {
+			t.Errorf("Expected no differences, got %+v", diff)
+		}
+	})
+
+	t.Run("added and removed", func(t *testing.T) {
+		stale := FirewallRule{Family: "ipv4", Priority: 1, Source: "10.0.0.5", Destination: "9.9.9.9/32", Action: "ACCEPT"}
+		diff := DiffRules([]FirewallRule{gateway, api, private, deny}, []FirewallRule{gateway, stale, deny})
+
+		if len(diff.Added) != 1 || diff.Added[0] != stale {
+			t.Errorf("Expected %v added, got %v", stale, diff.Added)
+		}
+		if len(diff.Removed) != 2 || diff.Removed[0] != api || diff.Removed[1] != private {
+			t.Errorf("Expected %v and %v removed, got %v", api, private, diff.Removed)
+		}
+		if len(diff.Reordered) != 0 {
+			t.Errorf("Expected no reordered rules, got %v", diff.Reordered)
+		}
+	})
+
+	t.Run("reordered", func(t *testing.T) {
+		moved := private
+		moved.Priority = 50
+		diff := DiffRules([]FirewallRule{gateway, private, deny}, []FirewallRule{gateway, moved, deny})
+
+		if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+			t.Errorf("Expected only a reorder, got %+v", diff)
+		}
+		if len(diff.Reordered) != 1 || diff.Reordered[0].Rule != private || diff.Reordered[0].LivePriority != 50 {
+			t.Errorf("Expected %v moved to priority 50, got %v", private, diff.Reordered)
+		}
+	})
+}
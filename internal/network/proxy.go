@@ -0,0 +1,53 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ProxyHost extracts the host (domain or IP, without port) from a proxy URL.
+// A URL without scheme (proxy.corp:3128) is treated as http://.
+func ProxyHost(proxyURL string) (string, error) {
+	raw := strings.TrimSpace(proxyURL)
+	if raw == "" {
+		return "", fmt.Errorf("proxy URL is empty")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid proxy URL '%s': %w", proxyURL, err)
+	}
+	host := parsed.Hostname()
+	if host == "" {
+		return "", fmt.Errorf("invalid proxy URL '%s': missing host", proxyURL)
+	}
+	return host, nil
+}
+
+// ProxyEnv returns the proxy environment variables for a container.
+// Both upper- and lower-case variants are set since tools disagree on which they read.
+// NO_PROXY always covers localhost and, when known, the gateway (host) IP.
+func ProxyEnv(proxyURL, gatewayIP string) map[string]string {
+	if strings.TrimSpace(proxyURL) == "" {
+		return nil
+	}
+
+	noProxy := []string{"localhost", "127.0.0.1", "::1"}
+	if gatewayIP != "" {
+		noProxy = append(noProxy, gatewayIP)
+	}
+	noProxyValue := strings.Join(noProxy, ",")
+
+	return map[string]string{
+		"HTTP_PROXY":  proxyURL,
+		"HTTPS_PROXY": proxyURL,
+		"NO_PROXY":    noProxyValue,
+		"http_proxy":  proxyURL,
+		"https_proxy": proxyURL,
+		"no_proxy":    noProxyValue,
+	}
+}
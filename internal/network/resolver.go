@@ -10,9 +10,14 @@ import (
 	"time"
 )
 
+// ttlLookupTimeout bounds each DNS TTL query made alongside resolution
+const ttlLookupTimeout = 2 * time.Second
+
 // Resolver handles DNS resolution with caching and fallback
 type Resolver struct {
 	cache *IPCache
+	ttls  map[string]time.Duration // DNS TTLs from the latest ResolveAll
+	ipv6  bool                     // Also return IPv6 addresses (see IncludeIPv6)
 }
 
 // NewResolver creates a new resolver with a cache
@@ -20,14 +25,23 @@ func NewResolver(cache *IPCache) *Resolver {
 	return &Resolver{cache: cache}
 }
 
-// ResolveDomain resolves a single domain to IPv4 addresses
-// If the input is already an IPv4 address, it returns it directly
+// IncludeIPv6 makes the resolver return IPv6 addresses (AAAA records) too,
+// for denied domains, which must be blocked over both address families
+func (r *Resolver) IncludeIPv6() {
+	r.ipv6 = true
+}
+
+// ResolveDomain resolves a single domain to IPv4 addresses (and IPv6 ones
+// with IncludeIPv6). If the input is already an IP address, it returns it directly
 func (r *Resolver) ResolveDomain(domain string) ([]string, error) {
 	// Check if input is already an IP address
 	if ip := net.ParseIP(domain); ip != nil {
 		if ipv4 := ip.To4(); ipv4 != nil {
 			return []string{ipv4.String()}, nil
 		}
+		if r.ipv6 {
+			return []string{ip.String()}, nil
+		}
 		return nil, fmt.Errorf("%s is not a valid IPv4 address", domain)
 	}
 
@@ -35,23 +49,38 @@ func (r *Resolver) ResolveDomain(domain string) ([]string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	addrs, err := net.DefaultResolver.LookupIP(ctx, "ip4", domain)
+	network := "ip4"
+	if r.ipv6 {
+		network = "ip"
+	}
+	addrs, err := net.DefaultResolver.LookupIP(ctx, network, domain)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve %s: %w", domain, err)
 	}
 
+	ips := r.addressStrings(addrs)
+	if len(ips) == 0 {
+		if r.ipv6 {
+			return nil, fmt.Errorf("no addresses found for %s", domain)
+		}
+		return nil, fmt.Errorf("no IPv4 addresses found for %s", domain)
+	}
+
+	return ips, nil
+}
+
+// addressStrings returns the IPv4 addresses among addrs, and the IPv6 ones
+// with IncludeIPv6
+func (r *Resolver) addressStrings(addrs []net.IP) []string {
 	ips := make([]string, 0, len(addrs))
 	for _, addr := range addrs {
 		if ipv4 := addr.To4(); ipv4 != nil {
 			ips = append(ips, ipv4.String())
+		} else if r.ipv6 && addr.To16() != nil {
+			ips = append(ips, addr.String())
 		}
 	}
-
-	if len(ips) == 0 {
-		return nil, fmt.Errorf("no IPv4 addresses found for %s", domain)
-	}
-
-	return ips, nil
+	return ips
 }
 
 // ResolveAll resolves all domains to IPs with caching fallback
@@ -59,6 +88,8 @@ func (r *Resolver) ResolveAll(domains []string) (map[string][]string, error) {
 	results := make(map[string][]string)
 	hasError := false
 	resolvedCount := 0
+	r.ttls = make(map[string]time.Duration)
+	lookupTTLs := true
 
 	for _, domain := range domains {
 		ips, err := r.ResolveDomain(domain)
@@ -81,6 +112,19 @@ func (r *Resolver) ResolveAll(domains []string) (map[string][]string, error) {
 
 		results[domain] = ips
 		resolvedCount++
+
+		// TTLs only tune the refresh interval; stop asking once the nameserver doesn't answer
+		if lookupTTLs && net.ParseIP(domain) == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), ttlLookupTimeout)
+			ttl, err := lookupTTL(ctx, domain)
+			cancel()
+			if err != nil {
+				log.Printf("Could not determine DNS TTL for %s: %v", domain, err)
+				lookupTTLs = false
+			} else {
+				r.ttls[domain] = ttl
+			}
+		}
 	}
 
 	// If we couldn't resolve any domains and have no cache, return error
@@ -128,12 +172,27 @@ func (r *Resolver) IPsUnchanged(newIPs map[string][]string) bool {
 	return true
 }
 
-// UpdateCache updates the cache with new IPs
+// UpdateCache updates the cache with new IPs and the TTLs of the latest resolution
 func (r *Resolver) UpdateCache(newIPs map[string][]string) {
 	r.cache.Domains = newIPs
+	r.cache.TTLs = make(map[string]int, len(r.ttls))
+	for domain, ttl := range r.ttls {
+		r.cache.TTLs[domain] = int(ttl / time.Second)
+	}
 	r.cache.LastUpdate = time.Now()
 }
 
+// MinTTL returns the shortest DNS TTL seen by the latest ResolveAll (0 = unknown)
+func (r *Resolver) MinTTL() time.Duration {
+	var minTTL time.Duration
+	for _, ttl := range r.ttls {
+		if minTTL == 0 || ttl < minTTL {
+			minTTL = ttl
+		}
+	}
+	return minTTL
+}
+
 // GetCache returns the current cache
 func (r *Resolver) GetCache() *IPCache {
 	return r.cache
@@ -1,6 +1,8 @@
 package network
 
 import (
+	"net"
+	"slices"
 	"testing"
 )
 
@@ -82,3 +84,38 @@ func TestResolveDomain_DomainName(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveDomain_RawIPv6WithIncludeIPv6(t *testing.T) {
+	resolver := NewResolver(&IPCache{Domains: make(map[string][]string)})
+	resolver.IncludeIPv6()
+
+	for input, want := range map[string]string{
+		"8.8.8.8":              "8.8.8.8",
+		"2001:4860:4860::8888": "2001:4860:4860::8888",
+		"::ffff:1.2.3.4":       "1.2.3.4", // IPv4-mapped stays IPv4
+	} {
+		got, err := resolver.ResolveDomain(input)
+		if err != nil {
+			t.Errorf("ResolveDomain(%q) unexpected error: %v", input, err)
+			continue
+		}
+		if len(got) != 1 || got[0] != want {
+			t.Errorf("ResolveDomain(%q) = %v, want [%s]", input, got, want)
+		}
+	}
+}
+
+func TestAddressStrings(t *testing.T) {
+	addrs := []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("2606:2800:220:1:248:1893:25c8:1946")}
+
+	resolver := NewResolver(&IPCache{Domains: make(map[string][]string)})
+	if got := resolver.addressStrings(addrs); !slices.Equal(got, []string{"93.184.216.34"}) {
+		t.Errorf("Expected only the IPv4 address by default, got %v", got)
+	}
+
+	resolver.IncludeIPv6()
+	want := []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"}
+	if got := resolver.addressStrings(addrs); !slices.Equal(got, want) {
+		t.Errorf("Expected %v with IncludeIPv6, got %v", want, got)
+	}
+}
@@ -0,0 +1,185 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// minRefreshInterval is the floor for TTL-driven refreshes, so very short TTLs
+// (some CDNs use a few seconds) don't make the refresher hammer DNS and firewalld
+const minRefreshInterval = time.Minute
+
+const (
+	dnsTypeA     = 1
+	dnsClassIN   = 1
+	dnsHeaderLen = 12
+)
+
+// NextRefreshInterval returns how long the refresher waits before re-resolving:
+// the configured interval, shortened to the shortest DNS TTL seen (but never
+// below minRefreshInterval). A zero TTL means unknown and keeps the interval.
+func NextRefreshInterval(configured, minTTL time.Duration) time.Duration {
+	if minTTL <= 0 || minTTL >= configured {
+		return configured
+	}
+	return max(minTTL, minRefreshInterval)
+}
+
+// lookupTTL queries the system's first nameserver for domain's A records and
+// returns the shortest TTL in the answer (including any CNAME chain). The net
+// package doesn't expose TTLs, so this sends its own query; callers treat
+// errors as "TTL unknown".
+func lookupTTL(ctx context.Context, domain string) (time.Duration, error) {
+	server, err := systemNameserver("/etc/resolv.conf")
+	if err != nil {
+		return 0, err
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	query, err := buildDNSQuery(id, domain)
+	if err != nil {
+		return 0, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, fmt.Errorf("failed to contact nameserver %s: %w", server, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline) // Only fails on closed connections
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return 0, fmt.Errorf("failed to send DNS query: %w", err)
+	}
+	response := make([]byte, 4096)
+	n, err := conn.Read(response)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read DNS response: %w", err)
+	}
+
+	ttl, err := parseMinTTL(response[:n], id)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ttl) * time.Second, nil
+}
+
+// systemNameserver returns the first nameserver (host:port) in a resolv.conf file
+func systemNameserver(resolvConf string) (string, error) {
+	file, err := os.Open(resolvConf)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", resolvConf, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53"), nil
+		}
+	}
+	return "", fmt.Errorf("no nameserver in %s", resolvConf)
+}
+
+// buildDNSQuery encodes a recursive A query for domain (RFC 1035 section 4)
+func buildDNSQuery(id uint16, domain string) ([]byte, error) {
+	msg := make([]byte, dnsHeaderLen, dnsHeaderLen+len(domain)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // RD: recursion desired
+	binary.BigEndian.PutUint16(msg[4:], 1)      // QDCOUNT
+
+	for _, label := range strings.Split(strings.TrimSuffix(domain, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain name %q", domain)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeA)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	return msg, nil
+}
+
+// parseMinTTL returns the shortest TTL among the answer records of a DNS response
+func parseMinTTL(msg []byte, id uint16) (uint32, error) {
+	if len(msg) < dnsHeaderLen {
+		return 0, fmt.Errorf("DNS response too short")
+	}
+	if binary.BigEndian.Uint16(msg[0:]) != id {
+		return 0, fmt.Errorf("DNS response ID mismatch")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if rcode := flags & 0x000f; rcode != 0 {
+		return 0, fmt.Errorf("DNS query failed (rcode %d)", rcode)
+	}
+	qdCount := int(binary.BigEndian.Uint16(msg[4:]))
+	anCount := int(binary.BigEndian.Uint16(msg[6:]))
+	if anCount == 0 {
+		return 0, fmt.Errorf("DNS response has no answers")
+	}
+
+	offset := dnsHeaderLen
+	for i := 0; i < qdCount; i++ {
+		end, err := skipDNSName(msg, offset)
+		if err != nil {
+			return 0, err
+		}
+		offset = end + 4 // QTYPE + QCLASS
+	}
+
+	var minTTL uint32
+	for i := 0; i < anCount; i++ {
+		end, err := skipDNSName(msg, offset)
+		if err != nil {
+			return 0, err
+		}
+		// TYPE(2) CLASS(2) TTL(4) RDLENGTH(2)
+		if end+10 > len(msg) {
+			return 0, fmt.Errorf("truncated DNS answer")
+		}
+		ttl := binary.BigEndian.Uint32(msg[end+4:])
+		rdLength := int(binary.BigEndian.Uint16(msg[end+8:]))
+		offset = end + 10 + rdLength
+		if offset > len(msg) {
+			return 0, fmt.Errorf("truncated DNS answer")
+		}
+		if i == 0 || ttl < minTTL {
+			minTTL = ttl
+		}
+	}
+	return minTTL, nil
+}
+
+// skipDNSName returns the offset just past the (possibly compressed) name at offset
+func skipDNSName(msg []byte, offset int) (int, error) {
+	for {
+		if offset >= len(msg) {
+			return 0, fmt.Errorf("truncated DNS name")
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xc0 == 0xc0:
+			// Compression pointer: two bytes, ends the name
+			if offset+2 > len(msg) {
+				return 0, fmt.Errorf("truncated DNS name")
+			}
+			return offset + 2, nil
+		default:
+			offset += 1 + length
+		}
+	}
+}
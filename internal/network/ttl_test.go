@@ -0,0 +1,119 @@
+package network
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// dnsAnswer appends an answer record pointing at the question name (offset 12)
+func dnsAnswer(msg []byte, rrType uint16, ttl uint32, rdata []byte) []byte {
+	msg = append(msg, 0xc0, dnsHeaderLen) // Compressed name
+	msg = binary.BigEndian.AppendUint16(msg, rrType)
+	msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	msg = binary.BigEndian.AppendUint32(msg, ttl)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(rdata)))
+	return append(msg, rdata...)
+}
+
+func TestBuildDNSQuery(t *testing.T) {
+	query, err := buildDNSQuery(0x1234, "api.example.com")
+	if err != nil {
+		t.Fatalf("buildDNSQuery() unexpected error: %v", err)
+	}
+
+	want := []byte{3, 'a', 'p', 'i', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0, 0, 1, 0, 1}
+	if binary.BigEndian.Uint16(query) != 0x1234 || string(query[dnsHeaderLen:]) != string(want) {
+		t.Errorf("Unexpected query: %v", query)
+	}
+
+	if _, err := buildDNSQuery(1, "bad..domain"); err == nil {
+		t.Error("Expected error for empty label")
+	}
+}
+
+func TestParseMinTTL(t *testing.T) {
+	response := func(rcode uint16, answers func([]byte) []byte) []byte {
+		msg, _ := buildDNSQuery(42, "cdn.example.com")
+		binary.BigEndian.PutUint16(msg[2:], 0x8180|rcode) // Response, RD, RA
+		msg = answers(msg)
+		return msg
+	}
+
+	// CNAME (300s) to two A records (60s, 20s)
+	msg := response(0, func(m []byte) []byte {
+		m = dnsAnswer(m, 5, 300, []byte{0xc0, dnsHeaderLen})
+		m = dnsAnswer(m, dnsTypeA, 60, []byte{192, 0, 2, 1})
+		return dnsAnswer(m, dnsTypeA, 20, []byte{192, 0, 2, 2})
+	})
+	binary.BigEndian.PutUint16(msg[6:], 3)
+
+	ttl, err := parseMinTTL(msg, 42)
+	if err != nil {
+		t.Fatalf("parseMinTTL() unexpected error: %v", err)
+	}
+	if ttl != 20 {
+		t.Errorf("parseMinTTL() = %d, want 20", ttl)
+	}
+
+	if _, err := parseMinTTL(msg, 43); err == nil {
+		t.Error("Expected error for mismatched ID")
+	}
+	if _, err := parseMinTTL(msg[:len(msg)-3], 42); err == nil {
+		t.Error("Expected error for truncated response")
+	}
+	if _, err := parseMinTTL(response(3, func(m []byte) []byte { return m }), 42); err == nil {
+		t.Error("Expected error for NXDOMAIN")
+	}
+	if _, err := parseMinTTL(response(0, func(m []byte) []byte { return m }), 42); err == nil {
+		t.Error("Expected error for response without answers")
+	}
+}
+
+func TestNextRefreshInterval(t *testing.T) {
+	interval := 30 * time.Minute
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{0, interval},                         // Unknown TTL
+		{2 * time.Hour, interval},             // Stable domains keep the configured interval
+		{5 * time.Minute, 5 * time.Minute},    // Short TTL refreshes sooner
+		{5 * time.Second, minRefreshInterval}, // Floor
+	}
+
+	for _, tt := range tests {
+		if got := NextRefreshInterval(interval, tt.ttl); got != tt.want {
+			t.Errorf("NextRefreshInterval(%s, %s) = %s, want %s", interval, tt.ttl, got, tt.want)
+		}
+	}
+}
+
+func TestSystemNameserver(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resolv.conf")
+	if err := os.WriteFile(path, []byte("# comment\nsearch lan\nnameserver 10.0.0.1\nnameserver 10.0.0.2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := systemNameserver(path)
+	if err != nil || server != "10.0.0.1:53" {
+		t.Errorf("systemNameserver() = (%q, %v), want 10.0.0.1:53", server, err)
+	}
+
+	if err := os.WriteFile(path, []byte("nameserver fd00::1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if server, _ := systemNameserver(path); server != "[fd00::1]:53" {
+		t.Errorf("systemNameserver() = %q, want [fd00::1]:53", server)
+	}
+
+	if err := os.WriteFile(path, []byte("search lan\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := systemNameserver(path); err == nil {
+		t.Error("Expected error without nameserver")
+	}
+}
@@ -0,0 +1,58 @@
+package network
+
+import (
+	"strings"
+)
+
+// IsWildcardDomain reports whether an allowlist entry is a wildcard like "*.example.com"
+func IsWildcardDomain(domain string) bool {
+	return strings.HasPrefix(domain, "*.")
+}
+
+// validWildcard checks that a wildcard has exactly one leading "*." and at
+// least two labels after it ("*.com" is too broad to be meaningful)
+func validWildcard(pattern string) bool {
+	suffix := strings.TrimPrefix(pattern, "*.")
+	return suffix != pattern && !strings.Contains(suffix, "*") && strings.Count(suffix, ".") >= 1 &&
+		!strings.HasPrefix(suffix, ".") && !strings.HasSuffix(suffix, ".")
+}
+
+// ExpandWildcards replaces wildcard allowlist entries ("*.example.com") with the
+// explicit subdomains configured for them in expansions (["api", "cdn"] ->
+// api.example.com, cdn.example.com). Firewall rules are IP-based, so a wildcard
+// cannot be resolved directly: wildcards without a configured expansion (or with
+// an invalid pattern) are dropped and returned as skipped. Order is preserved
+// and duplicates are removed.
+func ExpandWildcards(domains []string, expansions map[string][]string) (expanded, skipped []string) {
+	seen := make(map[string]bool)
+	add := func(domain string) {
+		if !seen[domain] {
+			seen[domain] = true
+			expanded = append(expanded, domain)
+		}
+	}
+
+	for _, domain := range domains {
+		if !IsWildcardDomain(domain) {
+			add(domain)
+			continue
+		}
+
+		subdomains := expansions[domain]
+		if !validWildcard(domain) || len(subdomains) == 0 {
+			skipped = append(skipped, domain)
+			continue
+		}
+
+		suffix := strings.TrimPrefix(domain, "*.")
+		for _, sub := range subdomains {
+			sub = strings.Trim(strings.TrimSpace(sub), ".")
+			if sub == "" {
+				continue
+			}
+			add(sub + "." + suffix)
+		}
+	}
+
+	return expanded, skipped
+}
@@ -0,0 +1,52 @@
+package network
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/config"
+)
+
+func TestExpandWildcards(t *testing.T) {
+	expansions := map[string][]string{
+		"*.githubusercontent.com": {"raw", "objects", " avatars "},
+		"*.com":                   {"example"},
+	}
+
+	tests := []struct {
+		name         string
+		domains      []string
+		wantExpanded string
+		wantSkipped  string
+	}{
+		{"no wildcards", []string{"github.com", "8.8.8.8"}, "github.com,8.8.8.8", ""},
+		{"expanded", []string{"github.com", "*.githubusercontent.com"}, "github.com,raw.githubusercontent.com,objects.githubusercontent.com,avatars.githubusercontent.com", ""},
+		{"deduplicated", []string{"raw.githubusercontent.com", "*.githubusercontent.com"}, "raw.githubusercontent.com,objects.githubusercontent.com,avatars.githubusercontent.com", ""},
+		{"no expansion configured", []string{"github.com", "*.example.org"}, "github.com", "*.example.org"},
+		{"too broad", []string{"*.com"}, "", "*.com"},
+		{"nested wildcard", []string{"*.*.example.org"}, "", "*.*.example.org"},
+	}
+
+	for _, tt := range tests {
+		expanded, skipped := ExpandWildcards(tt.domains, expansions)
+		if got := strings.Join(expanded, ","); got != tt.wantExpanded {
+			t.Errorf("%s: expanded = %s, want %s", tt.name, got, tt.wantExpanded)
+		}
+		if got := strings.Join(skipped, ","); got != tt.wantSkipped {
+			t.Errorf("%s: skipped = %s, want %s", tt.name, got, tt.wantSkipped)
+		}
+	}
+}
+
+func TestAllowedDomainsExpandsWildcards(t *testing.T) {
+	m := NewManager(&config.NetworkConfig{
+		Mode:               config.NetworkModeAllowlist,
+		AllowedDomains:     []string{"*.githubusercontent.com", "*.unknown.example"},
+		WildcardSubdomains: map[string][]string{"*.githubusercontent.com": {"raw"}},
+	})
+
+	domains := m.allowedDomains()
+	if strings.Join(domains, ",") != "raw.githubusercontent.com" {
+		t.Errorf("Expected only the expanded subdomain, got %v", domains)
+	}
+}
@@ -0,0 +1,125 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix is the executable name prefix of coi plugins: 'coi foo' runs coi-foo
+// from PATH when foo is not a built-in command (like git and kubectl)
+const Prefix = "coi-"
+
+// Context is what coi tells a plugin about itself through the environment
+type Context struct {
+	ContainerPrefix string   // Prefix of coi container names
+	ConfigPaths     []string // Config files in increasing precedence
+	Profile         string   // --profile given before the plugin name
+}
+
+// Find returns the path of the coi-<name> executable on PATH
+func Find(name string) (string, error) {
+	if name == "" || strings.ContainsRune(name, os.PathSeparator) {
+		return "", fmt.Errorf("invalid plugin name '%s'", name)
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return "", fmt.Errorf("plugin '%s' not found (no %s%s on PATH)", name, Prefix, name)
+	}
+	return path, nil
+}
+
+// List returns the plugins on PATH by name. As with command lookup, the first
+// directory on PATH providing a name wins.
+func List() map[string]string {
+	plugins := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), Prefix)
+			if !ok || name == "" {
+				continue
+			}
+			if _, seen := plugins[name]; seen {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Mode()&0o111 != 0 {
+				plugins[name] = path
+			}
+		}
+	}
+	return plugins
+}
+
+// Env returns the environment a plugin runs with: base (coi's own) plus the
+// context plugins need to find coi containers and config. A COI_CONFIG already
+// in base is kept.
+func Env(base []string, ctx Context) []string {
+	env := append(append([]string{}, base...), "COI_CONTAINER_PREFIX="+ctx.ContainerPrefix)
+	if !hasVar(base, "COI_CONFIG") {
+		for i := len(ctx.ConfigPaths) - 1; i >= 0; i-- {
+			if _, err := os.Stat(ctx.ConfigPaths[i]); err == nil {
+				env = append(env, "COI_CONFIG="+ctx.ConfigPaths[i])
+				break
+			}
+		}
+	}
+	if ctx.Profile != "" {
+		env = append(env, "COI_PROFILE="+ctx.Profile)
+	}
+	return env
+}
+
+// hasVar reports whether env sets name to a non-empty value
+func hasVar(env []string, name string) bool {
+	for _, kv := range env {
+		if value, ok := strings.CutPrefix(kv, name+"="); ok && value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// FlagLookup reports whether coi knows a global flag (name without dashes,
+// shorthand for single-dash flags) and whether it takes a value
+type FlagLookup func(name string, shorthand bool) (known, takesValue bool)
+
+// SplitArgs splits coi's args into the global flags before the first
+// positional argument, that argument (a command or plugin name) and the args
+// after it. ok is false when there is no name, or a leading flag is unknown
+// or "--", so cobra handles (and reports) the command line itself.
+func SplitArgs(args []string, lookup FlagLookup) (flags []string, name string, rest []string, ok bool) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return args[:i], arg, args[i+1:], true
+		}
+		if arg == "--" {
+			return nil, "", nil, false
+		}
+
+		var flagName string
+		var shorthand, attached bool
+		if long, isLong := strings.CutPrefix(arg, "--"); isLong {
+			flagName, _, attached = strings.Cut(long, "=")
+		} else {
+			// -e, -eVALUE or -e=VALUE
+			flagName, shorthand, attached = arg[1:2], true, len(arg) > 2
+		}
+
+		known, takesValue := lookup(flagName, shorthand)
+		if !known {
+			return nil, "", nil, false
+		}
+		if takesValue && !attached {
+			i++ // The value is the next argument
+		}
+	}
+	return nil, "", nil, false
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeExecutable creates an executable (or, with mode 0o644, a plain) file
+func writeExecutable(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindAndList(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writeExecutable(t, filepath.Join(first, "coi-mcp"), 0o755)
+	writeExecutable(t, filepath.Join(second, "coi-mcp"), 0o755)
+	writeExecutable(t, filepath.Join(second, "coi-report"), 0o755)
+	writeExecutable(t, filepath.Join(second, "coi-notes"), 0o644) // Not executable
+	writeExecutable(t, filepath.Join(second, "coi-"), 0o755)      // No name
+	writeExecutable(t, filepath.Join(second, "other"), 0o755)
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	want := map[string]string{
+		"mcp":    filepath.Join(first, "coi-mcp"),
+		"report": filepath.Join(second, "coi-report"),
+	}
+	if got := List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List() = %v, want %v", got, want)
+	}
+
+	path, err := Find("mcp")
+	if err != nil {
+		t.Fatalf("Find() unexpected error: %v", err)
+	}
+	if path != want["mcp"] {
+		t.Errorf("Find() = %q, want the first match on PATH %q", path, want["mcp"])
+	}
+
+	for _, name := range []string{"notes", "missing", "", "../coi-mcp"} {
+		if _, err := Find(name); err == nil {
+			t.Errorf("Find(%q) expected an error", name)
+		}
+	}
+}
+
+func TestEnv(t *testing.T) {
+	dir := t.TempDir()
+	userConfig := filepath.Join(dir, "config.toml")
+	projectConfig := filepath.Join(dir, ".coi.toml")
+	writeExecutable(t, userConfig, 0o644)
+
+	ctx := Context{
+		ContainerPrefix: "coi-",
+		ConfigPaths:     []string{userConfig, projectConfig}, // The project config does not exist
+		Profile:         "work",
+	}
+	base := []string{"PATH=/usr/bin"}
+	env := Env(base, ctx)
+
+	for _, want := range []string{"PATH=/usr/bin", "COI_CONTAINER_PREFIX=coi-", "COI_CONFIG=" + userConfig, "COI_PROFILE=work"} {
+		if !contains(env, want) {
+			t.Errorf("Expected %q in %v", want, env)
+		}
+	}
+	if len(base) != 1 {
+		t.Errorf("Env() modified its base: %v", base)
+	}
+
+	// An explicit COI_CONFIG wins, and no profile means no COI_PROFILE
+	env = Env([]string{"COI_CONFIG=/etc/coi.toml"}, Context{ContainerPrefix: "coi-", ConfigPaths: []string{userConfig}})
+	for _, kv := range env {
+		if kv == "COI_CONFIG="+userConfig || strings.HasPrefix(kv, "COI_PROFILE=") {
+			t.Errorf("Unexpected %q in %v", kv, env)
+		}
+	}
+}
+
+func contains(env []string, kv string) bool {
+	for _, item := range env {
+		if item == kv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSplitArgs(t *testing.T) {
+	// --profile and -e take values; --persistent is a bool
+	lookup := func(name string, shorthand bool) (bool, bool) {
+		if shorthand {
+			return name == "e", true
+		}
+		switch name {
+		case "profile", "env":
+			return true, true
+		case "persistent":
+			return true, false
+		}
+		return false, false
+	}
+
+	tests := []struct {
+		args      []string
+		wantFlags []string
+		wantName  string
+		wantRest  []string
+		wantOK    bool
+	}{
+		{[]string{"mcp", "--port", "8080"}, []string{}, "mcp", []string{"--port", "8080"}, true},
+		{[]string{"--profile", "work", "mcp", "-v"}, []string{"--profile", "work"}, "mcp", []string{"-v"}, true},
+		{[]string{"--profile=work", "--persistent", "mcp"}, []string{"--profile=work", "--persistent"}, "mcp", []string{}, true},
+		{[]string{"-e", "A=1", "-eB=2", "mcp"}, []string{"-e", "A=1", "-eB=2"}, "mcp", []string{}, true},
+		{[]string{"--persistent"}, nil, "", nil, false},
+		{[]string{"--profile"}, nil, "", nil, false},
+		{[]string{"--unknown", "mcp"}, nil, "", nil, false},
+		{[]string{"-x", "mcp"}, nil, "", nil, false},
+		{[]string{"--", "mcp"}, nil, "", nil, false},
+		{nil, nil, "", nil, false},
+	}
+
+	for _, tt := range tests {
+		flags, name, rest, ok := SplitArgs(tt.args, lookup)
+		if ok != tt.wantOK || name != tt.wantName {
+			t.Errorf("SplitArgs(%v) = name %q, ok %t; want %q, %t", tt.args, name, ok, tt.wantName, tt.wantOK)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if !reflect.DeepEqual(flags, tt.wantFlags) || !reflect.DeepEqual(rest, tt.wantRest) {
+			t.Errorf("SplitArgs(%v) = flags %v, rest %v; want %v, %v", tt.args, flags, rest, tt.wantFlags, tt.wantRest)
+		}
+	}
+}
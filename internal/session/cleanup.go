@@ -2,12 +2,14 @@ package session
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/mensfeld/code-on-incus/internal/config"
 	"github.com/mensfeld/code-on-incus/internal/container"
 	"github.com/mensfeld/code-on-incus/internal/network"
 	"github.com/mensfeld/code-on-incus/internal/tool"
@@ -16,13 +18,18 @@ import (
 // CleanupOptions contains options for cleaning up a session
 type CleanupOptions struct {
 	ContainerName  string
-	SessionID      string    // COI session ID for saving tool config data
-	Persistent     bool      // If true, stop but don't delete container
-	SessionsDir    string    // e.g., ~/.coi/sessions-claude
-	SaveSession    bool      // Whether to save tool config directory
-	Workspace      string    // Workspace directory path
-	Tool           tool.Tool // AI coding tool being used
+	SessionID      string               // COI session ID for saving tool config data
+	Persistent     bool                 // If true, stop but don't delete container
+	Policy         config.CleanupPolicy // Non-persistent container still running at exit: keep (default), delete (--rm) or ask
+	SessionsDir    string               // e.g., ~/.coi/sessions-claude
+	SaveSession    bool                 // Whether to save tool config directory
+	Workspace      string               // Workspace directory path
+	Tool           tool.Tool            // AI coding tool being used
 	NetworkManager *network.Manager
+	Context        context.Context                   // Cancels saving session data (e.g. Ctrl+C during cleanup); defaults to Background
+	StopReason     string                            // Why the session ended abnormally (e.g. OOMStopReason), reported instead of a plain stop
+	DeleteGrace    time.Duration                     // Keep a stopped non-persistent container this long before deleting it (delete_grace_minutes)
+	ScheduleDelete func(deleteAfter time.Time) error // Arranges deletion once the grace period ends (e.g. starts a reaper)
 	Logger         func(string)
 }
 
@@ -35,11 +42,19 @@ func Cleanup(opts CleanupOptions) error {
 		}
 	}
 
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+
 	if opts.ContainerName == "" {
 		opts.Logger("No container to clean up")
 		return nil
 	}
 
+	if opts.StopReason != "" {
+		opts.Logger(fmt.Sprintf("Warning: %s", opts.StopReason))
+	}
+
 	mgr := container.NewManager(opts.ContainerName)
 
 	// Check if container exists
@@ -49,26 +64,58 @@ func Cleanup(opts CleanupOptions) error {
 		opts.Logger(fmt.Sprintf("Warning: Could not check container existence: %v", err))
 	}
 
+	// Report network traffic while the counters are still there (a stopped container has none)
+	if exists {
+		if counters, err := mgr.NetworkCounters(); err == nil {
+			opts.Logger(formatNetworkTraffic(counters))
+		}
+	}
+
 	// Always save session data if container exists (works even from stopped containers)
 	// This ensures --resume works regardless of how the user exited (including sudo shutdown 0)
 	// Skip if tool uses ENV-based auth (no config directory to save)
+	saveFailed := false
 	if opts.SaveSession && exists && opts.SessionID != "" && opts.SessionsDir != "" && opts.Tool != nil && opts.Tool.ConfigDirName() != "" {
-		if err := saveSessionData(mgr, opts.SessionID, opts.Persistent, opts.Workspace, opts.SessionsDir, opts.Tool, opts.Logger); err != nil {
-			opts.Logger(fmt.Sprintf("Warning: Failed to save session data: %v", err))
+		if err := saveSessionData(opts.Context, mgr, opts.SessionID, opts.Persistent, opts.Workspace, opts.SessionsDir, opts.Tool, opts.Logger); err != nil {
+			saveFailed = true
+			if opts.Context.Err() != nil {
+				opts.Logger("Saving session data cancelled - this session cannot be resumed")
+			} else {
+				opts.Logger(fmt.Sprintf("Warning: Failed to save session data: %v", err))
+			}
+		}
+	}
+
+	// The forwarded SSH agent belongs to this session; a container that is
+	// kept must not give later processes access to its keys
+	if exists {
+		if err := mgr.RemoveDevice(SSHAgentDeviceName); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Failed to remove the SSH agent forwarding: %v", err))
 		}
 	}
 
 	// Handle container based on persistence mode
-	if opts.Persistent {
+	if saveFailed && exists && !opts.Persistent {
+		// The container holds the only copy of the session data, so don't delete it
+		opts.Logger(fmt.Sprintf("Container kept because its session data was not saved - copy files out with 'coi file pull', then remove it with 'coi kill %s'", opts.ContainerName))
+	} else if opts.Persistent {
 		// Persistent mode: keep container for reuse (with all its data/modifications)
 		if exists {
 			opts.Logger("Container kept running - use 'coi attach' to reconnect, 'coi shutdown' to stop, or 'coi kill' to force stop")
 		} else {
 			opts.Logger("Container was stopped but kept for reuse")
 		}
+	} else if opts.Policy == config.CleanupDelete {
+		// --rm / cleanup_policy = "delete": delete regardless of how the user exited
+		if exists {
+			opts.Logger("Removing container (cleanup policy: delete)...")
+			deleteContainer(mgr, opts)
+		} else {
+			opts.Logger("Container was already removed")
+		}
 	} else {
 		// Non-persistent mode: behavior depends on how user exited
-		// - If container is running (user typed 'exit' or detached): keep it running
+		// - If container is running (user typed 'exit' or detached): keep it (or ask)
 		// - If container is stopped (user did 'sudo shutdown 0'): delete it
 		if exists {
 			// Check if container is stopped, with retries to handle shutdown delay
@@ -82,26 +129,23 @@ func Cleanup(opts CleanupOptions) error {
 				}
 			}
 
-			if running {
+			if running && opts.Policy == config.CleanupAsk && confirmDelete(opts.ContainerName) {
+				opts.Logger("Removing container...")
+				deleteContainer(mgr, opts)
+			} else if running {
 				// Container still running - user exited normally, keep it for potential re-attach
 				opts.Logger("Container kept running - use 'coi attach' to reconnect, 'coi shutdown' to stop, or 'coi kill' to force stop")
+			} else if opts.DeleteGrace > 0 {
+				// Container stopped, but delete_grace_minutes keeps it around to pull files from
+				keepStoppedContainer(mgr, opts)
 			} else {
-				// Container stopped (user did 'sudo shutdown 0') - delete it
-				opts.Logger("Container was stopped, removing...")
-
-				// Delete container first (this detaches any ACLs from its devices)
-				if err := mgr.Delete(true); err != nil {
-					opts.Logger(fmt.Sprintf("Warning: Failed to delete container: %v", err))
+				// Container stopped (user did 'sudo shutdown 0', or it was killed) - delete it
+				if opts.StopReason != "" {
+					opts.Logger("Removing stopped container...")
 				} else {
-					opts.Logger("Container removed (session data saved for --resume)")
-
-					// Clean up network ACL after successfully deleting container
-					// The ACL is now detached and can be safely deleted
-					if opts.NetworkManager != nil {
-						// Ignore errors - ACL might already be removed or not exist for this network mode
-						_ = opts.NetworkManager.Teardown(context.Background(), opts.ContainerName)
-					}
+					opts.Logger("Container was stopped, removing...")
 				}
+				deleteContainer(mgr, opts)
 			}
 		} else {
 			opts.Logger("Container was already removed")
@@ -111,8 +155,81 @@ func Cleanup(opts CleanupOptions) error {
 	return nil
 }
 
+// formatNetworkTraffic summarizes the bytes a container transferred since it started
+func formatNetworkTraffic(counters container.NetworkCounters) string {
+	const mib = 1024 * 1024
+	return fmt.Sprintf("Network traffic since container start: %.1f MiB received, %.1f MiB sent",
+		float64(counters.BytesReceived)/mib, float64(counters.BytesSent)/mib)
+}
+
+// confirmDelete asks whether to delete a container that is still running
+// (cleanup_policy = "ask"). Without an answer (e.g. no terminal) it is kept.
+func confirmDelete(containerName string) bool {
+	fmt.Fprintf(os.Stderr, "Container %s is still running. Delete it? [y/N]: ", containerName)
+	var response string
+	_, _ = fmt.Scanln(&response) // An unreadable answer counts as "no"
+	return response == "y" || response == "Y"
+}
+
+// deleteContainer force-deletes the container and then tears down its network ACL
+func deleteContainer(mgr *container.Manager, opts CleanupOptions) {
+	// Delete container first (this detaches any ACLs from its devices)
+	if err := mgr.Delete(true); err != nil {
+		opts.Logger(fmt.Sprintf("Warning: Failed to delete container: %v", err))
+		return
+	}
+	if opts.SaveSession {
+		opts.Logger("Container removed (session data saved for --resume)")
+	} else {
+		opts.Logger("Container removed")
+	}
+
+	// Clean up network ACL after successfully deleting container
+	// The ACL is now detached and can be safely deleted
+	if opts.NetworkManager != nil {
+		// Ignore errors - ACL might already be removed or not exist for this network mode
+		_ = opts.NetworkManager.Teardown(context.Background(), opts.ContainerName)
+	}
+}
+
+// keepStoppedContainer marks a stopped container for deletion once
+// opts.DeleteGrace has passed instead of deleting it now. If it can't be
+// marked, it is deleted right away as without a grace period.
+func keepStoppedContainer(mgr *container.Manager, opts CleanupOptions) {
+	deleteAfter := time.Now().Add(opts.DeleteGrace)
+	if err := SetDeleteAfter(mgr, deleteAfter); err != nil {
+		opts.Logger(fmt.Sprintf("Warning: Could not mark container for later deletion: %v", err))
+		opts.Logger("Container was stopped, removing...")
+		deleteContainer(mgr, opts)
+		return
+	}
+
+	// The stopped container has given up its IP, which the firewall rules are keyed by
+	if opts.NetworkManager != nil {
+		_ = opts.NetworkManager.Teardown(context.Background(), opts.ContainerName)
+	}
+
+	opts.Logger(fmt.Sprintf("Container was stopped, keeping it until %s - use 'coi file pull %s:<path> <dest>' to copy files out",
+		deleteAfter.Format("15:04"), opts.ContainerName))
+	if opts.ScheduleDelete != nil {
+		if err := opts.ScheduleDelete(deleteAfter); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: %v - the container will be deleted by the first coi shell or coi list after that", err))
+		}
+	}
+}
+
+// SaveSessionData saves the tool config directory of a session from its
+// container (running or stopped) so it can be resumed, e.g. before a bulk
+// 'coi stop' or 'coi delete'. Tools with ENV-based auth have nothing to save.
+func SaveSessionData(ctx context.Context, mgr *container.Manager, metadata SessionMetadata, sessionsDir string, t tool.Tool, logger func(string)) error {
+	if t == nil || t.ConfigDirName() == "" {
+		return nil
+	}
+	return saveSessionData(ctx, mgr, metadata.SessionID, metadata.Persistent, metadata.Workspace, sessionsDir, t, logger)
+}
+
 // saveSessionData saves the tool config directory from the container
-func saveSessionData(mgr *container.Manager, sessionID string, persistent bool, workspace string, sessionsDir string, t tool.Tool, logger func(string)) error {
+func saveSessionData(ctx context.Context, mgr *container.Manager, sessionID string, persistent bool, workspace string, sessionsDir string, t tool.Tool, logger func(string)) error {
 	// Determine home directory
 	// For coi images, we always use /home/code
 	// For other images, we use /root
@@ -139,10 +256,25 @@ func saveSessionData(mgr *container.Manager, sessionID string, persistent bool,
 		}
 	}
 
+	// Logs and caches the tool rebuilds itself are not worth saving, and keyring
+	// credentials must never land in the sessions directory
+	metadataPath := filepath.Join(localSessionDir, "metadata.json")
+	previous, previousErr := LoadSessionMetadata(metadataPath)
+	exclude := t.ExcludeFromSave()
+	if previousErr == nil && previous.KeyringCredentials {
+		exclude = append(exclude, credentialsFileName)
+	}
+
 	// Pull config directory from container
 	// Note: incus file pull works on stopped containers, so we don't need to check if running
 	// If config dir doesn't exist, PullDirectory will fail and we handle it gracefully
-	if err := mgr.PullDirectory(stateDir, localConfigDir); err != nil {
+	progress := func(p container.TransferProgress) {
+		logger(fmt.Sprintf("Saving session data... %d files (%.1f MB)", p.Files, float64(p.Bytes)/(1024*1024)))
+	}
+	if err := mgr.PullDirectoryExcluding(ctx, stateDir, localConfigDir, exclude, progress); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
 		// Check if it's a "not found" error - this is expected if config dir doesn't exist
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "No such file") {
 			logger(fmt.Sprintf("No %s directory found in container", configDirName))
@@ -160,8 +292,19 @@ func saveSessionData(mgr *container.Manager, sessionID string, persistent bool,
 		SavedAt:       getCurrentTime(),
 	}
 
-	metadataPath := filepath.Join(localSessionDir, "metadata.json")
-	if err := saveMetadata(metadataPath, metadata); err != nil {
+	// Keep the settings recorded when the session started
+	if previousErr == nil {
+		metadata.NetworkMode = previous.NetworkMode
+		metadata.AllowedDomains = previous.AllowedDomains
+		metadata.KeyringCredentials = previous.KeyringCredentials
+		metadata.MountPath = previous.MountPath
+		metadata.LaunchCommand = previous.LaunchCommand
+		metadata.LaunchEnv = previous.LaunchEnv
+		metadata.LaunchCwd = previous.LaunchCwd
+		metadata.HomeCacheDir = previous.HomeCacheDir
+	}
+
+	if err := SaveMetadata(metadataPath, metadata); err != nil {
 		// Non-fatal - session data is already saved
 		logger(fmt.Sprintf("Warning: Failed to save metadata: %v", err))
 	}
@@ -177,21 +320,49 @@ type SessionMetadata struct {
 	Persistent    bool   `json:"persistent"`
 	Workspace     string `json:"workspace"`
 	SavedAt       string `json:"saved_at"`
+
+	// Network settings the session was started with, inherited on resume
+	NetworkMode    string   `json:"network_mode,omitempty"`
+	AllowedDomains []string `json:"allowed_domains,omitempty"`
+
+	// Credentials came from the host keyring and are left out of saved state
+	KeyringCredentials bool `json:"keyring_credentials,omitempty"`
+
+	// Where the workspace was mounted (--mount-at); empty means /workspace
+	MountPath string `json:"mount_path,omitempty"`
+
+	// The tool command line of the latest launch (shows resume mode and tool session ID)
+	LaunchCommand string `json:"launch_command,omitempty"`
+
+	// Environment and working directory of the latest launch, reused by coi attach --relaunch
+	LaunchEnv map[string]string `json:"launch_env,omitempty"`
+	LaunchCwd string            `json:"launch_cwd,omitempty"`
+
+	// Host directory mounted at ~/.cache (--mount-home); it outlives the session
+	HomeCacheDir string `json:"home_cache_dir,omitempty"`
 }
 
-// saveMetadata saves session metadata to a JSON file
-func saveMetadata(path string, metadata SessionMetadata) error {
-	// Simple JSON marshaling
-	content := fmt.Sprintf(`{
-  "session_id": "%s",
-  "container_name": "%s",
-  "persistent": %t,
-  "workspace": "%s",
-  "saved_at": "%s"
+// SessionMountPath returns where a saved session had its workspace mounted
+func SessionMountPath(sessionsDir, sessionID string) string {
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, sessionID, "metadata.json"))
+	if err != nil || metadata.MountPath == "" {
+		return ContainerWorkspacePath
+	}
+	return metadata.MountPath
 }
-`, metadata.SessionID, metadata.ContainerName, metadata.Persistent, metadata.Workspace, metadata.SavedAt)
 
-	return os.WriteFile(path, []byte(content), 0o644)
+// SaveMetadata saves session metadata to a JSON file
+func SaveMetadata(path string, metadata SessionMetadata) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	// The launch environment can hold --env secrets, so keep it private
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0o600)
 }
 
 // getCurrentTime returns current time in RFC3339 format
@@ -199,60 +370,148 @@ func getCurrentTime() string {
 	return time.Now().Format(time.RFC3339)
 }
 
-// SaveMetadataEarly saves session metadata at session start so coi list can show correct status
-func SaveMetadataEarly(sessionsDir, sessionID, containerName, workspace string, persistent bool) error {
+// SaveMetadataEarly saves session metadata at session start so coi list can show
+// correct status. SavedAt is filled in automatically.
+func SaveMetadataEarly(sessionsDir string, metadata SessionMetadata) error {
 	// Create session directory if it doesn't exist
-	sessionDir := filepath.Join(sessionsDir, sessionID)
+	sessionDir := filepath.Join(sessionsDir, metadata.SessionID)
 	if err := os.MkdirAll(sessionDir, 0o755); err != nil {
 		return fmt.Errorf("failed to create session directory: %w", err)
 	}
 
-	metadata := SessionMetadata{
-		SessionID:     sessionID,
-		ContainerName: containerName,
-		Persistent:    persistent,
-		Workspace:     workspace,
-		SavedAt:       getCurrentTime(),
-	}
+	metadata.SavedAt = getCurrentTime()
 
 	metadataPath := filepath.Join(sessionDir, "metadata.json")
-	return saveMetadata(metadataPath, metadata)
+	return SaveMetadata(metadataPath, metadata)
+}
+
+// RecordLaunchCommand stores the command line, environment and working
+// directory the tool was launched with in
+// the session's metadata (written by SaveMetadataEarly)
+func RecordLaunchCommand(sessionsDir, sessionID, command string, env map[string]string, cwd string) error {
+	metadataPath := filepath.Join(sessionsDir, sessionID, "metadata.json")
+	metadata, err := LoadSessionMetadata(metadataPath)
+	if err != nil {
+		return err
+	}
+	metadata.LaunchCommand = command
+	metadata.LaunchEnv = env
+	metadata.LaunchCwd = cwd
+	return SaveMetadata(metadataPath, *metadata)
 }
 
-// SessionExists checks if a session with the given ID exists and is valid
-func SessionExists(sessionsDir, sessionID string) bool {
-	statePath := filepath.Join(sessionsDir, sessionID, ".claude")
+// SessionExists checks if a session with the given ID exists and is valid:
+// it holds the tool's config directory (e.g. .claude), or for tools without
+// one (ENV-based auth) its metadata
+func SessionExists(sessionsDir, sessionID, configDirName string) bool {
+	if configDirName == "" {
+		_, err := os.Stat(filepath.Join(sessionsDir, sessionID, "metadata.json"))
+		return err == nil
+	}
+	statePath := filepath.Join(sessionsDir, sessionID, configDirName)
 	info, err := os.Stat(statePath)
 	return err == nil && info.IsDir()
 }
 
-// ListSavedSessions lists all saved sessions in the sessions directory
-func ListSavedSessions(sessionsDir string) ([]string, error) {
+// RenameContainerInSessions points saved session metadata (of every tool)
+// recording oldName at newName, so coi list and resume follow a renamed
+// container. Returns the number of sessions updated.
+func RenameContainerInSessions(baseDir, oldName, newName string) (int, error) {
+	dirs, err := ListToolSessionsDirs(baseDir)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir.Dir)
+		if err != nil {
+			return updated, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			metadataPath := filepath.Join(dir.Dir, entry.Name(), "metadata.json")
+			metadata, err := LoadSessionMetadata(metadataPath)
+			if err != nil || metadata.ContainerName != oldName {
+				continue
+			}
+			metadata.ContainerName = newName
+			if err := SaveMetadata(metadataPath, *metadata); err != nil {
+				return updated, fmt.Errorf("failed to update session %s: %w", entry.Name(), err)
+			}
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// ListSavedSessions lists the saved sessions in the sessions directory that
+// are valid for a tool with configDirName (see SessionExists). Directories
+// holding only the metadata written at launch are left out: their session is
+// still running, or failed before anything was saved.
+func ListSavedSessions(sessionsDir, configDirName string) ([]string, error) {
+	sessions, _, err := scanSessionDirs(sessionsDir, configDirName)
+	return sessions, err
+}
+
+// ListIncompleteSessions lists the session directories that are not valid
+// saved sessions, i.e. what ListSavedSessions leaves out
+func ListIncompleteSessions(sessionsDir, configDirName string) ([]string, error) {
+	_, incomplete, err := scanSessionDirs(sessionsDir, configDirName)
+	return incomplete, err
+}
+
+// scanSessionDirs splits the session directories into valid sessions and the rest
+func scanSessionDirs(sessionsDir, configDirName string) ([]string, []string, error) {
 	entries, err := os.ReadDir(sessionsDir)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return []string{}, nil
+			return []string{}, []string{}, nil
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
-	var sessions []string
+	sessions := []string{}
+	incomplete := []string{}
 	for _, entry := range entries {
-		if entry.IsDir() {
-			// Check if it contains a .claude directory
-			statePath := filepath.Join(sessionsDir, entry.Name(), ".claude")
-			if info, err := os.Stat(statePath); err == nil && info.IsDir() {
-				sessions = append(sessions, entry.Name())
-			}
+		if !entry.IsDir() {
+			continue
+		}
+		if SessionExists(sessionsDir, entry.Name(), configDirName) {
+			sessions = append(sessions, entry.Name())
+		} else {
+			incomplete = append(incomplete, entry.Name())
 		}
 	}
 
-	return sessions, nil
+	return sessions, incomplete, nil
+}
+
+// RemoveEarlyMetadata deletes the session directory SaveMetadataEarly
+// created, for a session that failed to start. Directories holding anything
+// besides the metadata are kept.
+func RemoveEarlyMetadata(sessionsDir, sessionID string) error {
+	sessionDir := filepath.Join(sessionsDir, sessionID)
+	entries, err := os.ReadDir(sessionDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() != "metadata.json" {
+			return nil
+		}
+	}
+	return os.RemoveAll(sessionDir)
 }
 
 // GetLatestSession returns the most recently saved session ID
 func GetLatestSession(sessionsDir string) (string, error) {
-	sessions, err := ListSavedSessions(sessionsDir)
+	sessions, err := ListSavedSessions(sessionsDir, ConfigDirForSessionsDir(sessionsDir))
 	if err != nil {
 		return "", err
 	}
@@ -292,7 +551,7 @@ func GetLatestSession(sessionsDir string) (string, error) {
 
 // GetLatestSessionForWorkspace returns the most recent session ID for a specific workspace
 func GetLatestSessionForWorkspace(sessionsDir, workspacePath string) (string, error) {
-	sessions, err := ListSavedSessions(sessionsDir)
+	sessions, err := ListSavedSessions(sessionsDir, ConfigDirForSessionsDir(sessionsDir))
 	if err != nil {
 		return "", err
 	}
@@ -344,6 +603,63 @@ func GetLatestSessionForWorkspace(sessionsDir, workspacePath string) (string, er
 	return latestSession, nil
 }
 
+// AutoResumeMaxAge is how recently a session must have been saved for
+// [defaults] auto_resume to pick it up; older ones start fresh
+const AutoResumeMaxAge = 7 * 24 * time.Hour
+
+// RecentSessionForWorkspace returns the latest saved session for workspacePath
+// and when it was saved, if that was at most maxAge before now
+func RecentSessionForWorkspace(sessionsDir, workspacePath string, maxAge time.Duration, now time.Time) (string, time.Time, bool) {
+	sessionID, err := GetLatestSessionForWorkspace(sessionsDir, workspacePath)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, sessionID, "metadata.json"))
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	savedAt, err := time.Parse(time.RFC3339, metadata.SavedAt)
+	if err != nil || now.Sub(savedAt) > maxAge {
+		return "", time.Time{}, false
+	}
+	return sessionID, savedAt, true
+}
+
+// GetLatestSessionForContainer returns the metadata of the most recent session
+// that ran in containerName. Unlike GetLatestSession it also finds sessions
+// whose tool config has not been saved yet (metadata is written at session start).
+func GetLatestSessionForContainer(sessionsDir, containerName string) (*SessionMetadata, error) {
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var latest *SessionMetadata
+	var latestTime time.Time
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, entry.Name(), "metadata.json"))
+		if err != nil || metadata.ContainerName != containerName {
+			continue
+		}
+		savedTime, err := time.Parse(time.RFC3339, metadata.SavedAt)
+		if err != nil {
+			continue
+		}
+		if latest == nil || savedTime.After(latestTime) {
+			latest = metadata
+			latestTime = savedTime
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no session found for container %s", containerName)
+	}
+	return latest, nil
+}
+
 // LoadSessionMetadata loads session metadata from a JSON file
 func LoadSessionMetadata(path string) (*SessionMetadata, error) {
 	data, err := os.ReadFile(path)
@@ -352,21 +668,8 @@ func LoadSessionMetadata(path string) (*SessionMetadata, error) {
 	}
 
 	var metadata SessionMetadata
-	// Simple JSON parsing
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "\"session_id\"") {
-			metadata.SessionID = extractJSONValue(line)
-		} else if strings.Contains(line, "\"container_name\"") {
-			metadata.ContainerName = extractJSONValue(line)
-		} else if strings.Contains(line, "\"persistent\"") {
-			metadata.Persistent = strings.Contains(line, "true")
-		} else if strings.Contains(line, "\"workspace\"") {
-			metadata.Workspace = extractJSONValue(line)
-		} else if strings.Contains(line, "\"saved_at\"") {
-			metadata.SavedAt = extractJSONValue(line)
-		}
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("invalid metadata: %w", err)
 	}
 
 	if metadata.SessionID == "" {
@@ -376,24 +679,13 @@ func LoadSessionMetadata(path string) (*SessionMetadata, error) {
 	return &metadata, nil
 }
 
-// extractJSONValue extracts the value from a JSON line like `"key": "value",`
-func extractJSONValue(line string) string {
-	// Find the value between quotes after the colon
-	parts := strings.SplitN(line, ":", 2)
-	if len(parts) != 2 {
-		return ""
-	}
-
-	value := strings.TrimSpace(parts[1])
-	value = strings.Trim(value, `",`)
-	return value
-}
-
 // GetCLISessionID extracts the CLI tool's session ID from a saved coi session.
-// CLI tools store sessions in .claude/projects/-workspace/<session-id>.jsonl
-// Returns empty string if no session found.
+// CLI tools store sessions in .claude/projects/<project dir>/<session-id>.jsonl,
+// where the project dir is derived from the workspace mount path (-workspace
+// for /workspace). Returns empty string if no session found.
 func GetCLISessionID(sessionsDir, coiSessionID string) string {
-	projectsDir := filepath.Join(sessionsDir, coiSessionID, ".claude", "projects", "-workspace")
+	projectDir := ProjectDirName(SessionMountPath(sessionsDir, coiSessionID))
+	projectsDir := filepath.Join(sessionsDir, coiSessionID, ".claude", "projects", projectDir)
 
 	entries, err := os.ReadDir(projectsDir)
 	if err != nil {
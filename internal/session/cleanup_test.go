@@ -0,0 +1,321 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionMetadataRoundTrip(t *testing.T) {
+	sessionsDir := t.TempDir()
+
+	err := SaveMetadataEarly(sessionsDir, SessionMetadata{
+		SessionID:      "abc-123",
+		ContainerName:  "coi-deadbeef-1",
+		Persistent:     true,
+		Workspace:      "/home/user/project",
+		NetworkMode:    "allowlist",
+		AllowedDomains: []string{"github.com", "api.anthropic.com"},
+	})
+	if err != nil {
+		t.Fatalf("SaveMetadataEarly() unexpected error: %v", err)
+	}
+
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, "abc-123", "metadata.json"))
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() unexpected error: %v", err)
+	}
+
+	if metadata.ContainerName != "coi-deadbeef-1" || !metadata.Persistent || metadata.Workspace != "/home/user/project" {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+	if metadata.SavedAt == "" {
+		t.Error("Expected SavedAt to be filled in")
+	}
+	if metadata.NetworkMode != "allowlist" || strings.Join(metadata.AllowedDomains, ",") != "github.com,api.anthropic.com" {
+		t.Errorf("Expected network settings to round-trip, got mode=%q domains=%v", metadata.NetworkMode, metadata.AllowedDomains)
+	}
+}
+
+func TestRecordLaunchCommand(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, SessionMetadata{SessionID: "abc-123", Workspace: "/home/user/project"}); err != nil {
+		t.Fatalf("SaveMetadataEarly() unexpected error: %v", err)
+	}
+
+	command := "claude --verbose --resume 5f0c6e7a"
+	env := map[string]string{"HOME": "/home/code", "HTTPS_PROXY": "http://10.0.0.1:3128", "API_TOKEN": "secret"}
+	if err := RecordLaunchCommand(sessionsDir, "abc-123", command, env, "/workspace/sub"); err != nil {
+		t.Fatalf("RecordLaunchCommand() unexpected error: %v", err)
+	}
+
+	metadata, err := LoadSessionMetadata(filepath.Join(sessionsDir, "abc-123", "metadata.json"))
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() unexpected error: %v", err)
+	}
+	if metadata.LaunchCommand != command {
+		t.Errorf("Expected launch command %q, got %q", command, metadata.LaunchCommand)
+	}
+	if !reflect.DeepEqual(metadata.LaunchEnv, env) {
+		t.Errorf("Expected launch env %v, got %v", env, metadata.LaunchEnv)
+	}
+	if metadata.LaunchCwd != "/workspace/sub" {
+		t.Errorf("Expected launch cwd /workspace/sub, got %q", metadata.LaunchCwd)
+	}
+	if metadata.Workspace != "/home/user/project" {
+		t.Errorf("Expected other metadata to be kept, got %+v", metadata)
+	}
+
+	info, err := os.Stat(filepath.Join(sessionsDir, "abc-123", "metadata.json"))
+	if err != nil {
+		t.Fatalf("Stat() unexpected error: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Expected metadata mode 0600 (it holds the launch env), got %o", perm)
+	}
+
+	if err := RecordLaunchCommand(sessionsDir, "missing", command, nil, ""); err == nil {
+		t.Error("Expected an error for a session without metadata")
+	}
+}
+
+func TestLoadSessionMetadataLegacy(t *testing.T) {
+	// Metadata written before network settings were recorded
+	path := filepath.Join(t.TempDir(), "metadata.json")
+	legacy := `{
+  "session_id": "old-session",
+  "container_name": "coi-deadbeef-1",
+  "persistent": false,
+  "workspace": "/home/user/project",
+  "saved_at": "2025-01-01T00:00:00Z"
+}
+`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	metadata, err := LoadSessionMetadata(path)
+	if err != nil {
+		t.Fatalf("LoadSessionMetadata() unexpected error: %v", err)
+	}
+	if metadata.SessionID != "old-session" || metadata.NetworkMode != "" || metadata.AllowedDomains != nil {
+		t.Errorf("Unexpected legacy metadata: %+v", metadata)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"workspace": "/tmp"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSessionMetadata(path); err == nil {
+		t.Error("Expected error for metadata without session_id")
+	}
+}
+
+func TestGetLatestSessionForContainer(t *testing.T) {
+	sessionsDir := t.TempDir()
+
+	sessions := []SessionMetadata{
+		{SessionID: "old", ContainerName: "coi-deadbeef-1", SavedAt: "2025-01-01T10:00:00Z"},
+		{SessionID: "new", ContainerName: "coi-deadbeef-1", SavedAt: "2025-01-02T10:00:00Z", Persistent: true},
+		{SessionID: "other", ContainerName: "coi-deadbeef-2", SavedAt: "2025-01-03T10:00:00Z"},
+	}
+	for _, metadata := range sessions {
+		dir := filepath.Join(sessionsDir, metadata.SessionID)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := SaveMetadata(filepath.Join(dir, "metadata.json"), metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	latest, err := GetLatestSessionForContainer(sessionsDir, "coi-deadbeef-1")
+	if err != nil {
+		t.Fatalf("GetLatestSessionForContainer() unexpected error: %v", err)
+	}
+	if latest.SessionID != "new" || !latest.Persistent {
+		t.Errorf("Expected session 'new', got %+v", latest)
+	}
+
+	if _, err := GetLatestSessionForContainer(sessionsDir, "coi-deadbeef-3"); err == nil {
+		t.Error("Expected error for container without sessions")
+	}
+	if _, err := GetLatestSessionForContainer(filepath.Join(sessionsDir, "missing"), "coi-deadbeef-1"); err == nil {
+		t.Error("Expected error for missing sessions directory")
+	}
+}
+
+func TestRecentSessionForWorkspace(t *testing.T) {
+	sessionsDir := t.TempDir()
+	workspace := "/home/me/project"
+	hash := WorkspaceHash(workspace)
+
+	sessions := []SessionMetadata{
+		{SessionID: "older", ContainerName: "coi-" + hash + "-1", SavedAt: "2025-01-01T10:00:00Z"},
+		{SessionID: "latest", ContainerName: "coi-" + hash + "-2", SavedAt: "2025-01-05T10:00:00Z"},
+		{SessionID: "elsewhere", ContainerName: "coi-deadbeef-1", SavedAt: "2025-01-06T10:00:00Z"},
+	}
+	for _, metadata := range sessions {
+		dir := filepath.Join(sessionsDir, metadata.SessionID)
+		if err := os.MkdirAll(filepath.Join(dir, ".claude"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := SaveMetadata(filepath.Join(dir, "metadata.json"), metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	sessionID, savedAt, ok := RecentSessionForWorkspace(sessionsDir, workspace, AutoResumeMaxAge, now)
+	if !ok || sessionID != "latest" || !savedAt.Equal(time.Date(2025, 1, 5, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 'latest', got %q (%v, %v)", sessionID, savedAt, ok)
+	}
+
+	// Too old to pick up automatically
+	if sessionID, _, ok := RecentSessionForWorkspace(sessionsDir, workspace, 12*time.Hour, now); ok {
+		t.Errorf("Expected no recent session, got %q", sessionID)
+	}
+
+	// No sessions for this workspace
+	if sessionID, _, ok := RecentSessionForWorkspace(sessionsDir, "/home/me/other", AutoResumeMaxAge, now); ok {
+		t.Errorf("Expected no session for another workspace, got %q", sessionID)
+	}
+}
+
+func TestSessionExists(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sessionsDir, "with-state", ".claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveMetadataEarly(sessionsDir, SessionMetadata{SessionID: "metadata-only"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sessionID     string
+		configDirName string
+		want          bool
+	}{
+		{"with-state", ".claude", true},
+		{"metadata-only", ".claude", false},
+		{"metadata-only", "", true},
+		{"with-state", ".aider", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		if got := SessionExists(sessionsDir, tt.sessionID, tt.configDirName); got != tt.want {
+			t.Errorf("SessionExists(%q, %q) = %v, want %v", tt.sessionID, tt.configDirName, got, tt.want)
+		}
+	}
+}
+
+func TestRenameContainerInSessions(t *testing.T) {
+	baseDir := t.TempDir()
+	claudeDir := filepath.Join(baseDir, "sessions-claude")
+	aiderDir := filepath.Join(baseDir, "sessions-aider")
+	for dir, metadata := range map[string]SessionMetadata{
+		claudeDir: {SessionID: "one", ContainerName: "coi-abc12345-1"},
+		aiderDir:  {SessionID: "two", ContainerName: "coi-abc12345-1"},
+	} {
+		if err := SaveMetadataEarly(dir, metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := SaveMetadataEarly(claudeDir, SessionMetadata{SessionID: "other", ContainerName: "coi-abc12345-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := RenameContainerInSessions(baseDir, "coi-abc12345-1", "coi-refactor")
+	if err != nil {
+		t.Fatalf("RenameContainerInSessions() error: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("Expected 2 sessions updated, got %d", updated)
+	}
+
+	for _, path := range []string{
+		filepath.Join(claudeDir, "one", "metadata.json"),
+		filepath.Join(aiderDir, "two", "metadata.json"),
+	} {
+		metadata, err := LoadSessionMetadata(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata.ContainerName != "coi-refactor" {
+			t.Errorf("%s: expected container coi-refactor, got %s", path, metadata.ContainerName)
+		}
+	}
+	other, err := LoadSessionMetadata(filepath.Join(claudeDir, "other", "metadata.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ContainerName != "coi-abc12345-2" {
+		t.Errorf("Expected unrelated session to keep its container, got %s", other.ContainerName)
+	}
+}
+
+func TestListSavedSessionsSkipsEarlyMetadata(t *testing.T) {
+	sessionsDir := t.TempDir()
+
+	// "saved" has the tool state; "started" only the metadata written at launch
+	for _, id := range []string{"saved", "started"} {
+		if err := SaveMetadataEarly(sessionsDir, SessionMetadata{SessionID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(sessionsDir, "saved", ".claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := ListSavedSessions(sessionsDir, ".claude")
+	if err != nil {
+		t.Fatalf("ListSavedSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0] != "saved" {
+		t.Errorf("Expected only 'saved', got %v", sessions)
+	}
+
+	incomplete, err := ListIncompleteSessions(sessionsDir, ".claude")
+	if err != nil {
+		t.Fatalf("ListIncompleteSessions failed: %v", err)
+	}
+	if len(incomplete) != 1 || incomplete[0] != "started" {
+		t.Errorf("Expected only 'started', got %v", incomplete)
+	}
+
+	// Tools without a config directory count sessions by metadata
+	if sessions, _ := ListSavedSessions(sessionsDir, ""); len(sessions) != 2 {
+		t.Errorf("Expected both sessions for a tool without config dir, got %v", sessions)
+	}
+
+	if sessions, err := ListSavedSessions(filepath.Join(sessionsDir, "missing"), ".claude"); err != nil || len(sessions) != 0 {
+		t.Errorf("Expected no sessions for a missing directory, got %v (%v)", sessions, err)
+	}
+}
+
+func TestRemoveEarlyMetadata(t *testing.T) {
+	sessionsDir := t.TempDir()
+	for _, id := range []string{"failed", "saved"} {
+		if err := SaveMetadataEarly(sessionsDir, SessionMetadata{SessionID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(sessionsDir, "saved", ".claude"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"failed", "saved", "missing"} {
+		if err := RemoveEarlyMetadata(sessionsDir, id); err != nil {
+			t.Errorf("RemoveEarlyMetadata(%s) failed: %v", id, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(sessionsDir, "failed")); !os.IsNotExist(err) {
+		t.Error("Metadata-only session directory should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, "saved", ".claude")); err != nil {
+		t.Error("Session directory with saved state must be kept")
+	}
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// MaxClockDrift is how far a container clock may differ from the host's before
+// it is reported. date +%s has one-second resolution, so smaller differences
+// are noise.
+const MaxClockDrift = 5 * time.Second
+
+// MeasureClockDrift returns how far the container clock is ahead of the host
+// clock (negative when behind), comparing `date +%s` in the container with the
+// midpoint of the host time around the call
+func MeasureClockDrift(mgr *container.Manager) (time.Duration, error) {
+	before := time.Now()
+	output, err := mgr.ExecArgsCapture([]string{"date", "+%s"}, container.ExecCommandOptions{})
+	after := time.Now()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read container clock: %w", err)
+	}
+
+	containerTime, err := parseEpochSeconds(output)
+	if err != nil {
+		return 0, err
+	}
+	hostTime := before.Add(after.Sub(before) / 2)
+	return containerTime.Sub(hostTime).Round(time.Second), nil
+}
+
+// parseEpochSeconds parses `date +%s` output
+func parseEpochSeconds(output string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected container clock output %q", strings.TrimSpace(output))
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+// ClockDriftExceeded reports whether drift (either direction) exceeds MaxClockDrift
+func ClockDriftExceeded(drift time.Duration) bool {
+	return drift > MaxClockDrift || drift < -MaxClockDrift
+}
+
+// DescribeClockDrift renders drift for warnings, e.g. "container clock is 42s ahead of the host"
+func DescribeClockDrift(drift time.Duration) string {
+	switch {
+	case drift > 0:
+		return fmt.Sprintf("container clock is %s ahead of the host", drift)
+	case drift < 0:
+		return fmt.Sprintf("container clock is %s behind the host", -drift)
+	default:
+		return "container clock matches the host"
+	}
+}
@@ -0,0 +1,47 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEpochSeconds(t *testing.T) {
+	got, err := parseEpochSeconds("1700000000\n")
+	if err != nil {
+		t.Fatalf("parseEpochSeconds() unexpected error: %v", err)
+	}
+	if got.Unix() != 1700000000 {
+		t.Errorf("parseEpochSeconds() = %d, want 1700000000", got.Unix())
+	}
+
+	if _, err := parseEpochSeconds("date: invalid option"); err == nil {
+		t.Error("Expected error for non-numeric output")
+	}
+}
+
+func TestClockDriftExceeded(t *testing.T) {
+	tests := []struct {
+		drift time.Duration
+		want  bool
+	}{
+		{0, false},
+		{MaxClockDrift, false},
+		{MaxClockDrift + time.Second, true},
+		{-MaxClockDrift - time.Second, true},
+		{-2 * time.Second, false},
+	}
+	for _, tt := range tests {
+		if got := ClockDriftExceeded(tt.drift); got != tt.want {
+			t.Errorf("ClockDriftExceeded(%s) = %v, want %v", tt.drift, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeClockDrift(t *testing.T) {
+	if got := DescribeClockDrift(42 * time.Second); got != "container clock is 42s ahead of the host" {
+		t.Errorf("Unexpected description: %s", got)
+	}
+	if got := DescribeClockDrift(-90 * time.Second); got != "container clock is 1m30s behind the host" {
+		t.Errorf("Unexpected description: %s", got)
+	}
+}
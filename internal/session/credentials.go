@@ -0,0 +1,89 @@
+package session
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// credentialsFileName is the tool credentials file in its config directory
+const credentialsFileName = ".credentials.json"
+
+// credentialsMayRemain reports whether a --no-credentials container may still
+// hold tool credentials: restored with a resumed session's saved data, or
+// left in a reused container by an earlier run
+func credentialsMayRemain(noCredentials, resuming, reused bool) bool {
+	return noCredentials && (resuming || reused)
+}
+
+// removeCredentialsCommand returns the shell command deleting the tool
+// credentials file from the config directory configDirName under homeDir
+func removeCredentialsCommand(homeDir, configDirName string) string {
+	return "rm -f " + container.ShellQuote(filepath.Join(homeDir, configDirName, credentialsFileName))
+}
+
+// CredentialSource is where a tool's credentials come from: the host config
+// directory (the default) or the host keyring ([tool] credential_source =
+// "keyring:<service>/<account>")
+type CredentialSource struct {
+	Keyring bool
+	Service string
+	Account string
+}
+
+// ParseCredentialSource parses a credential_source value. "" and "file" mean
+// the credentials file in the host config directory.
+func ParseCredentialSource(value string) (CredentialSource, error) {
+	if value == "" || value == "file" {
+		return CredentialSource{}, nil
+	}
+	spec, ok := strings.CutPrefix(value, "keyring:")
+	if !ok {
+		return CredentialSource{}, fmt.Errorf("invalid credential_source '%s' - must be 'file' or 'keyring:<service>/<account>'", value)
+	}
+	// Services may contain "/", accounts (user names, emails) don't
+	i := strings.LastIndex(spec, "/")
+	if i <= 0 || i == len(spec)-1 {
+		return CredentialSource{}, fmt.Errorf("invalid credential_source '%s' - expected 'keyring:<service>/<account>'", value)
+	}
+	return CredentialSource{Keyring: true, Service: spec[:i], Account: spec[i+1:]}, nil
+}
+
+// String renders the source for messages, e.g. "keyring:Claude Code-credentials/me"
+func (s CredentialSource) String() string {
+	if !s.Keyring {
+		return "file"
+	}
+	return fmt.Sprintf("keyring:%s/%s", s.Service, s.Account)
+}
+
+// ReadSecret fetches the credentials from the host keyring: the macOS Keychain
+// via security(1), or the Secret Service (GNOME Keyring, KWallet) via
+// secret-tool(1) elsewhere
+func (s CredentialSource) ReadSecret() (string, error) {
+	args := keyringLookupCommand(runtime.GOOS, s.Service, s.Account)
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return "", fmt.Errorf("cannot read credentials from the keyring: %s not found", args[0])
+	}
+	output, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from the keyring: %w", s, err)
+	}
+	secret := strings.TrimRight(string(output), "\n")
+	if secret == "" {
+		return "", fmt.Errorf("keyring entry %s is empty", s)
+	}
+	return secret, nil
+}
+
+// keyringLookupCommand is the command that prints a keyring secret on goos
+func keyringLookupCommand(goos, service, account string) []string {
+	if goos == "darwin" {
+		return []string{"security", "find-generic-password", "-s", service, "-a", account, "-w"}
+	}
+	return []string{"secret-tool", "lookup", "service", service, "account", account}
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseCredentialSource(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    CredentialSource
+		wantErr bool
+	}{
+		{"", CredentialSource{}, false},
+		{"file", CredentialSource{}, false},
+		{"keyring:coi/me", CredentialSource{Keyring: true, Service: "coi", Account: "me"}, false},
+		{"keyring:Claude Code-credentials/me@example.com", CredentialSource{Keyring: true, Service: "Claude Code-credentials", Account: "me@example.com"}, false},
+		{"keyring:org/claude/me", CredentialSource{Keyring: true, Service: "org/claude", Account: "me"}, false},
+		{"keyring:coi", CredentialSource{}, true},
+		{"keyring:/me", CredentialSource{}, true},
+		{"keyring:coi/", CredentialSource{}, true},
+		{"vault:coi/me", CredentialSource{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCredentialSource(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCredentialSource(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCredentialSource(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCredentialSourceString(t *testing.T) {
+	if got := (CredentialSource{}).String(); got != "file" {
+		t.Errorf("String() = %q, want %q", got, "file")
+	}
+	source := CredentialSource{Keyring: true, Service: "coi", Account: "me"}
+	if got := source.String(); got != "keyring:coi/me" {
+		t.Errorf("String() = %q, want %q", got, "keyring:coi/me")
+	}
+}
+
+func TestKeyringLookupCommand(t *testing.T) {
+	darwin := keyringLookupCommand("darwin", "coi", "me")
+	if want := []string{"security", "find-generic-password", "-s", "coi", "-a", "me", "-w"}; !slices.Equal(darwin, want) {
+		t.Errorf("darwin command = %v, want %v", darwin, want)
+	}
+	linux := keyringLookupCommand("linux", "coi", "me")
+	if want := []string{"secret-tool", "lookup", "service", "coi", "account", "me"}; !slices.Equal(linux, want) {
+		t.Errorf("linux command = %v, want %v", linux, want)
+	}
+}
+
+func TestCredentialsMayRemain(t *testing.T) {
+	tests := []struct {
+		noCredentials, resuming, reused bool
+		want                            bool
+	}{
+		{noCredentials: false, resuming: true, reused: true, want: false},
+		{noCredentials: true, resuming: false, reused: false, want: false}, // fresh container, nothing restored
+		{noCredentials: true, resuming: true, reused: false, want: true},   // saved session data may include them
+		{noCredentials: true, resuming: false, reused: true, want: true},   // kept by the reused container
+	}
+	for _, tt := range tests {
+		if got := credentialsMayRemain(tt.noCredentials, tt.resuming, tt.reused); got != tt.want {
+			t.Errorf("credentialsMayRemain(%v, %v, %v) = %v, want %v", tt.noCredentials, tt.resuming, tt.reused, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveCredentialsCommand(t *testing.T) {
+	got := removeCredentialsCommand("/home/code", ".claude")
+	if want := "rm -f /home/code/.claude/.credentials.json"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	got = removeCredentialsCommand("/home/my user", ".claude")
+	if want := "rm -f '/home/my user/.claude/.credentials.json'"; got != want {
+		t.Errorf("Expected quoted path %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// DeadlineConfigKey is the container config key holding a session's wall-clock
+// deadline (RFC3339). It is persisted on the container so a detached reaper
+// process can enforce --max-duration after coi shell has exited.
+const DeadlineConfigKey = "user.coi.deadline"
+
+// SetDeadline records the session deadline on the container
+func SetDeadline(mgr *container.Manager, deadline time.Time) error {
+	return mgr.SetUserConfig(DeadlineConfigKey, FormatDeadline(deadline))
+}
+
+// ClearDeadline removes any deadline left on a reused container by an earlier run
+func ClearDeadline(mgr *container.Manager) error {
+	return container.IncusExecQuiet("config", "unset", mgr.ContainerName, DeadlineConfigKey)
+}
+
+// GetDeadline reads the session deadline from the container.
+// Returns ok=false if no deadline is set.
+func GetDeadline(containerName string) (deadline time.Time, ok bool, err error) {
+	value, err := container.IncusOutput("config", "get", containerName, DeadlineConfigKey)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read deadline: %w", err)
+	}
+	return ParseDeadline(value)
+}
+
+// FormatDeadline formats a deadline the way it is stored on the container
+func FormatDeadline(deadline time.Time) string {
+	return deadline.UTC().Format(time.RFC3339)
+}
+
+// ParseDeadline parses a stored deadline. An empty value means no deadline.
+func ParseDeadline(value string) (deadline time.Time, ok bool, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false, nil
+	}
+	deadline, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid deadline %q: %w", value, err)
+	}
+	return deadline, true, nil
+}
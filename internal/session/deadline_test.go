@@ -0,0 +1,26 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDeadline(t *testing.T) {
+	deadline := time.Date(2026, 3, 1, 10, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	got, ok, err := ParseDeadline(FormatDeadline(deadline))
+	if err != nil || !ok {
+		t.Fatalf("ParseDeadline() = %v, %v, %v", got, ok, err)
+	}
+	if !got.Equal(deadline) {
+		t.Errorf("Expected %v, got %v", deadline, got)
+	}
+
+	if _, ok, err := ParseDeadline("  "); ok || err != nil {
+		t.Errorf("Expected no deadline for empty value, got ok=%v err=%v", ok, err)
+	}
+
+	if _, _, err := ParseDeadline("tomorrow"); err == nil {
+		t.Error("Expected error for invalid deadline")
+	}
+}
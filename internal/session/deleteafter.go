@@ -0,0 +1,111 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// DeleteAfterConfigKey is the container config key holding the time (RFC3339)
+// after which a stopped non-persistent container kept for delete_grace_minutes
+// may be deleted
+const DeleteAfterConfigKey = "user.coi.delete_after"
+
+// SetDeleteAfter marks a stopped container for deletion once deleteAfter passes
+func SetDeleteAfter(mgr *container.Manager, deleteAfter time.Time) error {
+	return mgr.SetUserConfig(DeleteAfterConfigKey, FormatDeadline(deleteAfter))
+}
+
+// ClearDeleteAfter removes the deletion mark from a container that is reused
+func ClearDeleteAfter(mgr *container.Manager) error {
+	return clearDeleteAfter(mgr.ContainerName)
+}
+
+func clearDeleteAfter(containerName string) error {
+	return container.IncusExecQuiet("config", "unset", containerName, DeleteAfterConfigKey)
+}
+
+// GetDeleteAfter reads the deletion time from the container.
+// Returns ok=false if the container is not marked for deletion.
+func GetDeleteAfter(containerName string) (deleteAfter time.Time, ok bool, err error) {
+	value, err := container.IncusOutput("config", "get", containerName, DeleteAfterConfigKey)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read delete_after: %w", err)
+	}
+	return ParseDeadline(value)
+}
+
+// inGracePeriod reports whether a container's config marks it as kept until
+// a deletion time that has not passed yet
+func inGracePeriod(config map[string]string, now time.Time) bool {
+	deleteAfter, ok, err := ParseDeadline(config[DeleteAfterConfigKey])
+	return err == nil && ok && now.Before(deleteAfter)
+}
+
+// expiredContainers returns the stopped containers matching prefix whose
+// grace period has passed
+func expiredContainers(containers []slotContainer, prefix string, now time.Time) []string {
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(prefix))
+
+	var expired []string
+	for _, c := range containers {
+		if !re.MatchString(c.Name) || c.Status == "Running" {
+			continue
+		}
+		deleteAfter, ok, err := ParseDeadline(c.Config[DeleteAfterConfigKey])
+		if err != nil || !ok || now.Before(deleteAfter) {
+			continue
+		}
+		expired = append(expired, c.Name)
+	}
+	return expired
+}
+
+// restartedContainers returns the running containers matching prefix that
+// still carry a deletion mark: they were started again (e.g. with
+// 'coi container start') and must not be deleted once they stop
+func restartedContainers(containers []slotContainer, prefix string) []string {
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(prefix))
+
+	var restarted []string
+	for _, c := range containers {
+		if re.MatchString(c.Name) && c.Status == "Running" && c.Config[DeleteAfterConfigKey] != "" {
+			restarted = append(restarted, c.Name)
+		}
+	}
+	return restarted
+}
+
+// DeleteExpiredContainers deletes stopped coi containers whose
+// delete_grace_minutes grace period has passed. It runs at the start of coi
+// commands so kept containers go away even if their reaper did not run.
+// Containers that were started again lose their deletion mark.
+func DeleteExpiredContainers(logger func(string)) error {
+	output, err := container.IncusOutput("list", "--format=json")
+	if err != nil {
+		return err
+	}
+
+	var containers []slotContainer
+	if err := json.Unmarshal([]byte(output), &containers); err != nil {
+		return fmt.Errorf("failed to parse container list: %w", err)
+	}
+
+	for _, name := range restartedContainers(containers, GetContainerPrefix()) {
+		if err := clearDeleteAfter(name); err != nil {
+			logger(fmt.Sprintf("Warning: Failed to clear the deletion time of restarted %s: %v", name, err))
+		}
+	}
+
+	for _, name := range expiredContainers(containers, GetContainerPrefix(), time.Now()) {
+		if err := container.NewManager(name).Delete(true); err != nil {
+			logger(fmt.Sprintf("Warning: Failed to delete %s after its grace period: %v", name, err))
+			continue
+		}
+		logger(fmt.Sprintf("Deleted stopped container %s (grace period ended)", name))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpiredContainers(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	past := FormatDeadline(now.Add(-time.Minute))
+	future := FormatDeadline(now.Add(time.Minute))
+
+	containers := []slotContainer{
+		{Name: "coi-abcd1234-1", Status: "Stopped", Config: map[string]string{DeleteAfterConfigKey: past}},
+		{Name: "coi-abcd1234-2", Status: "Stopped", Config: map[string]string{DeleteAfterConfigKey: future}}, // still in grace
+		{Name: "coi-abcd1234-3", Status: "Running", Config: map[string]string{DeleteAfterConfigKey: past}},   // restarted
+		{Name: "coi-abcd1234-4", Status: "Stopped"},                                                          // not marked
+		{Name: "coi-abcd1234-5", Status: "Stopped", Config: map[string]string{DeleteAfterConfigKey: "soon"}}, // unparseable
+		{Name: "other-1", Status: "Stopped", Config: map[string]string{DeleteAfterConfigKey: past}},          // not a coi container
+	}
+
+	got := expiredContainers(containers, "coi-", now)
+	expected := []string{"coi-abcd1234-1"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestRestartedContainers(t *testing.T) {
+	mark := FormatDeadline(time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC))
+
+	containers := []slotContainer{
+		{Name: "coi-abcd1234-1", Status: "Stopped", Config: map[string]string{DeleteAfterConfigKey: mark}}, // still kept
+		{Name: "coi-abcd1234-2", Status: "Running", Config: map[string]string{DeleteAfterConfigKey: mark}}, // started again
+		{Name: "coi-abcd1234-3", Status: "Running"},                                                        // not marked
+		{Name: "other-1", Status: "Running", Config: map[string]string{DeleteAfterConfigKey: mark}},        // not a coi container
+	}
+
+	got := restartedContainers(containers, "coi-")
+	expected := []string{"coi-abcd1234-2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestInGracePeriod(t *testing.T) {
+	now := time.Now()
+
+	if !inGracePeriod(map[string]string{DeleteAfterConfigKey: FormatDeadline(now.Add(time.Hour))}, now) {
+		t.Error("Expected container marked for later deletion to be in its grace period")
+	}
+	if inGracePeriod(map[string]string{DeleteAfterConfigKey: FormatDeadline(now.Add(-time.Hour))}, now) {
+		t.Error("Expected expired grace period not to count")
+	}
+	if inGracePeriod(nil, now) {
+		t.Error("Expected unmarked container not to be in a grace period")
+	}
+}
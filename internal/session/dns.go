@@ -0,0 +1,42 @@
+package session
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// ValidateDNSServers checks that [network] dns_servers are IP addresses
+func ValidateDNSServers(servers []string) error {
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("invalid dns_servers entry '%s' - expected an IP address", server)
+		}
+	}
+	return nil
+}
+
+// ResolvConf returns the /etc/resolv.conf contents using servers
+func ResolvConf(servers []string) string {
+	var b strings.Builder
+	b.WriteString("# Written by coi from [network] dns_servers\n")
+	for _, server := range servers {
+		fmt.Fprintf(&b, "nameserver %s\n", server)
+	}
+	return b.String()
+}
+
+// setContainerDNS replaces the container's /etc/resolv.conf with servers. A
+// symlink (systemd-resolved's stub at 127.0.0.53) is removed first so the
+// file is written in place of the link instead of through it.
+func setContainerDNS(mgr *container.Manager, servers []string) error {
+	if _, err := mgr.ExecArgsCapture([]string{"sh", "-c", "[ -L /etc/resolv.conf ] && rm -f /etc/resolv.conf; exit 0"}, container.ExecCommandOptions{}); err != nil {
+		return fmt.Errorf("failed to replace /etc/resolv.conf: %w", err)
+	}
+	if err := mgr.CreateFile("/etc/resolv.conf", ResolvConf(servers)); err != nil {
+		return fmt.Errorf("failed to write /etc/resolv.conf: %w", err)
+	}
+	return nil
+}
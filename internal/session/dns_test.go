@@ -0,0 +1,28 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDNSServers(t *testing.T) {
+	if err := ValidateDNSServers([]string{"1.1.1.1", "2606:4700:4700::1111"}); err != nil {
+		t.Errorf("Expected IPv4 and IPv6 servers to be valid, got %v", err)
+	}
+	if err := ValidateDNSServers([]string{"1.1.1.1", "dns.example.com"}); err == nil {
+		t.Error("Expected an error for a hostname")
+	}
+	if err := ValidateDNSServers(nil); err != nil {
+		t.Errorf("Expected no servers to be valid, got %v", err)
+	}
+}
+
+func TestResolvConf(t *testing.T) {
+	got := ResolvConf([]string{"1.1.1.1", "9.9.9.9"})
+	if !strings.Contains(got, "nameserver 1.1.1.1\nnameserver 9.9.9.9\n") {
+		t.Errorf("Expected both nameservers in order, got:\n%s", got)
+	}
+	if !strings.HasPrefix(got, "#") {
+		t.Errorf("Expected a comment header, got:\n%s", got)
+	}
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// sensitiveEnvMarkers flag host variable names that look like secrets. A glob
+// pattern never forwards these; naming the variable exactly is the opt-in.
+var sensitiveEnvMarkers = []string{
+	"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "PRIVATE", "API_KEY", "ACCESS_KEY",
+}
+
+// reservedEnvNames are container variables that describe the container itself
+// (or mark it as a sandbox) and are never taken from the host, even by name
+var reservedEnvNames = []string{"HOME", "PATH", "TERM", "IS_SANDBOX", "USER", "LOGNAME", "SHELL", "PWD"}
+
+// IsSensitiveEnvName reports whether an environment variable name looks like it
+// holds a secret (e.g. GITHUB_TOKEN, AWS_SECRET_ACCESS_KEY)
+func IsSensitiveEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, marker := range sensitiveEnvMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateEnvPatterns checks that every --env-passthrough pattern is a valid glob
+func ValidateEnvPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("empty --env-passthrough pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --env-passthrough pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// PassthroughEnv selects the host variables (os.Environ() format) whose names
+// match any of the glob patterns. Secret-looking names matched only by a
+// wildcard are left out and returned, sorted, as blocked; a pattern without
+// wildcards that names one forwards it. Reserved names (HOME, PATH, ...) are
+// never selected.
+func PassthroughEnv(environ, patterns []string) (env map[string]string, blocked []string) {
+	env = make(map[string]string)
+	if len(patterns) == 0 {
+		return env, nil
+	}
+
+	blockedSet := make(map[string]bool)
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" || slices.Contains(reservedEnvNames, name) {
+			continue
+		}
+
+		matched, explicit := false, false
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok { // Patterns are validated up front
+				matched = true
+				if pattern == name {
+					explicit = true
+				}
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		if IsSensitiveEnvName(name) && !explicit {
+			blockedSet[name] = true
+			continue
+		}
+		env[name] = value
+	}
+
+	for name := range blockedSet {
+		blocked = append(blocked, name)
+	}
+	sort.Strings(blocked)
+	return env, blocked
+}
+
+// AddPassthroughEnv adds the --env-passthrough variables to env without
+// replacing any variable coi already set there (HOME, TERM, IS_SANDBOX,
+// locale and proxy settings). Explicit --env values are applied afterwards.
+func AddPassthroughEnv(env, passthrough map[string]string) {
+	for name, value := range passthrough {
+		if _, set := env[name]; !set {
+			env[name] = value
+		}
+	}
+}
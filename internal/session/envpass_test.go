@@ -0,0 +1,113 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPassthroughEnv(t *testing.T) {
+	environ := []string{
+		"GIT_AUTHOR_NAME=Jane Doe",
+		"GIT_AUTHOR_EMAIL=jane@example.com",
+		"AWS_REGION=eu-west-1",
+		"AWS_SECRET_ACCESS_KEY=abc=def",
+		"AWS_SESSION_TOKEN=xyz",
+		"HOME=/home/jane",
+		"malformed",
+	}
+
+	tests := []struct {
+		name        string
+		patterns    []string
+		wantEnv     map[string]string
+		wantBlocked []string
+	}{
+		{
+			name:     "no patterns",
+			patterns: nil,
+			wantEnv:  map[string]string{},
+		},
+		{
+			name:     "git family",
+			patterns: []string{"GIT_*"},
+			wantEnv:  map[string]string{"GIT_AUTHOR_NAME": "Jane Doe", "GIT_AUTHOR_EMAIL": "jane@example.com"},
+		},
+		{
+			name:        "secrets blocked by glob",
+			patterns:    []string{"AWS_*"},
+			wantEnv:     map[string]string{"AWS_REGION": "eu-west-1"},
+			wantBlocked: []string{"AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"},
+		},
+		{
+			name:        "exact name opts in",
+			patterns:    []string{"AWS_*", "AWS_SECRET_ACCESS_KEY"},
+			wantEnv:     map[string]string{"AWS_REGION": "eu-west-1", "AWS_SECRET_ACCESS_KEY": "abc=def"},
+			wantBlocked: []string{"AWS_SESSION_TOKEN"},
+		},
+		{
+			name:     "no match",
+			patterns: []string{"NPM_*"},
+			wantEnv:  map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, blocked := PassthroughEnv(environ, tt.patterns)
+			if !reflect.DeepEqual(env, tt.wantEnv) {
+				t.Errorf("env = %v, want %v", env, tt.wantEnv)
+			}
+			if !reflect.DeepEqual(blocked, tt.wantBlocked) {
+				t.Errorf("blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestValidateEnvPatterns(t *testing.T) {
+	if err := ValidateEnvPatterns([]string{"GIT_*", "AWS_REGION", "LC_?"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := ValidateEnvPatterns([]string{"GIT_["}); err == nil {
+		t.Error("Expected error for malformed pattern")
+	}
+	if err := ValidateEnvPatterns([]string{""}); err == nil {
+		t.Error("Expected error for empty pattern")
+	}
+}
+
+func TestIsSensitiveEnvName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"GITHUB_TOKEN":      true,
+		"OPENAI_API_KEY":    true,
+		"DB_PASSWORD":       true,
+		"GIT_AUTHOR_NAME":   false,
+		"AWS_REGION":        false,
+		"aws_secret_access": true,
+	} {
+		if got := IsSensitiveEnvName(name); got != want {
+			t.Errorf("IsSensitiveEnvName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestPassthroughEnvReservedNames(t *testing.T) {
+	environ := []string{"HOME=/home/me", "PATH=/usr/local/bin:/usr/bin", "TERM=xterm-kitty", "IS_SANDBOX=0", "EDITOR=vim"}
+
+	for _, patterns := range [][]string{{"*"}, {"HOME", "PATH", "TERM", "IS_SANDBOX", "EDITOR"}} {
+		env, _ := PassthroughEnv(environ, patterns)
+		if want := map[string]string{"EDITOR": "vim"}; !reflect.DeepEqual(env, want) {
+			t.Errorf("PassthroughEnv(%v) = %v, want %v", patterns, env, want)
+		}
+	}
+}
+
+func TestAddPassthroughEnv(t *testing.T) {
+	env := map[string]string{"HOME": "/home/code", "TERM": "xterm-256color", "IS_SANDBOX": "1", "LANG": "C.UTF-8"}
+	AddPassthroughEnv(env, map[string]string{"LANG": "de_DE.UTF-8", "IS_SANDBOX": "0", "EDITOR": "vim"})
+
+	want := map[string]string{"HOME": "/home/code", "TERM": "xterm-256color", "IS_SANDBOX": "1", "LANG": "C.UTF-8", "EDITOR": "vim"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("env = %v, want %v", env, want)
+	}
+}
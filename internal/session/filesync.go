@@ -0,0 +1,148 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// SyncMapping maps a host file to a path in the container (coi sync)
+type SyncMapping struct {
+	HostPath      string
+	ContainerPath string
+}
+
+// ParseSyncMapping parses a "host-file:container-path" argument. The host path
+// is made absolute; the container path must be absolute.
+func ParseSyncMapping(arg string) (SyncMapping, error) {
+	hostPath, containerPath, ok := strings.Cut(arg, ":")
+	if !ok || hostPath == "" || containerPath == "" {
+		return SyncMapping{}, fmt.Errorf("invalid mapping '%s' - expected <host-file>:<container-path>", arg)
+	}
+	if !strings.HasPrefix(containerPath, "/") {
+		return SyncMapping{}, fmt.Errorf("invalid mapping '%s' - container path must be absolute", arg)
+	}
+
+	absHost, err := filepath.Abs(hostPath)
+	if err != nil {
+		return SyncMapping{}, fmt.Errorf("invalid host path '%s': %w", hostPath, err)
+	}
+	info, err := os.Stat(absHost)
+	if err != nil {
+		return SyncMapping{}, fmt.Errorf("host file '%s' not found: %w", hostPath, err)
+	}
+	if !info.Mode().IsRegular() {
+		return SyncMapping{}, fmt.Errorf("'%s' is not a regular file", hostPath)
+	}
+
+	return SyncMapping{HostPath: absHost, ContainerPath: filepath.Clean(containerPath)}, nil
+}
+
+// SyncParentDirsCommand returns a shell command creating the missing parent
+// directories of containerPath owned by uid, so the code user can write next
+// to the synced file. Existing directories are left alone.
+func SyncParentDirsCommand(containerPath string, uid int) string {
+	var dirs []string
+	for dir := path.Dir(containerPath); dir != "/" && dir != "."; dir = path.Dir(dir) {
+		dirs = append([]string{container.ShellQuote(dir)}, dirs...)
+	}
+	if len(dirs) == 0 {
+		return "true"
+	}
+	return fmt.Sprintf(`for d in %s; do [ -d "$d" ] || { mkdir "$d" && chown %d:%d "$d"; } || exit 1; done`,
+		strings.Join(dirs, " "), uid, uid)
+}
+
+// fileState identifies a version of a file; missing files have exists unset
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
+
+// WatchFiles polls paths every interval and calls onChange with a path once
+// it has changed and then stayed unchanged for debounce, so an editor saving
+// in several writes (or replacing the file) triggers one call. A file that is
+// missing is not reported until it reappears. WatchFiles returns when ctx is
+// cancelled.
+func WatchFiles(ctx context.Context, paths []string, interval, debounce time.Duration, onChange func(path string)) {
+	// Polling rather than inotify keeps coi free of platform-specific watchers,
+	// and follows editors that save by renaming a new file over the old one
+	watcher := newFileWatcher(paths, debounce)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, path := range watcher.poll(now) {
+				onChange(path)
+			}
+		}
+	}
+}
+
+// fileWatcher tracks the state of watched files between polls
+type fileWatcher struct {
+	paths     []string
+	debounce  time.Duration
+	synced    map[string]fileState // State last reported (or seen at start)
+	pending   map[string]fileState // Changed state waiting to settle
+	changedAt map[string]time.Time // When the pending state was first seen
+}
+
+func newFileWatcher(paths []string, debounce time.Duration) *fileWatcher {
+	w := &fileWatcher{
+		paths:     paths,
+		debounce:  debounce,
+		synced:    make(map[string]fileState, len(paths)),
+		pending:   make(map[string]fileState),
+		changedAt: make(map[string]time.Time),
+	}
+	for _, path := range paths {
+		w.synced[path] = statFile(path)
+	}
+	return w
+}
+
+// poll checks the files at time now and returns those whose change has
+// settled since the last poll
+func (w *fileWatcher) poll(now time.Time) []string {
+	var changed []string
+	for _, path := range w.paths {
+		state := statFile(path)
+		if state == w.synced[path] {
+			delete(w.pending, path)
+			continue
+		}
+		if last, ok := w.pending[path]; !ok || state != last {
+			w.pending[path] = state
+			w.changedAt[path] = now
+			continue
+		}
+		if now.Sub(w.changedAt[path]) < w.debounce || !state.exists {
+			continue
+		}
+		w.synced[path] = state
+		delete(w.pending, path)
+		changed = append(changed, path)
+	}
+	return changed
+}
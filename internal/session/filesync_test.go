@@ -0,0 +1,117 @@
+package session
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSyncMapping(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "prompt.md")
+	if err := os.WriteFile(file, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mapping, err := ParseSyncMapping(file + ":/home/code/prompt.md")
+	if err != nil {
+		t.Fatalf("ParseSyncMapping failed: %v", err)
+	}
+	if mapping.HostPath != file || mapping.ContainerPath != "/home/code/prompt.md" {
+		t.Errorf("Unexpected mapping: %+v", mapping)
+	}
+
+	tests := []struct {
+		arg     string
+		errPart string
+	}{
+		{file, "expected <host-file>:<container-path>"},
+		{file + ":relative/path", "must be absolute"},
+		{filepath.Join(dir, "missing") + ":/tmp/x", "not found"},
+		{dir + ":/tmp/x", "not a regular file"},
+	}
+	for _, tt := range tests {
+		_, err := ParseSyncMapping(tt.arg)
+		if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+			t.Errorf("ParseSyncMapping(%q) error = %v, expected it to contain %q", tt.arg, err, tt.errPart)
+		}
+	}
+}
+
+func TestSyncParentDirsCommand(t *testing.T) {
+	tests := []struct {
+		containerPath string
+		expected      string
+	}{
+		{"/home/code/.config/app/env", `for d in /home /home/code /home/code/.config /home/code/.config/app; do [ -d "$d" ] || { mkdir "$d" && chown 1000:1000 "$d"; } || exit 1; done`},
+		{"/tmp/my notes/n.md", `for d in /tmp '/tmp/my notes'; do [ -d "$d" ] || { mkdir "$d" && chown 1000:1000 "$d"; } || exit 1; done`},
+		{"/file", "true"},
+	}
+	for _, tt := range tests {
+		if got := SyncParentDirsCommand(tt.containerPath, 1000); got != tt.expected {
+			t.Errorf("SyncParentDirsCommand(%q) = %q, want %q", tt.containerPath, got, tt.expected)
+		}
+	}
+}
+
+func TestFileWatcherDebounces(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "env")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("A=1\n")
+
+	start := time.Now()
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	w := newFileWatcher([]string{file}, 100*time.Millisecond)
+
+	if changed := w.poll(at(10)); len(changed) != 0 {
+		t.Errorf("Expected no change before any write, got %v", changed)
+	}
+
+	// Several quick writes, like an editor saving in steps: each restarts the debounce
+	for i, content := range []string{"A=2\n", "A=22\n", "A=222\n"} {
+		write(content)
+		if changed := w.poll(at(20 + 20*i)); len(changed) != 0 {
+			t.Errorf("Expected no change while the file is being written, got %v", changed)
+		}
+	}
+	if changed := w.poll(at(120)); len(changed) != 0 {
+		t.Errorf("Expected no change before the debounce passed, got %v", changed)
+	}
+	if changed := w.poll(at(160)); !slices.Equal(changed, []string{file}) {
+		t.Errorf("Expected one change once the file settled, got %v", changed)
+	}
+	if changed := w.poll(at(300)); len(changed) != 0 {
+		t.Errorf("Expected the change to be reported once, got %v", changed)
+	}
+
+	// A removed file is not reported until it reappears
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	w.poll(at(400))
+	if changed := w.poll(at(600)); len(changed) != 0 {
+		t.Errorf("Expected a missing file not to be reported, got %v", changed)
+	}
+	write("A=3\n")
+	w.poll(at(700))
+	if changed := w.poll(at(800)); !slices.Equal(changed, []string{file}) {
+		t.Errorf("Expected the recreated file to be reported, got %v", changed)
+	}
+}
+
+func TestWatchFilesStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	// Must return right away for a cancelled context
+	WatchFiles(ctx, nil, time.Hour, time.Hour, func(string) { t.Error("Unexpected change") })
+}
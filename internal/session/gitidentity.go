@@ -0,0 +1,112 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// maxGitIgnoreSize bounds the host global gitignore copied into containers
+const maxGitIgnoreSize = 256 * 1024
+
+// GitIdentity is the host's global git identity copied into containers with
+// [defaults] sync_git_identity. Credentials (credential helpers, tokens) are
+// deliberately not part of it - use --ssh-agent for pushing.
+type GitIdentity struct {
+	Name      string
+	Email     string
+	GitIgnore string // Contents of the global excludes file, if any
+}
+
+// Empty reports whether there is nothing to copy
+func (g GitIdentity) Empty() bool {
+	return g.Name == "" && g.Email == "" && g.GitIgnore == ""
+}
+
+// HostGitIdentity reads user.name, user.email and the global excludes file
+// (core.excludesFile, or git's default ~/.config/git/ignore) from the host
+func HostGitIdentity() GitIdentity {
+	if _, err := exec.LookPath("git"); err != nil {
+		return GitIdentity{}
+	}
+
+	identity := GitIdentity{
+		Name:  hostGitConfig("user.name"),
+		Email: hostGitConfig("user.email"),
+	}
+
+	excludesFile := hostGitConfig("--path", "core.excludesFile")
+	if excludesFile == "" {
+		if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+			excludesFile = filepath.Join(configHome, "git", "ignore")
+		} else if homeDir, err := os.UserHomeDir(); err == nil {
+			excludesFile = filepath.Join(homeDir, ".config", "git", "ignore")
+		}
+	}
+	if info, err := os.Stat(excludesFile); err == nil && info.Mode().IsRegular() && info.Size() <= maxGitIgnoreSize {
+		if data, err := os.ReadFile(excludesFile); err == nil {
+			identity.GitIgnore = string(data)
+		}
+	}
+
+	return identity
+}
+
+// hostGitConfig returns a global git config value, or "" if unset
+func hostGitConfig(args ...string) string {
+	cmdArgs := append([]string{"config", "--global", "--get"}, args...)
+	output, err := exec.Command("git", cmdArgs...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// gitIdentityArgs returns the git config commands that apply identity
+func gitIdentityArgs(identity GitIdentity) [][]string {
+	var commands [][]string
+	if identity.Name != "" {
+		commands = append(commands, []string{"git", "config", "--global", "user.name", identity.Name})
+	}
+	if identity.Email != "" {
+		commands = append(commands, []string{"git", "config", "--global", "user.email", identity.Email})
+	}
+	return commands
+}
+
+// applyGitIdentity writes identity into the global git config of the user the
+// tool runs as (uid, with home directory homeDir). The gitignore goes to git's
+// default excludes location, so no core.excludesFile path is needed.
+func applyGitIdentity(mgr *container.Manager, identity GitIdentity, homeDir string, uid int) error {
+	opts := container.ExecCommandOptions{User: &uid, Env: map[string]string{"HOME": homeDir}}
+
+	if _, err := mgr.ExecArgsCapture([]string{"sh", "-c", "command -v git"}, opts); err != nil {
+		return fmt.Errorf("git is not installed in the container")
+	}
+
+	for _, args := range gitIdentityArgs(identity) {
+		if output, err := mgr.ExecArgsCapture(args, opts); err != nil {
+			return fmt.Errorf("failed to run %s: %w (%s)", strings.Join(args[:4], " "), err, strings.TrimSpace(output))
+		}
+	}
+
+	if identity.GitIgnore != "" {
+		ignoreDir := filepath.Join(homeDir, ".config", "git")
+		if _, err := mgr.ExecArgsCapture([]string{"mkdir", "-p", ignoreDir}, opts); err != nil {
+			return fmt.Errorf("failed to create %s: %w", ignoreDir, err)
+		}
+		ignorePath := filepath.Join(ignoreDir, "ignore")
+		if err := mgr.CreateFile(ignorePath, identity.GitIgnore); err != nil {
+			return fmt.Errorf("failed to copy global gitignore: %w", err)
+		}
+		if err := mgr.Chown(ignorePath, uid, uid); err != nil {
+			return fmt.Errorf("failed to set ownership of %s: %w", ignorePath, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGitIdentityArgs(t *testing.T) {
+	identity := GitIdentity{Name: "Jane O'Doe", Email: "jane@example.com"}
+	want := [][]string{
+		{"git", "config", "--global", "user.name", "Jane O'Doe"},
+		{"git", "config", "--global", "user.email", "jane@example.com"},
+	}
+	if got := gitIdentityArgs(identity); !reflect.DeepEqual(got, want) {
+		t.Errorf("gitIdentityArgs() = %v, want %v", got, want)
+	}
+
+	if got := gitIdentityArgs(GitIdentity{Email: "jane@example.com"}); len(got) != 1 || got[0][3] != "user.email" {
+		t.Errorf("expected only user.email to be set, got %v", got)
+	}
+}
+
+func TestGitIdentityEmpty(t *testing.T) {
+	if !(GitIdentity{}).Empty() {
+		t.Error("expected zero identity to be empty")
+	}
+	if (GitIdentity{GitIgnore: ".DS_Store\n"}).Empty() {
+		t.Error("expected identity with only a gitignore not to be empty")
+	}
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// HomeCacheDevice is the Incus disk device mounting a workspace's persistent
+// cache directory at ~/.cache (coi shell --mount-home)
+const HomeCacheDevice = "home-cache"
+
+// HomeCachesDir returns the host directory holding the persistent caches of
+// all workspaces
+func HomeCachesDir(baseDir string) string {
+	return filepath.Join(baseDir, "caches")
+}
+
+// HomeCacheDir returns the host directory mounted at ~/.cache for a
+// workspace, shared by all its sessions and slots
+func HomeCacheDir(baseDir, workspacePath string) string {
+	return filepath.Join(HomeCachesDir(baseDir), WorkspaceHash(workspacePath))
+}
+
+// deviceContainer is the part of 'incus list --format=json' output needed to
+// find the host directories a container mounts
+type deviceContainer struct {
+	Name    string                       `json:"name"`
+	Devices map[string]map[string]string `json:"devices"`
+}
+
+// MountedHomeCaches returns the names of the cache directories under
+// HomeCachesDir(baseDir) that a container mounts, whatever its name (e.g.
+// created with --name), running or stopped
+func MountedHomeCaches(baseDir string) (map[string]bool, error) {
+	output, err := container.IncusOutput("list", "--format=json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var containers []deviceContainer
+	if err := json.Unmarshal([]byte(output), &containers); err != nil {
+		return nil, fmt.Errorf("failed to parse container list: %w", err)
+	}
+	return mountedHomeCaches(containers, HomeCachesDir(baseDir)), nil
+}
+
+// mountedHomeCaches returns the names of the directories directly under
+// cachesDir that are (or contain) the source of a container's disk device
+func mountedHomeCaches(containers []deviceContainer, cachesDir string) map[string]bool {
+	mounted := make(map[string]bool)
+	for _, c := range containers {
+		for _, device := range c.Devices {
+			if device["type"] != "disk" || device["source"] == "" {
+				continue
+			}
+			rel, err := filepath.Rel(cachesDir, filepath.Clean(device["source"]))
+			if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			mounted[strings.Split(rel, string(filepath.Separator))[0]] = true
+		}
+	}
+	return mounted
+}
@@ -0,0 +1,38 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMountedHomeCaches(t *testing.T) {
+	output := `[
+  {"name":"coi-abcd1234-1","devices":{
+    "workspace":{"type":"disk","source":"/home/u/project","path":"/workspace"},
+    "home-cache":{"type":"disk","source":"/home/u/.coi/caches/abcd1234","path":"/home/code/.cache"}}},
+  {"name":"my-named-box","status":"Stopped","devices":{
+    "home-cache":{"type":"disk","source":"/home/u/.coi/caches/ef567890/","path":"/home/code/.cache"}}},
+  {"name":"other","devices":{
+    "data":{"type":"disk","source":"/home/u/.coi/caches/11112222/pip","path":"/data"},
+    "eth1":{"type":"nic","source":"/home/u/.coi/caches/33334444"},
+    "all":{"type":"disk","source":"/home/u/.coi/caches","path":"/caches"},
+    "sibling":{"type":"disk","source":"/home/u/.coi/caches-old/55556666","path":"/old"}}},
+  {"name":"bare"}
+]`
+
+	var containers []deviceContainer
+	if err := json.Unmarshal([]byte(output), &containers); err != nil {
+		t.Fatal(err)
+	}
+
+	got := mountedHomeCaches(containers, HomeCachesDir("/home/u/.coi"))
+	want := map[string]bool{
+		"abcd1234": true,
+		"ef567890": true, // Container named with --name
+		"11112222": true, // Subdirectory of a cache
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -2,7 +2,33 @@ package session
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+// SessionIDGenerator produces a new random session ID
+type SessionIDGenerator func() (string, error)
+
+// maxSessionIDAttempts bounds how often UniqueSessionID regenerates on collision
+const maxSessionIDAttempts = 10
+
+// friendlyAdjectives and friendlyNouns make up GenerateFriendlySessionID IDs
+var (
+	friendlyAdjectives = []string{
+		"amber", "bold", "brave", "bright", "calm", "clever", "cosmic", "crisp", "eager", "fancy",
+		"gentle", "golden", "happy", "hidden", "jolly", "keen", "lively", "lucky", "mellow", "misty",
+		"noble", "proud", "quick", "quiet", "rapid", "rusty", "shiny", "silent", "snowy", "solid",
+		"steady", "sunny", "swift", "tidy", "vivid", "warm", "wild", "wise", "witty", "zesty",
+	}
+	friendlyNouns = []string{
+		"badger", "beacon", "breeze", "canyon", "cedar", "comet", "coral", "falcon", "fern", "forest",
+		"harbor", "heron", "island", "lagoon", "lantern", "maple", "meadow", "meteor", "otter", "panda",
+		"pebble", "pine", "planet", "raven", "reef", "river", "rocket", "sparrow", "summit", "tiger",
+		"tulip", "valley", "walrus", "willow", "wolf", "yak", "zebra", "glacier", "orchid", "quartz",
+	}
 )
 
 // GenerateSessionID creates a new session ID in UUID format
@@ -26,3 +52,45 @@ func GenerateSessionID() (string, error) {
 		bytes[10:16],
 	), nil
 }
+
+// GenerateFriendlySessionID creates an easy to type session ID such as
+// "swift-otter-4821" (adjective-noun-number)
+func GenerateFriendlySessionID() (string, error) {
+	adjective, err := randomIndex(len(friendlyAdjectives))
+	if err != nil {
+		return "", err
+	}
+	noun, err := randomIndex(len(friendlyNouns))
+	if err != nil {
+		return "", err
+	}
+	number, err := randomIndex(10000)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s-%04d", friendlyAdjectives[adjective], friendlyNouns[noun], number), nil
+}
+
+func randomIndex(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return int(i.Int64()), nil
+}
+
+// UniqueSessionID generates IDs with generate until one has no directory in
+// sessionsDir yet (saved or just started sessions), so a new session never
+// overwrites an existing one
+func UniqueSessionID(sessionsDir string, generate SessionIDGenerator) (string, error) {
+	for attempt := 0; attempt < maxSessionIDAttempts; attempt++ {
+		id, err := generate()
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(filepath.Join(sessionsDir, id)); errors.Is(err, os.ErrNotExist) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("failed to generate an unused session ID after %d attempts", maxSessionIDAttempts)
+}
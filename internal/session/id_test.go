@@ -1,6 +1,9 @@
 package session
 
 import (
+	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
 )
 
@@ -92,3 +95,50 @@ func TestGenerateSessionIDFormat(t *testing.T) {
 		t.Errorf("UUID variant incorrect: expected 8/9/a/b at position 19, got '%c'", variant)
 	}
 }
+
+func TestGenerateFriendlySessionID(t *testing.T) {
+	pattern := regexp.MustCompile(`^[a-z]+-[a-z]+-\d{4}$`)
+	for i := 0; i < 20; i++ {
+		id, err := GenerateFriendlySessionID()
+		if err != nil {
+			t.Fatalf("GenerateFriendlySessionID() failed: %v", err)
+		}
+		if !pattern.MatchString(id) {
+			t.Errorf("GenerateFriendlySessionID() = %s, want adjective-noun-number", id)
+		}
+	}
+}
+
+func TestUniqueSessionIDRegeneratesOnCollision(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(sessionsDir, "taken"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	candidates := []string{"taken", "free"}
+	generate := func() (string, error) {
+		id := candidates[0]
+		candidates = candidates[1:]
+		return id, nil
+	}
+
+	id, err := UniqueSessionID(sessionsDir, generate)
+	if err != nil {
+		t.Fatalf("UniqueSessionID() unexpected error: %v", err)
+	}
+	if id != "free" {
+		t.Errorf("Expected the colliding ID to be skipped, got %s", id)
+	}
+}
+
+func TestUniqueSessionIDGivesUp(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(sessionsDir, "taken"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := UniqueSessionID(sessionsDir, func() (string, error) { return "taken", nil })
+	if err == nil {
+		t.Error("Expected an error when every generated ID collides")
+	}
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LabelConfigPrefix is the Incus config key prefix under which session labels are stored
+const LabelConfigPrefix = "user.coi.label."
+
+// labelKeyPattern matches label keys that are valid as part of an Incus config key
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ParseLabels parses --label key=value pairs into a key -> value map
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid label '%s' - expected key=value", pair)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		if !labelKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid label key '%s' - use letters, digits, '-', '_' and '.'", key)
+		}
+		labels[key] = parts[1]
+	}
+	return labels, nil
+}
+
+// LabelsFromConfig extracts session labels from an Incus container config map
+// (as returned by incus list --format=json)
+func LabelsFromConfig(config map[string]interface{}) map[string]string {
+	labels := make(map[string]string)
+	for key, value := range config {
+		if !strings.HasPrefix(key, LabelConfigPrefix) {
+			continue
+		}
+		if str, ok := value.(string); ok {
+			labels[strings.TrimPrefix(key, LabelConfigPrefix)] = str
+		}
+	}
+	return labels
+}
+
+// MatchLabels reports whether labels contain every key=value pair in filter
+func MatchLabels(labels, filter map[string]string) bool {
+	for key, value := range filter {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatLabels renders labels as a sorted, comma-separated key=value list
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
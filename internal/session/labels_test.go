@@ -0,0 +1,76 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]string{"task=refactor", "owner=team.a", "note=a=b", "empty="})
+	if err != nil {
+		t.Fatalf("ParseLabels() unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"task":  "refactor",
+		"owner": "team.a",
+		"note":  "a=b",
+		"empty": "",
+	}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected %v, got %v", expected, labels)
+	}
+
+	invalid := []string{"novalue", "=x", "bad key=1", "-task=1", "task/x=1"}
+	for _, pair := range invalid {
+		if _, err := ParseLabels([]string{pair}); err == nil {
+			t.Errorf("Expected error for '%s'", pair)
+		}
+	}
+}
+
+func TestLabelsFromConfig(t *testing.T) {
+	config := map[string]interface{}{
+		"image.description":         "coi",
+		PersistentConfigKey:         "true",
+		LabelConfigPrefix + "task":  "refactor",
+		LabelConfigPrefix + "owner": "alice",
+	}
+
+	expected := map[string]string{"task": "refactor", "owner": "alice"}
+	if labels := LabelsFromConfig(config); !reflect.DeepEqual(labels, expected) {
+		t.Errorf("Expected %v, got %v", expected, labels)
+	}
+
+	if labels := LabelsFromConfig(nil); len(labels) != 0 {
+		t.Errorf("Expected no labels for nil config, got %v", labels)
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"task": "refactor", "owner": "alice"}
+
+	tests := []struct {
+		filter   map[string]string
+		expected bool
+	}{
+		{nil, true},
+		{map[string]string{"task": "refactor"}, true},
+		{map[string]string{"task": "refactor", "owner": "alice"}, true},
+		{map[string]string{"task": "tests"}, false},
+		{map[string]string{"missing": ""}, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchLabels(labels, tt.filter); got != tt.expected {
+			t.Errorf("MatchLabels(%v): expected %v, got %v", tt.filter, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	got := FormatLabels(map[string]string{"task": "refactor", "owner": "alice"})
+	if got != "owner=alice, task=refactor" {
+		t.Errorf("Expected sorted labels, got '%s'", got)
+	}
+}
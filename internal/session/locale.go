@@ -0,0 +1,81 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// timezonePattern matches IANA zone names like "Europe/Warsaw" or "Etc/GMT+2"
+var timezonePattern = regexp.MustCompile(`^[A-Za-z0-9_+\-]+(/[A-Za-z0-9_+\-]+)*$`)
+
+// localePattern matches locale names like "en_US.UTF-8" or "C.UTF-8"
+var localePattern = regexp.MustCompile(`^[A-Za-z0-9_.@\-]+$`)
+
+// HostTimezone returns the host's IANA timezone name from TZ, /etc/timezone or
+// the /etc/localtime symlink, or "" if it cannot be determined
+func HostTimezone() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); ValidTimezone(tz) {
+		return tz
+	}
+
+	if data, err := os.ReadFile("/etc/timezone"); err == nil {
+		if tz := strings.TrimSpace(string(data)); ValidTimezone(tz) {
+			return tz
+		}
+	}
+
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		return timezoneFromLocaltime(target)
+	}
+
+	return ""
+}
+
+// timezoneFromLocaltime extracts the zone name from an /etc/localtime symlink
+// target such as /usr/share/zoneinfo/Europe/Warsaw
+func timezoneFromLocaltime(target string) string {
+	idx := strings.Index(target, "zoneinfo/")
+	if idx == -1 {
+		return ""
+	}
+	tz := target[idx+len("zoneinfo/"):]
+	if !ValidTimezone(tz) {
+		return ""
+	}
+	return tz
+}
+
+// ValidTimezone reports whether tz looks like an IANA zone name (and is safe to
+// use in a shell command)
+func ValidTimezone(tz string) bool {
+	return tz != "" && !strings.Contains(tz, "..") && timezonePattern.MatchString(tz)
+}
+
+// LocaleEnv returns the LANG/LC_ALL environment for a locale, or nil if unset
+func LocaleEnv(locale string) (map[string]string, error) {
+	if locale == "" {
+		return nil, nil
+	}
+	if !localePattern.MatchString(locale) {
+		return nil, fmt.Errorf("invalid locale %q (expected e.g. C.UTF-8 or en_US.UTF-8)", locale)
+	}
+	return map[string]string{"LANG": locale, "LC_ALL": locale}, nil
+}
+
+// setContainerTimezone points /etc/localtime at the given zone inside the container
+func setContainerTimezone(mgr *container.Manager, tz string) error {
+	if !ValidTimezone(tz) {
+		return fmt.Errorf("invalid timezone %q", tz)
+	}
+
+	root := 0
+	cmd := fmt.Sprintf("test -f /usr/share/zoneinfo/%[1]s && ln -sf /usr/share/zoneinfo/%[1]s /etc/localtime && echo %[1]s > /etc/timezone", tz)
+	if _, err := mgr.ExecCommand(cmd, container.ExecCommandOptions{User: &root, Capture: true}); err != nil {
+		return fmt.Errorf("timezone %s is not available in the container (is tzdata installed?)", tz)
+	}
+	return nil
+}
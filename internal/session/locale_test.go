@@ -0,0 +1,68 @@
+package session
+
+import "testing"
+
+func TestValidTimezone(t *testing.T) {
+	tests := []struct {
+		tz    string
+		valid bool
+	}{
+		{"Europe/Warsaw", true},
+		{"America/Argentina/Buenos_Aires", true},
+		{"Etc/GMT+2", true},
+		{"UTC", true},
+		{"", false},
+		{"../../etc/passwd", false},
+		{"Europe/Warsaw; rm -rf /", false},
+		{"/Europe/Warsaw", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidTimezone(tt.tz); got != tt.valid {
+			t.Errorf("ValidTimezone(%q) = %v, want %v", tt.tz, got, tt.valid)
+		}
+	}
+}
+
+func TestTimezoneFromLocaltime(t *testing.T) {
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"/usr/share/zoneinfo/Europe/Warsaw", "Europe/Warsaw"},
+		{"../usr/share/zoneinfo/UTC", "UTC"},
+		{"/var/db/timezone/zoneinfo/America/New_York", "America/New_York"},
+		{"/etc/localtime.bak", ""},
+	}
+
+	for _, tt := range tests {
+		if got := timezoneFromLocaltime(tt.target); got != tt.expected {
+			t.Errorf("timezoneFromLocaltime(%q) = %q, want %q", tt.target, got, tt.expected)
+		}
+	}
+}
+
+func TestHostTimezoneFromEnv(t *testing.T) {
+	t.Setenv("TZ", ":Asia/Tokyo")
+	if got := HostTimezone(); got != "Asia/Tokyo" {
+		t.Errorf("HostTimezone() = %q, want Asia/Tokyo", got)
+	}
+}
+
+func TestLocaleEnv(t *testing.T) {
+	env, err := LocaleEnv("en_US.UTF-8")
+	if err != nil {
+		t.Fatalf("LocaleEnv() unexpected error: %v", err)
+	}
+	if env["LANG"] != "en_US.UTF-8" || env["LC_ALL"] != "en_US.UTF-8" {
+		t.Errorf("Unexpected locale env: %v", env)
+	}
+
+	if env, err := LocaleEnv(""); err != nil || env != nil {
+		t.Errorf("Expected no env for empty locale, got %v, %v", env, err)
+	}
+
+	if _, err := LocaleEnv("en_US.UTF-8 x"); err == nil {
+		t.Error("Expected error for locale with spaces")
+	}
+}
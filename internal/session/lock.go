@@ -0,0 +1,95 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// SlotLock is a host-side advisory lock (flock) that serializes slot allocation
+// and container creation for one workspace, so concurrent 'coi shell' runs
+// cannot pick the same slot between AllocateSlot and Setup creating the container.
+type SlotLock struct {
+	file *os.File
+}
+
+// LockWorkspaceSlots acquires the slot lock for a workspace, blocking until any
+// other coi process holding it releases it. The lock file lives in ~/.coi/locks.
+func LockWorkspaceSlots(workspacePath string) (*SlotLock, error) {
+	file, err := lockHostFile(WorkspaceHash(workspacePath) + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	return &SlotLock{file: file}, nil
+}
+
+// Release releases the lock. Safe to call more than once and on a nil lock.
+func (l *SlotLock) Release() {
+	if l == nil {
+		return
+	}
+	unlockHostFile(l.file)
+	l.file = nil
+}
+
+// CleanupLock is a host-side advisory lock (flock) held while a container's
+// session is saved and the container stopped or deleted, so 'coi shell' and a
+// detached 'coi reap' do not clean up the same container at the same time.
+type CleanupLock struct {
+	file *os.File
+}
+
+// LockContainerCleanup acquires the cleanup lock for a container, blocking
+// until any other coi process cleaning it up is done
+func LockContainerCleanup(containerName string) (*CleanupLock, error) {
+	file, err := lockHostFile("cleanup-" + containerName + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	return &CleanupLock{file: file}, nil
+}
+
+// Release releases the lock. Safe to call more than once and on a nil lock.
+func (l *CleanupLock) Release() {
+	if l == nil {
+		return
+	}
+	unlockHostFile(l.file)
+	l.file = nil
+}
+
+// lockHostFile opens ~/.coi/locks/<name> and takes an exclusive flock on it
+func lockHostFile(name string) (*os.File, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	locksDir := filepath.Join(homeDir, ".coi", "locks")
+	if err := os.MkdirAll(locksDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create locks directory: %w", err)
+	}
+
+	lockPath := filepath.Join(locksDir, name)
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+	}
+	return file, nil
+}
+
+// unlockHostFile drops the flock taken by lockHostFile. file may be nil.
+func unlockHostFile(file *os.File) {
+	if file == nil {
+		return
+	}
+	// Closing the file also drops the flock
+	_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	_ = file.Close()
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockWorkspaceSlots(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	first, err := LockWorkspaceSlots("/home/user/project")
+	if err != nil {
+		t.Fatalf("LockWorkspaceSlots() unexpected error: %v", err)
+	}
+
+	acquired := make(chan *SlotLock)
+	go func() {
+		second, err := LockWorkspaceSlots("/home/user/project")
+		if err != nil {
+			t.Errorf("LockWorkspaceSlots() unexpected error: %v", err)
+		}
+		acquired <- second
+	}()
+
+	// A second lock for the same workspace must wait for the first
+	select {
+	case <-acquired:
+		t.Fatal("Expected second lock to block while the first is held")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	first.Release()
+	first.Release() // Releasing twice is a no-op
+
+	select {
+	case second := <-acquired:
+		second.Release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected second lock to be acquired after release")
+	}
+
+	// Different workspaces do not block each other
+	a, err := LockWorkspaceSlots("/home/user/a")
+	if err != nil {
+		t.Fatalf("LockWorkspaceSlots() unexpected error: %v", err)
+	}
+	defer a.Release()
+	b, err := LockWorkspaceSlots("/home/user/b")
+	if err != nil {
+		t.Fatalf("LockWorkspaceSlots() unexpected error: %v", err)
+	}
+	b.Release()
+
+	var nilLock *SlotLock
+	nilLock.Release()
+}
+
+func TestLockContainerCleanup(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	first, err := LockContainerCleanup("coi-abc-1")
+	if err != nil {
+		t.Fatalf("LockContainerCleanup() unexpected error: %v", err)
+	}
+
+	acquired := make(chan *CleanupLock)
+	go func() {
+		second, err := LockContainerCleanup("coi-abc-1")
+		if err != nil {
+			t.Errorf("LockContainerCleanup() unexpected error: %v", err)
+		}
+		acquired <- second
+	}()
+
+	// Cleanup of another container is not blocked
+	other, err := LockContainerCleanup("coi-abc-2")
+	if err != nil {
+		t.Fatalf("LockContainerCleanup() unexpected error: %v", err)
+	}
+	other.Release()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected second lock to block while the first is held")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	first.Release()
+	select {
+	case second := <-acquired:
+		second.Release()
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected second lock to be acquired after release")
+	}
+
+	var nilLock *CleanupLock
+	nilLock.Release()
+}
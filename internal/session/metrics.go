@@ -0,0 +1,139 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Session exit reasons recorded in metrics
+const (
+	ExitReasonExited      = "exited"      // Tool exited normally
+	ExitReasonInterrupted = "interrupted" // Ctrl+C / SIGINT
+	ExitReasonShutdown    = "shutdown"    // Container was shut down from within
+	ExitReasonDetached    = "detached"    // Background session, coi returned immediately
+	ExitReasonError       = "error"       // Tool or exec failed
+)
+
+// MetricRecord is one line of ~/.coi/metrics.jsonl, written when a session ends
+type MetricRecord struct {
+	SessionID        string    `json:"session_id"`
+	Tool             string    `json:"tool"`
+	NetworkMode      string    `json:"network_mode"`
+	StartedAt        time.Time `json:"started_at"`
+	DurationSeconds  float64   `json:"duration_seconds"`
+	ExitReason       string    `json:"exit_reason"`
+	Resumed          bool      `json:"resumed"`
+	ImageFingerprint string    `json:"image_fingerprint,omitempty"`
+}
+
+// MetricsSummary aggregates metric records for 'coi metrics'
+type MetricsSummary struct {
+	Sessions        int
+	Days            int // Distinct days with at least one session
+	SessionsPerDay  float64
+	AverageDuration time.Duration
+	MostUsedTool    string
+	ExitReasons     map[string]int
+}
+
+// MetricsPath returns the location of the local metrics log (~/.coi/metrics.jsonl)
+func MetricsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".coi", "metrics.jsonl"), nil
+}
+
+// AppendMetric appends a record to the metrics log at path, creating it if needed
+func AppendMetric(path string, record MetricRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode metric: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write metric: %w", err)
+	}
+	return nil
+}
+
+// LoadMetrics reads all records from the metrics log. A missing file yields no
+// records; malformed lines are skipped.
+func LoadMetrics(path string) ([]MetricRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	defer f.Close()
+
+	var records []MetricRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record MetricRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics file: %w", err)
+	}
+	return records, nil
+}
+
+// SummarizeMetrics computes sessions per active day, average duration, the most
+// used tool and a count of exit reasons
+func SummarizeMetrics(records []MetricRecord) MetricsSummary {
+	summary := MetricsSummary{ExitReasons: make(map[string]int)}
+	if len(records) == 0 {
+		return summary
+	}
+
+	days := make(map[string]bool)
+	tools := make(map[string]int)
+	var total float64
+	for _, r := range records {
+		days[r.StartedAt.Local().Format("2006-01-02")] = true
+		tools[r.Tool]++
+		summary.ExitReasons[r.ExitReason]++
+		total += r.DurationSeconds
+	}
+
+	summary.Sessions = len(records)
+	summary.Days = len(days)
+	summary.SessionsPerDay = float64(summary.Sessions) / float64(summary.Days)
+	summary.AverageDuration = time.Duration(total / float64(summary.Sessions) * float64(time.Second))
+
+	// Ties resolve alphabetically so the output is stable
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if summary.MostUsedTool == "" || tools[name] > tools[summary.MostUsedTool] {
+			summary.MostUsedTool = name
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAndLoadMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "metrics.jsonl")
+
+	records, err := LoadMetrics(path)
+	if err != nil {
+		t.Fatalf("LoadMetrics() on missing file unexpected error: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Expected no records from missing file, got %d", len(records))
+	}
+
+	started := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b"} {
+		record := MetricRecord{SessionID: id, Tool: "claude", StartedAt: started, DurationSeconds: 60, ExitReason: ExitReasonExited}
+		if err := AppendMetric(path, record); err != nil {
+			t.Fatalf("AppendMetric() unexpected error: %v", err)
+		}
+	}
+
+	// Malformed lines are skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("not json\n")
+	f.Close()
+
+	records, err = LoadMetrics(path)
+	if err != nil {
+		t.Fatalf("LoadMetrics() unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[1].SessionID != "b" || !records[1].StartedAt.Equal(started) {
+		t.Errorf("Unexpected record: %+v", records[1])
+	}
+}
+
+func TestSummarizeMetrics(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.Add(24 * time.Hour)
+
+	summary := SummarizeMetrics([]MetricRecord{
+		{Tool: "claude", StartedAt: day1, DurationSeconds: 60, ExitReason: ExitReasonExited},
+		{Tool: "claude", StartedAt: day1, DurationSeconds: 120, ExitReason: ExitReasonInterrupted},
+		{Tool: "aider", StartedAt: day2, DurationSeconds: 180, ExitReason: ExitReasonExited},
+	})
+
+	if summary.Sessions != 3 {
+		t.Errorf("Sessions = %d, want 3", summary.Sessions)
+	}
+	if summary.Days != 2 {
+		t.Errorf("Days = %d, want 2", summary.Days)
+	}
+	if summary.SessionsPerDay != 1.5 {
+		t.Errorf("SessionsPerDay = %v, want 1.5", summary.SessionsPerDay)
+	}
+	if summary.AverageDuration != 2*time.Minute {
+		t.Errorf("AverageDuration = %v, want 2m", summary.AverageDuration)
+	}
+	if summary.MostUsedTool != "claude" {
+		t.Errorf("MostUsedTool = %q, want claude", summary.MostUsedTool)
+	}
+	if summary.ExitReasons[ExitReasonExited] != 2 || summary.ExitReasons[ExitReasonInterrupted] != 1 {
+		t.Errorf("Unexpected exit reasons: %v", summary.ExitReasons)
+	}
+
+	empty := SummarizeMetrics(nil)
+	if empty.Sessions != 0 || empty.MostUsedTool != "" {
+		t.Errorf("Expected empty summary, got %+v", empty)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mensfeld/code-on-incus/internal/container"
 )
@@ -45,56 +47,233 @@ func ContainerName(workspacePath string, slot int) string {
 	return fmt.Sprintf("%s%s-%d", prefix, hash, slot)
 }
 
-// AllocateSlot finds the next available slot for a workspace
-// Returns the slot number (1, 2, 3, ...) or 0 if no slots available
-func AllocateSlot(workspacePath string, maxSlots int) (int, error) {
-	if maxSlots == 0 {
-		maxSlots = 10 // Default max 10 parallel sessions
+// maxContainerNameLength is the longest container name Incus accepts
+const maxContainerNameLength = 63
+
+// hashSlotPattern matches the <workspace-hash>-<slot> part of slot container names
+var hashSlotPattern = regexp.MustCompile(`^[a-f0-9]{8}-\d+$`)
+
+// namedInvalidChars matches runs of characters not allowed in container names
+var namedInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// NamedContainerName returns the container name for an explicitly named
+// environment (coi shell --name): <prefix><name>, with name lowercased and
+// characters Incus does not allow replaced by "-". Named containers are not
+// tied to a workspace, so names that look like <hash>-<slot> are rejected.
+func NamedContainerName(name string) (string, error) {
+	sanitized := strings.Trim(namedInvalidChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if sanitized == "" {
+		return "", fmt.Errorf("invalid container name %q: use letters, digits and '-'", name)
+	}
+	if hashSlotPattern.MatchString(sanitized) {
+		return "", fmt.Errorf("invalid container name %q: it looks like a workspace slot name (<hash>-<slot>)", name)
+	}
+
+	containerName := GetContainerPrefix() + sanitized
+	if len(containerName) > maxContainerNameLength {
+		return "", fmt.Errorf("invalid container name %q: %s is longer than %d characters", name, containerName, maxContainerNameLength)
+	}
+	return containerName, nil
+}
+
+// incusNamePattern matches names Incus accepts for instances
+var incusNamePattern = regexp.MustCompile(`^[a-zA-Z]([a-zA-Z0-9-]*[a-zA-Z0-9])?$`)
+
+// ValidateRenameTarget checks that a container can be renamed to newName. A
+// name in the slot scheme (<prefix><hash>-<slot>) is only accepted for a slot
+// container of the same workspace, since it would otherwise be taken for
+// another workspace's session.
+func ValidateRenameTarget(oldName, newName string) error {
+	if newName == oldName {
+		return fmt.Errorf("container is already named %s", newName)
+	}
+	if !incusNamePattern.MatchString(newName) || len(newName) > maxContainerNameLength {
+		return fmt.Errorf("invalid container name %q: use letters, digits and '-', starting with a letter (at most %d characters)", newName, maxContainerNameLength)
 	}
 
+	newHash, _, err := ParseContainerName(newName)
+	if err != nil {
+		return nil // Not a slot name
+	}
+	if oldHash, _, err := ParseContainerName(oldName); err == nil && oldHash == newHash {
+		return nil
+	}
+	return fmt.Errorf("refusing to rename to %s: it follows the slot naming scheme (<hash>-<slot>) of another workspace", newName)
+}
+
+// ParseNamedContainerName returns the name of an explicitly named container
+// (see NamedContainerName); ok is false for workspace slot containers and
+// names without the container prefix
+func ParseNamedContainerName(containerName string) (name string, ok bool) {
+	name, ok = strings.CutPrefix(containerName, GetContainerPrefix())
+	if !ok || name == "" || hashSlotPattern.MatchString(name) {
+		return "", false
+	}
+	return name, true
+}
+
+// PersistentConfigKey is the Incus config key recording whether a container
+// was created in persistent mode ("true" or "false"). Containers created by
+// older versions of coi only carry it when persistent, or not at all.
+const PersistentConfigKey = "user.coi.persistent"
+
+// slotContainer is the subset of `incus list --format=json` used for slot allocation
+type slotContainer struct {
+	Name   string            `json:"name"`
+	Status string            `json:"status"`
+	Config map[string]string `json:"config"`
+}
+
+// persistenceLookup reports whether the saved sessions of a container say it
+// is persistent; found is false when no saved session mentions it
+type persistenceLookup func(containerName string) (persistent, found bool)
+
+// stoppedContainerKept reports whether a stopped container must be kept
+// rather than deleted as a leftover: it is persistent, still in its
+// delete_grace_minutes grace period, or not known to be ephemeral. A
+// container without PersistentConfigKey predates coi setting it on every
+// container, so its saved session metadata decides, and without any it is kept.
+func stoppedContainerKept(name string, config map[string]string, savedPersistent persistenceLookup, now time.Time) bool {
+	if inGracePeriod(config, now) {
+		return true
+	}
+	switch config[PersistentConfigKey] {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	persistent, found := savedPersistent(name)
+	return persistent || !found
+}
+
+// savedSessionPersistent looks through every tool's saved sessions under
+// ~/.coi for ones that ran in containerName
+func savedSessionPersistent(containerName string) (persistent, found bool) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return false, false
+	}
+	return savedSessionPersistentIn(filepath.Join(homeDir, ".coi"), containerName)
+}
+
+// savedSessionPersistentIn is savedSessionPersistent for the coi directory
+// baseDir. Any persistent session of the container makes it persistent.
+func savedSessionPersistentIn(baseDir, containerName string) (persistent, found bool) {
+	dirs, err := ListToolSessionsDirs(baseDir)
+	if err != nil {
+		return false, false
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir.Dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			metadata, err := LoadSessionMetadata(filepath.Join(dir.Dir, entry.Name(), "metadata.json"))
+			if err != nil || metadata.ContainerName != containerName {
+				continue
+			}
+			found = true
+			if metadata.Persistent {
+				return true, true
+			}
+		}
+	}
+	return false, found
+}
+
+// containerKeptWhenStopped reads a stopped container's config and reports
+// whether Setup must keep it (see stoppedContainerKept)
+func containerKeptWhenStopped(containerName string) bool {
+	config := make(map[string]string)
+	for _, key := range []string{PersistentConfigKey, DeleteAfterConfigKey} {
+		if value, err := container.IncusOutput("config", "get", containerName, key); err == nil {
+			if value = strings.TrimSpace(value); value != "" {
+				config[key] = value
+			}
+		}
+	}
+	return stoppedContainerKept(containerName, config, savedSessionPersistent, time.Now())
+}
+
+// occupiedSlots returns the slots held by containers matching prefix.
+// Running containers occupy a slot, and so do stopped ones that Setup would
+// keep (see stoppedContainerKept) - other stopped non-persistent leftovers are
+// deleted by Setup, so they must not exhaust the slot pool.
+func occupiedSlots(containers []slotContainer, prefix string, savedPersistent persistenceLookup) map[int]bool {
+	slots := make(map[int]bool)
+	re := regexp.MustCompile(fmt.Sprintf(`^%s(\d+)$`, regexp.QuoteMeta(prefix)))
+
+	for _, c := range containers {
+		matches := re.FindStringSubmatch(c.Name)
+		if len(matches) < 2 {
+			continue
+		}
+		slotNum, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+		if c.Status == "Running" || stoppedContainerKept(c.Name, c.Config, savedPersistent, time.Now()) {
+			slots[slotNum] = true
+		}
+	}
+
+	return slots
+}
+
+// listOccupiedSlots returns the occupied slots for a workspace
+func listOccupiedSlots(workspacePath string) (map[int]bool, error) {
 	hash := WorkspaceHash(workspacePath)
 	prefix := fmt.Sprintf("%s%s-", GetContainerPrefix(), hash)
 
 	// Get all containers matching our workspace
 	output, err := container.IncusOutput("list", "--format=json")
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	// Parse running containers using proper JSON parsing
-	runningSlots := make(map[int]bool)
-	re := regexp.MustCompile(fmt.Sprintf(`^%s(\d+)$`, regexp.QuoteMeta(prefix)))
-
 	// Parse JSON array of containers
-	var containers []struct {
-		Name string `json:"name"`
-	}
+	var containers []slotContainer
 	if err := json.Unmarshal([]byte(output), &containers); err != nil {
 		// Fallback: if JSON parsing fails, try regex on raw output
+		// Status is unknown here, so conservatively treat every match as occupied
+		slots := make(map[int]bool)
+		re := regexp.MustCompile(fmt.Sprintf(`^%s(\d+)$`, regexp.QuoteMeta(prefix)))
 		nameMatches := regexp.MustCompile(`"name"\s*:\s*"([^"]+)"`).FindAllStringSubmatch(output, -1)
 		for _, match := range nameMatches {
 			if len(match) > 1 {
-				containerName := match[1]
-				if matches := re.FindStringSubmatch(containerName); len(matches) > 1 {
+				if matches := re.FindStringSubmatch(match[1]); len(matches) > 1 {
 					if slotNum, err := strconv.Atoi(matches[1]); err == nil {
-						runningSlots[slotNum] = true
+						slots[slotNum] = true
 					}
 				}
 			}
 		}
-	} else {
-		for _, c := range containers {
-			if matches := re.FindStringSubmatch(c.Name); len(matches) > 1 {
-				if slotNum, err := strconv.Atoi(matches[1]); err == nil {
-					runningSlots[slotNum] = true
-				}
-			}
-		}
+		return slots, nil
+	}
+
+	return occupiedSlots(containers, prefix, savedSessionPersistent), nil
+}
+
+// AllocateSlot finds the next available slot for a workspace
+// Returns the slot number (1, 2, 3, ...) or 0 if no slots available
+func AllocateSlot(workspacePath string, maxSlots int) (int, error) {
+	if maxSlots == 0 {
+		maxSlots = 10 // Default max 10 parallel sessions
+	}
+
+	usedSlots, err := listOccupiedSlots(workspacePath)
+	if err != nil {
+		return 0, err
 	}
 
 	// Find first available slot
 	for slot := 1; slot <= maxSlots; slot++ {
-		if !runningSlots[slot] {
+		if !usedSlots[slot] {
 			return slot, nil
 		}
 	}
@@ -109,49 +288,14 @@ func AllocateSlotFrom(workspacePath string, startSlot, maxSlots int) (int, error
 		maxSlots = 10 // Default max 10 parallel sessions
 	}
 
-	hash := WorkspaceHash(workspacePath)
-	prefix := fmt.Sprintf("%s%s-", GetContainerPrefix(), hash)
-
-	// Get all containers matching our workspace
-	output, err := container.IncusOutput("list", "--format=json")
+	usedSlots, err := listOccupiedSlots(workspacePath)
 	if err != nil {
 		return 0, err
 	}
 
-	// Parse running containers using proper JSON parsing
-	runningSlots := make(map[int]bool)
-	re := regexp.MustCompile(fmt.Sprintf(`^%s(\d+)$`, regexp.QuoteMeta(prefix)))
-
-	// Parse JSON array of containers
-	var containers []struct {
-		Name string `json:"name"`
-	}
-	if err := json.Unmarshal([]byte(output), &containers); err != nil {
-		// Fallback: if JSON parsing fails, try regex on raw output
-		nameMatches := regexp.MustCompile(`"name"\s*:\s*"([^"]+)"`).FindAllStringSubmatch(output, -1)
-		for _, match := range nameMatches {
-			if len(match) > 1 {
-				containerName := match[1]
-				if matches := re.FindStringSubmatch(containerName); len(matches) > 1 {
-					if slotNum, err := strconv.Atoi(matches[1]); err == nil {
-						runningSlots[slotNum] = true
-					}
-				}
-			}
-		}
-	} else {
-		for _, c := range containers {
-			if matches := re.FindStringSubmatch(c.Name); len(matches) > 1 {
-				if slotNum, err := strconv.Atoi(matches[1]); err == nil {
-					runningSlots[slotNum] = true
-				}
-			}
-		}
-	}
-
 	// Find first available slot starting from startSlot
 	for slot := startSlot; slot <= maxSlots; slot++ {
-		if !runningSlots[slot] {
+		if !usedSlots[slot] {
 			return slot, nil
 		}
 	}
@@ -3,7 +3,11 @@ package session
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestWorkspaceHash(t *testing.T) {
@@ -222,6 +226,80 @@ func TestParseContainerName(t *testing.T) {
 	}
 }
 
+func TestNamedContainerName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "simple name", input: "myenv", want: "coi-myenv"},
+		{name: "sanitized", input: "My Env_2", want: "coi-my-env-2"},
+		{name: "trims separators", input: "--dev--", want: "coi-dev"},
+		{name: "empty after sanitizing", input: "!!!", wantErr: true},
+		{name: "looks like a slot name", input: "abc12345-1", wantErr: true},
+		{name: "too long", input: strings.Repeat("a", 60), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NamedContainerName(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NamedContainerName(%q) expected error, got %q", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NamedContainerName(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("NamedContainerName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNamedContainerName(t *testing.T) {
+	if name, ok := ParseNamedContainerName("coi-myenv"); !ok || name != "myenv" {
+		t.Errorf("Expected named container 'myenv', got %q (ok=%v)", name, ok)
+	}
+	if _, ok := ParseNamedContainerName("coi-abc12345-1"); ok {
+		t.Error("Expected slot container not to be parsed as named")
+	}
+	if _, ok := ParseNamedContainerName("other-myenv"); ok {
+		t.Error("Expected container without prefix not to be parsed as named")
+	}
+}
+
+func TestValidateRenameTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldName string
+		newName string
+		wantErr bool
+	}{
+		{name: "slot to named", oldName: "coi-abc12345-1", newName: "coi-refactor"},
+		{name: "named to named", oldName: "coi-refactor", newName: "coi-review"},
+		{name: "slot within the same workspace", oldName: "coi-abc12345-1", newName: "coi-abc12345-3"},
+		{name: "slot of another workspace", oldName: "coi-abc12345-1", newName: "coi-def67890-1", wantErr: true},
+		{name: "named into a slot name", oldName: "coi-refactor", newName: "coi-abc12345-1", wantErr: true},
+		{name: "same name", oldName: "coi-refactor", newName: "coi-refactor", wantErr: true},
+		{name: "invalid characters", oldName: "coi-refactor", newName: "coi_review", wantErr: true},
+		{name: "starts with a digit", oldName: "coi-refactor", newName: "1coi", wantErr: true},
+		{name: "too long", oldName: "coi-refactor", newName: "coi-" + strings.Repeat("a", 60), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRenameTarget(tt.oldName, tt.newName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRenameTarget(%q, %q) error = %v, wantErr %v", tt.oldName, tt.newName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // TestAllocateSlot is an integration test that would need container mocking
 // Skipping for now as it requires Incus interaction
 func TestAllocateSlotLogic(t *testing.T) {
@@ -241,3 +319,91 @@ func TestAllocateSlotFromLogic(t *testing.T) {
 	// This would test AllocateSlotFrom but requires mocking Incus commands
 	// TODO: Add integration test
 }
+
+func TestOccupiedSlots(t *testing.T) {
+	prefix := "coi-abcd1234-"
+	ephemeral := map[string]string{PersistentConfigKey: "false"}
+	containers := []slotContainer{
+		{Name: "coi-abcd1234-1", Status: "Running"},
+		{Name: "coi-abcd1234-2", Status: "Stopped", Config: ephemeral}, // leftover, slot is free
+		{Name: "coi-abcd1234-3", Status: "Stopped", Config: map[string]string{PersistentConfigKey: "true"}},
+		{Name: "coi-ffff0000-4", Status: "Running"}, // other workspace
+		{Name: "coi-abcd1234-x", Status: "Running"}, // not a slot
+		{Name: "coi-abcd1234-5", Status: "Stopped", Config: map[string]string{
+			PersistentConfigKey:  "false",
+			DeleteAfterConfigKey: FormatDeadline(time.Now().Add(time.Hour)), // still in its grace period
+		}},
+		{Name: "coi-abcd1234-6", Status: "Stopped", Config: map[string]string{
+			PersistentConfigKey:  "false",
+			DeleteAfterConfigKey: FormatDeadline(time.Now().Add(-time.Hour)), // grace period over, slot is free
+		}},
+		// Created by an older coi without the persistent key: saved sessions decide
+		{Name: "coi-abcd1234-7", Status: "Stopped"}, // persistent session saved
+		{Name: "coi-abcd1234-8", Status: "Stopped"}, // ephemeral session saved, slot is free
+		{Name: "coi-abcd1234-9", Status: "Stopped"}, // no saved session, kept to be safe
+	}
+	saved := map[string]bool{"coi-abcd1234-7": true, "coi-abcd1234-8": false}
+	lookup := func(name string) (bool, bool) {
+		persistent, found := saved[name]
+		return persistent, found
+	}
+
+	slots := occupiedSlots(containers, prefix, lookup)
+
+	expected := map[int]bool{1: true, 3: true, 5: true, 7: true, 9: true}
+	if len(slots) != len(expected) {
+		t.Errorf("Expected %d occupied slots, got %v", len(expected), slots)
+	}
+	for slot := range expected {
+		if !slots[slot] {
+			t.Errorf("Expected slot %d to be occupied, got %v", slot, slots)
+		}
+	}
+	if slots[2] {
+		t.Error("Expected stopped non-persistent container not to occupy slot 2")
+	}
+}
+
+func TestSavedSessionPersistentIn(t *testing.T) {
+	baseDir := t.TempDir()
+	writeMetadata := func(tool, sessionID string, metadata SessionMetadata) {
+		t.Helper()
+		dir := filepath.Join(baseDir, "sessions-"+tool, sessionID)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := SaveMetadata(filepath.Join(dir, "metadata.json"), metadata); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeMetadata("claude", "a", SessionMetadata{SessionID: "a", ContainerName: "coi-abcd1234-1"})
+	writeMetadata("claude", "b", SessionMetadata{SessionID: "b", ContainerName: "coi-abcd1234-2"})
+	writeMetadata("aider", "c", SessionMetadata{SessionID: "c", ContainerName: "coi-abcd1234-2", Persistent: true})
+
+	if persistent, found := savedSessionPersistentIn(baseDir, "coi-abcd1234-1"); persistent || !found {
+		t.Errorf("Expected ephemeral saved session, got persistent=%v found=%v", persistent, found)
+	}
+	if persistent, found := savedSessionPersistentIn(baseDir, "coi-abcd1234-2"); !persistent || !found {
+		t.Errorf("Expected any persistent session to win, got persistent=%v found=%v", persistent, found)
+	}
+	if _, found := savedSessionPersistentIn(baseDir, "coi-abcd1234-3"); found {
+		t.Error("Expected no saved session for an unknown container")
+	}
+}
+
+func TestHomeCacheDir(t *testing.T) {
+	base := "/home/user/.coi"
+	dir := HomeCacheDir(base, "/home/user/project")
+
+	if want := filepath.Join(HomeCachesDir(base), WorkspaceHash("/home/user/project")); dir != want {
+		t.Errorf("Expected %s, got %s", want, dir)
+	}
+	// The cache directory name matches the workspace's container names, so
+	// coi clean --caches can tell which caches are still in use
+	if !strings.HasPrefix(ContainerName("/home/user/project", 2), GetContainerPrefix()+filepath.Base(dir)+"-") {
+		t.Errorf("Expected container names to start with the cache directory name %s", filepath.Base(dir))
+	}
+	if HomeCacheDir(base, "/home/user/other") == dir {
+		t.Error("Expected different workspaces to get different caches")
+	}
+}
@@ -0,0 +1,141 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// memoryEventsPath is the container's cgroup v2 memory.events; the container's
+// cgroup namespace makes it report events for the whole container
+const memoryEventsPath = "/sys/fs/cgroup/memory.events"
+
+// oomPollInterval is how often OOMMonitor samples the OOM kill counter. The
+// counter disappears with the container, so the last sample is what remains
+// when a container is stopped by the OOM killer.
+const oomPollInterval = 10 * time.Second
+
+// ParseOOMKills returns the oom_kill counter from a cgroup v2 memory.events file
+func ParseOOMKills(memoryEvents string) (int, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(memoryEvents))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return 0, false
+			}
+			return count, true
+		}
+	}
+	return 0, false
+}
+
+// OOMKillCount reads how many processes the kernel OOM killer has killed in the
+// container. Requires the container to be running with cgroup v2.
+func OOMKillCount(mgr *container.Manager) (int, error) {
+	output, err := mgr.ExecArgsCapture([]string{"cat", memoryEventsPath}, container.ExecCommandOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", memoryEventsPath, err)
+	}
+	count, ok := ParseOOMKills(output)
+	if !ok {
+		return 0, fmt.Errorf("no oom_kill counter in %s", memoryEventsPath)
+	}
+	return count, nil
+}
+
+// OOMStopReason describes OOM kills for the user, including the container's
+// memory limit ("" = unlimited) so the limit can be tuned
+func OOMStopReason(kills int, memoryLimit string) string {
+	limit := "no memory limit set"
+	if memoryLimit != "" {
+		limit = fmt.Sprintf("memory limit %s", memoryLimit)
+	}
+	return fmt.Sprintf("%d process(es) were killed for lack of memory (%s) - raise the limit with --limit-memory or [limits.memory] limit", kills, limit)
+}
+
+// OOMMonitor tracks OOM kills in a session container from session start until
+// Stop, so a tool killed for running out of memory can be reported instead of
+// looking like a normal exit
+type OOMMonitor struct {
+	mgr *container.Manager
+
+	mu       sync.Mutex
+	baseline int
+	last     int
+	ok       bool // baseline was read (cgroup v2 counter available)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// StartOOMMonitor records the current OOM kill count and samples it in the
+// background. If the counter cannot be read (e.g. cgroup v1) the monitor is inert.
+func StartOOMMonitor(mgr *container.Manager) *OOMMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &OOMMonitor{mgr: mgr, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+
+	count, err := OOMKillCount(mgr)
+	if err != nil {
+		close(m.done)
+		return m
+	}
+	m.baseline, m.last, m.ok = count, count, true
+
+	go m.run()
+	return m
+}
+
+// run samples the counter until Stop is called
+func (m *OOMMonitor) run() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(oomPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.sample()
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// sample updates the last known count; failures (container stopped) keep the previous value
+func (m *OOMMonitor) sample() {
+	count, err := OOMKillCount(m.mgr)
+	if err != nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if count > m.last {
+		m.last = count
+	}
+}
+
+// Stop ends monitoring and returns how many processes were OOM-killed since the
+// monitor started (a final sample is taken if the container is still running)
+func (m *OOMMonitor) Stop() int {
+	m.cancel()
+	<-m.done
+
+	if !m.ok {
+		return 0
+	}
+	m.sample()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.last - m.baseline
+}
@@ -0,0 +1,42 @@
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOOMKills(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   int
+		wantOK bool
+	}{
+		{"no kills", "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\noom_group_kill 0\n", 0, true},
+		{"kills", "low 0\nhigh 12\nmax 40\noom 3\noom_kill 2\n", 2, true},
+		{"missing counter", "low 0\nhigh 0\n", 0, false},
+		{"malformed counter", "oom_kill many\n", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseOOMKills(tt.input)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseOOMKills() = (%d, %v), want (%d, %v)", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestOOMStopReason(t *testing.T) {
+	limited := OOMStopReason(1, "2GiB")
+	if !strings.Contains(limited, "1 process(es) were killed for lack of memory") || !strings.Contains(limited, "memory limit 2GiB") || !strings.Contains(limited, "--limit-memory") {
+		t.Errorf("Unexpected reason: %q", limited)
+	}
+
+	unlimited := OOMStopReason(3, "")
+	if !strings.Contains(unlimited, "3 process(es)") || !strings.Contains(unlimited, "no memory limit set") {
+		t.Errorf("Unexpected reason: %q", unlimited)
+	}
+}
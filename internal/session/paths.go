@@ -1,7 +1,9 @@
 package session
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/mensfeld/code-on-incus/internal/tool"
 )
@@ -11,3 +13,90 @@ import (
 func GetSessionsDir(baseDir string, t tool.Tool) string {
 	return filepath.Join(baseDir, t.SessionsDirName())
 }
+
+// sessionsDirPrefix starts every tool's sessions directory name (sessions-claude, ...)
+const sessionsDirPrefix = "sessions-"
+
+// ConfigDirForSessionsDir returns the config directory name of the tool whose
+// sessions directory is sessionsDir (".claude" for sessions-claude), or of
+// the default tool for other directory names. Tools this version of coi does
+// not support get "", so their sessions count by metadata alone.
+func ConfigDirForSessionsDir(sessionsDir string) string {
+	toolName, ok := strings.CutPrefix(filepath.Base(sessionsDir), sessionsDirPrefix)
+	if !ok {
+		return tool.GetDefault().ConfigDirName()
+	}
+	t, err := tool.Get(toolName)
+	if err != nil {
+		return ""
+	}
+	return t.ConfigDirName()
+}
+
+// ToolSessionsDir is the sessions directory of one tool
+type ToolSessionsDir struct {
+	Tool string // Tool name from the directory name, e.g. "aider" for sessions-aider
+	Dir  string
+}
+
+// ListToolSessionsDirs returns the sessions directories of every tool that has
+// one under baseDir, sorted by tool name. Tools are not required to be
+// supported by this version of coi, so sessions of a tool used earlier still show up.
+func ListToolSessionsDirs(baseDir string) ([]ToolSessionsDir, error) {
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var dirs []ToolSessionsDir
+	for _, entry := range entries {
+		toolName, ok := strings.CutPrefix(entry.Name(), sessionsDirPrefix)
+		if !entry.IsDir() || !ok || toolName == "" {
+			continue
+		}
+		dirs = append(dirs, ToolSessionsDir{Tool: toolName, Dir: filepath.Join(baseDir, entry.Name())})
+	}
+	return dirs, nil // ReadDir sorts by name, so by tool
+}
+
+// FindSessionTool looks for a saved session in every tool's sessions
+// directory and returns the one holding it
+func FindSessionTool(baseDir, sessionID string) (ToolSessionsDir, bool) {
+	dirs, err := ListToolSessionsDirs(baseDir)
+	if err != nil {
+		return ToolSessionsDir{}, false
+	}
+	for _, dir := range dirs {
+		if info, err := os.Stat(filepath.Join(dir.Dir, sessionID)); err == nil && info.IsDir() {
+			return dir, true
+		}
+	}
+	return ToolSessionsDir{}, false
+}
+
+// WorkspaceStatus describes how a saved session's workspace relates to the current one
+type WorkspaceStatus int
+
+const (
+	// WorkspaceSame means the session was created in the current workspace
+	WorkspaceSame WorkspaceStatus = iota
+	// WorkspaceDifferent means the session's workspace exists but is not the current one
+	WorkspaceDifferent
+	// WorkspaceMissing means the session's workspace no longer exists (moved or renamed)
+	WorkspaceMissing
+)
+
+// CheckSessionWorkspace compares the workspace recorded in session metadata
+// with the current workspace. An empty original (old metadata) counts as the same.
+func CheckSessionWorkspace(original, current string) WorkspaceStatus {
+	if original == "" || filepath.Clean(original) == filepath.Clean(current) {
+		return WorkspaceSame
+	}
+	if info, err := os.Stat(original); err != nil || !info.IsDir() {
+		return WorkspaceMissing
+	}
+	return WorkspaceDifferent
+}
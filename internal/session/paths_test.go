@@ -0,0 +1,100 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckSessionWorkspace(t *testing.T) {
+	current := t.TempDir()
+	other := t.TempDir()
+
+	tests := []struct {
+		name     string
+		original string
+		want     WorkspaceStatus
+	}{
+		{
+			name:     "same workspace",
+			original: current,
+			want:     WorkspaceSame,
+		},
+		{
+			name:     "same workspace with trailing slash",
+			original: current + "/",
+			want:     WorkspaceSame,
+		},
+		{
+			name:     "empty original (legacy metadata)",
+			original: "",
+			want:     WorkspaceSame,
+		},
+		{
+			name:     "different existing workspace",
+			original: other,
+			want:     WorkspaceDifferent,
+		},
+		{
+			name:     "moved workspace",
+			original: filepath.Join(other, "renamed-away"),
+			want:     WorkspaceMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckSessionWorkspace(tt.original, current); got != tt.want {
+				t.Errorf("CheckSessionWorkspace(%q) = %d, want %d", tt.original, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListToolSessionsDirs(t *testing.T) {
+	baseDir := t.TempDir()
+	for _, dir := range []string{"sessions-claude/abc", "sessions-aider/def", "sessions", "storage"} {
+		if err := os.MkdirAll(filepath.Join(baseDir, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(baseDir, "sessions-notes"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dirs, err := ListToolSessionsDirs(baseDir)
+	if err != nil {
+		t.Fatalf("ListToolSessionsDirs() error: %v", err)
+	}
+	if len(dirs) != 2 || dirs[0].Tool != "aider" || dirs[1].Tool != "claude" {
+		t.Fatalf("Expected aider and claude sessions dirs, got %+v", dirs)
+	}
+	if dirs[1].Dir != filepath.Join(baseDir, "sessions-claude") {
+		t.Errorf("Unexpected claude sessions dir: %s", dirs[1].Dir)
+	}
+
+	if dirs, err := ListToolSessionsDirs(filepath.Join(baseDir, "missing")); err != nil || len(dirs) != 0 {
+		t.Errorf("Expected no dirs and no error for a missing base dir, got %v, %v", dirs, err)
+	}
+
+	owner, found := FindSessionTool(baseDir, "def")
+	if !found || owner.Tool != "aider" {
+		t.Errorf("Expected session def to belong to aider, got %+v (found: %v)", owner, found)
+	}
+	if _, found := FindSessionTool(baseDir, "nope"); found {
+		t.Error("Expected unknown session not to be found")
+	}
+}
+
+func TestConfigDirForSessionsDir(t *testing.T) {
+	tests := map[string]string{
+		"/home/me/.coi/sessions-claude":  ".claude",
+		"/home/me/.coi/sessions-unknown": "",
+		"/tmp/custom":                    ".claude", // Default tool
+	}
+	for dir, expected := range tests {
+		if got := ConfigDirForSessionsDir(dir); got != expected {
+			t.Errorf("ConfigDirForSessionsDir(%s) = %q, expected %q", dir, got, expected)
+		}
+	}
+}
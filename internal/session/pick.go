@@ -0,0 +1,109 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PickEntry is a session offered by coi pick
+type PickEntry struct {
+	Running   bool   // Running container (attach) rather than a saved session (resume)
+	Name      string // Container name or session ID
+	Workspace string
+	Since     time.Time // Container creation or session save time
+}
+
+// PickAction is what the user chose to do with the selected entry
+type PickAction int
+
+const (
+	PickCancel PickAction = iota
+	PickAttach
+	PickResume
+)
+
+// OrderPickEntries lists running containers first (in the given order), then
+// saved sessions newest first
+func OrderPickEntries(running, saved []PickEntry) []PickEntry {
+	sorted := append([]PickEntry{}, saved...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Since.After(sorted[j].Since)
+	})
+	return append(append([]PickEntry{}, running...), sorted...)
+}
+
+// ParsePickTime parses the timestamps shown by coi list (RFC3339 or
+// "2006-01-02 15:04:05"); unknown formats give the zero time
+func ParsePickTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// FormatAge renders how long before now t was, e.g. "5m ago"
+func FormatAge(t, now time.Time) string {
+	if t.IsZero() {
+		return "unknown age"
+	}
+	age := now.Sub(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
+
+// PickLine is the menu line for entry n (1-based)
+func PickLine(n int, entry PickEntry, toolName string, now time.Time) string {
+	workspace := entry.Workspace
+	if workspace == "" {
+		workspace = "unknown workspace"
+	}
+	if entry.Running {
+		return fmt.Sprintf("%d. [running] %s  %s  %s  started %s", n, entry.Name, workspace, toolName, FormatAge(entry.Since, now))
+	}
+	return fmt.Sprintf("%d. [saved]   %s  %s  %s  saved %s", n, entry.Name, workspace, toolName, FormatAge(entry.Since, now))
+}
+
+// DefaultPickAction attaches to running entries and resumes saved ones
+func DefaultPickAction(entry PickEntry) PickAction {
+	if entry.Running {
+		return PickAttach
+	}
+	return PickResume
+}
+
+// PickKey applies a key read from a raw terminal to the menu: arrows and j/k
+// move the selection (wrapping), 1-9 jump to an entry, Enter takes the default
+// action, r resumes a saved entry and q, Esc or Ctrl+C cancel. done is true
+// once the user has chosen; other keys are ignored.
+func PickKey(key string, entries []PickEntry, selected int) (newSelected int, action PickAction, done bool) {
+	switch {
+	case key == "\x1b[A" || key == "k":
+		return (selected + len(entries) - 1) % len(entries), PickCancel, false
+	case key == "\x1b[B" || key == "j":
+		return (selected + 1) % len(entries), PickCancel, false
+	case key == "\r" || key == "\n":
+		return selected, DefaultPickAction(entries[selected]), true
+	case key == "r":
+		if !entries[selected].Running {
+			return selected, PickResume, true
+		}
+	case key == "q" || key == "\x1b" || key == "\x03":
+		return selected, PickCancel, true
+	case len(key) == 1 && key[0] >= '1' && key[0] <= '9':
+		if i := int(key[0] - '1'); i < len(entries) {
+			return i, PickCancel, false
+		}
+	}
+	return selected, PickCancel, false
+}
@@ -0,0 +1,111 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrderPickEntries(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	running := []PickEntry{{Running: true, Name: "coi-b-1"}, {Running: true, Name: "coi-a-1"}}
+	saved := []PickEntry{
+		{Name: "old", Since: now.Add(-48 * time.Hour)},
+		{Name: "unknown"},
+		{Name: "new", Since: now.Add(-time.Minute)},
+	}
+
+	entries := OrderPickEntries(running, saved)
+	want := []string{"coi-b-1", "coi-a-1", "new", "old", "unknown"}
+	if len(entries) != len(want) {
+		t.Fatalf("OrderPickEntries() returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, name := range want {
+		if entries[i].Name != name {
+			t.Errorf("Entry %d = %q, want %q", i, entries[i].Name, name)
+		}
+	}
+	if saved[0].Name != "old" {
+		t.Error("OrderPickEntries() reordered its input")
+	}
+}
+
+func TestParsePickTime(t *testing.T) {
+	want := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, value := range []string{"2026-01-02T15:04:05Z", "2026-01-02 15:04:05"} {
+		if got := ParsePickTime(value); !got.Equal(want) {
+			t.Errorf("ParsePickTime(%q) = %v, want %v", value, got, want)
+		}
+	}
+	if got := ParsePickTime("yesterday"); !got.IsZero() {
+		t.Errorf("ParsePickTime(unknown format) = %v, want the zero time", got)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		since time.Time
+		want  string
+	}{
+		{time.Time{}, "unknown age"},
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5m ago"},
+		{now.Add(-3 * time.Hour), "3h ago"},
+		{now.Add(-50 * time.Hour), "2d ago"},
+	}
+	for _, tt := range tests {
+		if got := FormatAge(tt.since, now); got != tt.want {
+			t.Errorf("FormatAge(%v) = %q, want %q", tt.since, got, tt.want)
+		}
+	}
+}
+
+func TestPickLine(t *testing.T) {
+	now := time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC)
+	running := PickEntry{Running: true, Name: "coi-abc-1", Workspace: "/home/user/project", Since: now.Add(-2 * time.Hour)}
+	if got, want := PickLine(1, running, "claude", now), "1. [running] coi-abc-1  /home/user/project  claude  started 2h ago"; got != want {
+		t.Errorf("PickLine(running) = %q, want %q", got, want)
+	}
+	saved := PickEntry{Name: "abc-123"}
+	if got, want := PickLine(2, saved, "claude", now), "2. [saved]   abc-123  unknown workspace  claude  saved unknown age"; got != want {
+		t.Errorf("PickLine(saved) = %q, want %q", got, want)
+	}
+}
+
+func TestPickKey(t *testing.T) {
+	entries := []PickEntry{{Running: true, Name: "coi-abc-1"}, {Name: "abc-123"}, {Name: "def-456"}}
+
+	tests := []struct {
+		name         string
+		key          string
+		selected     int
+		wantSelected int
+		wantAction   PickAction
+		wantDone     bool
+	}{
+		{"down", "\x1b[B", 0, 1, PickCancel, false},
+		{"j wraps", "j", 2, 0, PickCancel, false},
+		{"up wraps", "\x1b[A", 0, 2, PickCancel, false},
+		{"k", "k", 2, 1, PickCancel, false},
+		{"digit jumps", "3", 0, 2, PickCancel, false},
+		{"digit out of range", "9", 1, 1, PickCancel, false},
+		{"enter attaches running", "\r", 0, 0, PickAttach, true},
+		{"enter resumes saved", "\n", 1, 1, PickResume, true},
+		{"r resumes saved", "r", 2, 2, PickResume, true},
+		{"r ignored on running", "r", 0, 0, PickCancel, false},
+		{"q cancels", "q", 1, 1, PickCancel, true},
+		{"esc cancels", "\x1b", 0, 0, PickCancel, true},
+		{"ctrl-c cancels", "\x03", 0, 0, PickCancel, true},
+		{"other key ignored", "x", 1, 1, PickCancel, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, action, done := PickKey(tt.key, entries, tt.selected)
+			if selected != tt.wantSelected || action != tt.wantAction || done != tt.wantDone {
+				t.Errorf("PickKey(%q, %d) = %d, %d, %t; want %d, %d, %t",
+					tt.key, tt.selected, selected, action, done, tt.wantSelected, tt.wantAction, tt.wantDone)
+			}
+		})
+	}
+}
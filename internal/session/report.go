@@ -0,0 +1,105 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+// Report is what coi info shows about a saved session
+type Report struct {
+	ID              string   `json:"id"`
+	Tool            string   `json:"tool"`
+	Path            string   `json:"path"`
+	Workspace       string   `json:"workspace,omitempty"`
+	ContainerName   string   `json:"container_name,omitempty"`
+	SavedAt         string   `json:"saved_at,omitempty"`
+	Persistent      bool     `json:"persistent"`
+	NetworkMode     string   `json:"network_mode,omitempty"`
+	AllowedDomains  []string `json:"allowed_domains,omitempty"`
+	HasState        bool     `json:"has_state"`
+	SizeBytes       int64    `json:"size_bytes"`
+	TranscriptFiles int      `json:"transcript_files"`
+	ToolSessionID   string   `json:"tool_session_id,omitempty"`
+	LaunchCommand   string   `json:"launch_command,omitempty"`
+	LaunchCwd       string   `json:"launch_cwd,omitempty"`
+	HomeCacheDir    string   `json:"home_cache_dir,omitempty"`
+	ResumeCommand   string   `json:"resume_command,omitempty"`
+
+	// Problems reading the metadata; the report is still usable
+	Warnings []string `json:"-"`
+}
+
+// BuildReport collects a saved session's metadata and tool state. The launch
+// environment is left out since it can hold --env secrets.
+func BuildReport(sessionsDir, sessionID string, t tool.Tool) (*Report, error) {
+	sessionDir := filepath.Join(sessionsDir, sessionID)
+	if info, err := os.Stat(sessionDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+
+	report := &Report{
+		ID:   sessionID,
+		Tool: t.Name(),
+		Path: sessionDir,
+	}
+
+	if data, err := os.ReadFile(filepath.Join(sessionDir, "metadata.json")); err == nil {
+		var metadata SessionMetadata
+		if err := json.Unmarshal(data, &metadata); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("Could not parse metadata: %v", err))
+		}
+		report.Workspace = metadata.Workspace
+		report.ContainerName = metadata.ContainerName
+		report.SavedAt = metadata.SavedAt
+		report.Persistent = metadata.Persistent
+		report.NetworkMode = metadata.NetworkMode
+		report.AllowedDomains = metadata.AllowedDomains
+		report.LaunchCommand = metadata.LaunchCommand
+		report.LaunchCwd = metadata.LaunchCwd
+		report.HomeCacheDir = metadata.HomeCacheDir
+	} else {
+		report.Warnings = append(report.Warnings, "No metadata found")
+	}
+
+	if size, err := DirSize(sessionDir); err == nil {
+		report.SizeBytes = size
+	}
+
+	if configDirName := t.ConfigDirName(); configDirName != "" {
+		stateDir := filepath.Join(sessionDir, configDirName)
+		if info, err := os.Stat(stateDir); err == nil && info.IsDir() {
+			report.HasState = true
+			// Transcripts live under projects/-workspace (-workspace-<subdir> with --cwd,
+			// or the mount path's own directory with --mount-at)
+			transcripts, _ := filepath.Glob(filepath.Join(stateDir, "projects", "*", "*.jsonl"))
+			report.TranscriptFiles = len(transcripts)
+			report.ToolSessionID, _ = t.DiscoverSessionID(stateDir)
+		}
+	}
+
+	if report.HasState {
+		// --resume takes an optional value, so the ID must be attached with "="
+		report.ResumeCommand = fmt.Sprintf("coi shell --resume=%s", sessionID)
+	}
+
+	return report, nil
+}
+
+// DirSize returns the total size of the files under path
+func DirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,96 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
+)
+
+func TestBuildReport(t *testing.T) {
+	sessionsDir := t.TempDir()
+	err := SaveMetadataEarly(sessionsDir, SessionMetadata{
+		SessionID:      "abc-123",
+		ContainerName:  "coi-abc-1",
+		Workspace:      "/home/user/project",
+		Persistent:     true,
+		NetworkMode:    "allowlist",
+		AllowedDomains: []string{"github.com"},
+	})
+	if err != nil {
+		t.Fatalf("SaveMetadataEarly() unexpected error: %v", err)
+	}
+	if err := RecordLaunchCommand(sessionsDir, "abc-123", "claude --verbose", map[string]string{"API_TOKEN": "secret"}, "/workspace/sub"); err != nil {
+		t.Fatalf("RecordLaunchCommand() unexpected error: %v", err)
+	}
+
+	projectDir := filepath.Join(sessionsDir, "abc-123", ".claude", "projects", "-workspace-sub")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	transcript := "5f0c6e7a-1b2c-4d3e-8f9a-0b1c2d3e4f5a"
+	if err := os.WriteFile(filepath.Join(projectDir, transcript+".jsonl"), []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := BuildReport(sessionsDir, "abc-123", tool.NewClaude())
+	if err != nil {
+		t.Fatalf("BuildReport() unexpected error: %v", err)
+	}
+
+	if report.Tool != "claude" || report.ContainerName != "coi-abc-1" || report.Workspace != "/home/user/project" {
+		t.Errorf("Expected metadata in the report, got %+v", report)
+	}
+	if !report.Persistent || report.NetworkMode != "allowlist" || len(report.AllowedDomains) != 1 {
+		t.Errorf("Expected persistent allowlist session, got %+v", report)
+	}
+	if report.LaunchCommand != "claude --verbose" || report.LaunchCwd != "/workspace/sub" {
+		t.Errorf("Expected launch command and directory, got %q in %q", report.LaunchCommand, report.LaunchCwd)
+	}
+	if !report.HasState || report.TranscriptFiles != 1 || report.ToolSessionID != transcript {
+		t.Errorf("Expected tool state with one transcript, got %+v", report)
+	}
+	if report.SizeBytes == 0 {
+		t.Error("Expected a non-zero size")
+	}
+	if report.ResumeCommand != "coi shell --resume=abc-123" {
+		t.Errorf("Expected resume hint with --resume=, got %q", report.ResumeCommand)
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v", report.Warnings)
+	}
+}
+
+func TestBuildReportWithoutState(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sessionsDir, "abc-123"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := BuildReport(sessionsDir, "abc-123", tool.NewClaude())
+	if err != nil {
+		t.Fatalf("BuildReport() unexpected error: %v", err)
+	}
+	if report.HasState || report.ResumeCommand != "" {
+		t.Errorf("Expected nothing to resume, got %+v", report)
+	}
+	if len(report.Warnings) != 1 {
+		t.Errorf("Expected a missing metadata warning, got %v", report.Warnings)
+	}
+
+	if err := os.WriteFile(filepath.Join(sessionsDir, "abc-123", "metadata.json"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = BuildReport(sessionsDir, "abc-123", tool.NewClaude())
+	if err != nil {
+		t.Fatalf("BuildReport() unexpected error: %v", err)
+	}
+	if len(report.Warnings) != 1 {
+		t.Errorf("Expected a parse warning, got %v", report.Warnings)
+	}
+
+	if _, err := BuildReport(sessionsDir, "missing", tool.NewClaude()); err == nil {
+		t.Error("Expected an error for a missing session")
+	}
+}
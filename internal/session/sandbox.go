@@ -0,0 +1,116 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseSandboxOverrides parses --sandbox-set key=value pairs into a key -> value map.
+// Values are parsed as JSON (true, 42, {"a":1}); anything that is not valid JSON
+// is kept as a plain string. Keys may use dots to address nested settings
+// (e.g. permissions.defaultMode=acceptEdits).
+func ParseSandboxOverrides(pairs []string) (map[string]interface{}, error) {
+	overrides := make(map[string]interface{})
+	for _, pair := range pairs {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid sandbox setting '%s' - expected key=value", pair)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		for _, segment := range strings.Split(key, ".") {
+			if segment == "" {
+				return nil, fmt.Errorf("invalid sandbox setting key '%s'", key)
+			}
+		}
+
+		var value interface{}
+		if err := json.Unmarshal([]byte(parts[1]), &value); err != nil {
+			value = parts[1]
+		}
+		overrides[key] = value
+	}
+	return overrides, nil
+}
+
+// ApplySandboxOverrides returns a copy of settings with the overrides applied.
+// Dotted keys create or descend into nested objects. The input map is not modified.
+func ApplySandboxOverrides(settings, overrides map[string]interface{}) map[string]interface{} {
+	result := copySettings(settings)
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+
+	// Sorted so a parent key ("permissions") is applied before its children
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		segments := strings.Split(key, ".")
+		current := result
+		for _, segment := range segments[:len(segments)-1] {
+			next, ok := current[segment].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				current[segment] = next
+			}
+			current = next
+		}
+		current[segments[len(segments)-1]] = overrides[key]
+	}
+
+	return result
+}
+
+// copySettings deep-copies nested settings maps so overrides never mutate the tool defaults
+func copySettings(settings map[string]interface{}) map[string]interface{} {
+	if settings == nil {
+		return nil
+	}
+	result := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			result[key] = copySettings(v)
+		case map[string]string:
+			nested := make(map[string]interface{}, len(v))
+			for k, s := range v {
+				nested[k] = s
+			}
+			result[key] = nested
+		default:
+			result[key] = v
+		}
+	}
+	return result
+}
+
+// MergeSettingsJSON merges settings into the JSON object in existing (top-level
+// keys replace the existing ones) and returns the indented result. Empty input
+// starts a new object.
+func MergeSettingsJSON(existing []byte, settings map[string]interface{}) ([]byte, error) {
+	merged := make(map[string]interface{})
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := json.Unmarshal(existing, &merged); err != nil {
+			return nil, fmt.Errorf("not a JSON object: %w", err)
+		}
+		if merged == nil {
+			merged = make(map[string]interface{})
+		}
+	}
+	for key, value := range settings {
+		merged[key] = value
+	}
+
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,113 @@
+package session
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseSandboxOverrides(t *testing.T) {
+	overrides, err := ParseSandboxOverrides([]string{
+		"bypassPermissionsModeAccepted=false",
+		"permissions.defaultMode=acceptEdits",
+		`env={"FOO":"bar"}`,
+		"maxTurns=3",
+	})
+	if err != nil {
+		t.Fatalf("ParseSandboxOverrides() unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"bypassPermissionsModeAccepted": false,
+		"permissions.defaultMode":       "acceptEdits",
+		"env":                           map[string]interface{}{"FOO": "bar"},
+		"maxTurns":                      float64(3),
+	}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected %v, got %v", expected, overrides)
+	}
+
+	invalid := []string{"novalue", "=x", "a..b=1", ".a=1"}
+	for _, pair := range invalid {
+		if _, err := ParseSandboxOverrides([]string{pair}); err == nil {
+			t.Errorf("Expected error for '%s'", pair)
+		}
+	}
+}
+
+func TestApplySandboxOverrides(t *testing.T) {
+	base := map[string]interface{}{
+		"allowDangerouslySkipPermissions": true,
+		"permissions": map[string]string{
+			"defaultMode": "bypassPermissions",
+		},
+	}
+
+	result := ApplySandboxOverrides(base, map[string]interface{}{
+		"permissions.defaultMode":         "acceptEdits",
+		"allowDangerouslySkipPermissions": false,
+		"extra.nested.key":                true,
+	})
+
+	expected := map[string]interface{}{
+		"allowDangerouslySkipPermissions": false,
+		"permissions": map[string]interface{}{
+			"defaultMode": "acceptEdits",
+		},
+		"extra": map[string]interface{}{
+			"nested": map[string]interface{}{"key": true},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	// Tool defaults must not be modified
+	if base["permissions"].(map[string]string)["defaultMode"] != "bypassPermissions" {
+		t.Errorf("Expected base settings to be unchanged, got %v", base)
+	}
+	if base["allowDangerouslySkipPermissions"] != true {
+		t.Errorf("Expected base settings to be unchanged, got %v", base)
+	}
+}
+
+func TestMergeSettingsJSON(t *testing.T) {
+	settings := map[string]interface{}{
+		"mode":        "it's new; $(rm -rf /)",
+		"permissions": map[string]interface{}{"defaultMode": "bypassPermissions"},
+	}
+
+	merged, err := MergeSettingsJSON([]byte(`{"keep": 1, "mode": "old", "permissions": {"allow": ["Bash"]}}`), settings)
+	if err != nil {
+		t.Fatalf("MergeSettingsJSON() unexpected error: %v", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(merged, &result); err != nil {
+		t.Fatalf("merged output is not valid JSON: %v\n%s", err, merged)
+	}
+	if result["keep"] != float64(1) || result["mode"] != settings["mode"] {
+		t.Errorf("Unexpected merge result: %v", result)
+	}
+	// Top-level keys are replaced, not deep-merged
+	permissions, _ := result["permissions"].(map[string]interface{})
+	if _, ok := permissions["allow"]; ok || permissions["defaultMode"] != "bypassPermissions" {
+		t.Errorf("Expected permissions to be replaced, got %v", permissions)
+	}
+
+	// Missing or empty files start a new object
+	for _, existing := range []string{"", "  \n"} {
+		merged, err := MergeSettingsJSON([]byte(existing), settings)
+		if err != nil {
+			t.Fatalf("MergeSettingsJSON(%q) unexpected error: %v", existing, err)
+		}
+		if err := json.Unmarshal(merged, &result); err != nil || result["mode"] != settings["mode"] {
+			t.Errorf("MergeSettingsJSON(%q) = %s", existing, merged)
+		}
+	}
+
+	for _, invalid := range []string{`{"truncated": `, `["not", "an", "object"]`} {
+		if _, err := MergeSettingsJSON([]byte(invalid), settings); err == nil {
+			t.Errorf("MergeSettingsJSON(%q) expected error", invalid)
+		}
+	}
+}
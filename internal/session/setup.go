@@ -2,10 +2,11 @@ package session
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -47,16 +48,6 @@ func isColimaOrLimaEnvironment() bool {
 	return false
 }
 
-// buildJSONFromSettings converts a settings map to a properly escaped JSON string
-// Uses json.Marshal to ensure proper escaping and avoid command injection
-func buildJSONFromSettings(settings map[string]interface{}) (string, error) {
-	jsonBytes, err := json.Marshal(settings)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal settings: %w", err)
-	}
-	return string(jsonBytes), nil
-}
-
 // setupMounts mounts all configured directories to the container
 func setupMounts(mgr *container.Manager, mountConfig *MountConfig, useShift bool, logger func(string)) error {
 	if mountConfig == nil || len(mountConfig.Mounts) == 0 {
@@ -80,22 +71,120 @@ func setupMounts(mgr *container.Manager, mountConfig *MountConfig, useShift bool
 	return nil
 }
 
+const (
+	// SSHAgentDeviceName is the Incus proxy device used to forward the host SSH agent
+	SSHAgentDeviceName = "ssh-agent"
+	// SSHAgentContainerSocket is where the forwarded SSH agent socket appears in the container
+	SSHAgentContainerSocket = "/tmp/coi-ssh-agent.sock"
+)
+
+// consoleLogTailLines is how much of the console log a boot failure includes
+const consoleLogTailLines = 20
+
+// readyProbeTimeout bounds how long Setup waits for [defaults] ready_probe
+const readyProbeTimeout = 2 * time.Minute
+
+// readyProbeAttemptTimeout bounds a single run of the ready probe
+const readyProbeAttemptTimeout = 15 * time.Second
+
+// readyProbeReportInterval is how often Setup reports it is still waiting for the probe
+const readyProbeReportInterval = 10 * time.Second
+
 // SetupOptions contains options for setting up a session
 type SetupOptions struct {
-	WorkspacePath string
-	Image         string
-	Persistent    bool // Keep container between sessions (don't delete on cleanup)
-	ResumeFromID  string
-	Slot          int
-	MountConfig   *MountConfig // Multi-mount support
-	SessionsDir   string       // e.g., ~/.coi/sessions-claude
-	CLIConfigPath string       // e.g., ~/.claude (host CLI config to copy credentials from)
-	Tool          tool.Tool    // AI coding tool being used
-	NetworkConfig *config.NetworkConfig
-	DisableShift  bool                 // Disable UID shifting (for Colima/Lima environments)
-	LimitsConfig  *config.LimitsConfig // Resource and time limits
-	IncusProject  string               // Incus project name
-	Logger        func(string)
+	WorkspacePath    string
+	MountPath        string // Where the workspace is mounted in the container (default: ContainerWorkspacePath)
+	Image            string
+	Persistent       bool // Keep container between sessions (don't delete on cleanup)
+	ResumeFromID     string
+	StrictResume     bool // Fail instead of warning when resume state can't be restored
+	Slot             int
+	Name             string           // Explicit container name (coi shell --name); replaces the workspace slot name
+	MountConfig      *MountConfig     // Multi-mount support
+	HomeCacheDir     string           // Host directory mounted at ~/.cache so tool caches outlive the container (--mount-home)
+	SessionsDir      string           // e.g., ~/.coi/sessions-claude
+	CLIConfigPath    string           // e.g., ~/.claude (host CLI config to copy credentials from)
+	CredentialSource CredentialSource // Where the tool credentials come from (default: CLIConfigPath)
+	Tool             tool.Tool        // AI coding tool being used
+	NetworkConfig    *config.NetworkConfig
+	DisableShift     bool                   // Disable UID shifting (for Colima/Lima environments)
+	ForceShift       bool                   // Use UID shifting even where it would be auto-disabled (--shift)
+	LimitsConfig     *config.LimitsConfig   // Resource and time limits
+	IncusProject     string                 // Incus project name
+	SSHAgentSocket   string                 // Host SSH agent socket to forward (empty = disabled)
+	CoiDerived       bool                   // Image was published from a coi container (e.g. coi clone), run as code user
+	PullImage        bool                   // Fetch the latest version of the image before creating a new container (--pull-image)
+	SandboxOverrides map[string]interface{} // Per-invocation overrides of the tool's sandbox settings (--sandbox-set)
+	SkipSettings     bool                   // Copy the tool's config files without merging sandbox settings (--no-inject-settings)
+	NoCredentials    bool                   // Copy no host credentials or tool config, only the sandbox settings (--no-credentials)
+	Labels           map[string]string      // Session labels stored as user.coi.label.* config keys
+	SlotLock         *SlotLock              // Released once the container is running (see LockWorkspaceSlots)
+	Timezone         string                 // IANA zone to set in the container (empty = leave UTC)
+	ReadyProbe       string                 // Command that must exit 0 before setup finishes (empty = container running is enough)
+	CheckClockDrift  bool                   // Warn when the container clock differs from the host's
+	GitIdentity      *GitIdentity           // Host git identity to copy into the container (nil = don't)
+	Logger           func(string)
+}
+
+// imagePull is how --pull-image updates an image
+type imagePull int
+
+const (
+	// pullNothing: the image is built locally (coi or derived from it)
+	pullNothing imagePull = iota
+	// pullRemote: download the latest version from the image's remote
+	pullRemote
+	// pullRefresh: refresh a local alias from the remote it was copied from, if any
+	pullRefresh
+)
+
+// imagePullFor decides how --pull-image updates image
+func imagePullFor(image string, coiImage bool) imagePull {
+	if coiImage {
+		return pullNothing
+	}
+	if _, _, remote := container.RemoteImageRef(image); remote {
+		return pullRemote
+	}
+	return pullRefresh
+}
+
+// pullImage fetches the latest version of image before a new container is
+// created from it (--pull-image). Only remote images can fail the launch;
+// local images without a remote to refresh from are used as they are.
+func pullImage(image string, coiImage bool, logger func(string)) error {
+	switch imagePullFor(image, coiImage) {
+	case pullNothing:
+		logger("Note: --pull-image has no effect on locally built images - rebuild with 'coi build --force' to update the base")
+		return nil
+	case pullRemote:
+		logger(fmt.Sprintf("Pulling latest %s...", image))
+		return container.RefreshImage(image)
+	default:
+		logger(fmt.Sprintf("Refreshing %s...", image))
+		if err := container.RefreshImage(image); err != nil {
+			logger(fmt.Sprintf("Note: --pull-image could not refresh local image %s, using it as is: %v", image, err))
+		}
+		return nil
+	}
+}
+
+// stoppedContainerAction decides what Setup does with an existing stopped
+// container: restart it (persistent mode) or delete it as a leftover. A named
+// container (--name) is a user-owned environment and a kept container may
+// hold persistent data (see stoppedContainerKept), so neither is deleted
+// without --persistent.
+func stoppedContainerAction(containerName string, persistent, named bool, kept func(string) bool) (restart bool, err error) {
+	switch {
+	case persistent:
+		return true, nil
+	case named:
+		return false, fmt.Errorf("container %s is a stopped named environment - use --persistent to reuse it, or 'coi kill %s' to delete it first", containerName, containerName)
+	case kept(containerName):
+		// Persistent (possibly marked by an older coi only in its session metadata) or in its grace period
+		return false, fmt.Errorf("container %s is stopped but may hold persistent data - use --persistent to reuse it, or 'coi kill %s' to delete it first", containerName, containerName)
+	}
+	return false, nil
 }
 
 // SetupResult contains the result of setup
@@ -125,6 +214,12 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 
 	// 1. Generate container name
 	containerName := ContainerName(opts.WorkspacePath, opts.Slot)
+	if opts.Name != "" {
+		var err error
+		if containerName, err = NamedContainerName(opts.Name); err != nil {
+			return nil, err
+		}
+	}
 	result.ContainerName = containerName
 	result.Manager = container.NewManager(containerName)
 	opts.Logger(fmt.Sprintf("Container name: %s", containerName))
@@ -168,6 +263,31 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 		}
 	}
 
+	// Custom DNS servers must be IPs, and in allowlist mode they have to be allowed too
+	if opts.NetworkConfig != nil && len(opts.NetworkConfig.DNSServers) > 0 {
+		if err := ValidateDNSServers(opts.NetworkConfig.DNSServers); err != nil {
+			return nil, err
+		}
+		if opts.NetworkConfig.Mode == config.NetworkModeAllowlist {
+			for _, server := range opts.NetworkConfig.DNSServers {
+				if !slices.Contains(opts.NetworkConfig.AllowedDomains, server) {
+					opts.Logger(fmt.Sprintf("Warning: DNS server %s is not in allowed_domains - the allowlist firewall will block it", server))
+				}
+			}
+		}
+	}
+
+	// 1.6 Fetch keyring credentials up front, so a locked or missing entry fails before launch
+	var keyringSecret string
+	if opts.CredentialSource.Keyring && !opts.NoCredentials && opts.Tool != nil && opts.Tool.ConfigDirName() != "" {
+		secret, err := opts.CredentialSource.ReadSecret()
+		if err != nil {
+			return nil, err
+		}
+		keyringSecret = secret
+		opts.Logger(fmt.Sprintf("Using credentials from %s", opts.CredentialSource))
+	}
+
 	// 2. Determine image
 	image := opts.Image
 	if image == "" {
@@ -175,19 +295,21 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 	}
 	result.Image = image
 
-	// Check if image exists
-	exists, err := container.ImageExists(image)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check image: %w", err)
-	}
-	if !exists {
-		return nil, fmt.Errorf("image '%s' not found - run 'coi build' first", image)
+	// Check if image exists (remote references are resolved by incus init)
+	if _, _, remote := container.RemoteImageRef(image); !remote {
+		exists, err := container.ImageExists(image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check image: %w", err)
+		}
+		if !exists {
+			return nil, fmt.Errorf("image '%s' not found - run 'coi build' first", image)
+		}
 	}
 
 	// 3. Determine execution context
 	// coi image has the claude user pre-configured, so run as that user
 	// Other images don't have this setup, so run as root
-	usingCoiImage := image == CoiImage
+	usingCoiImage := image == CoiImage || opts.CoiDerived
 	result.RunAsRoot = !usingCoiImage
 	if result.RunAsRoot {
 		result.HomeDir = "/root"
@@ -197,7 +319,7 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 
 	// 4. Check if container already exists
 	var skipLaunch bool
-	exists, err = result.Manager.Exists()
+	exists, err := result.Manager.Exists()
 	if err != nil {
 		return nil, fmt.Errorf("failed to check if container exists: %w", err)
 	}
@@ -214,6 +336,8 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 			if opts.Persistent {
 				opts.Logger("Container already running, reusing...")
 				skipLaunch = true
+			} else if opts.Name != "" {
+				return nil, fmt.Errorf("container %s is already running - attach with 'coi attach %s', or use --persistent to reuse it", containerName, opts.Name)
 			} else {
 				// ERROR: A running container exists for this slot, but we're not in persistent mode
 				// This means AllocateSlot() gave us a slot that's already in use!
@@ -221,7 +345,11 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 			}
 		} else {
 			// Container exists but is stopped
-			if opts.Persistent {
+			restart, err := stoppedContainerAction(containerName, opts.Persistent, opts.Name != "", containerKeptWhenStopped)
+			if err != nil {
+				return nil, err
+			}
+			if restart {
 				// Restart the stopped persistent container
 				opts.Logger("Restarting existing persistent container...")
 				if err := result.Manager.Start(); err != nil {
@@ -240,55 +368,107 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 		}
 	}
 
+	// A reused container may have been kept for delete_grace_minutes by an
+	// earlier run (and started again since); it must not be deleted later
+	if skipLaunch {
+		_ = ClearDeleteAfter(result.Manager)
+	}
+
+	// A reused container keeps its workspace mount, so it must be where the tool will run
+	mountPath := opts.MountPath
+	if mountPath == "" {
+		mountPath = ContainerWorkspacePath
+	}
+	if skipLaunch {
+		if current := WorkspaceMountPath(result.Manager); current != mountPath {
+			return nil, fmt.Errorf("container %s has the workspace mounted at %s, not %s - use --mount-at %s or delete the container first", result.ContainerName, current, mountPath, current)
+		}
+	}
+
+	// 4.5 A new container needs the host tool config to copy credentials from.
+	// Without it the session starts like --no-credentials, with only the sandbox settings.
+	if !skipLaunch && opts.ResumeFromID == "" && !opts.NoCredentials && !opts.CredentialSource.Keyring &&
+		opts.Tool != nil && opts.Tool.ConfigDirName() != "" && opts.CLIConfigPath != "" {
+		missing, err := checkHostCLIConfig(opts.CLIConfigPath, opts.Tool)
+		if err != nil {
+			return nil, err
+		}
+		if missing != "" {
+			opts.Logger("Warning: " + missing)
+			opts.NoCredentials = true
+		}
+	}
+
+	// Failures once the container exists must not leave a container this run
+	// created behind (holding its slot), see abortLaunch
+	created := false
+	fail := func(err error) (*SetupResult, error) {
+		if created {
+			abortLaunch(result, opts.Logger)
+		}
+		return nil, err
+	}
+
 	// 5. Create and configure container (but don't start yet if we need to add devices)
 	// Always launch as non-ephemeral so we can save session data even if container is stopped
 	// (e.g., via 'sudo shutdown 0' from within). Cleanup will delete if not --persistent.
 	if !skipLaunch {
+		if opts.PullImage {
+			if err := pullImage(image, usingCoiImage, opts.Logger); err != nil {
+				return nil, err
+			}
+		}
+
 		opts.Logger(fmt.Sprintf("Creating container from %s...", image))
 		// Create container without starting it (init)
-		if err := container.IncusExec("init", image, result.ContainerName); err != nil {
+		initArgs := []string{"init", image, result.ContainerName}
+		// Record the mode so a stopped persistent container keeps its slot
+		initArgs = append(initArgs, "--config", fmt.Sprintf("%s=%t", PersistentConfigKey, opts.Persistent))
+		if err := container.IncusExec(initArgs...); err != nil {
 			return nil, fmt.Errorf("failed to create container: %w", err)
 		}
+		created = true
 
 		// Configure UID/GID mapping for bind mounts based on environment
 		// Local: Use shift=true (kernel idmap support)
 		// CI: Use raw.idmap (kernel lacks idmap support, runner UID 1001 → container UID 1000)
 		// Colima/Lima: Disable shift (VM already handles UID mapping via virtiofs)
 
-		// Auto-detect Colima/Lima environment if not explicitly configured
-		disableShift := opts.DisableShift
-		if !disableShift && isColimaOrLimaEnvironment() {
-			disableShift = true
-			opts.Logger("Auto-detected Colima/Lima environment - disabling UID shifting")
-		}
-
-		useShift := !disableShift
+		// Auto-detect Colima/Lima environment unless forced either way
+		useShift := ResolveUIDShift(opts.DisableShift, opts.ForceShift, opts.Logger)
 		isCI := os.Getenv("CI") == "true" || os.Getenv("GITHUB_ACTIONS") == "true"
 
-		if isCI {
+		if isCI && !opts.ForceShift {
 			opts.Logger("Configuring UID/GID mapping for CI environment...")
 			if err := container.IncusExec("config", "set", result.ContainerName, "raw.idmap", "both 1001 1000"); err != nil {
 				opts.Logger(fmt.Sprintf("Warning: Failed to set raw.idmap: %v", err))
 			}
 			useShift = false // Don't use shift=true with raw.idmap
-		} else if disableShift {
-			if !opts.DisableShift {
-				// Was auto-detected, not explicitly configured
-				opts.Logger("UID shifting disabled (auto-detected Colima/Lima environment)")
-			} else {
-				opts.Logger("UID shifting disabled (configured via disable_shift option)")
-			}
+		} else if opts.DisableShift {
+			opts.Logger("UID shifting disabled (disable_shift option or --disable-shift)")
 		}
 
 		// Add disk devices BEFORE starting container
 		opts.Logger(fmt.Sprintf("Adding workspace mount: %s", opts.WorkspacePath))
-		if err := result.Manager.MountDisk("workspace", opts.WorkspacePath, "/workspace", useShift); err != nil {
-			return nil, fmt.Errorf("failed to add workspace device: %w", err)
+		if err := result.Manager.MountDisk("workspace", opts.WorkspacePath, mountPath, useShift); err != nil {
+			return fail(fmt.Errorf("failed to add workspace device: %w", err))
 		}
 
 		// Mount all configured directories
 		if err := setupMounts(result.Manager, opts.MountConfig, useShift, opts.Logger); err != nil {
-			return nil, err
+			return fail(err)
+		}
+
+		// Persistent tool caches (npm, pip, build caches) shared by the workspace's sessions
+		if opts.HomeCacheDir != "" {
+			if err := os.MkdirAll(opts.HomeCacheDir, 0o755); err != nil {
+				return fail(fmt.Errorf("failed to create cache directory '%s': %w", opts.HomeCacheDir, err))
+			}
+			cachePath := filepath.Join(result.HomeDir, ".cache")
+			opts.Logger(fmt.Sprintf("Adding cache mount: %s -> %s", opts.HomeCacheDir, cachePath))
+			if err := result.Manager.MountDisk(HomeCacheDevice, opts.HomeCacheDir, cachePath, useShift); err != nil {
+				return fail(fmt.Errorf("failed to add cache mount: %w", err))
+			}
 		}
 
 		// Apply resource limits before starting (if configured)
@@ -318,28 +498,106 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 				Project: opts.IncusProject,
 			}
 			if err := limits.ApplyResourceLimits(applyOpts); err != nil {
-				return nil, fmt.Errorf("failed to apply resource limits: %w", err)
+				return fail(fmt.Errorf("failed to apply resource limits: %w", err))
 			}
 		}
 
 		// Now start the container
 		opts.Logger("Starting container...")
 		if err := result.Manager.Start(); err != nil {
-			return nil, fmt.Errorf("failed to start container: %w", err)
+			return fail(fmt.Errorf("failed to start container: %w", err))
+		}
+	}
+
+	// The container now occupies its slot (running), so other coi runs may allocate
+	opts.SlotLock.Release()
+
+	// Apply session labels (also updates labels on reused persistent containers)
+	if len(opts.Labels) > 0 {
+		opts.Logger(fmt.Sprintf("Setting labels: %s", FormatLabels(opts.Labels)))
+		for key, value := range opts.Labels {
+			if err := result.Manager.SetUserConfig(LabelConfigPrefix+key, value); err != nil {
+				opts.Logger(fmt.Sprintf("Warning: Failed to set label %s: %v", key, err))
+			}
 		}
 	}
 
 	// 6. Wait for ready
 	opts.Logger("Waiting for container to be ready...")
 	if err := waitForReady(result.Manager, 30, opts.Logger); err != nil {
-		return nil, err
+		return fail(err)
+	}
+
+	// Use the configured nameservers instead of the Incus network's DNS
+	// (re-applied on reuse so config changes carry over)
+	if opts.NetworkConfig != nil && len(opts.NetworkConfig.DNSServers) > 0 {
+		if err := setContainerDNS(result.Manager, opts.NetworkConfig.DNSServers); err != nil {
+			return fail(fmt.Errorf("failed to configure DNS servers: %w", err))
+		}
+		opts.Logger(fmt.Sprintf("DNS servers set to %s", strings.Join(opts.NetworkConfig.DNSServers, ", ")))
+	}
+	if opts.ReadyProbe != "" {
+		opts.Logger(fmt.Sprintf("Waiting for ready probe: %s", opts.ReadyProbe))
+		if err := waitForReadyProbe(result.Manager, opts.ReadyProbe, readyProbeTimeout, opts.Logger); err != nil {
+			return fail(err)
+		}
+	}
+
+	// The mount point of the persistent cache is created by root (best effort,
+	// not recursive: the cache contents already belong to the code user)
+	if opts.HomeCacheDir != "" && !result.RunAsRoot {
+		cachePath := filepath.Join(result.HomeDir, ".cache")
+		owner := fmt.Sprintf("%d:%d", container.CodeUID, container.CodeUID)
+		if _, err := result.Manager.ExecArgsCapture([]string{"chown", owner, cachePath}, container.ExecCommandOptions{}); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Could not set ownership of %s: %v", cachePath, err))
+		}
+	}
+
+	// Fail before launching when the tool is missing, instead of leaving the
+	// user with a tool command failing in a background tmux pane
+	if opts.Tool != nil {
+		if err := ValidateTool(result.Manager, opts.Tool, image, result.RunAsRoot); err != nil {
+			return fail(err)
+		}
+	}
+
+	// A drifted clock breaks TLS and token expiry checks in confusing ways (best effort)
+	if opts.CheckClockDrift {
+		if drift, err := MeasureClockDrift(result.Manager); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Could not check container clock: %v", err))
+		} else if ClockDriftExceeded(drift) {
+			opts.Logger(fmt.Sprintf("Warning: %s - TLS and token expiry checks may fail; restart the container (or fix time sync in the VM) to resync it", DescribeClockDrift(drift)))
+		}
+	}
+
+	// Match the host timezone so commit and log times make sense (best effort)
+	if opts.Timezone != "" {
+		if err := setContainerTimezone(result.Manager, opts.Timezone); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Could not set timezone: %v", err))
+		} else {
+			opts.Logger(fmt.Sprintf("Timezone set to %s", opts.Timezone))
+		}
+	}
+
+	// Commit as the host user instead of "unknown" (best effort, re-applied on
+	// reuse so identity changes on the host carry over)
+	if opts.GitIdentity != nil && !opts.GitIdentity.Empty() {
+		uid := container.CodeUID
+		if result.RunAsRoot {
+			uid = 0
+		}
+		if err := applyGitIdentity(result.Manager, *opts.GitIdentity, result.HomeDir, uid); err != nil {
+			opts.Logger(fmt.Sprintf("Warning: Could not set git identity: %v", err))
+		} else {
+			opts.Logger(fmt.Sprintf("Git identity set to %s <%s>", opts.GitIdentity.Name, opts.GitIdentity.Email))
+		}
 	}
 
 	// 7. Start timeout monitor if max_duration is configured
 	if opts.LimitsConfig != nil && opts.LimitsConfig.Runtime.MaxDuration != "" {
 		duration, err := limits.ParseDuration(opts.LimitsConfig.Runtime.MaxDuration)
 		if err != nil {
-			return nil, fmt.Errorf("invalid max_duration: %w", err)
+			return fail(fmt.Errorf("invalid max_duration: %w", err))
 		}
 		if duration > 0 {
 			result.TimeoutMonitor = limits.NewTimeoutMonitor(
@@ -354,11 +612,33 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 		}
 	}
 
+	// Forward the host SSH agent (hot-plugged, so it also works for reused persistent containers)
+	if opts.SSHAgentSocket != "" {
+		opts.Logger("Forwarding SSH agent...")
+		if err := result.Manager.AddSocketProxy(SSHAgentDeviceName, opts.SSHAgentSocket, SSHAgentContainerSocket, container.CodeUID, container.CodeUID); err != nil {
+			return fail(fmt.Errorf("failed to forward SSH agent: %w", err))
+		}
+	} else if skipLaunch {
+		// A reused container may still forward the agent of an earlier --ssh-agent session
+		if err := result.Manager.RemoveDevice(SSHAgentDeviceName); err != nil {
+			return fail(fmt.Errorf("failed to remove the SSH agent forwarded by an earlier session: %w", err))
+		}
+	}
+
 	// 8. Setup network isolation (after container is running and has IP)
 	if opts.NetworkConfig != nil {
 		result.NetworkManager = network.NewManager(opts.NetworkConfig)
 		if err := result.NetworkManager.SetupForContainer(context.Background(), result.ContainerName); err != nil {
-			return nil, fmt.Errorf("failed to setup network isolation: %w", err)
+			return fail(fmt.Errorf("failed to setup network isolation: %w", err))
+		}
+	}
+
+	// Tool sandbox settings with per-invocation overrides applied
+	var sandboxSettings map[string]interface{}
+	if opts.Tool != nil && !opts.SkipSettings {
+		sandboxSettings = opts.Tool.GetSandboxSettings()
+		if len(opts.SandboxOverrides) > 0 {
+			sandboxSettings = ApplySandboxOverrides(sandboxSettings, opts.SandboxOverrides)
 		}
 	}
 
@@ -368,18 +648,32 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 		// If we launched a new container (not reusing persistent one), restore config from saved session
 		if !skipLaunch && opts.SessionsDir != "" {
 			if err := restoreSessionData(result.Manager, opts.ResumeFromID, result.HomeDir, opts.SessionsDir, opts.Tool, opts.Logger); err != nil {
+				if opts.StrictResume {
+					return fail(fmt.Errorf("could not restore session data (--strict-resume): %w", err))
+				}
 				opts.Logger(fmt.Sprintf("Warning: Could not restore session data: %v", err))
 			}
 		}
 
 		// Always inject fresh credentials when resuming (whether persistent container or restored session)
-		if opts.CLIConfigPath != "" {
-			if err := injectCredentials(result.Manager, opts.CLIConfigPath, result.HomeDir, opts.Tool, opts.Logger); err != nil {
+		if opts.CLIConfigPath != "" && !opts.NoCredentials {
+			if err := injectCredentials(result.Manager, opts.CLIConfigPath, result.HomeDir, opts.Tool, keyringSecret, sandboxSettings, opts.Logger); err != nil {
+				if opts.StrictResume {
+					return fail(fmt.Errorf("could not inject credentials (--strict-resume): %w", err))
+				}
 				opts.Logger(fmt.Sprintf("Warning: Could not inject credentials: %v", err))
 			}
 		}
 	}
 
+	// --no-credentials: restored session data or a reused container can still
+	// hold credentials from an earlier run, which the sandbox must not get
+	if credentialsMayRemain(opts.NoCredentials, opts.ResumeFromID != "", skipLaunch) && opts.Tool != nil && opts.Tool.ConfigDirName() != "" {
+		if _, err := result.Manager.ExecCommand(removeCredentialsCommand(result.HomeDir, opts.Tool.ConfigDirName()), container.ExecCommandOptions{Capture: true}); err != nil {
+			return fail(fmt.Errorf("could not remove credentials from the container (--no-credentials): %w", err))
+		}
+	}
+
 	// 10. Workspace and configured mounts are already mounted (added before container start in step 5)
 	if skipLaunch {
 		opts.Logger("Reusing existing workspace and mount configurations")
@@ -388,21 +682,29 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 	// 11. Setup CLI tool config (skip if resuming - config already restored)
 	// Skip entirely if tool uses ENV-based auth (ConfigDirName returns "")
 	if opts.Tool != nil && opts.Tool.ConfigDirName() != "" {
-		if opts.CLIConfigPath != "" && opts.ResumeFromID == "" {
+		if opts.NoCredentials && opts.ResumeFromID == "" {
+			// Unauthenticated sandbox: the tool gets the sandbox settings but nothing from the host
+			if !skipLaunch {
+				opts.Logger(fmt.Sprintf("Setting up %s config without host credentials...", opts.Tool.Name()))
+				if err := setupSandboxOnlyConfig(result.Manager, result.HomeDir, opts.Tool, sandboxSettings, opts.Logger); err != nil {
+					opts.Logger(fmt.Sprintf("Warning: Failed to setup %s config: %v", opts.Tool.Name(), err))
+				}
+			}
+		} else if opts.CLIConfigPath != "" && opts.ResumeFromID == "" {
 			// Check if host config directory exists
 			if _, err := os.Stat(opts.CLIConfigPath); err == nil {
 				// Copy and inject settings (but only if NOT resuming)
 				// Only run on first launch, not when restarting persistent container
 				if !skipLaunch {
 					opts.Logger(fmt.Sprintf("Setting up %s config...", opts.Tool.Name()))
-					if err := setupCLIConfig(result.Manager, opts.CLIConfigPath, result.HomeDir, opts.Tool, opts.Logger); err != nil {
+					if err := setupCLIConfig(result.Manager, opts.CLIConfigPath, result.HomeDir, opts.Tool, keyringSecret, sandboxSettings, opts.Logger); err != nil {
 						opts.Logger(fmt.Sprintf("Warning: Failed to setup %s config: %v", opts.Tool.Name(), err))
 					}
 				} else {
 					opts.Logger(fmt.Sprintf("Reusing existing %s config (persistent container)", opts.Tool.Name()))
 				}
 			} else if !os.IsNotExist(err) {
-				return nil, fmt.Errorf("failed to check %s config directory: %w", opts.Tool.Name(), err)
+				return fail(fmt.Errorf("failed to check %s config directory: %w", opts.Tool.Name(), err))
 			}
 		} else if opts.ResumeFromID != "" {
 			opts.Logger(fmt.Sprintf("Resuming session - using restored %s config", opts.Tool.Name()))
@@ -415,6 +717,27 @@ func Setup(opts SetupOptions) (*SetupResult, error) {
 	return result, nil
 }
 
+// abortLaunch undoes a launch that failed after Setup created the container:
+// the container is deleted, also a persistent one (it never finished setup, and
+// reusing it would skip the config setup of a first launch), and the network
+// isolation and timeout monitor set up for it are stopped
+func abortLaunch(result *SetupResult, logger func(string)) {
+	if result.TimeoutMonitor != nil {
+		result.TimeoutMonitor.Stop()
+	}
+
+	logger(fmt.Sprintf("Setup failed, removing container %s...", result.ContainerName))
+	if err := result.Manager.Delete(true); err != nil {
+		logger(fmt.Sprintf("Warning: Failed to delete container: %v", err))
+	}
+
+	if result.NetworkManager != nil {
+		if err := result.NetworkManager.Teardown(context.Background(), result.ContainerName); err != nil {
+			logger(fmt.Sprintf("Warning: Failed to tear down network: %v", err))
+		}
+	}
+}
+
 // waitForReady waits for container to be ready
 func waitForReady(mgr *container.Manager, maxRetries int, logger func(string)) error {
 	for i := 0; i < maxRetries; i++ {
@@ -437,9 +760,77 @@ func waitForReady(mgr *container.Manager, maxRetries int, logger func(string)) e
 		}
 	}
 
+	// The console log usually shows why (a failing init unit, a full disk, ...)
+	if consoleLog, err := mgr.ConsoleLog(); err == nil && strings.TrimSpace(consoleLog) != "" {
+		return fmt.Errorf("container failed to become ready after %d seconds; last console output (coi container logs %s):\n%s",
+			maxRetries, mgr.ContainerName, container.TailLines(consoleLog, consoleLogTailLines))
+	}
 	return fmt.Errorf("container failed to become ready after %d seconds", maxRetries)
 }
 
+// ValidateTool checks the tool's binary is on the PATH of the user it will run as
+func ValidateTool(mgr *container.Manager, t tool.Tool, image string, runAsRoot bool) error {
+	user := container.CodeUID
+	if runAsRoot {
+		user = 0
+	}
+	return t.Validate(image, func(name string) bool {
+		_, err := mgr.ExecCommand("command -v "+container.ShellQuote(name)+" >/dev/null 2>&1", container.ExecCommandOptions{User: &user, Capture: true})
+		return err == nil
+	})
+}
+
+// waitForReadyProbe runs probe (bash -c, as root) every second until it exits 0,
+// e.g. so a database or language server in a custom image is up before the tool starts
+func waitForReadyProbe(mgr *container.Manager, probe string, timeout time.Duration, logger func(string)) error {
+	run := func(ctx context.Context) (string, error) {
+		return mgr.ExecArgsCaptureContext(ctx, []string{"bash", "-c", probe}, container.ExecCommandOptions{})
+	}
+	return pollReadyProbe(probe, run, timeout, readyProbeAttemptTimeout, time.Second, logger)
+}
+
+// pollReadyProbe runs the probe every interval until it succeeds or timeout
+// passes. Each run is cancelled after attemptTimeout (or at the overall
+// deadline), so a probe that hangs is retried instead of blocking setup.
+func pollReadyProbe(probe string, run func(context.Context) (string, error), timeout, attemptTimeout, interval time.Duration, logger func(string)) error {
+	start := time.Now()
+	deadline := start.Add(timeout)
+	lastReport := start
+	var lastErr error
+	var lastOutput string
+	for attempt := 1; ; attempt++ {
+		limit := attemptTimeout
+		if remaining := time.Until(deadline); remaining > 0 && remaining < limit {
+			limit = remaining
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), limit)
+		lastOutput, lastErr = run(ctx)
+		if lastErr != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			lastErr = fmt.Errorf("probe did not finish within %s", limit)
+		}
+		cancel()
+
+		if lastErr == nil {
+			logger(fmt.Sprintf("Ready probe passed after %s (%d attempt(s))", time.Since(start).Round(time.Second), attempt))
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+
+		time.Sleep(min(interval, time.Until(deadline)))
+		if time.Since(lastReport) >= readyProbeReportInterval {
+			lastReport = time.Now()
+			logger(fmt.Sprintf("Still waiting for ready probe... (%s elapsed)", time.Since(start).Round(time.Second)))
+		}
+	}
+
+	if output := strings.TrimSpace(lastOutput); output != "" {
+		return fmt.Errorf("ready probe %q did not succeed within %s: %w (output: %s)", probe, timeout, lastErr, output)
+	}
+	return fmt.Errorf("ready probe %q did not succeed within %s: %w", probe, timeout, lastErr)
+}
+
 // restoreSessionData restores tool config directory from a saved session
 // Used when resuming a non-persistent session (container was deleted and recreated)
 func restoreSessionData(mgr *container.Manager, resumeID, homeDir, sessionsDir string, t tool.Tool, logger func(string)) error {
@@ -457,7 +848,7 @@ func restoreSessionData(mgr *container.Manager, resumeID, homeDir, sessionsDir s
 	// PushDirectory extracts the parent from the path and pushes to create the directory there
 	// So we pass the full destination path where the config dir should end up
 	destConfigPath := filepath.Join(homeDir, configDirName)
-	if err := mgr.PushDirectory(sourceConfigDir, destConfigPath); err != nil {
+	if err := mgr.PushDirectory(context.Background(), sourceConfigDir, destConfigPath, nil); err != nil {
 		return fmt.Errorf("failed to push %s directory: %w", configDirName, err)
 	}
 
@@ -475,20 +866,27 @@ func restoreSessionData(mgr *container.Manager, resumeID, homeDir, sessionsDir s
 
 // injectCredentials copies credentials and essential config from host to container when resuming
 // This ensures fresh authentication while preserving the session conversation history
-func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, logger func(string)) error {
+func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, keyringSecret string, sandboxSettings map[string]interface{}, logger func(string)) error {
 	logger("Injecting fresh credentials and config for session resume...")
 
 	configDirName := t.ConfigDirName()
+	destCredentials := filepath.Join(homeDir, configDirName, credentialsFileName)
 
-	// Copy .credentials.json from host to container
-	credentialsPath := filepath.Join(hostCLIConfigPath, ".credentials.json")
-	if _, err := os.Stat(credentialsPath); err != nil {
-		return fmt.Errorf("credentials file not found: %w", err)
-	}
+	if keyringSecret != "" {
+		// Keyring credentials go straight into the container, never to a host file
+		if err := mgr.WriteSecretFile(destCredentials, keyringSecret); err != nil {
+			return fmt.Errorf("failed to write credentials: %w", err)
+		}
+	} else {
+		// Copy .credentials.json from host to container
+		credentialsPath := filepath.Join(hostCLIConfigPath, credentialsFileName)
+		if _, err := os.Stat(credentialsPath); err != nil {
+			return fmt.Errorf("credentials file not found: %w", err)
+		}
 
-	destCredentials := filepath.Join(homeDir, configDirName, ".credentials.json")
-	if err := mgr.PushFile(credentialsPath, destCredentials); err != nil {
-		return fmt.Errorf("failed to push credentials: %w", err)
+		if err := mgr.PushFile(credentialsPath, destCredentials); err != nil {
+			return fmt.Errorf("failed to push credentials: %w", err)
+		}
 	}
 
 	// Fix ownership if running as non-root user
@@ -498,37 +896,18 @@ func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string
 		}
 	}
 
-	// Get sandbox settings from tool
-	sandboxSettings := t.GetSandboxSettings()
+	// Sandbox settings come from the tool (plus any --sandbox-set overrides)
 	if len(sandboxSettings) > 0 {
 		// Get the state config filename (e.g., ".claude.json" or ".aider.json")
 		stateConfigFilename := fmt.Sprintf(".%s.json", t.Name())
 		stateConfigPath := filepath.Join(filepath.Dir(hostCLIConfigPath), stateConfigFilename)
 
 		if _, err := os.Stat(stateConfigPath); err == nil {
-			logger(fmt.Sprintf("Copying %s for session resume...", stateConfigFilename))
+			logger(fmt.Sprintf("Copying %s with sandbox settings for session resume...", stateConfigFilename))
 			stateJsonDest := filepath.Join(homeDir, stateConfigFilename)
-			if err := mgr.PushFile(stateConfigPath, stateJsonDest); err != nil {
+			if err := writeSettingsFile(mgr, stateConfigPath, stateJsonDest, sandboxSettings, true, logger); err != nil {
 				logger(fmt.Sprintf("Warning: Failed to copy %s: %v", stateConfigFilename, err))
 			} else {
-				// Inject sandbox settings using tool's GetSandboxSettings()
-				logger(fmt.Sprintf("Injecting sandbox settings into %s...", stateConfigFilename))
-				settingsJSON, err := buildJSONFromSettings(sandboxSettings)
-				if err != nil {
-					logger(fmt.Sprintf("Warning: Failed to build JSON from settings: %v", err))
-				} else {
-					// Properly escape the JSON string for shell command
-					escapedJSON := strings.ReplaceAll(settingsJSON, "'", "'\"'\"'")
-					injectCmd := fmt.Sprintf(
-						`python3 -c 'import json; f=open("%s","r+"); d=json.load(f); updates=json.loads('"'"'%s'"'"'); d.update(updates); f.seek(0); json.dump(d,f,indent=2); f.truncate()'`,
-						stateJsonDest,
-						escapedJSON,
-					)
-					if _, err := mgr.ExecCommand(injectCmd, container.ExecCommandOptions{Capture: true}); err != nil {
-						logger(fmt.Sprintf("Warning: Failed to inject settings into %s: %v", stateConfigFilename, err))
-					}
-				}
-
 				// Fix ownership if running as non-root user
 				if homeDir != "/root" {
 					if err := mgr.Chown(stateJsonDest, container.CodeUID, container.CodeUID); err != nil {
@@ -543,25 +922,45 @@ func injectCredentials(mgr *container.Manager, hostCLIConfigPath, homeDir string
 	return nil
 }
 
+// checkHostCLIConfig checks the host config directory t copies credentials
+// from. If it does not exist, the returned message explains that the tool
+// starts without credentials; other problems with it are errors.
+func checkHostCLIConfig(hostCLIConfigPath string, t tool.Tool) (string, error) {
+	info, err := os.Stat(hostCLIConfigPath)
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("no %s found on the host - %s starts without credentials (log into %s on the host first by running '%s', or pass --no-credentials to silence this)",
+			hostCLIConfigPath, t.Name(), t.Name(), t.Binary()), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check %s config directory: %w", t.Name(), err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s config path %s is not a directory", t.Name(), hostCLIConfigPath)
+	}
+	return "", nil
+}
+
 // setupCLIConfig copies tool config directory and injects sandbox settings
-func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, logger func(string)) error {
+func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t tool.Tool, keyringSecret string, sandboxSettings map[string]interface{}, logger func(string)) error {
 	configDirName := t.ConfigDirName()
 	stateDir := filepath.Join(homeDir, configDirName)
 
 	// Create config directory in container
 	logger(fmt.Sprintf("Creating %s directory in container...", configDirName))
-	mkdirCmd := fmt.Sprintf("mkdir -p %s", stateDir)
-	if _, err := mgr.ExecCommand(mkdirCmd, container.ExecCommandOptions{Capture: true}); err != nil {
+	if _, err := mgr.ExecArgsCapture([]string{"mkdir", "-p", stateDir}, container.ExecCommandOptions{}); err != nil {
 		return fmt.Errorf("failed to create %s directory: %w", configDirName, err)
 	}
 
-	// Copy only essential files from config directory (skip debug logs with permission issues)
-	essentialFiles := []string{
-		".credentials.json",
-		"config.yml",
-		"settings.json",
+	if keyringSecret != "" {
+		logger(fmt.Sprintf("  - Writing %s from the keyring", credentialsFileName))
+		if err := mgr.WriteSecretFile(filepath.Join(stateDir, credentialsFileName), keyringSecret); err != nil {
+			return fmt.Errorf("failed to write credentials: %w", err)
+		}
 	}
 
+	// settings.json is written below with the sandbox settings merged in
+	essentialFiles := essentialConfigFiles(keyringSecret != "", len(sandboxSettings) > 0)
+
 	logger(fmt.Sprintf("Copying essential CLI config files from %s", hostCLIConfigPath))
 	for _, filename := range essentialFiles {
 		srcPath := filepath.Join(hostCLIConfigPath, filename)
@@ -576,45 +975,12 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 		}
 	}
 
-	// Get sandbox settings from tool and merge into settings.json if needed
-	sandboxSettings := t.GetSandboxSettings()
+	// Merge sandbox settings (tool defaults plus overrides) into settings.json if needed
 	if len(sandboxSettings) > 0 {
-		settingsPath := filepath.Join(stateDir, "settings.json")
 		logger("Merging sandbox settings into settings.json...")
-		settingsJSON, err := buildJSONFromSettings(sandboxSettings)
-		if err != nil {
-			logger(fmt.Sprintf("Warning: Failed to build JSON from settings: %v", err))
-		} else {
-			// Check if settings.json exists in container
-			checkCmd := fmt.Sprintf("test -f %s && echo exists || echo missing", settingsPath)
-			checkResult, err := mgr.ExecCommand(checkCmd, container.ExecCommandOptions{Capture: true})
-
-			if err != nil || strings.TrimSpace(checkResult) == "missing" {
-				// File doesn't exist, create it with sandbox settings
-				logger("settings.json not found in container, creating with sandbox settings")
-				settingsBytes, err := json.MarshalIndent(sandboxSettings, "", "  ")
-				if err != nil {
-					return fmt.Errorf("failed to marshal sandbox settings: %w", err)
-				}
-				if err := mgr.CreateFile(settingsPath, string(settingsBytes)+"\n"); err != nil {
-					return fmt.Errorf("failed to create settings.json: %w", err)
-				}
-			} else {
-				// File exists, merge sandbox settings into it
-				logger("Merging sandbox settings into existing settings.json")
-				// Properly escape the JSON string for shell command
-				escapedJSON := strings.ReplaceAll(settingsJSON, "'", "'\"'\"'")
-				injectCmd := fmt.Sprintf(
-					`python3 -c 'import json; f=open("%s","r+"); d=json.load(f); updates=json.loads('"'"'%s'"'"'); d.update(updates); f.seek(0); json.dump(d,f,indent=2); f.truncate()'`,
-					settingsPath,
-					escapedJSON,
-				)
-				if _, err := mgr.ExecCommand(injectCmd, container.ExecCommandOptions{Capture: true}); err != nil {
-					logger(fmt.Sprintf("Warning: Failed to inject settings into settings.json: %v", err))
-				} else {
-					logger("Successfully merged sandbox settings into settings.json")
-				}
-			}
+		hostSettingsPath := filepath.Join(hostCLIConfigPath, "settings.json")
+		if err := writeSettingsFile(mgr, hostSettingsPath, filepath.Join(stateDir, "settings.json"), sandboxSettings, false, logger); err != nil {
+			return fmt.Errorf("failed to write settings.json: %w", err)
 		}
 		logger(fmt.Sprintf("%s config copied and sandbox settings merged into settings.json", t.Name()))
 	} else {
@@ -631,33 +997,16 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 		logger(fmt.Sprintf("Found %s (size: %d bytes), copying to container...", stateConfigFilename, info.Size()))
 		stateJsonDest := filepath.Join(homeDir, stateConfigFilename)
 
-		// Push the file to container
-		if err := mgr.PushFile(stateConfigPath, stateJsonDest); err != nil {
-			return fmt.Errorf("failed to copy %s: %w", stateConfigFilename, err)
-		}
-		logger(fmt.Sprintf("%s copied to %s", stateConfigFilename, stateJsonDest))
-
-		// Inject sandbox settings if tool provides them
+		// Push the file to container, with sandbox settings injected if the tool provides them
 		if len(sandboxSettings) > 0 {
 			logger(fmt.Sprintf("Injecting sandbox settings into %s...", stateConfigFilename))
-			settingsJSON, err := buildJSONFromSettings(sandboxSettings)
-			if err != nil {
-				logger(fmt.Sprintf("Warning: Failed to build JSON from settings: %v", err))
-			} else {
-				// Properly escape the JSON string for shell command
-				escapedJSON := strings.ReplaceAll(settingsJSON, "'", "'\"'\"'")
-				injectCmd := fmt.Sprintf(
-					`python3 -c 'import json; f=open("%s","r+"); d=json.load(f); updates=json.loads('"'"'%s'"'"'); d.update(updates); f.seek(0); json.dump(d,f,indent=2); f.truncate()'`,
-					stateJsonDest,
-					escapedJSON,
-				)
-				if _, err := mgr.ExecCommand(injectCmd, container.ExecCommandOptions{Capture: true}); err != nil {
-					logger(fmt.Sprintf("Warning: Failed to inject settings into %s: %v", stateConfigFilename, err))
-				} else {
-					logger(fmt.Sprintf("Successfully injected sandbox settings into %s", stateConfigFilename))
-				}
+			if err := writeSettingsFile(mgr, stateConfigPath, stateJsonDest, sandboxSettings, true, logger); err != nil {
+				return fmt.Errorf("failed to copy %s: %w", stateConfigFilename, err)
 			}
+		} else if err := mgr.PushFile(stateConfigPath, stateJsonDest); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", stateConfigFilename, err)
 		}
+		logger(fmt.Sprintf("%s copied to %s", stateConfigFilename, stateJsonDest))
 
 		// Fix ownership if running as non-root user
 		if homeDir != "/root" {
@@ -670,8 +1019,7 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 		// Fix ownership of entire config directory recursively
 		if homeDir != "/root" {
 			logger(fmt.Sprintf("Fixing ownership of entire %s directory to %d:%d", configDirName, container.CodeUID, container.CodeUID))
-			chownCmd := fmt.Sprintf("chown -R %d:%d %s", container.CodeUID, container.CodeUID, stateDir)
-			if _, err := mgr.ExecCommand(chownCmd, container.ExecCommandOptions{Capture: true}); err != nil {
+			if err := mgr.Chown(stateDir, container.CodeUID, container.CodeUID); err != nil {
 				return fmt.Errorf("failed to set %s directory ownership: %w", configDirName, err)
 			}
 		}
@@ -686,6 +1034,70 @@ func setupCLIConfig(mgr *container.Manager, hostCLIConfigPath, homeDir string, t
 	return nil
 }
 
+// setupSandboxOnlyConfig creates the tool config directory with only the
+// sandbox settings, for --no-credentials: no credentials, account state or
+// host settings are copied, so the tool has to log in inside the container
+func setupSandboxOnlyConfig(mgr *container.Manager, homeDir string, t tool.Tool, sandboxSettings map[string]interface{}, logger func(string)) error {
+	configDirName := t.ConfigDirName()
+	stateDir := filepath.Join(homeDir, configDirName)
+	if _, err := mgr.ExecArgsCapture([]string{"mkdir", "-p", stateDir}, container.ExecCommandOptions{}); err != nil {
+		return fmt.Errorf("failed to create %s directory: %w", configDirName, err)
+	}
+
+	owned := []string{stateDir}
+	if len(sandboxSettings) > 0 {
+		settings, err := MergeSettingsJSON(nil, sandboxSettings)
+		if err != nil {
+			return fmt.Errorf("failed to build sandbox settings: %w", err)
+		}
+
+		stateConfigPath := filepath.Join(homeDir, fmt.Sprintf(".%s.json", t.Name()))
+		for _, path := range []string{filepath.Join(stateDir, "settings.json"), stateConfigPath} {
+			if err := mgr.CreateFile(path, string(settings)); err != nil {
+				return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+			}
+		}
+		owned = append(owned, stateConfigPath)
+		logger(fmt.Sprintf("%s config created with sandbox settings only (no credentials)", t.Name()))
+	} else {
+		logger(fmt.Sprintf("%s config directory created (no credentials)", t.Name()))
+	}
+
+	// Fix ownership if running as non-root user
+	if homeDir != "/root" {
+		for _, path := range owned {
+			if err := mgr.Chown(path, container.CodeUID, container.CodeUID); err != nil {
+				return fmt.Errorf("failed to set %s ownership: %w", filepath.Base(path), err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeSettingsFile writes the host JSON file at hostPath (if it exists) to
+// destPath in the container with settings merged in. The merge happens on the
+// host, so images need no JSON tooling and nothing is interpolated into a
+// command. private files (the tool state, which holds account data) are written
+// with mode 0600 without a host temp file. A host file that is not a JSON
+// object is copied unchanged.
+func writeSettingsFile(mgr *container.Manager, hostPath, destPath string, settings map[string]interface{}, private bool, logger func(string)) error {
+	existing, err := os.ReadFile(hostPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", hostPath, err)
+	}
+
+	merged, err := MergeSettingsJSON(existing, settings)
+	if err != nil {
+		logger(fmt.Sprintf("Warning: Not injecting sandbox settings into %s: %v", filepath.Base(hostPath), err))
+		return mgr.PushFile(hostPath, destPath)
+	}
+
+	if private {
+		return mgr.WriteSecretFile(destPath, string(merged))
+	}
+	return mgr.CreateFile(destPath, string(merged))
+}
+
 // hasLimits checks if any limits are configured
 func hasLimits(cfg *config.LimitsConfig) bool {
 	if cfg == nil {
@@ -705,3 +1117,18 @@ func hasLimits(cfg *config.LimitsConfig) bool {
 		cfg.Disk.Priority != 0 ||
 		cfg.Runtime.MaxProcesses != 0
 }
+
+// essentialConfigFiles lists the host config files copied into a new container
+// (not debug logs, which can have permission issues). Files coi writes itself
+// are left out: credentials from the keyring, and settings.json when sandbox
+// settings are merged into it.
+func essentialConfigFiles(keyringCredentials, mergedSettings bool) []string {
+	var files []string
+	for _, name := range []string{credentialsFileName, "config.yml", "settings.json"} {
+		if (keyringCredentials && name == credentialsFileName) || (mergedSettings && name == "settings.json") {
+			continue
+		}
+		files = append(files, name)
+	}
+	return files
+}
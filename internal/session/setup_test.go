@@ -1,8 +1,15 @@
 package session
 
 import (
+	"context"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/tool"
 )
 
 func TestIsColimaOrLimaEnvironment(t *testing.T) {
@@ -116,3 +123,156 @@ func TestIsColimaOrLimaEnvironment_Integration(t *testing.T) {
 
 	// The test passes regardless - we're just checking it doesn't panic
 }
+
+func TestCheckHostCLIConfig(t *testing.T) {
+	claude := tool.NewClaude()
+	dir := t.TempDir()
+
+	if warning, err := checkHostCLIConfig(dir, claude); warning != "" || err != nil {
+		t.Errorf("Expected existing config dir to pass, got %q, %v", warning, err)
+	}
+
+	// A missing directory is not an error: the session starts without credentials
+	missing := filepath.Join(dir, ".claude")
+	warning, err := checkHostCLIConfig(missing, claude)
+	if err != nil {
+		t.Fatalf("Expected no error for a missing config dir, got %v", err)
+	}
+	if !strings.Contains(warning, missing) || !strings.Contains(warning, "without credentials") {
+		t.Errorf("Expected warning to name the directory and say there are no credentials, got %q", warning)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkHostCLIConfig(file, claude); err == nil {
+		t.Error("Expected an error when the config path is a file")
+	}
+}
+
+func TestStoppedContainerAction(t *testing.T) {
+	kept := func(string) bool { return true }
+	leftover := func(string) bool { return false }
+
+	tests := []struct {
+		name        string
+		persistent  bool
+		named       bool
+		kept        func(string) bool
+		wantRestart bool
+		wantErr     string
+	}{
+		{name: "persistent restarts", persistent: true, kept: leftover, wantRestart: true},
+		{name: "persistent named restarts", persistent: true, named: true, kept: kept, wantRestart: true},
+		{name: "ephemeral leftover is deleted", kept: leftover},
+		{name: "stopped named environment is refused", named: true, kept: leftover, wantErr: "stopped named environment"},
+		{name: "kept container is refused", kept: kept, wantErr: "may hold persistent data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restart, err := stoppedContainerAction("coi-demo", tt.persistent, tt.named, tt.kept)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				if !strings.Contains(err.Error(), "--persistent") {
+					t.Errorf("Expected error to suggest --persistent, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if restart != tt.wantRestart {
+				t.Errorf("Expected restart=%v, got %v", tt.wantRestart, restart)
+			}
+		})
+	}
+}
+
+func TestImagePullFor(t *testing.T) {
+	tests := []struct {
+		image    string
+		coiImage bool
+		want     imagePull
+	}{
+		{image: "coi", coiImage: true, want: pullNothing},
+		{image: "my-clone", coiImage: true, want: pullNothing},
+		{image: "images:ubuntu/24.04", want: pullRemote},
+		{image: "ubuntu", want: pullRefresh},
+		{image: "local:ubuntu", want: pullRefresh},
+	}
+	for _, tt := range tests {
+		if got := imagePullFor(tt.image, tt.coiImage); got != tt.want {
+			t.Errorf("imagePullFor(%q, %v) = %v, want %v", tt.image, tt.coiImage, got, tt.want)
+		}
+	}
+}
+
+func TestPollReadyProbeRetriesHungAttempt(t *testing.T) {
+	attempts := 0
+	run := func(ctx context.Context) (string, error) {
+		attempts++
+		if attempts == 1 {
+			<-ctx.Done() // The first run hangs until it is cancelled
+			return "", ctx.Err()
+		}
+		return "", nil
+	}
+
+	var logs []string
+	start := time.Now()
+	err := pollReadyProbe("true", run, 5*time.Second, 20*time.Millisecond, time.Millisecond, func(msg string) { logs = append(logs, msg) })
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Expected the hung attempt to be retried once, got %d attempts", attempts)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the hung attempt to be cut off quickly, took %s", elapsed)
+	}
+	if len(logs) == 0 || !strings.Contains(logs[len(logs)-1], "2 attempt(s)") {
+		t.Errorf("Expected success message with the attempt count, got %v", logs)
+	}
+}
+
+func TestPollReadyProbeTimesOut(t *testing.T) {
+	run := func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "still starting", ctx.Err()
+	}
+
+	start := time.Now()
+	err := pollReadyProbe("pg_isready", run, 50*time.Millisecond, time.Hour, time.Millisecond, func(string) {})
+	if err == nil {
+		t.Fatal("Expected an error for a probe that never finishes")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the overall timeout to bound a hung attempt, took %s", elapsed)
+	}
+	for _, want := range []string{`"pg_isready"`, "did not finish", "still starting"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %s, got %v", want, err)
+		}
+	}
+}
+
+func TestEssentialConfigFiles(t *testing.T) {
+	tests := []struct {
+		keyring, merged bool
+		want            []string
+	}{
+		{false, false, []string{".credentials.json", "config.yml", "settings.json"}},
+		{true, false, []string{"config.yml", "settings.json"}},
+		{false, true, []string{".credentials.json", "config.yml"}},
+		{true, true, []string{"config.yml"}},
+	}
+	for _, tt := range tests {
+		if got := essentialConfigFiles(tt.keyring, tt.merged); !slices.Equal(got, tt.want) {
+			t.Errorf("essentialConfigFiles(%t, %t) = %v, want %v", tt.keyring, tt.merged, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// TmuxNewSessionCommand builds the shell command that creates a detached tmux
+// session in workDir running command with env exported. When the command exits
+// the session falls back to bash; SIGINT is trapped so Ctrl+C reaches the tool
+// without killing the session. Every interpolated value is quoted, so working
+// directories and env values may contain spaces or quotes.
+func TmuxNewSessionCommand(sessionName, workDir string, env map[string]string, command string) string {
+	return container.ShellJoin([]string{"tmux", "new-session", "-d", "-s", sessionName, "-c", workDir, tmuxShellCommand(env, command)})
+}
+
+// TmuxRespawnPaneCommand builds the shell command that restarts command in the
+// (dead) pane of an existing tmux session, with the same bash fallback and
+// quoting as TmuxNewSessionCommand
+func TmuxRespawnPaneCommand(sessionName, workDir string, env map[string]string, command string) string {
+	return container.ShellJoin([]string{"tmux", "respawn-pane", "-k", "-t", sessionName, "-c", workDir, tmuxShellCommand(env, command)})
+}
+
+// tmuxShellCommand wraps command for tmux: exports env, traps SIGINT and falls
+// back to bash when command exits
+func tmuxShellCommand(env map[string]string, command string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var script strings.Builder
+	script.WriteString("trap : INT; ")
+	for _, k := range keys {
+		fmt.Fprintf(&script, "export %s=%s; ", k, container.ShellQuote(env[k]))
+	}
+	fmt.Fprintf(&script, "%s; exec bash", command)
+
+	// tmux runs its shell-command argument through the default shell
+	return "bash -c " + container.ShellQuote(script.String())
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// awkwardPath contains a space and a single quote
+const awkwardPath = "/workspace/my project/it's here"
+
+// shellWords returns the words sh parses from a command line
+func shellWords(t *testing.T, commandLine string) []string {
+	t.Helper()
+	out, err := exec.Command("sh", "-c", "for w in "+commandLine+"; do printf '%s\\0' \"$w\"; done").Output()
+	if err != nil {
+		t.Fatalf("sh failed to parse %q: %v", commandLine, err)
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+}
+
+func TestTmuxNewSessionCommand(t *testing.T) {
+	env := map[string]string{"HOME": "/home/code", "NOTE": "it's a \"test\" $HOME"}
+	command := TmuxNewSessionCommand("coi-test-1", awkwardPath, env, "claude --verbose")
+
+	words := shellWords(t, command)
+	want := []string{"tmux", "new-session", "-d", "-s", "coi-test-1", "-c", awkwardPath}
+	if len(words) != len(want)+1 {
+		t.Fatalf("Expected %d words, got %d: %q", len(want)+1, len(words), words)
+	}
+	for i, w := range want {
+		if words[i] != w {
+			t.Errorf("word %d = %q, want %q", i, words[i], w)
+		}
+	}
+
+	// tmux hands the last word to the shell: bash -c <script>
+	inner := shellWords(t, words[len(words)-1])
+	if len(inner) != 3 || inner[0] != "bash" || inner[1] != "-c" {
+		t.Fatalf("Unexpected session command %q", inner)
+	}
+	script := inner[2]
+	if !strings.HasPrefix(script, "trap : INT; ") || !strings.HasSuffix(script, "claude --verbose; exec bash") {
+		t.Errorf("Unexpected script %q", script)
+	}
+
+	// Exported values survive unchanged
+	out, err := exec.Command("sh", "-c", strings.TrimSuffix(script, "claude --verbose; exec bash")+`printf %s "$NOTE"`).Output()
+	if err != nil {
+		t.Fatalf("Failed to run exports: %v", err)
+	}
+	if string(out) != env["NOTE"] {
+		t.Errorf("NOTE = %q, want %q", out, env["NOTE"])
+	}
+}
@@ -0,0 +1,52 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// shiftNoticeFile records that the Colima/Lima UID shifting notice was shown
+const shiftNoticeFile = "colima-shift-notice"
+
+// ResolveUIDShift decides whether bind mounts use Incus UID shifting
+// (shift=true). disableShift and forceShift come from [incus] disable_shift and
+// --disable-shift/--shift; when neither is set, shifting is disabled inside
+// Colima/Lima VMs, which already map UIDs via virtiofs. The first time that
+// happens an explanation is logged; later launches stay quiet.
+func ResolveUIDShift(disableShift, forceShift bool, logger func(string)) bool {
+	if forceShift {
+		return true
+	}
+	if disableShift {
+		return false
+	}
+	if !isColimaOrLimaEnvironment() {
+		return true
+	}
+
+	if markShiftNoticeShown() {
+		logger("Detected a Colima/Lima VM - disabling Incus UID shifting for bind mounts")
+		logger("  The VM already maps file ownership via virtiofs, so shift=true would give")
+		logger("  workspace files the wrong owner. Use --shift to force shifting, or set")
+		logger("  disable_shift = true under [incus] to make this explicit.")
+	}
+	return false
+}
+
+// markShiftNoticeShown records the notice in ~/.coi and reports whether this is
+// the first time. If the marker cannot be written the notice is shown anyway.
+func markShiftNoticeShown() bool {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return true
+	}
+	coiDir := filepath.Join(homeDir, ".coi")
+	marker := filepath.Join(coiDir, shiftNoticeFile)
+	if _, err := os.Stat(marker); err == nil {
+		return false
+	}
+	if err := os.MkdirAll(coiDir, 0o755); err == nil {
+		_ = os.WriteFile(marker, nil, 0o644) // Best effort: worst case the notice repeats
+	}
+	return true
+}
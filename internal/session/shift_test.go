@@ -0,0 +1,18 @@
+package session
+
+import "testing"
+
+func TestResolveUIDShiftOverrides(t *testing.T) {
+	var logged []string
+	logger := func(msg string) { logged = append(logged, msg) }
+
+	if !ResolveUIDShift(false, true, logger) {
+		t.Error("Expected --shift to force UID shifting")
+	}
+	if ResolveUIDShift(true, false, logger) {
+		t.Error("Expected disable_shift to disable UID shifting")
+	}
+	if len(logged) != 0 {
+		t.Errorf("Expected no detection notice when forced either way, got %v", logged)
+	}
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// tmuxPaneFormat is the list-panes format parsed by ParseTmuxPaneState
+const tmuxPaneFormat = "#{pane_dead} #{pane_current_command}"
+
+// shellCommands are pane commands meaning the AI tool has exited and the pane
+// fell back to its shell (see TmuxNewSessionCommand)
+var shellCommands = map[string]bool{"bash": true, "sh": true, "zsh": true, "dash": true}
+
+// TmuxPaneState describes the first pane of a tmux session
+type TmuxPaneState struct {
+	Dead    bool   // The pane's process exited (only kept with remain-on-exit)
+	Command string // Foreground command in the pane, e.g. "claude" or "bash"
+}
+
+// ToolExited reports whether the session no longer runs the AI tool: the pane
+// is dead, or only the fallback shell is left
+func (s TmuxPaneState) ToolExited() bool {
+	return s.Dead || shellCommands[path.Base(s.Command)]
+}
+
+// ParseTmuxPaneState parses `tmux list-panes -F tmuxPaneFormat` output,
+// using the first pane
+func ParseTmuxPaneState(output string) (TmuxPaneState, error) {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	dead, command, _ := strings.Cut(strings.TrimSpace(line), " ")
+	switch dead {
+	case "0", "1":
+		return TmuxPaneState{Dead: dead == "1", Command: strings.TrimSpace(command)}, nil
+	default:
+		return TmuxPaneState{}, fmt.Errorf("unexpected tmux pane state %q", line)
+	}
+}
+
+// GetTmuxPaneState queries the state of a tmux session's pane, running tmux as user
+func GetTmuxPaneState(mgr *container.Manager, sessionName string, user int) (TmuxPaneState, error) {
+	output, err := mgr.ExecArgsCapture(
+		[]string{"tmux", "list-panes", "-t", sessionName, "-F", tmuxPaneFormat},
+		container.ExecCommandOptions{User: &user},
+	)
+	if err != nil {
+		return TmuxPaneState{}, fmt.Errorf("failed to query tmux session %s: %w", sessionName, err)
+	}
+	return ParseTmuxPaneState(output)
+}
+
+// ParseTmuxAttached parses `tmux display-message -p '#{session_attached}'`
+// output: the number of clients attached to the session
+func ParseTmuxAttached(output string) (bool, error) {
+	clients, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return false, fmt.Errorf("unexpected tmux attached count %q", strings.TrimSpace(output))
+	}
+	return clients > 0, nil
+}
+
+// TmuxClientAttached reports whether a tmux client is attached to a session, running tmux as user
+func TmuxClientAttached(mgr *container.Manager, sessionName string, user int) (bool, error) {
+	output, err := mgr.ExecArgsCapture(
+		[]string{"tmux", "display-message", "-p", "-t", sessionName, "#{session_attached}"},
+		container.ExecCommandOptions{User: &user},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to query tmux session %s: %w", sessionName, err)
+	}
+	return ParseTmuxAttached(output)
+}
+
+// ErrNoTmuxSession is returned by ProbeTmuxSession when tmux reports that the
+// session (or its server) does not exist
+var ErrNoTmuxSession = errors.New("no tmux session")
+
+// tmuxProbeCommand runs tmux has-session and prints its exit status, so tmux's
+// own failure can be told apart from incus exec failing
+func tmuxProbeCommand(sessionName string) []string {
+	return []string{"sh", "-c", `tmux has-session -t "$1" 2>/dev/null; echo $?`, "sh", sessionName}
+}
+
+// ParseTmuxProbe classifies the result of tmuxProbeCommand: nil when the
+// session exists, ErrNoTmuxSession when has-session failed, and any other error
+// (including the context's, when tmux did not answer in time) as is
+func ParseTmuxProbe(output string, err error) error {
+	if err != nil {
+		return err
+	}
+	switch status := strings.TrimSpace(output); status {
+	case "0":
+		return nil
+	case "1":
+		return ErrNoTmuxSession
+	default:
+		return fmt.Errorf("tmux has-session exited with status %q", status)
+	}
+}
+
+// ProbeTmuxSession checks with tmux has-session, running tmux as user, that a
+// session exists and the tmux server answers within timeout. A server that does
+// not answer gives an error wrapping context.DeadlineExceeded.
+func ProbeTmuxSession(mgr *container.Manager, sessionName string, user int, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	output, err := mgr.ExecArgsCaptureContext(ctx, tmuxProbeCommand(sessionName), container.ExecCommandOptions{User: &user})
+	return ParseTmuxProbe(output, err)
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+func TestParseTmuxPaneState(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		want       TmuxPaneState
+		wantExited bool
+		wantErr    bool
+	}{
+		{"tool running", "0 claude\n", TmuxPaneState{Command: "claude"}, false, false},
+		{"fallback shell", "0 bash\n", TmuxPaneState{Command: "bash"}, true, false},
+		{"dead pane", "1 claude\n", TmuxPaneState{Dead: true, Command: "claude"}, true, false},
+		{"first pane wins", "0 node\n0 bash\n", TmuxPaneState{Command: "node"}, false, false},
+		{"empty", "", TmuxPaneState{}, false, true},
+		{"garbage", "can't find session", TmuxPaneState{}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTmuxPaneState(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTmuxPaneState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTmuxPaneState() = %+v, want %+v", got, tt.want)
+			}
+			if got.ToolExited() != tt.wantExited {
+				t.Errorf("ToolExited() = %v, want %v", got.ToolExited(), tt.wantExited)
+			}
+		})
+	}
+}
+
+func TestParseTmuxAttached(t *testing.T) {
+	tests := []struct {
+		output  string
+		want    bool
+		wantErr bool
+	}{
+		{"0\n", false, false},
+		{"1\n", true, false},
+		{"2", true, false},
+		{"can't find session: coi-x", false, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTmuxAttached(tt.output)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTmuxAttached(%q) error = %v, wantErr %v", tt.output, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTmuxAttached(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestParseTmuxProbe(t *testing.T) {
+	incusErr := &container.ExitError{ExitCode: 1, Err: errors.New("exit status 1")}
+
+	tests := []struct {
+		name         string
+		output       string
+		err          error
+		wantErr      bool
+		wantNoSess   bool
+		wantDeadline bool
+	}{
+		{name: "session exists", output: "0\n"},
+		{name: "no session", output: "1\n", wantErr: true, wantNoSess: true},
+		{name: "tmux missing", output: "127\n", wantErr: true},
+		{name: "timed out", err: context.DeadlineExceeded, wantErr: true, wantDeadline: true},
+		{name: "timed out with partial output", output: "1", err: context.DeadlineExceeded, wantErr: true, wantDeadline: true},
+		{name: "incus exec failed", err: incusErr, wantErr: true},
+		{name: "no status", output: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ParseTmuxProbe(tt.output, tt.err)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTmuxProbe() error = %v, wantErr %t", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrNoTmuxSession); got != tt.wantNoSess {
+				t.Errorf("ParseTmuxProbe() = %v, is ErrNoTmuxSession %t, want %t", err, got, tt.wantNoSess)
+			}
+			if got := errors.Is(err, context.DeadlineExceeded); got != tt.wantDeadline {
+				t.Errorf("ParseTmuxProbe() = %v, is DeadlineExceeded %t, want %t", err, got, tt.wantDeadline)
+			}
+		})
+	}
+}
+
+func TestTmuxProbeCommand(t *testing.T) {
+	// The session name is passed as an argument, never spliced into the script
+	got := tmuxProbeCommand("coi-abc-1; reboot")
+	if len(got) != 5 || got[0] != "sh" || got[4] != "coi-abc-1; reboot" || strings.Contains(got[2], "reboot") {
+		t.Errorf("tmuxProbeCommand() = %q", got)
+	}
+}
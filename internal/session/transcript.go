@@ -0,0 +1,188 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxTranscriptLine is the largest single .jsonl record we accept (tool results can be big)
+const maxTranscriptLine = 16 * 1024 * 1024
+
+// TranscriptEntry is a single readable message from a CLI transcript
+type TranscriptEntry struct {
+	Role      string    `json:"role"`
+	Timestamp time.Time `json:"timestamp"`
+	Text      string    `json:"text"`
+}
+
+// transcriptRecord mirrors the subset of a Claude .jsonl record we care about
+type transcriptRecord struct {
+	Type      string `json:"type"`
+	Timestamp string `json:"timestamp"`
+	Message   *struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// transcriptContentBlock is one element of a structured message content array
+type transcriptContentBlock struct {
+	Type    string          `json:"type"`
+	Text    string          `json:"text"`
+	Name    string          `json:"name"`
+	Content json.RawMessage `json:"content"`
+}
+
+// TranscriptFiles returns the transcript (.jsonl) files of a saved session.
+// CLI tools store transcripts in <configDir>/projects/<project dir>/<session-id>.jsonl,
+// where the project dir comes from the workspace mount path (-workspace for /workspace)
+func TranscriptFiles(sessionsDir, coiSessionID, configDirName string) ([]string, error) {
+	projectDir := ProjectDirName(SessionMountPath(sessionsDir, coiSessionID))
+	projectsDir := filepath.Join(sessionsDir, coiSessionID, configDirName, "projects", projectDir)
+
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".jsonl") {
+			files = append(files, filepath.Join(projectsDir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// ReadTranscript parses a .jsonl transcript into readable entries.
+// Records that are not user/assistant messages (summaries, metadata) are skipped.
+func ReadTranscript(path string) ([]TranscriptEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []TranscriptEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTranscriptLine)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var record transcriptRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			continue // Skip malformed lines
+		}
+		if record.Message == nil {
+			continue
+		}
+
+		text := transcriptText(record.Message.Content)
+		if text == "" {
+			continue
+		}
+
+		role := record.Message.Role
+		if role == "" {
+			role = record.Type
+		}
+
+		entry := TranscriptEntry{Role: role, Text: text}
+		if ts, err := time.Parse(time.RFC3339Nano, record.Timestamp); err == nil {
+			entry.Timestamp = ts
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read transcript %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// FilterTranscript keeps entries at or after since (if non-zero) whose text matches pattern (if non-nil)
+func FilterTranscript(entries []TranscriptEntry, since time.Time, pattern *regexp.Regexp) []TranscriptEntry {
+	filtered := make([]TranscriptEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !since.IsZero() && (entry.Timestamp.IsZero() || entry.Timestamp.Before(since)) {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(entry.Text) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// SortTranscript orders entries (e.g. from several transcript files) by
+// timestamp. Entries without one stay right after the entry before them.
+func SortTranscript(entries []TranscriptEntry) {
+	type keyed struct {
+		key   time.Time
+		entry TranscriptEntry
+	}
+	items := make([]keyed, len(entries))
+	var last time.Time
+	for i, entry := range entries {
+		if !entry.Timestamp.IsZero() {
+			last = entry.Timestamp
+		}
+		items[i] = keyed{key: last, entry: entry}
+	}
+	sort.SliceStable(items, func(a, b int) bool {
+		return items[a].key.Before(items[b].key)
+	})
+	for i, item := range items {
+		entries[i] = item.entry
+	}
+}
+
+// transcriptText flattens message content (plain string or content blocks) into text
+func transcriptText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var plain string
+	if err := json.Unmarshal(raw, &plain); err == nil {
+		return strings.TrimSpace(plain)
+	}
+
+	var blocks []transcriptContentBlock
+	if err := json.Unmarshal(raw, &blocks); err != nil {
+		return ""
+	}
+
+	var parts []string
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			if text := strings.TrimSpace(block.Text); text != "" {
+				parts = append(parts, text)
+			}
+		case "tool_use":
+			parts = append(parts, fmt.Sprintf("[tool: %s]", block.Name))
+		case "tool_result":
+			result := transcriptText(block.Content)
+			if idx := strings.Index(result, "\n"); idx != -1 {
+				result = result[:idx] + " ..."
+			}
+			parts = append(parts, strings.TrimSpace("[tool result] "+result))
+		}
+	}
+
+	return strings.Join(parts, "\n")
+}
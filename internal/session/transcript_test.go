@@ -0,0 +1,142 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+const sampleTranscript = `{"type":"summary","summary":"Fix tests"}
+{"type":"user","timestamp":"2026-01-10T10:00:00.000Z","message":{"role":"user","content":"please fix the failing test"}}
+{"type":"assistant","timestamp":"2026-01-10T10:00:05.000Z","message":{"role":"assistant","content":[{"type":"text","text":"Looking at the test now."},{"type":"tool_use","name":"Bash","input":{"command":"go test ./..."}}]}}
+{"type":"user","timestamp":"2026-01-10T10:00:09.000Z","message":{"role":"user","content":[{"type":"tool_result","content":"FAIL foo\nmore output"}]}}
+not json
+{"type":"assistant","timestamp":"2026-01-10T12:30:00.000Z","message":{"role":"assistant","content":[{"type":"text","text":"All tests pass now."}]}}
+`
+
+func writeSampleTranscript(t *testing.T) (string, string) {
+	t.Helper()
+	sessionsDir := t.TempDir()
+	projectsDir := filepath.Join(sessionsDir, "sess-1", ".claude", "projects", "-workspace")
+	if err := os.MkdirAll(projectsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create projects dir: %v", err)
+	}
+	path := filepath.Join(projectsDir, "abc.jsonl")
+	if err := os.WriteFile(path, []byte(sampleTranscript), 0o644); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	return sessionsDir, path
+}
+
+func TestTranscriptFiles(t *testing.T) {
+	sessionsDir, path := writeSampleTranscript(t)
+
+	files, err := TranscriptFiles(sessionsDir, "sess-1", ".claude")
+	if err != nil {
+		t.Fatalf("TranscriptFiles() unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0] != path {
+		t.Errorf("Expected [%s], got %v", path, files)
+	}
+
+	if _, err := TranscriptFiles(sessionsDir, "missing", ".claude"); err == nil {
+		t.Error("Expected error for missing session")
+	}
+}
+
+func TestTranscriptFilesCustomMountPath(t *testing.T) {
+	sessionsDir := t.TempDir()
+	if err := SaveMetadataEarly(sessionsDir, SessionMetadata{SessionID: "sess-2", MountPath: "/home/me/my.app"}); err != nil {
+		t.Fatal(err)
+	}
+	projectsDir := filepath.Join(sessionsDir, "sess-2", ".claude", "projects", "-home-me-my-app")
+	if err := os.MkdirAll(projectsDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(projectsDir, "abc.jsonl")
+	if err := os.WriteFile(path, []byte(sampleTranscript), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := TranscriptFiles(sessionsDir, "sess-2", ".claude")
+	if err != nil {
+		t.Fatalf("TranscriptFiles() unexpected error: %v", err)
+	}
+	if len(files) != 1 || files[0] != path {
+		t.Errorf("Expected [%s], got %v", path, files)
+	}
+}
+
+func TestReadTranscript(t *testing.T) {
+	_, path := writeSampleTranscript(t)
+
+	entries, err := ReadTranscript(path)
+	if err != nil {
+		t.Fatalf("ReadTranscript() unexpected error: %v", err)
+	}
+
+	if len(entries) != 4 {
+		t.Fatalf("Expected 4 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if entries[0].Role != "user" || entries[0].Text != "please fix the failing test" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].Text != "Looking at the test now.\n[tool: Bash]" {
+		t.Errorf("Unexpected assistant text: %q", entries[1].Text)
+	}
+	if entries[2].Text != "[tool result] FAIL foo ..." {
+		t.Errorf("Unexpected tool result text: %q", entries[2].Text)
+	}
+	if entries[3].Timestamp.Hour() != 12 {
+		t.Errorf("Expected timestamp hour 12, got %v", entries[3].Timestamp)
+	}
+}
+
+func TestFilterTranscript(t *testing.T) {
+	_, path := writeSampleTranscript(t)
+	entries, err := ReadTranscript(path)
+	if err != nil {
+		t.Fatalf("ReadTranscript() unexpected error: %v", err)
+	}
+
+	since := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	if got := FilterTranscript(entries, since, nil); len(got) != 1 {
+		t.Errorf("Expected 1 entry since %v, got %d", since, len(got))
+	}
+
+	pattern := regexp.MustCompile("(?i)test")
+	if got := FilterTranscript(entries, time.Time{}, pattern); len(got) != 3 {
+		t.Errorf("Expected 3 entries matching 'test', got %d", len(got))
+	}
+
+	if got := FilterTranscript(entries, time.Time{}, nil); len(got) != len(entries) {
+		t.Errorf("Expected no filtering, got %d of %d", len(got), len(entries))
+	}
+}
+
+func TestSortTranscript(t *testing.T) {
+	at := func(minute int) time.Time {
+		return time.Date(2026, 1, 10, 10, minute, 0, 0, time.UTC)
+	}
+	// Two files read one after another: the second started before the first ended
+	entries := []TranscriptEntry{
+		{Role: "user", Timestamp: at(0), Text: "a1"},
+		{Role: "assistant", Timestamp: at(5), Text: "a2"},
+		{Role: "assistant", Text: "a3 (no timestamp)"},
+		{Role: "user", Timestamp: at(2), Text: "b1"},
+		{Role: "assistant", Timestamp: at(7), Text: "b2"},
+		{Role: "user", Timestamp: at(5), Text: "b3"},
+	}
+
+	SortTranscript(entries)
+
+	want := []string{"a1", "b1", "a2", "a3 (no timestamp)", "b3", "b2"}
+	for i, text := range want {
+		if entries[i].Text != text {
+			t.Errorf("Entry %d = %q, want %q (order: %v)", i, entries[i].Text, text, entries)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package session
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mensfeld/code-on-incus/internal/container"
+)
+
+// ContainerWorkspacePath is where the workspace is mounted inside the container
+// unless --mount-at picks another path
+const ContainerWorkspacePath = "/workspace"
+
+// reservedMountDirs are system directories the workspace must not replace or
+// be mounted inside
+var reservedMountDirs = []string{
+	"/bin", "/boot", "/dev", "/etc", "/lib", "/lib64", "/proc", "/root",
+	"/run", "/sbin", "/sys", "/tmp", "/usr", "/var",
+}
+
+// ValidateMountPath checks a custom workspace mount path (--mount-at): it must
+// be absolute and must not hide a system directory or the code user's home.
+func ValidateMountPath(mountPath string) error {
+	if !path.IsAbs(mountPath) {
+		return fmt.Errorf("mount path '%s' must be absolute", mountPath)
+	}
+	cleaned := path.Clean(mountPath)
+	if cleaned == "/" {
+		return fmt.Errorf("cannot mount the workspace at /")
+	}
+	for _, dir := range reservedMountDirs {
+		if cleaned == dir || strings.HasPrefix(cleaned, dir+"/") {
+			return fmt.Errorf("cannot mount the workspace at %s: it is inside system directory %s", cleaned, dir)
+		}
+	}
+	home := "/home/" + container.CodeUser
+	if cleaned == home || strings.HasPrefix(home, cleaned+"/") {
+		return fmt.Errorf("cannot mount the workspace at %s: it would hide %s", cleaned, home)
+	}
+	return nil
+}
+
+// ResolveWorkDir returns the container working directory for a path relative
+// to the workspace (e.g. "packages/api" -> "/workspace/packages/api").
+// An empty path resolves to the workspace root. Absolute paths and paths that
+// escape the workspace (via "..") are rejected.
+func ResolveWorkDir(relative string) (string, error) {
+	return ResolveWorkDirAt(ContainerWorkspacePath, relative)
+}
+
+// ResolveWorkDirAt is ResolveWorkDir for a workspace mounted at mountPath
+func ResolveWorkDirAt(mountPath, relative string) (string, error) {
+	relative = strings.TrimSpace(relative)
+	if relative == "" {
+		return mountPath, nil
+	}
+	if path.IsAbs(relative) {
+		return "", fmt.Errorf("working directory '%s' must be relative to the workspace", relative)
+	}
+
+	cleaned := path.Clean(relative)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("working directory '%s' is outside the workspace", relative)
+	}
+	if cleaned == "." {
+		return mountPath, nil
+	}
+	return path.Join(mountPath, cleaned), nil
+}
+
+// projectDirInvalidChars matches the characters Claude replaces in project directory names
+var projectDirInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9]`)
+
+// ProjectDirName returns the directory under <configDir>/projects where CLI
+// tools keep the transcripts of sessions started in dir, e.g. "-workspace"
+// for /workspace (every character other than letters and digits becomes "-")
+func ProjectDirName(dir string) string {
+	return projectDirInvalidChars.ReplaceAllString(dir, "-")
+}
+
+// WorkspaceMountPath returns where a container has the workspace mounted,
+// falling back to ContainerWorkspacePath if it cannot be determined
+func WorkspaceMountPath(mgr *container.Manager) string {
+	if mountPath, err := mgr.DeviceConfig("workspace", "path"); err == nil && mountPath != "" {
+		return mountPath
+	}
+	return ContainerWorkspacePath
+}
@@ -0,0 +1,89 @@
+package session
+
+import "testing"
+
+func TestResolveWorkDir(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"", "/workspace", false},
+		{".", "/workspace", false},
+		{"packages/api", "/workspace/packages/api", false},
+		{"packages/api/", "/workspace/packages/api", false},
+		{"./packages/../libs", "/workspace/libs", false},
+		{"..", "", true},
+		{"../other", "", true},
+		{"packages/../../etc", "", true},
+		{"/etc", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ResolveWorkDir(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ResolveWorkDir(%q) expected error, got '%s'", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ResolveWorkDir(%q) unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ResolveWorkDir(%q): expected '%s', got '%s'", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestResolveWorkDirAt(t *testing.T) {
+	got, err := ResolveWorkDirAt("/home/me/app", "packages/api")
+	if err != nil || got != "/home/me/app/packages/api" {
+		t.Errorf("ResolveWorkDirAt() = %q, %v; want /home/me/app/packages/api", got, err)
+	}
+	if got, err := ResolveWorkDirAt("/home/me/app", ""); err != nil || got != "/home/me/app" {
+		t.Errorf("ResolveWorkDirAt() = %q, %v; want /home/me/app", got, err)
+	}
+	if _, err := ResolveWorkDirAt("/home/me/app", "../other"); err == nil {
+		t.Error("Expected error for a path outside the workspace")
+	}
+}
+
+func TestValidateMountPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"/workspace", false},
+		{"/home/alice/projects/app", false},
+		{"/srv/app/", false},
+		{"/home/code/app", false},
+		{"relative/path", true},
+		{"/", true},
+		{"/etc", true},
+		{"/usr/local/src/app", true},
+		{"/proc/1", true},
+		{"/home", true},
+		{"/home/code", true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateMountPath(tt.path); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateMountPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProjectDirName(t *testing.T) {
+	tests := map[string]string{
+		"/workspace":              "-workspace",
+		"/workspace/packages/api": "-workspace-packages-api",
+		"/home/me/my.app":         "-home-me-my-app",
+	}
+	for dir, want := range tests {
+		if got := ProjectDirName(dir); got != want {
+			t.Errorf("ProjectDirName(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultWidth and DefaultHeight are used when the terminal size is unknown
+const (
+	DefaultWidth  = 80
+	DefaultHeight = 24
+)
+
+// castHeader is the first line of an asciicast v2 file
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// CastRecorder writes terminal output as an asciicast v2 recording (a header
+// line, then one [seconds, "o", data] event per write), playable with
+// 'asciinema play'. It is safe for concurrent use.
+type CastRecorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	start   time.Time
+	now     func() time.Time
+	pending []byte // Incomplete UTF-8 sequence held back for the next write
+	err     error
+}
+
+// NewCastRecorder writes the recording header to w and returns a recorder for
+// a width x height terminal
+func NewCastRecorder(w io.Writer, width, height int, title string, env map[string]string) (*CastRecorder, error) {
+	return newCastRecorder(w, width, height, title, env, time.Now)
+}
+
+func newCastRecorder(w io.Writer, width, height int, title string, env map[string]string, now func() time.Time) (*CastRecorder, error) {
+	r := &CastRecorder{w: w, start: now(), now: now}
+	header, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: r.start.Unix(),
+		Title:     title,
+		Env:       env,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode recording header: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "%s\n", header); err != nil {
+		return nil, fmt.Errorf("failed to write recording: %w", err)
+	}
+	return r, nil
+}
+
+// Write records p as an output event. A multi-byte character split across
+// writes is recorded with the write that completes it. Recording errors are
+// remembered (see Err) but never fail the write, so a full disk doesn't break
+// the session being recorded.
+func (r *CastRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data := append(r.pending, p...)
+	cut := completeUTF8(data)
+	r.pending = append([]byte(nil), data[cut:]...)
+	r.writeEvent(data[:cut])
+	return len(p), nil
+}
+
+// Close records any held back bytes. It does not close the underlying writer.
+func (r *CastRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.writeEvent(r.pending)
+	r.pending = nil
+	return r.err
+}
+
+// Err returns the first error writing the recording, if any
+func (r *CastRecorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// writeEvent appends an output event; the caller holds r.mu
+func (r *CastRecorder) writeEvent(data []byte) {
+	if len(data) == 0 || r.err != nil {
+		return
+	}
+	event, err := json.Marshal([]interface{}{
+		r.now().Sub(r.start).Seconds(),
+		"o",
+		string(data),
+	})
+	if err == nil {
+		_, err = fmt.Fprintf(r.w, "%s\n", event)
+	}
+	if err != nil {
+		r.err = fmt.Errorf("failed to write recording: %w", err)
+	}
+}
+
+// completeUTF8 returns the length of the longest prefix of data that does not
+// end in the middle of a UTF-8 sequence
+func completeUTF8(data []byte) int {
+	// A sequence is at most utf8.UTFMax bytes, so only the tail can be incomplete
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// Size returns the width and height of the terminal on stdin, or the defaults
+// if stdin is not a terminal
+func Size() (int, int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	if err != nil {
+		return DefaultWidth, DefaultHeight
+	}
+	width, height, ok := parseSttySize(string(output))
+	if !ok {
+		return DefaultWidth, DefaultHeight
+	}
+	return width, height
+}
+
+// parseSttySize parses 'stty size' output ("rows columns") into width and height
+func parseSttySize(output string) (int, int, bool) {
+	fields := strings.Fields(output)
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	rows, err1 := strconv.Atoi(fields[0])
+	cols, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows <= 0 || cols <= 0 {
+		return 0, 0, false
+	}
+	return cols, rows, true
+}
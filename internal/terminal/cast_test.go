@@ -0,0 +1,104 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCastRecorder(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	now := start
+	clock := func() time.Time { return now }
+
+	var buf bytes.Buffer
+	r, err := newCastRecorder(&buf, 120, 40, "coi shell test", map[string]string{"TERM": "xterm-256color"}, clock)
+	if err != nil {
+		t.Fatalf("newCastRecorder failed: %v", err)
+	}
+
+	now = start.Add(500 * time.Millisecond)
+	_, _ = r.Write([]byte("hello\r\n"))
+	// "é" split across writes is recorded whole with the second write
+	now = start.Add(time.Second)
+	_, _ = r.Write([]byte("caf\xc3"))
+	now = start.Add(2 * time.Second)
+	_, _ = r.Write([]byte("\xa9\x1b[0m"))
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header and 3 events, got %d lines:\n%s", len(lines), buf.String())
+	}
+
+	var header castHeader
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("Invalid header: %v", err)
+	}
+	if header.Version != 2 || header.Width != 120 || header.Height != 40 || header.Timestamp != 1700000000 {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+	if header.Env["TERM"] != "xterm-256color" || header.Title != "coi shell test" {
+		t.Errorf("Unexpected header metadata: %+v", header)
+	}
+
+	expected := []struct {
+		time float64
+		data string
+	}{
+		{0.5, "hello\r\n"},
+		{1, "caf"},
+		{2, "é\x1b[0m"},
+	}
+	for i, want := range expected {
+		var event []interface{}
+		if err := json.Unmarshal([]byte(lines[i+1]), &event); err != nil {
+			t.Fatalf("Invalid event %d: %v", i, err)
+		}
+		if len(event) != 3 || event[0] != want.time || event[1] != "o" || event[2] != want.data {
+			t.Errorf("Event %d = %v, expected [%v o %q]", i, event, want.time, want.data)
+		}
+	}
+}
+
+func TestCastRecorderFlushesOnClose(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := NewCastRecorder(&buf, 80, 24, "", nil)
+	if err != nil {
+		t.Fatalf("NewCastRecorder failed: %v", err)
+	}
+	_, _ = r.Write([]byte("\xe2\x82"))
+	if strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("Incomplete character should be held back, got:\n%s", buf.String())
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Errorf("Close should record held back bytes, got:\n%s", buf.String())
+	}
+}
+
+func TestParseSttySize(t *testing.T) {
+	tests := []struct {
+		output        string
+		width, height int
+		ok            bool
+	}{
+		{"40 120\n", 120, 40, true},
+		{"24 80", 80, 24, true},
+		{"", 0, 0, false},
+		{"0 0\n", 0, 0, false},
+		{"stty: invalid argument", 0, 0, false},
+	}
+	for _, tt := range tests {
+		width, height, ok := parseSttySize(tt.output)
+		if width != tt.width || height != tt.height || ok != tt.ok {
+			t.Errorf("parseSttySize(%q) = %d, %d, %v; expected %d, %d, %v", tt.output, width, height, ok, tt.width, tt.height, tt.ok)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package tool
 
 import (
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // Tool represents an AI coding tool that can be run in COI containers
@@ -11,6 +16,10 @@ type Tool interface {
 	// Name returns the tool name (e.g., "claude", "aider", "cursor")
 	Name() string
 
+	// Description returns the human-readable tool name used in help text and
+	// messages (e.g., "Claude Code", "Aider")
+	Description() string
+
 	// Binary returns the binary name to execute
 	Binary() string
 
@@ -31,11 +40,39 @@ type Tool interface {
 	// DiscoverSessionID finds the tool's internal session ID from saved state
 	// stateDir: path to the tool's config directory with saved state
 	// Return "" if tool doesn't support session resume (will start fresh each time)
-	DiscoverSessionID(stateDir string) string
+	// or no session was saved, and an error if the saved state can't be read
+	DiscoverSessionID(stateDir string) (string, error)
 
 	// GetSandboxSettings returns settings to inject for sandbox/bypass permissions
 	// Return empty map if tool doesn't need settings injection
 	GetSandboxSettings() map[string]interface{}
+
+	// WorkingDir returns the default working directory relative to /workspace
+	// Return "" to start in the workspace root (overridden by coi shell --cwd)
+	WorkingDir() string
+
+	// CredentialsValid reports whether the host credentials in configDir (e.g. ~/.claude)
+	// look usable, e.g. are not expired. Return true if the tool cannot tell.
+	CredentialsValid(configDir string) bool
+
+	// Validate checks the tool is installed in image, using commandExists to
+	// probe the container for a command (e.g. via command -v)
+	Validate(image string, commandExists func(name string) bool) error
+
+	// ExcludeFromSave returns glob patterns for paths in the config directory
+	// that are not worth saving with a session (logs, caches). Patterns are
+	// matched against slash-separated paths relative to the config directory;
+	// a pattern without "/" matches a file or directory name at any depth.
+	ExcludeFromSave() []string
+}
+
+// validateBinary is the Validate implementation for tools that only need their
+// binary on the PATH
+func validateBinary(t Tool, image string, commandExists func(name string) bool) error {
+	if !commandExists(t.Binary()) {
+		return fmt.Errorf("tool '%s' not installed in image '%s' (no '%s' command found)", t.Name(), image, t.Binary())
+	}
+	return nil
 }
 
 // ClaudeTool implements Tool for Claude Code
@@ -50,6 +87,10 @@ func (c *ClaudeTool) Name() string {
 	return "claude"
 }
 
+func (c *ClaudeTool) Description() string {
+	return "Claude Code"
+}
+
 func (c *ClaudeTool) Binary() string {
 	return "claude"
 }
@@ -74,18 +115,62 @@ func (c *ClaudeTool) BuildCommand(sessionID string, resume bool, resumeSessionID
 			cmd = append(cmd, "--resume")
 		}
 	} else {
-		cmd = append(cmd, "--session-id", sessionID)
+		cmd = append(cmd, "--session-id", claudeSessionUUID(sessionID))
 	}
 
 	return cmd
 }
 
-func (c *ClaudeTool) DiscoverSessionID(stateDir string) string {
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// claudeSessionUUID returns sessionID if it is a UUID, and otherwise (friendly
+// session IDs) a UUID derived from it, since Claude's --session-id only accepts
+// UUIDs. Resume finds the ID again via DiscoverSessionID.
+func claudeSessionUUID(sessionID string) string {
+	if uuidPattern.MatchString(sessionID) {
+		return sessionID
+	}
+	sum := sha1.Sum([]byte("coi-session:" + sessionID))
+	sum[6] = (sum[6] & 0x0f) | 0x50 // Version 5 (name-based, SHA-1)
+	sum[8] = (sum[8] & 0x3f) | 0x80 // Variant 10
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func (c *ClaudeTool) DiscoverSessionID(stateDir string) (string, error) {
+	if _, err := os.Stat(stateDir); err != nil {
+		return "", fmt.Errorf("no saved state: %w", err)
+	}
+
 	// Claude stores sessions as .jsonl files in projects/-workspace/
 	// This logic is extracted from cleanup.go:387-411
-	projectsDir := filepath.Join(stateDir, "projects", "-workspace")
+	if id := firstSessionFile(filepath.Join(stateDir, "projects", "-workspace")); id != "" {
+		return id, nil
+	}
 
-	entries, err := os.ReadDir(projectsDir)
+	// Sessions started in a workspace subdirectory (--cwd) live in
+	// projects/-workspace-<subdir>/ (Claude replaces "/" with "-")
+	subdirs, _ := filepath.Glob(filepath.Join(stateDir, "projects", "-workspace-*"))
+	for _, dir := range subdirs {
+		if id := firstSessionFile(dir); id != "" {
+			return id, nil
+		}
+	}
+
+	// Workspaces mounted elsewhere (--mount-at /home/me/app) get their own
+	// project directory (-home-me-app)
+	others, _ := filepath.Glob(filepath.Join(stateDir, "projects", "*"))
+	for _, dir := range others {
+		if id := firstSessionFile(dir); id != "" {
+			return id, nil
+		}
+	}
+
+	return "", nil
+}
+
+// firstSessionFile returns the name (without .jsonl) of the first session file in dir
+func firstSessionFile(dir string) string {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return ""
 	}
@@ -111,3 +196,38 @@ func (c *ClaudeTool) GetSandboxSettings() map[string]interface{} {
 		},
 	}
 }
+
+func (c *ClaudeTool) WorkingDir() string {
+	return ""
+}
+
+// claudeCredentials is the subset of .credentials.json needed to check expiry
+type claudeCredentials struct {
+	ClaudeAiOauth *struct {
+		ExpiresAt int64 `json:"expiresAt"` // Unix milliseconds
+	} `json:"claudeAiOauth"`
+}
+
+func (c *ClaudeTool) CredentialsValid(configDir string) bool {
+	data, err := os.ReadFile(filepath.Join(configDir, ".credentials.json"))
+	if err != nil {
+		return true // Missing credentials are reported separately (or API key auth is used)
+	}
+
+	var creds claudeCredentials
+	if err := json.Unmarshal(data, &creds); err != nil || creds.ClaudeAiOauth == nil || creds.ClaudeAiOauth.ExpiresAt == 0 {
+		return true // Unknown format - can't tell
+	}
+
+	return time.Now().Before(time.UnixMilli(creds.ClaudeAiOauth.ExpiresAt))
+}
+
+func (c *ClaudeTool) Validate(image string, commandExists func(name string) bool) error {
+	return validateBinary(c, image, commandExists)
+}
+
+// ExcludeFromSave drops Claude's debug logs and caches it rebuilds on its own.
+// file-history is kept: it backs checkpoint rewinds in resumed sessions.
+func (c *ClaudeTool) ExcludeFromSave() []string {
+	return []string{"debug", "statsig", "shell-snapshots", "*.log"}
+}
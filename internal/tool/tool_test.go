@@ -1,10 +1,12 @@
 package tool
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestClaudeToolBasics(t *testing.T) {
@@ -14,6 +16,10 @@ func TestClaudeToolBasics(t *testing.T) {
 		t.Errorf("Expected name 'claude', got '%s'", tool.Name())
 	}
 
+	if tool.Description() != "Claude Code" {
+		t.Errorf("Expected description 'Claude Code', got '%s'", tool.Description())
+	}
+
 	if tool.Binary() != "claude" {
 		t.Errorf("Expected binary 'claude', got '%s'", tool.Binary())
 	}
@@ -25,15 +31,43 @@ func TestClaudeToolBasics(t *testing.T) {
 	if tool.SessionsDirName() != "sessions-claude" {
 		t.Errorf("Expected sessions dir 'sessions-claude', got '%s'", tool.SessionsDirName())
 	}
+
+	if tool.WorkingDir() != "" {
+		t.Errorf("Expected workspace root working dir, got '%s'", tool.WorkingDir())
+	}
+}
+
+func TestClaudeValidate(t *testing.T) {
+	tool := NewClaude()
+
+	var probed string
+	err := tool.Validate("coi", func(name string) bool {
+		probed = name
+		return true
+	})
+	if err != nil {
+		t.Errorf("Expected no error when binary exists, got %v", err)
+	}
+	if probed != "claude" {
+		t.Errorf("Expected probe for 'claude', got '%s'", probed)
+	}
+
+	err = tool.Validate("my-image", func(name string) bool { return false })
+	if err == nil {
+		t.Fatal("Expected error when binary is missing")
+	}
+	if !strings.Contains(err.Error(), "tool 'claude' not installed in image 'my-image'") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
 }
 
 func TestClaudeBuildCommand_NewSession(t *testing.T) {
 	tool := NewClaude()
-	sessionID := "test-session-123"
+	sessionID := "0b6e1c3a-5f2d-4c8e-9a7b-1d2e3f4a5b6c"
 
 	cmd := tool.BuildCommand(sessionID, false, "")
 
-	expected := []string{"claude", "--verbose", "--permission-mode", "bypassPermissions", "--session-id", "test-session-123"}
+	expected := []string{"claude", "--verbose", "--permission-mode", "bypassPermissions", "--session-id", sessionID}
 
 	if len(cmd) != len(expected) {
 		t.Fatalf("Expected %d args, got %d: %v", len(expected), len(cmd), cmd)
@@ -46,6 +80,21 @@ func TestClaudeBuildCommand_NewSession(t *testing.T) {
 	}
 }
 
+func TestClaudeBuildCommand_FriendlySessionID(t *testing.T) {
+	tool := NewClaude()
+
+	cmd := tool.BuildCommand("swift-otter-4821", false, "")
+	id := cmd[len(cmd)-1]
+
+	// Claude only accepts UUIDs, so friendly IDs map to a stable v5 UUID
+	if !uuidPattern.MatchString(id) || id[14] != '5' {
+		t.Errorf("Expected a version 5 UUID for a friendly session ID, got %s", id)
+	}
+	if again := tool.BuildCommand("swift-otter-4821", false, ""); again[len(again)-1] != id {
+		t.Errorf("Expected the same UUID for the same session ID, got %s and %s", id, again[len(again)-1])
+	}
+}
+
 func TestClaudeBuildCommand_ResumeWithID(t *testing.T) {
 	tool := NewClaude()
 	resumeSessionID := "cli-session-456"
@@ -114,7 +163,58 @@ func TestClaudeDiscoverSessionID_ValidSession(t *testing.T) {
 	}
 
 	// Test discovery
-	discovered := tool.DiscoverSessionID(tmpDir)
+	discovered, err := tool.DiscoverSessionID(tmpDir)
+	if err != nil {
+		t.Fatalf("DiscoverSessionID failed: %v", err)
+	}
+	if discovered != sessionID {
+		t.Errorf("Expected session ID '%s', got '%s'", sessionID, discovered)
+	}
+}
+
+func TestClaudeDiscoverSessionID_WorkspaceSubdir(t *testing.T) {
+	tool := NewClaude()
+
+	// Sessions started with --cwd packages/api are stored under -workspace-packages-api
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, "projects", "-workspace-packages-api")
+	if err := os.MkdirAll(projectsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	sessionID := "subdir-session-xyz"
+	if err := os.WriteFile(filepath.Join(projectsDir, sessionID+".jsonl"), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	discovered, err := tool.DiscoverSessionID(tmpDir)
+	if err != nil {
+		t.Fatalf("DiscoverSessionID failed: %v", err)
+	}
+	if discovered != sessionID {
+		t.Errorf("Expected session ID '%s', got '%s'", sessionID, discovered)
+	}
+}
+
+func TestClaudeDiscoverSessionID_CustomMountPath(t *testing.T) {
+	tool := NewClaude()
+
+	// Workspaces mounted with --mount-at /home/me/app are stored under -home-me-app
+	tmpDir := t.TempDir()
+	projectsDir := filepath.Join(tmpDir, "projects", "-home-me-app")
+	if err := os.MkdirAll(projectsDir, 0o755); err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+
+	sessionID := "mounted-session-123"
+	if err := os.WriteFile(filepath.Join(projectsDir, sessionID+".jsonl"), []byte("{}"), 0o644); err != nil {
+		t.Fatalf("Failed to create session file: %v", err)
+	}
+
+	discovered, err := tool.DiscoverSessionID(tmpDir)
+	if err != nil {
+		t.Fatalf("DiscoverSessionID failed: %v", err)
+	}
 	if discovered != sessionID {
 		t.Errorf("Expected session ID '%s', got '%s'", sessionID, discovered)
 	}
@@ -131,7 +231,10 @@ func TestClaudeDiscoverSessionID_NoSession(t *testing.T) {
 	}
 
 	// Test discovery with no session files
-	discovered := tool.DiscoverSessionID(tmpDir)
+	discovered, err := tool.DiscoverSessionID(tmpDir)
+	if err != nil {
+		t.Fatalf("DiscoverSessionID failed: %v", err)
+	}
 	if discovered != "" {
 		t.Errorf("Expected empty session ID, got '%s'", discovered)
 	}
@@ -141,10 +244,13 @@ func TestClaudeDiscoverSessionID_NonExistentDir(t *testing.T) {
 	tool := NewClaude()
 
 	// Test discovery with non-existent directory
-	discovered := tool.DiscoverSessionID("/nonexistent/path")
+	discovered, err := tool.DiscoverSessionID("/nonexistent/path")
 	if discovered != "" {
 		t.Errorf("Expected empty session ID for non-existent path, got '%s'", discovered)
 	}
+	if err == nil {
+		t.Error("Expected an error for non-existent path")
+	}
 }
 
 func TestClaudeGetSandboxSettings(t *testing.T) {
@@ -226,3 +332,53 @@ func indexOf(slice []string, item string) int {
 	}
 	return -1
 }
+
+func TestClaudeCredentialsValid(t *testing.T) {
+	tool := NewClaude()
+
+	writeCredentials := func(t *testing.T, content string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ".credentials.json"), []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write credentials: %v", err)
+		}
+		return dir
+	}
+
+	future := time.Now().Add(time.Hour).UnixMilli()
+	past := time.Now().Add(-time.Hour).UnixMilli()
+
+	valid := writeCredentials(t, fmt.Sprintf(`{"claudeAiOauth":{"accessToken":"x","expiresAt":%d}}`, future))
+	if !tool.CredentialsValid(valid) {
+		t.Error("Expected unexpired credentials to be valid")
+	}
+
+	expired := writeCredentials(t, fmt.Sprintf(`{"claudeAiOauth":{"accessToken":"x","expiresAt":%d}}`, past))
+	if tool.CredentialsValid(expired) {
+		t.Error("Expected expired credentials to be invalid")
+	}
+
+	// Formats we can't interpret are assumed valid
+	for _, content := range []string{`{}`, `not json`, `{"claudeAiOauth":{"accessToken":"x"}}`} {
+		if !tool.CredentialsValid(writeCredentials(t, content)) {
+			t.Errorf("Expected unknown credentials format %q to be treated as valid", content)
+		}
+	}
+
+	if !tool.CredentialsValid(t.TempDir()) {
+		t.Error("Expected missing credentials file to be treated as valid")
+	}
+}
+
+func TestClaudeExcludeFromSave(t *testing.T) {
+	excludes := NewClaude().ExcludeFromSave()
+
+	if indexOf(excludes, "debug") == -1 {
+		t.Errorf("Expected debug logs to be excluded, got %v", excludes)
+	}
+	// Transcripts and file history are needed to resume
+	for _, kept := range []string{"projects", "file-history"} {
+		if indexOf(excludes, kept) != -1 {
+			t.Errorf("Expected %s to be saved, got excludes %v", kept, excludes)
+		}
+	}
+}
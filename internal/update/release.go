@@ -0,0 +1,118 @@
+package update
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fetchTimeout bounds the release lookup so an unreachable endpoint can't hang coi
+const fetchTimeout = 10 * time.Second
+
+// Release is the subset of a GitHub "latest release" API response coi uses
+type Release struct {
+	TagName string `json:"tag_name"`
+	URL     string `json:"html_url"`
+}
+
+// Version returns the release tag without a leading "v"
+func (r Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+// FetchLatest queries releaseURL (a GitHub releases/latest endpoint or one that
+// returns the same JSON) for the newest release
+func FetchLatest(ctx context.Context, releaseURL string) (*Release, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", releaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query %s: HTTP %d", releaseURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release info: %w", err)
+	}
+	return parseRelease(body)
+}
+
+// parseRelease decodes a release response, requiring a tag name
+func parseRelease(body []byte) (*Release, error) {
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release info: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release info has no tag_name")
+	}
+	return &release, nil
+}
+
+// CompareVersions compares dotted numeric versions such as "v0.6.0" and
+// "0.10.1", returning -1, 0 or 1. Pre-release and build suffixes ("-rc1",
+// "+abc") are ignored. ok is false when either version is not numeric (e.g.
+// "dev" builds), in which case they cannot be compared.
+func CompareVersions(a, b string) (result int, ok bool) {
+	partsA, okA := parseVersion(a)
+	partsB, okB := parseVersion(b)
+	if !okA || !okB {
+		return 0, false
+	}
+
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x = partsA[i]
+		}
+		if i < len(partsB) {
+			y = partsB[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// parseVersion splits "v1.2.3-rc1" into [1 2 3]
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	if version == "" {
+		return nil, false
+	}
+
+	fields := strings.Split(version, ".")
+	parts := make([]int, len(fields))
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
@@ -0,0 +1,62 @@
+package update
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		want   int
+		wantOK bool
+	}{
+		{"0.6.0", "v0.6.0", 0, true},
+		{"0.6.0", "0.7.0", -1, true},
+		{"0.10.0", "0.9.3", 1, true},
+		{"1.0", "1.0.0", 0, true},
+		{"1.0.1", "1.0", 1, true},
+		{"0.7.0-rc1", "0.7.0", 0, true},
+		{"dev", "0.7.0", 0, false},
+		{"0.7.0", "", 0, false},
+		{"0.x.0", "0.7.0", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := CompareVersions(tt.a, tt.b)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("CompareVersions(%q, %q) = (%d, %v), want (%d, %v)", tt.a, tt.b, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFetchLatest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			_, _ = w.Write([]byte(`{"tag_name": "v0.7.0", "html_url": "https://example.com/releases/v0.7.0", "body": "notes"}`))
+		case "/empty":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	release, err := FetchLatest(context.Background(), server.URL+"/latest")
+	if err != nil {
+		t.Fatalf("FetchLatest() unexpected error: %v", err)
+	}
+	if release.Version() != "0.7.0" || release.URL != "https://example.com/releases/v0.7.0" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+
+	if _, err := FetchLatest(context.Background(), server.URL+"/empty"); err == nil {
+		t.Error("Expected error for release without tag_name")
+	}
+	if _, err := FetchLatest(context.Background(), server.URL+"/missing"); err == nil {
+		t.Error("Expected error for HTTP 404")
+	}
+}
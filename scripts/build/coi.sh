@@ -20,6 +20,26 @@ log() {
     echo "[coi] $*"
 }
 
+# Distribution of the base image (ubuntu or debian), set by detect_distro
+DISTRO_ID=""
+
+#######################################
+# Detect the base distribution
+# coi build checks this before running the script; this guards direct use
+#######################################
+detect_distro() {
+    DISTRO_ID="$(. /etc/os-release && echo "$ID")"
+    case "$DISTRO_ID" in
+        ubuntu|debian)
+            log "Building on $(. /etc/os-release && echo "$PRETTY_NAME")"
+            ;;
+        *)
+            log "ERROR: unsupported base distribution '$DISTRO_ID' (supported: ubuntu, debian)"
+            exit 1
+            ;;
+    esac
+}
+
 #######################################
 # Configure DNS if misconfigured
 # Only applies fix if DNS resolution fails
@@ -74,7 +94,7 @@ install_base_dependencies() {
         dnsutils \
         build-essential libssl-dev libreadline-dev zlib1g-dev \
         libffi-dev libyaml-dev libgmp-dev \
-        libsqlite3-dev libpq-dev libmysqlclient-dev \
+        libsqlite3-dev libpq-dev default-libmysqlclient-dev \
         libxml2-dev libxslt1-dev libcurl4-openssl-dev
 
     log "Base dependencies installed"
@@ -98,9 +118,14 @@ install_nodejs() {
 create_code_user() {
     log "Creating code user..."
 
-    # Rename ubuntu user to code
-    usermod -l "$CODE_USER" -d "/home/$CODE_USER" -m ubuntu
-    groupmod -n "$CODE_USER" ubuntu
+    if id ubuntu > /dev/null 2>&1; then
+        # Rename the image's default ubuntu user (uid 1000) to code
+        usermod -l "$CODE_USER" -d "/home/$CODE_USER" -m ubuntu
+        groupmod -n "$CODE_USER" ubuntu
+    else
+        # Debian images have no default user
+        useradd -m -u "$CODE_UID" -s /bin/bash "$CODE_USER"
+    fi
     mkdir -p "/home/$CODE_USER/.claude"
     mkdir -p "/home/$CODE_USER/.ssh"
     chmod 700 "/home/$CODE_USER/.ssh"
@@ -195,11 +220,11 @@ install_docker() {
 
     # Add Docker GPG key
     install -m 0755 -d /etc/apt/keyrings
-    curl -fsSL https://download.docker.com/linux/ubuntu/gpg | gpg --dearmor -o /etc/apt/keyrings/docker.gpg
+    curl -fsSL "https://download.docker.com/linux/$DISTRO_ID/gpg" | gpg --dearmor -o /etc/apt/keyrings/docker.gpg
     chmod a+r /etc/apt/keyrings/docker.gpg
 
     # Add Docker repository
-    echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.gpg] https://download.docker.com/linux/ubuntu $(. /etc/os-release && echo $VERSION_CODENAME) stable" | tee /etc/apt/sources.list.d/docker.list > /dev/null
+    echo "deb [arch=$(dpkg --print-architecture) signed-by=/etc/apt/keyrings/docker.gpg] https://download.docker.com/linux/$DISTRO_ID $(. /etc/os-release && echo $VERSION_CODENAME) stable" | tee /etc/apt/sources.list.d/docker.list > /dev/null
 
     # Install Docker
     apt-get update -qq
@@ -249,6 +274,7 @@ cleanup() {
 main() {
     log "Starting coi image build..."
 
+    detect_distro
     configure_dns_if_needed
     install_base_dependencies
     install_nodejs